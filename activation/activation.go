@@ -0,0 +1,71 @@
+// Package activation implements the systemd socket activation protocol, so
+// the server can be started on demand with its listening sockets inherited
+// from the service manager instead of managing ports itself.
+package activation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by the service manager
+const listenFDsStart = 3
+
+// Listeners returns the listening sockets passed to this process through the
+// LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES environment variables, or nil
+// when the process was not socket-activated. The variables are unset so
+// child processes do not inherit them.
+func Listeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return listeners(os.Getpid(), os.Getenv, listenFDsStart)
+}
+
+// listeners converts the descriptors announced in the environment, starting
+// at fd start, into listeners
+func listeners(pid int, getenv func(string) string, start int) ([]net.Listener, error) {
+	if getenv("LISTEN_PID") != strconv.Itoa(pid) {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", getenv("LISTEN_FDS"))
+	}
+
+	names := strings.Split(getenv("LISTEN_FDNAMES"), ":")
+	result := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		fd := start + i
+		name := fmt.Sprintf("LISTEN_FD_%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(fd), name)
+		listener, err := net.FileListener(file)
+		// FileListener duplicates the descriptor, so the original can be closed
+		file.Close()
+		if err != nil {
+			for _, l := range result {
+				l.Close()
+			}
+			return nil, fmt.Errorf("inherited fd %d (%s) is not a listening socket: %w", fd, name, err)
+		}
+		result = append(result, listener)
+	}
+	return result, nil
+}
+
+// Address formats the address of a listener as network://address, for
+// example tcp://127.0.0.1:9000 or unix:///run/mock-lsp-server.sock
+func Address(listener net.Listener) string {
+	addr := listener.Addr()
+	return addr.Network() + "://" + addr.String()
+}
@@ -0,0 +1,77 @@
+package activation
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+// env returns a getenv function backed by a map
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestListenersNotActivated(t *testing.T) {
+	testCases := []struct {
+		name string
+		vars map[string]string
+	}{
+		{"no variables", map[string]string{}},
+		{"other process", map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := listeners(4242, env(tc.vars), listenFDsStart)
+			if err != nil || got != nil {
+				t.Errorf("Expected no listeners, got %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestListenersInheritedSocket(t *testing.T) {
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer original.Close()
+
+	// Duplicate the socket to stand in for a descriptor passed by systemd
+	file, err := original.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get socket file: %v", err)
+	}
+
+	vars := map[string]string{"LISTEN_PID": "4242", "LISTEN_FDS": "1", "LISTEN_FDNAMES": "lsp"}
+	got, err := listeners(4242, env(vars), int(file.Fd()))
+	if err != nil {
+		t.Fatalf("listeners failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 listener, got %d", len(got))
+	}
+	defer got[0].Close()
+
+	if expected := "tcp://" + original.Addr().String(); Address(got[0]) != expected {
+		t.Errorf("Expected address %s, got %s", expected, Address(got[0]))
+	}
+
+	client, err := net.Dial("tcp", original.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	server, err := got[0].Accept()
+	if err != nil {
+		t.Fatalf("Expected the inherited listener to accept, got %v", err)
+	}
+	server.Close()
+}
+
+func TestListenersInvalidCount(t *testing.T) {
+	vars := map[string]string{"LISTEN_PID": strconv.Itoa(4242), "LISTEN_FDS": "many"}
+	if _, err := listeners(4242, env(vars), listenFDsStart); err == nil {
+		t.Error("Expected an error for an invalid LISTEN_FDS")
+	}
+}
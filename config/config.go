@@ -1,18 +1,27 @@
 package config
 
 import (
-	"slices"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var alphanumericHyphenUnderscore = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
+// uriSchemePattern matches a URI scheme as defined by RFC 3986
+var uriSchemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*$`)
+
+// traceIDPattern matches a W3C trace context trace ID
+var traceIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
 // ServerConfig represents the complete server configuration
 type ServerConfig struct {
 	AppName string         `json:"app_name" validate:"required,min=1,max=100"`
@@ -28,6 +37,9 @@ type ServerSettings struct {
 	Description string   `json:"description" validate:"max=500"`
 	Timeout     Duration `json:"timeout" validate:"min=1s,max=300s"`
 	MaxRequests int      `json:"max_requests" validate:"min=1,max=10000"`
+	// ExitCodes maps ExitOutcomes names to process exit codes. Outcomes left
+	// out keep their default codes.
+	ExitCodes map[string]int `json:"exit_codes"`
 }
 
 // LoggingConfig represents logging configuration with validation
@@ -40,27 +52,102 @@ type LoggingConfig struct {
 	MaxAge     int    `json:"max_age_days" validate:"min=0,max=365"`
 	Compress   bool   `json:"compress"`
 	Format     string `json:"format" validate:"oneof=text json"`
+	// PayloadMethods lists the methods whose params and results are logged
+	// in full, such as "textDocument/hover". A * matches any run of
+	// characters, so "workspace/*" selects every workspace method and "*"
+	// every method. Payloads of other methods are never logged.
+	PayloadMethods []string `json:"payload_methods"`
 }
 
+// DefaultShutdownDrainTimeout is how long shutdown waits for the requests in
+// flight unless configured otherwise
+const DefaultShutdownDrainTimeout = 5 * time.Second
+
 // LSPConfig represents LSP-specific configuration
 type LSPConfig struct {
-	InitializeTimeout Duration          `json:"initialize_timeout" validate:"min=1s,max=60s"`
-	CompletionConfig  CompletionConfig  `json:"completion" validate:"required"`
-	HoverConfig       HoverConfig       `json:"hover" validate:"required"`
-	DiagnosticsConfig DiagnosticsConfig `json:"diagnostics" validate:"required"`
-	MockData          MockDataConfig    `json:"mock_data" validate:"required"`
-	Features          map[string]bool   `json:"features"`
-	TriggerCharacters []string          `json:"trigger_characters" validate:"max=20"`
-	Extensions        []string          `json:"extensions" validate:"dive,min=1,max=10"`
+	InitializeTimeout   Duration               `json:"initialize_timeout" validate:"min=1s,max=60s"`
+	CompletionConfig    CompletionConfig       `json:"completion" validate:"required"`
+	HoverConfig         HoverConfig            `json:"hover" validate:"required"`
+	DiagnosticsConfig   DiagnosticsConfig      `json:"diagnostics" validate:"required"`
+	CodeActionConfig    CodeActionConfig       `json:"code_action"`
+	RenameConfig        RenameConfig           `json:"rename"`
+	SignatureHelpConfig SignatureHelpConfig    `json:"signature_help"`
+	InlayHintConfig     InlayHintConfig        `json:"inlay_hint"`
+	InlineCompletion    InlineCompletionConfig `json:"inline_completion"`
+	FileOperations      FileOperationsConfig   `json:"file_operations"`
+	ExecuteCommand      ExecuteCommandConfig   `json:"execute_command"`
+	MockData            MockDataConfig         `json:"mock_data" validate:"required"`
+	Latency             LatencyConfig          `json:"latency"`
+	Presets             map[string]string      `json:"presets"`
+	PresetReverseRanges bool                   `json:"preset_reverse_ranges"` // Swap range ends in the extreme-positions preset
+	Features            map[string]bool        `json:"features"`
+	TriggerCharacters   []string               `json:"trigger_characters" validate:"max=20"`
+	Extensions          []string               `json:"extensions" validate:"dive,min=1,max=10"`
+	AllowedSchemes      []string               `json:"allowed_schemes"` // Document URI schemes accepted; empty allows any
+	Locale              string                 `json:"locale"`          // Language of server messages; empty follows the client
+	TraceMetadata       TraceMetadataConfig    `json:"trace_metadata"`
+	RecentTraffic       int                    `json:"recent_traffic" validate:"min=0,max=10000"` // Wire messages kept in memory
+	Redaction           RedactionConfig        `json:"redaction"`
+	ReadOnly            bool                   `json:"read_only"` // Never change client state; code actions are disabled
+	Minimal             bool                   `json:"minimal"`   // Support only the lifecycle and text sync
+	// SchemaValidation decodes the params of every incoming message into
+	// its protocol type and logs the fields the client sent that the type
+	// dropped, to surface drift between clients and the protocol types
+	SchemaValidation bool `json:"schema_validation"`
+	// DynamicRegistration registers features with client/registerCapability
+	// instead of announcing them in initialize, for clients that support it
+	DynamicRegistration bool `json:"dynamic_registration"`
+	// NotificationQueue bounds the diagnostics and progress notifications
+	// waiting to be sent to the client
+	NotificationQueue NotificationQueueConfig `json:"notification_queue"`
+	// ShutdownDrainTimeout is how long shutdown waits for the requests in
+	// flight to finish before cancelling them. Zero, as left by a config
+	// file that does not set it, means DefaultShutdownDrainTimeout.
+	ShutdownDrainTimeout Duration `json:"shutdown_drain_timeout"`
+	// MemoryPressure degrades the server gracefully when the heap grows,
+	// instead of letting it grow until the process is killed
+	MemoryPressure MemoryPressureConfig `json:"memory_pressure"`
+	// Indexing simulates indexing the workspace after initialized, so
+	// clients can be tested against a partially indexed server
+	Indexing IndexingConfig `json:"indexing"`
+	// PartialResults shapes the streaming of list results to clients that
+	// ask for partial results
+	PartialResults PartialResultsConfig `json:"partial_results"`
+	// RequestIDs configures how the IDs of incoming requests are checked
+	// and how the IDs of server-initiated requests are generated
+	RequestIDs RequestIDsConfig `json:"request_ids"`
+	// FailurePreset selects one of FailurePresets, a bundle of injected
+	// delays, errors and lost responses; empty injects no failures
+	FailurePreset string `json:"failure_preset"`
+	// ClientSettings lets workspace/didChangeConfiguration reconfigure the
+	// running server from the client's settings
+	ClientSettings ClientSettingsConfig `json:"client_settings"`
+	// DisableInvariants lets randomized and edge-case responses break hard
+	// protocol constraints, such as ranges within the uinteger bounds and
+	// parseable URIs, instead of having them repaired before they are sent
+	DisableInvariants bool `json:"disable_invariants"`
+}
+
+// ClientSettingsConfig configures live reconfiguration: the settings
+// section, shaped like LSPConfig, is merged into the running configuration
+type ClientSettingsConfig struct {
+	Section string `json:"section"` // Settings section read; empty uses "mockLsp"
+	Pull    bool   `json:"pull"`    // Request the section with workspace/configuration after initialized and on every change
 }
 
 // CompletionConfig configures completion behavior
 type CompletionConfig struct {
-	Enabled           bool     `json:"enabled"`
-	MaxItems          int      `json:"max_items" validate:"min=1,max=1000"`
-	TriggerCharacters []string `json:"trigger_characters" validate:"max=10"`
-	CaseSensitive     bool     `json:"case_sensitive"`
-	IncludeSnippets   bool     `json:"include_snippets"`
+	Enabled           bool           `json:"enabled"`
+	MaxItems          int            `json:"max_items" validate:"min=1,max=1000"`
+	TriggerCharacters []string       `json:"trigger_characters" validate:"max=10"`
+	CaseSensitive     bool           `json:"case_sensitive"`
+	IncludeSnippets   bool           `json:"include_snippets"`
+	Kinds             map[string]int `json:"kinds"`             // Percentage of the items per CompletionItemKinds name; empty keeps the default items
+	Deprecated        int            `json:"deprecated"`        // Percentage of the items marked deprecated
+	Preselect         int            `json:"preselect"`         // Percentage of the items marked preselected
+	SortText          string         `json:"sort_text"`         // One of CompletionSortTexts; empty leaves sortText unset
+	FilterText        bool           `json:"filter_text"`       // Give items a filterText differing from their label
+	CommitCharacters  []string       `json:"commit_characters"` // Characters given to items in growing prefixes, starting with none
 }
 
 // HoverConfig configures hover behavior
@@ -70,6 +157,72 @@ type HoverConfig struct {
 	ShowDocs    bool `json:"show_docs"`
 	ShowExample bool `json:"show_example"`
 	MaxLength   int  `json:"max_length" validate:"min=100,max=10000"`
+	// Markdown lists Markdown elements from HoverMarkdownElements appended
+	// to the hover content, to exercise client Markdown renderers
+	Markdown []string `json:"markdown"`
+}
+
+// CodeActionConfig configures the quick fixes and refactorings offered by
+// textDocument/codeAction
+type CodeActionConfig struct {
+	Enabled      bool     `json:"enabled"`
+	Kinds        []string `json:"kinds"`        // CodeActionKinds offered and announced
+	QuickFixes   int      `json:"quick_fixes"`  // Quick fixes per diagnostic of the server
+	Refactorings int      `json:"refactorings"` // Actions per refactor and source kind
+	Resolve      bool     `json:"resolve"`      // Leave edits to codeAction/resolve for clients supporting it
+}
+
+// RenameConfig bounds the workspace edits of textDocument/rename, which
+// replace the occurrences of the renamed word in the open documents
+type RenameConfig struct {
+	Files        int `json:"files"`          // Documents edited at most, the renamed one first
+	EditsPerFile int `json:"edits_per_file"` // Occurrences replaced per document at most
+}
+
+// SignatureHelpConfig configures textDocument/signatureHelp
+type SignatureHelpConfig struct {
+	TriggerCharacters   []string `json:"trigger_characters"`   // Characters that open signature help
+	RetriggerCharacters []string `json:"retrigger_characters"` // Characters that update open signature help
+	Signatures          int      `json:"signatures"`           // Overloads offered, the n-th with n parameters
+}
+
+// ExecuteCommandConfig configures the commands of workspace/executeCommand
+type ExecuteCommandConfig struct {
+	// Commands maps the command IDs announced in executeCommandProvider to
+	// what running them does. Other commands are refused.
+	Commands map[string]CommandConfig `json:"commands"`
+}
+
+// CommandConfig is a mock command run by workspace/executeCommand
+type CommandConfig struct {
+	Result json.RawMessage `json:"result"` // Returned as is; null when unset
+	// ApplyEdit sends workspace/applyEdit inserting EditText at the start of
+	// the document whose URI is the first argument of the command
+	ApplyEdit bool   `json:"apply_edit"`
+	EditText  string `json:"edit_text"` // Empty inserts a comment naming the command
+}
+
+// InlayHintConfig configures textDocument/inlayHint
+type InlayHintConfig struct {
+	LineInterval int      `json:"line_interval"` // Hints go on every n-th line
+	WordInterval int      `json:"word_interval"` // and there on every n-th word
+	Kinds        []string `json:"kinds"`         // Hint kinds, taken in turn, from InlayHintKinds
+	Resolve      bool     `json:"resolve"`       // Leave tooltips to inlayHint/resolve
+}
+
+// InlineCompletionConfig configures the ghost text suggestions of
+// textDocument/inlineCompletion
+type InlineCompletionConfig struct {
+	Suggestions int `json:"suggestions"` // Suggestions offered; 0 uses 3
+	Lines       int `json:"lines"`       // Lines of each suggestion; 0 uses 3
+}
+
+// FileOperationsConfig configures the workspace/willCreateFiles,
+// willRenameFiles and willDeleteFiles requests and their did* notifications
+type FileOperationsConfig struct {
+	Filters       []string `json:"filters"`        // Globs of the files the client reports; empty matches every file
+	RenameImports bool     `json:"rename_imports"` // Patch the relative import paths of renamed files in open documents
+	DeleteImports bool     `json:"delete_imports"` // Remove the lines importing deleted files from open documents
 }
 
 // DiagnosticsConfig configures diagnostic reporting
@@ -80,6 +233,19 @@ type DiagnosticsConfig struct {
 	Severities   []string `json:"severities" validate:"dive,oneof=error warning info hint"`
 	MockWarnings bool     `json:"mock_warnings"`
 	MockErrors   bool     `json:"mock_errors"`
+	// Duplicates publishes this many extra exact copies of each diagnostic
+	Duplicates int `json:"duplicates" validate:"min=0,max=10"`
+	// Overlapping adds a diagnostic whose range overlaps each original
+	Overlapping bool `json:"overlapping"`
+	// SeverityVariants adds copies of each diagnostic differing only in severity
+	SeverityVariants bool `json:"severity_variants"`
+	// UnopenedURIs receive diagnostics once the client is initialized, without
+	// ever being opened (untitled:, git:, or file: URIs of closed files)
+	UnopenedURIs []string `json:"unopened_uris" validate:"max=100"`
+	// Mode is how clients get diagnostics, one of DiagnosticModes: pushed
+	// with publishDiagnostics, pulled with textDocument/diagnostic and
+	// workspace/diagnostic, or both. Empty pushes.
+	Mode string `json:"mode"`
 }
 
 // MockDataConfig configures mock data generation
@@ -90,6 +256,418 @@ type MockDataConfig struct {
 	UseRealistic   bool     `json:"use_realistic"`
 	CustomPrefixes []string `json:"custom_prefixes" validate:"max=50"`
 	Languages      []string `json:"languages" validate:"dive,min=2,max=10"`
+	Randomize      bool     `json:"randomize"`  // Generate randomized, spec-valid responses from Seed
+	Unicode        []string `json:"unicode"`    // Unicode torture categories mixed into labels and messages
+	Deprecated     bool     `json:"deprecated"` // Mark the mock method and function deprecated in symbols, completion and diagnostics
+	// WorkspaceSymbols is the number of symbols workspace/symbol searches,
+	// split between SymbolKinds by percentage
+	WorkspaceSymbols int            `json:"workspace_symbols"`
+	SymbolKinds      map[string]int `json:"symbol_kinds"` // Percentage of the workspace symbols per SymbolKinds name
+}
+
+// TraceMetadataConfig configures the trace IDs injected into the open-ended
+// data and experimental sections of responses, so mock traffic can be
+// correlated with traces recorded by other systems
+type TraceMetadataConfig struct {
+	Enabled bool   `json:"enabled"`
+	Field   string `json:"field"`    // Key the trace metadata is stored under
+	TraceID string `json:"trace_id"` // W3C trace ID; empty generates one per server
+}
+
+// RedactionConfig configures how document contents are hidden in the
+// recorded traffic and logs, so proprietary source does not leak into CI
+// artifacts
+type RedactionConfig struct {
+	Mode      string   `json:"mode"`      // One of RedactionModes; empty keeps content verbatim
+	Allowlist []string `json:"allowlist"` // JSON fields kept verbatim; empty uses the defaults
+}
+
+// NotificationQueueConfig configures the bounded queue diagnostics and
+// progress notifications are sent through, so a client reading slowly does
+// not block the handlers
+type NotificationQueueConfig struct {
+	Size     int    `json:"size" validate:"min=0,max=100000"` // Notifications waiting to be sent; 0 sends inline
+	Overflow string `json:"overflow"`                         // One of QueueOverflowPolicies; empty drops the oldest
+}
+
+// MemoryPressureConfig configures the degradation past a heap size: caches
+// are trimmed, a warning is logged to the client and large responses are
+// refused until the heap shrinks again
+type MemoryPressureConfig struct {
+	HeapLimitMB      int      `json:"heap_limit_mb"`      // Heap size that starts degradation; 0 turns monitoring off
+	MaxResponseBytes int      `json:"max_response_bytes"` // Larger responses are refused while degraded
+	Interval         Duration `json:"interval"`           // How often the heap is sampled
+}
+
+// IndexingConfig configures the simulated indexing of the workspace: the
+// workspace symbols become available in steps over the duration, reported
+// with work done progress
+type IndexingConfig struct {
+	Duration Duration `json:"duration"` // How long indexing takes; 0 has every symbol indexed at once
+	Steps    int      `json:"steps"`    // Progress steps over the duration; 0 uses 10
+}
+
+// PartialResultsConfig configures the partial results streamed with
+// $/progress to clients that send a partialResultToken with references,
+// documentSymbol and workspace/symbol requests
+type PartialResultsConfig struct {
+	ChunkSize int      `json:"chunk_size"` // Items per $/progress notification; 0 uses 1000
+	Delay     Duration `json:"delay"`      // Pause between chunks
+}
+
+// RequestIDsConfig configures request IDs in both directions: clients
+// reusing the ID of an earlier request are reported, and server-initiated
+// requests get IDs from the selected strategy, so replays see the same IDs
+type RequestIDsConfig struct {
+	Strategy         string `json:"strategy"`          // One of IDStrategies; empty numbers requests from 1
+	Prefix           string `json:"prefix"`            // Prefix of prefixed IDs; empty uses "mock-"
+	Seed             int64  `json:"seed"`              // Seed of random IDs; 0 picks one per session
+	RejectDuplicates bool   `json:"reject_duplicates"` // Answer requests reusing an ID with InvalidRequest
+}
+
+// LatencyConfig configures simulated response latency
+type LatencyConfig struct {
+	SLOs map[string]SLOConfig `json:"slos"`
+	// Budgets is the handling time per method past which the client is
+	// warned through window/logMessage
+	Budgets map[string]Duration `json:"budgets"`
+	// Delays is a fixed delay per method added before the request is
+	// handled, on top of the SLO latency, so tests can cancel slow requests
+	// deterministically
+	Delays map[string]Duration `json:"delays"`
+}
+
+// SLOConfig declares the latency and size objectives for a single method.
+// The latency injector samples delays so that roughly half of the responses
+// finish within P50 and 99% within P99.
+type SLOConfig struct {
+	P50              Duration `json:"p50"`
+	P99              Duration `json:"p99"`
+	MaxResponseBytes int      `json:"max_response_bytes" validate:"min=0"`
+}
+
+// Edge-case response presets that can be selected per method in LSPConfig.Presets
+const (
+	PresetEmptyEverything      = "empty-everything"
+	PresetNullsEverywhere      = "nulls-everywhere"
+	PresetSurrogatePairs       = "surrogate-pairs"
+	PresetAllOptionalFieldsSet = "all-optional-fields-set"
+	PresetExtremePositions     = "extreme-positions"
+)
+
+// ResponsePresets lists the names accepted in LSPConfig.Presets
+var ResponsePresets = []string{
+	PresetEmptyEverything,
+	PresetNullsEverywhere,
+	PresetSurrogatePairs,
+	PresetAllOptionalFieldsSet,
+	PresetExtremePositions,
+}
+
+// Failure presets selected with -failure-preset or LSPConfig.FailurePreset
+const (
+	FailurePresetFlakyNetwork     = "flaky-network"
+	FailurePresetOverloadedServer = "overloaded-server"
+	FailurePresetSlowStart        = "slow-start"
+	FailurePresetCrashy           = "crashy"
+)
+
+// FailurePresets lists the names accepted in LSPConfig.FailurePreset
+var FailurePresets = []string{
+	FailurePresetFlakyNetwork,
+	FailurePresetOverloadedServer,
+	FailurePresetSlowStart,
+	FailurePresetCrashy,
+}
+
+// Editor profiles selected with -client-profile
+const (
+	ClientProfileVSCode = "vscode"
+	ClientProfileNeovim = "neovim"
+	ClientProfileHelix  = "helix"
+	ClientProfileEmacs  = "emacs"
+)
+
+// ClientProfiles lists the names accepted by -client-profile
+var ClientProfiles = []string{
+	ClientProfileVSCode,
+	ClientProfileNeovim,
+	ClientProfileHelix,
+	ClientProfileEmacs,
+}
+
+// ApplyClientProfile adjusts c to the quirks of the named editor: what its
+// client supports without declaring it well, the features it lacks and the
+// characters it triggers completion and signature help on. Profiles apply
+// on top of the defaults, so a config file still overrides them.
+func (c *ServerConfig) ApplyClientProfile(name string) error {
+	lsp := &c.LSP
+	features := func(enabled map[string]bool) {
+		lsp.Features = maps.Clone(lsp.Features)
+		if lsp.Features == nil {
+			lsp.Features = make(map[string]bool)
+		}
+		maps.Copy(lsp.Features, enabled)
+	}
+	switch name {
+	case ClientProfileVSCode:
+		// Registers everything dynamically and resolves lazily
+		lsp.DynamicRegistration = true
+		lsp.CodeActionConfig.Resolve = true
+		lsp.InlayHintConfig.Resolve = true
+		lsp.CompletionConfig.TriggerCharacters = []string{".", ":", "(", "\"", "/"}
+		lsp.CompletionConfig.CommitCharacters = []string{".", "("}
+		lsp.SignatureHelpConfig.RetriggerCharacters = []string{",", ")"}
+	case ClientProfileNeovim:
+		// The built-in client expands no snippets without a plugin and
+		// registers few features dynamically
+		lsp.DynamicRegistration = false
+		lsp.CompletionConfig.IncludeSnippets = false
+		lsp.CompletionConfig.TriggerCharacters = []string{".", ":"}
+		lsp.CodeActionConfig.Resolve = true
+		lsp.SignatureHelpConfig.TriggerCharacters = []string{"(", ","}
+	case ClientProfileHelix:
+		// No semantic highlighting and no folding
+		lsp.DynamicRegistration = false
+		lsp.CompletionConfig.TriggerCharacters = []string{".", ":"}
+		lsp.CodeActionConfig.Resolve = true
+		lsp.InlayHintConfig.Resolve = false
+		lsp.SignatureHelpConfig.TriggerCharacters = []string{"(", ","}
+		features(map[string]bool{"semantic_tokens": false, "folding_range": false})
+	case ClientProfileEmacs:
+		// Eglot, the built-in client, has no semantic tokens and needs
+		// yasnippet for snippets
+		lsp.DynamicRegistration = false
+		lsp.CompletionConfig.IncludeSnippets = false
+		lsp.CompletionConfig.TriggerCharacters = []string{".", ":", ">"}
+		lsp.CodeActionConfig.Resolve = true
+		features(map[string]bool{"semantic_tokens": false})
+	default:
+		return fmt.Errorf("unknown client profile %q: must be one of %s", name, strings.Join(ClientProfiles, ", "))
+	}
+	return nil
+}
+
+// Locales with a message catalog. LocalePseudo is derived from English.
+const (
+	LocaleEnglish  = "en"
+	LocaleGerman   = "de"
+	LocaleJapanese = "ja"
+	LocalePseudo   = "qps-ploc"
+)
+
+// SupportedLocales lists the locales accepted in LSPConfig.Locale. Regional
+// variants such as "de-AT" are accepted for any of these languages.
+var SupportedLocales = []string{
+	LocaleEnglish,
+	LocaleGerman,
+	LocaleJapanese,
+	LocalePseudo,
+}
+
+// Redaction modes that can be set in RedactionConfig.Mode
+const (
+	RedactionNone  = "none"  // Keep content verbatim
+	RedactionHash  = "hash"  // Replace strings with a SHA-256 prefix and their length
+	RedactionElide = "elide" // Replace strings with their length only
+)
+
+// Strategies that can be set in RequestIDsConfig.Strategy
+const (
+	IDStrategySequential = "sequential" // Numbers counting up from 1
+	IDStrategyPrefixed   = "prefixed"   // Strings of the prefix and a number counting up from 1
+	IDStrategyRandom     = "random"     // Seeded random numbers
+)
+
+// IDStrategies lists the strategies accepted in RequestIDsConfig.Strategy
+var IDStrategies = []string{
+	IDStrategySequential,
+	IDStrategyPrefixed,
+	IDStrategyRandom,
+}
+
+// RedactionModes lists the modes accepted in RedactionConfig.Mode
+var RedactionModes = []string{
+	RedactionNone,
+	RedactionHash,
+	RedactionElide,
+}
+
+// What to do with a notification when the notification queue is full
+const (
+	QueueDropOldest = "drop_oldest" // Drop the notification waiting longest
+	QueueDropNewest = "drop_newest" // Drop the notification being queued
+	QueueBlock      = "block"       // Wait for the client to catch up
+)
+
+// How clients get diagnostics, selected with DiagnosticsConfig.Mode
+const (
+	DiagnosticModePush = "push" // publishDiagnostics after changes
+	DiagnosticModePull = "pull" // textDocument/diagnostic and workspace/diagnostic
+	DiagnosticModeBoth = "both" // Either way
+)
+
+// Outcomes of a server process, each with its own exit code
+const (
+	ExitAfterShutdown      = "after_shutdown"      // exit notification after a shutdown request
+	ExitWithoutShutdown    = "without_shutdown"    // exit notification without a shutdown request
+	ExitFatal              = "fatal"               // startup or runtime error the server cannot recover from
+	ExitExpectationFailure = "expectation_failure" // scenario expectations that were not met
+)
+
+// ExitOutcomes lists the outcomes accepted in ServerSettings.ExitCodes
+var ExitOutcomes = []string{
+	ExitAfterShutdown,
+	ExitWithoutShutdown,
+	ExitFatal,
+	ExitExpectationFailure,
+}
+
+// ExitCode returns the process exit code for outcome, falling back to the
+// default code when it is not configured
+func (s ServerSettings) ExitCode(outcome string) int {
+	if code, ok := s.ExitCodes[outcome]; ok {
+		return code
+	}
+	return DefaultConfig().Server.ExitCodes[outcome]
+}
+
+// FeatureNames lists the names accepted in LSPConfig.Features
+var FeatureNames = []string{
+	"completion",
+	"hover",
+	"definition",
+	"references",
+	"document_symbol",
+	"workspace_symbol",
+	"code_action",
+	"formatting",
+	"range_formatting",
+	"rename",
+	"signature_help",
+	"semantic_tokens",
+	"inlay_hint",
+	"folding_range",
+	"selection_range",
+	"linked_editing_range",
+	"inline_completion",
+	"file_operations",
+	"diagnostics",
+}
+
+// FeatureEnabled reports whether the named feature is enabled: it is not
+// switched off in Features, and for completion, hover and diagnostics, its
+// own section is enabled. Features missing from Features are enabled.
+func (c LSPConfig) FeatureEnabled(name string) bool {
+	if enabled, ok := c.Features[name]; ok && !enabled {
+		return false
+	}
+	switch name {
+	case "completion":
+		return c.CompletionConfig.Enabled
+	case "hover":
+		return c.HoverConfig.Enabled
+	case "diagnostics":
+		return c.DiagnosticsConfig.Enabled
+	case "code_action":
+		return c.CodeActionConfig.Enabled
+	}
+	return true
+}
+
+// CompletionItemKinds lists the names accepted in CompletionConfig.Kinds, in
+// the order of the LSP CompletionItemKind values, which start at 1
+var CompletionItemKinds = []string{
+	"text", "method", "function", "constructor", "field",
+	"variable", "class", "interface", "module", "property",
+	"unit", "value", "enum", "keyword", "snippet",
+	"color", "file", "reference", "folder", "enum_member",
+	"constant", "struct", "event", "operator", "type_parameter",
+}
+
+// SymbolKinds lists the names accepted in MockDataConfig.SymbolKinds, in the
+// order of the LSP SymbolKind values, which start at 1
+var SymbolKinds = []string{
+	"file", "module", "namespace", "package", "class",
+	"method", "property", "field", "constructor", "enum",
+	"interface", "function", "variable", "constant", "string",
+	"number", "boolean", "array", "object", "key",
+	"null", "enum_member", "struct", "event", "operator",
+	"type_parameter",
+}
+
+// CodeActionKinds lists the kinds accepted in CodeActionConfig.Kinds
+var CodeActionKinds = []string{
+	"quickfix",
+	"refactor.extract",
+	"refactor.inline",
+	"refactor.rewrite",
+	"source.organizeImports",
+	"source.fixAll",
+}
+
+// HoverMarkdownElements lists the elements accepted in HoverConfig.Markdown
+var HoverMarkdownElements = []string{"code_block", "table", "link", "image"}
+
+// InlayHintKinds lists the kinds accepted in InlayHintConfig.Kinds
+var InlayHintKinds = []string{"type", "parameter"}
+
+// Completion sortText orderings
+const (
+	// SortTextReverse orders items in reverse alphabetical order of labels
+	SortTextReverse = "reverse_alphabetical"
+	// SortTextNumeric prefixes labels with unpadded positions, so item 10
+	// sorts before item 2 unless clients compare sortText as strings
+	SortTextNumeric = "numeric_prefix"
+)
+
+// CompletionSortTexts lists the orderings accepted in
+// CompletionConfig.SortText
+var CompletionSortTexts = []string{
+	SortTextReverse,
+	SortTextNumeric,
+}
+
+// QueueOverflowPolicies lists the policies accepted in
+// NotificationQueueConfig.Overflow
+var QueueOverflowPolicies = []string{
+	QueueDropOldest,
+	QueueDropNewest,
+	QueueBlock,
+}
+
+// DiagnosticModes lists the modes accepted in DiagnosticsConfig.Mode
+var DiagnosticModes = []string{
+	DiagnosticModePush,
+	DiagnosticModePull,
+	DiagnosticModeBoth,
+}
+
+// DefaultRedactionAllowlist lists the JSON fields kept verbatim when
+// RedactionConfig.Allowlist is empty: identifiers that reveal no source
+var DefaultRedactionAllowlist = []string{
+	"uri",
+	"rootUri",
+	"languageId",
+	"method",
+	"locale",
+	"triggerCharacter",
+}
+
+// Unicode torture categories that can be listed in MockDataConfig.Unicode
+const (
+	UnicodeCombining = "combining"
+	UnicodeRTL       = "rtl"
+	UnicodeEmoji     = "emoji"
+	UnicodeAstral    = "astral"
+)
+
+// UnicodeCategories lists the names accepted in MockDataConfig.Unicode
+var UnicodeCategories = []string{
+	UnicodeCombining,
+	UnicodeRTL,
+	UnicodeEmoji,
+	UnicodeAstral,
 }
 
 // ValidationError represents a configuration validation error
@@ -162,6 +740,12 @@ func DefaultConfig() *ServerConfig {
 			Description: "A mock LSP server for testing and development",
 			Timeout:     Duration(30 * time.Second),
 			MaxRequests: 1000,
+			ExitCodes: map[string]int{
+				ExitAfterShutdown:      0,
+				ExitWithoutShutdown:    1,
+				ExitFatal:              2,
+				ExitExpectationFailure: 3,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
@@ -174,7 +758,12 @@ func DefaultConfig() *ServerConfig {
 			Format:     "text",
 		},
 		LSP: LSPConfig{
-			InitializeTimeout: Duration(10 * time.Second),
+			InitializeTimeout:    Duration(10 * time.Second),
+			ShutdownDrainTimeout: Duration(DefaultShutdownDrainTimeout),
+			MemoryPressure: MemoryPressureConfig{
+				MaxResponseBytes: 64 * 1024,
+				Interval:         Duration(time.Second),
+			},
 			CompletionConfig: CompletionConfig{
 				Enabled:           true,
 				MaxItems:          100,
@@ -189,6 +778,26 @@ func DefaultConfig() *ServerConfig {
 				ShowExample: false,
 				MaxLength:   1000,
 			},
+			CodeActionConfig: CodeActionConfig{
+				Enabled:      true,
+				Kinds:        []string{"quickfix", "refactor.extract", "refactor.rewrite", "source.organizeImports"},
+				QuickFixes:   2,
+				Refactorings: 1,
+			},
+			RenameConfig: RenameConfig{
+				Files:        10,
+				EditsPerFile: 50,
+			},
+			SignatureHelpConfig: SignatureHelpConfig{
+				TriggerCharacters:   []string{"("},
+				RetriggerCharacters: []string{","},
+				Signatures:          3,
+			},
+			InlayHintConfig: InlayHintConfig{
+				LineInterval: 1,
+				WordInterval: 3,
+				Kinds:        []string{"type", "parameter"},
+			},
 			DiagnosticsConfig: DiagnosticsConfig{
 				Enabled:      true,
 				MaxIssues:    50,
@@ -198,23 +807,50 @@ func DefaultConfig() *ServerConfig {
 				MockErrors:   false,
 			},
 			MockData: MockDataConfig{
-				Enabled:        true,
-				Seed:           0, // Use random seed if 0
-				ItemCount:      50,
-				UseRealistic:   true,
-				CustomPrefixes: []string{"mock", "test", "example"},
-				Languages:      []string{"go", "typescript", "python"},
+				Enabled:          true,
+				Seed:             0, // Use random seed if 0
+				ItemCount:        50,
+				UseRealistic:     true,
+				CustomPrefixes:   []string{"mock", "test", "example"},
+				Languages:        []string{"go", "typescript", "python"},
+				WorkspaceSymbols: 50,
+				SymbolKinds: map[string]int{
+					"class":     20,
+					"interface": 10,
+					"function":  30,
+					"method":    20,
+					"variable":  10,
+					"constant":  10,
+				},
 			},
 			Features: map[string]bool{
-				"completion":      true,
-				"hover":           true,
-				"definition":      true,
-				"references":      true,
-				"document_symbol": true,
-				"diagnostics":     true,
+				"completion":           true,
+				"hover":                true,
+				"definition":           true,
+				"references":           true,
+				"document_symbol":      true,
+				"workspace_symbol":     true,
+				"code_action":          true,
+				"formatting":           true,
+				"range_formatting":     true,
+				"rename":               true,
+				"signature_help":       true,
+				"semantic_tokens":      true,
+				"inlay_hint":           true,
+				"folding_range":        true,
+				"selection_range":      true,
+				"linked_editing_range": true,
+				"inline_completion":    true,
+				"file_operations":      true,
+				"diagnostics":          true,
 			},
 			TriggerCharacters: []string{".", ":", "(", "[", "{"},
 			Extensions:        []string{".go", ".ts", ".js", ".py"},
+			TraceMetadata: TraceMetadataConfig{
+				Enabled: false,
+				Field:   "mockLspTrace",
+			},
+			RecentTraffic: 200,
 		},
 	}
 }
@@ -229,17 +865,36 @@ func LoadFromFile(path string) (*ServerConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config ServerConfig
-	if err := json.Unmarshal(data, &config); err != nil {
+	config, err := Parse(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	return config, nil
+}
+
+// Parse decodes a configuration in the format of the config file
+func Parse(data []byte) (*ServerConfig, error) {
+	var config ServerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
 
 // LoadFromFileWithDefaults loads config from file, falling back to defaults for missing fields
 func LoadFromFileWithDefaults(path string) (*ServerConfig, error) {
-	defaultConfig := DefaultConfig()
+	return LoadFromFileWithProfile(path, "")
+}
+
+// LoadFromFileWithProfile loads config from file like
+// LoadFromFileWithDefaults, with the defaults adjusted to the named client
+// profile first. An empty profile keeps the defaults.
+func LoadFromFileWithProfile(path, profile string) (*ServerConfig, error) {
+	defaultConfig, err := profileDefaults(profile)
+	if err != nil {
+		return nil, err
+	}
 
 	if path == "" {
 		return defaultConfig, nil
@@ -259,6 +914,33 @@ func LoadFromFileWithDefaults(path string) (*ServerConfig, error) {
 	return mergedConfig, nil
 }
 
+// ParseWithProfile is LoadFromFileWithProfile for the contents of a config
+// file that was read elsewhere, such as from stdin or a command line flag
+func ParseWithProfile(data []byte, profile string) (*ServerConfig, error) {
+	defaultConfig, err := profileDefaults(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return mergeConfigs(defaultConfig, parsed), nil
+}
+
+// profileDefaults returns the default configuration adjusted to the named
+// client profile; an empty profile keeps the defaults
+func profileDefaults(profile string) (*ServerConfig, error) {
+	defaultConfig := DefaultConfig()
+	if profile != "" {
+		if err := defaultConfig.ApplyClientProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	return defaultConfig, nil
+}
+
 // SaveToFile saves configuration to a JSON file
 func (c *ServerConfig) SaveToFile(path string) error {
 	// Ensure directory exists
@@ -279,6 +961,58 @@ func (c *ServerConfig) SaveToFile(path string) error {
 	return nil
 }
 
+// ApplyLSPOptions returns a copy of the configuration with the JSON object
+// options merged into its LSP section, as sent by clients in
+// initializationOptions. Keys are those of the "lsp" section of the config
+// file; nested objects are merged and lists are replaced. It also returns
+// the top-level keys that were not recognized. The configuration is left
+// unchanged if options is not an object or the result does not validate.
+func (c *ServerConfig) ApplyLSPOptions(options []byte) (*ServerConfig, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(options, &fields); err != nil {
+		return nil, nil, fmt.Errorf("initialization options must be a JSON object: %w", err)
+	}
+
+	// Round trip through JSON so the maps and lists of c are not shared
+	current, err := json.Marshal(c.LSP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal LSP config: %w", err)
+	}
+	var known map[string]json.RawMessage
+	if err := json.Unmarshal(current, &known); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse LSP config: %w", err)
+	}
+
+	result := *c
+	result.LSP = LSPConfig{}
+	if err := json.Unmarshal(current, &result.LSP); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy LSP config: %w", err)
+	}
+
+	recognized := make(map[string]json.RawMessage, len(fields))
+	var ignored []string
+	for key, value := range fields {
+		if _, ok := known[key]; ok {
+			recognized[key] = value
+		} else {
+			ignored = append(ignored, key)
+		}
+	}
+	slices.Sort(ignored)
+
+	data, err := json.Marshal(recognized)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal initialization options: %w", err)
+	}
+	if err := json.Unmarshal(data, &result.LSP); err != nil {
+		return nil, nil, fmt.Errorf("invalid initialization options: %w", err)
+	}
+	if err := result.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &result, ignored, nil
+}
+
 // Validate validates the configuration
 func (c *ServerConfig) Validate() error {
 	var errors ValidationErrors
@@ -468,6 +1202,24 @@ func (c *ServerConfig) validateServer() error {
 		})
 	}
 
+	for _, outcome := range slices.Sorted(maps.Keys(c.Server.ExitCodes)) {
+		code := c.Server.ExitCodes[outcome]
+		if !slices.Contains(ExitOutcomes, outcome) {
+			errors = append(errors, ValidationError{
+				Field:   "server.exit_codes",
+				Value:   outcome,
+				Message: fmt.Sprintf("exit outcome must be one of: %s", strings.Join(ExitOutcomes, ", ")),
+			})
+		} else if code < 0 || code > 125 {
+			// Shells reserve 126 and above for exec failures and signals
+			errors = append(errors, ValidationError{
+				Field:   "server.exit_codes." + outcome,
+				Value:   fmt.Sprintf("%d", code),
+				Message: "exit code must be between 0 and 125",
+			})
+		}
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
@@ -510,7 +1262,7 @@ func (c *ServerConfig) validateLogging() error {
 				Message: "file name must be less than 255 characters",
 			})
 		}
-		
+
 		// Check for invalid file name characters
 		invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
 		for _, char := range invalidChars {
@@ -579,6 +1331,16 @@ func (c *ServerConfig) validateLogging() error {
 		})
 	}
 
+	for i, method := range c.Logging.PayloadMethods {
+		if strings.TrimSpace(method) == "" {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("logging.payload_methods[%d]", i),
+				Value:   method,
+				Message: "payload method must not be empty",
+			})
+		}
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
@@ -604,6 +1366,14 @@ func (c *ServerConfig) validateLSP() error {
 		})
 	}
 
+	if drain := c.LSP.ShutdownDrainTimeout.Duration(); drain <= 0 || drain > time.Minute {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.shutdown_drain_timeout",
+			Value:   c.LSP.ShutdownDrainTimeout.String(),
+			Message: "shutdown_drain_timeout must be above 0 and at most 1 minute",
+		})
+	}
+
 	// Validate completion config
 	if err := c.validateCompletionConfig(); err != nil {
 		if ve, ok := err.(ValidationErrors); ok {
@@ -618,85 +1388,670 @@ func (c *ServerConfig) validateLSP() error {
 		}
 	}
 
-	// Validate diagnostics config
-	if err := c.validateDiagnosticsConfig(); err != nil {
+	// Validate code action config
+	if err := c.validateCodeActionConfig(); err != nil {
 		if ve, ok := err.(ValidationErrors); ok {
 			errors = append(errors, ve...)
 		}
 	}
 
-	// Validate mock data config
-	if err := c.validateMockDataConfig(); err != nil {
+	// Validate rename config
+	if err := c.validateRenameConfig(); err != nil {
 		if ve, ok := err.(ValidationErrors); ok {
 			errors = append(errors, ve...)
 		}
 	}
 
-	// Validate trigger characters
-	if len(c.LSP.TriggerCharacters) > 20 {
-		errors = append(errors, ValidationError{
-			Field:   "lsp.trigger_characters",
-			Value:   fmt.Sprintf("%v", c.LSP.TriggerCharacters),
-			Message: "trigger_characters list cannot exceed 20 items",
-		})
+	// Validate signature help config
+	if err := c.validateSignatureHelpConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
 	}
 
-	// Validate extensions
-	if len(c.LSP.Extensions) > 50 {
-		errors = append(errors, ValidationError{
-			Field:   "lsp.extensions",
-			Value:   fmt.Sprintf("%v", c.LSP.Extensions),
-			Message: "extensions list cannot exceed 50 items",
-		})
+	// Validate inlay hint config
+	if err := c.validateInlayHintConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
 	}
 
-	for i, ext := range c.LSP.Extensions {
-		if !strings.HasPrefix(ext, ".") {
-			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("lsp.extensions[%d]", i),
-				Value:   ext,
-				Message: "extension must start with a dot (e.g., '.go')",
-			})
-		}
-		if len(ext) > 10 {
-			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("lsp.extensions[%d]", i),
-				Value:   ext,
-				Message: "extension must be less than 10 characters",
-			})
+	// Validate inline completion config
+	if err := c.validateInlineCompletionConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
 		}
 	}
 
-	if len(errors) > 0 {
-		return errors
+	// Validate file operations config
+	if err := c.validateFileOperationsConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
 	}
-	return nil
-}
-
-// validateCompletionConfig validates completion configuration
-func (c *ServerConfig) validateCompletionConfig() error {
-	var errors ValidationErrors
 
-	if c.LSP.CompletionConfig.MaxItems < 1 {
-		errors = append(errors, ValidationError{
-			Field:   "lsp.completion.max_items",
-			Value:   fmt.Sprintf("%d", c.LSP.CompletionConfig.MaxItems),
-			Message: "completion max_items must be at least 1",
-		})
-	} else if c.LSP.CompletionConfig.MaxItems > 10000 {
-		errors = append(errors, ValidationError{
-			Field:   "lsp.completion.max_items",
-			Value:   fmt.Sprintf("%d", c.LSP.CompletionConfig.MaxItems),
-			Message: "completion max_items must be less than 10,000",
-		})
+	// Validate execute command config
+	if err := c.validateExecuteCommandConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
 	}
 
-	if len(c.LSP.CompletionConfig.TriggerCharacters) > 10 {
-		errors = append(errors, ValidationError{
-			Field:   "lsp.completion.trigger_characters",
-			Value:   fmt.Sprintf("%v", c.LSP.CompletionConfig.TriggerCharacters),
-			Message: "completion trigger_characters list cannot exceed 10 items",
-		})
+	// Validate diagnostics config
+	if err := c.validateDiagnosticsConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate mock data config
+	if err := c.validateMockDataConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate latency config
+	if err := c.validateLatencyConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate response presets
+	if err := c.validatePresetsConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate trace metadata config
+	if err := c.validateTraceMetadataConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate redaction config
+	if err := c.validateRedactionConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate notification queue config
+	if err := c.validateNotificationQueueConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate memory pressure config
+	if err := c.validateMemoryPressureConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	// Validate indexing config
+	if err := c.validateIndexingConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	if err := c.validatePartialResultsConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	if err := c.validateRequestIDsConfig(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.LSP.Features)) {
+		if !slices.Contains(FeatureNames, name) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.features[%s]", name),
+				Value:   name,
+				Message: fmt.Sprintf("feature must be one of: %s", strings.Join(FeatureNames, ", ")),
+			})
+		}
+	}
+
+	// Validate trigger characters
+	if len(c.LSP.TriggerCharacters) > 20 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.trigger_characters",
+			Value:   fmt.Sprintf("%v", c.LSP.TriggerCharacters),
+			Message: "trigger_characters list cannot exceed 20 items",
+		})
+	}
+
+	// Validate extensions
+	if len(c.LSP.Extensions) > 50 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.extensions",
+			Value:   fmt.Sprintf("%v", c.LSP.Extensions),
+			Message: "extensions list cannot exceed 50 items",
+		})
+	}
+
+	for i, ext := range c.LSP.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.extensions[%d]", i),
+				Value:   ext,
+				Message: "extension must start with a dot (e.g., '.go')",
+			})
+		}
+		if len(ext) > 10 {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.extensions[%d]", i),
+				Value:   ext,
+				Message: "extension must be less than 10 characters",
+			})
+		}
+	}
+
+	// Validate recent traffic buffer size
+	if c.LSP.RecentTraffic < 0 || c.LSP.RecentTraffic > 10000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.recent_traffic",
+			Value:   fmt.Sprintf("%d", c.LSP.RecentTraffic),
+			Message: "recent_traffic must be between 0 and 10000",
+		})
+	}
+
+	// Validate locale
+	if locale := strings.ToLower(strings.ReplaceAll(c.LSP.Locale, "_", "-")); locale != "" {
+		language, _, _ := strings.Cut(locale, "-")
+		if !slices.Contains(SupportedLocales, locale) && !slices.Contains(SupportedLocales, language) {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.locale",
+				Value:   c.LSP.Locale,
+				Message: fmt.Sprintf("locale must be one of: %s", strings.Join(SupportedLocales, ", ")),
+			})
+		}
+	}
+
+	if preset := c.LSP.FailurePreset; preset != "" && !slices.Contains(FailurePresets, preset) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.failure_preset",
+			Value:   preset,
+			Message: fmt.Sprintf("failure preset must be one of: %s", strings.Join(FailurePresets, ", ")),
+		})
+	}
+
+	// Validate allowed URI schemes
+	for i, scheme := range c.LSP.AllowedSchemes {
+		if !uriSchemePattern.MatchString(scheme) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.allowed_schemes[%d]", i),
+				Value:   scheme,
+				Message: "scheme must start with a letter and contain only letters, digits, '+', '-' and '.'",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateTraceMetadataConfig validates trace metadata injection configuration
+func (c *ServerConfig) validateTraceMetadataConfig() error {
+	var errors ValidationErrors
+	trace := c.LSP.TraceMetadata
+
+	if trace.Field != "" && !alphanumericHyphenUnderscore.MatchString(trace.Field) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.trace_metadata.field",
+			Value:   trace.Field,
+			Message: "field can only contain alphanumeric characters, hyphens, and underscores",
+		})
+	}
+
+	if trace.TraceID != "" && (!traceIDPattern.MatchString(trace.TraceID) || strings.Trim(trace.TraceID, "0") == "") {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.trace_metadata.trace_id",
+			Value:   trace.TraceID,
+			Message: "trace_id must be 32 lowercase hex characters and not all zeros",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateRedactionConfig validates content redaction configuration
+func (c *ServerConfig) validateRedactionConfig() error {
+	var errors ValidationErrors
+	redaction := c.LSP.Redaction
+
+	if redaction.Mode != "" && !slices.Contains(RedactionModes, redaction.Mode) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.redaction.mode",
+			Value:   redaction.Mode,
+			Message: fmt.Sprintf("mode must be one of: %s", strings.Join(RedactionModes, ", ")),
+		})
+	}
+
+	for i, field := range redaction.Allowlist {
+		if field == "" {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.redaction.allowlist[%d]", i),
+				Value:   field,
+				Message: "allowlist entries cannot be empty",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateNotificationQueueConfig validates the outbound notification queue
+// configuration
+func (c *ServerConfig) validateNotificationQueueConfig() error {
+	var errors ValidationErrors
+	queue := c.LSP.NotificationQueue
+
+	if queue.Size < 0 || queue.Size > 100000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.notification_queue.size",
+			Value:   fmt.Sprintf("%d", queue.Size),
+			Message: "size must be between 0 and 100000",
+		})
+	}
+
+	if queue.Overflow != "" && !slices.Contains(QueueOverflowPolicies, queue.Overflow) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.notification_queue.overflow",
+			Value:   queue.Overflow,
+			Message: fmt.Sprintf("overflow must be one of: %s", strings.Join(QueueOverflowPolicies, ", ")),
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateMemoryPressureConfig validates the memory pressure configuration.
+// The sampling interval only matters when monitoring is on.
+func (c *ServerConfig) validateMemoryPressureConfig() error {
+	var errors ValidationErrors
+	pressure := c.LSP.MemoryPressure
+
+	if pressure.HeapLimitMB < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.memory_pressure.heap_limit_mb",
+			Value:   fmt.Sprintf("%d", pressure.HeapLimitMB),
+			Message: "heap_limit_mb must not be negative",
+		})
+	}
+	if pressure.MaxResponseBytes < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.memory_pressure.max_response_bytes",
+			Value:   fmt.Sprintf("%d", pressure.MaxResponseBytes),
+			Message: "max_response_bytes must not be negative",
+		})
+	}
+	if interval := pressure.Interval.Duration(); pressure.HeapLimitMB > 0 && (interval < 10*time.Millisecond || interval > time.Minute) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.memory_pressure.interval",
+			Value:   pressure.Interval.String(),
+			Message: "interval must be between 10ms and 1 minute",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateIndexingConfig validates the simulated indexing
+func (c *ServerConfig) validateIndexingConfig() error {
+	var errors ValidationErrors
+	indexing := c.LSP.Indexing
+
+	if duration := indexing.Duration.Duration(); duration < 0 || duration > 10*time.Minute {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.indexing.duration",
+			Value:   indexing.Duration.String(),
+			Message: "duration must be between 0 and 10 minutes",
+		})
+	}
+	if indexing.Steps < 0 || indexing.Steps > 1000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.indexing.steps",
+			Value:   fmt.Sprintf("%d", indexing.Steps),
+			Message: "steps must be between 0 and 1000",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validatePartialResultsConfig validates the streaming of partial results
+func (c *ServerConfig) validatePartialResultsConfig() error {
+	var errors ValidationErrors
+	partial := c.LSP.PartialResults
+
+	if partial.ChunkSize < 0 || partial.ChunkSize > 100000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.partial_results.chunk_size",
+			Value:   fmt.Sprintf("%d", partial.ChunkSize),
+			Message: "chunk_size must be between 0 and 100,000",
+		})
+	}
+	if delay := partial.Delay.Duration(); delay < 0 || delay > time.Minute {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.partial_results.delay",
+			Value:   partial.Delay.String(),
+			Message: "delay must be between 0 and 1 minute",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateRequestIDsConfig validates the request ID strategy
+func (c *ServerConfig) validateRequestIDsConfig() error {
+	var errors ValidationErrors
+	ids := c.LSP.RequestIDs
+
+	if ids.Strategy != "" && !slices.Contains(IDStrategies, ids.Strategy) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.request_ids.strategy",
+			Value:   ids.Strategy,
+			Message: fmt.Sprintf("request ID strategy must be one of: %s", strings.Join(IDStrategies, ", ")),
+		})
+	}
+	if len(ids.Prefix) > 64 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.request_ids.prefix",
+			Value:   ids.Prefix,
+			Message: "prefix cannot exceed 64 characters",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateCompletionConfig validates completion configuration
+func (c *ServerConfig) validateCompletionConfig() error {
+	var errors ValidationErrors
+
+	if c.LSP.CompletionConfig.MaxItems < 1 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.max_items",
+			Value:   fmt.Sprintf("%d", c.LSP.CompletionConfig.MaxItems),
+			Message: "completion max_items must be at least 1",
+		})
+	} else if c.LSP.CompletionConfig.MaxItems > 10000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.max_items",
+			Value:   fmt.Sprintf("%d", c.LSP.CompletionConfig.MaxItems),
+			Message: "completion max_items must be less than 10,000",
+		})
+	}
+
+	if len(c.LSP.CompletionConfig.TriggerCharacters) > 10 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.trigger_characters",
+			Value:   fmt.Sprintf("%v", c.LSP.CompletionConfig.TriggerCharacters),
+			Message: "completion trigger_characters list cannot exceed 10 items",
+		})
+	}
+
+	total := 0
+	for _, name := range slices.Sorted(maps.Keys(c.LSP.CompletionConfig.Kinds)) {
+		percent := c.LSP.CompletionConfig.Kinds[name]
+		if !slices.Contains(CompletionItemKinds, name) {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.completion.kinds",
+				Value:   name,
+				Message: fmt.Sprintf("completion kind must be one of: %s", strings.Join(CompletionItemKinds, ", ")),
+			})
+		}
+		if percent < 0 || percent > 100 {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.completion.kinds." + name,
+				Value:   fmt.Sprintf("%d", percent),
+				Message: "completion kind percentage must be between 0 and 100",
+			})
+		}
+		total += percent
+	}
+	if len(c.LSP.CompletionConfig.Kinds) > 0 && total != 100 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.kinds",
+			Value:   fmt.Sprintf("%d", total),
+			Message: "completion kind percentages must add up to 100",
+		})
+	}
+
+	if sortText := c.LSP.CompletionConfig.SortText; sortText != "" && !slices.Contains(CompletionSortTexts, sortText) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.sort_text",
+			Value:   sortText,
+			Message: fmt.Sprintf("completion sort_text must be one of: %s", strings.Join(CompletionSortTexts, ", ")),
+		})
+	}
+
+	if len(c.LSP.CompletionConfig.CommitCharacters) > 10 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.completion.commit_characters",
+			Value:   fmt.Sprintf("%v", c.LSP.CompletionConfig.CommitCharacters),
+			Message: "completion commit_characters list cannot exceed 10 items",
+		})
+	}
+	for _, char := range c.LSP.CompletionConfig.CommitCharacters {
+		if utf8.RuneCountInString(char) != 1 {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.completion.commit_characters",
+				Value:   char,
+				Message: "completion commit characters must be single characters",
+			})
+		}
+	}
+
+	flags := []struct {
+		name    string
+		percent int
+	}{
+		{"deprecated", c.LSP.CompletionConfig.Deprecated},
+		{"preselect", c.LSP.CompletionConfig.Preselect},
+	}
+	for _, flag := range flags {
+		if flag.percent < 0 || flag.percent > 100 {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.completion." + flag.name,
+				Value:   fmt.Sprintf("%d", flag.percent),
+				Message: fmt.Sprintf("completion %s percentage must be between 0 and 100", flag.name),
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateHoverConfig validates hover configuration
+func (c *ServerConfig) validateHoverConfig() error {
+	var errors ValidationErrors
+
+	if c.LSP.HoverConfig.MaxLength < 100 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.hover.max_length",
+			Value:   fmt.Sprintf("%d", c.LSP.HoverConfig.MaxLength),
+			Message: "hover max_length must be at least 100",
+		})
+	} else if c.LSP.HoverConfig.MaxLength > 100000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.hover.max_length",
+			Value:   fmt.Sprintf("%d", c.LSP.HoverConfig.MaxLength),
+			Message: "hover max_length must be less than 100,000",
+		})
+	}
+	for i, element := range c.LSP.HoverConfig.Markdown {
+		if !slices.Contains(HoverMarkdownElements, element) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.hover.markdown[%d]", i),
+				Value:   element,
+				Message: fmt.Sprintf("hover markdown element must be one of: %s", strings.Join(HoverMarkdownElements, ", ")),
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateCodeActionConfig validates code action configuration
+func (c *ServerConfig) validateCodeActionConfig() error {
+	var errors ValidationErrors
+
+	for i, kind := range c.LSP.CodeActionConfig.Kinds {
+		if !slices.Contains(CodeActionKinds, kind) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.code_action.kinds[%d]", i),
+				Value:   kind,
+				Message: fmt.Sprintf("code action kind must be one of: %s", strings.Join(CodeActionKinds, ", ")),
+			})
+		}
+	}
+	if c.LSP.CodeActionConfig.QuickFixes < 0 || c.LSP.CodeActionConfig.QuickFixes > 20 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.code_action.quick_fixes",
+			Value:   fmt.Sprintf("%d", c.LSP.CodeActionConfig.QuickFixes),
+			Message: "quick_fixes must be between 0 and 20",
+		})
+	}
+	if c.LSP.CodeActionConfig.Refactorings < 0 || c.LSP.CodeActionConfig.Refactorings > 20 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.code_action.refactorings",
+			Value:   fmt.Sprintf("%d", c.LSP.CodeActionConfig.Refactorings),
+			Message: "refactorings must be between 0 and 20",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateRenameConfig validates rename configuration
+func (c *ServerConfig) validateRenameConfig() error {
+	var errors ValidationErrors
+
+	if c.LSP.RenameConfig.Files < 1 || c.LSP.RenameConfig.Files > 1000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.rename.files",
+			Value:   fmt.Sprintf("%d", c.LSP.RenameConfig.Files),
+			Message: "files must be between 1 and 1000",
+		})
+	}
+	if c.LSP.RenameConfig.EditsPerFile < 1 || c.LSP.RenameConfig.EditsPerFile > 10000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.rename.edits_per_file",
+			Value:   fmt.Sprintf("%d", c.LSP.RenameConfig.EditsPerFile),
+			Message: "edits_per_file must be between 1 and 10000",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateExecuteCommandConfig validates the mock commands
+func (c *ServerConfig) validateExecuteCommandConfig() error {
+	var errors ValidationErrors
+
+	for _, id := range slices.Sorted(maps.Keys(c.LSP.ExecuteCommand.Commands)) {
+		if strings.TrimSpace(id) == "" {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.execute_command.commands",
+				Value:   fmt.Sprintf("%q", id),
+				Message: "command IDs must not be blank",
+			})
+		}
+		if result := c.LSP.ExecuteCommand.Commands[id].Result; result != nil && !json.Valid(result) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.execute_command.commands[%s].result", id),
+				Value:   string(result),
+				Message: "result must be valid JSON",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateInlayHintConfig validates inlay hint configuration
+func (c *ServerConfig) validateInlayHintConfig() error {
+	var errors ValidationErrors
+
+	if c.LSP.InlayHintConfig.LineInterval < 1 || c.LSP.InlayHintConfig.LineInterval > 1000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.inlay_hint.line_interval",
+			Value:   fmt.Sprintf("%d", c.LSP.InlayHintConfig.LineInterval),
+			Message: "line_interval must be between 1 and 1000",
+		})
+	}
+	if c.LSP.InlayHintConfig.WordInterval < 1 || c.LSP.InlayHintConfig.WordInterval > 100 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.inlay_hint.word_interval",
+			Value:   fmt.Sprintf("%d", c.LSP.InlayHintConfig.WordInterval),
+			Message: "word_interval must be between 1 and 100",
+		})
+	}
+	if len(c.LSP.InlayHintConfig.Kinds) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.inlay_hint.kinds",
+			Value:   "[]",
+			Message: "inlay hint kinds must not be empty",
+		})
+	}
+	for i, kind := range c.LSP.InlayHintConfig.Kinds {
+		if !slices.Contains(InlayHintKinds, kind) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.inlay_hint.kinds[%d]", i),
+				Value:   kind,
+				Message: fmt.Sprintf("inlay hint kind must be one of: %s", strings.Join(InlayHintKinds, ", ")),
+			})
+		}
 	}
 
 	if len(errors) > 0 {
@@ -705,21 +2060,83 @@ func (c *ServerConfig) validateCompletionConfig() error {
 	return nil
 }
 
-// validateHoverConfig validates hover configuration
-func (c *ServerConfig) validateHoverConfig() error {
+// validateInlineCompletionConfig validates inline completion configuration
+func (c *ServerConfig) validateInlineCompletionConfig() error {
 	var errors ValidationErrors
+	inline := c.LSP.InlineCompletion
 
-	if c.LSP.HoverConfig.MaxLength < 100 {
+	if inline.Suggestions < 0 || inline.Suggestions > 50 {
 		errors = append(errors, ValidationError{
-			Field:   "lsp.hover.max_length",
-			Value:   fmt.Sprintf("%d", c.LSP.HoverConfig.MaxLength),
-			Message: "hover max_length must be at least 100",
+			Field:   "lsp.inline_completion.suggestions",
+			Value:   fmt.Sprintf("%d", inline.Suggestions),
+			Message: "suggestions must be between 0 and 50",
 		})
-	} else if c.LSP.HoverConfig.MaxLength > 100000 {
+	}
+	if inline.Lines < 0 || inline.Lines > 100 {
 		errors = append(errors, ValidationError{
-			Field:   "lsp.hover.max_length",
-			Value:   fmt.Sprintf("%d", c.LSP.HoverConfig.MaxLength),
-			Message: "hover max_length must be less than 100,000",
+			Field:   "lsp.inline_completion.lines",
+			Value:   fmt.Sprintf("%d", inline.Lines),
+			Message: "lines must be between 0 and 100",
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateFileOperationsConfig validates file operations configuration
+func (c *ServerConfig) validateFileOperationsConfig() error {
+	var errors ValidationErrors
+	filters := c.LSP.FileOperations.Filters
+
+	if len(filters) > 50 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.file_operations.filters",
+			Value:   fmt.Sprintf("%d filters", len(filters)),
+			Message: "at most 50 filters are allowed",
+		})
+	}
+	for i, glob := range filters {
+		if strings.TrimSpace(glob) == "" {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.file_operations.filters[%d]", i),
+				Value:   glob,
+				Message: "filter glob cannot be empty",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateSignatureHelpConfig validates signature help configuration
+func (c *ServerConfig) validateSignatureHelpConfig() error {
+	var errors ValidationErrors
+
+	if len(c.LSP.SignatureHelpConfig.TriggerCharacters) > 10 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.signature_help.trigger_characters",
+			Value:   fmt.Sprintf("%v", c.LSP.SignatureHelpConfig.TriggerCharacters),
+			Message: "signature help trigger_characters list cannot exceed 10 items",
+		})
+	}
+	if len(c.LSP.SignatureHelpConfig.RetriggerCharacters) > 10 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.signature_help.retrigger_characters",
+			Value:   fmt.Sprintf("%v", c.LSP.SignatureHelpConfig.RetriggerCharacters),
+			Message: "signature help retrigger_characters list cannot exceed 10 items",
+		})
+	}
+	if c.LSP.SignatureHelpConfig.Signatures < 1 || c.LSP.SignatureHelpConfig.Signatures > 5 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.signature_help.signatures",
+			Value:   fmt.Sprintf("%d", c.LSP.SignatureHelpConfig.Signatures),
+			Message: "signatures must be between 1 and 5",
 		})
 	}
 
@@ -761,6 +2178,38 @@ func (c *ServerConfig) validateDiagnosticsConfig() error {
 		})
 	}
 
+	if c.LSP.DiagnosticsConfig.Duplicates < 0 || c.LSP.DiagnosticsConfig.Duplicates > 10 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.diagnostics.duplicates",
+			Value:   fmt.Sprintf("%d", c.LSP.DiagnosticsConfig.Duplicates),
+			Message: "duplicates must be between 0 and 10",
+		})
+	}
+
+	if len(c.LSP.DiagnosticsConfig.UnopenedURIs) > 100 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.diagnostics.unopened_uris",
+			Value:   fmt.Sprintf("%d URIs", len(c.LSP.DiagnosticsConfig.UnopenedURIs)),
+			Message: "unopened_uris list cannot exceed 100 items",
+		})
+	}
+	if mode := c.LSP.DiagnosticsConfig.Mode; mode != "" && !slices.Contains(DiagnosticModes, mode) {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.diagnostics.mode",
+			Value:   mode,
+			Message: fmt.Sprintf("diagnostics mode must be one of: %s", strings.Join(DiagnosticModes, ", ")),
+		})
+	}
+	for i, uri := range c.LSP.DiagnosticsConfig.UnopenedURIs {
+		if parsed, err := url.Parse(uri); err != nil || parsed.Scheme == "" {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.diagnostics.unopened_uris[%d]", i),
+				Value:   uri,
+				Message: "URI must be absolute and include a scheme",
+			})
+		}
+	}
+
 	// Validate severities
 	validSeverities := []string{"error", "warning", "info", "hint"}
 	for i, severity := range c.LSP.DiagnosticsConfig.Severities {
@@ -806,6 +2255,16 @@ func (c *ServerConfig) validateMockDataConfig() error {
 		})
 	}
 
+	for i, category := range c.LSP.MockData.Unicode {
+		if !slices.Contains(UnicodeCategories, category) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("lsp.mock_data.unicode[%d]", i),
+				Value:   category,
+				Message: fmt.Sprintf("unicode category must be one of: %s", strings.Join(UnicodeCategories, ", ")),
+			})
+		}
+	}
+
 	// Validate custom prefixes
 	for i, prefix := range c.LSP.MockData.CustomPrefixes {
 		if len(prefix) > 50 {
@@ -824,6 +2283,41 @@ func (c *ServerConfig) validateMockDataConfig() error {
 		}
 	}
 
+	if c.LSP.MockData.WorkspaceSymbols < 0 || c.LSP.MockData.WorkspaceSymbols > 10000 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.mock_data.workspace_symbols",
+			Value:   fmt.Sprintf("%d", c.LSP.MockData.WorkspaceSymbols),
+			Message: "workspace_symbols must be between 0 and 10,000",
+		})
+	}
+
+	total := 0
+	for _, name := range slices.Sorted(maps.Keys(c.LSP.MockData.SymbolKinds)) {
+		percent := c.LSP.MockData.SymbolKinds[name]
+		if !slices.Contains(SymbolKinds, name) {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.mock_data.symbol_kinds",
+				Value:   name,
+				Message: fmt.Sprintf("symbol kind must be one of: %s", strings.Join(SymbolKinds, ", ")),
+			})
+		}
+		if percent < 0 || percent > 100 {
+			errors = append(errors, ValidationError{
+				Field:   "lsp.mock_data.symbol_kinds." + name,
+				Value:   fmt.Sprintf("%d", percent),
+				Message: "symbol kind percentage must be between 0 and 100",
+			})
+		}
+		total += percent
+	}
+	if len(c.LSP.MockData.SymbolKinds) > 0 && total != 100 {
+		errors = append(errors, ValidationError{
+			Field:   "lsp.mock_data.symbol_kinds",
+			Value:   fmt.Sprintf("%d", total),
+			Message: "symbol kind percentages must add up to 100",
+		})
+	}
+
 	// Validate languages
 	for i, lang := range c.LSP.MockData.Languages {
 		if len(lang) < 2 || len(lang) > 20 {
@@ -848,6 +2342,119 @@ func (c *ServerConfig) validateMockDataConfig() error {
 	return nil
 }
 
+// validateLatencyConfig validates per-method SLO and budget declarations
+func (c *ServerConfig) validateLatencyConfig() error {
+	var errors ValidationErrors
+
+	for method, slo := range c.LSP.Latency.SLOs {
+		field := fmt.Sprintf("lsp.latency.slos[%s]", method)
+		if method == "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   method,
+				Message: "SLO method name is required",
+			})
+		}
+		if slo.P50.Duration() < 0 || slo.P99.Duration() < 0 {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   fmt.Sprintf("p50=%s p99=%s", slo.P50, slo.P99),
+				Message: "SLO latencies must be non-negative",
+			})
+		}
+		if slo.P99.Duration() < slo.P50.Duration() {
+			errors = append(errors, ValidationError{
+				Field:   field + ".p99",
+				Value:   slo.P99.String(),
+				Message: "p99 must be greater than or equal to p50",
+			})
+		}
+		if slo.P99.Duration() > 5*time.Minute {
+			errors = append(errors, ValidationError{
+				Field:   field + ".p99",
+				Value:   slo.P99.String(),
+				Message: "p99 must be less than 5 minutes",
+			})
+		}
+		if slo.MaxResponseBytes < 0 {
+			errors = append(errors, ValidationError{
+				Field:   field + ".max_response_bytes",
+				Value:   fmt.Sprintf("%d", slo.MaxResponseBytes),
+				Message: "max_response_bytes must be non-negative",
+			})
+		}
+	}
+
+	for method, budget := range c.LSP.Latency.Budgets {
+		field := fmt.Sprintf("lsp.latency.budgets[%s]", method)
+		if method == "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   method,
+				Message: "budget method name is required",
+			})
+		}
+		if budget.Duration() <= 0 {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   budget.String(),
+				Message: "budget must be positive",
+			})
+		}
+	}
+
+	for method, delay := range c.LSP.Latency.Delays {
+		field := fmt.Sprintf("lsp.latency.delays[%s]", method)
+		if method == "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   method,
+				Message: "delay method name is required",
+			})
+		}
+		if delay.Duration() < 0 || delay.Duration() > 5*time.Minute {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   delay.String(),
+				Message: "delay must be between 0 and 5 minutes",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validatePresetsConfig validates per-method edge-case response presets
+func (c *ServerConfig) validatePresetsConfig() error {
+	var errors ValidationErrors
+
+	for method, preset := range c.LSP.Presets {
+		field := fmt.Sprintf("lsp.presets[%s]", method)
+		if method == "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   preset,
+				Message: "preset method name is required",
+			})
+		}
+		if !slices.Contains(ResponsePresets, preset) {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Value:   preset,
+				Message: fmt.Sprintf("preset must be one of: %s", strings.Join(ResponsePresets, ", ")),
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
 // mergeConfigs merges two configurations, with override taking precedence
 func mergeConfigs(base, override *ServerConfig) *ServerConfig {
 	result := *base // Copy base config
@@ -873,6 +2480,13 @@ func mergeConfigs(base, override *ServerConfig) *ServerConfig {
 	if override.Server.MaxRequests != 0 {
 		result.Server.MaxRequests = override.Server.MaxRequests
 	}
+	if override.Server.ExitCodes != nil {
+		result.Server.ExitCodes = maps.Clone(base.Server.ExitCodes)
+		if result.Server.ExitCodes == nil {
+			result.Server.ExitCodes = make(map[string]int)
+		}
+		maps.Copy(result.Server.ExitCodes, override.Server.ExitCodes)
+	}
 
 	// Merge logging settings
 	if override.Logging.Level != "" {
@@ -893,6 +2507,9 @@ func mergeConfigs(base, override *ServerConfig) *ServerConfig {
 	if override.Logging.Format != "" {
 		result.Logging.Format = override.Logging.Format
 	}
+	if override.Logging.PayloadMethods != nil {
+		result.Logging.PayloadMethods = override.Logging.PayloadMethods
+	}
 
 	// Merge LSP settings with nested configuration merging
 	if override.LSP.InitializeTimeout.Duration() != 0 {
@@ -903,9 +2520,294 @@ func mergeConfigs(base, override *ServerConfig) *ServerConfig {
 	if override.LSP.CompletionConfig.MaxItems != 0 {
 		result.LSP.CompletionConfig.MaxItems = override.LSP.CompletionConfig.MaxItems
 	}
+	if override.LSP.CompletionConfig.TriggerCharacters != nil {
+		result.LSP.CompletionConfig.TriggerCharacters = override.LSP.CompletionConfig.TriggerCharacters
+	}
 	if override.LSP.CompletionConfig.CaseSensitive {
 		result.LSP.CompletionConfig.CaseSensitive = override.LSP.CompletionConfig.CaseSensitive
 	}
+	if override.LSP.CompletionConfig.Kinds != nil {
+		result.LSP.CompletionConfig.Kinds = override.LSP.CompletionConfig.Kinds
+	}
+	if override.LSP.CompletionConfig.Deprecated != 0 {
+		result.LSP.CompletionConfig.Deprecated = override.LSP.CompletionConfig.Deprecated
+	}
+	if override.LSP.CompletionConfig.Preselect != 0 {
+		result.LSP.CompletionConfig.Preselect = override.LSP.CompletionConfig.Preselect
+	}
+	if override.LSP.CompletionConfig.SortText != "" {
+		result.LSP.CompletionConfig.SortText = override.LSP.CompletionConfig.SortText
+	}
+	if override.LSP.CompletionConfig.FilterText {
+		result.LSP.CompletionConfig.FilterText = override.LSP.CompletionConfig.FilterText
+	}
+	if override.LSP.CompletionConfig.CommitCharacters != nil {
+		result.LSP.CompletionConfig.CommitCharacters = override.LSP.CompletionConfig.CommitCharacters
+	}
+
+	// Merge hover config
+	if override.LSP.HoverConfig.Markdown != nil {
+		result.LSP.HoverConfig.Markdown = override.LSP.HoverConfig.Markdown
+	}
+
+	// Merge execute command config
+	if override.LSP.ExecuteCommand.Commands != nil {
+		result.LSP.ExecuteCommand.Commands = override.LSP.ExecuteCommand.Commands
+	}
+
+	// Merge code action config
+	if override.LSP.CodeActionConfig.Kinds != nil {
+		result.LSP.CodeActionConfig.Kinds = override.LSP.CodeActionConfig.Kinds
+	}
+	if override.LSP.CodeActionConfig.QuickFixes != 0 {
+		result.LSP.CodeActionConfig.QuickFixes = override.LSP.CodeActionConfig.QuickFixes
+	}
+	if override.LSP.CodeActionConfig.Refactorings != 0 {
+		result.LSP.CodeActionConfig.Refactorings = override.LSP.CodeActionConfig.Refactorings
+	}
+	if override.LSP.CodeActionConfig.Resolve {
+		result.LSP.CodeActionConfig.Resolve = override.LSP.CodeActionConfig.Resolve
+	}
+
+	// Merge rename config
+	if override.LSP.RenameConfig.Files != 0 {
+		result.LSP.RenameConfig.Files = override.LSP.RenameConfig.Files
+	}
+	if override.LSP.RenameConfig.EditsPerFile != 0 {
+		result.LSP.RenameConfig.EditsPerFile = override.LSP.RenameConfig.EditsPerFile
+	}
+
+	// Merge signature help config
+	if override.LSP.SignatureHelpConfig.TriggerCharacters != nil {
+		result.LSP.SignatureHelpConfig.TriggerCharacters = override.LSP.SignatureHelpConfig.TriggerCharacters
+	}
+	if override.LSP.SignatureHelpConfig.RetriggerCharacters != nil {
+		result.LSP.SignatureHelpConfig.RetriggerCharacters = override.LSP.SignatureHelpConfig.RetriggerCharacters
+	}
+	if override.LSP.SignatureHelpConfig.Signatures != 0 {
+		result.LSP.SignatureHelpConfig.Signatures = override.LSP.SignatureHelpConfig.Signatures
+	}
+
+	// Merge inlay hint config
+	if override.LSP.InlayHintConfig.LineInterval != 0 {
+		result.LSP.InlayHintConfig.LineInterval = override.LSP.InlayHintConfig.LineInterval
+	}
+	if override.LSP.InlayHintConfig.WordInterval != 0 {
+		result.LSP.InlayHintConfig.WordInterval = override.LSP.InlayHintConfig.WordInterval
+	}
+	if override.LSP.InlayHintConfig.Kinds != nil {
+		result.LSP.InlayHintConfig.Kinds = override.LSP.InlayHintConfig.Kinds
+	}
+	if override.LSP.InlayHintConfig.Resolve {
+		result.LSP.InlayHintConfig.Resolve = override.LSP.InlayHintConfig.Resolve
+	}
+
+	// Merge inline completion config
+	if override.LSP.InlineCompletion.Suggestions != 0 {
+		result.LSP.InlineCompletion.Suggestions = override.LSP.InlineCompletion.Suggestions
+	}
+	if override.LSP.InlineCompletion.Lines != 0 {
+		result.LSP.InlineCompletion.Lines = override.LSP.InlineCompletion.Lines
+	}
+
+	// Merge file operations config
+	if override.LSP.FileOperations.Filters != nil {
+		result.LSP.FileOperations.Filters = override.LSP.FileOperations.Filters
+	}
+	if override.LSP.FileOperations.RenameImports {
+		result.LSP.FileOperations.RenameImports = override.LSP.FileOperations.RenameImports
+	}
+	if override.LSP.FileOperations.DeleteImports {
+		result.LSP.FileOperations.DeleteImports = override.LSP.FileOperations.DeleteImports
+	}
+
+	// Merge diagnostics config
+	if override.LSP.DiagnosticsConfig.Duplicates != 0 {
+		result.LSP.DiagnosticsConfig.Duplicates = override.LSP.DiagnosticsConfig.Duplicates
+	}
+	if override.LSP.DiagnosticsConfig.Overlapping {
+		result.LSP.DiagnosticsConfig.Overlapping = override.LSP.DiagnosticsConfig.Overlapping
+	}
+	if override.LSP.DiagnosticsConfig.SeverityVariants {
+		result.LSP.DiagnosticsConfig.SeverityVariants = override.LSP.DiagnosticsConfig.SeverityVariants
+	}
+	if len(override.LSP.DiagnosticsConfig.UnopenedURIs) > 0 {
+		result.LSP.DiagnosticsConfig.UnopenedURIs = override.LSP.DiagnosticsConfig.UnopenedURIs
+	}
+	if override.LSP.DiagnosticsConfig.Mode != "" {
+		result.LSP.DiagnosticsConfig.Mode = override.LSP.DiagnosticsConfig.Mode
+	}
+
+	// Merge mock data config
+	if override.LSP.MockData.Seed != 0 {
+		result.LSP.MockData.Seed = override.LSP.MockData.Seed
+	}
+	if override.LSP.MockData.ItemCount != 0 {
+		result.LSP.MockData.ItemCount = override.LSP.MockData.ItemCount
+	}
+	if override.LSP.MockData.Randomize {
+		result.LSP.MockData.Randomize = override.LSP.MockData.Randomize
+	}
+	if len(override.LSP.MockData.Unicode) > 0 {
+		result.LSP.MockData.Unicode = override.LSP.MockData.Unicode
+	}
+	if override.LSP.MockData.Deprecated {
+		result.LSP.MockData.Deprecated = override.LSP.MockData.Deprecated
+	}
+	if override.LSP.MockData.WorkspaceSymbols != 0 {
+		result.LSP.MockData.WorkspaceSymbols = override.LSP.MockData.WorkspaceSymbols
+	}
+	if override.LSP.MockData.SymbolKinds != nil {
+		result.LSP.MockData.SymbolKinds = override.LSP.MockData.SymbolKinds
+	}
+
+	// Merge latency config
+	if len(override.LSP.Latency.SLOs) > 0 {
+		result.LSP.Latency.SLOs = override.LSP.Latency.SLOs
+	}
+	if len(override.LSP.Latency.Budgets) > 0 {
+		result.LSP.Latency.Budgets = override.LSP.Latency.Budgets
+	}
+	if len(override.LSP.Latency.Delays) > 0 {
+		result.LSP.Latency.Delays = override.LSP.Latency.Delays
+	}
+
+	// Merge locale
+	if override.LSP.Locale != "" {
+		result.LSP.Locale = override.LSP.Locale
+	}
+
+	// Merge trace metadata config
+	if override.LSP.TraceMetadata.Enabled {
+		result.LSP.TraceMetadata.Enabled = override.LSP.TraceMetadata.Enabled
+	}
+	if override.LSP.TraceMetadata.Field != "" {
+		result.LSP.TraceMetadata.Field = override.LSP.TraceMetadata.Field
+	}
+	if override.LSP.TraceMetadata.TraceID != "" {
+		result.LSP.TraceMetadata.TraceID = override.LSP.TraceMetadata.TraceID
+	}
+
+	// Merge recent traffic buffer size
+	if override.LSP.RecentTraffic != 0 {
+		result.LSP.RecentTraffic = override.LSP.RecentTraffic
+	}
+
+	// Merge redaction config
+	if override.LSP.Redaction.Mode != "" {
+		result.LSP.Redaction.Mode = override.LSP.Redaction.Mode
+	}
+	if len(override.LSP.Redaction.Allowlist) > 0 {
+		result.LSP.Redaction.Allowlist = override.LSP.Redaction.Allowlist
+	}
+
+	// Merge read-only mode
+	if override.LSP.ReadOnly {
+		result.LSP.ReadOnly = override.LSP.ReadOnly
+	}
+	if override.LSP.SchemaValidation {
+		result.LSP.SchemaValidation = override.LSP.SchemaValidation
+	}
+
+	// Merge minimal mode
+	if override.LSP.Minimal {
+		result.LSP.Minimal = override.LSP.Minimal
+	}
+
+	// Merge notification queue
+	if override.LSP.NotificationQueue.Size > 0 {
+		result.LSP.NotificationQueue.Size = override.LSP.NotificationQueue.Size
+	}
+	if override.LSP.NotificationQueue.Overflow != "" {
+		result.LSP.NotificationQueue.Overflow = override.LSP.NotificationQueue.Overflow
+	}
+
+	// Merge features, keeping the base state of the features not listed
+	if override.LSP.Features != nil {
+		result.LSP.Features = maps.Clone(base.LSP.Features)
+		if result.LSP.Features == nil {
+			result.LSP.Features = make(map[string]bool)
+		}
+		maps.Copy(result.LSP.Features, override.LSP.Features)
+	}
+
+	// Merge dynamic registration
+	if override.LSP.DynamicRegistration {
+		result.LSP.DynamicRegistration = override.LSP.DynamicRegistration
+	}
+	if override.LSP.ShutdownDrainTimeout != 0 {
+		result.LSP.ShutdownDrainTimeout = override.LSP.ShutdownDrainTimeout
+	}
+
+	// Merge memory pressure config
+	if override.LSP.MemoryPressure.HeapLimitMB != 0 {
+		result.LSP.MemoryPressure.HeapLimitMB = override.LSP.MemoryPressure.HeapLimitMB
+	}
+	if override.LSP.MemoryPressure.MaxResponseBytes != 0 {
+		result.LSP.MemoryPressure.MaxResponseBytes = override.LSP.MemoryPressure.MaxResponseBytes
+	}
+	if override.LSP.MemoryPressure.Interval != 0 {
+		result.LSP.MemoryPressure.Interval = override.LSP.MemoryPressure.Interval
+	}
+
+	// Merge indexing config
+	if override.LSP.Indexing.Duration != 0 {
+		result.LSP.Indexing.Duration = override.LSP.Indexing.Duration
+	}
+	if override.LSP.Indexing.Steps != 0 {
+		result.LSP.Indexing.Steps = override.LSP.Indexing.Steps
+	}
+
+	// Merge partial results config
+	if override.LSP.PartialResults.ChunkSize != 0 {
+		result.LSP.PartialResults.ChunkSize = override.LSP.PartialResults.ChunkSize
+	}
+	if override.LSP.PartialResults.Delay != 0 {
+		result.LSP.PartialResults.Delay = override.LSP.PartialResults.Delay
+	}
+
+	// Merge request IDs config
+	if override.LSP.RequestIDs.Strategy != "" {
+		result.LSP.RequestIDs.Strategy = override.LSP.RequestIDs.Strategy
+	}
+	if override.LSP.RequestIDs.Prefix != "" {
+		result.LSP.RequestIDs.Prefix = override.LSP.RequestIDs.Prefix
+	}
+	if override.LSP.RequestIDs.Seed != 0 {
+		result.LSP.RequestIDs.Seed = override.LSP.RequestIDs.Seed
+	}
+	if override.LSP.RequestIDs.RejectDuplicates {
+		result.LSP.RequestIDs.RejectDuplicates = true
+	}
+
+	// Merge failure preset
+	if override.LSP.FailurePreset != "" {
+		result.LSP.FailurePreset = override.LSP.FailurePreset
+	}
+
+	// Merge client settings config
+	if override.LSP.ClientSettings.Section != "" {
+		result.LSP.ClientSettings.Section = override.LSP.ClientSettings.Section
+	}
+	if override.LSP.ClientSettings.Pull {
+		result.LSP.ClientSettings.Pull = true
+	}
+
+	// Merge allowed schemes
+	if len(override.LSP.AllowedSchemes) > 0 {
+		result.LSP.AllowedSchemes = override.LSP.AllowedSchemes
+	}
+
+	// Merge response presets
+	if len(override.LSP.Presets) > 0 {
+		result.LSP.Presets = override.LSP.Presets
+	}
+	if override.LSP.PresetReverseRanges {
+		result.LSP.PresetReverseRanges = override.LSP.PresetReverseRanges
+	}
+	if override.LSP.DisableInvariants {
+		result.LSP.DisableInvariants = override.LSP.DisableInvariants
+	}
 
 	return &result
 }
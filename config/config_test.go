@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -846,4 +849,1024 @@ func TestValidationBoundaryConditions(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestLatencyConfigValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		slo         SLOConfig
+		expectError bool
+	}{
+		{
+			name:        "Valid SLO",
+			slo:         SLOConfig{P50: Duration(50 * time.Millisecond), P99: Duration(200 * time.Millisecond), MaxResponseBytes: 4096},
+			expectError: false,
+		},
+		{
+			name:        "P99 Below P50",
+			slo:         SLOConfig{P50: Duration(200 * time.Millisecond), P99: Duration(50 * time.Millisecond)},
+			expectError: true,
+		},
+		{
+			name:        "Negative Response Size",
+			slo:         SLOConfig{P50: Duration(time.Millisecond), P99: Duration(time.Millisecond), MaxResponseBytes: -1},
+			expectError: true,
+		},
+		{
+			name:        "P99 Too Large",
+			slo:         SLOConfig{P50: Duration(time.Second), P99: Duration(10 * time.Minute)},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LSP.Latency.SLOs = map[string]SLOConfig{"textDocument/completion": tc.slo}
+			err := config.Validate()
+
+			if tc.expectError && err == nil {
+				t.Error("Expected validation error, got nil")
+			} else if !tc.expectError && err != nil {
+				t.Errorf("Expected no validation error, got: %v", err)
+			}
+		})
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		override := &ServerConfig{LSP: LSPConfig{Latency: LatencyConfig{
+			SLOs: map[string]SLOConfig{"textDocument/hover": {P99: Duration(time.Second)}},
+		}}}
+		merged := mergeConfigs(DefaultConfig(), override)
+		if _, ok := merged.LSP.Latency.SLOs["textDocument/hover"]; !ok {
+			t.Error("Expected hover SLO to be merged from override")
+		}
+	})
+
+	t.Run("Budgets", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LSP.Latency.Budgets = map[string]Duration{"textDocument/hover": Duration(100 * time.Millisecond)}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected a positive budget to be valid, got: %v", err)
+		}
+
+		config.LSP.Latency.Budgets["textDocument/completion"] = 0
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a zero budget")
+		}
+
+		override := &ServerConfig{LSP: LSPConfig{Latency: LatencyConfig{
+			Budgets: map[string]Duration{"textDocument/hover": Duration(time.Second)},
+		}}}
+		merged := mergeConfigs(DefaultConfig(), override)
+		if merged.LSP.Latency.Budgets["textDocument/hover"] != Duration(time.Second) {
+			t.Error("Expected hover budget to be merged from override")
+		}
+	})
+
+	t.Run("Delays", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LSP.Latency.Delays = map[string]Duration{"textDocument/hover": Duration(time.Second), "textDocument/completion": 0}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected non-negative delays to be valid, got: %v", err)
+		}
+
+		config.LSP.Latency.Delays["textDocument/completion"] = Duration(-time.Second)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a negative delay")
+		}
+
+		override := &ServerConfig{LSP: LSPConfig{Latency: LatencyConfig{
+			Delays: map[string]Duration{"textDocument/hover": Duration(time.Second)},
+		}}}
+		merged := mergeConfigs(DefaultConfig(), override)
+		if merged.LSP.Latency.Delays["textDocument/hover"] != Duration(time.Second) {
+			t.Error("Expected hover delay to be merged from override")
+		}
+	})
+}
+
+func TestPresetsConfigValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		presets     map[string]string
+		expectError bool
+	}{
+		{
+			name: "Known Presets",
+			presets: map[string]string{
+				"textDocument/completion":         PresetEmptyEverything,
+				"textDocument/hover":              PresetNullsEverywhere,
+				"textDocument/publishDiagnostics": PresetSurrogatePairs,
+				"textDocument/documentSymbol":     PresetAllOptionalFieldsSet,
+			},
+			expectError: false,
+		},
+		{
+			name:        "Unknown Preset",
+			presets:     map[string]string{"textDocument/hover": "mostly-harmless"},
+			expectError: true,
+		},
+		{
+			name:        "Empty Method",
+			presets:     map[string]string{"": PresetEmptyEverything},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LSP.Presets = tc.presets
+			err := config.Validate()
+
+			if tc.expectError && err == nil {
+				t.Error("Expected validation error, got nil")
+			} else if !tc.expectError && err != nil {
+				t.Errorf("Expected no validation error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestMockDataUnicodeValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.MockData.Unicode = UnicodeCategories
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.MockData.Unicode = []string{UnicodeEmoji, "klingon"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for unknown unicode category, got nil")
+	}
+}
+
+func TestDiagnosticsDuplicatesValidation(t *testing.T) {
+	for _, duplicates := range []int{-1, 11} {
+		config := DefaultConfig()
+		config.LSP.DiagnosticsConfig.Duplicates = duplicates
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for duplicates=%d, got nil", duplicates)
+		}
+	}
+
+	config := DefaultConfig()
+	config.LSP.DiagnosticsConfig.Duplicates = 3
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+}
+
+func TestDiagnosticsUnopenedURIsValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.DiagnosticsConfig.UnopenedURIs = []string{"untitled:Untitled-1", "git:/repo/a.go?ref=HEAD", "file:///closed.go"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.DiagnosticsConfig.UnopenedURIs = []string{"relative/path.go"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for URI without scheme, got nil")
+	}
+}
+
+func TestDiagnosticsModeValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, mode := range append([]string{""}, DiagnosticModes...) {
+		config.LSP.DiagnosticsConfig.Mode = mode
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error for mode %q, got: %v", mode, err)
+		}
+	}
+
+	config.LSP.DiagnosticsConfig.Mode = "poll"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for unknown mode, got nil")
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{DiagnosticsConfig: DiagnosticsConfig{Mode: DiagnosticModePull}}})
+	if merged.LSP.DiagnosticsConfig.Mode != DiagnosticModePull {
+		t.Errorf("Expected mode to be merged from override, got %q", merged.LSP.DiagnosticsConfig.Mode)
+	}
+}
+
+func TestIndexingValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.Indexing = IndexingConfig{Duration: Duration(30 * time.Second), Steps: 20}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.Indexing = IndexingConfig{Duration: Duration(-time.Second), Steps: 1001}
+	err := config.Validate()
+	if ve, ok := err.(ValidationErrors); !ok || len(ve) != 2 {
+		t.Errorf("Expected errors for the duration and steps, got: %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{Indexing: IndexingConfig{Duration: Duration(time.Minute), Steps: 4}}})
+	if merged.LSP.Indexing.Duration != Duration(time.Minute) || merged.LSP.Indexing.Steps != 4 {
+		t.Errorf("Expected indexing to be merged from override, got %+v", merged.LSP.Indexing)
+	}
+}
+
+func TestPartialResultsValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.PartialResults = PartialResultsConfig{ChunkSize: 10, Delay: Duration(100 * time.Millisecond)}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.PartialResults = PartialResultsConfig{ChunkSize: -1, Delay: Duration(2 * time.Minute)}
+	err := config.Validate()
+	if ve, ok := err.(ValidationErrors); !ok || len(ve) != 2 {
+		t.Errorf("Expected errors for the chunk size and delay, got: %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{PartialResults: PartialResultsConfig{ChunkSize: 2, Delay: Duration(time.Second)}}})
+	if merged.LSP.PartialResults.ChunkSize != 2 || merged.LSP.PartialResults.Delay != Duration(time.Second) {
+		t.Errorf("Expected partial results to be merged from override, got %+v", merged.LSP.PartialResults)
+	}
+}
+
+func TestRequestIDsValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.RequestIDs = RequestIDsConfig{Strategy: IDStrategyPrefixed, Prefix: "replay-", RejectDuplicates: true}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.RequestIDs = RequestIDsConfig{Strategy: "uuid", Prefix: strings.Repeat("x", 65)}
+	err := config.Validate()
+	if ve, ok := err.(ValidationErrors); !ok || len(ve) != 2 {
+		t.Errorf("Expected errors for the strategy and prefix, got: %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{RequestIDs: RequestIDsConfig{Strategy: IDStrategyRandom, Seed: 7, RejectDuplicates: true}}})
+	if ids := merged.LSP.RequestIDs; ids.Strategy != IDStrategyRandom || ids.Seed != 7 || !ids.RejectDuplicates {
+		t.Errorf("Expected request IDs to be merged from override, got %+v", ids)
+	}
+}
+
+func TestInlineCompletionValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.InlineCompletion = InlineCompletionConfig{Suggestions: 5, Lines: 10}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.InlineCompletion = InlineCompletionConfig{Suggestions: 51, Lines: -1}
+	err := config.Validate()
+	if ve, ok := err.(ValidationErrors); !ok || len(ve) != 2 {
+		t.Errorf("Expected errors for the suggestions and lines, got: %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{InlineCompletion: InlineCompletionConfig{Suggestions: 1, Lines: 2}}})
+	if merged.LSP.InlineCompletion.Suggestions != 1 || merged.LSP.InlineCompletion.Lines != 2 {
+		t.Errorf("Expected inline completion to be merged from override, got %+v", merged.LSP.InlineCompletion)
+	}
+}
+
+func TestFileOperationsValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.FileOperations = FileOperationsConfig{Filters: []string{"**/*.go", "**/*.{ts,js}"}, RenameImports: true}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.FileOperations.Filters = []string{"**/*.go", " "}
+	err := config.Validate()
+	if ve, ok := err.(ValidationErrors); !ok || len(ve) != 1 || ve[0].Field != "lsp.file_operations.filters[1]" {
+		t.Errorf("Expected an error for the empty filter, got: %v", err)
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{FileOperations: FileOperationsConfig{Filters: []string{"src/**"}, DeleteImports: true}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if len(merged.LSP.FileOperations.Filters) != 1 || !merged.LSP.FileOperations.DeleteImports || merged.LSP.FileOperations.RenameImports {
+		t.Errorf("Expected file operations to be merged from override, got %+v", merged.LSP.FileOperations)
+	}
+}
+
+func TestFailurePresetValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, preset := range FailurePresets {
+		config.LSP.FailurePreset = preset
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error for %s, got: %v", preset, err)
+		}
+	}
+
+	config.LSP.FailurePreset = "meteor-strike"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for unknown failure preset, got nil")
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{FailurePreset: FailurePresetCrashy}})
+	if merged.LSP.FailurePreset != FailurePresetCrashy {
+		t.Errorf("Expected failure preset to be merged from override, got %q", merged.LSP.FailurePreset)
+	}
+}
+
+func TestClientSettingsMerge(t *testing.T) {
+	override := &ServerConfig{LSP: LSPConfig{ClientSettings: ClientSettingsConfig{Section: "mock", Pull: true}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if merged.LSP.ClientSettings.Section != "mock" || !merged.LSP.ClientSettings.Pull {
+		t.Errorf("Expected client settings to be merged from override, got %+v", merged.LSP.ClientSettings)
+	}
+}
+
+func TestAllowedSchemesValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.AllowedSchemes = []string{"file", "untitled", "vscode-notebook-cell", "git+ssh"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.AllowedSchemes = []string{"file:"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for invalid scheme, got nil")
+	}
+}
+
+func TestLocaleValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, locale := range []string{"", "en", "de-DE", "ja_JP", "qps-ploc"} {
+		config.LSP.Locale = locale
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error for locale %q, got: %v", locale, err)
+		}
+	}
+
+	config.LSP.Locale = "fr"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for unsupported locale, got nil")
+	}
+}
+
+func TestTraceMetadataValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.TraceMetadata = TraceMetadataConfig{Enabled: true, Field: "otel_trace", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	invalid := []TraceMetadataConfig{
+		{Field: "trace.id"},
+		{TraceID: "4BF92F3577B34DA6A3CE929D0E0E4736"},
+		{TraceID: "4bf92f35"},
+		{TraceID: "00000000000000000000000000000000"},
+	}
+	for _, trace := range invalid {
+		config.LSP.TraceMetadata = trace
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v, got nil", trace)
+		}
+	}
+}
+
+func TestRecentTrafficValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.RecentTraffic = 10001
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for oversized recent_traffic, got nil")
+	}
+
+	config.LSP.RecentTraffic = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for negative recent_traffic, got nil")
+	}
+}
+
+func TestRedactionValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, mode := range RedactionModes {
+		config.LSP.Redaction = RedactionConfig{Mode: mode, Allowlist: []string{"uri", "label"}}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error for mode %s, got: %v", mode, err)
+		}
+	}
+
+	invalid := []RedactionConfig{
+		{Mode: "encrypt"},
+		{Mode: RedactionHash, Allowlist: []string{""}},
+	}
+	for _, redaction := range invalid {
+		config.LSP.Redaction = redaction
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v, got nil", redaction)
+		}
+	}
+}
+
+func TestNotificationQueueValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, policy := range append([]string{""}, QueueOverflowPolicies...) {
+		config.LSP.NotificationQueue = NotificationQueueConfig{Size: 64, Overflow: policy}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error for overflow %q, got: %v", policy, err)
+		}
+	}
+
+	invalid := []NotificationQueueConfig{
+		{Size: -1},
+		{Size: 100001},
+		{Size: 64, Overflow: "drop_everything"},
+	}
+	for _, queue := range invalid {
+		config.LSP.NotificationQueue = queue
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v, got nil", queue)
+		}
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{NotificationQueue: NotificationQueueConfig{Size: 8, Overflow: QueueBlock}}})
+	if merged.LSP.NotificationQueue.Size != 8 || merged.LSP.NotificationQueue.Overflow != QueueBlock {
+		t.Errorf("Expected the notification queue to be merged from override, got %+v", merged.LSP.NotificationQueue)
+	}
+}
+
+func TestCompletionConfigValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		completion func(*CompletionConfig)
+		wantErr    bool
+	}{
+		{"default items", func(*CompletionConfig) {}, false},
+		{"kind mix", func(c *CompletionConfig) {
+			c.Kinds = map[string]int{"function": 40, "variable": 30, "class": 10, "keyword": 10, "snippet": 10}
+			c.Deprecated = 20
+			c.Preselect = 5
+		}, false},
+		{"single kind", func(c *CompletionConfig) { c.Kinds = map[string]int{"type_parameter": 100} }, false},
+		{"unknown kind", func(c *CompletionConfig) { c.Kinds = map[string]int{"function": 50, "lambda": 50} }, true},
+		{"short of 100", func(c *CompletionConfig) { c.Kinds = map[string]int{"function": 50, "class": 40} }, true},
+		{"negative percentage", func(c *CompletionConfig) { c.Kinds = map[string]int{"function": 110, "class": -10} }, true},
+		{"deprecated over 100", func(c *CompletionConfig) { c.Deprecated = 101 }, true},
+		{"negative preselect", func(c *CompletionConfig) { c.Preselect = -1 }, true},
+		{"sort text", func(c *CompletionConfig) { c.SortText = SortTextNumeric }, false},
+		{"unknown sort text", func(c *CompletionConfig) { c.SortText = "random" }, true},
+		{"commit characters", func(c *CompletionConfig) { c.CommitCharacters = []string{".", "(", "→"} }, false},
+		{"multi-character commit", func(c *CompletionConfig) { c.CommitCharacters = []string{"::"} }, true},
+		{"empty commit character", func(c *CompletionConfig) { c.CommitCharacters = []string{""} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.completion(&config.LSP.CompletionConfig)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{CompletionConfig: CompletionConfig{Kinds: map[string]int{"keyword": 100}, Deprecated: 50}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if merged.LSP.CompletionConfig.Kinds["keyword"] != 100 || merged.LSP.CompletionConfig.Deprecated != 50 {
+		t.Errorf("Expected the completion kind mix to be merged from override, got %+v", merged.LSP.CompletionConfig)
+	}
+}
+
+func TestApplyLSPOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     string
+		wantIgnored []string
+		wantErr     bool
+		check       func(t *testing.T, config *ServerConfig)
+	}{
+		{
+			name:    "scalar and nested keys",
+			options: `{"locale": "de", "diagnostics": {"duplicates": 2}}`,
+			check: func(t *testing.T, config *ServerConfig) {
+				if config.LSP.Locale != "de" {
+					t.Errorf("Expected locale de, got %q", config.LSP.Locale)
+				}
+				if config.LSP.DiagnosticsConfig.Duplicates != 2 {
+					t.Errorf("Expected 2 duplicates, got %d", config.LSP.DiagnosticsConfig.Duplicates)
+				}
+				if !config.LSP.DiagnosticsConfig.Enabled {
+					t.Error("Expected keys missing from the options to keep their values")
+				}
+			},
+		},
+		{
+			name:    "explicit false overrides",
+			options: `{"completion": {"enabled": false}}`,
+			check: func(t *testing.T, config *ServerConfig) {
+				if config.LSP.CompletionConfig.Enabled {
+					t.Error("Expected completion to be disabled")
+				}
+			},
+		},
+		{
+			name:        "unknown keys are ignored",
+			options:     `{"typescript": {"tsdk": "node_modules"}, "locale": "ja", "format": true}`,
+			wantIgnored: []string{"format", "typescript"},
+			check: func(t *testing.T, config *ServerConfig) {
+				if config.LSP.Locale != "ja" {
+					t.Errorf("Expected locale ja, got %q", config.LSP.Locale)
+				}
+			},
+		},
+		{name: "not an object", options: `"verbose"`, wantErr: true},
+		{name: "wrong type", options: `{"recent_traffic": "many"}`, wantErr: true},
+		{name: "invalid value", options: `{"recent_traffic": -1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := DefaultConfig()
+			config, ignored, err := base.ApplyLSPOptions([]byte(tt.options))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error for %s, got nil", tt.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("Expected ignored keys %v, got %v", tt.wantIgnored, ignored)
+			}
+			tt.check(t, config)
+			if !reflect.DeepEqual(base, DefaultConfig()) {
+				t.Error("Expected the base configuration to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestReadOnlyMerge(t *testing.T) {
+	config := DefaultConfig()
+	if config.LSP.ReadOnly {
+		t.Error("Expected read-only mode to be off by default")
+	}
+
+	merged := mergeConfigs(config, &ServerConfig{LSP: LSPConfig{ReadOnly: true}})
+	if !merged.LSP.ReadOnly {
+		t.Error("Expected read_only to be merged from override")
+	}
+}
+
+func TestSchemaValidationMerge(t *testing.T) {
+	config := DefaultConfig()
+	if config.LSP.SchemaValidation {
+		t.Error("Expected schema validation to be off by default")
+	}
+
+	merged := mergeConfigs(config, &ServerConfig{LSP: LSPConfig{SchemaValidation: true}})
+	if !merged.LSP.SchemaValidation {
+		t.Error("Expected schema_validation to be merged from override")
+	}
+}
+
+func TestApplyClientProfile(t *testing.T) {
+	for _, name := range ClientProfiles {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			if err := config.ApplyClientProfile(name); err != nil {
+				t.Fatalf("Failed to apply profile: %v", err)
+			}
+			if err := config.Validate(); err != nil {
+				t.Errorf("Expected the profile to validate, got %v", err)
+			}
+		})
+	}
+
+	config := DefaultConfig()
+	if err := config.ApplyClientProfile("sublime"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+	if err := config.ApplyClientProfile(ClientProfileHelix); err != nil {
+		t.Fatalf("Failed to apply profile: %v", err)
+	}
+	if config.LSP.FeatureEnabled("semantic_tokens") || !config.LSP.FeatureEnabled("hover") {
+		t.Errorf("Expected helix to lose semantic tokens only, got %v", config.LSP.Features)
+	}
+	if DefaultConfig().LSP.Features["semantic_tokens"] != true {
+		t.Error("Expected the profile to leave the defaults untouched")
+	}
+}
+
+func TestLoadFromFileWithProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"lsp": {"completion": {"trigger_characters": ["#"]}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadFromFileWithProfile(path, ClientProfileNeovim)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if fmt.Sprint(config.LSP.CompletionConfig.TriggerCharacters) != "[#]" {
+		t.Errorf("Expected the config file to override the profile, got %v", config.LSP.CompletionConfig.TriggerCharacters)
+	}
+	if config.LSP.CompletionConfig.IncludeSnippets {
+		t.Error("Expected the neovim profile to turn off snippets")
+	}
+
+	if _, err := LoadFromFileWithProfile("", "sublime"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestParseWithProfile(t *testing.T) {
+	config, err := ParseWithProfile([]byte(`{"lsp": {"completion": {"trigger_characters": ["#"]}}}`), ClientProfileNeovim)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if fmt.Sprint(config.LSP.CompletionConfig.TriggerCharacters) != "[#]" || config.LSP.CompletionConfig.IncludeSnippets {
+		t.Errorf("Expected the config over the neovim profile, got %+v", config.LSP.CompletionConfig)
+	}
+
+	if _, err := ParseWithProfile([]byte(`{"lsp": [`), ""); err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+}
+
+func TestHoverMarkdown(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.HoverConfig.Markdown = HoverMarkdownElements
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.HoverConfig.Markdown = []string{"video"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for unknown markdown element, got nil")
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{HoverConfig: HoverConfig{Markdown: []string{"table"}}}})
+	if !reflect.DeepEqual(merged.LSP.HoverConfig.Markdown, []string{"table"}) {
+		t.Errorf("Expected markdown to be merged from override, got %v", merged.LSP.HoverConfig.Markdown)
+	}
+}
+
+func TestExecuteCommandValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.ExecuteCommand.Commands = map[string]CommandConfig{"mock.run": {Result: json.RawMessage(`[1, 2]`), ApplyEdit: true}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	config.LSP.ExecuteCommand.Commands = map[string]CommandConfig{" ": {}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for a blank command ID, got nil")
+	}
+
+	config.LSP.ExecuteCommand.Commands = map[string]CommandConfig{"mock.run": {Result: json.RawMessage(`{`)}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for an invalid result, got nil")
+	}
+}
+
+func TestCodeActionResolveMerge(t *testing.T) {
+	config := DefaultConfig()
+	if config.LSP.CodeActionConfig.Resolve {
+		t.Error("Expected code action resolve to be off by default")
+	}
+
+	merged := mergeConfigs(config, &ServerConfig{LSP: LSPConfig{CodeActionConfig: CodeActionConfig{Resolve: true}}})
+	if !merged.LSP.CodeActionConfig.Resolve {
+		t.Error("Expected code_action.resolve to be merged from override")
+	}
+	if merged.LSP.CodeActionConfig.QuickFixes != config.LSP.CodeActionConfig.QuickFixes {
+		t.Error("Expected quick_fixes to be kept from base")
+	}
+}
+
+func TestDynamicRegistrationMerge(t *testing.T) {
+	config := DefaultConfig()
+	if config.LSP.DynamicRegistration {
+		t.Error("Expected dynamic registration to be off by default")
+	}
+
+	merged := mergeConfigs(config, &ServerConfig{LSP: LSPConfig{DynamicRegistration: true}})
+	if !merged.LSP.DynamicRegistration {
+		t.Error("Expected dynamic_registration to be merged from override")
+	}
+}
+
+func TestDeprecatedMerge(t *testing.T) {
+	config := DefaultConfig()
+	if config.LSP.MockData.Deprecated {
+		t.Error("Expected deprecated marking to be off by default")
+	}
+
+	merged := mergeConfigs(config, &ServerConfig{LSP: LSPConfig{MockData: MockDataConfig{Deprecated: true}}})
+	if !merged.LSP.MockData.Deprecated {
+		t.Error("Expected mock_data.deprecated to be merged from override")
+	}
+}
+
+func TestShutdownDrainTimeoutValidation(t *testing.T) {
+	tests := []struct {
+		drain   time.Duration
+		wantErr bool
+	}{
+		{0, true},
+		{5 * time.Second, false},
+		{time.Minute, false},
+		{-time.Second, true},
+		{2 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.LSP.ShutdownDrainTimeout = Duration(tt.drain)
+		err := config.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Expected error %v for drain timeout %s, got %v", tt.wantErr, tt.drain, err)
+		}
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{ShutdownDrainTimeout: Duration(time.Second)}})
+	if merged.LSP.ShutdownDrainTimeout.Duration() != time.Second {
+		t.Errorf("Expected shutdown_drain_timeout to be merged from override, got %s", merged.LSP.ShutdownDrainTimeout)
+	}
+
+	// A config file leaving it at zero keeps the default
+	parsed, err := ParseWithProfile([]byte(`{"lsp": {"shutdown_drain_timeout": "0s"}}`), "")
+	if err != nil {
+		t.Fatalf("ParseWithProfile failed: %v", err)
+	}
+	if parsed.LSP.ShutdownDrainTimeout.Duration() != DefaultShutdownDrainTimeout {
+		t.Errorf("Expected a zero shutdown_drain_timeout to keep the default, got %s", parsed.LSP.ShutdownDrainTimeout)
+	}
+	if err := parsed.Validate(); err != nil {
+		t.Errorf("Expected the merged config to be valid, got %v", err)
+	}
+}
+
+func TestExitCodesValidation(t *testing.T) {
+	tests := []struct {
+		outcome string
+		code    int
+		wantErr bool
+	}{
+		{ExitWithoutShutdown, 0, false},
+		{ExitFatal, 125, false},
+		{ExitExpectationFailure, -1, true},
+		{ExitAfterShutdown, 126, true},
+		{"timeout", 4, true},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Server.ExitCodes[tt.outcome] = tt.code
+		err := config.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Expected error %v for exit code %s=%d, got %v", tt.wantErr, tt.outcome, tt.code, err)
+		}
+	}
+}
+
+func TestExitCodesMerge(t *testing.T) {
+	base := DefaultConfig()
+	merged := mergeConfigs(base, &ServerConfig{Server: ServerSettings{ExitCodes: map[string]int{ExitWithoutShutdown: 0}}})
+
+	want := map[string]int{ExitAfterShutdown: 0, ExitWithoutShutdown: 0, ExitFatal: 2, ExitExpectationFailure: 3}
+	for outcome, code := range want {
+		if got := merged.Server.ExitCode(outcome); got != code {
+			t.Errorf("Expected exit code %d for %s, got %d", code, outcome, got)
+		}
+	}
+	if got := base.Server.ExitCode(ExitWithoutShutdown); got != 1 {
+		t.Errorf("Expected the base config to keep exit code 1 for %s, got %d", ExitWithoutShutdown, got)
+	}
+	if got := (ServerSettings{}).ExitCode(ExitFatal); got != 2 {
+		t.Errorf("Expected unconfigured outcomes to use the default exit code 2, got %d", got)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	config := DefaultConfig()
+	config.LSP.Features["call_hierarchy"] = false
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "lsp.features[call_hierarchy]") {
+		t.Errorf("Expected an error for the unknown feature call_hierarchy, got %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{Features: map[string]bool{"hover": false}}})
+	if merged.LSP.FeatureEnabled("hover") {
+		t.Error("Expected hover to be disabled by the override")
+	}
+	if !merged.LSP.FeatureEnabled("definition") || !DefaultConfig().LSP.FeatureEnabled("hover") {
+		t.Error("Expected the features not overridden to stay enabled")
+	}
+
+	config = DefaultConfig()
+	config.LSP.DiagnosticsConfig.Enabled = false
+	if config.LSP.FeatureEnabled("diagnostics") {
+		t.Error("Expected diagnostics to be disabled by its own section")
+	}
+}
+
+func TestWorkspaceSymbolsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockData func(*MockDataConfig)
+		wantErr  bool
+	}{
+		{"default symbols", func(*MockDataConfig) {}, false},
+		{"no symbols", func(m *MockDataConfig) { m.WorkspaceSymbols = 0 }, false},
+		{"too many symbols", func(m *MockDataConfig) { m.WorkspaceSymbols = 10001 }, true},
+		{"single kind", func(m *MockDataConfig) { m.SymbolKinds = map[string]int{"enum_member": 100} }, false},
+		{"unknown kind", func(m *MockDataConfig) { m.SymbolKinds = map[string]int{"class": 50, "trait": 50} }, true},
+		{"short of 100", func(m *MockDataConfig) { m.SymbolKinds = map[string]int{"class": 50, "method": 40} }, true},
+		{"negative percentage", func(m *MockDataConfig) { m.SymbolKinds = map[string]int{"class": 110, "method": -10} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.mockData(&config.LSP.MockData)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{MockData: MockDataConfig{WorkspaceSymbols: 5, SymbolKinds: map[string]int{"struct": 100}}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if merged.LSP.MockData.WorkspaceSymbols != 5 || len(merged.LSP.MockData.SymbolKinds) != 1 {
+		t.Errorf("Expected the workspace symbols to be merged from override, got %+v", merged.LSP.MockData)
+	}
+}
+
+func TestCodeActionConfigValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		codeAction func(*CodeActionConfig)
+		wantErr    bool
+	}{
+		{"default actions", func(*CodeActionConfig) {}, false},
+		{"every kind", func(c *CodeActionConfig) { c.Kinds = CodeActionKinds }, false},
+		{"no kinds", func(c *CodeActionConfig) { c.Kinds = []string{} }, false},
+		{"unknown kind", func(c *CodeActionConfig) { c.Kinds = []string{"quickfix", "refactor.move"} }, true},
+		{"negative quick fixes", func(c *CodeActionConfig) { c.QuickFixes = -1 }, true},
+		{"too many refactorings", func(c *CodeActionConfig) { c.Refactorings = 21 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.codeAction(&config.LSP.CodeActionConfig)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{CodeActionConfig: CodeActionConfig{Kinds: []string{"source.fixAll"}, QuickFixes: 5}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if len(merged.LSP.CodeActionConfig.Kinds) != 1 || merged.LSP.CodeActionConfig.QuickFixes != 5 || merged.LSP.CodeActionConfig.Refactorings != 1 {
+		t.Errorf("Expected the code action config to be merged from override, got %+v", merged.LSP.CodeActionConfig)
+	}
+}
+
+func TestRenameConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		rename  func(*RenameConfig)
+		wantErr bool
+	}{
+		{"default limits", func(*RenameConfig) {}, false},
+		{"single edit", func(c *RenameConfig) { c.Files, c.EditsPerFile = 1, 1 }, false},
+		{"no files", func(c *RenameConfig) { c.Files = 0 }, true},
+		{"too many files", func(c *RenameConfig) { c.Files = 1001 }, true},
+		{"negative edits", func(c *RenameConfig) { c.EditsPerFile = -1 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.rename(&config.LSP.RenameConfig)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{RenameConfig: RenameConfig{Files: 3}}})
+	if merged.LSP.RenameConfig.Files != 3 || merged.LSP.RenameConfig.EditsPerFile != 50 {
+		t.Errorf("Expected the rename config to be merged from override, got %+v", merged.LSP.RenameConfig)
+	}
+}
+
+func TestSignatureHelpConfigValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		signatureHelp func(*SignatureHelpConfig)
+		wantErr       bool
+	}{
+		{"default signatures", func(*SignatureHelpConfig) {}, false},
+		{"no trigger characters", func(c *SignatureHelpConfig) { c.TriggerCharacters, c.RetriggerCharacters = []string{}, nil }, false},
+		{"too many trigger characters", func(c *SignatureHelpConfig) { c.TriggerCharacters = strings.Split("abcdefghijk", "") }, true},
+		{"too many retrigger characters", func(c *SignatureHelpConfig) { c.RetriggerCharacters = strings.Split("abcdefghijk", "") }, true},
+		{"no signatures", func(c *SignatureHelpConfig) { c.Signatures = 0 }, true},
+		{"too many signatures", func(c *SignatureHelpConfig) { c.Signatures = 6 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.signatureHelp(&config.LSP.SignatureHelpConfig)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{SignatureHelpConfig: SignatureHelpConfig{TriggerCharacters: []string{"(", "<"}}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if len(merged.LSP.SignatureHelpConfig.TriggerCharacters) != 2 || merged.LSP.SignatureHelpConfig.Signatures != 3 {
+		t.Errorf("Expected the signature help config to be merged from override, got %+v", merged.LSP.SignatureHelpConfig)
+	}
+}
+
+func TestInlayHintConfigValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		inlayHint func(*InlayHintConfig)
+		wantErr   bool
+	}{
+		{"default hints", func(*InlayHintConfig) {}, false},
+		{"parameter hints only", func(c *InlayHintConfig) { c.Kinds = []string{"parameter"} }, false},
+		{"no line interval", func(c *InlayHintConfig) { c.LineInterval = 0 }, true},
+		{"word interval too large", func(c *InlayHintConfig) { c.WordInterval = 101 }, true},
+		{"no kinds", func(c *InlayHintConfig) { c.Kinds = []string{} }, true},
+		{"unknown kind", func(c *InlayHintConfig) { c.Kinds = []string{"type", "chaining"} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.inlayHint(&config.LSP.InlayHintConfig)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	override := &ServerConfig{LSP: LSPConfig{InlayHintConfig: InlayHintConfig{LineInterval: 5, Resolve: true}}}
+	merged := mergeConfigs(DefaultConfig(), override)
+	if merged.LSP.InlayHintConfig.LineInterval != 5 || merged.LSP.InlayHintConfig.WordInterval != 3 || !merged.LSP.InlayHintConfig.Resolve || len(merged.LSP.InlayHintConfig.Kinds) != 2 {
+		t.Errorf("Expected the inlay hint config to be merged from override, got %+v", merged.LSP.InlayHintConfig)
+	}
+}
+
+func TestMemoryPressureValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		pressure func(*MemoryPressureConfig)
+		wantErr  bool
+	}{
+		{"monitoring off", func(*MemoryPressureConfig) {}, false},
+		{"heap limit", func(c *MemoryPressureConfig) { c.HeapLimitMB = 512 }, false},
+		{"refuse every response", func(c *MemoryPressureConfig) { c.HeapLimitMB, c.MaxResponseBytes = 512, 0 }, false},
+		{"negative heap limit", func(c *MemoryPressureConfig) { c.HeapLimitMB = -1 }, true},
+		{"negative response size", func(c *MemoryPressureConfig) { c.MaxResponseBytes = -1 }, true},
+		{"interval too short", func(c *MemoryPressureConfig) { c.HeapLimitMB, c.Interval = 512, Duration(time.Millisecond) }, true},
+		{"interval unused", func(c *MemoryPressureConfig) { c.Interval = 0 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.pressure(&config.LSP.MemoryPressure)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{LSP: LSPConfig{MemoryPressure: MemoryPressureConfig{HeapLimitMB: 256}}})
+	if merged.LSP.MemoryPressure.HeapLimitMB != 256 || merged.LSP.MemoryPressure.MaxResponseBytes != 64*1024 || merged.LSP.MemoryPressure.Interval != Duration(time.Second) {
+		t.Errorf("Expected the memory pressure config to be merged from override, got %+v", merged.LSP.MemoryPressure)
+	}
+}
+
+func TestPayloadMethodsValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.Logging.PayloadMethods = []string{"textDocument/hover", "workspace/*"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected payload methods to be valid, got %v", err)
+	}
+	config.Logging.PayloadMethods = []string{"textDocument/hover", " "}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "logging.payload_methods[1]") {
+		t.Errorf("Expected an error for the empty payload method, got %v", err)
+	}
+
+	merged := mergeConfigs(DefaultConfig(), &ServerConfig{Logging: LoggingConfig{PayloadMethods: []string{"*"}}})
+	if len(merged.Logging.PayloadMethods) != 1 || merged.Logging.PayloadMethods[0] != "*" {
+		t.Errorf("Expected the payload methods to be merged from override, got %v", merged.Logging.PayloadMethods)
+	}
+}
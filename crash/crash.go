@@ -0,0 +1,81 @@
+// Package crash writes postmortem reports when the server panics or fails
+// fatally, so failures in CI can be debugged after the process is gone.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// stackBufferSize bounds the goroutine dump captured in a report
+const stackBufferSize = 1 << 20
+
+// Report is the content of a crash report file
+type Report struct {
+	Time      time.Time      `json:"time"`
+	AppName   string         `json:"app_name"`
+	Reason    string         `json:"reason"`
+	PID       int            `json:"pid"`
+	Args      []string       `json:"args"`
+	GoVersion string         `json:"go_version"`
+	Platform  string         `json:"platform"`
+	Stack     string         `json:"stack"`
+	Config    map[string]any `json:"config,omitempty"`
+	RecentLog []string       `json:"recent_log,omitempty"`
+}
+
+// NewReport creates a report for the given reason, capturing the stacks of
+// all goroutines at the time of the call
+func NewReport(appName, reason string) *Report {
+	stack := make([]byte, stackBufferSize)
+	stack = stack[:runtime.Stack(stack, true)]
+
+	return &Report{
+		Time:      time.Now(),
+		AppName:   appName,
+		Reason:    reason,
+		PID:       os.Getpid(),
+		Args:      os.Args,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Stack:     string(stack),
+		Config:    make(map[string]any),
+	}
+}
+
+// AddConfig adds a snapshot of a configuration to the report under name
+func (r *Report) AddConfig(name string, config any) {
+	r.Config[name] = config
+}
+
+// Write stores the report as JSON in dir, falling back to the system
+// temporary directory when dir cannot be written. It returns the file path.
+func (r *Report) Write(dir string) (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode crash report: %w", err)
+	}
+
+	name := fmt.Sprintf("crash-%s-%d.json", r.Time.Format("20060102T150405"), r.PID)
+	path, err := writeFile(dir, name, data)
+	if err != nil && dir != os.TempDir() {
+		path, err = writeFile(os.TempDir(), name, data)
+	}
+	return path, err
+}
+
+// writeFile writes data to name in dir, creating dir if needed
+func writeFile(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash directory %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write crash report %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,64 @@
+package crash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	report := NewReport("test-app", "panic: boom")
+	report.AddConfig("flags", map[string]string{"log": "none"})
+	report.RecentLog = []string{"first", "last"}
+
+	path, err := report.Write(dir)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("Expected report in %s, got %s", dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var written Report
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if written.Reason != "panic: boom" {
+		t.Errorf("Expected reason 'panic: boom', got %q", written.Reason)
+	}
+	if !strings.Contains(written.Stack, "TestReportWrite") {
+		t.Errorf("Expected stack to include the caller, got %q", written.Stack)
+	}
+	if len(written.RecentLog) != 2 || written.RecentLog[1] != "last" {
+		t.Errorf("Expected recent log entries, got %v", written.RecentLog)
+	}
+	if _, ok := written.Config["flags"]; !ok {
+		t.Error("Expected flags config snapshot")
+	}
+}
+
+func TestReportWriteFallsBackToTempDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	path, err := NewReport("test-app", "fatal").Write(filepath.Join(file, "cache"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	defer os.Remove(path)
+
+	if filepath.Dir(path) != os.TempDir() {
+		t.Errorf("Expected report in %s, got %s", os.TempDir(), path)
+	}
+}
@@ -142,3 +142,18 @@ func (dr *DirectoryResolver) GetConfigDirectory() (string, error) {
 
 	return dr.maybeEnsureDir(filepath.Join(xdgConfigHome, dr.appName))
 }
+
+// GetRuntimeDirectory returns appropriate directory for runtime files such as PID files
+// For root: /run/{appName}
+// For regular users: $XDG_RUNTIME_DIR/{appName}, or {tmp}/{appName}-{uid} when it is unset
+func (dr *DirectoryResolver) GetRuntimeDirectory() (string, error) {
+	if dr.isRoot(dr.user) {
+		return dr.maybeEnsureDir(filepath.Join("/", "run", dr.appName))
+	}
+
+	if xdgRuntimeDir := os.Getenv("XDG_RUNTIME_DIR"); xdgRuntimeDir != "" && runtime.GOOS != "windows" {
+		return dr.maybeEnsureDir(filepath.Join(xdgRuntimeDir, dr.appName))
+	}
+
+	return dr.maybeEnsureDir(filepath.Join(os.TempDir(), fmt.Sprintf("%s-%s", dr.appName, dr.user.Uid)))
+}
@@ -1,6 +1,7 @@
 package directories
 
 import (
+	"os"
 	"os/user"
 	"path/filepath"
 	"testing"
@@ -218,3 +219,67 @@ func TestDirectoryResolver_GetConfigDirectory(t *testing.T) {
 		})
 	}
 }
+
+func TestDirectoryResolver_GetRuntimeDirectory(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		// Named input parameters for receiver constructor.
+		appName         string
+		user            *user.User
+		xdgRuntimeDir   string
+		shouldEnsureDir bool
+		want            string
+		wantErr         bool
+	}{
+		{
+			name:    "root",
+			appName: "test",
+			user: &user.User{
+				Uid: "0",
+			},
+			shouldEnsureDir: false,
+			want:            "/run/test",
+			wantErr:         false,
+		},
+		{
+			name:    "regular user",
+			appName: "test",
+			user: &user.User{
+				Uid: "1000",
+			},
+			xdgRuntimeDir:   "/run/user/1000",
+			shouldEnsureDir: false,
+			want:            filepath.Join("/run/user/1000", "test"),
+			wantErr:         false,
+		},
+		{
+			name:    "regular user without XDG_RUNTIME_DIR",
+			appName: "test",
+			user: &user.User{
+				Uid: "1000",
+			},
+			shouldEnsureDir: false,
+			want:            filepath.Join(os.TempDir(), "test-1000"),
+			wantErr:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_RUNTIME_DIR", tt.xdgRuntimeDir)
+			dr := NewDirectoryResolver(tt.appName, tt.user, tt.shouldEnsureDir)
+			got, gotErr := dr.GetRuntimeDirectory()
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetRuntimeDirectory() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetRuntimeDirectory() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("GetRuntimeDirectory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
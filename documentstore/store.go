@@ -0,0 +1,161 @@
+// Package documentstore keeps the text documents a client has open. A Store
+// is safe for concurrent use by the handlers of a session, hands out copies
+// so callers never share a document with the store, and notifies
+// subscribers of every change after it was made.
+package documentstore
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// Kinds of change reported to subscribers
+const (
+	Opened  = "opened"  // Put a document, new or replacing an open one
+	Changed = "changed" // Updated an open document
+	Closed  = "closed"  // Deleted an open document
+)
+
+// Event is a change made to the store
+type Event struct {
+	Kind            string                    // Opened, Changed or Closed
+	Document        protocol.TextDocumentItem // The document after the change, or as it was when closed
+	PreviousVersion int32                     // Version before a change, 0 when opened
+}
+
+// Store holds the open documents by URI
+type Store struct {
+	mu          sync.RWMutex
+	documents   map[string]protocol.TextDocumentItem
+	subscribers map[int]func(Event)
+	nextID      int
+}
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		documents:   make(map[string]protocol.TextDocumentItem),
+		subscribers: make(map[int]func(Event)),
+	}
+}
+
+// Get returns the document open at uri
+func (s *Store) Get(uri string) (protocol.TextDocumentItem, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.documents[uri]
+	return doc, ok
+}
+
+// Put opens doc under its URI, replacing a document open there
+func (s *Store) Put(doc protocol.TextDocumentItem) {
+	s.mu.Lock()
+	s.documents[string(doc.Uri)] = doc
+	s.mu.Unlock()
+	s.notify(Event{Kind: Opened, Document: doc})
+}
+
+// Update changes the document open at uri with update, which is called with
+// a copy the store then keeps, and returns the updated document. Nothing is
+// changed and false is returned when no document is open at uri.
+func (s *Store) Update(uri string, update func(doc *protocol.TextDocumentItem)) (protocol.TextDocumentItem, bool) {
+	s.mu.Lock()
+	doc, ok := s.documents[uri]
+	if !ok {
+		s.mu.Unlock()
+		return protocol.TextDocumentItem{}, false
+	}
+	previous := doc.Version
+	update(&doc)
+	doc.Uri = protocol.DocumentUri(uri)
+	s.documents[uri] = doc
+	s.mu.Unlock()
+	s.notify(Event{Kind: Changed, Document: doc, PreviousVersion: previous})
+	return doc, true
+}
+
+// Delete closes the document open at uri and reports whether there was one
+func (s *Store) Delete(uri string) bool {
+	s.mu.Lock()
+	doc, ok := s.documents[uri]
+	delete(s.documents, uri)
+	s.mu.Unlock()
+	if ok {
+		s.notify(Event{Kind: Closed, Document: doc, PreviousVersion: doc.Version})
+	}
+	return ok
+}
+
+// List returns the open documents sorted by URI
+func (s *Store) List() []protocol.TextDocumentItem {
+	s.mu.RLock()
+	documents := slices.Collect(maps.Values(s.documents))
+	s.mu.RUnlock()
+	slices.SortFunc(documents, func(a, b protocol.TextDocumentItem) int {
+		return cmp.Compare(a.Uri, b.Uri)
+	})
+	return documents
+}
+
+// Len returns the number of open documents
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.documents)
+}
+
+// Texts returns the text of every open document by URI
+func (s *Store) Texts() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	texts := make(map[string]string, len(s.documents))
+	for uri, doc := range s.documents {
+		texts[uri] = doc.Text
+	}
+	return texts
+}
+
+// Versions returns the version of every open document by URI
+func (s *Store) Versions() map[string]int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make(map[string]int32, len(s.documents))
+	for uri, doc := range s.documents {
+		versions[uri] = doc.Version
+	}
+	return versions
+}
+
+// Subscribe calls fn with every change made to the store from now on, and
+// returns a function ending the subscription. fn runs on the goroutine
+// making the change, once the store is unlocked, so it may use the store.
+func (s *Store) Subscribe(fn func(Event)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// notify calls the subscribers with event, in the order they subscribed
+func (s *Store) notify(event Event) {
+	s.mu.RLock()
+	ids := slices.Sorted(maps.Keys(s.subscribers))
+	subscribers := make([]func(Event), len(ids))
+	for i, id := range ids {
+		subscribers[i] = s.subscribers[id]
+	}
+	s.mu.RUnlock()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
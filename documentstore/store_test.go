@@ -0,0 +1,138 @@
+package documentstore
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// document returns an open document at uri
+func document(uri, text string, version int32) protocol.TextDocumentItem {
+	return protocol.TextDocumentItem{Uri: protocol.DocumentUri(uri), LanguageId: "go", Version: version, Text: text}
+}
+
+func TestStore(t *testing.T) {
+	store := New()
+	if _, ok := store.Get("file:///a.go"); ok || store.Len() != 0 {
+		t.Fatal("Expected an empty store")
+	}
+
+	store.Put(document("file:///b.go", "package b", 1))
+	store.Put(document("file:///a.go", "package a", 3))
+	if doc, ok := store.Get("file:///a.go"); !ok || doc.Text != "package a" || doc.Version != 3 {
+		t.Errorf("Get() = %+v, %v", doc, ok)
+	}
+
+	updated, ok := store.Update("file:///a.go", func(doc *protocol.TextDocumentItem) {
+		doc.Text += "\n"
+		doc.Version = 4
+		doc.Uri = "file:///moved.go"
+	})
+	if !ok || updated.Text != "package a\n" || updated.Uri != "file:///a.go" {
+		t.Errorf("Expected the update to keep the URI, got %+v, %v", updated, ok)
+	}
+	if _, ok := store.Update("file:///missing.go", func(*protocol.TextDocumentItem) { t.Error("Expected no update of a missing document") }); ok {
+		t.Error("Expected no update of a missing document")
+	}
+
+	if want := map[string]int32{"file:///a.go": 4, "file:///b.go": 1}; !maps.Equal(store.Versions(), want) {
+		t.Errorf("Versions() = %v, want %v", store.Versions(), want)
+	}
+	if want := map[string]string{"file:///a.go": "package a\n", "file:///b.go": "package b"}; !maps.Equal(store.Texts(), want) {
+		t.Errorf("Texts() = %v, want %v", store.Texts(), want)
+	}
+	if list := store.List(); len(list) != 2 || list[0].Uri != "file:///a.go" || list[1].Uri != "file:///b.go" {
+		t.Errorf("Expected the documents sorted by URI, got %+v", list)
+	}
+
+	if !store.Delete("file:///a.go") || store.Delete("file:///a.go") {
+		t.Error("Expected only the first delete to find the document")
+	}
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 document left, got %d", store.Len())
+	}
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	store := New()
+	store.Put(document("file:///a.go", "package a", 1))
+
+	doc, _ := store.Get("file:///a.go")
+	doc.Text = "changed"
+	for _, listed := range store.List() {
+		listed.Text = "changed"
+	}
+	if doc, _ := store.Get("file:///a.go"); doc.Text != "package a" {
+		t.Errorf("Expected the stored document to be unchanged, got %q", doc.Text)
+	}
+}
+
+func TestStoreSubscribe(t *testing.T) {
+	store := New()
+	var events []string
+	unsubscribe := store.Subscribe(func(event Event) {
+		// Subscribers may use the store
+		doc, open := store.Get(string(event.Document.Uri))
+		events = append(events, fmt.Sprintf("%s %s v%d from v%d open=%v %q", event.Kind, event.Document.Uri, event.Document.Version, event.PreviousVersion, open, doc.Text))
+	})
+
+	store.Put(document("file:///a.go", "a", 1))
+	store.Update("file:///a.go", func(doc *protocol.TextDocumentItem) {
+		doc.Text = "ab"
+		doc.Version = 2
+	})
+	store.Update("file:///missing.go", func(*protocol.TextDocumentItem) {})
+	store.Delete("file:///a.go")
+	store.Delete("file:///a.go")
+	unsubscribe()
+	store.Put(document("file:///b.go", "b", 1))
+
+	want := []string{
+		`opened file:///a.go v1 from v0 open=true "a"`,
+		`changed file:///a.go v2 from v1 open=true "ab"`,
+		`closed file:///a.go v2 from v2 open=false ""`,
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %q, got %q", want, events)
+	}
+}
+
+func TestStoreConcurrentAccess(t *testing.T) {
+	store := New()
+	var opened atomic.Int64
+	store.Subscribe(func(event Event) {
+		if event.Kind == Opened {
+			opened.Add(1)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uri := fmt.Sprintf("file:///doc%d.go", i%5)
+			store.Put(document(uri, "", 1))
+			for range 50 {
+				store.Update(uri, func(doc *protocol.TextDocumentItem) {
+					doc.Text += "x"
+					doc.Version++
+				})
+				store.List()
+				store.Versions()
+			}
+			store.Delete(uri)
+		}()
+	}
+	wg.Wait()
+	if store.Len() != 0 {
+		t.Errorf("Expected every document to be closed, got %d", store.Len())
+	}
+	if opened.Load() != 20 {
+		t.Errorf("Expected 20 opened events, got %d", opened.Load())
+	}
+}
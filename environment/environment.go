@@ -0,0 +1,164 @@
+// Package environment detects containerized runtime environments and adjusts
+// the defaults that do not suit them, so Docker-based test rigs need less
+// configuration.
+package environment
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"mock-lsp-server/logging"
+)
+
+// containerCgroupMarkers appear in the cgroup of PID 1 inside containers
+var containerCgroupMarkers = []string{"docker", "kubepods", "containerd", "lxc", "podman", "libpod"}
+
+// Environment describes the properties of the runtime environment that
+// affect directory resolution and logging defaults
+type Environment struct {
+	Container bool // Running inside a container
+	PID1      bool // Running as the init process, which gets no default signal handling
+	NoHome    bool // The user has no usable home directory
+	ReadOnly  bool // The default data location is not writable
+
+	findings []string
+}
+
+// probes gives detect access to the system, so tests can fake it
+type probes struct {
+	pid      int
+	getenv   func(string) string
+	exists   func(string) bool
+	readFile func(string) ([]byte, error)
+	writable func(string) bool
+}
+
+// Detect inspects the environment of the current process for u, which may
+// be nil when the current user cannot be determined
+func Detect(u *user.User) *Environment {
+	return detect(u, probes{
+		pid:      os.Getpid(),
+		getenv:   os.Getenv,
+		exists:   exists,
+		readFile: os.ReadFile,
+		writable: writable,
+	})
+}
+
+// detect implements Detect on top of the given probes
+func detect(u *user.User, p probes) *Environment {
+	env := &Environment{}
+
+	switch {
+	case p.exists("/.dockerenv"):
+		env.Container = true
+		env.findings = append(env.findings, "container detected (/.dockerenv)")
+	case p.exists("/run/.containerenv"):
+		env.Container = true
+		env.findings = append(env.findings, "container detected (/run/.containerenv)")
+	case p.getenv("container") != "":
+		env.Container = true
+		env.findings = append(env.findings, fmt.Sprintf("container detected (container=%s)", p.getenv("container")))
+	case p.getenv("KUBERNETES_SERVICE_HOST") != "":
+		env.Container = true
+		env.findings = append(env.findings, "container detected (Kubernetes)")
+	default:
+		if cgroup, err := p.readFile("/proc/1/cgroup"); err == nil {
+			for _, marker := range containerCgroupMarkers {
+				if strings.Contains(string(cgroup), marker) {
+					env.Container = true
+					env.findings = append(env.findings, fmt.Sprintf("container detected (%s cgroup)", marker))
+					break
+				}
+			}
+		}
+	}
+
+	if p.pid == 1 {
+		env.PID1 = true
+		env.findings = append(env.findings, "running as PID 1; SIGTERM and SIGINT shut the server down")
+	}
+
+	if u == nil || u.HomeDir == "" || u.HomeDir == "/" || !p.exists(u.HomeDir) {
+		env.NoHome = true
+		env.findings = append(env.findings, "no usable home directory")
+	}
+
+	dataRoot := ""
+	switch {
+	case u != nil && u.Uid == "0":
+		dataRoot = "/var"
+	case !env.NoHome:
+		dataRoot = u.HomeDir
+	}
+	if dataRoot != "" && !p.writable(dataRoot) {
+		env.ReadOnly = true
+		env.findings = append(env.findings, fmt.Sprintf("%s is not writable", dataRoot))
+	}
+
+	return env
+}
+
+// Findings describes what was detected, one entry per finding
+func (e *Environment) Findings() []string {
+	return e.findings
+}
+
+// DefaultLogOutput returns the log output to use when none was requested:
+// stderr inside containers and wherever files cannot be written, otherwise
+// a log file
+func (e *Environment) DefaultLogOutput() string {
+	if e.Container || e.NoHome || e.ReadOnly {
+		return logging.OutputStderr
+	}
+	return logging.OutputFile
+}
+
+// ConfigureDirectories points the XDG base directories that are not already
+// set at a per-user directory under the system temporary directory when the
+// user has no usable or writable home. Root keeps its system directories.
+// It returns the base directory used, or an empty string if nothing changed.
+func (e *Environment) ConfigureDirectories(appName string, u *user.User) string {
+	if !e.NoHome && !e.ReadOnly {
+		return ""
+	}
+	uid := "unknown"
+	if u != nil {
+		if u.Uid == "0" {
+			return ""
+		}
+		uid = u.Uid
+	}
+
+	base := filepath.Join(os.TempDir(), fmt.Sprintf("%s-%s", appName, uid))
+	for variable, dir := range map[string]string{
+		"XDG_DATA_HOME":   "share",
+		"XDG_CONFIG_HOME": "config",
+		"XDG_CACHE_HOME":  "cache",
+	} {
+		if os.Getenv(variable) == "" {
+			os.Setenv(variable, filepath.Join(base, dir))
+		}
+	}
+	return base
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writable reports whether a file can be created in dir
+func writable(dir string) bool {
+	file, err := os.CreateTemp(dir, ".write-probe-*")
+	if err != nil {
+		return false
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return true
+}
@@ -0,0 +1,174 @@
+package environment
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mock-lsp-server/logging"
+)
+
+// fakeProbes returns probes for a system where only the given paths exist
+// and are writable
+func fakeProbes(pid int, env map[string]string, paths []string, cgroup string) probes {
+	has := func(path string) bool {
+		for _, p := range paths {
+			if p == path {
+				return true
+			}
+		}
+		return false
+	}
+	return probes{
+		pid:    pid,
+		getenv: func(key string) string { return env[key] },
+		exists: has,
+		readFile: func(path string) ([]byte, error) {
+			if path == "/proc/1/cgroup" && cgroup != "" {
+				return []byte(cgroup), nil
+			}
+			return nil, errors.New("not found")
+		},
+		writable: has,
+	}
+}
+
+func TestDetect(t *testing.T) {
+	regular := &user.User{Uid: "1000", HomeDir: "/home/test"}
+	root := &user.User{Uid: "0", HomeDir: "/root"}
+
+	tests := []struct {
+		name   string
+		u      *user.User
+		probes probes
+		want   Environment
+	}{
+		{
+			name:   "host",
+			u:      regular,
+			probes: fakeProbes(42, nil, []string{"/home/test"}, "0::/user.slice"),
+			want:   Environment{},
+		},
+		{
+			name:   "docker",
+			u:      root,
+			probes: fakeProbes(1, nil, []string{"/.dockerenv", "/root", "/var"}, ""),
+			want:   Environment{Container: true, PID1: true},
+		},
+		{
+			name:   "podman",
+			u:      regular,
+			probes: fakeProbes(7, nil, []string{"/run/.containerenv", "/home/test"}, ""),
+			want:   Environment{Container: true},
+		},
+		{
+			name:   "container variable",
+			u:      regular,
+			probes: fakeProbes(7, map[string]string{"container": "systemd-nspawn"}, []string{"/home/test"}, ""),
+			want:   Environment{Container: true},
+		},
+		{
+			name:   "kubernetes cgroup",
+			u:      regular,
+			probes: fakeProbes(7, nil, []string{"/home/test"}, "0::/kubepods/besteffort/pod1"),
+			want:   Environment{Container: true},
+		},
+		{
+			name:   "no home",
+			u:      &user.User{Uid: "1000", HomeDir: "/nonexistent"},
+			probes: fakeProbes(7, nil, nil, ""),
+			want:   Environment{NoHome: true},
+		},
+		{
+			name:   "unknown user",
+			u:      nil,
+			probes: fakeProbes(7, nil, nil, ""),
+			want:   Environment{NoHome: true},
+		},
+		{
+			name: "read-only home",
+			u:    regular,
+			probes: func() probes {
+				p := fakeProbes(7, nil, []string{"/home/test"}, "")
+				p.writable = func(string) bool { return false }
+				return p
+			}(),
+			want: Environment{ReadOnly: true},
+		},
+		{
+			name:   "read-only root",
+			u:      root,
+			probes: fakeProbes(7, nil, []string{"/root"}, ""),
+			want:   Environment{ReadOnly: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detect(tt.u, tt.probes)
+			if got.Container != tt.want.Container || got.PID1 != tt.want.PID1 || got.NoHome != tt.want.NoHome || got.ReadOnly != tt.want.ReadOnly {
+				t.Errorf("Expected %+v, got %+v", tt.want, *got)
+			}
+			detected := 0
+			for _, flag := range []bool{got.Container, got.PID1, got.NoHome, got.ReadOnly} {
+				if flag {
+					detected++
+				}
+			}
+			if len(got.Findings()) != detected {
+				t.Errorf("Expected %d findings, got %v", detected, got.Findings())
+			}
+		})
+	}
+}
+
+func TestEnvironment_DefaultLogOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		env  Environment
+		want string
+	}{
+		{"host", Environment{}, logging.OutputFile},
+		{"PID 1 only", Environment{PID1: true}, logging.OutputFile},
+		{"container", Environment{Container: true}, logging.OutputStderr},
+		{"no home", Environment{NoHome: true}, logging.OutputStderr},
+		{"read-only", Environment{ReadOnly: true}, logging.OutputStderr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.env.DefaultLogOutput(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEnvironment_ConfigureDirectories(t *testing.T) {
+	variables := []string{"XDG_DATA_HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME"}
+	for _, variable := range variables {
+		t.Setenv(variable, "")
+	}
+	t.Setenv("XDG_CONFIG_HOME", "/custom/config")
+
+	env := &Environment{NoHome: true}
+	base := env.ConfigureDirectories("test", &user.User{Uid: "1000"})
+
+	wantBase := filepath.Join(os.TempDir(), "test-1000")
+	if base != wantBase {
+		t.Errorf("Expected base %s, got %s", wantBase, base)
+	}
+	got := []string{os.Getenv("XDG_DATA_HOME"), os.Getenv("XDG_CONFIG_HOME"), os.Getenv("XDG_CACHE_HOME")}
+	want := []string{filepath.Join(wantBase, "share"), "/custom/config", filepath.Join(wantBase, "cache")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if base := (&Environment{}).ConfigureDirectories("test", &user.User{Uid: "1000"}); base != "" {
+		t.Errorf("Expected no change with a usable home, got %s", base)
+	}
+	if base := (&Environment{NoHome: true}).ConfigureDirectories("test", &user.User{Uid: "0"}); base != "" {
+		t.Errorf("Expected no change for root, got %s", base)
+	}
+}
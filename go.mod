@@ -5,4 +5,6 @@ go 1.24.2
 require (
 	github.com/myleshyson/lsprotocol-go v1.0.0 // direct
 	github.com/sourcegraph/jsonrpc2 v0.2.1 // direct
+	github.com/mattn/go-sqlite3 v1.14.33 // direct
+	gopkg.in/yaml.v3 v3.0.1 // direct
 )
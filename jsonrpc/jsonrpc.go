@@ -0,0 +1,166 @@
+// Package jsonrpc is the JSON-RPC 2.0 layer the server is written against.
+// It defines the messages, the connection and the handler interface, so the
+// lsp package does not depend on a JSON-RPC implementation. NewConn provides
+// connections backed by sourcegraph/jsonrpc2 over any ObjectStream, such as
+// the streams of the transport package.
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ID is the ID of a request, either a number or a string
+type ID struct {
+	Num uint64
+	Str string
+	// IsString selects Str as the ID; it must be set for string IDs
+	IsString bool
+}
+
+// String returns the ID as it appears on the wire
+func (id ID) String() string {
+	if id.IsString {
+		return strconv.Quote(id.Str)
+	}
+	return strconv.FormatUint(id.Num, 10)
+}
+
+// MarshalJSON implements json.Marshaler
+func (id ID) MarshalJSON() ([]byte, error) {
+	if id.IsString {
+		return json.Marshal(id.Str)
+	}
+	return json.Marshal(id.Num)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting number and string IDs
+func (id *ID) UnmarshalJSON(data []byte) error {
+	var num uint64
+	if err := json.Unmarshal(data, &num); err == nil {
+		*id = ID{Num: num}
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*id = ID{Str: str, IsString: true}
+	return nil
+}
+
+// Request is a request, or a notification when Notif is set
+type Request struct {
+	Method string
+	Params *json.RawMessage
+	ID     ID
+	Notif  bool
+}
+
+// Response is the response to a request, carrying a result or an error
+type Response struct {
+	ID     ID
+	Result *json.RawMessage
+	Error  *Error
+}
+
+// Error is the error of a response
+type Error struct {
+	Code    int64            `json:"code"`
+	Message string           `json:"message"`
+	Data    *json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: code %v message: %s", e.Code, e.Message)
+}
+
+// Error codes defined by the JSON-RPC specification
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// ErrClosed is returned when sending through a closed connection
+var ErrClosed = errors.New("jsonrpc: connection is closed")
+
+// CallOption configures a request or notification sent through a Conn
+type CallOption func(*callOptions)
+
+// callOptions are the options of a request
+type callOptions struct {
+	id *ID
+}
+
+// PickID sends the request with id instead of the next ID of the connection
+func PickID(id ID) CallOption {
+	return func(o *callOptions) { o.id = &id }
+}
+
+// Conn is a JSON-RPC connection
+type Conn interface {
+	// Reply sends the result of the request id
+	Reply(ctx context.Context, id ID, result any) error
+	// ReplyWithError sends the error of the request id
+	ReplyWithError(ctx context.Context, id ID, respErr *Error) error
+	// Notify sends a notification
+	Notify(ctx context.Context, method string, params any, opts ...CallOption) error
+	// Call sends a request and decodes its result into result. An error
+	// response is returned as an *Error.
+	Call(ctx context.Context, method string, params, result any, opts ...CallOption) error
+	// DisconnectNotify returns a channel closed when the connection closes
+	DisconnectNotify() <-chan struct{}
+	// Close closes the connection
+	Close() error
+}
+
+// Handler handles the requests and notifications read from a connection.
+// Handle is called for one message at a time, in the order they are read,
+// and must reply to requests itself.
+type Handler interface {
+	Handle(ctx context.Context, conn Conn, req *Request)
+}
+
+// HandlerFunc is a Handler function
+type HandlerFunc func(ctx context.Context, conn Conn, req *Request)
+
+// Handle calls f
+func (f HandlerFunc) Handle(ctx context.Context, conn Conn, req *Request) {
+	f(ctx, conn, req)
+}
+
+// HandlerWithError returns a Handler replying to requests with what handle
+// returns. An *Error is sent as is and other errors as their message.
+// Notifications get no reply.
+func HandlerWithError(handle func(ctx context.Context, conn Conn, req *Request) (any, error)) Handler {
+	return HandlerFunc(func(ctx context.Context, conn Conn, req *Request) {
+		result, err := handle(ctx, conn, req)
+		if req.Notif {
+			return
+		}
+		if err == nil {
+			conn.Reply(ctx, req.ID, result)
+			return
+		}
+		respErr, ok := err.(*Error)
+		if !ok {
+			respErr = &Error{Message: err.Error()}
+		}
+		conn.ReplyWithError(ctx, req.ID, respErr)
+	})
+}
+
+// AsyncHandler returns a Handler handling every message in a goroutine of
+// its own, so messages are no longer handled in order
+func AsyncHandler(handler Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, conn Conn, req *Request) {
+		go handler.Handle(ctx, conn, req)
+	})
+}
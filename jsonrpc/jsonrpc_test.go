@@ -0,0 +1,36 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIDJSON(t *testing.T) {
+	tests := []struct {
+		id   ID
+		wire string
+	}{
+		{ID{Num: 7}, `7`},
+		{ID{}, `0`},
+		{ID{Str: "abc", IsString: true}, `"abc"`},
+		{ID{IsString: true}, `""`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.id)
+		if err != nil || string(data) != tt.wire {
+			t.Errorf("Marshal(%#v) = %s (%v), want %s", tt.id, data, err, tt.wire)
+		}
+		var id ID
+		if err := json.Unmarshal([]byte(tt.wire), &id); err != nil || id != tt.id {
+			t.Errorf("Unmarshal(%s) = %#v (%v), want %#v", tt.wire, id, err, tt.id)
+		}
+		if id.String() != tt.wire {
+			t.Errorf("String() = %s, want %s", id.String(), tt.wire)
+		}
+	}
+
+	var id ID
+	if err := json.Unmarshal([]byte(`{}`), &id); err == nil {
+		t.Error("Expected an object ID to be rejected")
+	}
+}
@@ -0,0 +1,166 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ObjectStream reads and writes JSON-RPC messages. It is the method set of
+// jsonrpc2.ObjectStream and transport.ObjectStream.
+type ObjectStream interface {
+	WriteObject(obj any) error
+	ReadObject(v any) error
+	Close() error
+}
+
+// NewStream returns the stream of sourcegraph/jsonrpc2 over rwc, framing
+// messages with Content-Length headers
+func NewStream(rwc io.ReadWriteCloser) ObjectStream {
+	return jsonrpc2.NewBufferedStream(rwc, jsonrpc2.VSCodeObjectCodec{})
+}
+
+// ConnOpt configures a connection created by NewConn
+type ConnOpt func(*connOptions)
+
+// connOptions are the options of a connection created by NewConn
+type connOptions struct {
+	onRecv []func(*Request, *Response)
+	onSend []func(*Request, *Response)
+	logger *log.Logger
+}
+
+// OnRecv calls f with every request or notification read, and with every
+// response read along with the request it answers
+func OnRecv(f func(req *Request, resp *Response)) ConnOpt {
+	return func(o *connOptions) { o.onRecv = append(o.onRecv, f) }
+}
+
+// OnSend calls f with every request, notification or response sent; the
+// request is nil for responses
+func OnSend(f func(req *Request, resp *Response)) ConnOpt {
+	return func(o *connOptions) { o.onSend = append(o.onSend, f) }
+}
+
+// SetLogger logs the errors of the connection to logger
+func SetLogger(logger *log.Logger) ConnOpt {
+	return func(o *connOptions) { o.logger = logger }
+}
+
+// NewConn creates a connection backed by sourcegraph/jsonrpc2 that reads
+// messages from stream and dispatches them to handler until the stream
+// fails or the connection is closed
+func NewConn(ctx context.Context, stream ObjectStream, handler Handler, opts ...ConnOpt) Conn {
+	var options connOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var connOpts []jsonrpc2.ConnOpt
+	if options.logger != nil {
+		connOpts = append(connOpts, jsonrpc2.SetLogger(options.logger))
+	}
+	for _, f := range options.onRecv {
+		connOpts = append(connOpts, jsonrpc2.OnRecv(func(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+			f(fromRequest(req), fromResponse(resp))
+		}))
+	}
+	for _, f := range options.onSend {
+		connOpts = append(connOpts, jsonrpc2.OnSend(func(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+			f(fromRequest(req), fromResponse(resp))
+		}))
+	}
+	return sourcegraphConn{jsonrpc2.NewConn(ctx, stream, sourcegraphHandler{handler}, connOpts...)}
+}
+
+// sourcegraphHandler passes the messages of a jsonrpc2 connection to a
+// Handler
+type sourcegraphHandler struct {
+	handler Handler
+}
+
+// Handle implements jsonrpc2.Handler
+func (h sourcegraphHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	h.handler.Handle(ctx, sourcegraphConn{conn}, fromRequest(req))
+}
+
+// sourcegraphConn is a Conn backed by a jsonrpc2 connection. It is a
+// comparable value, equal for the same connection, so it can key
+// per-connection state.
+type sourcegraphConn struct {
+	conn *jsonrpc2.Conn
+}
+
+// Reply implements Conn
+func (c sourcegraphConn) Reply(ctx context.Context, id ID, result any) error {
+	return closedError(c.conn.Reply(ctx, jsonrpc2.ID(id), result))
+}
+
+// ReplyWithError implements Conn
+func (c sourcegraphConn) ReplyWithError(ctx context.Context, id ID, respErr *Error) error {
+	return closedError(c.conn.ReplyWithError(ctx, jsonrpc2.ID(id), (*jsonrpc2.Error)(respErr)))
+}
+
+// Notify implements Conn
+func (c sourcegraphConn) Notify(ctx context.Context, method string, params any, opts ...CallOption) error {
+	return closedError(c.conn.Notify(ctx, method, params, sourcegraphCallOptions(opts)...))
+}
+
+// Call implements Conn
+func (c sourcegraphConn) Call(ctx context.Context, method string, params, result any, opts ...CallOption) error {
+	err := c.conn.Call(ctx, method, params, result, sourcegraphCallOptions(opts)...)
+	var respErr *jsonrpc2.Error
+	if errors.As(err, &respErr) {
+		return (*Error)(respErr)
+	}
+	return closedError(err)
+}
+
+// DisconnectNotify implements Conn
+func (c sourcegraphConn) DisconnectNotify() <-chan struct{} {
+	return c.conn.DisconnectNotify()
+}
+
+// Close implements Conn
+func (c sourcegraphConn) Close() error {
+	return closedError(c.conn.Close())
+}
+
+// closedError replaces jsonrpc2.ErrClosed with ErrClosed
+func closedError(err error) error {
+	if errors.Is(err, jsonrpc2.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
+
+// sourcegraphCallOptions converts call options to those of jsonrpc2
+func sourcegraphCallOptions(opts []CallOption) []jsonrpc2.CallOption {
+	var options callOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.id == nil {
+		return nil
+	}
+	return []jsonrpc2.CallOption{jsonrpc2.PickID(jsonrpc2.ID(*options.id))}
+}
+
+// fromRequest converts a jsonrpc2 request, keeping nil
+func fromRequest(req *jsonrpc2.Request) *Request {
+	if req == nil {
+		return nil
+	}
+	return &Request{Method: req.Method, Params: req.Params, ID: ID(req.ID), Notif: req.Notif}
+}
+
+// fromResponse converts a jsonrpc2 response, keeping nil
+func fromResponse(resp *jsonrpc2.Response) *Response {
+	if resp == nil {
+		return nil
+	}
+	return &Response{ID: ID(resp.ID), Result: resp.Result, Error: (*Error)(resp.Error)}
+}
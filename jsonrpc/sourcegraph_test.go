@@ -0,0 +1,93 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// connPair connects a client to a server handled by handler
+func connPair(t *testing.T, handler Handler, opts ...ConnOpt) Conn {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+	server := NewConn(ctx, NewStream(serverSide), handler, opts...)
+	client := NewConn(ctx, NewStream(clientSide), HandlerWithError(func(context.Context, Conn, *Request) (any, error) {
+		return nil, nil
+	}))
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+func TestConnCall(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	record := func(req *Request, resp *Response) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case resp != nil:
+			received = append(received, "response "+resp.ID.String())
+		case req.Notif:
+			received = append(received, "notification "+req.Method)
+		default:
+			received = append(received, "request "+req.ID.String()+" "+req.Method)
+		}
+	}
+	client := connPair(t, HandlerWithError(func(_ context.Context, _ Conn, req *Request) (any, error) {
+		if req.Method == "fail" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "bad params"}
+		}
+		return req.Method, nil
+	}), OnRecv(record), OnSend(record))
+	ctx := context.Background()
+
+	var result string
+	if err := client.Call(ctx, "echo", nil, &result, PickID(ID{Str: "picked", IsString: true})); err != nil || result != "echo" {
+		t.Errorf("Call = %q (%v), want echo", result, err)
+	}
+	var respErr *Error
+	if err := client.Call(ctx, "fail", nil, nil); !errors.As(err, &respErr) || respErr.Code != CodeInvalidParams {
+		t.Errorf("Expected the error response as an *Error, got %v", err)
+	}
+	if err := client.Notify(ctx, "note", nil); err != nil {
+		t.Errorf("Notify failed: %v", err)
+	}
+
+	// The notification is only known to be read once a later call returns
+	if err := client.Call(ctx, "echo", nil, &result); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		`request "picked" echo`, `response "picked"`,
+		`request 1 fail`, `response 1`,
+		`notification note`,
+		`request 2 echo`, `response 2`,
+	}
+	if len(received) != len(want) {
+		t.Fatalf("Expected hooks %v, got %v", want, received)
+	}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Errorf("Hook %d = %s, want %s", i, received[i], want[i])
+		}
+	}
+}
+
+func TestConnClosed(t *testing.T) {
+	client := connPair(t, HandlerWithError(func(context.Context, Conn, *Request) (any, error) {
+		return nil, nil
+	}))
+	client.Close()
+	<-client.DisconnectNotify()
+	if err := client.Notify(context.Background(), "note", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after closing, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// compressedExt is appended to the names of compressed log files
+const compressedExt = ".gz"
+
+// gzipFile is a log file written as a gzip stream. Every write is flushed,
+// so the file stays readable up to the last entry if the process dies; the
+// compression dictionary carries over between flushes.
+type gzipFile struct {
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// Write compresses p into the file and flushes it
+func (g *gzipFile) Write(p []byte) (int, error) {
+	n, err := g.gz.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, g.gz.Flush()
+}
+
+// Close finishes the gzip stream and closes the file
+func (g *gzipFile) Close() error {
+	err := g.gz.Close()
+	if closeErr := g.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openLogStream opens path for appending, wrapped in a gzip stream when
+// compress is set. Appending to an existing compressed file adds a new gzip
+// member, which gzip readers decode as one continuous stream.
+func openLogStream(path string, compress bool) (io.WriteCloser, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	if !compress {
+		return file, nil
+	}
+	return &gzipFile{file: file, gz: gzip.NewWriter(file)}, nil
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readGzip decompresses the file at path. A stream that is still being
+// written ends without a trailer, which is not an error here.
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected a gzip stream in %s: %v", path, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Failed to decompress %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestOpenLogStream_Compressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+
+	for _, entry := range []string{"first run\n", "second run\n"} {
+		stream, err := openLogStream(path, true)
+		if err != nil {
+			t.Fatalf("openLogStream failed: %v", err)
+		}
+		if _, err := stream.Write([]byte(entry)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		// Flushed entries are readable before the stream is closed
+		if data := readGzip(t, path); !strings.HasSuffix(data, entry) {
+			t.Errorf("Expected %q to be readable before closing, got %q", entry, data)
+		}
+		if err := stream.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	if data := readGzip(t, path); data != "first run\nsecond run\n" {
+		t.Errorf("Expected both runs in the appended stream, got %q", data)
+	}
+}
+
+func TestManager_CompressedLogFile(t *testing.T) {
+	for _, daily := range []bool{false, true} {
+		dir := t.TempDir()
+		manager := NewManager("test-app", &user.User{Uid: "1000", HomeDir: dir}, true)
+		manager.config.Compress = true
+		manager.config.Daily = daily
+		if err := manager.Initialize(filepath.Join(dir, "logs"), ""); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		manager.Info("compressed entry")
+		path := manager.GetLogPath()
+		manager.Close()
+
+		want := "test-app.log.gz"
+		if daily {
+			want = datedFileName("test-app.log", time.Now()) + ".gz"
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("Expected log file %s, got %s", want, path)
+		}
+		if data := readGzip(t, path); !strings.Contains(data, "compressed entry") {
+			t.Errorf("Expected the entry in the compressed log, got %q", data)
+		}
+	}
+}
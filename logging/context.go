@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+)
+
+// fieldsKey is the context key structured log fields are stored under
+type fieldsKey struct{}
+
+// ContextWithField returns a copy of ctx carrying the log field key=value in
+// addition to the fields ctx already carries. The fields of ctx are shared
+// with the copy rather than copied.
+func ContextWithField(ctx context.Context, key string, value interface{}) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &field{key: key, value: value, next: contextFields(ctx)})
+}
+
+// contextFields returns the list of log fields carried by ctx, newest first
+func contextFields(ctx context.Context) *field {
+	fields, _ := ctx.Value(fieldsKey{}).(*field)
+	return fields
+}
+
+// FieldsFromContext returns the log fields carried by ctx, or nil if there
+// are none. A key set more than once has its newest value.
+func FieldsFromContext(ctx context.Context) map[string]interface{} {
+	head := contextFields(ctx)
+	if head == nil {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	for f := head; f != nil; f = f.next {
+		if _, ok := fields[f.key]; !ok {
+			fields[f.key] = f.value
+		}
+	}
+	return fields
+}
+
+// ForContext returns a logger that adds the log fields carried by ctx to the
+// logger's own context. Fields of ctx take precedence over the logger's.
+func (sl *StructuredLogger) ForContext(ctx context.Context) *StructuredLogger {
+	head := contextFields(ctx)
+	if head == nil {
+		return sl
+	}
+
+	// The list of ctx ends in the fields of other loggers' contexts, so its
+	// few nodes are copied in front of the logger's fields, oldest first
+	var buf [8]*field
+	nodes := buf[:0]
+	for f := head; f != nil; f = f.next {
+		nodes = append(nodes, f)
+	}
+	fields := sl.fields
+	for i := len(nodes) - 1; i >= 0; i-- {
+		fields = &field{key: nodes[i].key, value: nodes[i].value, next: fields}
+	}
+	return &StructuredLogger{manager: sl.manager, fields: fields}
+}
@@ -0,0 +1,169 @@
+package logging_test
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"strings"
+	"sync"
+	"testing"
+
+	"mock-lsp-server/logging"
+)
+
+func TestContextWithField(t *testing.T) {
+	if fields := logging.FieldsFromContext(context.Background()); fields != nil {
+		t.Errorf("Expected no fields, got %v", fields)
+	}
+
+	parent := logging.ContextWithField(context.Background(), "client_id", "client-1")
+	child := logging.ContextWithField(parent, "request_id", "7")
+
+	if fields := logging.FieldsFromContext(parent); len(fields) != 1 {
+		t.Errorf("Expected the parent context to keep 1 field, got %v", fields)
+	}
+	fields := logging.FieldsFromContext(child)
+	if fields["client_id"] != "client-1" || fields["request_id"] != "7" {
+		t.Errorf("Expected client_id and request_id fields, got %v", fields)
+	}
+}
+
+func TestStructuredLogger_ForContext(t *testing.T) {
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: t.TempDir()}, false)
+	if err := manager.SetOutput(logging.OutputNone); err != nil {
+		t.Fatalf("SetOutput failed: %v", err)
+	}
+	if err := manager.Initialize("", ""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	logger := manager.NewStructuredLogger().WithContext("component", "test")
+	if logger.ForContext(context.Background()) != logger {
+		t.Error("Expected the same logger for a context without fields")
+	}
+
+	ctx := logging.ContextWithField(context.Background(), "request_id", "7")
+	logger.ForContext(ctx).Info("handled")
+	logger.Info("plain")
+
+	entries := manager.RecentEntries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if !strings.Contains(entries[0], "request_id=7") || !strings.Contains(entries[0], "component=test") {
+		t.Errorf("Expected context and logger fields, got %q", entries[0])
+	}
+	if strings.Contains(entries[1], "request_id") {
+		t.Errorf("Expected ForContext to leave the original logger unchanged, got %q", entries[1])
+	}
+}
+
+// newDiscardManager returns an initialized manager that keeps entries in
+// memory only
+func newDiscardManager(tb testing.TB) *logging.Manager {
+	tb.Helper()
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: tb.TempDir()}, false)
+	if err := manager.SetOutput(logging.OutputNone); err != nil {
+		tb.Fatalf("SetOutput failed: %v", err)
+	}
+	if err := manager.Initialize("", ""); err != nil {
+		tb.Fatalf("Initialize failed: %v", err)
+	}
+	return manager
+}
+
+func TestStructuredLogger_FieldPrecedence(t *testing.T) {
+	manager := newDiscardManager(t)
+	base := manager.NewStructuredLogger().WithContext("component", "test").WithContext("method", "base")
+
+	ctx := logging.ContextWithField(context.Background(), "method", "old")
+	ctx = logging.ContextWithField(ctx, "method", "ctx")
+	base.ForContext(ctx).Info("from context")
+	base.ForContext(ctx).WithContext("method", "override").Info("overridden")
+	base.Info("base")
+
+	entries := manager.RecentEntries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	tests := []struct {
+		entry string
+		want  string
+	}{
+		{entries[0], "method=ctx"},
+		{entries[1], "method=override"},
+		{entries[2], "method=base"},
+	}
+	for _, tt := range tests {
+		if strings.Count(tt.entry, "method=") != 1 || !strings.Contains(tt.entry, tt.want) {
+			t.Errorf("Expected a single %s, got %q", tt.want, tt.entry)
+		}
+	}
+}
+
+func TestStructuredLogger_ConcurrentDerive(t *testing.T) {
+	manager := newDiscardManager(t)
+	base := manager.NewStructuredLogger().WithContext("component", "test")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := logging.ContextWithField(context.Background(), "request_id", i)
+			for range 50 {
+				base.ForContext(ctx).WithContext("operation", "derive").Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, entry := range manager.RecentEntries() {
+		if strings.Count(entry, "request_id=") != 1 || !strings.Contains(entry, "component=test") {
+			t.Fatalf("Expected each entry to keep its own fields, got %q", entry)
+		}
+	}
+}
+
+// benchLogger keeps derived loggers alive so benchmarks measure their cost
+var benchLogger *logging.StructuredLogger
+
+func BenchmarkStructuredLogger_WithContext(b *testing.B) {
+	logger := newDiscardManager(b).NewStructuredLogger()
+	for i := range 8 {
+		logger = logger.WithContext(fmt.Sprintf("key%d", i), i)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		benchLogger = logger.WithContext("operation", "bench")
+	}
+}
+
+func BenchmarkStructuredLogger_ForContext(b *testing.B) {
+	logger := newDiscardManager(b).NewStructuredLogger().WithContext("component", "bench").WithContext("client_id", "client-1")
+	ctx := logging.ContextWithField(context.Background(), "method", "textDocument/hover")
+	ctx = logging.ContextWithField(ctx, "request_id", "7")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		benchLogger = logger.ForContext(ctx)
+	}
+}
+
+// BenchmarkStructuredLogger_ErrorPath builds a logger per error as the LSP
+// error handler does and logs through it
+func BenchmarkStructuredLogger_ErrorPath(b *testing.B) {
+	logger := newDiscardManager(b).NewStructuredLogger().WithContext("component", "bench").WithContext("client_id", "client-1")
+	ctx := logging.ContextWithField(context.Background(), "method", "textDocument/hover")
+	ctx = logging.ContextWithField(ctx, "request_id", "7")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.ForContext(ctx).
+			WithContext("operation", "hover").
+			WithContext("error_code", "InvalidParams").
+			WithContext("uri", "file:///bench.go").
+			Error("Operation failed: %v", "bench")
+	}
+}
@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Log formats selectable with the log_format configuration key
+const (
+	FormatText = "text" // One line per entry, as written by earlier versions
+	FormatJSON = "json" // One JSON object per entry, from slog.JSONHandler
+)
+
+// Formats lists the accepted log formats
+var Formats = []string{FormatText, FormatJSON}
+
+// slogLevel returns the slog level corresponding to l
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarning:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// levelName returns the name textHandler prints for level
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return LogLevelDebug.String()
+	case level < slog.LevelWarn:
+		return LogLevelInfo.String()
+	case level < slog.LevelError:
+		return LogLevelWarning.String()
+	default:
+		return LogLevelError.String()
+	}
+}
+
+// textHandler is a slog handler writing entries in the format of the
+// original leveled logger:
+//
+//	2006/01/02 15:04:05 [app] [LEVEL] message [key=value ...]
+type textHandler struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	appName string
+	level   slog.Leveler
+	attrs   []slog.Attr
+	group   string // Prefix of the keys of attributes added later
+}
+
+// newTextHandler creates a text handler writing entries of at least level to w
+func newTextHandler(w io.Writer, appName string, level slog.Leveler) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, appName: appName, level: level}
+}
+
+// Enabled reports whether records of level are written
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes a single entry for the record
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b bytes.Buffer
+	b.WriteString(record.Time.Format("2006/01/02 15:04:05"))
+	fmt.Fprintf(&b, " [%s] [%s] %s", h.appName, levelName(record.Level), record.Message)
+
+	var pairs []string
+	for _, attr := range h.attrs {
+		pairs = appendAttr(pairs, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		pairs = appendAttr(pairs, h.group, attr)
+		return true
+	})
+	if len(pairs) > 0 {
+		fmt.Fprintf(&b, " [%s]", strings.Join(pairs, " "))
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(b.Bytes())
+	return err
+}
+
+// appendAttr appends attr as key=value pairs, flattening groups into
+// dotted keys
+func appendAttr(pairs []string, prefix string, attr slog.Attr) []string {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return pairs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			pairs = appendAttr(pairs, prefix, member)
+		}
+		return pairs
+	}
+	return append(pairs, fmt.Sprintf("%s%s=%v", prefix, attr.Key, attr.Value.Any()))
+}
+
+// WithAttrs returns a handler adding attrs to every entry
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		if h.group != "" {
+			attr.Key = h.group + attr.Key
+		}
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with name
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// fanoutHandler passes every record to several handlers
+type fanoutHandler []slog.Handler
+
+// Enabled reports whether any handler accepts records of level
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to every handler accepting its level
+func (f fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a fanout of the handlers with attrs added
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+// WithGroup returns a fanout of the handlers with the group opened
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// recentWriter stores each entry written to it in the manager's ring buffer
+type recentWriter struct {
+	manager *Manager
+}
+
+// Write remembers p as a single entry without its trailing newline
+func (w recentWriter) Write(p []byte) (int, error) {
+	w.manager.remember(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
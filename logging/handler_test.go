@@ -0,0 +1,121 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"mock-lsp-server/logging"
+)
+
+// initializeWithConfig initializes a manager logging to a file in a temporary
+// directory with the given logging config file content
+func initializeWithConfig(t *testing.T, configJSON string) (*logging.Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(configJSON), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: dir}, true)
+	if err := manager.Initialize(filepath.Join(dir, "logs"), configPath); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	return manager, manager.GetLogPath()
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	return string(data)
+}
+
+func TestManager_TextFormat(t *testing.T) {
+	manager, path := initializeWithConfig(t, `{"log_level": "info"}`)
+
+	manager.Debug("hidden")
+	manager.Info("plain %d", 1)
+	manager.NewStructuredLogger().WithContext("b", 2).WithContext("a", "x").Warning("structured")
+	manager.GetLogger().Printf("standard")
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	patterns := []string{
+		`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[test-app\] \[INFO\] plain 1$`,
+		`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[test-app\] \[WARNING\] structured \[a=x b=2\]$`,
+		`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[test-app\] \[INFO\] standard$`,
+	}
+	if len(lines) != len(patterns) {
+		t.Fatalf("Expected %d lines, got %q", len(patterns), lines)
+	}
+	for i, pattern := range patterns {
+		if !regexp.MustCompile(pattern).MatchString(lines[i]) {
+			t.Errorf("Expected line %d to match %s, got %q", i, pattern, lines[i])
+		}
+	}
+}
+
+func TestManager_JSONFormat(t *testing.T) {
+	manager, path := initializeWithConfig(t, `{"log_format": "json"}`)
+
+	manager.NewStructuredLogger().WithContext("component", "test").Error("failed: %s", "reason")
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(readLog(t, path)), &entry); err != nil {
+		t.Fatalf("Expected a JSON entry: %v", err)
+	}
+	want := map[string]any{"level": "ERROR", "msg": "failed: reason", "app": "test-app", "component": "test"}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+}
+
+func TestManager_InvalidFormat(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"log_format": "xml"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: dir}, false)
+	if err := manager.Initialize("", configPath); err == nil {
+		t.Error("Expected an invalid log format to be rejected")
+	}
+}
+
+func TestManager_AddHandler(t *testing.T) {
+	var extra bytes.Buffer
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: t.TempDir()}, false)
+	manager.AddHandler(slog.NewJSONHandler(&extra, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if err := manager.SetOutput(logging.OutputNone); err != nil {
+		t.Fatalf("SetOutput failed: %v", err)
+	}
+	if err := manager.Initialize("", ""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	manager.Info("info only")
+	manager.Slog().Warn("exported", "key", "value")
+
+	output := extra.String()
+	if strings.Contains(output, "info only") {
+		t.Errorf("Expected the added handler to filter levels itself, got %q", output)
+	}
+	if !strings.Contains(output, `"msg":"exported"`) || !strings.Contains(output, `"key":"value"`) {
+		t.Errorf("Expected the warning in the added handler, got %q", output)
+	}
+	if entries := manager.RecentEntries(); len(entries) != 2 || !strings.HasSuffix(entries[1], "exported [key=value]") {
+		t.Errorf("Expected both entries in memory, got %q", entries)
+	}
+}
@@ -2,13 +2,18 @@
 package logging
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"mock-lsp-server/directories" // Replace with your actual module path
@@ -56,13 +61,30 @@ func ParseLogLevel(level string) LogLevel {
 	}
 }
 
+// Log outputs selectable with SetOutput
+const (
+	OutputFile   = "file"   // Log to a file, falling back as configured
+	OutputStderr = "stderr" // Log to stderr without creating any files
+	OutputNone   = "none"   // Discard all log output
+)
+
+// recentCapacity is the number of log entries kept in memory for crash reports
+const recentCapacity = 100
+
+// Outputs lists the log outputs accepted by SetOutput
+var Outputs = []string{OutputFile, OutputStderr, OutputNone}
+
 // Config represents the logging configuration
 type Config struct {
 	LogDir     string `json:"log_dir"`
 	LogLevel   string `json:"log_level"`
 	LogFile    string `json:"log_file"`
-	MaxSize    int    `json:"max_size_mb"` // Maximum size in MB before rotation
-	MaxBackups int    `json:"max_backups"` // Maximum number of backup files
+	MaxSize    int    `json:"max_size_mb"`  // Maximum size in MB before rotation
+	MaxBackups int    `json:"max_backups"`  // Maximum number of backup files
+	LogFormat  string `json:"log_format"`   // One of Formats, text by default
+	Daily      bool   `json:"daily"`        // Date-stamp the log file and roll over daily
+	MaxAge     int    `json:"max_age_days"` // Days to keep daily log files, 0 keeps all
+	Compress   bool   `json:"compress"`     // Write gzip-compressed log files
 }
 
 // Manager handles logging operations with directory resolution and configuration
@@ -71,8 +93,20 @@ type Manager struct {
 	resolver     *directories.DirectoryResolver
 	config       *Config
 	logger       *log.Logger
-	logFile      *os.File
+	slogger      *slog.Logger
+	handlers     []slog.Handler // Handlers added with AddHandler
+	logFile      io.WriteCloser
 	currentLevel LogLevel
+	level        *slog.LevelVar // currentLevel as seen by the slog handlers
+	output       string         // One of Outputs
+	fallback     bool           // Fall back to the default directory, then stderr
+	logDirectory string         // Directory of the opened log file, empty for stderr
+	logFilePath  string         // Path of the opened log file, empty for stderr
+	initialized  bool           // Whether Initialize has chosen the log output
+	fallbacks    []string       // Warnings describing the fallbacks taken
+	recentMu     sync.Mutex
+	recent       []string // Ring buffer of the latest log entries
+	recentNext   int      // Index in recent the next entry is written to
 }
 
 // NewManager creates a new logging manager
@@ -82,6 +116,8 @@ func NewManager(appName string, user *user.User, shouldEnsureDir bool) *Manager
 		resolver:     directories.NewDirectoryResolver(appName, user, shouldEnsureDir),
 		config:       &Config{LogLevel: "info"}, // Default to info level
 		currentLevel: LogLevelInfo,
+		level:        new(slog.LevelVar),
+		output:       OutputFile,
 	}
 }
 
@@ -99,13 +135,20 @@ func (lm *Manager) LoadConfig(configPath string) error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	if err := json.Unmarshal(data, lm.config); err != nil {
+	if err := lm.ParseConfig(data); err != nil {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	return nil
 }
 
+// ParseConfig loads configuration from the contents of a config file that
+// was read elsewhere, such as from stdin. Initialize keeps it when given an
+// empty config path.
+func (lm *Manager) ParseConfig(data []byte) error {
+	return json.Unmarshal(data, lm.config)
+}
+
 // GetLogDirectory returns the appropriate log directory based on CLI override, config, or defaults
 func (lm *Manager) GetLogDirectory(cliLogDir string) (string, error) {
 	// Priority: CLI flag > config file > user-specific default
@@ -142,8 +185,37 @@ func (lm *Manager) GetDefaultConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
-// GetLogFileName returns the log file name from config or default
+// SetFallback controls whether Initialize falls back to the default log
+// directory and then to stderr-only logging when the log file cannot be opened
+func (lm *Manager) SetFallback(enabled bool) {
+	lm.fallback = enabled
+}
+
+// SetOutput selects where Initialize sends log output. OutputStderr and
+// OutputNone never create directories or files.
+func (lm *Manager) SetOutput(output string) error {
+	if !slices.Contains(Outputs, output) {
+		return fmt.Errorf("invalid log output %q: must be one of %s", output, strings.Join(Outputs, ", "))
+	}
+	lm.output = output
+	return nil
+}
+
+// GetLogFileName returns the log file name from config or default. With
+// daily log files it is the name of today's file.
 func (lm *Manager) GetLogFileName() string {
+	name := lm.baseLogFileName()
+	if lm.config.Daily {
+		name = datedFileName(name, time.Now())
+	}
+	if lm.config.Compress {
+		name += compressedExt
+	}
+	return name
+}
+
+// baseLogFileName returns the log file name without a date stamp
+func (lm *Manager) baseLogFileName() string {
 	if lm.config.LogFile != "" {
 		return lm.config.LogFile
 	}
@@ -157,35 +229,149 @@ func (lm *Manager) Initialize(cliLogDir, configPath string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Resolve log directory
-	logDirectory, err := lm.GetLogDirectory(cliLogDir)
-	if err != nil {
-		return fmt.Errorf("failed to resolve log directory: %w", err)
+	// Set log level and format from config
+	lm.SetLogLevel(ParseLogLevel(lm.config.LogLevel))
+	if lm.config.LogFormat != "" && !slices.Contains(Formats, lm.config.LogFormat) {
+		return fmt.Errorf("invalid log format %q: must be one of %s", lm.config.LogFormat, strings.Join(Formats, ", "))
+	}
+	if lm.config.MaxAge < 0 {
+		return fmt.Errorf("invalid max_age_days %d: must not be negative", lm.config.MaxAge)
 	}
 
-	// Create log file path
-	logFileName := lm.GetLogFileName()
-	logFilePath := filepath.Join(logDirectory, logFileName)
+	// Skip file creation entirely for the stderr and none outputs
+	switch lm.output {
+	case OutputStderr:
+		lm.useWriter(os.Stderr)
+		lm.initialized = true
+		return nil
+	case OutputNone:
+		lm.useWriter(nil)
+		lm.initialized = true
+		return nil
+	}
+
+	// Open the log file in the resolved directory
+	logFile, err := lm.openLogFile(cliLogDir)
+
+	// Fall back to the default directory, unless that was the one that failed
+	if err != nil && lm.fallback && (cliLogDir != "" || lm.config.LogDir != "") {
+		lm.fallbacks = append(lm.fallbacks, fmt.Sprintf("%v; falling back to the default log directory", err))
+		logFile, err = lm.openDefaultLogFile()
+	}
+
+	// Fall back to stderr so the server still works on read-only filesystems
+	if err != nil && lm.fallback {
+		lm.fallbacks = append(lm.fallbacks, fmt.Sprintf("%v; logging to stderr only", err))
+		logFile, err = nil, nil
+	}
 
-	// Open log file
-	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to open log file %s: %w", logFilePath, err)
+		return err
 	}
 
 	// Store file handle for cleanup
 	lm.logFile = logFile
+	lm.initialized = true
 
-	// Set log level from config
-	lm.currentLevel = ParseLogLevel(lm.config.LogLevel)
+	// Route log entries to the file, or to stderr after falling back
+	if logFile != nil {
+		lm.useWriter(logFile)
+	} else {
+		lm.useWriter(os.Stderr)
+	}
 
-	// Create logger with timestamp and source info
-	lm.logger = log.New(logFile, "", 0) // No prefix, we'll handle it ourselves
+	// Surface the fallbacks on stderr and in the log that was opened
+	for _, warning := range lm.fallbacks {
+		if logFile != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		lm.Warning("%s", warning)
+	}
 
 	return nil
 }
 
-// GetLogger returns the configured logger instance
+// useWriter builds the slog handler chain writing entries to w, or to no
+// output when w is nil. Every chain keeps the recent entries in memory and
+// feeds the handlers added with AddHandler.
+func (lm *Manager) useWriter(w io.Writer) {
+	handlers := fanoutHandler{newTextHandler(recentWriter{lm}, lm.appName, lm.level)}
+	if w != nil {
+		handlers = append(handlers, lm.outputHandler(w))
+	}
+	handlers = append(handlers, lm.handlers...)
+
+	lm.slogger = slog.New(handlers)
+	lm.logger = slog.NewLogLogger(handlers, slog.LevelInfo)
+}
+
+// outputHandler returns the handler writing entries to w in the configured format
+func (lm *Manager) outputHandler(w io.Writer) slog.Handler {
+	if lm.config.LogFormat == FormatJSON {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lm.level}).
+			WithAttrs([]slog.Attr{slog.String("app", lm.appName)})
+	}
+	return newTextHandler(w, lm.appName, lm.level)
+}
+
+// AddHandler sends log entries to h in addition to the configured output,
+// e.g. to export them through a third-party slog handler. Handlers must be
+// added before Initialize. h filters levels itself.
+func (lm *Manager) AddHandler(h slog.Handler) {
+	lm.handlers = append(lm.handlers, h)
+}
+
+// Slog returns the slog logger behind the manager, or nil before Initialize
+func (lm *Manager) Slog() *slog.Logger {
+	return lm.slogger
+}
+
+// openLogFile opens the log file in the directory chosen by GetLogDirectory
+func (lm *Manager) openLogFile(cliLogDir string) (io.WriteCloser, error) {
+	logDirectory, err := lm.GetLogDirectory(cliLogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve log directory: %w", err)
+	}
+	return lm.openLogFileIn(logDirectory)
+}
+
+// openDefaultLogFile opens the log file in the user-specific default directory
+func (lm *Manager) openDefaultLogFile() (io.WriteCloser, error) {
+	logDirectory, err := lm.resolver.GetLogDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve default log directory: %w", err)
+	}
+	if err := os.MkdirAll(logDirectory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create default log directory %s: %w", logDirectory, err)
+	}
+	return lm.openLogFileIn(logDirectory)
+}
+
+// openLogFileIn opens the log file for appending in the given directory and
+// records where it lives
+func (lm *Manager) openLogFileIn(logDirectory string) (io.WriteCloser, error) {
+	if lm.config.Daily {
+		daily, err := openDailyFile(logDirectory, lm.baseLogFileName(), lm.config.Compress, lm.config.MaxAge, time.Now)
+		if err != nil {
+			return nil, err
+		}
+		lm.logDirectory = logDirectory
+		lm.logFilePath = daily.Path()
+		return daily, nil
+	}
+
+	logFilePath := filepath.Join(logDirectory, lm.GetLogFileName())
+	logFile, err := openLogStream(logFilePath, lm.config.Compress)
+	if err != nil {
+		return nil, err
+	}
+	lm.logDirectory = logDirectory
+	lm.logFilePath = logFilePath
+	return logFile, nil
+}
+
+// GetLogger returns a standard logger writing info-level entries through the
+// configured handlers
 func (lm *Manager) GetLogger() *log.Logger {
 	return lm.logger
 }
@@ -201,21 +387,59 @@ func (lm *Manager) GetLogFilePath(cliLogDir string) (string, error) {
 	return filepath.Join(logDirectory, logFileName), nil
 }
 
+// GetLogPath returns the path of the log file opened by Initialize, or an
+// empty string when logging to stderr or nowhere. With daily log files it is
+// the file currently written to.
+func (lm *Manager) GetLogPath() string {
+	if daily, ok := lm.logFile.(*dailyFile); ok {
+		return daily.Path()
+	}
+	return lm.logFilePath
+}
+
 // shouldLog checks if a message at the given level should be logged
 func (lm *Manager) shouldLog(level LogLevel) bool {
 	return level >= lm.currentLevel
 }
 
-// logWithLevel writes a structured log message with the given level
+// logWithLevel writes a log message with the given level
 func (lm *Manager) logWithLevel(level LogLevel, format string, args ...interface{}) {
-	if lm.logger == nil || !lm.shouldLog(level) {
+	if lm.slogger == nil || !lm.shouldLog(level) {
 		return
 	}
+	lm.logAttrs(level, fmt.Sprintf(format, args...))
+}
 
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	message := fmt.Sprintf(format, args...)
-	logEntry := fmt.Sprintf("%s [%s] [%s] %s", timestamp, lm.appName, level.String(), message)
-	lm.logger.Println(logEntry)
+// logAttrs writes a log message with the given level and attributes
+func (lm *Manager) logAttrs(level LogLevel, message string, attrs ...slog.Attr) {
+	if lm.slogger == nil {
+		return
+	}
+	lm.slogger.LogAttrs(context.Background(), level.slogLevel(), message, attrs...)
+}
+
+// remember stores a log entry in the ring buffer, overwriting the oldest
+// entry once it is full
+func (lm *Manager) remember(entry string) {
+	lm.recentMu.Lock()
+	defer lm.recentMu.Unlock()
+	if len(lm.recent) < recentCapacity {
+		lm.recent = append(lm.recent, entry)
+		return
+	}
+	lm.recent[lm.recentNext] = entry
+	lm.recentNext = (lm.recentNext + 1) % recentCapacity
+}
+
+// RecentEntries returns the latest log entries, oldest first. They are kept
+// in memory whatever the output, so crash reports can include them even when
+// logging is disabled.
+func (lm *Manager) RecentEntries() []string {
+	lm.recentMu.Lock()
+	defer lm.recentMu.Unlock()
+	entries := make([]string, 0, len(lm.recent))
+	entries = append(entries, lm.recent[lm.recentNext:]...)
+	return append(entries, lm.recent[:lm.recentNext]...)
 }
 
 // Log writes a general message to the log (INFO level)
@@ -246,6 +470,7 @@ func (lm *Manager) Error(format string, args ...interface{}) {
 // SetLogLevel changes the current log level
 func (lm *Manager) SetLogLevel(level LogLevel) {
 	lm.currentLevel = level
+	lm.level.Set(level.slogLevel())
 }
 
 // GetLogLevel returns the current log level
@@ -253,69 +478,75 @@ func (lm *Manager) GetLogLevel() LogLevel {
 	return lm.currentLevel
 }
 
-// StructuredLogger provides a structured logging interface
+// StructuredLogger provides a structured logging interface. Its context is
+// an immutable list of fields shared with the loggers derived from it, so
+// deriving a logger never copies the fields of its parent and loggers are
+// safe to share between goroutines.
 type StructuredLogger struct {
 	manager *Manager
-	context map[string]interface{}
+	fields  *field
+}
+
+// field is a node of an immutable list of log fields, newest first
+type field struct {
+	key   string
+	value interface{}
+	next  *field
 }
 
 // NewStructuredLogger creates a new structured logger
 func (lm *Manager) NewStructuredLogger() *StructuredLogger {
-	return &StructuredLogger{
-		manager: lm,
-		context: make(map[string]interface{}),
-	}
+	return &StructuredLogger{manager: lm}
 }
 
-// WithContext adds context to the logger
+// WithContext returns a logger adding key=value to the context of sl. A key
+// already in the context takes the new value.
 func (sl *StructuredLogger) WithContext(key string, value interface{}) *StructuredLogger {
-	newLogger := &StructuredLogger{
+	return &StructuredLogger{
 		manager: sl.manager,
-		context: make(map[string]interface{}),
-	}
-	// Copy existing context
-	for k, v := range sl.context {
-		newLogger.context[k] = v
-	}
-	// Add new context
-	newLogger.context[key] = value
-	return newLogger
-}
-
-// formatMessage formats a message with context
-func (sl *StructuredLogger) formatMessage(format string, args ...interface{}) string {
-	message := fmt.Sprintf(format, args...)
-	if len(sl.context) > 0 {
-		contextStr := ""
-		for k, v := range sl.context {
-			if contextStr != "" {
-				contextStr += " "
-			}
-			contextStr += fmt.Sprintf("%s=%v", k, v)
+		fields:  &field{key: key, value: value, next: sl.fields},
+	}
+}
+
+// attrs returns the logger's context as slog attributes, sorted by key. Only
+// the newest value of each key is kept.
+func (sl *StructuredLogger) attrs() []slog.Attr {
+	var attrs []slog.Attr
+	for f := sl.fields; f != nil; f = f.next {
+		if !slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == f.key }) {
+			attrs = append(attrs, slog.Any(f.key, f.value))
 		}
-		return fmt.Sprintf("%s [%s]", message, contextStr)
 	}
-	return message
+	slices.SortFunc(attrs, func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) })
+	return attrs
+}
+
+// log writes a message with the logger's context at the given level
+func (sl *StructuredLogger) log(level LogLevel, format string, args ...interface{}) {
+	if !sl.manager.shouldLog(level) {
+		return
+	}
+	sl.manager.logAttrs(level, fmt.Sprintf(format, args...), sl.attrs()...)
 }
 
 // Debug logs a debug message with context
 func (sl *StructuredLogger) Debug(format string, args ...interface{}) {
-	sl.manager.Debug("%s", sl.formatMessage(format, args...))
+	sl.log(LogLevelDebug, format, args...)
 }
 
 // Info logs an info message with context
 func (sl *StructuredLogger) Info(format string, args ...interface{}) {
-	sl.manager.Info("%s", sl.formatMessage(format, args...))
+	sl.log(LogLevelInfo, format, args...)
 }
 
 // Warning logs a warning message with context
 func (sl *StructuredLogger) Warning(format string, args ...interface{}) {
-	sl.manager.Warning("%s", sl.formatMessage(format, args...))
+	sl.log(LogLevelWarning, format, args...)
 }
 
 // Error logs an error message with context
 func (sl *StructuredLogger) Error(format string, args ...interface{}) {
-	sl.manager.Error("%s", sl.formatMessage(format, args...))
+	sl.log(LogLevelError, format, args...)
 }
 
 // Printf provides compatibility with standard logger interface
@@ -336,16 +567,21 @@ func (lm *Manager) Close() error {
 	return nil
 }
 
-// GetInfo returns information about the current logging setup
+// GetInfo returns information about the current logging setup. After
+// Initialize it describes the log output actually in use.
 func (lm *Manager) GetInfo(cliLogDir string) (*LogInfo, error) {
-	logDirectory, err := lm.GetLogDirectory(cliLogDir)
-	if err != nil {
-		return nil, err
-	}
+	logDirectory, logFilePath := lm.logDirectory, lm.GetLogPath()
+	if !lm.initialized {
+		var err error
+		logDirectory, err = lm.GetLogDirectory(cliLogDir)
+		if err != nil {
+			return nil, err
+		}
 
-	logFilePath, err := lm.GetLogFilePath(cliLogDir)
-	if err != nil {
-		return nil, err
+		logFilePath, err = lm.GetLogFilePath(cliLogDir)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	configPath, err := lm.GetDefaultConfigPath()
@@ -361,6 +597,9 @@ func (lm *Manager) GetInfo(cliLogDir string) (*LogInfo, error) {
 		LogFileName:    lm.GetLogFileName(),
 		UsingCLIDir:    cliLogDir != "",
 		UsingConfigDir: lm.config.LogDir != "",
+		UsingStderr:    lm.initialized && lm.logFile == nil && lm.output != OutputNone,
+		Output:         lm.output,
+		Fallbacks:      lm.fallbacks,
 	}, nil
 }
 
@@ -373,4 +612,7 @@ type LogInfo struct {
 	LogFileName    string
 	UsingCLIDir    bool
 	UsingConfigDir bool
+	Output         string   // Selected output, one of Outputs
+	UsingStderr    bool     // Logging to stderr instead of a file
+	Fallbacks      []string // Warnings describing the fallbacks taken
 }
@@ -1,8 +1,10 @@
 package logging_test
 
 import (
+	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"mock-lsp-server/logging"
@@ -174,3 +176,103 @@ func TestLogLevelManagement(t *testing.T) {
 		})
 	}
 }
+
+// unwritableDir returns a directory path that cannot be created, even as
+// root, because its parent is a regular file
+func unwritableDir(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	return filepath.Join(file, "logs")
+}
+
+// Test the log directory fallback chain
+func TestManager_InitializeFallback(t *testing.T) {
+	regularUser := &user.User{Uid: "1000", HomeDir: t.TempDir()}
+
+	testCases := []struct {
+		name              string
+		unwritableDefault bool
+		fallback          bool
+		wantErr           bool
+		wantStderr        bool
+		wantDefault       bool
+	}{
+		{"fallback disabled fails", false, false, true, false, false},
+		{"falls back to default directory", false, true, false, false, true},
+		{"falls back to stderr", true, true, false, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dataHome := filepath.Join(regularUser.HomeDir, tc.name)
+			if tc.unwritableDefault {
+				dataHome = unwritableDir(t)
+			}
+			t.Setenv("XDG_DATA_HOME", dataHome)
+
+			manager := logging.NewManager("test-app", regularUser, false)
+			manager.SetFallback(tc.fallback)
+			err := manager.Initialize(unwritableDir(t), "")
+			defer manager.Close()
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected Initialize to fail without fallback")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+
+			info, err := manager.GetInfo("")
+			if err != nil {
+				t.Fatalf("GetInfo failed: %v", err)
+			}
+			if info.UsingStderr != tc.wantStderr {
+				t.Errorf("Expected UsingStderr %v, got %v", tc.wantStderr, info.UsingStderr)
+			}
+			if len(info.Fallbacks) == 0 {
+				t.Error("Expected the fallbacks to be reported")
+			}
+			if tc.wantDefault {
+				expected := filepath.Join(dataHome, "test-app", "logs", "test-app.log")
+				if info.LogFilePath != expected {
+					t.Errorf("Expected log file %s, got %s", expected, info.LogFilePath)
+				}
+				if _, err := os.Stat(expected); err != nil {
+					t.Errorf("Expected log file to exist: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// Test the in-memory ring buffer of recent log entries
+func TestManager_RecentEntries(t *testing.T) {
+	manager := logging.NewManager("test-app", &user.User{Uid: "1000", HomeDir: t.TempDir()}, false)
+	if err := manager.SetOutput(logging.OutputNone); err != nil {
+		t.Fatalf("SetOutput failed: %v", err)
+	}
+	if err := manager.Initialize("", ""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	for i := 0; i < 150; i++ {
+		manager.Info("entry %d", i)
+	}
+
+	entries := manager.RecentEntries()
+	if len(entries) != 100 {
+		t.Fatalf("Expected 100 entries, got %d", len(entries))
+	}
+	if !strings.HasSuffix(entries[0], "entry 50") {
+		t.Errorf("Expected oldest entry to be 'entry 50', got %q", entries[0])
+	}
+	if !strings.HasSuffix(entries[99], "entry 149") {
+		t.Errorf("Expected newest entry to be 'entry 149', got %q", entries[99])
+	}
+}
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayout is the date format of date-stamped log file names
+const dateLayout = "2006-01-02"
+
+// datedFileName inserts the date of t before the extension of name, turning
+// mock-lsp-server.log into mock-lsp-server-2024-06-01.log
+func datedFileName(name string, t time.Time) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), t.Format(dateLayout), ext)
+}
+
+// dailyFile is a log file that rolls over to a new date-stamped file on the
+// first write of each day and prunes date-stamped files older than maxAge days
+type dailyFile struct {
+	mu       sync.Mutex
+	dir      string
+	name     string // Undated file name the dated names derive from
+	suffix   string // Appended to dated names, compressedExt when compressing
+	compress bool
+	maxAge   int // Days to keep old files, zero keeps them all
+	now      func() time.Time
+	day      string // Date of the open file
+	path     string // Path of the open file
+	file     io.WriteCloser
+}
+
+// openDailyFile opens today's log file for name in dir, gzip-compressed if
+// compress is set, and prunes old ones
+func openDailyFile(dir, name string, compress bool, maxAge int, now func() time.Time) (*dailyFile, error) {
+	d := &dailyFile{dir: dir, name: name, compress: compress, maxAge: maxAge, now: now}
+	if compress {
+		d.suffix = compressedExt
+	}
+	if err := d.open(now()); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// open opens the file of the day of t for appending and prunes old files
+func (d *dailyFile) open(t time.Time) error {
+	path := filepath.Join(d.dir, datedFileName(d.name, t)+d.suffix)
+	file, err := openLogStream(path, d.compress)
+	if err != nil {
+		return err
+	}
+	d.file = file
+	d.path = path
+	d.day = t.Format(dateLayout)
+	d.prune(t)
+	return nil
+}
+
+// prune removes the date-stamped files of name dated more than maxAge days
+// before t. Files that cannot be removed are left for the next rollover.
+func (d *dailyFile) prune(t time.Time) {
+	if d.maxAge <= 0 {
+		return
+	}
+	ext := filepath.Ext(d.name)
+	prefix := strings.TrimSuffix(d.name, ext) + "-"
+	cutoff := t.AddDate(0, 0, -d.maxAge).Format(dateLayout)
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		date, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		date, ok = strings.CutSuffix(date, ext+d.suffix)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			continue
+		}
+		// Dates in dateLayout sort chronologically as strings
+		if date < cutoff {
+			os.Remove(filepath.Join(d.dir, entry.Name()))
+		}
+	}
+}
+
+// Write appends p to the file of the current day, rolling over first if the
+// day changed since the last write
+func (d *dailyFile) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now := d.now(); now.Format(dateLayout) != d.day {
+		previous := d.file
+		if err := d.open(now); err != nil {
+			// Keep writing to the previous file rather than losing entries
+			return previous.Write(p)
+		}
+		previous.Close()
+	}
+	return d.file.Write(p)
+}
+
+// Path returns the path of the file currently written to
+func (d *dailyFile) Path() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.path
+}
+
+// Close closes the current file
+func (d *dailyFile) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatedFileName(t *testing.T) {
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"mock-lsp-server.log": "mock-lsp-server-2024-06-01.log",
+		"custom":              "custom-2024-06-01",
+		"app.debug.txt":       "app.debug-2024-06-01.txt",
+	}
+	for name, want := range tests {
+		if got := datedFileName(name, day); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestDailyFile_Rollover(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 23, 59, 0, 0, time.UTC)
+
+	// Files from before the retention window, within it and unrelated files
+	for _, name := range []string{"app-2024-05-20.log", "app-2024-05-29.log", "app-notadate.log", "other-2024-01-01.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	daily, err := openDailyFile(dir, "app.log", false, 7, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("openDailyFile failed: %v", err)
+	}
+	defer daily.Close()
+
+	if _, err := daily.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := daily.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if want := filepath.Join(dir, "app-2024-06-02.log"); daily.Path() != want {
+		t.Errorf("Expected current file %s, got %s", want, daily.Path())
+	}
+	for name, want := range map[string]string{"app-2024-06-01.log": "first\n", "app-2024-06-02.log": "second\n"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, data, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{"app-2024-05-29.log", "app-2024-06-01.log", "app-2024-06-02.log", "app-notadate.log", "other-2024-01-01.log"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Expected files %v after pruning, got %v", want, names)
+	}
+}
+
+func TestManager_DailyLogFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"daily": true, "max_age_days": 30}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	manager := NewManager("test-app", &user.User{Uid: "1000", HomeDir: dir}, true)
+	if err := manager.Initialize(filepath.Join(dir, "logs"), configPath); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer manager.Close()
+	manager.Info("dated")
+
+	want := filepath.Join(dir, "logs", datedFileName("test-app.log", time.Now()))
+	if path := manager.GetLogPath(); path != want {
+		t.Errorf("Expected log file %s, got %s", want, path)
+	}
+	if data, err := os.ReadFile(want); err != nil || !strings.Contains(string(data), "dated") {
+		t.Errorf("Expected the entry in %s, got %q (%v)", want, data, err)
+	}
+}
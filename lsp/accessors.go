@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// ServerState is the position of a server in the LSP lifecycle
+type ServerState string
+
+// Lifecycle states, in the order a well-behaved client moves through them
+const (
+	StateUninitialized ServerState = "uninitialized" // No initialize request yet
+	StateInitializing  ServerState = "initializing"  // Answered initialize, awaiting initialized
+	StateInitialized   ServerState = "initialized"   // Received the initialized notification
+	StateShuttingDown  ServerState = "shutting_down" // Answered shutdown, awaiting exit
+	StateExited        ServerState = "exited"        // Received the exit notification
+)
+
+// setState moves the server to the given lifecycle state, notifying the
+// state change hooks if it differs from the current one
+func (s *MockLSPServer) setState(state ServerState) {
+	s.mu.Lock()
+	from := s.state
+	s.state = state
+	s.mu.Unlock()
+
+	if from != state {
+		emit(&s.hooks, &s.hooks.stateChanges, StateChangeEvent{From: from, To: state})
+	}
+}
+
+// State returns the lifecycle state of the server
+func (s *MockLSPServer) State() ServerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// OpenDocuments returns copies of the documents currently open, keyed by URI
+func (s *MockLSPServer) OpenDocuments() map[string]protocol.TextDocumentItem {
+	documents := make(map[string]protocol.TextDocumentItem)
+	for _, doc := range s.documents.List() {
+		documents[string(doc.Uri)] = doc
+	}
+	return documents
+}
+
+// Capabilities returns the capabilities the server announces in its
+// initialize response
+func (s *MockLSPServer) Capabilities() protocol.ServerCapabilities {
+	return s.capabilities()
+}
+
+// Stats returns a snapshot of the request statistics, as served by
+// $/mockLsp/stats
+func (s *MockLSPServer) Stats() StatsSnapshot {
+	snapshot := s.stats.Snapshot(s.openDocumentCount())
+	snapshot.Client = s.ClientFingerprint()
+	snapshot.ExpectationFailures = s.ExpectationFailures()
+	return snapshot
+}
+
+// ClientFingerprint returns the fingerprint of the client, or nil before
+// the initialize request
+func (s *MockLSPServer) ClientFingerprint() *ClientFingerprint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestServerAccessors(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	if state := server.State(); state != StateUninitialized {
+		t.Errorf("Expected state %s, got %s", StateUninitialized, state)
+	}
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if state := server.State(); state != StateInitializing {
+		t.Errorf("Expected state %s, got %s", StateInitializing, state)
+	}
+
+	announced, err := encodeWire(initResult.Capabilities)
+	if err != nil {
+		t.Fatalf("Failed to encode announced capabilities: %v", err)
+	}
+	exposed, err := encodeWire(server.Capabilities())
+	if err != nil {
+		t.Fatalf("Failed to encode capabilities: %v", err)
+	}
+	if string(announced) != string(exposed) {
+		t.Errorf("Expected Capabilities() to match the initialize response, got %s and %s", exposed, announced)
+	}
+
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("Failed to send initialized: %v", err)
+	}
+	doc := protocol.TextDocumentItem{Uri: "file:///accessors.go", LanguageId: "go", Text: "package main", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("Failed to send didOpen: %v", err)
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+
+	if state := server.State(); state != StateInitialized {
+		t.Errorf("Expected state %s, got %s", StateInitialized, state)
+	}
+
+	documents := server.OpenDocuments()
+	if !reflect.DeepEqual(documents, map[string]protocol.TextDocumentItem{string(doc.Uri): doc}) {
+		t.Errorf("Expected the opened document, got %+v", documents)
+	}
+	documents[string(doc.Uri)] = protocol.TextDocumentItem{Text: "modified"}
+	if text, _ := server.documentText(string(doc.Uri)); text != doc.Text {
+		t.Errorf("Expected OpenDocuments to return a copy, got text %q", text)
+	}
+
+	stats := server.Stats()
+	if stats.OpenDocuments != 1 {
+		t.Errorf("Expected 1 open document, got %d", stats.OpenDocuments)
+	}
+	if stats.Notifications["textDocument/didOpen"] != 1 {
+		t.Errorf("Expected 1 didOpen notification, got %d", stats.Notifications["textDocument/didOpen"])
+	}
+
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if state := server.State(); state != StateShuttingDown {
+		t.Errorf("Expected state %s, got %s", StateShuttingDown, state)
+	}
+}
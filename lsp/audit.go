@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Audit event names
+const (
+	AuditConnected    = "connected"               // A client connected
+	AuditInitialized  = "initialized"             // The client sent initialize
+	AuditCapabilities = "capabilities_registered" // The server announced its capabilities
+	AuditShutdown     = "shutdown"                // The client asked the server to stop
+	AuditDisconnected = "disconnected"            // The connection closed
+)
+
+// AuditEvent is a single high-level lifecycle event in the audit log
+type AuditEvent struct {
+	Time          time.Time `json:"time"`
+	Event         string    `json:"event"`
+	ClientID      string    `json:"client_id"`
+	ClientName    string    `json:"client_name,omitempty"`
+	ClientVersion string    `json:"client_version,omitempty"`
+	Capabilities  []string  `json:"capabilities,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Remote        string    `json:"remote,omitempty"`
+}
+
+// AuditLog writes audit events as JSON lines. It is safe for use by the
+// servers of concurrent connections.
+type AuditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAuditLog creates an audit log writing to w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// Record writes event as a single JSON line
+func (a *AuditLog) Record(event AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.w.Write(append(data, '\n'))
+	return err
+}
+
+// SetAuditLog makes the server record its lifecycle events in audit
+func (s *MockLSPServer) SetAuditLog(audit *AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = audit
+}
+
+// Audit records event in the audit log, if one is set, filling in the time
+// and what is known about the client
+func (s *MockLSPServer) Audit(event AuditEvent) {
+	s.mu.Lock()
+	audit := s.audit
+	event.ClientName, event.ClientVersion = s.clientName, s.clientVersion
+	s.mu.Unlock()
+	if audit == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	event.ClientID = s.clientID
+	if err := audit.Record(event); err != nil {
+		s.logger.Printf("[%s] ERROR: Failed to write audit event %s: %v", s.clientID, event.Event, err)
+	}
+}
+
+// capabilityNames returns the sorted names of the capabilities that are set
+func capabilityNames(capabilities any) []string {
+	data, err := encodeWire(capabilities)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	for name, value := range fields {
+		if string(value) == "null" || string(value) == "false" {
+			delete(fields, name)
+		}
+	}
+	return slices.Sorted(maps.Keys(fields))
+}
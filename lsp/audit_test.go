@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	server := createTestServer()
+	server.SetAuditLog(NewAuditLog(&buf))
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	server.Audit(AuditEvent{Event: AuditConnected, Remote: "stdio"})
+
+	params := protocol.InitializeParams{ClientInfo: &protocol.ClientInfo{Name: "test-editor", Version: "2.1.0"}}
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", params, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	var events []AuditEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event AuditEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", line, err)
+		}
+		if event.ClientID != server.ClientID() || event.Time.IsZero() {
+			t.Errorf("Expected client ID and time on every event, got %+v", event)
+		}
+		events = append(events, event)
+	}
+
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+	want := []string{AuditConnected, AuditInitialized, AuditCapabilities, AuditShutdown}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected events %v, got %v", want, names)
+	}
+
+	if events[0].ClientName != "" || events[0].Remote != "stdio" {
+		t.Errorf("Expected an anonymous stdio connection, got %+v", events[0])
+	}
+	if events[1].ClientName != "test-editor" || events[1].ClientVersion != "2.1.0" {
+		t.Errorf("Expected the client name and version, got %+v", events[1])
+	}
+	wantCapabilities := []string{"codeActionProvider", "completionProvider", "definitionProvider", "documentFormattingProvider", "documentRangeFormattingProvider", "documentSymbolProvider", "foldingRangeProvider", "hoverProvider", "inlayHintProvider", "inlineCompletionProvider", "linkedEditingRangeProvider", "referencesProvider", "renameProvider", "selectionRangeProvider", "semanticTokensProvider", "signatureHelpProvider", "textDocumentSync", "workspace", "workspaceSymbolProvider"}
+	if !reflect.DeepEqual(events[2].Capabilities, wantCapabilities) {
+		t.Errorf("Expected capabilities %v, got %v", wantCapabilities, events[2].Capabilities)
+	}
+	if events[3].Reason != "shutdown request" || events[3].ClientName != "test-editor" {
+		t.Errorf("Expected the shutdown reason with client info, got %+v", events[3])
+	}
+}
+
+func TestAuditWithoutLog(t *testing.T) {
+	// Recording without an audit log is a no-op
+	createTestServer().Audit(AuditEvent{Event: AuditConnected})
+}
@@ -0,0 +1,157 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// cancelRequestMethod is the notification cancelling a request in flight
+const cancelRequestMethod = "$/cancelRequest"
+
+// orderedQueueSize bounds the messages waiting for the worker of a
+// connection before its read loop blocks
+const orderedQueueSize = 1024
+
+// handleCancelRequest processes $/cancelRequest notifications by cancelling
+// the context of the request in flight with the given ID, which then
+// answers RequestCancelled instead of a late result. Other IDs are kept so a
+// request still queued behind another one is cancelled as it starts, unless
+// the connection already sent a request with the ID, which was answered.
+// The oldest kept IDs are forgotten once orderedQueueSize of them piled up.
+func (s *MockLSPServer) handleCancelRequest(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params struct {
+		ID jsonrpc.ID `json:"id"`
+	}
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.logError(ctx, "Failed to parse $/cancelRequest params")
+		return
+	}
+
+	key := underlyingConn(conn)
+	s.mu.Lock()
+	request, ok := s.inflight[params.ID]
+	answered := !ok && s.requestIDs != nil && s.requestIDs.conn == key && s.requestIDs.seen(params.ID)
+	if !ok && !answered && !slices.Contains(s.pendingCancels, params.ID) {
+		if len(s.pendingCancels) >= orderedQueueSize {
+			s.pendingCancels = slices.Delete(s.pendingCancels, 0, 1)
+		}
+		s.pendingCancels = append(s.pendingCancels, params.ID)
+	}
+	s.mu.Unlock()
+	switch {
+	case answered:
+		s.logInfo(ctx, "Request %s was already answered, nothing to cancel", params.ID)
+	case !ok:
+		s.logInfo(ctx, "Request %s is not in flight, cancelling it if it starts later", params.ID)
+	default:
+		s.logInfo(ctx, "Cancelling request %s", params.ID)
+		request.cancel()
+	}
+}
+
+// takePendingCancelLocked forgets a $/cancelRequest kept for id and reports
+// whether there was one. s.mu must be held.
+func (s *MockLSPServer) takePendingCancelLocked(id jsonrpc.ID) bool {
+	i := slices.Index(s.pendingCancels, id)
+	if i < 0 {
+		return false
+	}
+	s.pendingCancels = slices.Delete(s.pendingCancels, i, i+1)
+	return true
+}
+
+// orderedHandler handles the messages of each connection one at a time in
+// arrival order on a worker goroutine, leaving the read loop free to take
+// $/cancelRequest, which is handled at once
+type orderedHandler struct {
+	handler      jsonrpc.Handler
+	drainTimeout func() time.Duration
+
+	mu     sync.Mutex
+	queues map[jsonrpc.Conn]*orderedQueue
+}
+
+// orderedQueue holds the messages of a connection waiting for its worker
+type orderedQueue struct {
+	messages chan func()
+	// drain is cancelled once the messages queued so far must give up;
+	// shutdown starts a new one for the messages queued after it
+	drain  context.Context
+	cancel context.CancelFunc
+}
+
+// NewOrderedHandler wraps handler so a slow request does not keep the
+// connection from reading the $/cancelRequest meant for it: messages are
+// still handled one at a time in arrival order, but by a worker goroutine
+// per connection, while $/cancelRequest is handled as soon as it is read.
+// Since requests ahead of a shutdown request are still queued rather than
+// in flight when it is handled, the drain timeout starts when shutdown is
+// read: requests not finished by then are cancelled, as shutdown cancels
+// requests in flight. A nil drainTimeout waits for them.
+func NewOrderedHandler(handler jsonrpc.Handler, drainTimeout func() time.Duration) jsonrpc.Handler {
+	return &orderedHandler{handler: handler, drainTimeout: drainTimeout, queues: make(map[jsonrpc.Conn]*orderedQueue)}
+}
+
+// Handle implements jsonrpc.Handler
+func (h *orderedHandler) Handle(ctx context.Context, conn jsonrpc.Conn, req *jsonrpc.Request) {
+	if req.Method == cancelRequestMethod {
+		h.handler.Handle(ctx, conn, req)
+		return
+	}
+	queue, drain := h.queue(conn, req.Method == "shutdown" && !req.Notif)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(drain, cancel)
+	queue <- func() {
+		defer cancel()
+		defer stop()
+		h.handler.Handle(ctx, conn, req)
+	}
+}
+
+// queue returns the queue of the worker of conn, starting the worker on the
+// first message, and the context draining the message about to be queued.
+// shutdown starts the drain timeout of the messages queued before.
+func (h *orderedHandler) queue(conn jsonrpc.Conn, shutdown bool) (chan func(), context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	queue, ok := h.queues[conn]
+	if !ok {
+		queue = &orderedQueue{messages: make(chan func(), orderedQueueSize)}
+		queue.drain, queue.cancel = context.WithCancel(context.Background())
+		h.queues[conn] = queue
+		go h.work(conn, queue.messages)
+	}
+	if shutdown && h.drainTimeout != nil {
+		time.AfterFunc(h.drainTimeout(), queue.cancel)
+		queue.drain, queue.cancel = context.WithCancel(context.Background())
+	}
+	return queue.messages, queue.drain
+}
+
+// work handles the queued messages of conn until it disconnects, then the
+// messages still queued, such as a final exit notification
+func (h *orderedHandler) work(conn jsonrpc.Conn, queue chan func()) {
+	for {
+		select {
+		case handle := <-queue:
+			handle()
+		case <-conn.DisconnectNotify():
+			h.mu.Lock()
+			delete(h.queues, conn)
+			h.mu.Unlock()
+			for {
+				select {
+				case handle := <-queue:
+					handle()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
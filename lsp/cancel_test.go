@@ -0,0 +1,142 @@
+package lsp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+// connectOrderedTestClient is connectTestClient with the server handling
+// messages through NewOrderedHandler, as the server binary does
+func connectOrderedTestClient(t *testing.T, server *MockLSPServer, notify func(*jsonrpc.Request)) jsonrpc.Conn {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), NewOrderedHandler(server, server.ShutdownDrainTimeout), server.ConnOpts()...)
+	clientConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+			if notify != nil {
+				notify(req)
+			}
+			return nil, nil
+		}))
+
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	return clientConn
+}
+
+func TestCancelRequest(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Latency.Delays = map[string]config.Duration{"textDocument/hover": config.Duration(time.Hour)}
+	server.SetConfig(cfg)
+	client := connectOrderedTestClient(t, server, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	params := map[string]any{"textDocument": map[string]any{"uri": "file:///test.go"}, "position": map[string]any{"line": 0, "character": 0}}
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Call(ctx, "textDocument/hover", params, nil, jsonrpc.PickID(jsonrpc.ID{Num: 7}))
+	}()
+	if err := client.Notify(ctx, "$/cancelRequest", map[string]any{"id": 7}); err != nil {
+		t.Fatalf("$/cancelRequest failed: %v", err)
+	}
+
+	select {
+	case err := <-result:
+		if code := errorCode(err); code != int64(ErrorCodeRequestCancelled) {
+			t.Errorf("Expected RequestCancelled, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected the delayed request to be cancelled")
+	}
+	if cancelled := server.Stats().CancelledRequests["textDocument/hover"]; cancelled != 1 {
+		t.Errorf("Expected one cancelled hover request, got %d", cancelled)
+	}
+
+	// Requests after the cancelled one are still served
+	var stats StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &stats); err != nil {
+		t.Errorf("Expected the next request to be served, got %v", err)
+	}
+}
+
+func TestPendingCancels(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+	cancelRequest := func(id uint64) {
+		server.Dispatch(ctx, conn, testRequest(t, 0, cancelRequestMethod, map[string]any{"id": id}))
+	}
+
+	// A cancel for an answered request is not kept for a later one
+	server.Dispatch(ctx, conn, testRequest(t, 1, "$/mockLsp/stats", nil))
+	cancelRequest(1)
+	if len(server.pendingCancels) != 0 {
+		t.Errorf("Expected no cancel kept for an answered request, got %v", server.pendingCancels)
+	}
+
+	// A cancel for a request not started yet is used up as it starts
+	cancelRequest(2)
+	server.Dispatch(ctx, conn, testRequest(t, 2, "$/mockLsp/stats", nil))
+	if response, ok := conn.Response(jsonrpc.ID{Num: 2}); !ok || response.Error == nil || response.Error.Code != int64(ErrorCodeRequestCancelled) {
+		t.Errorf("Expected the request cancelled before it started to be cancelled, got %+v", response)
+	}
+	if len(server.pendingCancels) != 0 {
+		t.Errorf("Expected the cancel to be used up, got %v", server.pendingCancels)
+	}
+
+	// Past orderedQueueSize, the oldest kept cancels are forgotten
+	for id := range uint64(orderedQueueSize + 1) {
+		cancelRequest(100 + id)
+	}
+	if len(server.pendingCancels) != orderedQueueSize || server.pendingCancels[0] != (jsonrpc.ID{Num: 101}) {
+		t.Errorf("Expected the oldest cancel to be forgotten, got %d starting at %v", len(server.pendingCancels), server.pendingCancels[0])
+	}
+}
+
+func TestCancelledReplyDropsResult(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := testRequest(t, 3, "textDocument/hover", map[string]any{})
+	if err := server.reply(ctx, conn, req, map[string]any{"contents": "late"}); err != nil {
+		t.Fatalf("reply failed: %v", err)
+	}
+	response, ok := conn.Response(req.ID)
+	if !ok || response.Error == nil || response.Error.Code != int64(ErrorCodeRequestCancelled) {
+		t.Errorf("Expected RequestCancelled instead of the late result, got %+v", response)
+	}
+}
+
+func TestOrderedHandlerKeepsOrder(t *testing.T) {
+	server := createTestServer()
+	client := connectOrderedTestClient(t, server, nil)
+	ctx := context.Background()
+
+	document := map[string]any{"uri": "file:///order.go", "languageId": "go", "version": 1, "text": "package main\n"}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	var stats StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &stats); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.OpenDocuments != 1 {
+		t.Errorf("Expected the request to see the notification sent before it, got %d open documents", stats.OpenDocuments)
+	}
+}
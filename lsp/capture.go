@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/scenario"
+)
+
+// fixtureCapture accumulates the fixture of a session captured with
+// -capture-fixtures
+type fixtureCapture struct {
+	path    string
+	start   time.Time
+	fixture scenario.Fixture
+}
+
+// positionParams holds the fields shared by the requests made at a position
+type positionParams struct {
+	TextDocument *protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     *protocol.Position               `json:"position"`
+}
+
+// SetCaptureDir turns on fixture capture: the documents the client opens,
+// the positions it asks about and every message it sends are written as a
+// scenario.Fixture to a file in dir on shutdown, exit and WriteCapture. An
+// empty dir turns it off.
+func (s *MockLSPServer) SetCaptureDir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capture = nil
+	if dir != "" {
+		name := fmt.Sprintf("fixture-%s-%s.json", time.Now().Format("20060102-150405"), s.clientID)
+		s.capture = &fixtureCapture{
+			path:    filepath.Join(dir, name),
+			fixture: scenario.Fixture{Documents: []scenario.FixtureDocument{}, Positions: []scenario.FixturePosition{}, Messages: []scenario.FixtureMessage{}},
+		}
+	}
+}
+
+// captureMessage adds a message from the client to the fixture being
+// captured. The inspection requests of test harnesses are left out.
+func (s *MockLSPServer) captureMessage(req *jsonrpc.Request) {
+	s.mu.Lock()
+	capture := s.capture
+	s.mu.Unlock()
+	if capture == nil || strings.HasPrefix(req.Method, inspectionPrefix) {
+		return
+	}
+
+	var params json.RawMessage
+	if req.Params != nil {
+		params = append(params, *req.Params...)
+	}
+	var position *scenario.FixturePosition
+	var document *scenario.FixtureDocument
+	var client string
+	switch req.Method {
+	case "initialize":
+		var init struct {
+			ClientInfo *protocol.ClientInfo `json:"clientInfo"`
+		}
+		if json.Unmarshal(params, &init) == nil && init.ClientInfo != nil {
+			client = strings.TrimSpace(init.ClientInfo.Name + " " + init.ClientInfo.Version)
+		}
+	case "textDocument/didOpen":
+		var open protocol.DidOpenTextDocumentParams
+		if json.Unmarshal(params, &open) == nil {
+			document = &scenario.FixtureDocument{
+				URI:        string(open.TextDocument.Uri),
+				LanguageID: string(open.TextDocument.LanguageId),
+				Version:    open.TextDocument.Version,
+				Text:       open.TextDocument.Text,
+			}
+		}
+	default:
+		var at positionParams
+		if !req.Notif && json.Unmarshal(params, &at) == nil && at.TextDocument != nil && at.Position != nil {
+			uri := string(at.TextDocument.Uri)
+			text, _ := s.documentText(uri)
+			position = &scenario.FixturePosition{
+				Method:    req.Method,
+				URI:       uri,
+				Line:      at.Position.Line,
+				Character: at.Position.Character,
+				Word:      wordAt(text, *at.Position),
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if capture.start.IsZero() {
+		capture.start = now
+	}
+	fixture := &capture.fixture
+	fixture.Messages = append(fixture.Messages, scenario.FixtureMessage{
+		At:      config.Duration(now.Sub(capture.start)),
+		Method:  req.Method,
+		Request: !req.Notif,
+		Params:  params,
+	})
+	if client != "" {
+		fixture.Client = client
+	}
+	if document != nil {
+		fixture.Documents = append(fixture.Documents, *document)
+	}
+	if position != nil {
+		fixture.Positions = append(fixture.Positions, *position)
+	}
+}
+
+// WriteCapture writes the fixture captured so far and returns its path, or
+// an empty path when capture is off. Later writes replace the file.
+func (s *MockLSPServer) WriteCapture() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capture == nil {
+		return "", nil
+	}
+	if err := s.capture.fixture.Write(s.capture.path); err != nil {
+		return "", err
+	}
+	return s.capture.path, nil
+}
+
+// saveCapture writes the captured fixture, if capture is on, and logs where
+func (s *MockLSPServer) saveCapture(ctx context.Context) {
+	path, err := s.WriteCapture()
+	if err != nil {
+		s.logError(ctx, "Failed to write captured fixture: %v", err)
+	} else if path != "" {
+		s.logInfo(ctx, "Captured fixture written to %s", path)
+	}
+}
+
+// wordAt returns the word touching pos in text, or an empty string
+func wordAt(text string, pos protocol.Position) string {
+	rng, ok := wordRangeAt(text, pos)
+	if !ok {
+		return ""
+	}
+	line, _ := lineAt(text, pos.Line)
+	return line[utf16ToByteOffset(line, rng.Start.Character):utf16ToByteOffset(line, rng.End.Character)]
+}
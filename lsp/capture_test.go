@@ -0,0 +1,92 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/scenario"
+)
+
+func TestFixtureCapture(t *testing.T) {
+	dir := t.TempDir()
+	server := createTestServer()
+	server.SetCaptureDir(dir)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	initialize := map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{},
+		"clientInfo": map[string]any{"name": "editor", "version": "1.2"}}
+	if err := client.Call(ctx, "initialize", initialize, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	doc := protocol.TextDocumentItem{Uri: "file:///capture.txt", LanguageId: "plaintext", Text: "first second\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	hover := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: doc.Uri}, Position: protocol.Position{Line: 0, Character: 8}}
+	if err := client.Call(ctx, "textDocument/hover", hover, nil); err != nil {
+		t.Fatalf("Hover failed: %v", err)
+	}
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, nil); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "fixture-*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("Expected one fixture written on shutdown, got %v", paths)
+	}
+	fixture, err := scenario.LoadFixture(paths[0])
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+
+	if fixture.Client != "editor 1.2" {
+		t.Errorf("Expected client editor 1.2, got %q", fixture.Client)
+	}
+	if len(fixture.Documents) != 1 || fixture.Documents[0].Text != doc.Text || fixture.Documents[0].LanguageID != "plaintext" {
+		t.Errorf("Expected the opened document, got %+v", fixture.Documents)
+	}
+	want := scenario.FixturePosition{Method: "textDocument/hover", URI: string(doc.Uri), Line: 0, Character: 8, Word: "second"}
+	if len(fixture.Positions) != 1 || fixture.Positions[0] != want {
+		t.Errorf("Expected position %+v, got %+v", want, fixture.Positions)
+	}
+	var methods []string
+	for _, message := range fixture.Messages {
+		methods = append(methods, message.Method)
+	}
+	wantMethods := []string{"initialize", "textDocument/didOpen", "textDocument/hover", "shutdown"}
+	if len(methods) != len(wantMethods) {
+		t.Fatalf("Expected messages %v, got %v", wantMethods, methods)
+	}
+	for i := range wantMethods {
+		if methods[i] != wantMethods[i] {
+			t.Errorf("Expected message %d to be %s, got %s", i, wantMethods[i], methods[i])
+		}
+	}
+	if !fixture.Messages[2].Request || fixture.Messages[1].Request {
+		t.Errorf("Expected only requests to be marked as requests, got %+v", fixture.Messages)
+	}
+}
+
+func TestWordAt(t *testing.T) {
+	tests := []struct {
+		text string
+		pos  protocol.Position
+		want string
+	}{
+		{"first second\n", protocol.Position{Line: 0, Character: 2}, "first"},
+		{"a \U0001F600 café\n", protocol.Position{Line: 0, Character: 6}, "café"},
+		{"first second\n", protocol.Position{Line: 0, Character: 5}, "first"},
+		{"one\n", protocol.Position{Line: 3, Character: 0}, ""},
+	}
+	for _, tt := range tests {
+		if got := wordAt(tt.text, tt.pos); got != tt.want {
+			t.Errorf("Expected word %q at %+v in %q, got %q", tt.want, tt.pos, tt.text, got)
+		}
+	}
+}
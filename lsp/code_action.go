@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// diagnosticSource is the source of the diagnostics the server publishes,
+// which its quick fixes apply to
+const diagnosticSource = "mock-lsp"
+
+// codeActionKinds returns the configured code action kinds, as announced in
+// the capabilities
+func (s *MockLSPServer) codeActionKinds() []protocol.CodeActionKind {
+	kinds := make([]protocol.CodeActionKind, 0, len(s.config().LSP.CodeActionConfig.Kinds))
+	for _, kind := range s.config().LSP.CodeActionConfig.Kinds {
+		kinds = append(kinds, protocol.CodeActionKind(kind))
+	}
+	return kinds
+}
+
+// codeActionRequested reports whether kind is among the kinds the client
+// asked for. A kind is requested by its own name or by a parent kind, so
+// "refactor" requests "refactor.extract". An empty only requests every kind.
+func codeActionRequested(kind protocol.CodeActionKind, only []protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(only, func(requested protocol.CodeActionKind) bool {
+		return kind == requested || strings.HasPrefix(string(kind), string(requested)+".")
+	})
+}
+
+// codeActions returns the code actions of the configured kinds the client
+// asked for. Each diagnostic of the server in the context gets its quick
+// fixes, and the refactor and source kinds get their configured number of
+// actions on the requested range.
+func (s *MockLSPServer) codeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	cfg := s.config().LSP.CodeActionConfig
+	uri := params.TextDocument.Uri
+	var diagnostics []protocol.Diagnostic
+	for _, diagnostic := range params.Context.Diagnostics {
+		if diagnostic.Source == diagnosticSource {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	actions := []protocol.CodeAction{}
+	add := func(kind protocol.CodeActionKind, title string, edits ...protocol.TextEdit) *protocol.CodeAction {
+		actions = append(actions, protocol.CodeAction{
+			Title: title,
+			Kind:  &kind,
+			Edit:  &protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{uri: edits}},
+		})
+		return &actions[len(actions)-1]
+	}
+	replace := func(text string) protocol.TextEdit {
+		return protocol.TextEdit{Range: params.Range, NewText: text}
+	}
+
+	for _, kind := range s.codeActionKinds() {
+		if !codeActionRequested(kind, params.Context.Only) {
+			continue
+		}
+		switch kind {
+		case protocol.CodeActionKindQuickFix:
+			for _, diagnostic := range diagnostics {
+				for n := 1; n <= cfg.QuickFixes; n++ {
+					action := add(kind, s.message(msgCodeActionQuickFix, n, diagnostic.Message),
+						protocol.TextEdit{Range: diagnostic.Range, NewText: fmt.Sprintf("mockFix%d", n)})
+					action.Diagnostics = []protocol.Diagnostic{diagnostic}
+					action.IsPreferred = n == 1
+				}
+			}
+		case protocol.CodeActionKindSourceFixAll:
+			if len(diagnostics) == 0 {
+				continue
+			}
+			edits := make([]protocol.TextEdit, 0, len(diagnostics))
+			for _, diagnostic := range diagnostics {
+				edits = append(edits, protocol.TextEdit{Range: diagnostic.Range, NewText: "mockFix1"})
+			}
+			add(kind, s.message(msgCodeActionFixAll), edits...).Diagnostics = diagnostics
+		}
+
+		for n := 1; n <= cfg.Refactorings; n++ {
+			switch kind {
+			case protocol.CodeActionKindRefactorExtract:
+				name := fmt.Sprintf("mockExtracted%d", n)
+				add(kind, s.message(msgCodeActionExtract, name),
+					replace(name+"()"),
+					protocol.TextEdit{
+						Range:   protocol.Range{Start: protocol.Position{Line: params.Range.End.Line + 1}, End: protocol.Position{Line: params.Range.End.Line + 1}},
+						NewText: fmt.Sprintf("func %s() {}\n", name),
+					})
+			case protocol.CodeActionKindRefactorInline:
+				name := fmt.Sprintf("mockInlined%d", n)
+				add(kind, s.message(msgCodeActionInline, name), replace(name))
+			case protocol.CodeActionKindRefactorRewrite:
+				name := fmt.Sprintf("mockRewritten%d", n)
+				add(kind, s.message(msgCodeActionRewrite, name), replace(name))
+			case protocol.CodeActionKindSourceOrganizeImports:
+				add(kind, s.message(msgCodeActionOrganize, n),
+					protocol.TextEdit{NewText: fmt.Sprintf("import \"mock/imports%d\"\n", n)})
+			}
+		}
+	}
+	return actions
+}
+
+// handleCodeAction processes textDocument/codeAction requests. In read-only
+// mode every action is disabled. Clients supporting codeAction/resolve get
+// the actions without their edits when resolve is configured.
+func (s *MockLSPServer) handleCodeAction(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.CodeActionParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse code action params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send code action error: %v", replyErr)
+		}
+		return
+	}
+
+	actions := s.disableCodeActions(s.codeActions(params))
+	if s.codeActionResolveSupported() {
+		deferred, err := s.deferCodeActionEdits(params.TextDocument.Uri, actions)
+		if err != nil {
+			s.logError(ctx, "Failed to encode code action data: %v", err)
+		} else {
+			actions = deferred
+		}
+	}
+	s.logInfo(ctx, "Code action request for %s answered with %d actions", params.TextDocument.Uri, len(actions))
+
+	if err := s.reply(ctx, conn, req, actions); err != nil {
+		s.logError(ctx, "Failed to send code action response: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// unresolvedCodeActionsKeep is how many code actions sent without their
+// edit are remembered; older ones can no longer be resolved
+const unresolvedCodeActionsKeep = 256
+
+// unresolvedCodeAction is a code action sent without its edit, with the
+// data the client has to send back to resolve it
+type unresolvedCodeAction struct {
+	data any // As decoded from JSON, for comparison with the echoed data
+	edit *protocol.WorkspaceEdit
+}
+
+// codeActionData returns the data sent with a code action: the ID that
+// resolves it, and values that clients which do not pass data through
+// untouched tend to mangle, such as nulls, empty objects, floats and
+// characters JSON encoders escape
+func codeActionData(id string, uri protocol.DocumentUri) map[string]any {
+	return map[string]any{
+		"id":  id,
+		"uri": string(uri),
+		"probe": map[string]any{
+			"null":    nil,
+			"empty":   map[string]any{},
+			"list":    []any{1, "two", 3.5, false},
+			"unicode": "é😀\u2028<&>",
+			"nested":  map[string]any{"depth": map[string]any{"level": 2}},
+		},
+	}
+}
+
+// codeActionResolveSupported reports whether code actions are sent without
+// their edit, for codeAction/resolve to fill in: resolve is configured and
+// the client declared resolveSupport
+func (s *MockLSPServer) codeActionResolveSupported() bool {
+	if !s.config().LSP.CodeActionConfig.Resolve {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client != nil && slices.Contains(s.client.Capabilities, "textDocument.codeAction.resolveSupport")
+}
+
+// deferCodeActionEdits replaces the edits of the actions for uri with data
+// identifying them, and remembers the edits and data for codeAction/resolve
+func (s *MockLSPServer) deferCodeActionEdits(uri protocol.DocumentUri, actions []protocol.CodeAction) ([]protocol.CodeAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unresolved == nil {
+		s.unresolved = make(map[string]unresolvedCodeAction)
+	}
+	for i := range actions {
+		s.codeActionSeq++
+		id := fmt.Sprint(s.codeActionSeq)
+		data := codeActionData(id, uri)
+
+		encoded, err := encodeWire(data)
+		if err != nil {
+			return nil, err
+		}
+		var sent any
+		if err := json.Unmarshal(encoded, &sent); err != nil {
+			return nil, err
+		}
+		s.unresolved[id] = unresolvedCodeAction{data: sent, edit: actions[i].Edit}
+		s.unresolvedOrder = append(s.unresolvedOrder, id)
+
+		actions[i].Data = data
+		actions[i].Edit = nil
+	}
+	if excess := len(s.unresolvedOrder) - unresolvedCodeActionsKeep; excess > 0 {
+		for _, id := range s.unresolvedOrder[:excess] {
+			delete(s.unresolved, id)
+		}
+		s.unresolvedOrder = slices.Delete(s.unresolvedOrder, 0, excess)
+	}
+	return actions, nil
+}
+
+// dataDifferences describes where got differs from sent, by path
+func dataDifferences(sent, got any, path string) []string {
+	at := path
+	if at == "" {
+		at = "data"
+	}
+	switch s := sent.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: sent an object, got %s", at, describeJSON(got))}
+		}
+		var differences []string
+		keys := slices.Collect(maps.Keys(s))
+		for key := range g {
+			if _, ok := s[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			child := joinSchemaPath(path, key)
+			sentValue, sentOK := s[key]
+			gotValue, gotOK := g[key]
+			switch {
+			case !gotOK:
+				differences = append(differences, child+": missing")
+			case !sentOK:
+				differences = append(differences, fmt.Sprintf("%s: added %s", child, describeJSON(gotValue)))
+			default:
+				differences = append(differences, dataDifferences(sentValue, gotValue, child)...)
+			}
+		}
+		return differences
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(s) {
+			return []string{fmt.Sprintf("%s: sent %s, got %s", at, describeJSON(sent), describeJSON(got))}
+		}
+		var differences []string
+		for i := range s {
+			differences = append(differences, dataDifferences(s[i], g[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return differences
+	}
+	if !reflect.DeepEqual(sent, got) {
+		return []string{fmt.Sprintf("%s: sent %s, got %s", at, describeJSON(sent), describeJSON(got))}
+	}
+	return nil
+}
+
+// describeJSON returns value encoded as JSON, for messages
+func describeJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// handleCodeActionResolve processes codeAction/resolve requests, filling in
+// the edit of an action sent without one. The data of the action has to
+// come back exactly as it was sent; any difference is logged and counted,
+// as clients mangling data is a common bug. Actions whose ID is unknown are
+// returned unchanged.
+func (s *MockLSPServer) handleCodeActionResolve(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var action protocol.CodeAction
+	if req.Params == nil || json.Unmarshal(*req.Params, &action) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse code action",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send code action resolve error: %v", replyErr)
+		}
+		return
+	}
+
+	data, _ := action.Data.(map[string]any)
+	id, _ := data["id"].(string)
+	s.mu.Lock()
+	pending, ok := s.unresolved[id]
+	s.mu.Unlock()
+
+	switch {
+	case action.Data == nil:
+		s.stats.RecordCodeActionDataMismatch()
+		s.logError(ctx, "Code action %q came back without its data", action.Title)
+	case !ok:
+		s.logInfo(ctx, "Not resolving code action %q: unknown or expired data %s", action.Title, describeJSON(action.Data))
+	default:
+		if differences := dataDifferences(pending.data, action.Data, ""); len(differences) > 0 {
+			s.stats.RecordCodeActionDataMismatch()
+			s.logError(ctx, "Code action %q came back with modified data: %s", action.Title, strings.Join(differences, "; "))
+		}
+		action.Edit = pending.edit
+		s.logInfo(ctx, "Resolved code action %q", action.Title)
+	}
+
+	action = s.disableCodeActions([]protocol.CodeAction{action})[0]
+	if err := s.reply(ctx, conn, req, action); err != nil {
+		s.logError(ctx, "Failed to send code action resolve response: %v", err)
+	}
+}
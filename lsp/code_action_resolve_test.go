@@ -0,0 +1,92 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+func TestDataDifferences(t *testing.T) {
+	sent := map[string]any{"id": "1", "probe": map[string]any{"null": nil, "list": []any{1.0, "two"}, "empty": map[string]any{}}}
+	tests := []struct {
+		name string
+		got  any
+		want []string
+	}{
+		{"unchanged", map[string]any{"id": "1", "probe": map[string]any{"null": nil, "list": []any{1.0, "two"}, "empty": map[string]any{}}}, nil},
+		{"null dropped", map[string]any{"id": "1", "probe": map[string]any{"list": []any{1.0, "two"}, "empty": map[string]any{}}}, []string{"probe.null: missing"}},
+		{
+			"values changed",
+			map[string]any{"id": 1.0, "extra": true, "probe": map[string]any{"null": nil, "list": []any{1.0, "2"}, "empty": nil}},
+			[]string{`extra: added true`, `id: sent "1", got 1`, `probe.empty: sent an object, got null`, `probe.list[1]: sent "two", got "2"`},
+		},
+		{"not an object", "1", []string{`data: sent an object, got "1"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dataDifferences(sent, tt.got, ""); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCodeActionResolve(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.CodeActionConfig.Kinds = []string{"refactor.inline"}
+	cfg.LSP.CodeActionConfig.Refactorings = 1
+	cfg.LSP.CodeActionConfig.Resolve = true
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	capabilities := map[string]any{"textDocument": map[string]any{"codeAction": map[string]any{
+		"resolveSupport": map[string]any{"properties": []string{"edit"}},
+	}}}
+	if err := client.Call(ctx, "initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": capabilities}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///resolve.txt"},
+		"range":        map[string]any{"start": map[string]any{"line": 0, "character": 0}, "end": map[string]any{"line": 0, "character": 3}},
+		"context":      map[string]any{"diagnostics": []any{}},
+	}
+	var actions []map[string]any
+	if err := client.Call(ctx, "textDocument/codeAction", params, &actions); err != nil {
+		t.Fatalf("Code action request failed: %v", err)
+	}
+	if len(actions) != 1 || actions[0]["edit"] != nil || actions[0]["data"] == nil {
+		t.Fatalf("Expected an action with data and without an edit, got %v", actions)
+	}
+
+	var resolved map[string]any
+	if err := client.Call(ctx, "codeAction/resolve", actions[0], &resolved); err != nil {
+		t.Fatalf("Code action resolve failed: %v", err)
+	}
+	if resolved["edit"] == nil {
+		t.Errorf("Expected the resolved action to have its edit, got %v", resolved)
+	}
+	if got := server.stats.Snapshot(0).CodeActionDataMismatches; got != 0 {
+		t.Errorf("Expected no data mismatches for an untouched echo, got %d", got)
+	}
+
+	probe := actions[0]["data"].(map[string]any)["probe"].(map[string]any)
+	delete(probe, "null")
+	if err := client.Call(ctx, "codeAction/resolve", actions[0], &resolved); err != nil {
+		t.Fatalf("Code action resolve failed: %v", err)
+	}
+	if resolved["edit"] == nil {
+		t.Errorf("Expected a mangled echo to be resolved anyway, got %v", resolved)
+	}
+	delete(actions[0], "data")
+	if err := client.Call(ctx, "codeAction/resolve", actions[0], &resolved); err != nil {
+		t.Fatalf("Code action resolve failed: %v", err)
+	}
+	if got := server.stats.Snapshot(0).CodeActionDataMismatches; got != 2 {
+		t.Errorf("Expected 2 data mismatches, got %d", got)
+	}
+}
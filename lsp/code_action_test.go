@@ -0,0 +1,116 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestCodeActionRequested(t *testing.T) {
+	tests := []struct {
+		kind protocol.CodeActionKind
+		only []protocol.CodeActionKind
+		want bool
+	}{
+		{protocol.CodeActionKindQuickFix, nil, true},
+		{protocol.CodeActionKindRefactorExtract, []protocol.CodeActionKind{protocol.CodeActionKindRefactor}, true},
+		{protocol.CodeActionKindRefactorExtract, []protocol.CodeActionKind{protocol.CodeActionKindRefactorExtract}, true},
+		{protocol.CodeActionKindSourceOrganizeImports, []protocol.CodeActionKind{protocol.CodeActionKindQuickFix}, false},
+		{"refactorX", []protocol.CodeActionKind{protocol.CodeActionKindRefactor}, false},
+	}
+	for _, tt := range tests {
+		if got := codeActionRequested(tt.kind, tt.only); got != tt.want {
+			t.Errorf("codeActionRequested(%s, %v) = %v, want %v", tt.kind, tt.only, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAction(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.CodeActionConfig.Kinds = []string{"quickfix", "refactor.extract", "refactor.inline", "source.fixAll"}
+	cfg.LSP.CodeActionConfig.QuickFixes = 2
+	cfg.LSP.CodeActionConfig.Refactorings = 1
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	mine := map[string]any{
+		"range":   map[string]any{"start": map[string]any{"line": 1, "character": 0}, "end": map[string]any{"line": 1, "character": 10}},
+		"message": "This is a mock warning",
+		"source":  diagnosticSource,
+	}
+	theirs := map[string]any{
+		"range":   map[string]any{"start": map[string]any{"line": 3, "character": 0}, "end": map[string]any{"line": 3, "character": 4}},
+		"message": "from another server",
+		"source":  "other-lsp",
+	}
+	request := func(only ...string) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": "file:///actions.txt"},
+			"range":        map[string]any{"start": map[string]any{"line": 2, "character": 0}, "end": map[string]any{"line": 2, "character": 8}},
+			"context":      map[string]any{"diagnostics": []any{mine, theirs}, "only": only},
+		}
+	}
+	type action struct {
+		Title       string `json:"title"`
+		Kind        string `json:"kind"`
+		IsPreferred bool   `json:"isPreferred"`
+		Diagnostics []struct {
+			Source string `json:"source"`
+		} `json:"diagnostics"`
+		Edit struct {
+			Changes map[string][]struct {
+				Range   protocol.Range `json:"range"`
+				NewText string         `json:"newText"`
+			} `json:"changes"`
+		} `json:"edit"`
+		Disabled *struct {
+			Reason string `json:"reason"`
+		} `json:"disabled"`
+	}
+
+	var actions []action
+	if err := client.Call(ctx, "textDocument/codeAction", request(), &actions); err != nil {
+		t.Fatalf("Code action request failed: %v", err)
+	}
+	var kinds []string
+	for _, a := range actions {
+		kinds = append(kinds, a.Kind)
+	}
+	if got := strings.Join(kinds, " "); got != "quickfix quickfix refactor.extract refactor.inline source.fixAll" {
+		t.Fatalf("Expected two quick fixes for the server's diagnostic, then one action per kind, got %s", got)
+	}
+	fix := actions[0]
+	edits := fix.Edit.Changes["file:///actions.txt"]
+	if !fix.IsPreferred || actions[1].IsPreferred || len(fix.Diagnostics) != 1 || fix.Diagnostics[0].Source != diagnosticSource {
+		t.Errorf("Expected the first fix to be preferred and tied to the server's diagnostic, got %+v", fix)
+	}
+	if len(edits) != 1 || edits[0].Range.Start.Line != 1 || edits[0].NewText != "mockFix1" {
+		t.Errorf("Expected the fix to replace the diagnostic range, got %+v", edits)
+	}
+	if extract := actions[2].Edit.Changes["file:///actions.txt"]; len(extract) != 2 || extract[0].NewText != "mockExtracted1()" || extract[1].Range.Start.Line != 3 {
+		t.Errorf("Expected the extraction to replace the range and add a function below it, got %+v", extract)
+	}
+
+	if err := client.Call(ctx, "textDocument/codeAction", request("refactor"), &actions); err != nil {
+		t.Fatalf("Code action request failed: %v", err)
+	}
+	if len(actions) != 2 || actions[0].Kind != "refactor.extract" || actions[1].Kind != "refactor.inline" {
+		t.Errorf("Expected only the refactorings, got %+v", actions)
+	}
+
+	cfg.LSP.ReadOnly = true
+	server.SetConfig(cfg)
+	if err := client.Call(ctx, "textDocument/codeAction", request(), &actions); err != nil {
+		t.Fatalf("Code action request failed: %v", err)
+	}
+	for _, a := range actions {
+		if a.Disabled == nil {
+			t.Errorf("Expected %q to be disabled in read-only mode", a.Title)
+		}
+	}
+}
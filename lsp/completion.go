@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// Keys of the completion trigger counts in the stats
+const (
+	triggerNone             = "none"
+	triggerInvoked          = "invoked"
+	triggerCharacter        = "triggerCharacter"
+	triggerIncompleteResult = "triggerForIncompleteCompletions"
+)
+
+// completionTriggerKey returns the stats key of how completion was
+// triggered, including the character for trigger characters
+func completionTriggerKey(cc *protocol.CompletionContext) string {
+	if cc == nil {
+		return triggerNone
+	}
+	switch cc.TriggerKind {
+	case protocol.CompletionTriggerKindInvoked:
+		return triggerInvoked
+	case protocol.CompletionTriggerKindTriggerCharacter:
+		return triggerCharacter + ":" + cc.TriggerCharacter
+	case protocol.CompletionTriggerKindTriggerForIncompleteCompletions:
+		return triggerIncompleteResult
+	}
+	return fmt.Sprintf("%d", cc.TriggerKind)
+}
+
+// completionTrigger returns the character completion is for: the trigger
+// character, or when an incomplete list is completed again, the character
+// before the word being typed. It is empty for invoked completion.
+func completionTrigger(cc *protocol.CompletionContext, text string, pos protocol.Position) string {
+	if cc == nil {
+		return ""
+	}
+	switch cc.TriggerKind {
+	case protocol.CompletionTriggerKindTriggerCharacter:
+		return cc.TriggerCharacter
+	case protocol.CompletionTriggerKindTriggerForIncompleteCompletions:
+		word, ok := wordRangeAt(text, pos)
+		if !ok {
+			return ""
+		}
+		line, _ := lineAt(text, pos.Line)
+		r, size := utf8.DecodeLastRuneInString(line[:utf16ToByteOffset(line, word.Start.Character)])
+		if size == 0 {
+			return ""
+		}
+		return string(r)
+	}
+	return ""
+}
+
+// contextCompletionItems returns member-style items after "." and
+// module-style items after ":", or nil for other triggers
+func (s *MockLSPServer) contextCompletionItems(trigger string) []protocol.CompletionItem {
+	item := func(label string, kind protocol.CompletionItemKind, detail, insertText string) protocol.CompletionItem {
+		return protocol.CompletionItem{Label: label, Kind: &kind, Detail: s.message(detail), InsertText: insertText}
+	}
+
+	switch trigger {
+	case ".":
+		return []protocol.CompletionItem{
+			item("mockMethod", protocol.CompletionItemKindMethod, msgCompletionMemberDetail, "mockMethod()"),
+			item("mockField", protocol.CompletionItemKindField, msgCompletionMemberDetail, ""),
+			item("mockProperty", protocol.CompletionItemKindProperty, msgCompletionMemberDetail, ""),
+		}
+	case ":":
+		return []protocol.CompletionItem{
+			item("mockModule", protocol.CompletionItemKindModule, msgCompletionModuleDetail, ""),
+			item("MockStruct", protocol.CompletionItemKindStruct, msgCompletionModuleDetail, ""),
+			item("MockEnum", protocol.CompletionItemKindEnum, msgCompletionModuleDetail, ""),
+		}
+	}
+	return nil
+}
+
+// kindCount is the number of completion items or symbols of one kind in a
+// mix
+type kindCount[K ~uint32] struct {
+	name  string
+	kind  K
+	count int
+}
+
+// completionMix returns max_items completion items whose kinds follow the
+// configured percentages, or nil if no mix is configured. Kinds are
+// interleaved so clients have to sort and group them, and the deprecated
+// and preselected items are spread evenly over the list.
+func (s *MockLSPServer) completionMix() []protocol.CompletionItem {
+	completion := s.config().LSP.CompletionConfig
+	if len(completion.Kinds) == 0 {
+		return nil
+	}
+	counts := completionKindCounts(completion.Kinds, completion.MaxItems)
+	if counts == nil {
+		return nil
+	}
+
+	items := make([]protocol.CompletionItem, 0, completion.MaxItems)
+	emitted := make([]int, len(counts))
+	for len(items) < completion.MaxItems {
+		for i, kc := range counts {
+			if emitted[i] == kc.count {
+				continue
+			}
+			emitted[i]++
+			items = append(items, completionMixItem(kc, emitted[i]))
+		}
+	}
+
+	for i := range items {
+		if spread(i, completion.Deprecated) {
+			items[i].Deprecated = true
+			items[i].Tags = []protocol.CompletionItemTag{protocol.CompletionItemTagDeprecated}
+		}
+		// Counted from the end, so preselected items are not all deprecated
+		if spread(len(items)-1-i, completion.Preselect) {
+			items[i].Preselect = true
+		}
+	}
+	return items
+}
+
+// completionKindCounts splits total items between the completion item kinds
+// in proportion to their percentages
+func completionKindCounts(kinds map[string]int, total int) []kindCount[protocol.CompletionItemKind] {
+	return kindCounts[protocol.CompletionItemKind](config.CompletionItemKinds, kinds, total)
+}
+
+// kindCounts splits total items between the kinds in proportion to their
+// percentages, giving the items lost to rounding to the largest remainders.
+// names lists the kinds in the order of their LSP values, which start at 1.
+func kindCounts[K ~uint32](names []string, kinds map[string]int, total int) []kindCount[K] {
+	sum := 0
+	for _, name := range names {
+		sum += max(kinds[name], 0)
+	}
+	if sum == 0 {
+		return nil
+	}
+
+	var counts []kindCount[K]
+	remainders := map[string]int{}
+	assigned := 0
+	for i, name := range names {
+		percent := kinds[name]
+		if percent <= 0 {
+			continue
+		}
+		count := total * percent / sum
+		counts = append(counts, kindCount[K]{name: name, kind: K(i + 1), count: count})
+		remainders[name] = total * percent % sum
+		assigned += count
+	}
+
+	byRemainder := slices.Clone(counts)
+	slices.SortStableFunc(byRemainder, func(a, b kindCount[K]) int { return remainders[b.name] - remainders[a.name] })
+	for _, kc := range byRemainder[:total-assigned] {
+		i := slices.IndexFunc(counts, func(c kindCount[K]) bool { return c.name == kc.name })
+		counts[i].count++
+	}
+	return counts
+}
+
+// completionMixItem returns the nth completion item of a kind. Snippets
+// carry a placeholder so clients have to expand them.
+func completionMixItem(kc kindCount[protocol.CompletionItemKind], n int) protocol.CompletionItem {
+	kind := kc.kind
+	item := protocol.CompletionItem{
+		Label: fmt.Sprintf("mock%s%d", pascalCase(kc.name), n),
+		Kind:  &kind,
+	}
+	if kind == protocol.CompletionItemKindSnippet {
+		format := protocol.InsertTextFormatSnippet
+		item.InsertText = item.Label + "(${1:arg})$0"
+		item.InsertTextFormat = &format
+	}
+	return item
+}
+
+// pascalCase converts a snake_case kind name to PascalCase
+func pascalCase(name string) string {
+	var b strings.Builder
+	for part := range strings.SplitSeq(name, "_") {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// completionOrdering sets the configured sortText ordering, filterText and
+// commit characters on items. Each of them is a common source of client
+// bugs: sorting by label, filtering on the label instead of filterText, or
+// applying commit characters of the wrong item.
+func (s *MockLSPServer) completionOrdering(items []protocol.CompletionItem) {
+	completion := s.config().LSP.CompletionConfig
+
+	switch completion.SortText {
+	case config.SortTextReverse:
+		order := make([]int, len(items))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int { return strings.Compare(items[b].Label, items[a].Label) })
+		for rank, i := range order {
+			items[i].SortText = fmt.Sprintf("%05d", rank)
+		}
+	case config.SortTextNumeric:
+		for i := range items {
+			items[i].SortText = fmt.Sprintf("%d_%s", i+1, items[i].Label)
+		}
+	}
+
+	for i := range items {
+		if completion.FilterText {
+			items[i].FilterText = snakeCase(items[i].Label)
+			// Labels of snake_case languages are their own snake case
+			if items[i].FilterText == items[i].Label {
+				items[i].FilterText = strings.ReplaceAll(items[i].Label, "_", "")
+			}
+		}
+		if chars := completion.CommitCharacters; len(chars) > 0 {
+			items[i].CommitCharacters = slices.Clone(chars[:i%(len(chars)+1)])
+		}
+	}
+}
+
+// snakeCase returns label in snake_case, with digits split off as a word,
+// so that it differs from a camelCase label while matching the same word
+func snakeCase(label string) string {
+	var b strings.Builder
+	var prev rune
+	for i, r := range label {
+		upper := unicode.IsUpper(r)
+		digit := unicode.IsDigit(r)
+		if i > 0 && prev != '_' && r != '_' && (upper || digit != unicode.IsDigit(prev)) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+	return b.String()
+}
+
+// spread reports whether item i is among the percent of the items picked
+// at regular intervals
+func spread(i, percent int) bool {
+	return (i+1)*percent/100 > i*percent/100
+}
@@ -0,0 +1,289 @@
+package lsp
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestCompletionKindCounts(t *testing.T) {
+	tests := []struct {
+		name  string
+		kinds map[string]int
+		total int
+		want  map[protocol.CompletionItemKind]int
+	}{
+		{
+			"exact split",
+			map[string]int{"function": 40, "variable": 30, "class": 10, "keyword": 10, "snippet": 10},
+			100,
+			map[protocol.CompletionItemKind]int{
+				protocol.CompletionItemKindFunction: 40,
+				protocol.CompletionItemKindVariable: 30,
+				protocol.CompletionItemKindClass:    10,
+				protocol.CompletionItemKindKeyword:  10,
+				protocol.CompletionItemKindSnippet:  10,
+			},
+		},
+		{
+			"rounded to the largest remainders",
+			map[string]int{"function": 50, "variable": 30, "class": 20},
+			7,
+			map[protocol.CompletionItemKind]int{
+				protocol.CompletionItemKindFunction: 4,
+				protocol.CompletionItemKindVariable: 2,
+				protocol.CompletionItemKindClass:    1,
+			},
+		},
+		{
+			"zero percent kinds left out",
+			map[string]int{"keyword": 100, "snippet": 0},
+			3,
+			map[protocol.CompletionItemKind]int{protocol.CompletionItemKindKeyword: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := completionKindCounts(tt.kinds, tt.total)
+			if len(counts) != len(tt.want) {
+				t.Fatalf("Expected %d kinds, got %+v", len(tt.want), counts)
+			}
+			for _, kc := range counts {
+				if kc.count != tt.want[kc.kind] {
+					t.Errorf("Expected %d %s items, got %d", tt.want[kc.kind], kc.name, kc.count)
+				}
+			}
+		})
+	}
+}
+
+func TestCompletionKindMix(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.CompletionConfig.MaxItems = 20
+	cfg.LSP.CompletionConfig.Kinds = map[string]int{"function": 50, "enum_member": 25, "snippet": 25}
+	cfg.LSP.CompletionConfig.Deprecated = 10
+	cfg.LSP.CompletionConfig.Preselect = 5
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+
+	var list protocol.CompletionList
+	params := protocol.CompletionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///mix.go"}}
+	if err := client.Call(context.Background(), "textDocument/completion", params, &list); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+	if len(list.Items) != 20 {
+		t.Fatalf("Expected 20 items, got %d", len(list.Items))
+	}
+
+	kinds := map[protocol.CompletionItemKind]int{}
+	deprecated, preselected := 0, 0
+	for _, item := range list.Items {
+		kinds[*item.Kind]++
+		if item.Deprecated {
+			deprecated++
+			if len(item.Tags) != 1 || item.Tags[0] != protocol.CompletionItemTagDeprecated {
+				t.Errorf("Expected deprecated item %s to carry the deprecated tag, got %v", item.Label, item.Tags)
+			}
+		}
+		if item.Preselect {
+			preselected++
+		}
+		if *item.Kind == protocol.CompletionItemKindSnippet && (item.InsertTextFormat == nil || *item.InsertTextFormat != protocol.InsertTextFormatSnippet) {
+			t.Errorf("Expected snippet %s to use the snippet insert text format", item.Label)
+		}
+	}
+	if kinds[protocol.CompletionItemKindFunction] != 10 || kinds[protocol.CompletionItemKindEnumMember] != 5 || kinds[protocol.CompletionItemKindSnippet] != 5 {
+		t.Errorf("Expected 10 functions, 5 enum members and 5 snippets, got %v", kinds)
+	}
+	if deprecated != 2 || preselected != 1 {
+		t.Errorf("Expected 2 deprecated and 1 preselected items, got %d and %d", deprecated, preselected)
+	}
+	if list.Items[0].Label != "mockFunction1" || list.Items[1].Label != "mockSnippet1" {
+		t.Errorf("Expected kinds to be interleaved, got %s, %s", list.Items[0].Label, list.Items[1].Label)
+	}
+}
+
+func TestCompletionOrdering(t *testing.T) {
+	labels := []string{"mockFunction1", "mockVariable1", "mockClass1"}
+	tests := []struct {
+		name      string
+		sortText  string
+		wantOrder []string
+	}{
+		{"reverse alphabetical", config.SortTextReverse, []string{"mockVariable1", "mockFunction1", "mockClass1"}},
+		{"numeric prefix", config.SortTextNumeric, []string{"mockFunction1", "mockVariable1", "mockClass1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.CompletionConfig.SortText = tt.sortText
+			server.SetConfig(cfg)
+
+			items := make([]protocol.CompletionItem, len(labels))
+			for i, label := range labels {
+				items[i].Label = label
+			}
+			server.completionOrdering(items)
+
+			slices.SortFunc(items, func(a, b protocol.CompletionItem) int { return strings.Compare(a.SortText, b.SortText) })
+			for i, item := range items {
+				if item.Label != tt.wantOrder[i] {
+					t.Errorf("Expected %s at position %d, got %s (sortText %q)", tt.wantOrder[i], i, item.Label, item.SortText)
+				}
+			}
+		})
+	}
+
+	// Unpadded numeric prefixes put item 10 before item 2
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.CompletionConfig.SortText = config.SortTextNumeric
+	server.SetConfig(cfg)
+	items := make([]protocol.CompletionItem, 10)
+	server.completionOrdering(items)
+	if items[9].SortText >= items[1].SortText {
+		t.Errorf("Expected sortText %q to sort before %q", items[9].SortText, items[1].SortText)
+	}
+}
+
+func TestCompletionFilterAndCommitCharacters(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.CompletionConfig.FilterText = true
+	cfg.LSP.CompletionConfig.CommitCharacters = []string{".", "("}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+
+	var list protocol.CompletionList
+	params := protocol.CompletionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///filter.go"}}
+	if err := client.Call(context.Background(), "textDocument/completion", params, &list); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+
+	wantFilter := []string{"mock_function", "mock_variable", "mock_class"}
+	wantCommit := [][]string{nil, {"."}, {".", "("}}
+	for i, item := range list.Items {
+		if item.FilterText != wantFilter[i] {
+			t.Errorf("Expected filterText %q for %s, got %q", wantFilter[i], item.Label, item.FilterText)
+		}
+		if !slices.Equal(item.CommitCharacters, wantCommit[i]) {
+			t.Errorf("Expected commit characters %v for %s, got %v", wantCommit[i], item.Label, item.CommitCharacters)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"mockFunction":    "mock_function",
+		"mockEnumMember3": "mock_enum_member_3",
+		"MockClass":       "mock_class",
+		"mock_function":   "mock_function",
+		"item42x":         "item_42_x",
+	}
+	for label, want := range tests {
+		if got := snakeCase(label); got != want {
+			t.Errorf("Expected snakeCase(%q) = %q, got %q", label, want, got)
+		}
+	}
+}
+
+func TestCompletionContext(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	uri := protocol.DocumentUri("file:///context.txt")
+	doc := protocol.TextDocumentItem{Uri: uri, LanguageId: "plaintext", Text: "x.mo\npkg::Mo\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		context        *protocol.CompletionContext
+		position       protocol.Position
+		wantFirst      string
+		wantIncomplete bool
+	}{
+		{"no context", nil, protocol.Position{}, "mockFunction", false},
+		{"invoked", &protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindInvoked}, protocol.Position{}, "mockFunction", false},
+		{
+			"member trigger",
+			&protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindTriggerCharacter, TriggerCharacter: "."},
+			protocol.Position{Line: 0, Character: 2},
+			"mockMethod",
+			true,
+		},
+		{
+			"module trigger",
+			&protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindTriggerCharacter, TriggerCharacter: ":"},
+			protocol.Position{Line: 1, Character: 5},
+			"mockModule",
+			true,
+		},
+		{"other trigger", &protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindTriggerCharacter, TriggerCharacter: "("}, protocol.Position{}, "mockFunction", false},
+		{
+			"incomplete after member",
+			&protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindTriggerForIncompleteCompletions},
+			protocol.Position{Line: 0, Character: 4},
+			"mockMethod",
+			false,
+		},
+		{
+			"incomplete after module",
+			&protocol.CompletionContext{TriggerKind: protocol.CompletionTriggerKindTriggerForIncompleteCompletions},
+			protocol.Position{Line: 1, Character: 7},
+			"mockModule",
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := protocol.CompletionParams{Context: tt.context}
+			params.TextDocument.Uri = uri
+			params.Position = tt.position
+			// The trigger kind enum cannot go through json.Marshal
+			data, err := encodeWire(params)
+			if err != nil {
+				t.Fatalf("Failed to encode params: %v", err)
+			}
+
+			var list protocol.CompletionList
+			if err := client.Call(ctx, "textDocument/completion", data, &list); err != nil {
+				t.Fatalf("Completion request failed: %v", err)
+			}
+			if len(list.Items) == 0 || list.Items[0].Label != tt.wantFirst {
+				t.Fatalf("Expected first item %s, got %+v", tt.wantFirst, list.Items)
+			}
+			if list.IsIncomplete != tt.wantIncomplete {
+				t.Errorf("Expected isIncomplete %v, got %v", tt.wantIncomplete, list.IsIncomplete)
+			}
+		})
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	want := map[string]int64{
+		"none":                            1,
+		"invoked":                         1,
+		"triggerCharacter:.":              1,
+		"triggerCharacter::":              1,
+		"triggerCharacter:(":              1,
+		"triggerForIncompleteCompletions": 2,
+	}
+	if !maps.Equal(snapshot.CompletionTriggers, want) {
+		t.Errorf("Expected completion triggers %v, got %v", want, snapshot.CompletionTriggers)
+	}
+}
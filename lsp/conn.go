@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"context"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// Replier answers the requests of the client
+type Replier interface {
+	Reply(ctx context.Context, id jsonrpc.ID, result any) error
+	ReplyWithError(ctx context.Context, id jsonrpc.ID, respErr *jsonrpc.Error) error
+}
+
+// Notifier sends notifications and requests to the client
+type Notifier interface {
+	Notify(ctx context.Context, method string, params any, opts ...jsonrpc.CallOption) error
+	Call(ctx context.Context, method string, params, result any, opts ...jsonrpc.CallOption) error
+}
+
+// Conn is the connection handlers serve a request on. Every jsonrpc.Conn
+// implements it for real sessions, and lsptest.Conn records what handlers
+// send for unit tests that need no client.
+type Conn interface {
+	Replier
+	Notifier
+	// DisconnectNotify returns a channel closed when the connection closes
+	DisconnectNotify() <-chan struct{}
+}
+
+var _ Conn = jsonrpc.Conn(nil)
+
+// errorCountingConn counts the error responses to the requests of a method
+// handled through it in the stats
+type errorCountingConn struct {
+	Conn
+	stats  *Stats
+	method string
+}
+
+// ReplyWithError counts the error response, unless it answers a cancelled
+// request, and sends it
+func (c errorCountingConn) ReplyWithError(ctx context.Context, id jsonrpc.ID, respErr *jsonrpc.Error) error {
+	if respErr.Code != int64(ErrorCodeRequestCancelled) {
+		c.stats.RecordErrorResponse(c.method)
+	}
+	return c.Conn.ReplyWithError(ctx, id, respErr)
+}
+
+// underlyingConn returns the connection conn sends through, unwrapping an
+// errorCountingConn, so per-connection state is kept once per connection
+func underlyingConn(conn Conn) Conn {
+	if counting, ok := conn.(errorCountingConn); ok {
+		return counting.Conn
+	}
+	return conn
+}
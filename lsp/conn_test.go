@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+var _ Conn = (*lsptest.Conn)(nil)
+
+// testRequest builds a request, or a notification when id is 0
+func testRequest(t testing.TB, id uint64, method string, params any) *jsonrpc.Request {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Failed to encode %s params: %v", method, err)
+	}
+	raw := json.RawMessage(data)
+	return &jsonrpc.Request{Method: method, Params: &raw, ID: jsonrpc.ID{Num: id}, Notif: id == 0}
+}
+
+func TestDispatchWithoutClient(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	server.Dispatch(ctx, conn, testRequest(t, 1, "textDocument/hover", "not an object"))
+	if m, ok := conn.Response(jsonrpc.ID{Num: 1}); !ok || m.Kind != lsptest.KindError || m.Error.Code != jsonrpc.CodeInvalidParams {
+		t.Errorf("Expected InvalidParams for malformed hover params, got %+v", m)
+	}
+
+	hints := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///unopened.txt"},
+		"range":        map[string]any{"start": map[string]any{"line": 0, "character": 0}, "end": map[string]any{"line": 1, "character": 0}},
+	}
+	server.Dispatch(ctx, conn, testRequest(t, 2, "textDocument/inlayHint", hints))
+	if m, ok := conn.Response(jsonrpc.ID{Num: 2}); !ok || m.Kind != lsptest.KindReply || string(m.Result) != "null" {
+		t.Errorf("Expected null for an unopened document, got %+v", m)
+	}
+
+	document := map[string]any{"uri": "file:///opened.txt", "languageId": "plaintext", "version": 1, "text": "hello\n"}
+	server.Dispatch(ctx, conn, testRequest(t, 0, "textDocument/didOpen", map[string]any{"textDocument": document}))
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	m, err := conn.WaitFor(waitCtx, func(m lsptest.Message) bool { return m.Method == publishDiagnosticsMethod })
+	if err != nil {
+		t.Fatalf("Expected diagnostics to be published after didOpen: %v", err)
+	}
+	var diagnostics struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(m.Params, &diagnostics); err != nil || diagnostics.URI != "file:///opened.txt" {
+		t.Errorf("Expected diagnostics for the opened document, got %s", m.Params)
+	}
+}
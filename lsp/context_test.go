@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/logging"
+)
+
+func TestContextSuffix(t *testing.T) {
+	if suffix := contextSuffix(context.Background()); suffix != "" {
+		t.Errorf("Expected no suffix without fields, got %q", suffix)
+	}
+
+	ctx := logging.ContextWithField(context.Background(), "method", "textDocument/hover")
+	ctx = logging.ContextWithField(ctx, "request_id", "7")
+	ctx = logging.ContextWithField(ctx, "ratio", "100%")
+	if suffix, want := contextSuffix(ctx), " [method=textDocument/hover ratio=100%% request_id=7]"; suffix != want {
+		t.Errorf("Expected %q, got %q", want, suffix)
+	}
+}
+
+func TestHandleLogsRequestFields(t *testing.T) {
+	var logs bytes.Buffer
+	server := NewMockLSPServer(log.New(&logs, "", 0))
+	client := connectTestClient(t, server, nil)
+
+	if err := client.Call(context.Background(), "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	line := logs.String()
+	if !strings.Contains(line, "Shutdown request received") || !strings.Contains(line, "method=shutdown") || !strings.Contains(line, "request_id=") {
+		t.Errorf("Expected the shutdown log to carry method and request_id fields, got %q", line)
+	}
+}
+
+func TestHandleCancelledContext(t *testing.T) {
+	server := createTestServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	serverSide, clientSide := net.Pipe()
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server)
+	client := jsonrpc.NewConn(context.Background(), jsonrpc.NewStream(clientSide), nil)
+	defer client.Close()
+	defer serverConn.Close()
+
+	params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///cancelled.go"}}
+	err := client.Call(context.Background(), "textDocument/hover", params, nil)
+
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeRequestCancelled) {
+		t.Fatalf("Expected a RequestCancelled error, got %v", err)
+	}
+	if server.Stats().Requests["textDocument/hover"] != 1 {
+		t.Errorf("Expected the cancelled request to be counted")
+	}
+}
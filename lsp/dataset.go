@@ -0,0 +1,121 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+// MockDataset is the generated mock data that is expensive to rebuild or
+// must not change between runs: the workspace symbols and the seed of the
+// randomized responses, picked once when the configured seed is 0
+type MockDataset struct {
+	Key     string          `json:"key"` // Hash of the mock data config the dataset was generated from
+	Seed    int64           `json:"seed"`
+	Symbols []DatasetSymbol `json:"symbols"`
+}
+
+// DatasetSymbol is a workspace symbol of a MockDataset. Its file is
+// relative to the workspace root, which differs between sessions.
+type DatasetSymbol struct {
+	Name string `json:"name"`
+	Kind uint32 `json:"kind"`
+	File string `json:"file"`
+	Line uint32 `json:"line"`
+}
+
+// datasetKey hashes everything a dataset is generated from: the mock data
+// config, seed included, and the file extension of the workspace symbols
+func datasetKey(cfg *config.ServerConfig) string {
+	data, _ := json.Marshal(struct {
+		MockData config.MockDataConfig `json:"mock_data"`
+		Ext      string                `json:"ext"`
+	}{cfg.LSP.MockData, symbolExtension(cfg)})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// datasetPath returns the file the dataset with key is cached in
+func datasetPath(dir, key string) string {
+	return filepath.Join(dir, "mock-data-"+key+".json")
+}
+
+// GenerateMockDataset generates the mock dataset of cfg
+func GenerateMockDataset(cfg *config.ServerConfig) *MockDataset {
+	seed := cfg.LSP.MockData.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &MockDataset{
+		Key:     datasetKey(cfg),
+		Seed:    seed,
+		Symbols: generateWorkspaceSymbols(cfg),
+	}
+}
+
+// LoadMockDataset returns the dataset of cfg cached in dir, or generates it
+// and caches it there for later runs. The boolean reports whether the cached
+// dataset was reused. A cached dataset that cannot be read is regenerated.
+func LoadMockDataset(dir string, cfg *config.ServerConfig) (*MockDataset, bool, error) {
+	key := datasetKey(cfg)
+	path := datasetPath(dir, key)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var dataset MockDataset
+		if json.Unmarshal(data, &dataset) == nil && dataset.Key == key {
+			return &dataset, true, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, fmt.Errorf("failed to read mock dataset: %w", err)
+	}
+
+	dataset := GenerateMockDataset(cfg)
+	if err := saveMockDataset(path, dataset); err != nil {
+		return dataset, false, err
+	}
+	return dataset, false, nil
+}
+
+// saveMockDataset writes dataset to path through a temporary file, so
+// concurrent runs never read a partial dataset
+func saveMockDataset(path string, dataset *MockDataset) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mock dataset directory: %w", err)
+	}
+	data, err := json.Marshal(dataset)
+	if err != nil {
+		return fmt.Errorf("failed to encode mock dataset: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write mock dataset: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write mock dataset: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write mock dataset: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write mock dataset: %w", err)
+	}
+	return nil
+}
+
+// SetDataset makes the server answer from dataset instead of generating
+// the mock data itself, seeding the randomized responses with its seed.
+// It must follow SetConfig.
+func (s *MockLSPServer) SetDataset(dataset *MockDataset) {
+	s.dataset = dataset
+	s.SetRandomSource(NewSeededRandomSource(dataset.Seed))
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+func TestLoadMockDataset(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.Seed = 0
+	cfg.LSP.MockData.WorkspaceSymbols = 50
+	cfg.LSP.MockData.SymbolKinds = map[string]int{"class": 40, "function": 60}
+
+	first, reused, err := LoadMockDataset(dir, cfg)
+	if err != nil || reused {
+		t.Fatalf("Expected a generated dataset, got reused %v, err %v", reused, err)
+	}
+	if first.Seed == 0 || len(first.Symbols) != 50 {
+		t.Errorf("Expected a picked seed and 50 symbols, got seed %d and %d symbols", first.Seed, len(first.Symbols))
+	}
+
+	second, reused, err := LoadMockDataset(dir, cfg)
+	if err != nil || !reused {
+		t.Fatalf("Expected the cached dataset, got reused %v, err %v", reused, err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the cached dataset to equal the generated one")
+	}
+
+	cfg.LSP.MockData.Seed = 42
+	other, reused, err := LoadMockDataset(dir, cfg)
+	if err != nil || reused || other.Key == first.Key || other.Seed != 42 {
+		t.Errorf("Expected a new dataset for another seed, got key %s, seed %d, reused %v, err %v", other.Key, other.Seed, reused, err)
+	}
+
+	if err := os.WriteFile(datasetPath(dir, other.Key), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, reused, err := LoadMockDataset(dir, cfg); err != nil || reused {
+		t.Errorf("Expected a corrupt dataset to be regenerated, got reused %v, err %v", reused, err)
+	}
+}
+
+func TestWorkspaceSymbolsFromDataset(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.WorkspaceSymbols = 10
+	cfg.LSP.MockData.SymbolKinds = map[string]int{"class": 100}
+	server.SetConfig(cfg)
+	symbols, count := server.workspaceSymbols()
+	want := slices.Collect(symbols)
+	if len(want) != 10 || count != 10 {
+		t.Fatalf("Expected 10 generated symbols, got %d counted as %d", len(want), count)
+	}
+
+	dataset := GenerateMockDataset(cfg)
+	dataset.Symbols[0].Name = "cachedClass"
+	server.SetDataset(dataset)
+	symbols, count = server.workspaceSymbols()
+	got := slices.Collect(symbols)
+	if len(got) != len(want) || count != len(got) || got[0].Name != "cachedClass" || got[1].Name != want[1].Name || got[1].ContainerName != "class" {
+		t.Errorf("Expected the symbols of the dataset, got %+v", got)
+	}
+
+	cfg.LSP.MockData.WorkspaceSymbols = 5
+	if symbols, count := server.workspaceSymbols(); len(slices.Collect(symbols)) != 5 || count != 5 {
+		t.Errorf("Expected a dataset of another config to be ignored, got %d symbols", count)
+	}
+}
@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"context"
+	"time"
+)
+
+// scheduleDiagnostics publishes diagnostics for uri once the document has
+// not changed for the configured update delay. Each change replaces the
+// pending publication, so a burst of typing produces a single diagnostics
+// set for its final state, as with real servers.
+func (s *MockLSPServer) scheduleDiagnostics(ctx context.Context, conn Conn, uri string) {
+	if !s.pushDiagnostics() {
+		return
+	}
+	delay := s.config().LSP.DiagnosticsConfig.UpdateDelay.Duration()
+	ctx = context.WithoutCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.debounced == nil {
+		s.debounced = make(map[string]*time.Timer)
+	}
+	if pending, ok := s.debounced[uri]; ok {
+		pending.Stop()
+		s.stats.RecordCoalescedDiagnostics()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		// A newer change may have replaced this timer just as it fired
+		s.mu.Lock()
+		current := s.debounced[uri] == timer
+		if current {
+			delete(s.debounced, uri)
+		}
+		s.mu.Unlock()
+
+		if current {
+			s.sendMockDiagnostics(ctx, conn, uri)
+		}
+	})
+	s.debounced[uri] = timer
+}
+
+// cancelDiagnostics drops the pending diagnostics publication of uri, if
+// any
+func (s *MockLSPServer) cancelDiagnostics(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pending, ok := s.debounced[uri]; ok {
+		pending.Stop()
+		delete(s.debounced, uri)
+	}
+}
+
+// cancelAllDiagnostics drops every pending diagnostics publication
+func (s *MockLSPServer) cancelAllDiagnostics() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for uri, pending := range s.debounced {
+		pending.Stop()
+		delete(s.debounced, uri)
+	}
+}
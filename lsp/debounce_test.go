@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestDiagnosticsDebounce(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.DiagnosticsConfig.UpdateDelay = config.Duration(delay)
+	server.SetConfig(cfg)
+
+	var mu sync.Mutex
+	published := map[string]int{}
+	client := connectTestClient(t, server, nil)
+	server.OnDiagnosticsPublished(func(params protocol.PublishDiagnosticsParams) {
+		mu.Lock()
+		defer mu.Unlock()
+		published[string(params.Uri)]++
+	})
+	ctx := context.Background()
+	count := func(uri string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return published[uri]
+	}
+
+	typed := "file:///typed.go"
+	closed := "file:///closed.go"
+	for _, uri := range []string{typed, closed} {
+		doc := protocol.TextDocumentItem{Uri: protocol.DocumentUri(uri), LanguageId: "go", Text: "package main\n", Version: 1}
+		if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+			t.Fatalf("didOpen failed: %v", err)
+		}
+	}
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, nil); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if count(typed) != 1 || count(closed) != 1 {
+		t.Fatalf("Expected didOpen to publish immediately, got %v", published)
+	}
+
+	// A typing burst of ten edits
+	for version := int32(2); version <= 11; version++ {
+		change := protocol.DidChangeTextDocumentParams{
+			TextDocument:   protocol.VersionedTextDocumentIdentifier{Uri: protocol.DocumentUri(typed), Version: version},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{partialChange(1, 0, 1, 0, "x")},
+		}
+		if err := client.Notify(ctx, "textDocument/didChange", change); err != nil {
+			t.Fatalf("didChange failed: %v", err)
+		}
+	}
+	// An edit followed by closing the document
+	change := protocol.DidChangeTextDocumentParams{
+		TextDocument:   protocol.VersionedTextDocumentIdentifier{Uri: protocol.DocumentUri(closed), Version: 2},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{partialChange(1, 0, 1, 0, "x")},
+	}
+	if err := client.Notify(ctx, "textDocument/didChange", change); err != nil {
+		t.Fatalf("didChange failed: %v", err)
+	}
+	if err := client.Notify(ctx, "textDocument/didClose", protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{Uri: protocol.DocumentUri(closed)}}); err != nil {
+		t.Fatalf("didClose failed: %v", err)
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if count(typed) != 1 {
+		t.Errorf("Expected no diagnostics before the edits pause, got %d publications", count(typed))
+	}
+	if snapshot.CoalescedDiagnostics != 9 {
+		t.Errorf("Expected 9 coalesced publications, got %d", snapshot.CoalescedDiagnostics)
+	}
+
+	time.Sleep(4 * delay)
+	if got := count(typed); got != 2 {
+		t.Errorf("Expected one publication for the whole burst, got %d after didOpen", got-1)
+	}
+	if got := count(closed); got != 1 {
+		t.Errorf("Expected didClose to cancel the pending publication, got %d", got)
+	}
+}
@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// deprecatedMethod is the mock symbol marked deprecated when
+// lsp.mock_data.deprecated is set. Its use is reported at its selection
+// range, so clients render the strikethrough in the outline and the editor.
+const deprecatedMethod = "mockMethod"
+
+// deprecatedRange is the selection range of deprecatedMethod in the mock
+// document symbols
+var deprecatedRange = protocol.Range{
+	Start: protocol.Position{Line: 5, Character: 4},
+	End:   protocol.Position{Line: 5, Character: 14},
+}
+
+// deprecateSymbols tags deprecatedMethod among symbols and their children
+// with both SymbolTag.Deprecated and the older deprecated flag
+func deprecateSymbols(symbols []protocol.DocumentSymbol) {
+	for i := range symbols {
+		if symbols[i].Name == deprecatedMethod {
+			symbols[i].Tags = append(symbols[i].Tags, protocol.SymbolTagDeprecated)
+			symbols[i].Deprecated = true
+		}
+		deprecateSymbols(symbols[i].Children)
+	}
+}
+
+// deprecateCompletionItems marks the function and method items deprecated,
+// with both the deprecated tag and the older deprecated flag
+func deprecateCompletionItems(items []protocol.CompletionItem) {
+	for i := range items {
+		if items[i].Kind == nil {
+			continue
+		}
+		switch *items[i].Kind {
+		case protocol.CompletionItemKindFunction, protocol.CompletionItemKindMethod:
+			items[i].Tags = append(items[i].Tags, protocol.CompletionItemTagDeprecated)
+			items[i].Deprecated = true
+		}
+	}
+}
+
+// deprecatedDiagnostic reports the use of deprecatedMethod as a hint tagged
+// deprecated
+func (s *MockLSPServer) deprecatedDiagnostic() protocol.Diagnostic {
+	severity := protocol.DiagnosticSeverity(protocol.DiagnosticSeverityHint)
+	return protocol.Diagnostic{
+		Range:    deprecatedRange,
+		Severity: &severity,
+		Message:  s.message(msgDiagnosticDeprecated, deprecatedMethod),
+		Source:   diagnosticSource,
+		Tags:     []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated},
+	}
+}
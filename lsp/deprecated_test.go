@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestDeprecatedMarking(t *testing.T) {
+	for _, deprecated := range []bool{false, true} {
+		t.Run(map[bool]string{false: "off", true: "on"}[deprecated], func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.MockData.Deprecated = deprecated
+			server.SetConfig(cfg)
+
+			var published protocol.PublishDiagnosticsParams
+			server.OnDiagnosticsPublished(func(params protocol.PublishDiagnosticsParams) { published = params })
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			doc := protocol.TextDocumentItem{Uri: "file:///deprecated.txt", LanguageId: "plaintext", Text: "text\n", Version: 1}
+			if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+				t.Fatalf("didOpen failed: %v", err)
+			}
+			document := protocol.TextDocumentIdentifier{Uri: doc.Uri}
+
+			var symbols []protocol.DocumentSymbol
+			if err := client.Call(ctx, "textDocument/documentSymbol", protocol.DocumentSymbolParams{TextDocument: document}, &symbols); err != nil {
+				t.Fatalf("documentSymbol failed: %v", err)
+			}
+			method := symbols[0].Children[0]
+			if got := slices.Contains(method.Tags, protocol.SymbolTagDeprecated); got != deprecated || method.Deprecated != deprecated {
+				t.Errorf("Expected %s deprecated %v, got tags %v and flag %v", method.Name, deprecated, method.Tags, method.Deprecated)
+			}
+			if symbols[0].Deprecated || len(symbols[0].Tags) > 0 {
+				t.Errorf("Expected %s not to be deprecated, got %+v", symbols[0].Name, symbols[0])
+			}
+
+			var list protocol.CompletionList
+			if err := client.Call(ctx, "textDocument/completion", protocol.CompletionParams{TextDocument: document}, &list); err != nil {
+				t.Fatalf("Completion request failed: %v", err)
+			}
+			for _, item := range list.Items {
+				want := deprecated && *item.Kind == protocol.CompletionItemKindFunction
+				if got := slices.Contains(item.Tags, protocol.CompletionItemTagDeprecated); got != want || item.Deprecated != want {
+					t.Errorf("Expected %s deprecated %v, got tags %v and flag %v", item.Label, want, item.Tags, item.Deprecated)
+				}
+			}
+
+			// The stats request returns after the diagnostics were published
+			if err := client.Call(ctx, "$/mockLsp/stats", nil, nil); err != nil {
+				t.Fatalf("Stats request failed: %v", err)
+			}
+			tagged := slices.IndexFunc(published.Diagnostics, func(d protocol.Diagnostic) bool {
+				return slices.Contains(d.Tags, protocol.DiagnosticTagDeprecated)
+			})
+			if (tagged >= 0) != deprecated {
+				t.Fatalf("Expected a deprecated diagnostic %v, got %+v", deprecated, published.Diagnostics)
+			}
+			if deprecated && published.Diagnostics[tagged].Range != method.SelectionRange {
+				t.Errorf("Expected the deprecated diagnostic at %+v, got %+v", method.SelectionRange, published.Diagnostics[tagged].Range)
+			}
+		})
+	}
+}
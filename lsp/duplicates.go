@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// diagnosticSeverities lists every severity defined by the spec
+var diagnosticSeverities = []protocol.DiagnosticSeverity{
+	protocol.DiagnosticSeverityError,
+	protocol.DiagnosticSeverityWarning,
+	protocol.DiagnosticSeverityInformation,
+	protocol.DiagnosticSeverityHint,
+}
+
+// expandDiagnostics adds the duplicate, overlapping and severity-variant
+// diagnostics requested by cfg so client deduplication and merging logic can
+// be exercised. Each original is followed by its generated variants.
+func expandDiagnostics(diagnostics []protocol.Diagnostic, cfg config.DiagnosticsConfig) []protocol.Diagnostic {
+	if cfg.Duplicates == 0 && !cfg.Overlapping && !cfg.SeverityVariants {
+		return diagnostics
+	}
+
+	expanded := make([]protocol.Diagnostic, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		expanded = append(expanded, diagnostic)
+
+		for i := 0; i < cfg.Duplicates; i++ {
+			expanded = append(expanded, diagnostic)
+		}
+
+		if cfg.Overlapping {
+			overlap := diagnostic
+			overlap.Range = overlappingRange(diagnostic.Range)
+			overlap.Message = "Overlapping: " + diagnostic.Message
+			expanded = append(expanded, overlap)
+		}
+
+		if cfg.SeverityVariants {
+			for _, severity := range diagnosticSeverities {
+				if diagnostic.Severity != nil && *diagnostic.Severity == severity {
+					continue
+				}
+				variant := diagnostic
+				variantSeverity := severity
+				variant.Severity = &variantSeverity
+				expanded = append(expanded, variant)
+			}
+		}
+	}
+	return expanded
+}
+
+// overlappingRange returns a range that starts inside rng and extends past
+// its end. Zero-width ranges are overlapped by a range starting at the same
+// position.
+func overlappingRange(rng protocol.Range) protocol.Range {
+	if rng.Start == rng.End {
+		return protocol.Range{
+			Start: rng.Start,
+			End:   protocol.Position{Line: rng.End.Line, Character: rng.End.Character + 1},
+		}
+	}
+
+	shift := uint32(1)
+	if rng.Start.Line == rng.End.Line && rng.End.Character > rng.Start.Character+1 {
+		shift = (rng.End.Character - rng.Start.Character) / 2
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: rng.Start.Line, Character: rng.Start.Character + shift},
+		End:   protocol.Position{Line: rng.End.Line, Character: rng.End.Character + shift},
+	}
+}
@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestExpandDiagnostics(t *testing.T) {
+	severity := protocol.DiagnosticSeverityWarning
+	original := []protocol.Diagnostic{
+		{
+			Range:    protocol.Range{Start: protocol.Position{Line: 1, Character: 0}, End: protocol.Position{Line: 1, Character: 10}},
+			Severity: &severity,
+			Message:  "mock warning",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      config.DiagnosticsConfig
+		expected int
+	}{
+		{name: "Disabled", cfg: config.DiagnosticsConfig{}, expected: 1},
+		{name: "Duplicates", cfg: config.DiagnosticsConfig{Duplicates: 2}, expected: 3},
+		{name: "Overlapping", cfg: config.DiagnosticsConfig{Overlapping: true}, expected: 2},
+		{name: "Severity Variants", cfg: config.DiagnosticsConfig{SeverityVariants: true}, expected: 4},
+		{name: "All", cfg: config.DiagnosticsConfig{Duplicates: 1, Overlapping: true, SeverityVariants: true}, expected: 6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded := expandDiagnostics(original, tc.cfg)
+			if len(expanded) != tc.expected {
+				t.Errorf("Expected %d diagnostics, got %d", tc.expected, len(expanded))
+			}
+		})
+	}
+
+	expanded := expandDiagnostics(original, config.DiagnosticsConfig{Overlapping: true, SeverityVariants: true})
+	overlap := expanded[1]
+	if overlap.Range.Start.Character <= 0 || overlap.Range.Start.Character >= 10 || overlap.Range.End.Character <= 10 {
+		t.Errorf("Expected range overlapping 0-10, got %+v", overlap.Range)
+	}
+
+	seen := map[protocol.DiagnosticSeverity]bool{}
+	for _, variant := range expanded[2:] {
+		if variant.Message != original[0].Message || variant.Range != original[0].Range {
+			t.Errorf("Expected variant identical except for severity, got %+v", variant)
+		}
+		seen[*variant.Severity] = true
+	}
+	if len(seen) != 3 || seen[protocol.DiagnosticSeverityWarning] {
+		t.Errorf("Expected the three other severities, got %v", seen)
+	}
+}
+
+func TestOverlappingRange_ZeroWidth(t *testing.T) {
+	pos := protocol.Position{Line: 3, Character: 4}
+	rng := overlappingRange(protocol.Range{Start: pos, End: pos})
+	if rng.Start != pos || rng.End.Character != 5 {
+		t.Errorf("Expected range covering the zero-width position, got %+v", rng)
+	}
+}
@@ -1,10 +1,11 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
-	"github.com/sourcegraph/jsonrpc2"
+	"mock-lsp-server/jsonrpc"
 )
 
 // LSPErrorCode represents specific error codes for the LSP server
@@ -21,6 +22,7 @@ const (
 	// LSP-specific error codes
 	ErrorCodeServerNotInitialized LSPErrorCode = -32002
 	ErrorCodeUnknownErrorCode     LSPErrorCode = -32001
+	ErrorCodeRequestCancelled     LSPErrorCode = -32800
 
 	// Custom application error codes
 	ErrorCodeDocumentNotFound     LSPErrorCode = -32100
@@ -50,6 +52,8 @@ func (code LSPErrorCode) String() string {
 		return "ServerNotInitialized"
 	case ErrorCodeUnknownErrorCode:
 		return "UnknownErrorCode"
+	case ErrorCodeRequestCancelled:
+		return "RequestCancelled"
 	case ErrorCodeDocumentNotFound:
 		return "DocumentNotFound"
 	case ErrorCodeInvalidDocument:
@@ -109,15 +113,15 @@ func (e *LSPError) formatContext() string {
 	return fmt.Sprintf("[%s]", contextStr)
 }
 
-// ToJSONRPCError converts LSPError to jsonrpc2.Error
-func (e *LSPError) ToJSONRPCError() *jsonrpc2.Error {
+// ToJSONRPCError converts LSPError to jsonrpc.Error
+func (e *LSPError) ToJSONRPCError() *jsonrpc.Error {
 	var data *json.RawMessage
 	if e.Data != nil {
 		if raw, ok := e.Data.(*json.RawMessage); ok {
 			data = raw
 		}
 	}
-	return &jsonrpc2.Error{
+	return &jsonrpc.Error{
 		Code:    int64(e.Code),
 		Message: e.Message,
 		Data:    data,
@@ -181,6 +185,17 @@ func NewDocumentNotFoundError(uri string) *LSPError {
 		WithContext("uri", uri)
 }
 
+func NewUnsupportedSchemeError(uri string, scheme string) *LSPError {
+	return NewLSPError(ErrorCodeInvalidParams, fmt.Sprintf("URI scheme %q is not allowed: %s", scheme, uri)).
+		WithContext("uri", uri).
+		WithContext("scheme", scheme)
+}
+
+func NewRequestCancelledError(method string, cause error) *LSPError {
+	return NewLSPErrorWithCause(ErrorCodeRequestCancelled, fmt.Sprintf("request cancelled: %s", method), cause).
+		WithContext("method", method)
+}
+
 func NewInternalError(message string, cause error) *LSPError {
 	return NewLSPErrorWithCause(ErrorCodeInternalError, message, cause)
 }
@@ -195,8 +210,9 @@ func NewErrorHandler(server *MockLSPServer) *ErrorHandler {
 	return &ErrorHandler{server: server}
 }
 
-// HandleError processes an error and logs it appropriately
-func (eh *ErrorHandler) HandleError(err error, operation string) {
+// HandleError processes an error and logs it appropriately, together with
+// the log fields carried by ctx
+func (eh *ErrorHandler) HandleError(ctx context.Context, err error, operation string) {
 	if err == nil {
 		return
 	}
@@ -204,20 +220,20 @@ func (eh *ErrorHandler) HandleError(err error, operation string) {
 	if lspErr, ok := err.(*LSPError); ok {
 		// Log structured error with context
 		if eh.server.structuredLogger != nil {
-			logger := eh.server.structuredLogger.WithContext("operation", operation).WithContext("error_code", lspErr.Code.String())
+			logger := eh.server.structuredLogger.ForContext(ctx).WithContext("operation", operation).WithContext("error_code", lspErr.Code.String())
 			for k, v := range lspErr.Context {
 				logger = logger.WithContext(k, v)
 			}
 			logger.Error("LSP operation failed: %s", lspErr.Message)
 		} else {
-			eh.server.logError("LSP operation failed [%s]: %v", operation, err)
+			eh.server.logError(ctx, "LSP operation failed [%s]: %v", operation, err)
 		}
 	} else {
 		// Log generic error
 		if eh.server.structuredLogger != nil {
-			eh.server.structuredLogger.WithContext("operation", operation).Error("Operation failed: %v", err)
+			eh.server.structuredLogger.ForContext(ctx).WithContext("operation", operation).Error("Operation failed: %v", err)
 		} else {
-			eh.server.logError("Operation failed [%s]: %v", operation, err)
+			eh.server.logError(ctx, "Operation failed [%s]: %v", operation, err)
 		}
 	}
 }
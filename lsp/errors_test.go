@@ -1,6 +1,7 @@
 package lsp
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -17,6 +18,7 @@ func TestLSPErrorCode_String(t *testing.T) {
 		{ErrorCodeInternalError, "InternalError"},
 		{ErrorCodeServerNotInitialized, "ServerNotInitialized"},
 		{ErrorCodeUnknownErrorCode, "UnknownErrorCode"},
+		{ErrorCodeRequestCancelled, "RequestCancelled"},
 		{ErrorCodeDocumentNotFound, "DocumentNotFound"},
 		{ErrorCodeInvalidDocument, "InvalidDocument"},
 		{ErrorCodeDocumentSyncFailed, "DocumentSyncFailed"},
@@ -197,16 +199,16 @@ func TestErrorHandler(t *testing.T) {
 	}
 
 	// Test HandleError with nil error (should not panic)
-	errorHandler.HandleError(nil, "test_operation")
+	errorHandler.HandleError(context.Background(), nil, "test_operation")
 
 	// Test HandleError with LSPError
 	lspErr := NewLSPError(ErrorCodeInvalidParams, "test error")
 	lspErr = lspErr.WithContext("method", "test")
-	errorHandler.HandleError(lspErr, "test_operation")
+	errorHandler.HandleError(context.Background(), lspErr, "test_operation")
 
 	// Test HandleError with generic error
 	genericErr := errors.New("generic error")
-	errorHandler.HandleError(genericErr, "test_operation")
+	errorHandler.HandleError(context.Background(), genericErr, "test_operation")
 }
 
 func TestErrorHandler_WrapError(t *testing.T) {
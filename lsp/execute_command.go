@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// Outcomes of workspace/applyEdit requests, as counted in the stats
+const (
+	applyEditApplied  = "applied"
+	applyEditRejected = "rejected"
+	applyEditFailed   = "failed"
+)
+
+// commandIDs returns the IDs of the configured commands, sorted
+func (s *MockLSPServer) commandIDs() []string {
+	return slices.Sorted(maps.Keys(s.config().LSP.ExecuteCommand.Commands))
+}
+
+// commandDocument returns the document URI a command was run on: its first
+// argument, either a URI or an object with a uri field
+func commandDocument(arguments []any) (protocol.DocumentUri, bool) {
+	if len(arguments) == 0 {
+		return "", false
+	}
+	switch argument := arguments[0].(type) {
+	case string:
+		return protocol.DocumentUri(argument), argument != ""
+	case map[string]any:
+		uri, ok := argument["uri"].(string)
+		return protocol.DocumentUri(uri), ok && uri != ""
+	}
+	return "", false
+}
+
+// handleExecuteCommand processes workspace/executeCommand requests for the
+// configured commands, answering with their canned result. Commands that
+// apply an edit send workspace/applyEdit in the background, since handlers
+// cannot wait for client responses, and log how the client answered.
+func (s *MockLSPServer) handleExecuteCommand(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.ExecuteCommandParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse execute command params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send execute command error: %v", replyErr)
+		}
+		return
+	}
+
+	command, ok := s.config().LSP.ExecuteCommand.Commands[params.Command]
+	if !ok {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: fmt.Sprintf("unknown command %q", params.Command),
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send execute command error: %v", replyErr)
+		}
+		return
+	}
+	s.logInfo(ctx, "Executing command %s with %d arguments", params.Command, len(params.Arguments))
+
+	if command.ApplyEdit {
+		if uri, ok := commandDocument(params.Arguments); ok {
+			go s.applyCommandEdit(context.WithoutCancel(ctx), conn, params.Command, command, uri)
+		} else {
+			s.logError(ctx, "Command %s applies an edit but its first argument is no document URI", params.Command)
+		}
+	}
+
+	if err := s.reply(ctx, conn, req, command.Result); err != nil {
+		s.logError(ctx, "Failed to send execute command response: %v", err)
+	}
+}
+
+// applyCommandEdit asks the client to apply the edit of a command to uri
+// and records the outcome. Clients that did not declare applyEdit support
+// are not asked.
+func (s *MockLSPServer) applyCommandEdit(ctx context.Context, conn Conn, id string, command config.CommandConfig, uri protocol.DocumentUri) {
+	s.mu.Lock()
+	supported := s.client != nil && slices.Contains(s.client.Capabilities, "workspace.applyEdit")
+	s.mu.Unlock()
+	if !supported {
+		s.logInfo(ctx, "Not applying the edit of command %s: the client does not support workspace/applyEdit", id)
+		return
+	}
+
+	text := command.EditText
+	if text == "" {
+		text = fmt.Sprintf("// mock command %s\n", id)
+	}
+	params := protocol.ApplyWorkspaceEditParams{
+		Label: id,
+		Edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			uri: {{Range: protocol.Range{}, NewText: text}},
+		}},
+	}
+	var result protocol.ApplyWorkspaceEditResult
+	err := s.call(ctx, conn, "workspace/applyEdit", params, &result)
+	switch {
+	case errors.Is(err, errReadOnly):
+		s.stats.RecordApplyEdit(applyEditFailed)
+	case err != nil:
+		s.stats.RecordApplyEdit(applyEditFailed)
+		s.logError(ctx, "workspace/applyEdit of command %s failed: %v", id, err)
+	case !result.Applied:
+		s.stats.RecordApplyEdit(applyEditRejected)
+		s.logError(ctx, "Client rejected the edit of command %s: %s", id, result.FailureReason)
+	default:
+		s.stats.RecordApplyEdit(applyEditApplied)
+		s.logInfo(ctx, "Client applied the edit of command %s to %s", id, uri)
+	}
+}
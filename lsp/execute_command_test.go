@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+func TestCommandDocument(t *testing.T) {
+	tests := []struct {
+		arguments []any
+		want      protocol.DocumentUri
+		ok        bool
+	}{
+		{[]any{"file:///a.go", 1}, "file:///a.go", true},
+		{[]any{map[string]any{"uri": "file:///b.go"}}, "file:///b.go", true},
+		{[]any{map[string]any{"path": "/b.go"}}, "", false},
+		{[]any{1.0}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := commandDocument(tt.arguments); got != tt.want || ok != tt.ok {
+			t.Errorf("commandDocument(%v) = %q, %v, want %q, %v", tt.arguments, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExecuteCommand(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.ExecuteCommand.Commands = map[string]config.CommandConfig{
+		"mock.count": {Result: json.RawMessage(`{"count": 3}`)},
+		"mock.fix":   {ApplyEdit: true, EditText: "fixed\n"},
+	}
+	server.SetConfig(cfg)
+
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	applied := true
+	conn.Answer = func(string, json.RawMessage) (any, error) {
+		return map[string]any{"applied": applied, "failureReason": "read-only buffer"}, nil
+	}
+	ctx := context.Background()
+
+	initialize := map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{"workspace": map[string]any{"applyEdit": true}}}
+	server.Dispatch(ctx, conn, testRequest(t, 1, "initialize", initialize))
+	var initialized struct {
+		Capabilities struct {
+			ExecuteCommandProvider struct {
+				Commands []string `json:"commands"`
+			} `json:"executeCommandProvider"`
+		} `json:"capabilities"`
+	}
+	if m, ok := conn.Response(jsonrpc.ID{Num: 1}); !ok || json.Unmarshal(m.Result, &initialized) != nil {
+		t.Fatalf("Expected an initialize result, got %+v", m)
+	}
+	if got := initialized.Capabilities.ExecuteCommandProvider.Commands; len(got) != 2 || got[0] != "mock.count" || got[1] != "mock.fix" {
+		t.Errorf("Expected the configured commands to be announced, got %v", got)
+	}
+
+	server.Dispatch(ctx, conn, testRequest(t, 2, "workspace/executeCommand", map[string]any{"command": "mock.count"}))
+	if m, ok := conn.Response(jsonrpc.ID{Num: 2}); !ok || string(m.Result) != `{"count":3}` {
+		t.Errorf("Expected the canned result, got %+v", m)
+	}
+
+	server.Dispatch(ctx, conn, testRequest(t, 3, "workspace/executeCommand", map[string]any{"command": "mock.unknown"}))
+	if m, ok := conn.Response(jsonrpc.ID{Num: 3}); !ok || m.Kind != lsptest.KindError || m.Error.Code != jsonrpc.CodeInvalidParams {
+		t.Errorf("Expected InvalidParams for an unknown command, got %+v", m)
+	}
+
+	waitForApplyEdits := func(outcome string, want int64) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if server.stats.Snapshot(0).ApplyEdits[outcome] == want {
+				return
+			}
+		}
+		t.Fatalf("Expected %d %s edits, got %v", want, outcome, server.stats.Snapshot(0).ApplyEdits)
+	}
+
+	server.Dispatch(ctx, conn, testRequest(t, 4, "workspace/executeCommand", map[string]any{"command": "mock.fix", "arguments": []any{"file:///fix.go"}}))
+	if m, ok := conn.Response(jsonrpc.ID{Num: 4}); !ok || string(m.Result) != "null" {
+		t.Errorf("Expected null without a configured result, got %+v", m)
+	}
+	waitForApplyEdits(applyEditApplied, 1)
+	calls := conn.Sent("workspace/applyEdit")
+	var params struct {
+		Label string `json:"label"`
+		Edit  struct {
+			Changes map[string][]struct {
+				NewText string `json:"newText"`
+			} `json:"changes"`
+		} `json:"edit"`
+	}
+	if len(calls) != 1 || json.Unmarshal(calls[0].Params, &params) != nil || params.Label != "mock.fix" || params.Edit.Changes["file:///fix.go"][0].NewText != "fixed\n" {
+		t.Errorf("Expected an edit of the argument document, got %+v", calls)
+	}
+
+	applied = false
+	server.Dispatch(ctx, conn, testRequest(t, 5, "workspace/executeCommand", map[string]any{"command": "mock.fix", "arguments": []any{"file:///fix.go"}}))
+	waitForApplyEdits(applyEditRejected, 1)
+}
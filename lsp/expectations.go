@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// ExpectationFailure is a response the server generated that differs from
+// a scenario expectation for its request
+type ExpectationFailure struct {
+	Method      string   `json:"method"`
+	ID          string   `json:"id"`
+	Differences []string `json:"differences"`
+}
+
+// checkExpectations compares the encoded result of a request with the
+// scenario expectations that apply to it, logging and keeping every
+// difference
+func (s *MockLSPServer) checkExpectations(ctx context.Context, req *jsonrpc.Request, result json.RawMessage) {
+	s.mu.Lock()
+	sc := s.scenario
+	s.mu.Unlock()
+	if sc == nil || len(sc.Expectations) == 0 {
+		return
+	}
+
+	uri, pos := scenarioTarget(req)
+	expectations := sc.MatchingExpectations(req.Method, uri, pos)
+	if len(expectations) > 0 {
+		s.stats.RecordExpectationCheck(req.Method)
+	}
+	for _, expectation := range expectations {
+		differences := expectation.Diff(result)
+		if len(differences) == 0 {
+			continue
+		}
+		for _, difference := range differences {
+			s.logError(ctx, "Response to %s differs from the scenario expectation at %s", req.Method, difference)
+		}
+		s.mu.Lock()
+		s.expectationFails = append(s.expectationFails, ExpectationFailure{Method: req.Method, ID: req.ID.String(), Differences: differences})
+		s.mu.Unlock()
+	}
+}
+
+// ExpectationFailures returns the responses that differed from the
+// scenario expectations so far, in the order they were sent
+func (s *MockLSPServer) ExpectationFailures() []ExpectationFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.expectationFails)
+}
@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/scenario"
+)
+
+func TestScenarioExpectations(t *testing.T) {
+	sc, err := scenario.Parse([]byte(`{"expectations": [
+		{"method": "workspace/symbol", "response": [{"name": "mockClass1", "kind": 5, "containerName": "class"}], "ignore": ["$[*].location"]},
+		{"method": "textDocument/hover", "uri": "file:///drift.txt", "response": {"contents": "outdated"}, "ignore": ["$.range"]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.WorkspaceSymbols = 1
+	cfg.LSP.MockData.SymbolKinds = map[string]int{"class": 100}
+	server.SetConfig(cfg)
+	server.SetScenario(sc)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	if err := client.Call(ctx, "workspace/symbol", map[string]any{"query": ""}, nil); err != nil {
+		t.Fatalf("Workspace symbol request failed: %v", err)
+	}
+	if failures := server.ExpectationFailures(); len(failures) != 0 {
+		t.Fatalf("Expected the symbols to meet the expectation, got %+v", failures)
+	}
+	if code := server.exitCode(); code != cfg.Server.ExitCode(config.ExitWithoutShutdown) {
+		t.Errorf("Expected the without_shutdown exit code, got %d", code)
+	}
+
+	params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///drift.txt"}}
+	if err := client.Call(ctx, "textDocument/hover", params, nil); err != nil {
+		t.Fatalf("Hover failed: %v", err)
+	}
+	failures := server.ExpectationFailures()
+	if len(failures) != 1 || failures[0].Method != "textDocument/hover" || len(failures[0].Differences) == 0 {
+		t.Fatalf("Expected the hover to differ from the expectation, got %+v", failures)
+	}
+	if !strings.HasPrefix(failures[0].Differences[0], "$.contents: ") {
+		t.Errorf("Expected the difference at $.contents, got %q", failures[0].Differences[0])
+	}
+	if code := server.exitCode(); code != cfg.Server.ExitCode(config.ExitExpectationFailure) {
+		t.Errorf("Expected the expectation_failure exit code, got %d", code)
+	}
+}
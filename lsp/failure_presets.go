@@ -0,0 +1,271 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// dispatchFunc serves a request or notification on a connection
+type dispatchFunc func(ctx context.Context, conn Conn, req *jsonrpc.Request)
+
+// failureMiddleware wraps a dispatchFunc to inject a kind of failure
+type failureMiddleware func(next dispatchFunc) dispatchFunc
+
+// failureMiddlewares returns the middleware bundled by the named failure
+// preset, outermost first. Unknown and empty names bundle none.
+func (s *MockLSPServer) failureMiddlewares(preset string) []failureMiddleware {
+	switch preset {
+	case config.FailurePresetFlakyNetwork:
+		// Jittery round trips, responses lost on the way and the odd reset
+		return []failureMiddleware{
+			s.delayRequests(0, 300*time.Millisecond),
+			s.dropReplies(5),
+			s.failRequests(5, protocol.LSPErrorCodesRequestFailed, "connection reset by peer"),
+		}
+	case config.FailurePresetOverloadedServer:
+		// Slow answers, and a fifth of the requests shed outright
+		return []failureMiddleware{
+			s.delayRequests(500*time.Millisecond, 3*time.Second),
+			s.failRequests(20, protocol.LSPErrorCodesServerCancelled, "server overloaded, try again later"),
+		}
+	case config.FailurePresetSlowStart:
+		// A slow initialize, then requests held back while warming up
+		return []failureMiddleware{
+			s.delayInitialize(2 * time.Second),
+			s.warmUp(5 * time.Second),
+		}
+	case config.FailurePresetCrashy:
+		// Handlers that panic now and then, until the server stops
+		// answering altogether
+		return []failureMiddleware{
+			s.hangAfter(20, 100),
+			s.recoverPanics(),
+			s.panicRequests(5),
+		}
+	}
+	return nil
+}
+
+// composeFailures wraps next in middleware, the first outermost
+func composeFailures(next dispatchFunc, middleware []failureMiddleware) dispatchFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
+
+// failureExempt reports whether req is spared injected failures: every
+// notification, the lifecycle requests and the inspection requests of test
+// harnesses, so a session can always be set up, inspected and torn down
+func failureExempt(req *jsonrpc.Request) bool {
+	if req.Notif || strings.HasPrefix(req.Method, inspectionPrefix) {
+		return true
+	}
+	switch req.Method {
+	case "initialize", "shutdown":
+		return true
+	}
+	return false
+}
+
+// sleepContext sleeps for d, returning early with the error of ctx if it is
+// cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// chance returns true for percent out of every hundred calls on average
+func (s *MockLSPServer) chance(percent int) bool {
+	return s.snapshot().random.Intn(100) < percent
+}
+
+// delayRequests delays requests by a duration picked uniformly between low
+// and high
+func (s *MockLSPServer) delayRequests(low, high time.Duration) failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if !failureExempt(req) {
+				delay := low + time.Duration(s.snapshot().random.Float64()*float64(high-low))
+				if err := sleepContext(ctx, delay); err != nil {
+					s.replyCancelled(ctx, conn, req, err)
+					return
+				}
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// failRequests answers percent of the requests with an error of the given
+// code instead of handling them
+func (s *MockLSPServer) failRequests(percent int, code protocol.LSPErrorCodes, message string) failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if failureExempt(req) || !s.chance(percent) {
+				next(ctx, conn, req)
+				return
+			}
+			s.logInfo(ctx, "Failing %s with injected error %d", req.Method, code)
+			lspErr := NewLSPError(LSPErrorCode(code), message).WithContext("method", req.Method)
+			if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+				s.logError(ctx, "Failed to send injected error: %v", err)
+			}
+		}
+	}
+}
+
+// lostReplyConn handles a request without its response ever reaching the
+// client
+type lostReplyConn struct {
+	Conn
+}
+
+// Reply drops the response
+func (lostReplyConn) Reply(context.Context, jsonrpc.ID, any) error {
+	return nil
+}
+
+// ReplyWithError drops the error response
+func (lostReplyConn) ReplyWithError(context.Context, jsonrpc.ID, *jsonrpc.Error) error {
+	return nil
+}
+
+// dropReplies handles percent of the requests as usual but loses their
+// responses, leaving the client waiting
+func (s *MockLSPServer) dropReplies(percent int) failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if !failureExempt(req) && s.chance(percent) {
+				s.logInfo(ctx, "Dropping the response to %s", req.Method)
+				conn = lostReplyConn{conn}
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// delayInitialize delays the initialize request by d
+func (s *MockLSPServer) delayInitialize(d time.Duration) failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if req.Method == "initialize" {
+				if err := sleepContext(ctx, d); err != nil {
+					s.replyCancelled(ctx, conn, req, err)
+					return
+				}
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// warmUp holds requests back until d has passed since the initialized
+// notification, as a server still loading its project would
+func (s *MockLSPServer) warmUp(d time.Duration) failureMiddleware {
+	var (
+		mu    sync.Mutex
+		ready time.Time
+	)
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			mu.Lock()
+			if req.Method == "initialized" && ready.IsZero() {
+				ready = time.Now().Add(d)
+			}
+			wait := time.Until(ready)
+			mu.Unlock()
+
+			if !failureExempt(req) && wait > 0 {
+				s.logInfo(ctx, "Holding %s back for %s while warming up", req.Method, wait)
+				if err := sleepContext(ctx, wait); err != nil {
+					s.replyCancelled(ctx, conn, req, err)
+					return
+				}
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// injectedPanic is the value panicRequests panics with
+type injectedPanic struct {
+	method string
+}
+
+// panicRequests makes the handler of percent of the requests panic
+func (s *MockLSPServer) panicRequests(percent int) failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if !failureExempt(req) && s.chance(percent) {
+				panic(injectedPanic{method: req.Method})
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// recoverPanics answers requests whose handler panicked with an internal
+// error, so the connection outlives the crash
+func (s *MockLSPServer) recoverPanics() failureMiddleware {
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				s.logError(ctx, "Handler of %s panicked: %v", req.Method, recovered)
+				if req.Notif {
+					return
+				}
+				lspErr := NewInternalError(fmt.Sprintf("handler of %s crashed", req.Method), fmt.Errorf("%v", recovered))
+				if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+					s.logError(ctx, "Failed to send crash error: %v", err)
+				}
+			}()
+			next(ctx, conn, req)
+		}
+	}
+}
+
+// hangAfter stops answering requests once a number of them picked between
+// low and high has been served, as a server stuck in a dead loop would
+func (s *MockLSPServer) hangAfter(low, high int) failureMiddleware {
+	var (
+		mu     sync.Mutex
+		served int
+	)
+	limit := low + s.snapshot().random.Intn(high-low+1)
+	return func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			if !failureExempt(req) {
+				mu.Lock()
+				served++
+				hung := served > limit
+				mu.Unlock()
+				if hung {
+					s.logInfo(ctx, "Leaving %s unanswered: hung after %d requests", req.Method, limit)
+					return
+				}
+			}
+			next(ctx, conn, req)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+func TestFailurePresetsBundleMiddleware(t *testing.T) {
+	server := createTestServer()
+	for _, preset := range config.FailurePresets {
+		if middleware := server.failureMiddlewares(preset); len(middleware) == 0 {
+			t.Errorf("Expected %s to bundle middleware", preset)
+		}
+	}
+	if middleware := server.failureMiddlewares(""); middleware != nil {
+		t.Errorf("Expected no middleware without a preset, got %d", len(middleware))
+	}
+}
+
+func TestComposeFailuresOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) failureMiddleware {
+		return func(next dispatchFunc) dispatchFunc {
+			return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+				order = append(order, name)
+				next(ctx, conn, req)
+			}
+		}
+	}
+	handler := composeFailures(func(context.Context, Conn, *jsonrpc.Request) {
+		order = append(order, "handler")
+	}, []failureMiddleware{tag("outer"), tag("inner")})
+
+	handler(context.Background(), lsptest.NewConn(), &jsonrpc.Request{Method: "textDocument/hover"})
+	if got := len(order); got != 3 || order[0] != "outer" || order[1] != "inner" || order[2] != "handler" {
+		t.Errorf("Expected outer, inner, handler, got %v", order)
+	}
+}
+
+// setFailures wraps the route of server in middleware instead of that of
+// the configured failure preset
+func setFailures(server *MockLSPServer, middleware []failureMiddleware) {
+	next := *server.snapshot()
+	next.failures = composeFailures(server.route, middleware)
+	server.current.Store(&next)
+}
+
+// serveWithFailures dispatches req to a server whose route is wrapped in
+// middleware, returning what the server sent
+func serveWithFailures(t *testing.T, server *MockLSPServer, middleware []failureMiddleware, reqs ...*jsonrpc.Request) *lsptest.Conn {
+	t.Helper()
+	setFailures(server, middleware)
+	conn := lsptest.NewConn()
+	t.Cleanup(func() { conn.Close() })
+	for _, req := range reqs {
+		server.Dispatch(context.Background(), conn, req)
+	}
+	return conn
+}
+
+func TestFailRequests(t *testing.T) {
+	server := createTestServer()
+	conn := serveWithFailures(t, server,
+		[]failureMiddleware{server.failRequests(100, protocol.LSPErrorCodesServerCancelled, "overloaded")},
+		testRequest(t, 1, "initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}),
+		testRequest(t, 2, "workspace/symbol", map[string]any{"query": ""}),
+	)
+
+	if m, ok := conn.Response(jsonrpc.ID{Num: 1}); !ok || m.Error != nil {
+		t.Errorf("Expected initialize to be spared, got %+v", m)
+	}
+	m, ok := conn.Response(jsonrpc.ID{Num: 2})
+	if !ok || m.Error == nil || m.Error.Code != int64(protocol.LSPErrorCodesServerCancelled) {
+		t.Errorf("Expected ServerCancelled, got %+v", m)
+	}
+}
+
+func TestDropReplies(t *testing.T) {
+	server := createTestServer()
+	conn := serveWithFailures(t, server,
+		[]failureMiddleware{server.dropReplies(100)},
+		testRequest(t, 1, "workspace/symbol", map[string]any{"query": ""}),
+		testRequest(t, 2, "$/mockLsp/stats", nil),
+	)
+
+	if m, ok := conn.Response(jsonrpc.ID{Num: 1}); ok {
+		t.Errorf("Expected the response to be dropped, got %+v", m)
+	}
+	if _, ok := conn.Response(jsonrpc.ID{Num: 2}); !ok {
+		t.Error("Expected inspection requests to be answered")
+	}
+}
+
+func TestCrashyMiddleware(t *testing.T) {
+	server := createTestServer()
+	conn := serveWithFailures(t, server,
+		[]failureMiddleware{server.hangAfter(1, 1), server.recoverPanics(), server.panicRequests(100)},
+		testRequest(t, 1, "workspace/symbol", map[string]any{"query": ""}),
+		testRequest(t, 2, "workspace/symbol", map[string]any{"query": ""}),
+		testRequest(t, 3, "shutdown", nil),
+	)
+
+	m, ok := conn.Response(jsonrpc.ID{Num: 1})
+	if !ok || m.Error == nil || m.Error.Code != int64(ErrorCodeInternalError) {
+		t.Errorf("Expected the panic to become an internal error, got %+v", m)
+	}
+	if m, ok := conn.Response(jsonrpc.ID{Num: 2}); ok {
+		t.Errorf("Expected no answer once hung, got %+v", m)
+	}
+	if _, ok := conn.Response(jsonrpc.ID{Num: 3}); !ok {
+		t.Error("Expected shutdown to be answered while hung")
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	server := createTestServer()
+	const warmUp = 50 * time.Millisecond
+	start := time.Now()
+	conn := serveWithFailures(t, server,
+		[]failureMiddleware{server.warmUp(warmUp)},
+		testRequest(t, 0, "initialized", map[string]any{}),
+		testRequest(t, 1, "workspace/symbol", map[string]any{"query": ""}),
+	)
+
+	if elapsed := time.Since(start); elapsed < warmUp {
+		t.Errorf("Expected the request to be held back for %s, answered after %s", warmUp, elapsed)
+	}
+	if _, ok := conn.Response(jsonrpc.ID{Num: 1}); !ok {
+		t.Error("Expected the request to be answered after warming up")
+	}
+}
+
+func TestDelayRequestsCancelled(t *testing.T) {
+	server := createTestServer()
+	setFailures(server, []failureMiddleware{server.delayRequests(time.Hour, time.Hour)})
+	conn := lsptest.NewConn()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server.snapshot().failures(ctx, conn, testRequest(t, 1, "workspace/symbol", map[string]any{"query": ""}))
+
+	m, ok := conn.Response(jsonrpc.ID{Num: 1})
+	if !ok || m.Error == nil || m.Error.Code != int64(ErrorCodeRequestCancelled) {
+		t.Errorf("Expected RequestCancelled, got %+v", m)
+	}
+}
@@ -0,0 +1,371 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// featureSpec describes a feature that can be switched on and off at runtime
+type featureSpec struct {
+	method string
+	// capability is the dotted path of the client capability declaring
+	// dynamic registration support, empty if the feature is not registrable
+	capability string
+}
+
+// runtimeFeatures are the features $/mockLsp/setFeatures can toggle, keyed
+// by the names used in the features section of the configuration
+var runtimeFeatures = map[string]featureSpec{
+	"completion":           {"textDocument/completion", "textDocument.completion.dynamicRegistration"},
+	"hover":                {"textDocument/hover", "textDocument.hover.dynamicRegistration"},
+	"definition":           {"textDocument/definition", "textDocument.definition.dynamicRegistration"},
+	"references":           {"textDocument/references", "textDocument.references.dynamicRegistration"},
+	"document_symbol":      {"textDocument/documentSymbol", "textDocument.documentSymbol.dynamicRegistration"},
+	"workspace_symbol":     {"workspace/symbol", "workspace.symbol.dynamicRegistration"},
+	"code_action":          {"textDocument/codeAction", "textDocument.codeAction.dynamicRegistration"},
+	"formatting":           {"textDocument/formatting", "textDocument.formatting.dynamicRegistration"},
+	"range_formatting":     {"textDocument/rangeFormatting", "textDocument.rangeFormatting.dynamicRegistration"},
+	"rename":               {"textDocument/rename", "textDocument.rename.dynamicRegistration"},
+	"signature_help":       {"textDocument/signatureHelp", "textDocument.signatureHelp.dynamicRegistration"},
+	"semantic_tokens":      {"textDocument/semanticTokens", "textDocument.semanticTokens.dynamicRegistration"},
+	"inlay_hint":           {"textDocument/inlayHint", "textDocument.inlayHint.dynamicRegistration"},
+	"folding_range":        {"textDocument/foldingRange", "textDocument.foldingRange.dynamicRegistration"},
+	"selection_range":      {"textDocument/selectionRange", "textDocument.selectionRange.dynamicRegistration"},
+	"linked_editing_range": {"textDocument/linkedEditingRange", "textDocument.linkedEditingRange.dynamicRegistration"},
+	"inline_completion":    {"textDocument/inlineCompletion", "textDocument.inlineCompletion.dynamicRegistration"},
+	"file_operations":      {"workspace/willRenameFiles", ""},
+	"diagnostics":          {publishDiagnosticsMethod, ""},
+}
+
+// companionMethods maps further methods served by a runtime feature to its
+// name, so they are switched on and off along with it
+var companionMethods = map[string]string{
+	"textDocument/prepareRename":             "rename",
+	"textDocument/semanticTokens/full":       "semantic_tokens",
+	"textDocument/semanticTokens/full/delta": "semantic_tokens",
+	"textDocument/semanticTokens/range":      "semantic_tokens",
+	"inlayHint/resolve":                      "inlay_hint",
+	"codeAction/resolve":                     "code_action",
+	"textDocument/diagnostic":                "diagnostics",
+	"workspace/diagnostic":                   "diagnostics",
+	"workspace/willCreateFiles":              "file_operations",
+	"workspace/willDeleteFiles":              "file_operations",
+}
+
+// SetFeaturesParams are the parameters of $/mockLsp/setFeatures
+type SetFeaturesParams struct {
+	// Features maps feature names to whether they are enabled. Features not
+	// listed keep their current state.
+	Features map[string]bool `json:"features"`
+}
+
+// SetFeaturesResult is the response to $/mockLsp/setFeatures
+type SetFeaturesResult struct {
+	// Features is the state of every feature after the change
+	Features map[string]bool `json:"features"`
+	// Registered and Unregistered list the features the server is
+	// registering and unregistering with the client as a result
+	Registered   []string `json:"registered"`
+	Unregistered []string `json:"unregistered"`
+}
+
+// dynamicFeatures returns the features the server registers dynamically
+// with the client sending params: those whose dynamic registration the
+// client supports, if the configuration asks for dynamic registration
+func (s *MockLSPServer) dynamicFeatures(params protocol.InitializeParams) map[string]bool {
+	dynamic := make(map[string]bool)
+	if !s.config().LSP.DynamicRegistration {
+		return dynamic
+	}
+	data, err := encodeWire(params.Capabilities)
+	if err != nil {
+		return dynamic
+	}
+	var capabilities map[string]any
+	if err := json.Unmarshal(data, &capabilities); err != nil {
+		return dynamic
+	}
+	for name, spec := range runtimeFeatures {
+		if spec.capability != "" && declared(capabilities, spec.capability) {
+			dynamic[name] = true
+		}
+	}
+	return dynamic
+}
+
+// featureEnabled reports whether the named feature is switched on
+func (s *MockLSPServer) featureEnabled(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.featureEnabledLocked(name)
+}
+
+// featureEnabledLocked reports whether the named feature is switched on:
+// features toggled at runtime keep their state, the others follow the
+// configuration. The caller must hold s.mu.
+func (s *MockLSPServer) featureEnabledLocked(name string) bool {
+	if disabled, toggled := s.disabledFeatures[name]; toggled {
+		return !disabled
+	}
+	return s.config().LSP.FeatureEnabled(name)
+}
+
+// announceStatically reports whether the named feature belongs in the
+// capabilities of the initialize response: it is switched on and not
+// registered dynamically
+func (s *MockLSPServer) announceStatically(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.featureEnabledLocked(name) && !s.dynamic[name]
+}
+
+// Features returns whether each feature that can be toggled at runtime is
+// currently enabled
+func (s *MockLSPServer) Features() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	features := make(map[string]bool, len(runtimeFeatures))
+	for name := range runtimeFeatures {
+		features[name] = s.featureEnabledLocked(name)
+	}
+	return features
+}
+
+// rejectDisabledFeature answers requests for features disabled in the
+// configuration or switched off at runtime with MethodNotFound, as a server
+// lacking the feature would. It returns true if the request was handled.
+func (s *MockLSPServer) rejectDisabledFeature(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	if req.Notif {
+		return false
+	}
+	for name, spec := range runtimeFeatures {
+		if (spec.method == req.Method || companionMethods[req.Method] == name) && !s.featureEnabled(name) {
+			s.logInfo(ctx, "Rejecting %s: feature %s is disabled", req.Method, name)
+			s.replyMethodNotFound(ctx, conn, req)
+			return true
+		}
+	}
+	return false
+}
+
+// syncRegistrations brings the dynamic registrations of the client in line
+// with the enabled features, registering the features that were switched on
+// and unregistering those that were switched off. Nothing is registered
+// before the client sent initialized. The requests are sent in the
+// background, since handlers cannot wait for client responses, but in the
+// order the features were switched.
+func (s *MockLSPServer) syncRegistrations(ctx context.Context, conn Conn) (registered, unregistered []string) {
+	s.mu.Lock()
+	if s.state != StateInitialized {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	if s.registered == nil {
+		s.registered = make(map[string]bool)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.dynamic)) {
+		enabled := s.featureEnabledLocked(name)
+		switch {
+		case enabled && !s.registered[name]:
+			registered = append(registered, name)
+		case !enabled && s.registered[name]:
+			unregistered = append(unregistered, name)
+		}
+		s.registered[name] = enabled
+	}
+	s.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	var requests []registrationRequest
+	if len(registered) > 0 {
+		params := protocol.RegistrationParams{Registrations: []protocol.Registration{}}
+		for _, name := range registered {
+			params.Registrations = append(params.Registrations, protocol.Registration{
+				Id:              registrationID(name),
+				Method:          runtimeFeatures[name].method,
+				RegisterOptions: s.registerOptions(name),
+			})
+		}
+		requests = append(requests, registrationRequest{ctx: ctx, conn: conn, method: "client/registerCapability", params: params})
+	}
+	if len(unregistered) > 0 {
+		params := protocol.UnregistrationParams{Unregisterations: []protocol.Unregistration{}}
+		for _, name := range unregistered {
+			params.Unregisterations = append(params.Unregisterations, protocol.Unregistration{
+				Id:     registrationID(name),
+				Method: runtimeFeatures[name].method,
+			})
+		}
+		requests = append(requests, registrationRequest{ctx: ctx, conn: conn, method: "client/unregisterCapability", params: params})
+	}
+	if len(requests) > 0 {
+		s.registrationQueueFor(conn).push(requests...)
+	}
+	return registered, unregistered
+}
+
+// registrationRequest is a registerCapability or unregisterCapability
+// request waiting to be sent
+type registrationRequest struct {
+	ctx    context.Context
+	conn   Conn
+	method string
+	params any
+}
+
+// registrationQueue sends the registration requests of a connection from a
+// single goroutine, each once the client answered the one before, so the
+// client sees features registered and unregistered in the order they were
+// switched on and off
+type registrationQueue struct {
+	conn    Conn
+	mu      sync.Mutex
+	pending []registrationRequest
+	wake    chan struct{}
+}
+
+// push queues requests to be sent after those already queued
+func (q *registrationQueue) push(requests ...registrationRequest) {
+	q.mu.Lock()
+	q.pending = append(q.pending, requests...)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued requests in order until the connection closes
+func (q *registrationQueue) run(s *MockLSPServer) {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.conn.DisconnectNotify():
+			return
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			request := q.pending[0]
+			q.pending[0] = registrationRequest{}
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+
+			s.sendRegistration(request.ctx, request.conn, request.method, request.params)
+		}
+	}
+}
+
+// registrationQueueFor returns the registration queue of conn, starting it
+// on first use
+func (s *MockLSPServer) registrationQueueFor(conn Conn) *registrationQueue {
+	key := underlyingConn(conn)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registrations == nil || s.registrations.conn != key {
+		s.registrations = &registrationQueue{conn: key, wake: make(chan struct{}, 1)}
+		go s.registrations.run(s)
+	}
+	return s.registrations
+}
+
+// sendRegistration sends a registerCapability or unregisterCapability
+// request and logs the outcome
+func (s *MockLSPServer) sendRegistration(ctx context.Context, conn Conn, method string, params any) {
+	if err := s.call(ctx, conn, method, params, nil); err != nil {
+		s.logError(ctx, "Client rejected %s: %v", method, err)
+		return
+	}
+	s.logInfo(ctx, "Client accepted %s", method)
+}
+
+// registrationID is the ID under which the named feature is registered, so
+// it can be unregistered later
+func registrationID(name string) string {
+	return "mock-lsp-" + name
+}
+
+// registerOptions are the registration options of the named feature. The
+// null document selector applies the registration to every document the
+// client has open.
+func (s *MockLSPServer) registerOptions(name string) map[string]any {
+	options := map[string]any{"documentSelector": nil}
+	switch name {
+	case "completion":
+		options["triggerCharacters"] = s.completionTriggerCharacters()
+	case "code_action":
+		options["codeActionKinds"] = s.config().LSP.CodeActionConfig.Kinds
+		options["resolveProvider"] = s.config().LSP.CodeActionConfig.Resolve
+	case "rename":
+		options["prepareProvider"] = s.prepareRenameSupported()
+	case "signature_help":
+		options["triggerCharacters"], options["retriggerCharacters"] = s.signatureHelpTriggerCharacters()
+	case "semantic_tokens":
+		options["legend"] = semanticTokensLegend
+		options["full"] = map[string]any{"delta": true}
+		options["range"] = true
+	case "inlay_hint":
+		options["resolveProvider"] = s.config().LSP.InlayHintConfig.Resolve
+	}
+	return options
+}
+
+// handleSetFeatures processes the custom $/mockLsp/setFeatures request or
+// notification, which switches features on and off mid-session
+func (s *MockLSPServer) handleSetFeatures(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params SetFeaturesParams
+	var err error
+	if req.Params == nil {
+		err = fmt.Errorf("missing params")
+	} else if err = json.Unmarshal(*req.Params, &params); err == nil {
+		for name := range params.Features {
+			if _, ok := runtimeFeatures[name]; !ok {
+				err = fmt.Errorf("unknown feature %q", name)
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.logError(ctx, "Invalid $/mockLsp/setFeatures params: %v", err)
+		if !req.Notif {
+			lspErr := NewInvalidParamsError("invalid $/mockLsp/setFeatures params", err)
+			if replyErr := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); replyErr != nil {
+				s.logError(ctx, "Failed to send setFeatures error: %v", replyErr)
+			}
+		}
+		return
+	}
+
+	s.mu.Lock()
+	if s.disabledFeatures == nil {
+		s.disabledFeatures = make(map[string]bool)
+	}
+	for name, enabled := range params.Features {
+		s.disabledFeatures[name] = !enabled
+	}
+	s.mu.Unlock()
+	s.logInfo(ctx, "Features changed: %v", params.Features)
+
+	result := SetFeaturesResult{Features: s.Features(), Registered: []string{}, Unregistered: []string{}}
+	registered, unregistered := s.syncRegistrations(ctx, conn)
+	result.Registered = append(result.Registered, registered...)
+	result.Unregistered = append(result.Unregistered, unregistered...)
+
+	if req.Notif {
+		return
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send setFeatures response: %v", err)
+	}
+}
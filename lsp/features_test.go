@@ -0,0 +1,262 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+// dynamicInitializeParams are initialize params of a client supporting
+// dynamic registration of completion and hover
+var dynamicInitializeParams = json.RawMessage(`{
+	"processId": null,
+	"rootUri": null,
+	"capabilities": {
+		"textDocument": {
+			"completion": {"dynamicRegistration": true},
+			"hover": {"dynamicRegistration": true}
+		}
+	}
+}`)
+
+// nextRegistration waits for the next registration request the client
+// receives
+func nextRegistration(t *testing.T, requests <-chan *jsonrpc.Request) *jsonrpc.Request {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a registration request")
+		return nil
+	}
+}
+
+func TestSetFeaturesDynamicRegistration(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.DynamicRegistration = true
+	server.SetConfig(cfg)
+
+	requests := make(chan *jsonrpc.Request, 10)
+	// The client answers registrations on its read loop, which the server
+	// must keep reading while it handles requests
+	client := connectOrderedTestClient(t, server, func(req *jsonrpc.Request) {
+		if !req.Notif {
+			requests <- req
+		}
+	})
+	ctx := context.Background()
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", dynamicInitializeParams, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if initResult.Capabilities.HoverProvider != nil || initResult.Capabilities.CompletionProvider != nil {
+		t.Error("Expected dynamically registered features to be left out of the capabilities")
+	}
+	if initResult.Capabilities.DefinitionProvider == nil {
+		t.Error("Expected definition to be announced statically")
+	}
+
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+	req := nextRegistration(t, requests)
+	var registration protocol.RegistrationParams
+	if err := json.Unmarshal(*req.Params, &registration); err != nil {
+		t.Fatalf("Failed to decode registration: %v", err)
+	}
+	if req.Method != "client/registerCapability" || len(registration.Registrations) != 2 {
+		t.Fatalf("Expected completion and hover to be registered, got %s %s", req.Method, *req.Params)
+	}
+
+	var result SetFeaturesResult
+	params := SetFeaturesParams{Features: map[string]bool{"hover": false}}
+	if err := client.Call(ctx, "$/mockLsp/setFeatures", params, &result); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	if result.Features["hover"] || !result.Features["completion"] {
+		t.Errorf("Expected only hover to be disabled, got %v", result.Features)
+	}
+	if len(result.Unregistered) != 1 || result.Unregistered[0] != "hover" {
+		t.Errorf("Expected hover to be unregistered, got %v", result.Unregistered)
+	}
+
+	req = nextRegistration(t, requests)
+	var unregistration protocol.UnregistrationParams
+	if err := json.Unmarshal(*req.Params, &unregistration); err != nil {
+		t.Fatalf("Failed to decode unregistration: %v", err)
+	}
+	if req.Method != "client/unregisterCapability" || len(unregistration.Unregisterations) != 1 {
+		t.Fatalf("Expected hover to be unregistered, got %s %s", req.Method, *req.Params)
+	}
+	if got := unregistration.Unregisterations[0]; got.Id != registrationID("hover") || got.Method != "textDocument/hover" {
+		t.Errorf("Expected the hover registration ID, got %+v", got)
+	}
+
+	hover := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///test.go"}}
+	err := client.Call(ctx, "textDocument/hover", hover, nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound for disabled hover, got %v", err)
+	}
+
+	params = SetFeaturesParams{Features: map[string]bool{"hover": true}}
+	if err := client.Call(ctx, "$/mockLsp/setFeatures", params, &result); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	if req := nextRegistration(t, requests); req.Method != "client/registerCapability" {
+		t.Errorf("Expected hover to be registered again, got %s", req.Method)
+	}
+	if err := client.Call(ctx, "textDocument/hover", hover, nil); err != nil {
+		t.Errorf("Expected hover to work once enabled again, got %v", err)
+	}
+}
+
+func TestSetFeaturesStatic(t *testing.T) {
+	server := createTestServer()
+	var received []string
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		received = append(received, req.Method)
+	})
+	ctx := context.Background()
+
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+
+	params := SetFeaturesParams{Features: map[string]bool{"diagnostics": false, "definition": false}}
+	if err := client.Notify(ctx, "$/mockLsp/setFeatures", params); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	doc := protocol.TextDocumentItem{Uri: "file:///static.go", LanguageId: "go", Text: "package main\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	definition := protocol.DefinitionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: doc.Uri}}
+	err := client.Call(ctx, "textDocument/definition", definition, nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound for disabled definition, got %v", err)
+	}
+	if server.Capabilities().DefinitionProvider != nil {
+		t.Error("Expected disabled definition to be left out of the capabilities")
+	}
+	if features := server.Features(); features["diagnostics"] || !features["hover"] {
+		t.Errorf("Expected diagnostics and definition disabled, got %v", features)
+	}
+
+	for _, method := range received {
+		if method == publishDiagnosticsMethod || method == "client/unregisterCapability" {
+			t.Errorf("Expected no %s for a client without dynamic registration", method)
+		}
+	}
+
+	err = client.Call(ctx, "$/mockLsp/setFeatures", SetFeaturesParams{Features: map[string]bool{"call_hierarchy": false}}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeInvalidParams) {
+		t.Errorf("Expected InvalidParams for an unknown feature, got %v", err)
+	}
+}
+
+func TestConfiguredFeatures(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Features["hover"] = false
+	cfg.LSP.CompletionConfig.TriggerCharacters = []string{"@", "#"}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if initResult.Capabilities.HoverProvider != nil {
+		t.Error("Expected hover disabled in the configuration to be left out of the capabilities")
+	}
+	completion := initResult.Capabilities.CompletionProvider
+	if completion == nil || !slices.Equal(completion.TriggerCharacters, []string{"@", "#"}) {
+		t.Errorf("Expected the configured completion trigger characters, got %+v", completion)
+	}
+
+	hover := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///configured.txt"}}
+	err := client.Call(ctx, "textDocument/hover", hover, nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound for hover disabled in the configuration, got %v", err)
+	}
+
+	if err := client.Call(ctx, "$/mockLsp/setFeatures", SetFeaturesParams{Features: map[string]bool{"hover": true}}, nil); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	if server.Capabilities().HoverProvider == nil {
+		t.Error("Expected hover switched on at runtime to override the configuration")
+	}
+}
+
+func TestRuntimeFeaturesMatchConfig(t *testing.T) {
+	if !slices.Equal(slices.Sorted(maps.Keys(runtimeFeatures)), slices.Sorted(slices.Values(config.FeatureNames))) {
+		t.Errorf("Expected the runtime features %v to match config.FeatureNames %v", slices.Sorted(maps.Keys(runtimeFeatures)), config.FeatureNames)
+	}
+}
+
+func TestSetFeaturesRegistrationOrder(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.DynamicRegistration = true
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	// Each request is sent once the client answered the one before
+	var outstanding atomic.Int32
+	conn.Answer = func(method string, _ json.RawMessage) (any, error) {
+		if outstanding.Add(1) > 1 {
+			t.Errorf("Expected %s to wait for the answer to the previous request", method)
+		}
+		defer outstanding.Add(-1)
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	}
+	ctx := context.Background()
+
+	server.Dispatch(ctx, conn, testRequest(t, 1, "initialize", dynamicInitializeParams))
+	server.Dispatch(ctx, conn, testRequest(t, 0, "initialized", protocol.InitializedParams{}))
+	for i, enabled := range []bool{false, true, false} {
+		params := SetFeaturesParams{Features: map[string]bool{"hover": enabled}}
+		server.Dispatch(ctx, conn, testRequest(t, uint64(i+2), "$/mockLsp/setFeatures", params))
+	}
+
+	want := []string{"client/registerCapability", "client/unregisterCapability", "client/registerCapability", "client/unregisterCapability"}
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var got []string
+	if _, err := conn.WaitFor(waitCtx, func(lsptest.Message) bool {
+		got = got[:0]
+		for _, m := range conn.Messages() {
+			if m.Kind == lsptest.KindCall {
+				got = append(got, m.Method)
+			}
+		}
+		return len(got) == len(want)
+	}); err != nil {
+		t.Fatalf("Expected %d registration requests, got %v", len(want), got)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the registrations in the order the features were switched, got %v", got)
+	}
+}
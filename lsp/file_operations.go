@@ -0,0 +1,292 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// defaultFileOperationGlob matches every file and folder, announced when the
+// configuration lists no filters
+const defaultFileOperationGlob = "**/*"
+
+// fileOperationOptions returns the file operations the server is interested
+// in: every create, rename and delete, before and after, of the files
+// matching the configured globs
+func (s *MockLSPServer) fileOperationOptions() *protocol.FileOperationOptions {
+	globs := s.config().LSP.FileOperations.Filters
+	if len(globs) == 0 {
+		globs = []string{defaultFileOperationGlob}
+	}
+	filters := make([]protocol.FileOperationFilter, len(globs))
+	for i, glob := range globs {
+		filters[i] = protocol.FileOperationFilter{Scheme: "file", Pattern: protocol.FileOperationPattern{Glob: glob}}
+	}
+	registration := &protocol.FileOperationRegistrationOptions{Filters: filters}
+	return &protocol.FileOperationOptions{
+		DidCreate:  registration,
+		WillCreate: registration,
+		DidRename:  registration,
+		WillRename: registration,
+		DidDelete:  registration,
+		WillDelete: registration,
+	}
+}
+
+// filePath returns the path of a file URI, ok is false for other schemes
+func filePath(uri string) (p string, ok bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" || parsed.Path == "" {
+		return "", false
+	}
+	return parsed.Path, true
+}
+
+// importPath is a quoted relative path on a line of a document
+type importPath struct {
+	rng   protocol.Range // Range of the path, without the quotes
+	value string
+}
+
+// relativeImports returns the quoted paths starting with ./ or ../ in text,
+// in document order
+func relativeImports(text string) []importPath {
+	var imports []importPath
+	for number, line := range strings.Split(text, "\n") {
+		for offset := 0; offset < len(line); {
+			i := strings.IndexAny(line[offset:], "\"'`")
+			if i < 0 {
+				break
+			}
+			start := offset + i + 1
+			j := strings.IndexByte(line[start:], line[offset+i])
+			if j < 0 {
+				break
+			}
+			end := start + j
+			offset = end + 1
+			value := line[start:end]
+			if !strings.HasPrefix(value, "./") && !strings.HasPrefix(value, "../") {
+				continue
+			}
+			imports = append(imports, importPath{
+				rng: protocol.Range{
+					Start: protocol.Position{Line: uint32(number), Character: utf16Len(line[:start])},
+					End:   protocol.Position{Line: uint32(number), Character: utf16Len(line[:end])},
+				},
+				value: value,
+			})
+		}
+	}
+	return imports
+}
+
+// importedFile reports whether an import resolving to resolved refers to
+// file: the file itself, the file without its extension, or a path inside
+// it when file is a folder. rest is the part of resolved past file and
+// extension whether the import spells out the extension.
+func importedFile(resolved, file string) (rest string, extension, ok bool) {
+	switch {
+	case resolved == file:
+		return "", true, true
+	case strings.HasPrefix(resolved, file+"/"):
+		return resolved[len(file):], true, true
+	case path.Ext(file) != "" && resolved == strings.TrimSuffix(file, path.Ext(file)):
+		return "", false, true
+	}
+	return "", false, false
+}
+
+// relativeImport returns the path of target relative to the folder dir,
+// starting with ./ or ../ as relative imports do
+func relativeImport(dir, target string) string {
+	var from []string
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		from = strings.Split(dir, "/")
+	}
+	to := strings.Split(strings.Trim(target, "/"), "/")
+	common := 0
+	for common < len(from) && common < len(to)-1 && from[common] == to[common] {
+		common++
+	}
+	if common == len(from) {
+		return "./" + strings.Join(to[common:], "/")
+	}
+	return strings.Repeat("../", len(from)-common) + strings.Join(to[common:], "/")
+}
+
+// importEdit returns the workspace edit changing the relative imports of
+// the open documents: edit returns the edit of an import in the folder dir
+// resolving to resolved, ok is false for imports left alone. The rename
+// configuration bounds the documents and the edits per document. Edits
+// overlapping the previous one in the document are dropped, since clients
+// reject overlapping edits. The result is nil when no import changes.
+func (s *MockLSPServer) importEdit(edit func(imp importPath, dir, resolved string) (protocol.TextEdit, bool)) *protocol.WorkspaceEdit {
+	cfg := s.config().LSP.RenameConfig
+	texts := s.documents.Texts()
+
+	changes := make(map[protocol.DocumentUri][]protocol.TextEdit)
+	for _, uri := range slices.Sorted(maps.Keys(texts)) {
+		if len(changes) >= cfg.Files {
+			break
+		}
+		p, ok := filePath(uri)
+		if !ok {
+			continue
+		}
+		dir := path.Dir(p)
+		var edits []protocol.TextEdit
+		for _, imp := range relativeImports(texts[uri]) {
+			if len(edits) >= cfg.EditsPerFile {
+				break
+			}
+			textEdit, ok := edit(imp, dir, path.Join(dir, imp.value))
+			if !ok || len(edits) > 0 && positionBefore(textEdit.Range.Start, edits[len(edits)-1].Range.End) {
+				continue
+			}
+			edits = append(edits, textEdit)
+		}
+		if len(edits) > 0 {
+			changes[protocol.DocumentUri(uri)] = edits
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return &protocol.WorkspaceEdit{Changes: changes}
+}
+
+// renameImportsEdit returns the workspace edit pointing the relative imports
+// of renamed files and folders in the open documents at their new paths,
+// keeping or leaving out the extension as each import did
+func (s *MockLSPServer) renameImportsEdit(files []protocol.FileRename) *protocol.WorkspaceEdit {
+	return s.importEdit(func(imp importPath, dir, resolved string) (protocol.TextEdit, bool) {
+		for _, file := range files {
+			oldPath, oldOK := filePath(file.OldUri)
+			newPath, newOK := filePath(file.NewUri)
+			if !oldOK || !newOK {
+				continue
+			}
+			rest, extension, ok := importedFile(resolved, oldPath)
+			if !ok {
+				continue
+			}
+			target := newPath
+			if !extension {
+				target = strings.TrimSuffix(newPath, path.Ext(newPath))
+			}
+			return protocol.TextEdit{Range: imp.rng, NewText: relativeImport(dir, target+rest)}, true
+		}
+		return protocol.TextEdit{}, false
+	})
+}
+
+// deleteImportsEdit returns the workspace edit removing the lines of the
+// open documents that import deleted files or paths inside deleted folders
+func (s *MockLSPServer) deleteImportsEdit(files []protocol.FileDelete) *protocol.WorkspaceEdit {
+	return s.importEdit(func(imp importPath, _, resolved string) (protocol.TextEdit, bool) {
+		for _, file := range files {
+			p, ok := filePath(file.Uri)
+			if !ok {
+				continue
+			}
+			if _, _, ok := importedFile(resolved, p); !ok {
+				continue
+			}
+			start := protocol.Position{Line: imp.rng.Start.Line}
+			return protocol.TextEdit{Range: protocol.Range{Start: start, End: protocol.Position{Line: start.Line + 1}}}, true
+		}
+		return protocol.TextEdit{}, false
+	})
+}
+
+// replyFileOperation answers a will* file operation request with edit,
+// which is left out in read-only mode since clients apply it
+func (s *MockLSPServer) replyFileOperation(ctx context.Context, conn Conn, req *jsonrpc.Request, files int, edit *protocol.WorkspaceEdit) {
+	if s.readOnly() {
+		edit = nil
+	}
+	if edit != nil {
+		s.logInfo(ctx, "%s: %d files, edits in %d documents", req.Method, files, len(edit.Changes))
+	} else {
+		s.logInfo(ctx, "%s: %d files, no edits", req.Method, files)
+	}
+	if err := s.reply(ctx, conn, req, edit); err != nil {
+		s.logError(ctx, "Failed to send %s response: %v", req.Method, err)
+	}
+}
+
+// replyFileOperationError answers a will* file operation request whose
+// params cannot be parsed
+func (s *MockLSPServer) replyFileOperationError(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+		Code:    jsonrpc.CodeInvalidParams,
+		Message: "failed to parse " + req.Method + " params",
+	}); replyErr != nil {
+		s.logError(ctx, "Failed to send %s error: %v", req.Method, replyErr)
+	}
+}
+
+// handleWillCreateFiles processes workspace/willCreateFiles requests, which
+// never need edits: nothing imports files that do not exist yet
+func (s *MockLSPServer) handleWillCreateFiles(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.CreateFilesParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.replyFileOperationError(ctx, conn, req)
+		return
+	}
+	s.replyFileOperation(ctx, conn, req, len(params.Files), nil)
+}
+
+// handleWillRenameFiles processes workspace/willRenameFiles requests,
+// patching the relative imports of the renamed files in the open documents
+// when the configuration asks for it
+func (s *MockLSPServer) handleWillRenameFiles(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.RenameFilesParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.replyFileOperationError(ctx, conn, req)
+		return
+	}
+	var edit *protocol.WorkspaceEdit
+	if s.config().LSP.FileOperations.RenameImports {
+		edit = s.renameImportsEdit(params.Files)
+	}
+	s.replyFileOperation(ctx, conn, req, len(params.Files), edit)
+}
+
+// handleWillDeleteFiles processes workspace/willDeleteFiles requests,
+// removing the lines importing the deleted files from the open documents
+// when the configuration asks for it
+func (s *MockLSPServer) handleWillDeleteFiles(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.DeleteFilesParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.replyFileOperationError(ctx, conn, req)
+		return
+	}
+	var edit *protocol.WorkspaceEdit
+	if s.config().LSP.FileOperations.DeleteImports {
+		edit = s.deleteImportsEdit(params.Files)
+	}
+	s.replyFileOperation(ctx, conn, req, len(params.Files), edit)
+}
+
+// handleDidFileOperation processes the workspace/didCreateFiles,
+// didRenameFiles and didDeleteFiles notifications, which are only logged:
+// clients report the documents they reopen at the new paths themselves
+func (s *MockLSPServer) handleDidFileOperation(ctx context.Context, _ Conn, req *jsonrpc.Request) {
+	var params struct {
+		Files []json.RawMessage `json:"files"`
+	}
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.logError(ctx, "Failed to parse %s params", req.Method)
+		return
+	}
+	s.logInfo(ctx, "%s: %d files", req.Method, len(params.Files))
+}
@@ -0,0 +1,139 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+
+	"mock-lsp-server/config"
+)
+
+func TestRelativeImport(t *testing.T) {
+	tests := []struct {
+		dir, target, want string
+	}{
+		{"/src", "/src/util.ts", "./util.ts"},
+		{"/src/app", "/src/lib/util", "../lib/util"},
+		{"/src/app", "/util", "../../util"},
+		{"/", "/src/util", "./src/util"},
+	}
+	for _, tt := range tests {
+		if got := relativeImport(tt.dir, tt.target); got != tt.want {
+			t.Errorf("relativeImport(%q, %q) = %q, want %q", tt.dir, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeImports(t *testing.T) {
+	text := "import { a } from './a';\nconst b = require(\"../lib/b\"), c = `pkg`;\n"
+	imports := relativeImports(text)
+	if len(imports) != 2 || imports[0].value != "./a" || imports[1].value != "../lib/b" {
+		t.Fatalf("Expected the two relative imports, got %+v", imports)
+	}
+	want := protocol.Range{Start: protocol.Position{Line: 1, Character: 19}, End: protocol.Position{Line: 1, Character: 27}}
+	if imports[1].rng != want {
+		t.Errorf("Expected %v, got %v", want, imports[1].rng)
+	}
+}
+
+func TestWillRenameFiles(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.FileOperations.RenameImports = true
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	documents := map[string]string{
+		"file:///src/main.ts":     "import { a } from './util';\nimport { b } from './util.ts';\nimport { c } from './other';\n",
+		"file:///src/app/page.ts": "import { a } from '../util';\nimport { d } from '../lib/d';\n",
+	}
+	for uri, text := range documents {
+		document := map[string]any{"uri": uri, "languageId": "typescript", "version": 1, "text": text}
+		if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+			t.Fatalf("didOpen failed: %v", err)
+		}
+	}
+
+	params := map[string]any{"files": []map[string]any{
+		{"oldUri": "file:///src/util.ts", "newUri": "file:///src/lib/helpers.ts"},
+		{"oldUri": "file:///src/lib", "newUri": "file:///src/shared"},
+	}}
+	var edit *protocol.WorkspaceEdit
+	if err := client.Call(ctx, "workspace/willRenameFiles", params, &edit); err != nil {
+		t.Fatalf("willRenameFiles failed: %v", err)
+	}
+	if edit == nil {
+		t.Fatal("Expected a workspace edit")
+	}
+	main := edit.Changes["file:///src/main.ts"]
+	if len(main) != 2 || main[0].NewText != "./lib/helpers" || main[1].NewText != "./lib/helpers.ts" {
+		t.Errorf("Expected the extension to be kept as imported, got %+v", main)
+	}
+	page := edit.Changes["file:///src/app/page.ts"]
+	if len(page) != 2 || page[0].NewText != "../lib/helpers" || page[1].NewText != "../shared/d" {
+		t.Errorf("Expected the file and folder imports to be patched, got %+v", page)
+	}
+
+	params = map[string]any{"files": []map[string]any{{"oldUri": "file:///src/none.ts", "newUri": "file:///src/some.ts"}}}
+	edit = nil
+	if err := client.Call(ctx, "workspace/willRenameFiles", params, &edit); err != nil || edit != nil {
+		t.Errorf("Expected null when no import changes, got %+v and %v", edit, err)
+	}
+}
+
+func TestWillDeleteFiles(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.FileOperations.DeleteImports = true
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	text := "import { a, b } from './a'; import './a.css';\nimport { c } from './c';\n"
+	document := map[string]any{"uri": "file:///src/main.ts", "languageId": "typescript", "version": 1, "text": text}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := map[string]any{"files": []map[string]any{{"uri": "file:///src/a.ts"}, {"uri": "file:///src/a.css"}}}
+	var edit *protocol.WorkspaceEdit
+	if err := client.Call(ctx, "workspace/willDeleteFiles", params, &edit); err != nil {
+		t.Fatalf("willDeleteFiles failed: %v", err)
+	}
+	edits := edit.Changes["file:///src/main.ts"]
+	want := protocol.Range{End: protocol.Position{Line: 1}}
+	if len(edits) != 1 || edits[0].Range != want || edits[0].NewText != "" {
+		t.Errorf("Expected the importing line to be removed once, got %+v", edits)
+	}
+
+	cfg.LSP.ReadOnly = true
+	server.SetConfig(cfg)
+	edit = nil
+	if err := client.Call(ctx, "workspace/willDeleteFiles", params, &edit); err != nil || edit != nil {
+		t.Errorf("Expected null in read-only mode, got %+v and %v", edit, err)
+	}
+}
+
+func TestFileOperationsCapabilities(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.FileOperations.Filters = []string{"**/*.ts", "**/*.go"}
+	server.SetConfig(cfg)
+
+	workspace := server.capabilities().Workspace
+	if workspace == nil || workspace.FileOperations == nil || workspace.FileOperations.WillRename == nil {
+		t.Fatal("Expected the file operations to be announced")
+	}
+	filters := workspace.FileOperations.WillRename.Filters
+	if len(filters) != 2 || filters[1].Pattern.Glob != "**/*.go" || filters[1].Scheme != "file" {
+		t.Errorf("Expected the configured globs, got %+v", filters)
+	}
+
+	cfg.LSP.Features = map[string]bool{"file_operations": false}
+	server.SetConfig(cfg)
+	if server.capabilities().Workspace != nil {
+		t.Error("Expected no file operations with the feature disabled")
+	}
+}
@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// notableClientCapabilities are the client capabilities, as dotted paths
+// into ClientCapabilities, that most often explain differences in behavior
+// between editors
+var notableClientCapabilities = []string{
+	"general.positionEncodings",
+	"textDocument.synchronization.didSave",
+	"textDocument.completion.completionItem.snippetSupport",
+	"textDocument.completion.completionItem.resolveSupport",
+	"textDocument.hover",
+	"textDocument.codeAction.codeActionLiteralSupport",
+	"textDocument.codeAction.resolveSupport",
+	"textDocument.rename.prepareSupport",
+	"textDocument.publishDiagnostics.relatedInformation",
+	"textDocument.diagnostic",
+	"textDocument.semanticTokens",
+	"textDocument.inlayHint",
+	"workspace.applyEdit",
+	"workspace.configuration",
+	"workspace.workspaceFolders",
+	"workspace.didChangeWatchedFiles.dynamicRegistration",
+	"window.workDoneProgress",
+	"window.showDocument.support",
+}
+
+// ClientFingerprint summarizes who connected and with what, so sessions of
+// many editors can be told apart in logs and statistics
+type ClientFingerprint struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Capabilities lists the notable capabilities the client declared
+	Capabilities      []string `json:"capabilities"`
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
+	// Hash identifies the complete set of declared capabilities, so clients
+	// with the same name and version but different settings differ
+	Hash string `json:"hash"`
+}
+
+// newClientFingerprint builds the fingerprint of the client sending params
+func newClientFingerprint(params protocol.InitializeParams) *ClientFingerprint {
+	fp := &ClientFingerprint{Capabilities: []string{}}
+	if params.ClientInfo != nil {
+		fp.Name = params.ClientInfo.Name
+		fp.Version = params.ClientInfo.Version
+	}
+	if params.Capabilities.General != nil {
+		for _, encoding := range params.Capabilities.General.PositionEncodings {
+			fp.PositionEncodings = append(fp.PositionEncodings, string(encoding))
+		}
+	}
+
+	data, err := encodeWire(params.Capabilities)
+	if err != nil {
+		return fp
+	}
+	sum := sha256.Sum256(data)
+	fp.Hash = hex.EncodeToString(sum[:6])
+
+	var capabilities map[string]any
+	if err := json.Unmarshal(data, &capabilities); err != nil {
+		return fp
+	}
+	for _, path := range notableClientCapabilities {
+		if declared(capabilities, path) {
+			fp.Capabilities = append(fp.Capabilities, path)
+		}
+	}
+	return fp
+}
+
+// declared reports whether the value at the dotted path is true, an object,
+// or a non-empty list
+func declared(capabilities map[string]any, path string) bool {
+	var value any = capabilities
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return false
+		}
+		value = object[key]
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case map[string]any:
+		return true
+	case []any:
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+// String formats the fingerprint for a single log line
+func (fp *ClientFingerprint) String() string {
+	name := fp.Name
+	if name == "" {
+		name = "unknown client"
+	}
+	if fp.Version != "" {
+		name += " " + fp.Version
+	}
+	encodings := "default"
+	if len(fp.PositionEncodings) > 0 {
+		encodings = strings.Join(fp.PositionEncodings, ",")
+	}
+	return fmt.Sprintf("%s (capabilities %s, encodings %s, notable: %s)",
+		name, fp.Hash, encodings, strings.Join(fp.Capabilities, " "))
+}
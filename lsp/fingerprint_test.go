@@ -0,0 +1,137 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestNewClientFingerprint(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        string
+		wantName      string
+		wantVersion   string
+		wantCaps      []string
+		wantEncodings []string
+	}{
+		{
+			name:     "no client info or capabilities",
+			params:   `{"processId": null, "rootUri": null, "capabilities": {}}`,
+			wantCaps: []string{},
+		},
+		{
+			name: "editor with notable capabilities",
+			params: `{
+				"processId": null,
+				"rootUri": null,
+				"clientInfo": {"name": "Visual Studio Code", "version": "1.90.0"},
+				"capabilities": {
+					"general": {"positionEncodings": ["utf-16"]},
+					"textDocument": {
+						"completion": {"completionItem": {"snippetSupport": true}},
+						"hover": {},
+						"rename": {"prepareSupport": false}
+					},
+					"window": {"workDoneProgress": true}
+				}
+			}`,
+			wantName:    "Visual Studio Code",
+			wantVersion: "1.90.0",
+			wantCaps: []string{
+				"general.positionEncodings",
+				"textDocument.completion.completionItem.snippetSupport",
+				"textDocument.hover",
+				"window.workDoneProgress",
+			},
+			wantEncodings: []string{"utf-16"},
+		},
+		{
+			name:     "empty position encodings",
+			params:   `{"processId": null, "rootUri": null, "clientInfo": {"name": "Neovim"}, "capabilities": {"general": {"positionEncodings": []}}}`,
+			wantName: "Neovim",
+			wantCaps: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params protocol.InitializeParams
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatalf("Failed to parse params: %v", err)
+			}
+			fp := newClientFingerprint(params)
+
+			if fp.Name != tt.wantName {
+				t.Errorf("Expected name %q, got %q", tt.wantName, fp.Name)
+			}
+			if fp.Version != tt.wantVersion {
+				t.Errorf("Expected version %q, got %q", tt.wantVersion, fp.Version)
+			}
+			if !reflect.DeepEqual(fp.Capabilities, tt.wantCaps) {
+				t.Errorf("Expected capabilities %v, got %v", tt.wantCaps, fp.Capabilities)
+			}
+			if !reflect.DeepEqual(fp.PositionEncodings, tt.wantEncodings) {
+				t.Errorf("Expected encodings %v, got %v", tt.wantEncodings, fp.PositionEncodings)
+			}
+			if len(fp.Hash) != 12 {
+				t.Errorf("Expected a 12 character hash, got %q", fp.Hash)
+			}
+		})
+	}
+}
+
+func TestClientFingerprintHash(t *testing.T) {
+	parse := func(data string) *ClientFingerprint {
+		var params protocol.InitializeParams
+		if err := json.Unmarshal([]byte(data), &params); err != nil {
+			t.Fatalf("Failed to parse params: %v", err)
+		}
+		return newClientFingerprint(params)
+	}
+
+	a := parse(`{"processId": null, "rootUri": null, "clientInfo": {"name": "a"}, "capabilities": {"window": {"workDoneProgress": true}}}`)
+	b := parse(`{"processId": null, "rootUri": null, "clientInfo": {"name": "b"}, "capabilities": {"window": {"workDoneProgress": true}}}`)
+	c := parse(`{"processId": null, "rootUri": null, "clientInfo": {"name": "a"}, "capabilities": {"window": {"workDoneProgress": false}}}`)
+
+	if a.Hash != b.Hash {
+		t.Errorf("Expected equal capabilities to hash alike, got %s and %s", a.Hash, b.Hash)
+	}
+	if a.Hash == c.Hash {
+		t.Errorf("Expected different capabilities to hash differently, got %s for both", a.Hash)
+	}
+}
+
+func TestClientFingerprintInStats(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	if fp := server.ClientFingerprint(); fp != nil {
+		t.Errorf("Expected no fingerprint before initialize, got %+v", fp)
+	}
+
+	params := protocol.InitializeParams{ClientInfo: &protocol.ClientInfo{Name: "Helix", Version: "24.07"}}
+	if err := client.Call(ctx, "initialize", params, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if snapshot.Client == nil {
+		t.Fatal("Expected a client fingerprint in the stats")
+	}
+	if snapshot.Client.Name != "Helix" || snapshot.Client.Version != "24.07" {
+		t.Errorf("Expected Helix 24.07, got %s %s", snapshot.Client.Name, snapshot.Client.Version)
+	}
+
+	if got := server.ClientFingerprint().String(); !strings.HasPrefix(got, "Helix 24.07 (capabilities ") {
+		t.Errorf("Expected the summary to start with the client, got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// closingBrackets maps opening brackets to the brackets closing them
+var closingBrackets = map[rune]rune{'{': '}', '[': ']', '(': ')'}
+
+// bracketPair is a matched pair of brackets, at the positions of the
+// brackets themselves
+type bracketPair struct {
+	open, close protocol.Position
+	bracket     rune // The opening bracket
+}
+
+// bracketPairs returns the matched brackets of text, innermost pairs first.
+// Brackets in double-quoted strings and after // are skipped, and a closing
+// bracket that matches no open one is ignored.
+func bracketPairs(text string) []bracketPair {
+	type opening struct {
+		pos     protocol.Position
+		bracket rune
+	}
+	var pairs []bracketPair
+	var stack []opening
+	for number, line := range strings.Split(text, "\n") {
+		var character uint32
+		inString := false
+		for i := 0; i < len(line); {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			pos := protocol.Position{Line: uint32(number), Character: character}
+			switch {
+			case inString && r == '\\':
+				if next, nextSize := utf8.DecodeRuneInString(line[i+size:]); nextSize > 0 {
+					character += utf16Len(string(next))
+					size += nextSize
+				}
+			case r == '"':
+				inString = !inString
+			case inString:
+			case strings.HasPrefix(line[i:], "//"):
+				i = len(line)
+				continue
+			case closingBrackets[r] != 0:
+				stack = append(stack, opening{pos, r})
+			default:
+				for n := len(stack) - 1; n >= 0; n-- {
+					if closingBrackets[stack[n].bracket] == r {
+						pairs = append(pairs, bracketPair{open: stack[n].pos, close: pos, bracket: stack[n].bracket})
+						stack = stack[:n]
+						break
+					}
+				}
+			}
+			character += utf16Len(string(r))
+			i += size
+		}
+	}
+	return pairs
+}
+
+// indentation returns the width of the leading whitespace of line, and
+// false for blank lines
+func indentation(line string) (int, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if strings.TrimSpace(trimmed) == "" {
+		return 0, false
+	}
+	return len(line) - len(trimmed), true
+}
+
+// foldingRanges returns the folding ranges of text, ordered by start line:
+// multi-line bracket pairs, runs of // comment lines, and blocks of lines
+// indented deeper than the line before them. Bracket ranges end on the line
+// before the closing bracket, so it stays visible when folded, and are kept
+// over an indentation range starting on the same line.
+func foldingRanges(text string, lineFoldingOnly bool) []protocol.FoldingRange {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	byStart := map[uint32]protocol.FoldingRange{}
+
+	for _, pair := range bracketPairs(text) {
+		if pair.close.Line < pair.open.Line+2 {
+			continue
+		}
+		fold := protocol.FoldingRange{StartLine: pair.open.Line, EndLine: pair.close.Line - 1}
+		if strings.HasPrefix(strings.TrimSpace(lines[pair.open.Line]), "import") {
+			kind := protocol.FoldingRangeKindImports
+			fold.Kind = &kind
+		}
+		if !lineFoldingOnly {
+			fold.StartCharacter = pair.open.Character + 1
+			fold.EndCharacter = utf16Len(lines[fold.EndLine])
+		}
+		if existing, ok := byStart[fold.StartLine]; !ok || existing.EndLine < fold.EndLine {
+			byStart[fold.StartLine] = fold
+		}
+	}
+
+	for start := 0; start < len(lines); {
+		end := start
+		for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "//") {
+			end++
+		}
+		if end-start >= 2 {
+			kind := protocol.FoldingRangeKindComment
+			byStart[uint32(start)] = protocol.FoldingRange{StartLine: uint32(start), EndLine: uint32(end - 1), Kind: &kind}
+		}
+		start = max(end, start+1)
+	}
+
+	for start, line := range lines {
+		indent, ok := indentation(line)
+		if !ok {
+			continue
+		}
+		end := -1
+		for next := start + 1; next < len(lines); next++ {
+			nextIndent, ok := indentation(lines[next])
+			if !ok {
+				continue
+			}
+			if nextIndent <= indent {
+				break
+			}
+			end = next
+		}
+		if _, taken := byStart[uint32(start)]; end > start && !taken {
+			byStart[uint32(start)] = protocol.FoldingRange{StartLine: uint32(start), EndLine: uint32(end)}
+		}
+	}
+
+	folds := make([]protocol.FoldingRange, 0, len(byStart))
+	for _, start := range slices.Sorted(maps.Keys(byStart)) {
+		folds = append(folds, byStart[start])
+	}
+	return folds
+}
+
+// handleFoldingRange processes textDocument/foldingRange requests,
+// answering with the folding ranges of an open document and null for
+// documents the server does not know. The lineFoldingOnly and rangeLimit
+// capabilities of the client are honored.
+func (s *MockLSPServer) handleFoldingRange(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.FoldingRangeParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse folding range params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send folding range error: %v", replyErr)
+		}
+		return
+	}
+
+	var result []protocol.FoldingRange
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		s.mu.Lock()
+		folding := s.folding
+		s.mu.Unlock()
+
+		result = foldingRanges(text, folding != nil && folding.LineFoldingOnly)
+		if folding != nil && folding.RangeLimit > 0 && len(result) > int(folding.RangeLimit) {
+			s.logInfo(ctx, "Limiting %d folding ranges of %s to %d", len(result), uri, folding.RangeLimit)
+			result = result[:folding.RangeLimit]
+		}
+		s.logInfo(ctx, "Folding ranges for %s: %d ranges", uri, len(result))
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send folding range response: %v", err)
+	}
+}
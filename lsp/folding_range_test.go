@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestBracketPairs(t *testing.T) {
+	text := "f(a[1], \"(\") { // {\n\tx := \"\\\"}\"\n}\n)"
+	var got []string
+	for _, pair := range bracketPairs(text) {
+		got = append(got, fmt.Sprintf("%c %d:%d-%d:%d", pair.bracket, pair.open.Line, pair.open.Character, pair.close.Line, pair.close.Character))
+	}
+	want := []string{"[ 0:3-0:5", "( 0:1-0:11", "{ 0:13-2:0"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected pairs %v, got %v", want, got)
+	}
+}
+
+// fmtFold describes the lines, characters and kind of a folding range
+func fmtFold(fold protocol.FoldingRange) string {
+	kind := ""
+	if fold.Kind != nil {
+		kind = " " + string(*fold.Kind)
+	}
+	return fmt.Sprintf("%d:%d-%d:%d%s", fold.StartLine, fold.StartCharacter, fold.EndLine, fold.EndCharacter, kind)
+}
+
+func TestFoldingRanges(t *testing.T) {
+	tests := []struct {
+		name            string
+		text            string
+		lineFoldingOnly bool
+		want            []string
+	}{
+		{
+			name: "braces and imports",
+			text: "import (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println()\n\tos.Exit(0)\n}\n",
+			want: []string{"0:8-2:5 imports", "5:13-7:11"},
+		},
+		{
+			name:            "line folding only",
+			text:            "func main() {\n\tone()\n\ttwo()\n}\n",
+			lineFoldingOnly: true,
+			want:            []string{"0:0-2:0"},
+		},
+		{
+			name: "too short to fold",
+			text: "f() {\n}\nx := []int{1,\n2}\n",
+			want: nil,
+		},
+		{
+			name: "comments and indentation",
+			text: "// one\n// two\ndef f():\n    a = 1\n\n    b = 2\nrest\n",
+			want: []string{"0:0-1:0 comment", "2:0-5:0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, fold := range foldingRanges(tt.text, tt.lineFoldingOnly) {
+				got = append(got, fmtFold(fold))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected folds %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFoldingRangeRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	capabilities := map[string]any{"textDocument": map[string]any{"foldingRange": map[string]any{"lineFoldingOnly": true, "rangeLimit": 1}}}
+	if err := client.Call(ctx, "initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": capabilities}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	text := "a {\n\tb\n\tc\n}\nd {\n\te\n\tf\n}\n"
+	document := map[string]any{"uri": "file:///folds.txt", "languageId": "plaintext", "version": 1, "text": text}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	var folds []map[string]any
+	if err := client.Call(ctx, "textDocument/foldingRange", map[string]any{"textDocument": map[string]any{"uri": "file:///folds.txt"}}, &folds); err != nil {
+		t.Fatalf("Folding range request failed: %v", err)
+	}
+	if len(folds) != 1 || folds[0]["startLine"] != float64(0) || folds[0]["endLine"] != float64(2) || folds[0]["startCharacter"] != nil {
+		t.Errorf("Expected the first fold without characters, got %v", folds)
+	}
+
+	folds = nil
+	if err := client.Call(ctx, "textDocument/foldingRange", map[string]any{"textDocument": map[string]any{"uri": "file:///unopened.txt"}}, &folds); err != nil || folds != nil {
+		t.Errorf("Expected null for an unopened document, got %v and %v", folds, err)
+	}
+}
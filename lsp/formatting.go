@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// formatLine returns the edits of the mock formatter for one line: the
+// leading whitespace is re-indented in units of tabSize, using tabs unless
+// insertSpaces is set, and trailing whitespace is removed. Columns left over
+// after the last full unit stay spaces.
+func formatLine(number uint32, line string, options protocol.FormattingOptions) []protocol.TextEdit {
+	tabSize := max(options.TabSize, 1)
+	content := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(content)]
+	trimmed := strings.TrimRight(content, " \t")
+
+	var columns uint32
+	for _, r := range indent {
+		if r == '\t' {
+			columns += tabSize - columns%tabSize
+		} else {
+			columns++
+		}
+	}
+	unit := "\t"
+	if options.InsertSpaces {
+		unit = strings.Repeat(" ", int(tabSize))
+	}
+	normalized := strings.Repeat(unit, int(columns/tabSize)) + strings.Repeat(" ", int(columns%tabSize))
+	if trimmed == "" {
+		normalized = "" // Whitespace-only lines are emptied
+	}
+
+	var edits []protocol.TextEdit
+	if normalized != indent {
+		edits = append(edits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: number},
+				End:   protocol.Position{Line: number, Character: uint32(len(indent))},
+			},
+			NewText: normalized,
+		})
+	}
+	if trimmed != content && trimmed != "" {
+		start := uint32(len(indent)) + utf16Len(trimmed)
+		edits = append(edits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: number, Character: start},
+				End:   protocol.Position{Line: number, Character: start + utf16Len(content[len(trimmed):])},
+			},
+		})
+	}
+	return edits
+}
+
+// formatLines returns the edits of the mock formatter for lines first to
+// last of text, both included
+func formatLines(text string, first, last uint32, options protocol.FormattingOptions) []protocol.TextEdit {
+	edits := []protocol.TextEdit{}
+	for number := first; number <= last; number++ {
+		line, ok := lineAt(text, number)
+		if !ok {
+			break
+		}
+		edits = append(edits, formatLine(number, line, options)...)
+	}
+	return edits
+}
+
+// formatDocument returns the edits of the mock formatter for the whole
+// text: every line is formatted, and the final newlines are trimmed or
+// inserted as the options ask
+func formatDocument(text string, options protocol.FormattingOptions) []protocol.TextEdit {
+	lines := uint32(strings.Count(text, "\n"))
+	end := protocol.Position{Line: lines}
+	if last, ok := lineAt(text, lines); ok {
+		end.Character = utf16Len(last)
+	}
+
+	if options.TrimFinalNewlines {
+		// Keep the newline ending the last line with content, and format
+		// only the lines before the removed ones so no edits overlap
+		content := strings.TrimRight(text, " \t\r\n")
+		if kept := uint32(strings.Count(content, "\n")) + 1; kept < lines {
+			edits := formatLines(text, 0, kept-1, options)
+			return append(edits, protocol.TextEdit{
+				Range: protocol.Range{Start: protocol.Position{Line: kept}, End: end},
+			})
+		}
+	}
+
+	edits := formatLines(text, 0, lines, options)
+	if options.InsertFinalNewline && text != "" && !strings.HasSuffix(text, "\n") {
+		edits = append(edits, protocol.TextEdit{Range: protocol.Range{Start: end, End: end}, NewText: "\n"})
+	}
+	return edits
+}
+
+// handleFormatting processes textDocument/formatting requests, answering
+// with the edits of the mock formatter for an open document and null for
+// documents the server does not know
+func (s *MockLSPServer) handleFormatting(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.DocumentFormattingParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse formatting params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send formatting error: %v", replyErr)
+		}
+		return
+	}
+
+	var result []protocol.TextEdit
+	if text, ok := s.documentText(string(params.TextDocument.Uri)); ok {
+		result = formatDocument(text, params.Options)
+		s.logInfo(ctx, "Formatting %s with %d edits", params.TextDocument.Uri, len(result))
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send formatting response: %v", err)
+	}
+}
+
+// handleRangeFormatting processes textDocument/rangeFormatting requests like
+// handleFormatting, limited to the lines the range touches. A range ending
+// at the start of a line leaves that line alone.
+func (s *MockLSPServer) handleRangeFormatting(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.DocumentRangeFormattingParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse range formatting params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send range formatting error: %v", replyErr)
+		}
+		return
+	}
+
+	var result []protocol.TextEdit
+	if text, ok := s.documentText(string(params.TextDocument.Uri)); ok {
+		last := params.Range.End.Line
+		if params.Range.End.Character == 0 && last > params.Range.Start.Line {
+			last--
+		}
+		result = formatLines(text, params.Range.Start.Line, last, params.Options)
+		s.logInfo(ctx, "Formatting lines %d-%d of %s with %d edits", params.Range.Start.Line, last, params.TextDocument.Uri, len(result))
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send range formatting response: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// applyTextEdits applies non-overlapping edits to text, last first so
+// earlier positions stay valid
+func applyTextEdits(t *testing.T, text string, edits []protocol.TextEdit) string {
+	t.Helper()
+	for i := len(edits) - 1; i >= 0; i-- {
+		var err error
+		text, err = applyContentChange(text, protocol.TextDocumentContentChangeEvent{
+			Value: protocol.TextDocumentContentChangePartial{Range: edits[i].Range, Text: edits[i].NewText},
+		})
+		if err != nil {
+			t.Fatalf("Failed to apply edit %d %+v: %v", i, edits[i], err)
+		}
+	}
+	return text
+}
+
+func TestFormatDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		options protocol.FormattingOptions
+		want    string
+	}{
+		{"already formatted", "func main() {\n\tx := 1\n}\n", protocol.FormattingOptions{TabSize: 4}, "func main() {\n\tx := 1\n}\n"},
+		{"spaces to tabs", "a\n    b\n      c\n", protocol.FormattingOptions{TabSize: 4}, "a\n\tb\n\t  c\n"},
+		{"tabs to spaces", "a\n\tb\n \tc\n", protocol.FormattingOptions{TabSize: 2, InsertSpaces: true}, "a\n  b\n  c\n"},
+		{"trailing whitespace", "a  \n\tb\t\n   \n", protocol.FormattingOptions{TabSize: 4}, "a\n\tb\n\n"},
+		{"utf-16 positions", "😀é  \n", protocol.FormattingOptions{TabSize: 4}, "😀é\n"},
+		{"crlf", "  a \r\nb\r\n", protocol.FormattingOptions{TabSize: 2}, "\ta\r\nb\r\n"},
+		{"insert final newline", "a", protocol.FormattingOptions{TabSize: 4, InsertFinalNewline: true}, "a\n"},
+		{"trim final newlines", "a\n\n  \n\n", protocol.FormattingOptions{TabSize: 4, TrimFinalNewlines: true}, "a\n"},
+		{"trim final newlines of formatted lines", "  a \n\n", protocol.FormattingOptions{TabSize: 2, TrimFinalNewlines: true}, "\ta\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyTextEdits(t, tt.text, formatDocument(tt.text, tt.options)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRangeFormatting(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	text := "  a  \n  b  \n  c  \n"
+	open := map[string]any{"textDocument": map[string]any{"uri": "file:///format.txt", "languageId": "plaintext", "version": 1, "text": text}}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///format.txt"},
+		"range":        map[string]any{"start": map[string]any{"line": 1, "character": 3}, "end": map[string]any{"line": 2, "character": 0}},
+		"options":      map[string]any{"tabSize": 2, "insertSpaces": true},
+	}
+	var edits []protocol.TextEdit
+	if err := client.Call(ctx, "textDocument/rangeFormatting", params, &edits); err != nil {
+		t.Fatalf("Range formatting failed: %v", err)
+	}
+	if got, want := applyTextEdits(t, text, edits), "  a  \n  b\n  c  \n"; got != want {
+		t.Errorf("Expected only line 1 to be formatted to %q, got %q", want, got)
+	}
+
+	unknown := map[string]any{"textDocument": map[string]any{"uri": "file:///unknown.txt"}, "options": map[string]any{"tabSize": 2, "insertSpaces": true}}
+	var result any = "unset"
+	if err := client.Call(ctx, "textDocument/formatting", unknown, &result); err != nil || result != nil {
+		t.Errorf("Expected null for a document that is not open, got %v (%v)", result, err)
+	}
+}
@@ -0,0 +1,225 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// fuzzFragments are the building blocks of generated document text and
+// edits. They mix line endings, multi-byte and astral runes and combining
+// marks, which are where position conversions usually go wrong.
+var fuzzFragments = []string{
+	"a", "bc", "def", " ", "\t", "\n", "\r\n", "\n\n",
+	"\u00e9", "e\u0301", "\u4e16\u754c", "\U0001F600", "\U0001D487", "\u05e9\u05dc",
+}
+
+// SyncFuzzMismatch records an edit after which the server's buffer no longer
+// matched the reference implementation
+type SyncFuzzMismatch struct {
+	Sequence int                                       `json:"sequence"`
+	Edit     int                                       `json:"edit"`
+	Change   protocol.TextDocumentContentChangePartial `json:"change"`
+	Before   string                                    `json:"before"`
+	Expected string                                    `json:"expected"`
+	Actual   string                                    `json:"actual"`
+}
+
+// SyncFuzzReport summarizes a content-change fuzz replay
+type SyncFuzzReport struct {
+	Seed       int64              `json:"seed"`
+	Sequences  int                `json:"sequences"`
+	Edits      int                `json:"edits"`
+	Mismatches []SyncFuzzMismatch `json:"mismatches,omitempty"`
+}
+
+// referenceDocument is an independent model of a text document that applies
+// edits directly on UTF-16 code units. It is deliberately naive so it can
+// serve as the oracle for the byte-offset based sync engine.
+type referenceDocument struct {
+	units []uint16
+}
+
+// newReferenceDocument creates a reference document holding text
+func newReferenceDocument(text string) *referenceDocument {
+	return &referenceDocument{units: utf16.Encode([]rune(text))}
+}
+
+// Text returns the current document text
+func (d *referenceDocument) Text() string {
+	return string(utf16.Decode(d.units))
+}
+
+// lineBounds returns the start and end (excluding the line terminator) of a
+// line in code units, and the number of lines in the document
+func (d *referenceDocument) lineBounds(line uint32) (start, end, count int) {
+	current := uint32(0)
+	lineStart := 0
+	for i, unit := range d.units {
+		if unit != '\n' {
+			continue
+		}
+		if current == line {
+			start, end = lineStart, i
+		}
+		current++
+		lineStart = i + 1
+	}
+	if current == line {
+		start, end = lineStart, len(d.units)
+	}
+	if end > start && d.units[end-1] == '\r' {
+		end--
+	}
+	return start, end, int(current) + 1
+}
+
+// offset converts a position into a code unit index, clamping the character
+// to the line and moving off the second half of a surrogate pair
+func (d *referenceDocument) offset(pos protocol.Position) int {
+	start, end, _ := d.lineBounds(pos.Line)
+	offset := start + int(pos.Character)
+	if offset > end {
+		offset = end
+	}
+	if offset > start && offset < end && d.units[offset] >= 0xDC00 && d.units[offset] <= 0xDFFF {
+		offset--
+	}
+	return offset
+}
+
+// Apply replaces the text between two positions
+func (d *referenceDocument) Apply(change protocol.TextDocumentContentChangePartial) {
+	start := d.offset(change.Range.Start)
+	end := d.offset(change.Range.End)
+
+	units := make([]uint16, 0, len(d.units)+len(change.Text))
+	units = append(units, d.units[:start]...)
+	units = append(units, utf16.Encode([]rune(change.Text))...)
+	units = append(units, d.units[end:]...)
+	d.units = units
+}
+
+// syncFuzzer generates random documents and edits
+type syncFuzzer struct {
+	src RandomSource
+}
+
+// text returns a random string of up to max fragments
+func (f *syncFuzzer) text(max int) string {
+	var b strings.Builder
+	for i := f.src.Intn(max + 1); i > 0; i-- {
+		b.WriteString(fuzzFragments[f.src.Intn(len(fuzzFragments))])
+	}
+	return b.String()
+}
+
+// position returns a random position inside doc. Characters may run past the
+// end of the line to exercise clamping.
+func (f *syncFuzzer) position(doc *referenceDocument) protocol.Position {
+	_, _, lines := doc.lineBounds(0)
+	line := uint32(f.src.Intn(lines))
+	start, end, _ := doc.lineBounds(line)
+	return protocol.Position{Line: line, Character: uint32(f.src.Intn(end - start + 3))}
+}
+
+// change returns a random edit with an ordered range
+func (f *syncFuzzer) change(doc *referenceDocument) protocol.TextDocumentContentChangePartial {
+	start := f.position(doc)
+	end := f.position(doc)
+	if end.Line < start.Line || (end.Line == start.Line && end.Character < start.Character) {
+		start, end = end, start
+	}
+	return protocol.TextDocumentContentChangePartial{
+		Range: protocol.Range{Start: start, End: end},
+		Text:  f.text(3),
+	}
+}
+
+// RunSyncFuzz replays random edit sequences through the didChange handler of
+// a fresh server and checks the server's buffer against a reference
+// implementation after every edit, using $/mockLsp/documentHash to compare.
+func RunSyncFuzz(seed int64, sequences, editsPerSequence int) (*SyncFuzzReport, error) {
+	src := NewSeededRandomSource(seed)
+	fuzzer := &syncFuzzer{src: src}
+	report := &SyncFuzzReport{Seed: seed, Sequences: sequences}
+
+	server := NewMockLSPServer(log.New(io.Discard, "", 0))
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+	discard := jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+		return nil, nil
+	})
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server)
+	defer serverConn.Close()
+	client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide), discard)
+	defer client.Close()
+
+	for seq := 0; seq < sequences; seq++ {
+		uri := protocol.DocumentUri(fmt.Sprintf("file:///fuzz/%d.txt", seq))
+		doc := newReferenceDocument(fuzzer.text(20))
+
+		open := protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{Uri: uri, LanguageId: "plaintext", Version: 1, Text: doc.Text()},
+		}
+		if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+			return report, fmt.Errorf("didOpen failed: %w", err)
+		}
+
+		for edit := 0; edit < editsPerSequence; edit++ {
+			change := fuzzer.change(doc)
+			before := doc.Text()
+			doc.Apply(change)
+			report.Edits++
+
+			// Send the change as raw JSON: the protocol union type does not
+			// marshal through encoding/json
+			params := map[string]any{
+				"textDocument": map[string]any{"uri": uri, "version": edit + 2},
+				"contentChanges": []any{
+					map[string]any{"range": change.Range, "text": change.Text},
+				},
+			}
+			if err := client.Notify(ctx, "textDocument/didChange", params); err != nil {
+				return report, fmt.Errorf("didChange failed: %w", err)
+			}
+
+			var result DocumentHashResult
+			hashParams := DocumentHashParams{TextDocument: protocol.TextDocumentIdentifier{Uri: uri}}
+			if err := client.Call(ctx, "$/mockLsp/documentHash", hashParams, &result); err != nil {
+				return report, fmt.Errorf("documentHash failed: %w", err)
+			}
+
+			expected := doc.Text()
+			if result.Hash == hashDocument(&protocol.TextDocumentItem{Text: expected}).Hash {
+				continue
+			}
+
+			actual, _ := server.documentText(string(uri))
+			report.Mismatches = append(report.Mismatches, SyncFuzzMismatch{
+				Sequence: seq,
+				Edit:     edit,
+				Change:   change,
+				Before:   before,
+				Expected: expected,
+				Actual:   actual,
+			})
+			// Resynchronize so later edits are checked independently
+			doc = newReferenceDocument(actual)
+		}
+
+		closeParams := protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{Uri: uri}}
+		if err := client.Notify(ctx, "textDocument/didClose", closeParams); err != nil {
+			return report, fmt.Errorf("didClose failed: %w", err)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestReferenceDocument_Apply(t *testing.T) {
+	doc := newReferenceDocument("ab\r\n\U0001F600cd")
+
+	// Past the end of the first line clamps before the CRLF
+	doc.Apply(protocol.TextDocumentContentChangePartial{
+		Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 9}, End: protocol.Position{Line: 0, Character: 9}},
+		Text:  "!",
+	})
+	// Inside the surrogate pair moves to the start of the emoji
+	doc.Apply(protocol.TextDocumentContentChangePartial{
+		Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 1}, End: protocol.Position{Line: 1, Character: 3}},
+		Text:  "x",
+	})
+
+	if expected := "ab!\r\nxd"; doc.Text() != expected {
+		t.Errorf("Expected %q, got %q", expected, doc.Text())
+	}
+}
+
+func TestRunSyncFuzz(t *testing.T) {
+	report, err := RunSyncFuzz(42, 20, 25)
+	if err != nil {
+		t.Fatalf("RunSyncFuzz failed: %v", err)
+	}
+
+	if report.Edits != 500 {
+		t.Errorf("Expected 500 edits, got %d", report.Edits)
+	}
+	for _, mismatch := range report.Mismatches {
+		t.Errorf("Sequence %d edit %d: applying %+v to %q gave %q, expected %q",
+			mismatch.Sequence, mismatch.Edit, mismatch.Change, mismatch.Before, mismatch.Actual, mismatch.Expected)
+	}
+}
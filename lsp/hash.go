@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// DocumentHashParams are the parameters of the $/mockLsp/documentHash request
+type DocumentHashParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentHashResult describes the server's view of a document so clients can
+// check that both sides agree after an edit sequence
+type DocumentHashResult struct {
+	Uri       protocol.DocumentUri `json:"uri"`
+	Version   int32                `json:"version"`
+	Algorithm string               `json:"algorithm"`
+	Hash      string               `json:"hash"`
+	Length    int                  `json:"length"` // Length of the text in UTF-8 bytes
+	Lines     int                  `json:"lines"`
+}
+
+// hashDocument computes the SHA-256 hash of a document's text
+func hashDocument(doc *protocol.TextDocumentItem) DocumentHashResult {
+	sum := sha256.Sum256([]byte(doc.Text))
+	lines := 1
+	for i := 0; i < len(doc.Text); i++ {
+		if doc.Text[i] == '\n' {
+			lines++
+		}
+	}
+	return DocumentHashResult{
+		Uri:       doc.Uri,
+		Version:   doc.Version,
+		Algorithm: "sha256",
+		Hash:      hex.EncodeToString(sum[:]),
+		Length:    len(doc.Text),
+		Lines:     lines,
+	}
+}
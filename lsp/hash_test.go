@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestDocumentHashRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+	uri := protocol.DocumentUri("file:///hash.go")
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: uri, LanguageId: "go", Version: 1, Text: "package main\n"},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	change := map[string]any{
+		"textDocument": map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{
+			map[string]any{
+				"range": map[string]any{"start": map[string]any{"line": 0, "character": 8}, "end": map[string]any{"line": 0, "character": 12}},
+				"text":  "mock",
+			},
+		},
+	}
+	if err := client.Notify(ctx, "textDocument/didChange", change); err != nil {
+		t.Fatalf("didChange failed: %v", err)
+	}
+
+	var result DocumentHashResult
+	params := DocumentHashParams{TextDocument: protocol.TextDocumentIdentifier{Uri: uri}}
+	if err := client.Call(ctx, "$/mockLsp/documentHash", params, &result); err != nil {
+		t.Fatalf("documentHash request failed: %v", err)
+	}
+
+	expected := sha256.Sum256([]byte("package mock\n"))
+	if result.Hash != hex.EncodeToString(expected[:]) {
+		t.Errorf("Expected hash of edited text, got %s", result.Hash)
+	}
+	if result.Version != 2 {
+		t.Errorf("Expected version 2, got %d", result.Version)
+	}
+	if result.Algorithm != "sha256" || result.Length != 13 || result.Lines != 2 {
+		t.Errorf("Unexpected hash metadata: %+v", result)
+	}
+
+	params.TextDocument.Uri = "file:///missing.go"
+	if err := client.Call(ctx, "$/mockLsp/documentHash", params, &result); err == nil {
+		t.Error("Expected error for unknown document")
+	}
+}
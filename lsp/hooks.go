@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// RequestEvent describes a request received from the client
+type RequestEvent struct {
+	ID     string
+	Method string
+	Params *json.RawMessage
+}
+
+// NotificationEvent describes a notification received from the client
+type NotificationEvent struct {
+	Method string
+	Params *json.RawMessage
+}
+
+// StateChangeEvent describes a move between lifecycle states
+type StateChangeEvent struct {
+	From ServerState
+	To   ServerState
+}
+
+// hooks holds the callbacks embedders registered for server events
+type hooks struct {
+	mu            sync.Mutex
+	requests      []func(RequestEvent)
+	notifications []func(NotificationEvent)
+	diagnostics   []func(protocol.PublishDiagnosticsParams)
+	stateChanges  []func(StateChangeEvent)
+}
+
+// OnRequest registers fn to be called for every request received, before it
+// is handled
+func (s *MockLSPServer) OnRequest(fn func(RequestEvent)) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.requests = append(s.hooks.requests, fn)
+}
+
+// OnNotification registers fn to be called for every notification received,
+// before it is handled
+func (s *MockLSPServer) OnNotification(fn func(NotificationEvent)) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.notifications = append(s.hooks.notifications, fn)
+}
+
+// OnDiagnosticsPublished registers fn to be called after diagnostics were
+// sent to the client
+func (s *MockLSPServer) OnDiagnosticsPublished(fn func(protocol.PublishDiagnosticsParams)) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.diagnostics = append(s.hooks.diagnostics, fn)
+}
+
+// OnStateChange registers fn to be called whenever the lifecycle state changes
+func (s *MockLSPServer) OnStateChange(fn func(StateChangeEvent)) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.stateChanges = append(s.hooks.stateChanges, fn)
+}
+
+// emit calls each callback with event. Callbacks run on the goroutine
+// handling the message, outside of any server lock, so they may call the
+// server's accessors.
+func emit[T any](h *hooks, callbacks *[]func(T), event T) {
+	h.mu.Lock()
+	registered := slices.Clone(*callbacks)
+	h.mu.Unlock()
+	for _, fn := range registered {
+		fn(event)
+	}
+}
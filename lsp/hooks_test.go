@@ -0,0 +1,85 @@
+package lsp
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestServerHooks(t *testing.T) {
+	server := createTestServer()
+
+	var mu sync.Mutex
+	var requests, notifications []string
+	var published []protocol.DocumentUri
+	var states []StateChangeEvent
+
+	server.OnRequest(func(event RequestEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, event.Method)
+	})
+	server.OnNotification(func(event NotificationEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		notifications = append(notifications, event.Method)
+	})
+	server.OnDiagnosticsPublished(func(params protocol.PublishDiagnosticsParams) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, params.Uri)
+	})
+	server.OnStateChange(func(event StateChangeEvent) {
+		// Hooks run outside the server lock, so accessors are usable
+		if state := server.State(); state != event.To {
+			t.Errorf("Expected State() to report %s in the hook, got %s", event.To, state)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, event)
+	})
+
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("Failed to send initialized: %v", err)
+	}
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///hooks.go", LanguageId: "go", Text: "package main", Version: 1},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("Failed to send didOpen: %v", err)
+	}
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if want := []string{"initialize", "shutdown"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("Expected requests %v, got %v", want, requests)
+	}
+	if want := []string{"initialized", "textDocument/didOpen"}; !reflect.DeepEqual(notifications, want) {
+		t.Errorf("Expected notifications %v, got %v", want, notifications)
+	}
+	if want := []protocol.DocumentUri{"file:///hooks.go"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("Expected diagnostics for %v, got %v", want, published)
+	}
+	wantStates := []StateChangeEvent{
+		{From: StateUninitialized, To: StateInitializing},
+		{From: StateInitializing, To: StateInitialized},
+		{From: StateInitialized, To: StateShuttingDown},
+	}
+	if !reflect.DeepEqual(states, wantStates) {
+		t.Errorf("Expected state changes %v, got %v", wantStates, states)
+	}
+}
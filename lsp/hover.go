@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// hoverImage is a 1x1 PNG, small enough to inline as a data URI
+const hoverImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+// hoverMarkdown appends the named Markdown elements to hover content, each
+// with the constructs renderers most often get wrong: code blocks in the
+// language of the document and one whose fence is longer than the fences
+// inside it, a table with alignments, inline code and an escaped pipe,
+// inline, reference and autolinks, one to the document itself, and images
+// inlined as data URIs
+func hoverMarkdown(content string, elements []string, uri protocol.DocumentUri, profile *languageProfile) string {
+	language, signature := "go", "func mockFunction(value string) (int, error)"
+	if profile != nil {
+		language, signature = profile.language, profile.signature
+	}
+
+	var b strings.Builder
+	b.WriteString(content)
+	for _, element := range elements {
+		b.WriteString("\n\n")
+		switch element {
+		case "code_block":
+			fmt.Fprintf(&b, "```%s\n%s\n```\n\n", language, signature)
+			b.WriteString("````markdown\n```" + language + "\n// A fence inside a longer fence\n```\n````")
+		case "table":
+			b.WriteString("| Parameter | Type | Default |\n")
+			b.WriteString("|:----------|:----:|--------:|\n")
+			b.WriteString("| `value` | `string` | `\"\"` |\n")
+			b.WriteString("| `mode` | `a \\| b` | `a` |")
+		case "link":
+			fmt.Fprintf(&b, "See [the declaration](%s#L1), the [reference][spec] or <https://microsoft.github.io/language-server-protocol/>.\n\n", uri)
+			b.WriteString("[spec]: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/ \"LSP specification\"")
+		case "image":
+			fmt.Fprintf(&b, "![Mock pixel](%s \"A data URI image\")", hoverImage)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+func TestHoverMarkdown(t *testing.T) {
+	if got := hoverMarkdown("content", nil, "file:///a.go", nil); got != "content" {
+		t.Errorf("Expected content unchanged without elements, got %q", got)
+	}
+
+	got := hoverMarkdown("content", config.HoverMarkdownElements, "file:///a.go", nil)
+	for _, want := range []string{
+		"```go\nfunc mockFunction",
+		"````markdown\n```go\n",
+		"|:----------|:----:|--------:|",
+		"`a \\| b`",
+		"[the declaration](file:///a.go#L1)",
+		"[spec]: https://",
+		"![Mock pixel](data:image/png;base64,",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected hover Markdown to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestHoverMarkdownRequest(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.HoverConfig.Markdown = []string{"image"}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+
+	var hover struct {
+		Contents struct {
+			Kind  string `json:"kind"`
+			Value string `json:"value"`
+		} `json:"contents"`
+	}
+	params := map[string]any{"textDocument": map[string]any{"uri": "file:///hover.txt"}, "position": map[string]any{"line": 0, "character": 0}}
+	if err := client.Call(context.Background(), "textDocument/hover", params, &hover); err != nil {
+		t.Fatalf("Hover request failed: %v", err)
+	}
+	if hover.Contents.Kind != "markdown" || !strings.HasSuffix(hover.Contents.Value, "\"A data URI image\")") {
+		t.Errorf("Expected Markdown ending with the image, got %+v", hover.Contents)
+	}
+}
@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// defaultIndexingSteps is the number of progress steps of the simulated
+// indexing when the configuration sets none
+const defaultIndexingSteps = 10
+
+// indexingToken is the work done progress token of the simulated indexing
+var indexingToken = protocol.ProgressToken{Value: "mock-lsp-indexing"}
+
+// indexingBegin is a WorkDoneProgressBegin that always carries its
+// percentage. The protocol type omits a zero percentage, and clients may
+// then ignore the percentages of the reports that follow.
+type indexingBegin struct {
+	Kind       string `json:"kind"`
+	Title      string `json:"title"`
+	Message    string `json:"message"`
+	Percentage uint32 `json:"percentage"`
+}
+
+// indexedSymbols returns the part of the count workspace symbols indexed so
+// far: all of them unless the indexing simulation is running
+func (s *MockLSPServer) indexedSymbols(symbols iter.Seq[protocol.WorkspaceSymbol], count int) iter.Seq[protocol.WorkspaceSymbol] {
+	s.mu.Lock()
+	running, percent := s.indexing, s.indexedPercent
+	s.mu.Unlock()
+	if !running {
+		return symbols
+	}
+	indexed := count * percent / 100
+	return func(yield func(protocol.WorkspaceSymbol) bool) {
+		if indexed == 0 {
+			return
+		}
+		n := 0
+		for symbol := range symbols {
+			if !yield(symbol) {
+				return
+			}
+			if n++; n == indexed {
+				return
+			}
+		}
+	}
+}
+
+// startIndexing starts the simulated indexing of the workspace, if it is
+// configured. Workspace symbols become available in steps until indexing
+// finishes or the connection closes.
+func (s *MockLSPServer) startIndexing(ctx context.Context, conn Conn) {
+	indexing := s.config().LSP.Indexing
+	if indexing.Duration <= 0 {
+		return
+	}
+	steps := indexing.Steps
+	if steps == 0 {
+		steps = defaultIndexingSteps
+	}
+
+	s.mu.Lock()
+	s.indexing, s.indexedPercent = true, 0
+	s.mu.Unlock()
+	s.logInfo(ctx, "Indexing the workspace over %s in %d steps", indexing.Duration, steps)
+	go s.runIndexing(context.WithoutCancel(ctx), conn, indexing.Duration.Duration()/time.Duration(steps), steps)
+}
+
+// runIndexing advances the indexing a step every interval, reporting it
+// with work done progress to clients that support it
+func (s *MockLSPServer) runIndexing(ctx context.Context, conn Conn, interval time.Duration, steps int) {
+	_, total := s.workspaceSymbols()
+	s.mu.Lock()
+	progress := s.client != nil && slices.Contains(s.client.Capabilities, "window.workDoneProgress")
+	s.mu.Unlock()
+	if progress {
+		if err := s.call(ctx, conn, "window/workDoneProgress/create", protocol.WorkDoneProgressCreateParams{Token: indexingToken}, nil); err != nil {
+			s.logError(ctx, "Client refused the indexing progress token: %v", err)
+			progress = false
+		}
+	}
+	if progress {
+		s.sendIndexingProgress(ctx, conn, indexingBegin{Kind: "begin", Title: "Indexing", Message: fmt.Sprintf("0/%d symbols", total)})
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for step := 1; step <= steps; step++ {
+		select {
+		case <-ticker.C:
+		case <-conn.DisconnectNotify():
+			return
+		}
+
+		percent := step * 100 / steps
+		s.mu.Lock()
+		s.indexedPercent = percent
+		s.indexing = step < steps
+		s.mu.Unlock()
+
+		message := fmt.Sprintf("%d/%d symbols", total*percent/100, total)
+		if step < steps {
+			if progress {
+				s.sendIndexingProgress(ctx, conn, protocol.WorkDoneProgressReport{Kind: "report", Message: message, Percentage: uint32(percent)})
+			}
+			continue
+		}
+		s.logInfo(ctx, "Indexed the workspace: %s", message)
+		if progress {
+			s.sendIndexingProgress(ctx, conn, protocol.WorkDoneProgressEnd{Kind: "end", Message: message})
+		}
+	}
+}
+
+// sendIndexingProgress sends a $/progress notification of the indexing
+func (s *MockLSPServer) sendIndexingProgress(ctx context.Context, conn Conn, value any) {
+	if err := s.notify(ctx, conn, progressMethod, protocol.ProgressParams{Token: indexingToken, Value: value}); err != nil {
+		s.logError(ctx, "Failed to send indexing progress: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+func TestIndexing(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.WorkspaceSymbols = 10
+	cfg.LSP.MockData.SymbolKinds = map[string]int{"class": 100}
+	cfg.LSP.Indexing = config.IndexingConfig{Duration: config.Duration(40 * time.Millisecond), Steps: 2}
+	server.SetConfig(cfg)
+
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	initialize := map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{"window": map[string]any{"workDoneProgress": true}}}
+	server.Dispatch(ctx, conn, testRequest(t, 1, "initialize", initialize))
+	server.Dispatch(ctx, conn, testRequest(t, 0, "initialized", map[string]any{}))
+
+	symbolCount := func(id uint64) int {
+		t.Helper()
+		server.Dispatch(ctx, conn, testRequest(t, id, "workspace/symbol", map[string]any{"query": ""}))
+		var symbols []json.RawMessage
+		if m, ok := conn.Response(jsonrpc.ID{Num: id}); !ok || json.Unmarshal(m.Result, &symbols) != nil {
+			t.Fatalf("Expected workspace symbols, got %+v", m)
+		}
+		return len(symbols)
+	}
+	if got := symbolCount(2); got != 0 {
+		t.Errorf("Expected no symbols before indexing advanced, got %d", got)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := conn.WaitFor(waitCtx, func(m lsptest.Message) bool {
+		return m.Method == progressMethod && strings.Contains(string(m.Params), `"kind":"end"`)
+	}); err != nil {
+		t.Fatalf("Expected indexing to end: %v", err)
+	}
+	if got := symbolCount(3); got != 10 {
+		t.Errorf("Expected every symbol once indexed, got %d", got)
+	}
+
+	if created := conn.Sent("window/workDoneProgress/create"); len(created) != 1 {
+		t.Errorf("Expected the progress token to be created, got %+v", created)
+	}
+	var kinds []string
+	for _, m := range conn.Sent(progressMethod) {
+		var params struct {
+			Value struct {
+				Kind       string `json:"kind"`
+				Percentage *int   `json:"percentage"`
+			} `json:"value"`
+		}
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		kinds = append(kinds, params.Value.Kind)
+		if params.Value.Kind == "begin" && (params.Value.Percentage == nil || *params.Value.Percentage != 0) {
+			t.Errorf("Expected begin to carry a zero percentage, got %s", m.Params)
+		}
+	}
+	if strings.Join(kinds, ",") != "begin,report,end" {
+		t.Errorf("Expected begin, report and end, got %v", kinds)
+	}
+}
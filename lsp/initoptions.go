@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// InitializationOptionsResult is the response to
+// $/mockLsp/initializationOptions
+type InitializationOptionsResult struct {
+	// Received is the initializationOptions value exactly as sent, or null
+	Received json.RawMessage `json:"received"`
+	// Applied lists the keys merged into the LSP configuration
+	Applied []string `json:"applied"`
+	// Ignored lists the keys the server does not recognize
+	Ignored []string `json:"ignored"`
+	// Error explains why the options were not applied, if they were not
+	Error string `json:"error,omitempty"`
+}
+
+// applyInitializationOptions merges the recognized keys of the raw
+// initializationOptions into the LSP configuration and remembers the outcome
+// for $/mockLsp/initializationOptions. Invalid options are logged and leave
+// the configuration unchanged; they never fail the initialize request.
+func (s *MockLSPServer) applyInitializationOptions(ctx context.Context, raw json.RawMessage) {
+	result := InitializationOptionsResult{Received: raw, Applied: []string{}, Ignored: []string{}}
+	defer func() {
+		s.mu.Lock()
+		s.initOptions = &result
+		s.mu.Unlock()
+	}()
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+
+	cfg, ignored, err := s.config().ApplyLSPOptions(raw)
+	if err != nil {
+		result.Error = err.Error()
+		s.logError(ctx, "Ignoring initializationOptions: %v", err)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err == nil {
+		for key := range fields {
+			if !slices.Contains(ignored, key) {
+				result.Applied = append(result.Applied, key)
+			}
+		}
+		slices.Sort(result.Applied)
+	}
+	if ignored != nil {
+		result.Ignored = ignored
+		s.logInfo(ctx, "Unrecognized initializationOptions keys: %v", ignored)
+	}
+
+	if len(result.Applied) > 0 {
+		// Keep the trace ID of the session unless the options set one
+		if cfg.LSP.TraceMetadata.TraceID == "" {
+			cfg.LSP.TraceMetadata.TraceID = s.snapshot().traceID
+		}
+		s.SetConfig(cfg)
+		s.logInfo(ctx, "Applied initializationOptions keys: %v", result.Applied)
+	}
+}
+
+// InitializationOptions returns what the server received in the
+// initializationOptions of the initialize request and what it applied, or
+// nil before the initialize request
+func (s *MockLSPServer) InitializationOptions() *InitializationOptionsResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initOptions
+}
+
+// handleInitializationOptions processes the custom
+// $/mockLsp/initializationOptions request
+func (s *MockLSPServer) handleInitializationOptions(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	result := s.InitializationOptions()
+	if result == nil {
+		result = &InitializationOptionsResult{Received: json.RawMessage("null"), Applied: []string{}, Ignored: []string{}}
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send initializationOptions response: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestInitializationOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     any
+		wantApplied []string
+		wantIgnored []string
+		wantError   bool
+		wantLocale  string
+	}{
+		{
+			name:        "no options",
+			wantApplied: []string{},
+			wantIgnored: []string{},
+		},
+		{
+			name:        "recognized and unknown keys",
+			options:     map[string]any{"locale": "de", "editorTheme": "dark"},
+			wantApplied: []string{"locale"},
+			wantIgnored: []string{"editorTheme"},
+			wantLocale:  "de",
+		},
+		{
+			name:        "invalid value",
+			options:     map[string]any{"recent_traffic": -5},
+			wantApplied: []string{},
+			wantIgnored: []string{},
+			wantError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			var unset InitializationOptionsResult
+			if err := client.Call(ctx, "$/mockLsp/initializationOptions", nil, &unset); err != nil {
+				t.Fatalf("initializationOptions request failed: %v", err)
+			}
+			if string(unset.Received) != "null" {
+				t.Errorf("Expected null before initialize, got %s", unset.Received)
+			}
+
+			params := protocol.InitializeParams{InitializationOptions: tt.options}
+			if err := client.Call(ctx, "initialize", params, nil); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+
+			var result InitializationOptionsResult
+			if err := client.Call(ctx, "$/mockLsp/initializationOptions", nil, &result); err != nil {
+				t.Fatalf("initializationOptions request failed: %v", err)
+			}
+
+			want, err := json.Marshal(tt.options)
+			if err != nil {
+				t.Fatalf("Failed to encode options: %v", err)
+			}
+			if string(result.Received) != string(want) {
+				t.Errorf("Expected received %s, got %s", want, result.Received)
+			}
+			if !reflect.DeepEqual(result.Applied, tt.wantApplied) {
+				t.Errorf("Expected applied %v, got %v", tt.wantApplied, result.Applied)
+			}
+			if !reflect.DeepEqual(result.Ignored, tt.wantIgnored) {
+				t.Errorf("Expected ignored %v, got %v", tt.wantIgnored, result.Ignored)
+			}
+			if (result.Error != "") != tt.wantError {
+				t.Errorf("Expected error %v, got %q", tt.wantError, result.Error)
+			}
+			if server.config().LSP.Locale != tt.wantLocale {
+				t.Errorf("Expected locale %q, got %q", tt.wantLocale, server.config().LSP.Locale)
+			}
+		})
+	}
+}
+
+func TestInitializationOptionsKeepTraceID(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	traceID := server.snapshot().traceID
+
+	params := protocol.InitializeParams{InitializationOptions: map[string]any{"recent_traffic": 10}}
+	if err := client.Call(context.Background(), "initialize", params, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if server.config().LSP.RecentTraffic != 10 {
+		t.Errorf("Expected recent_traffic 10, got %d", server.config().LSP.RecentTraffic)
+	}
+	if server.snapshot().traceID != traceID {
+		t.Errorf("Expected trace ID %s to be kept, got %s", traceID, server.snapshot().traceID)
+	}
+}
@@ -0,0 +1,155 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// mockHintType is the type shown by type hints
+const mockHintType = "MockType"
+
+// inlayHintKinds maps the kind names of the configuration to protocol kinds
+var inlayHintKinds = map[string]protocol.InlayHintKind{
+	"type":      protocol.InlayHintKindType,
+	"parameter": protocol.InlayHintKindParameter,
+}
+
+// lineWords returns the byte offsets of the start and end of every word of
+// line, in order
+func lineWords(line string) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range line + " " {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	return words
+}
+
+// inlayHints returns the hints within rng of text: on every line_interval-th
+// line, every word_interval-th word gets a hint of the next configured kind.
+// Type hints follow the word and insert the type when accepted; parameter
+// hints precede it. Tooltips are left to inlayHint/resolve if configured.
+func (s *MockLSPServer) inlayHints(uri, text string, rng protocol.Range) []protocol.InlayHint {
+	cfg := s.config().LSP.InlayHintConfig
+	hints := []protocol.InlayHint{}
+	for number := rng.Start.Line; number <= rng.End.Line; number++ {
+		line, ok := lineAt(text, number)
+		if !ok {
+			break
+		}
+		if int(number)%cfg.LineInterval != 0 {
+			continue
+		}
+
+		words := lineWords(line)
+		for n := cfg.WordInterval; n <= len(words); n += cfg.WordInterval {
+			ordinal := n / cfg.WordInterval
+			span := words[n-1]
+			word := line[span[0]:span[1]]
+			kind := inlayHintKinds[cfg.Kinds[(ordinal-1)%len(cfg.Kinds)]]
+
+			hint := protocol.InlayHint{Kind: &kind}
+			if kind == protocol.InlayHintKindType {
+				hint.Position = protocol.Position{Line: number, Character: utf16Len(line[:span[1]])}
+				hint.Label = protocol.Or2[string, []protocol.InlayHintLabelPart]{Value: ": " + mockHintType}
+				hint.TextEdits = []protocol.TextEdit{{
+					Range:   protocol.Range{Start: hint.Position, End: hint.Position},
+					NewText: " " + mockHintType,
+				}}
+			} else {
+				hint.Position = protocol.Position{Line: number, Character: utf16Len(line[:span[0]])}
+				hint.Label = protocol.Or2[string, []protocol.InlayHintLabelPart]{Value: mockParameterName(ordinal) + ":"}
+				hint.PaddingRight = true
+			}
+			if positionBefore(hint.Position, rng.Start) || positionBefore(rng.End, hint.Position) {
+				continue
+			}
+
+			if cfg.Resolve {
+				hint.Data = map[string]any{"uri": uri, "word": word, "ordinal": ordinal}
+			} else {
+				hint.Tooltip = s.inlayHintTooltip(kind, word, ordinal)
+			}
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+// mockParameterName is the name parameter hints give the n-th hinted word
+// of a line
+func mockParameterName(n int) string {
+	return fmt.Sprintf("mockParam%d", n)
+}
+
+// inlayHintTooltip returns the localized tooltip of a hint on word
+func (s *MockLSPServer) inlayHintTooltip(kind protocol.InlayHintKind, word string, ordinal int) *protocol.Or2[string, protocol.MarkupContent] {
+	message := s.message(msgInlayHintParameter, mockParameterName(ordinal), word)
+	if kind == protocol.InlayHintKindType {
+		message = s.message(msgInlayHintType, word, mockHintType)
+	}
+	return &protocol.Or2[string, protocol.MarkupContent]{Value: message}
+}
+
+// handleInlayHint processes textDocument/inlayHint requests, answering with
+// the hints within the range of an open document and null for documents the
+// server does not know
+func (s *MockLSPServer) handleInlayHint(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.InlayHintParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse inlay hint params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send inlay hint error: %v", replyErr)
+		}
+		return
+	}
+
+	var result []protocol.InlayHint
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		result = s.inlayHints(uri, text, params.Range)
+		s.logInfo(ctx, "Inlay hints for lines %d-%d of %s: %d hints", params.Range.Start.Line, params.Range.End.Line, uri, len(result))
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send inlay hint response: %v", err)
+	}
+}
+
+// handleInlayHintResolve processes inlayHint/resolve requests, adding the
+// tooltip to a hint sent without one. Hints without the data of this server
+// are returned unchanged.
+func (s *MockLSPServer) handleInlayHintResolve(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var hint protocol.InlayHint
+	if req.Params == nil || json.Unmarshal(*req.Params, &hint) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse inlay hint",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send inlay hint resolve error: %v", replyErr)
+		}
+		return
+	}
+
+	data, _ := hint.Data.(map[string]any)
+	word, _ := data["word"].(string)
+	ordinal, _ := data["ordinal"].(float64)
+	if hint.Kind != nil && word != "" {
+		hint.Tooltip = s.inlayHintTooltip(*hint.Kind, word, int(ordinal))
+		s.logInfo(ctx, "Resolved inlay hint for %s at %d:%d", word, hint.Position.Line, hint.Position.Character)
+	}
+	if err := s.reply(ctx, conn, req, hint); err != nil {
+		s.logError(ctx, "Failed to send inlay hint resolve response: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+// inlayHint is an inlay hint as clients receive it
+type inlayHint struct {
+	Position struct {
+		Line      uint32 `json:"line"`
+		Character uint32 `json:"character"`
+	} `json:"position"`
+	Label        string         `json:"label"`
+	Kind         int            `json:"kind"`
+	PaddingRight bool           `json:"paddingRight"`
+	Tooltip      string         `json:"tooltip"`
+	TextEdits    []any          `json:"textEdits"`
+	Data         map[string]any `json:"data"`
+}
+
+func TestInlayHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		rng     [4]int // start line and character, end line and character
+		want    []string
+		resolve bool
+	}{
+		{"whole document", [4]int{0, 0, 9, 0}, []string{"0:10 : MockType", "0:17 mockParam2:", "0:35 : MockType", "2:7 : MockType"}, false},
+		{"part of a line", [4]int{0, 5, 0, 17}, []string{"0:10 : MockType", "0:17 mockParam2:"}, false},
+		{"skipped line", [4]int{1, 0, 1, 9}, nil, false},
+		{"resolved later", [4]int{2, 0, 2, 9}, []string{"2:7 : MockType"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.InlayHintConfig = config.InlayHintConfig{LineInterval: 2, WordInterval: 2, Kinds: []string{"type", "parameter"}, Resolve: tt.resolve}
+			server.SetConfig(cfg)
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			text := "alpha beta gamma delta epsilon zeta\nnot hinted here\none two three\n"
+			document := map[string]any{"uri": "file:///hints.txt", "languageId": "plaintext", "version": 1, "text": text}
+			if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+				t.Fatalf("didOpen failed: %v", err)
+			}
+
+			params := map[string]any{
+				"textDocument": map[string]any{"uri": "file:///hints.txt"},
+				"range": map[string]any{
+					"start": map[string]any{"line": tt.rng[0], "character": tt.rng[1]},
+					"end":   map[string]any{"line": tt.rng[2], "character": tt.rng[3]},
+				},
+			}
+			var hints []inlayHint
+			if err := client.Call(ctx, "textDocument/inlayHint", params, &hints); err != nil {
+				t.Fatalf("Inlay hint request failed: %v", err)
+			}
+			if len(hints) != len(tt.want) {
+				t.Fatalf("Expected hints %v, got %+v", tt.want, hints)
+			}
+			for i, hint := range hints {
+				if got := fmtHint(hint); got != tt.want[i] {
+					t.Errorf("Expected hint %q, got %q", tt.want[i], got)
+				}
+				if hint.Kind == 1 && len(hint.TextEdits) != 1 || hint.Kind == 2 && !hint.PaddingRight {
+					t.Errorf("Expected type hints to insert the type and parameter hints to be padded, got %+v", hint)
+				}
+				if tt.resolve != (hint.Tooltip == "") {
+					t.Errorf("Expected a tooltip only without resolve, got %+v", hint)
+				}
+			}
+
+			if tt.resolve {
+				var resolved inlayHint
+				raw := map[string]any{
+					"position":  map[string]any{"line": 2, "character": 7},
+					"label":     ": MockType",
+					"kind":      1,
+					"textEdits": hints[0].TextEdits,
+					"data":      hints[0].Data,
+				}
+				if err := client.Call(ctx, "inlayHint/resolve", raw, &resolved); err != nil {
+					t.Fatalf("Inlay hint resolve failed: %v", err)
+				}
+				if resolved.Tooltip != "two is inferred as MockType" || fmtHint(resolved) != "2:7 : MockType" {
+					t.Errorf("Expected the resolved tooltip, got %+v", resolved)
+				}
+			}
+		})
+	}
+}
+
+// fmtHint describes the position and label of a hint
+func fmtHint(hint inlayHint) string {
+	return fmt.Sprintf("%d:%d %s", hint.Position.Line, hint.Position.Character, hint.Label)
+}
+
+func TestInlayHintsRestricted(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///unopened.txt"},
+		"range":        map[string]any{"start": map[string]any{"line": 0, "character": 0}, "end": map[string]any{"line": 5, "character": 0}},
+	}
+	var hints []inlayHint
+	if err := client.Call(ctx, "textDocument/inlayHint", params, &hints); err != nil || hints != nil {
+		t.Errorf("Expected null for an unopened document, got %+v and %v", hints, err)
+	}
+
+	if err := server.SetProtocolVersion(Protocol316); err != nil {
+		t.Fatalf("SetProtocolVersion failed: %v", err)
+	}
+	if err := client.Call(ctx, "textDocument/inlayHint", params, nil); errorCode(err) != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound under protocol 3.16, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// defaultInlineCompletion is the number of suggestions and of lines per
+// suggestion used when the configuration leaves them at 0
+const defaultInlineCompletion = 3
+
+// typedWordRange returns the range from the start of the word being typed
+// before pos to pos, which is empty when pos does not follow a word
+func typedWordRange(line string, pos protocol.Position) protocol.Range {
+	offset := utf16ToByteOffset(line, pos.Character)
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !isWordRune(r) {
+			break
+		}
+		start -= size
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: pos.Line, Character: utf16Len(line[:start])},
+		End:   protocol.Position{Line: pos.Line, Character: utf16Len(line[:offset])},
+	}
+}
+
+// inlineCompletionItems returns the ghost text suggestions for pos in text.
+// The n-th suggestion calls the n-th mock function with the mock value and
+// continues with steps on further lines indented like the current one. Its
+// range replaces the word typed so far, leaving clients to filter on it.
+// Names take the custom prefixes of the mock data in turn.
+func (s *MockLSPServer) inlineCompletionItems(text string, pos protocol.Position) []protocol.InlineCompletionItem {
+	cfg := s.config().LSP.InlineCompletion
+	suggestions, lines := cfg.Suggestions, cfg.Lines
+	if suggestions == 0 {
+		suggestions = defaultInlineCompletion
+	}
+	if lines == 0 {
+		lines = defaultInlineCompletion
+	}
+	prefixes := s.config().LSP.MockData.CustomPrefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{"mock"}
+	}
+
+	line, _ := lineAt(text, pos.Line)
+	rng := typedWordRange(line, pos)
+	indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+
+	items := make([]protocol.InlineCompletionItem, suggestions)
+	for i := range items {
+		prefix := prefixes[i%len(prefixes)]
+		function := fmt.Sprintf("%sFunction%d", prefix, i+1)
+		value := prefix + "Value"
+
+		body := []string{fmt.Sprintf("%s(%s)", function, value)}
+		for step := 1; step < lines; step++ {
+			body = append(body, fmt.Sprintf("%s%sStep%d(%s)", indent, prefix, step, value))
+		}
+		items[i] = protocol.InlineCompletionItem{
+			InsertText: protocol.Or2[string, protocol.StringValue]{Value: strings.Join(body, "\n")},
+			FilterText: function,
+			Range:      &rng,
+		}
+	}
+	return items
+}
+
+// handleInlineCompletion processes textDocument/inlineCompletion requests,
+// answering with multi-line ghost text at the position in an open document,
+// and null for documents the server does not know
+func (s *MockLSPServer) handleInlineCompletion(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.InlineCompletionParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse inline completion params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send inline completion error: %v", replyErr)
+		}
+		return
+	}
+
+	var result *protocol.InlineCompletionList
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		result = &protocol.InlineCompletionList{Items: s.inlineCompletionItems(text, params.Position)}
+		s.logInfo(ctx, "Inline completion: %d suggestions in %s", len(result.Items), uri)
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send inline completion response: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestTypedWordRange(t *testing.T) {
+	line := "\tx := é_fo"
+	rng := typedWordRange(line, protocol.Position{Line: 2, Character: 10})
+	want := protocol.Range{Start: protocol.Position{Line: 2, Character: 6}, End: protocol.Position{Line: 2, Character: 10}}
+	if rng != want {
+		t.Errorf("Expected %v, got %v", want, rng)
+	}
+
+	rng = typedWordRange(line, protocol.Position{Line: 0, Character: 5})
+	if rng.Start != rng.End || rng.Start.Character != 5 {
+		t.Errorf("Expected an empty range off a word, got %v", rng)
+	}
+}
+
+func TestInlineCompletionItems(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.InlineCompletion = config.InlineCompletionConfig{Suggestions: 3, Lines: 2}
+	cfg.LSP.MockData.CustomPrefixes = []string{"app", "lib"}
+	server.SetConfig(cfg)
+
+	items := server.inlineCompletionItems("func main() {\n    ap\n}\n", protocol.Position{Line: 1, Character: 6})
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 suggestions, got %d", len(items))
+	}
+	wantTexts := []string{
+		"appFunction1(appValue)\n    appStep1(appValue)",
+		"libFunction2(libValue)\n    libStep1(libValue)",
+		"appFunction3(appValue)\n    appStep1(appValue)",
+	}
+	for i, item := range items {
+		if text, _ := item.InsertText.Value.(string); text != wantTexts[i] {
+			t.Errorf("Expected suggestion %d to be %q, got %q", i, wantTexts[i], text)
+		}
+		if item.Range == nil || item.Range.Start.Character != 4 || item.Range.End.Character != 6 {
+			t.Errorf("Expected suggestion %d to replace the typed word, got %v", i, item.Range)
+		}
+	}
+}
+
+func TestInlineCompletionRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	document := map[string]any{"uri": "file:///inline.go", "languageId": "go", "version": 1, "text": "mo\n"}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///inline.go"},
+		"position":     map[string]any{"line": 0, "character": 2},
+		"context":      map[string]any{"triggerKind": 2},
+	}
+	var list *protocol.InlineCompletionList
+	if err := client.Call(ctx, "textDocument/inlineCompletion", params, &list); err != nil {
+		t.Fatalf("Inline completion request failed: %v", err)
+	}
+	if list == nil || len(list.Items) != defaultInlineCompletion {
+		t.Fatalf("Expected %d suggestions, got %+v", defaultInlineCompletion, list)
+	}
+	if text, _ := list.Items[0].InsertText.Value.(string); text != "mockFunction1(mockValue)\nmockStep1(mockValue)\nmockStep2(mockValue)" {
+		t.Errorf("Expected a three-line suggestion, got %q", text)
+	}
+
+	params["textDocument"] = map[string]any{"uri": "file:///unopened.go"}
+	list = nil
+	if err := client.Call(ctx, "textDocument/inlineCompletion", params, &list); err != nil || list != nil {
+		t.Errorf("Expected null for an unopened document, got %+v and %v", list, err)
+	}
+}
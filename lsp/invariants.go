@@ -0,0 +1,243 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Invariants checked in randomized and edge-case payloads, the keys of the
+// invariantViolations stats
+const (
+	invariantPosition = "position" // Line and character are uintegers
+	invariantRange    = "range"    // A range starts no later than it ends
+	invariantURI      = "uri"      // URIs parse and are absolute
+	invariantVersion  = "version"  // Diagnostics versions of a document never decrease
+)
+
+// uriFields are the keys whose string values are document URIs
+var uriFields = []string{"uri", "targetUri", "oldUri", "newUri"}
+
+// invariantChecker repairs the payloads randomized and edge-case responses
+// produce where they break hard protocol constraints no real server would
+// break, so clients are tested against odd but possible messages
+type invariantChecker struct {
+	mu       sync.Mutex
+	versions map[string]int64 // Last diagnostics version published by URI
+}
+
+// newInvariantChecker creates a checker with no versions published
+func newInvariantChecker() *invariantChecker {
+	return &invariantChecker{versions: make(map[string]int64)}
+}
+
+// forget drops the diagnostics version published for uri, as a reopened
+// document starts over from its own version
+func (c *invariantChecker) forget(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.versions, uri)
+}
+
+// repair returns data with its invariant violations repaired, and the number
+// of violations of each invariant. data is returned unchanged when nothing
+// is violated or it cannot be decoded. orderRanges checks that ranges start
+// no later than they end.
+func (c *invariantChecker) repair(method string, data json.RawMessage, orderRanges bool) (json.RawMessage, map[string]int64) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return data, nil
+	}
+
+	violations := make(map[string]int64)
+	value = repairValue(value, orderRanges, violations)
+	if method == publishDiagnosticsMethod {
+		c.repairVersion(value, violations)
+	}
+	if len(violations) == 0 {
+		return data, nil
+	}
+	repaired, err := json.Marshal(value)
+	if err != nil {
+		return data, nil
+	}
+	return repaired, violations
+}
+
+// repairVersion drops the version of published diagnostics older than the
+// last version published for the document, which the client would have to
+// apply to text it no longer has
+func (c *invariantChecker) repairVersion(params any, violations map[string]int64) {
+	fields, ok := params.(map[string]any)
+	if !ok {
+		return
+	}
+	uri, _ := fields["uri"].(string)
+	number, ok := fields["version"].(json.Number)
+	if !ok {
+		return
+	}
+	version, err := number.Int64()
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.versions[uri]; ok && version < last {
+		delete(fields, "version")
+		violations[invariantVersion]++
+		return
+	}
+	c.versions[uri] = version
+}
+
+// repairValue repairs the positions, ranges and URIs in a decoded JSON value
+func repairValue(v any, orderRanges bool, violations map[string]int64) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = repairValue(value, orderRanges, violations)
+		}
+		if isPosition(v) {
+			repairPosition(v, violations)
+		}
+		if orderRanges && isRange(v) && positionAfter(v["start"].(map[string]any), v["end"].(map[string]any)) {
+			v["start"], v["end"] = v["end"], v["start"]
+			violations[invariantRange]++
+		}
+		for _, key := range uriFields {
+			if uri, ok := v[key].(string); ok && uri != "" && !validURI(uri) {
+				v[key] = repairURI(uri)
+				violations[invariantURI]++
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = repairValue(value, orderRanges, violations)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isPosition reports whether an object is a position
+func isPosition(v map[string]any) bool {
+	_, line := v["line"].(json.Number)
+	_, character := v["character"].(json.Number)
+	return line && character && len(v) == 2
+}
+
+// isRange reports whether an object is a range
+func isRange(v map[string]any) bool {
+	start, ok := v["start"].(map[string]any)
+	if !ok || !isPosition(start) {
+		return false
+	}
+	end, ok := v["end"].(map[string]any)
+	return ok && isPosition(end)
+}
+
+// repairPosition clamps the line and character of a position to the
+// uinteger range, rounding fractions down
+func repairPosition(v map[string]any, violations map[string]int64) {
+	for _, key := range []string{"line", "character"} {
+		number := v[key].(json.Number)
+		if n, err := number.Int64(); err == nil && n >= 0 && n <= maxUinteger {
+			continue
+		}
+		f, err := number.Float64()
+		if err != nil {
+			f = 0
+		}
+		v[key] = json.Number(fmt.Sprint(int64(math.Max(0, math.Min(maxUinteger, math.Floor(f))))))
+		violations[invariantPosition]++
+	}
+}
+
+// positionAfter reports whether position a follows position b
+func positionAfter(a, b map[string]any) bool {
+	aLine, _ := a["line"].(json.Number).Int64()
+	bLine, _ := b["line"].(json.Number).Int64()
+	if aLine != bLine {
+		return aLine > bLine
+	}
+	aCharacter, _ := a["character"].(json.Number).Int64()
+	bCharacter, _ := b["character"].(json.Number).Int64()
+	return aCharacter > bCharacter
+}
+
+// validURI reports whether uri parses as an absolute URI
+func validURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != ""
+}
+
+// repairURI percent-encodes the bytes that keep uri from parsing, keeping
+// valid escapes. A URI still invalid, such as one without a scheme, becomes
+// a file URI with uri as its path.
+func repairURI(uri string) string {
+	var b strings.Builder
+	for i := 0; i < len(uri); i++ {
+		c := uri[i]
+		switch {
+		case c == '%' && i+2 < len(uri) && isHex(uri[i+1]) && isHex(uri[i+2]):
+			b.WriteByte(c)
+		case c == '%' || c <= ' ' || c == 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if repaired := b.String(); validURI(repaired) {
+		return repaired
+	}
+	return (&url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(uri, "/")}).String()
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// invariantsEnforced reports whether outgoing payloads are checked: with
+// randomized responses, Unicode torture or response presets enabled, unless
+// the checks are disabled
+func (s *MockLSPServer) invariantsEnforced() bool {
+	cfg := s.config().LSP
+	if cfg.DisableInvariants {
+		return false
+	}
+	return cfg.MockData.Randomize || len(cfg.MockData.Unicode) > 0 || len(cfg.Presets) > 0
+}
+
+// enforceInvariants repairs the invariant violations of an outgoing payload
+// when invariants are enforced. Ranges are left reversed when the
+// extreme-positions preset was asked to reverse them.
+func (s *MockLSPServer) enforceInvariants(ctx context.Context, method string, data json.RawMessage) json.RawMessage {
+	if !s.invariantsEnforced() {
+		return data
+	}
+	data, violations := s.invariants.repair(method, data, !s.config().LSP.PresetReverseRanges)
+	if len(violations) == 0 {
+		return data
+	}
+	s.stats.RecordInvariantViolations(violations)
+	repaired := make([]string, 0, len(violations))
+	for _, invariant := range slices.Sorted(maps.Keys(violations)) {
+		repaired = append(repaired, fmt.Sprintf("%d %s", violations[invariant], invariant))
+	}
+	s.logError(ctx, "Repaired invariant violations in %s: %s", method, strings.Join(repaired, ", "))
+	return data
+}
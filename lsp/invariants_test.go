@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/lsptest"
+)
+
+func TestInvariantChecker_Repair(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		orderRanges bool
+		want        string
+		violations  map[string]int64
+	}{
+		{
+			name:        "valid payload unchanged",
+			data:        `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}}}`,
+			orderRanges: true,
+			want:        `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}}}`,
+		},
+		{
+			name:        "positions clamped",
+			data:        `{"start":{"line":-3,"character":2.5},"end":{"line":4294967296,"character":0}}`,
+			orderRanges: true,
+			want:        `{"end":{"character":0,"line":2147483647},"start":{"character":2,"line":0}}`,
+			violations:  map[string]int64{invariantPosition: 3},
+		},
+		{
+			name:        "reversed range swapped",
+			data:        `[{"range":{"start":{"line":5,"character":0},"end":{"line":2,"character":9}}}]`,
+			orderRanges: true,
+			want:        `[{"range":{"end":{"character":0,"line":5},"start":{"character":9,"line":2}}}]`,
+			violations:  map[string]int64{invariantRange: 1},
+		},
+		{
+			name: "reversed range kept",
+			data: `{"start":{"line":5,"character":0},"end":{"line":2,"character":9}}`,
+			want: `{"start":{"line":5,"character":0},"end":{"line":2,"character":9}}`,
+		},
+		{
+			name:       "URIs repaired",
+			data:       `{"uri":"file:///bad%zz\u0001.go","targetUri":"relative.go","label":"not a uri%"}`,
+			want:       `{"label":"not a uri%","targetUri":"file:///relative.go","uri":"file:///bad%25zz%01.go"}`,
+			violations: map[string]int64{invariantURI: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, violations := newInvariantChecker().repair("textDocument/hover", json.RawMessage(tt.data), tt.orderRanges)
+			if string(got) != tt.want {
+				t.Errorf("repair() = %s, want %s", got, tt.want)
+			}
+			if len(violations) != len(tt.violations) {
+				t.Fatalf("violations = %v, want %v", violations, tt.violations)
+			}
+			for invariant, count := range tt.violations {
+				if violations[invariant] != count {
+					t.Errorf("violations = %v, want %v", violations, tt.violations)
+				}
+			}
+		})
+	}
+}
+
+func TestInvariantChecker_DiagnosticsVersions(t *testing.T) {
+	checker := newInvariantChecker()
+	publish := func(version int) string {
+		data, _ := checker.repair(publishDiagnosticsMethod, json.RawMessage(fmt.Sprintf(`{"uri":"file:///a.go","version":%d,"diagnostics":[]}`, version)), true)
+		return string(data)
+	}
+
+	publish(3)
+	if got := publish(3); got != `{"uri":"file:///a.go","version":3,"diagnostics":[]}` {
+		t.Errorf("Expected the same version to be kept, got %s", got)
+	}
+	if got := publish(2); got != `{"diagnostics":[],"uri":"file:///a.go"}` {
+		t.Errorf("Expected the older version to be dropped, got %s", got)
+	}
+
+	checker.forget("file:///a.go")
+	if got := publish(1); got != `{"uri":"file:///a.go","version":1,"diagnostics":[]}` {
+		t.Errorf("Expected a reopened document to start over, got %s", got)
+	}
+}
+
+func TestEnforceInvariants(t *testing.T) {
+	reversed := protocol.Hover{
+		Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+			Value: protocol.MarkupContent{Kind: protocol.MarkupKindPlainText, Value: "reversed"},
+		},
+		Range: &protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 1}},
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.ServerConfig)
+		repaired  bool
+	}{
+		{
+			name:      "deterministic responses unchecked",
+			configure: func(cfg *config.ServerConfig) {},
+		},
+		{
+			name:      "randomized responses repaired",
+			configure: func(cfg *config.ServerConfig) { cfg.LSP.MockData.Randomize = true },
+			repaired:  true,
+		},
+		{
+			name: "invariants disabled",
+			configure: func(cfg *config.ServerConfig) {
+				cfg.LSP.MockData.Randomize = true
+				cfg.LSP.DisableInvariants = true
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			tt.configure(cfg)
+			server.SetConfig(cfg)
+			conn := lsptest.NewConn()
+			defer conn.Close()
+
+			req := testRequest(t, 1, "textDocument/hover", map[string]any{})
+			if err := server.reply(context.Background(), conn, req, reversed); err != nil {
+				t.Fatalf("reply failed: %v", err)
+			}
+			response, ok := conn.Response(req.ID)
+			if !ok {
+				t.Fatal("Expected a response")
+			}
+			var hover protocol.Hover
+			if err := json.Unmarshal(response.Result, &hover); err != nil {
+				t.Fatalf("Failed to decode hover: %v", err)
+			}
+			if repaired := hover.Range.Start.Line == 1; repaired != tt.repaired {
+				t.Errorf("Expected repaired = %v, got range %+v", tt.repaired, hover.Range)
+			}
+			want := int64(0)
+			if tt.repaired {
+				want = 1
+			}
+			if got := server.Stats().InvariantViolations[invariantRange]; got != want {
+				t.Errorf("Expected %d range violations in the stats, got %d", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package lsp
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JUnitTestSuites is a JUnit XML test report of a session, for CI jobs that
+// show test reports in their dashboards. Its suites hold a test case per
+// method for error responses and latency budgets, per method for scenario
+// expectations and per SLO.
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite groups the test cases of one kind of verdict
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is the verdict on one method or SLO, failed when Failure
+// is set
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+}
+
+// JUnitFailure explains a failed test case
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReport translates the verdicts of a stats snapshot into a JUnit
+// report: a method fails when some of its requests were answered with an
+// error or overran their latency budget, or when its responses differed
+// from the scenario expectations, and an SLO fails when it was not met.
+// Suites without test cases are left out.
+func NewJUnitReport(snapshot StatsSnapshot) JUnitTestSuites {
+	report := JUnitTestSuites{Name: "mock-lsp-server"}
+	if snapshot.ClientID != "" {
+		report.Name += " " + snapshot.ClientID
+	}
+	timestamp := ""
+	if !snapshot.StartedAt.IsZero() {
+		timestamp = snapshot.StartedAt.UTC().Format(time.RFC3339)
+	}
+
+	requests := JUnitTestSuite{Name: "requests", Timestamp: timestamp}
+	for _, method := range slices.Sorted(maps.Keys(snapshot.Requests)) {
+		total := snapshot.Requests[method]
+		var messages []string
+		if errors := snapshot.ErrorResponses[method]; errors > 0 {
+			messages = append(messages, fmt.Sprintf("%d of %d requests answered with an error", errors, total))
+		}
+		if overruns := snapshot.BudgetOverruns[method]; overruns > 0 {
+			messages = append(messages, fmt.Sprintf("%d of %d requests overran the latency budget", overruns, total))
+		}
+		testCase := JUnitTestCase{Name: method, ClassName: "requests"}
+		if len(messages) > 0 {
+			testCase.Failure = &JUnitFailure{Message: strings.Join(messages, "; "), Type: "requests"}
+		}
+		requests.add(testCase)
+	}
+
+	expectations := JUnitTestSuite{Name: "expectations", Timestamp: timestamp}
+	failures := make(map[string][]ExpectationFailure)
+	for _, failure := range snapshot.ExpectationFailures {
+		failures[failure.Method] = append(failures[failure.Method], failure)
+	}
+	for _, method := range slices.Sorted(maps.Keys(snapshot.ExpectationChecks)) {
+		testCase := JUnitTestCase{Name: method, ClassName: "expectations"}
+		if failed := failures[method]; len(failed) > 0 {
+			var text strings.Builder
+			responses := make(map[string]bool)
+			for _, failure := range failed {
+				responses[failure.ID] = true
+				for _, difference := range failure.Differences {
+					fmt.Fprintf(&text, "request %s: %s\n", failure.ID, difference)
+				}
+			}
+			testCase.Failure = &JUnitFailure{
+				Message: fmt.Sprintf("%d of %d responses differed from the scenario expectations", len(responses), snapshot.ExpectationChecks[method]),
+				Type:    "expectations",
+				Text:    text.String(),
+			}
+		}
+		expectations.add(testCase)
+	}
+
+	slos := JUnitTestSuite{Name: "slos", Timestamp: timestamp}
+	for _, slo := range snapshot.SLOs {
+		testCase := JUnitTestCase{Name: slo.Method, ClassName: "slos"}
+		if !slo.Met {
+			message := fmt.Sprintf("p50 %s (target %s), p99 %s (target %s)", slo.ActualP50, slo.TargetP50, slo.ActualP99, slo.TargetP99)
+			if slo.TargetMaxBytes > 0 {
+				message += fmt.Sprintf(", %d bytes (target %d)", slo.ActualMaxBytes, slo.TargetMaxBytes)
+			}
+			testCase.Failure = &JUnitFailure{Message: message, Type: "slos"}
+		}
+		slos.add(testCase)
+	}
+
+	for _, suite := range []JUnitTestSuite{requests, expectations, slos} {
+		if suite.Tests == 0 {
+			continue
+		}
+		report.Suites = append(report.Suites, suite)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+	return report
+}
+
+// add appends a test case to the suite and counts it
+func (s *JUnitTestSuite) add(testCase JUnitTestCase) {
+	s.Cases = append(s.Cases, testCase)
+	s.Tests++
+	if testCase.Failure != nil {
+		s.Failures++
+	}
+}
+
+// Write writes the report as an indented XML document
+func (r JUnitTestSuites) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// SetJUnitPath makes the server write a JUnit report of the session to path
+// when it shuts down, exits or disconnects. An empty path writes none.
+func (s *MockLSPServer) SetJUnitPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.junitPath = path
+}
+
+// WriteJUnitReport writes the JUnit report of the session so far, if a
+// path is set, and returns the path written
+func (s *MockLSPServer) WriteJUnitReport() (string, error) {
+	s.mu.Lock()
+	path := s.junitPath
+	s.mu.Unlock()
+	if path == "" {
+		return "", nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create JUnit report: %w", err)
+	}
+	if err := NewJUnitReport(s.Stats()).Write(file); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return path, file.Close()
+}
+
+// saveJUnitReport writes the JUnit report, if a path is set, and logs where
+func (s *MockLSPServer) saveJUnitReport(ctx context.Context) {
+	path, err := s.WriteJUnitReport()
+	if err != nil {
+		s.logError(ctx, "Failed to write JUnit report: %v", err)
+	} else if path != "" {
+		s.logInfo(ctx, "JUnit report written to %s", path)
+	}
+}
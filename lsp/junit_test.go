@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mock-lsp-server/lsptest"
+)
+
+func TestNewJUnitReport(t *testing.T) {
+	snapshot := StatsSnapshot{
+		ClientID:       "client-1",
+		Requests:       map[string]int64{"textDocument/hover": 4, "textDocument/completion": 2, "initialize": 1},
+		ErrorResponses: map[string]int64{"textDocument/hover": 1},
+		BudgetOverruns: map[string]int64{"textDocument/completion": 2},
+		ExpectationChecks: map[string]int64{
+			"textDocument/hover":      3,
+			"textDocument/definition": 1,
+		},
+		ExpectationFailures: []ExpectationFailure{
+			{Method: "textDocument/hover", ID: "7", Differences: []string{"contents.value: want \"a\", got \"b\""}},
+		},
+		SLOs: []SLOResult{
+			{Method: "textDocument/hover", TargetP50: "10ms", TargetP99: "50ms", ActualP50: "20ms", ActualP99: "80ms"},
+			{Method: "initialize", Met: true},
+		},
+	}
+
+	report := NewJUnitReport(snapshot)
+	if report.Tests != 7 || report.Failures != 4 {
+		t.Errorf("Expected 7 tests and 4 failures, got %d and %d", report.Tests, report.Failures)
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("Expected 3 suites, got %+v", report.Suites)
+	}
+
+	failures := make(map[string]string)
+	for _, suite := range report.Suites {
+		for _, testCase := range suite.Cases {
+			if testCase.Failure != nil {
+				failures[suite.Name+" "+testCase.Name] = testCase.Failure.Message
+			}
+		}
+	}
+	want := map[string]string{
+		"requests textDocument/completion": "2 of 2 requests overran the latency budget",
+		"requests textDocument/hover":      "1 of 4 requests answered with an error",
+		"expectations textDocument/hover":  "1 of 3 responses differed from the scenario expectations",
+		"slos textDocument/hover":          "p50 20ms (target 10ms), p99 80ms (target 50ms)",
+	}
+	for name, message := range want {
+		if failures[name] != message {
+			t.Errorf("Expected failure %q for %s, got %q", message, name, failures[name])
+		}
+	}
+	if len(failures) != len(want) {
+		t.Errorf("Expected failures %v, got %v", want, failures)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), xml.Header+"<testsuites") {
+		t.Errorf("Expected an XML document of test suites, got %s", out.String())
+	}
+	var decoded JUnitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode the report: %v", err)
+	}
+	if decoded.Suites[1].Cases[1].Failure.Text != "request 7: contents.value: want \"a\", got \"b\"\n" {
+		t.Errorf("Expected the differences in the failure text, got %+v", decoded.Suites[1].Cases[1].Failure)
+	}
+}
+
+func TestNewJUnitReport_EmptySuites(t *testing.T) {
+	report := NewJUnitReport(StatsSnapshot{Requests: map[string]int64{"initialize": 1}})
+	if len(report.Suites) != 1 || report.Suites[0].Name != "requests" || report.Failures != 0 {
+		t.Errorf("Expected a single passing requests suite, got %+v", report)
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+
+	// An error response and a cancellation, which is not counted
+	server.Dispatch(context.Background(), conn, testRequest(t, 1, "textDocument/hover", "not params"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server.Dispatch(ctx, conn, testRequest(t, 2, "textDocument/hover", map[string]any{}))
+	if errors := server.Stats().ErrorResponses["textDocument/hover"]; errors != 1 {
+		t.Errorf("Expected 1 error response to hover, got %d", errors)
+	}
+
+	if path, err := server.WriteJUnitReport(); path != "" || err != nil {
+		t.Errorf("Expected no report without a path, got %q, %v", path, err)
+	}
+	path := filepath.Join(t.TempDir(), "report.xml")
+	server.SetJUnitPath(path)
+	if written, err := server.WriteJUnitReport(); written != path || err != nil {
+		t.Fatalf("WriteJUnitReport() = %q, %v", written, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the report: %v", err)
+	}
+	if !strings.Contains(string(data), `<failure message="1 of 2 requests answered with an error" type="requests">`) {
+		t.Errorf("Expected the hover error in the report, got %s", data)
+	}
+}
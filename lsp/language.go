@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// extensionLanguages maps file extensions to the languageId clients are
+// expected to send for them. Ambiguous extensions such as .h are left out.
+var extensionLanguages = map[string]string{
+	".c":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".go":    "go",
+	".html":  "html",
+	".java":  "java",
+	".js":    "javascript",
+	".json":  "json",
+	".jsx":   "javascriptreact",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".md":    "markdown",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sh":    "shellscript",
+	".swift": "swift",
+	".ts":    "typescript",
+	".tsx":   "typescriptreact",
+	".txt":   "plaintext",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// languageProfile shapes mock responses after the conventions of a language
+type languageProfile struct {
+	language  string // languageId the profile was selected for
+	function  string // Function name in the naming style of the language
+	variable  string
+	class     string
+	signature string // Declaration of function shown in hover code blocks
+}
+
+// languageProfiles holds the response profiles by languageId. Documents of
+// other languages get the language-neutral default responses.
+var languageProfiles = map[string]languageProfile{
+	"go":         {function: "MockFunction", variable: "mockVariable", class: "MockType", signature: "func MockFunction() error"},
+	"python":     {function: "mock_function", variable: "mock_variable", class: "MockClass", signature: "def mock_function() -> None"},
+	"typescript": {function: "mockFunction", variable: "mockVariable", class: "MockClass", signature: "function mockFunction(): void"},
+	"javascript": {function: "mockFunction", variable: "mockVariable", class: "MockClass", signature: "function mockFunction()"},
+	"rust":       {function: "mock_function", variable: "mock_variable", class: "MockStruct", signature: "fn mock_function()"},
+}
+
+// detectLanguage returns the extension of the path of uri and the
+// languageId expected for it, which is empty for unknown extensions
+func detectLanguage(uri string) (ext, languageID string) {
+	p := uri
+	if parsed, err := url.Parse(uri); err == nil && parsed.Path != "" {
+		p = parsed.Path
+	}
+	ext = strings.ToLower(path.Ext(p))
+	return ext, extensionLanguages[ext]
+}
+
+// documentLanguage returns the languageId of an open document, falling back
+// to the language detected from its extension when the client sent none. It
+// returns an empty string for documents that are not open.
+func (s *MockLSPServer) documentLanguage(uri string) string {
+	doc, ok := s.documents.Get(uri)
+	if !ok {
+		return ""
+	}
+	if doc.LanguageId != "" {
+		return string(doc.LanguageId)
+	}
+	_, languageID := detectLanguage(uri)
+	return languageID
+}
+
+// languageProfile returns the response profile for the open document, or nil
+// if responses should stay language-neutral
+func (s *MockLSPServer) languageProfile(uri string) *languageProfile {
+	languageID := s.documentLanguage(uri)
+	profile, ok := languageProfiles[languageID]
+	if !ok {
+		return nil
+	}
+	profile.language = languageID
+	return &profile
+}
+
+// checkLanguage validates the languageId of an opened document against its
+// extension and the configured extensions and languages. A missing
+// languageId, or one contradicting an extension the server is configured
+// for, is logged, reported to the client and counted in the stats.
+func (s *MockLSPServer) checkLanguage(ctx context.Context, conn Conn, doc protocol.TextDocumentItem) {
+	uri := string(doc.Uri)
+	languageID := string(doc.LanguageId)
+	ext, expected := detectLanguage(uri)
+	configured := slices.ContainsFunc(s.config().LSP.Extensions, func(e string) bool {
+		return strings.EqualFold(e, ext)
+	})
+
+	var message string
+	switch {
+	case languageID == "":
+		s.logError(ctx, "Document %s was opened without a languageId", uri)
+		message = s.message(msgLanguageMissing, uri)
+	case configured && expected != "" && languageID != expected:
+		s.logError(ctx, "Document %s was opened as %q, but its extension %s suggests %q", uri, languageID, ext, expected)
+		message = s.message(msgLanguageMismatch, uri, languageID, expected)
+	default:
+		if languages := s.config().LSP.MockData.Languages; len(languages) > 0 && !slices.Contains(languages, languageID) {
+			s.logInfo(ctx, "Document %s has languageId %q, which is not among the configured languages %v", uri, languageID, languages)
+		}
+		return
+	}
+
+	s.stats.RecordLanguageMismatch()
+	params := protocol.LogMessageParams{Type: protocol.MessageTypeWarning, Message: message}
+	if err := s.notify(ctx, conn, "window/logMessage", params); err != nil {
+		s.logError(ctx, "Failed to send language warning: %v", err)
+	}
+}
+
+// completionItems adapts the default completion items to the profile
+func (p *languageProfile) completionItems(items []protocol.CompletionItem) []protocol.CompletionItem {
+	names := map[string]string{
+		"mockFunction": p.function,
+		"mockVariable": p.variable,
+		"mockClass":    p.class,
+	}
+	for i, item := range items {
+		name, ok := names[item.Label]
+		if !ok {
+			continue
+		}
+		items[i].Label = name
+		switch {
+		case item.InsertText == item.Label+"()":
+			items[i].InsertText = name + "()"
+		case item.InsertText != "":
+			items[i].InsertText = name
+		}
+	}
+	return items
+}
+
+// hoverContent appends a declaration in the language to markdown content
+func (p *languageProfile) hoverContent(content string) string {
+	return fmt.Sprintf("%s\n\n```%s\n%s\n```", content, p.language, p.signature)
+}
@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		uri      string
+		wantExt  string
+		wantLang string
+	}{
+		{"file:///src/main.go", ".go", "go"},
+		{"file:///src/App.TSX", ".tsx", "typescriptreact"},
+		{"file:///tmp/my%20script.py", ".py", "python"},
+		{"untitled:Untitled-1", "", ""},
+		{"file:///include/header.h", ".h", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			ext, lang := detectLanguage(tt.uri)
+			if ext != tt.wantExt || lang != tt.wantLang {
+				t.Errorf("Expected %q %q, got %q %q", tt.wantExt, tt.wantLang, ext, lang)
+			}
+		})
+	}
+}
+
+func TestLanguageValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		uri          string
+		languageID   string
+		wantWarning  string
+		wantMismatch int64
+	}{
+		{"matching languageId", "file:///app.py", "python", "", 0},
+		{"python opened as plaintext", "file:///app.py", "plaintext", `opened as "plaintext", but its extension suggests "python"`, 1},
+		{"missing languageId", "file:///app.go", "", "opened without a languageId", 1},
+		{"extension not configured", "file:///app.rb", "plaintext", "", 0},
+		{"unknown extension", "file:///Makefile", "makefile", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			var warnings []string
+			client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+				if req.Method != "window/logMessage" {
+					return
+				}
+				var params protocol.LogMessageParams
+				if err := json.Unmarshal(*req.Params, &params); err == nil {
+					warnings = append(warnings, params.Message)
+				}
+			})
+			ctx := context.Background()
+
+			err := client.Notify(ctx, "textDocument/didOpen", map[string]any{
+				"textDocument": map[string]any{"uri": tt.uri, "languageId": tt.languageID, "version": 1, "text": ""},
+			})
+			if err != nil {
+				t.Fatalf("didOpen failed: %v", err)
+			}
+			var snapshot StatsSnapshot
+			if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+				t.Fatalf("Stats request failed: %v", err)
+			}
+
+			if snapshot.LanguageMismatches != tt.wantMismatch {
+				t.Errorf("Expected %d language mismatches, got %d", tt.wantMismatch, snapshot.LanguageMismatches)
+			}
+			switch {
+			case tt.wantWarning == "" && len(warnings) > 0:
+				t.Errorf("Expected no warning, got %v", warnings)
+			case tt.wantWarning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.wantWarning)):
+				t.Errorf("Expected a warning containing %q, got %v", tt.wantWarning, warnings)
+			}
+		})
+	}
+}
+
+func TestLanguageProfiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		uri           string
+		languageID    string
+		wantFunction  string
+		wantHoverCode string
+	}{
+		{"python", "file:///app.py", "python", "mock_function", "```python\ndef mock_function() -> None\n```"},
+		{"go", "file:///main.go", "go", "MockFunction", "```go\nfunc MockFunction() error\n```"},
+		{"detected from extension", "file:///lib.rs", "", "mock_function", "```rust\nfn mock_function()\n```"},
+		{"no profile", "file:///notes.txt", "plaintext", "mockFunction", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			err := client.Notify(ctx, "textDocument/didOpen", map[string]any{
+				"textDocument": map[string]any{"uri": tt.uri, "languageId": tt.languageID, "version": 1, "text": "x\n"},
+			})
+			if err != nil {
+				t.Fatalf("didOpen failed: %v", err)
+			}
+			document := protocol.TextDocumentIdentifier{Uri: protocol.DocumentUri(tt.uri)}
+
+			var list protocol.CompletionList
+			if err := client.Call(ctx, "textDocument/completion", protocol.CompletionParams{TextDocument: document}, &list); err != nil {
+				t.Fatalf("Completion request failed: %v", err)
+			}
+			if len(list.Items) == 0 || list.Items[0].Label != tt.wantFunction {
+				t.Fatalf("Expected first item %q, got %+v", tt.wantFunction, list.Items)
+			}
+			if want := tt.wantFunction + "()"; list.Items[0].InsertText != want {
+				t.Errorf("Expected insert text %q, got %q", want, list.Items[0].InsertText)
+			}
+
+			var hover protocol.Hover
+			if err := client.Call(ctx, "textDocument/hover", protocol.HoverParams{TextDocument: document}, &hover); err != nil {
+				t.Fatalf("Hover request failed: %v", err)
+			}
+			markup, ok := hover.Contents.Value.(protocol.MarkupContent)
+			if !ok {
+				t.Fatalf("Expected MarkupContent, got %T", hover.Contents.Value)
+			}
+			hasCode := strings.Contains(markup.Value, "```")
+			if tt.wantHoverCode == "" && hasCode {
+				t.Errorf("Expected no code block, got %q", markup.Value)
+			}
+			if tt.wantHoverCode != "" && !strings.HasSuffix(markup.Value, tt.wantHoverCode) {
+				t.Errorf("Expected hover ending in %q, got %q", tt.wantHoverCode, markup.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// LatencyInjector delays responses according to per-method SLO declarations
+// and fixed delays
+type LatencyInjector struct {
+	src    RandomSource
+	slos   map[string]config.SLOConfig
+	delays map[string]config.Duration
+}
+
+// NewLatencyInjector creates a latency injector for the SLOs and delays of
+// the latency configuration
+func NewLatencyInjector(latency config.LatencyConfig, src RandomSource) *LatencyInjector {
+	return &LatencyInjector{
+		src:    src,
+		slos:   latency.SLOs,
+		delays: latency.Delays,
+	}
+}
+
+// SLO returns the declared SLO for a method, if any
+func (li *LatencyInjector) SLO(method string) (config.SLOConfig, bool) {
+	slo, ok := li.slos[method]
+	return slo, ok
+}
+
+// Sample picks a delay for the method so that the resulting distribution
+// honors the declared SLO: half of the samples fall at or below P50, 99% at
+// or below P99 and the remaining tail lands up to 20% beyond P99.
+func (li *LatencyInjector) Sample(method string) time.Duration {
+	slo, ok := li.slos[method]
+	if !ok {
+		return 0
+	}
+
+	u := li.src.Float64()
+	f := li.src.Float64()
+
+	p50 := float64(slo.P50.Duration())
+	p99 := float64(slo.P99.Duration())
+
+	switch {
+	case u < 0.5:
+		return time.Duration(f * p50)
+	case u < 0.99:
+		return time.Duration(p50 + f*(p99-p50))
+	default:
+		return time.Duration(p99 + f*p99*0.2)
+	}
+}
+
+// Delay sleeps for the fixed delay of the method plus a sampled duration,
+// returning early if ctx is cancelled
+func (li *LatencyInjector) Delay(ctx context.Context, method string) error {
+	delay := li.delays[method].Duration() + li.Sample(method)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkLatencyBudget counts a request to method that took longer than its
+// budget and warns the client through window/logMessage. Warnings are
+// aggregated per method: they are sent on the first, second, fourth,
+// eighth... overrun and carry the count so far, so a method that is always
+// slow does not flood the client.
+func (s *MockLSPServer) checkLatencyBudget(ctx context.Context, conn Conn, method string, elapsed, budget time.Duration) {
+	if elapsed <= budget {
+		return
+	}
+
+	overruns := s.stats.RecordBudgetOverrun(method)
+	s.logError(ctx, "Latency budget exceeded: %s took %s, budget %s (%d overruns)", method, elapsed, budget, overruns)
+	if overruns&(overruns-1) != 0 {
+		return
+	}
+
+	message := protocol.LogMessageParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: s.message(msgLatencyBudget, method, elapsed.Round(time.Millisecond), budget, overruns),
+	}
+	if err := s.notify(ctx, conn, "window/logMessage", message); err != nil {
+		s.logError(ctx, "Failed to send latency budget message: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+func TestLatencyInjector_SampleHonorsSLO(t *testing.T) {
+	slos := map[string]config.SLOConfig{
+		"textDocument/completion": {
+			P50: config.Duration(10 * time.Millisecond),
+			P99: config.Duration(50 * time.Millisecond),
+		},
+	}
+	injector := NewLatencyInjector(config.LatencyConfig{SLOs: slos}, NewSeededRandomSource(42))
+
+	const samples = 10000
+	var withinP50, withinP99 int
+	for i := 0; i < samples; i++ {
+		delay := injector.Sample("textDocument/completion")
+		if delay < 0 || delay > 60*time.Millisecond {
+			t.Fatalf("Sample out of bounds: %v", delay)
+		}
+		if delay <= 10*time.Millisecond {
+			withinP50++
+		}
+		if delay <= 50*time.Millisecond {
+			withinP99++
+		}
+	}
+
+	if ratio := float64(withinP50) / samples; ratio < 0.45 || ratio > 0.55 {
+		t.Errorf("Expected about half of samples within p50, got %.3f", ratio)
+	}
+	if ratio := float64(withinP99) / samples; ratio < 0.98 {
+		t.Errorf("Expected 99%% of samples within p99, got %.3f", ratio)
+	}
+
+	if delay := injector.Sample("textDocument/hover"); delay != 0 {
+		t.Errorf("Expected no delay for method without SLO, got %v", delay)
+	}
+}
+
+func TestLatencyInjector_DelayCancelled(t *testing.T) {
+	slos := map[string]config.SLOConfig{
+		"textDocument/hover": {
+			P50: config.Duration(time.Hour),
+			P99: config.Duration(time.Hour),
+		},
+	}
+	injector := NewLatencyInjector(config.LatencyConfig{SLOs: slos}, NewSeededRandomSource(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := injector.Delay(ctx, "textDocument/hover"); err == nil {
+		t.Error("Expected Delay to return an error for a cancelled context")
+	}
+}
+
+func TestLatencyBudget(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Latency.Budgets = map[string]config.Duration{
+		"textDocument/hover":      config.Duration(time.Nanosecond),
+		"textDocument/completion": config.Duration(100 * time.Millisecond),
+	}
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	hover := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///budget.go"},
+		"position":     map[string]any{"line": 0, "character": 0},
+	}
+	server.Dispatch(ctx, conn, testRequest(t, 1, "textDocument/hover", hover))
+	warnings := conn.Sent("window/logMessage")
+	if len(warnings) != 1 {
+		t.Fatalf("Expected a warning for the hover over its budget, got %d", len(warnings))
+	}
+	var params protocol.LogMessageParams
+	if err := json.Unmarshal(warnings[0].Params, &params); err != nil || params.Type != protocol.MessageTypeWarning ||
+		!strings.Contains(params.Message, "textDocument/hover") {
+		t.Errorf("Expected a warning naming the method, got %+v", params)
+	}
+
+	for i := 0; i < 8; i++ {
+		server.checkLatencyBudget(ctx, conn, "textDocument/completion", 150*time.Millisecond, 100*time.Millisecond)
+	}
+	server.checkLatencyBudget(ctx, conn, "textDocument/completion", 50*time.Millisecond, 100*time.Millisecond)
+
+	// Warnings go out on the 1st, 2nd, 4th and 8th completion overrun
+	if got := len(conn.Sent("window/logMessage")); got != 5 {
+		t.Errorf("Expected 5 aggregated warnings, got %d", got)
+	}
+	overruns := server.stats.Snapshot(0).BudgetOverruns
+	if overruns["textDocument/hover"] != 1 || overruns["textDocument/completion"] != 8 {
+		t.Errorf("Unexpected budget overruns: %v", overruns)
+	}
+}
+
+func TestLatencyBudgetReconfigured(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Latency.Budgets = map[string]config.Duration{"textDocument/hover": config.Duration(time.Nanosecond)}
+	server.SetConfig(cfg)
+	// The budget is dropped while the hover is handled
+	setFailures(server, []failureMiddleware{func(next dispatchFunc) dispatchFunc {
+		return func(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+			server.SetConfig(config.DefaultConfig())
+			next(ctx, conn, req)
+		}
+	}})
+	conn := lsptest.NewConn()
+	defer conn.Close()
+
+	hover := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///budget.go"},
+		"position":     map[string]any{"line": 0, "character": 0},
+	}
+	server.Dispatch(context.Background(), conn, testRequest(t, 1, "textDocument/hover", hover))
+	if overruns := server.stats.Snapshot(0).BudgetOverruns; overruns["textDocument/hover"] != 1 {
+		t.Errorf("Expected the budget in effect on arrival to apply, got overruns %v", overruns)
+	}
+}
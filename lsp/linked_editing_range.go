@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// linkedEditingWordPattern matches the words isWordRune accepts, so clients
+// stop the linked edit where the server would stop the word
+const linkedEditingWordPattern = `[\p{L}\p{N}\p{M}_]+`
+
+// linkedEditingRanges returns the ranges of every whole-word occurrence in
+// text of the word at pos, which clients edit together as one is typed in.
+// ok is false when pos is not on a word.
+func linkedEditingRanges(text string, pos protocol.Position) (protocol.LinkedEditingRanges, bool) {
+	word := wordAt(text, pos)
+	if word == "" {
+		return protocol.LinkedEditingRanges{}, false
+	}
+	return protocol.LinkedEditingRanges{
+		Ranges:      wordOccurrences(text, word, math.MaxInt),
+		WordPattern: linkedEditingWordPattern,
+	}, true
+}
+
+// handleLinkedEditingRange processes textDocument/linkedEditingRange
+// requests, answering with the occurrences of the word at the position in
+// an open document, and null off a word or for documents the server does
+// not know
+func (s *MockLSPServer) handleLinkedEditingRange(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.LinkedEditingRangeParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse linked editing range params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send linked editing range error: %v", replyErr)
+		}
+		return
+	}
+
+	var result *protocol.LinkedEditingRanges
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		if ranges, ok := linkedEditingRanges(text, params.Position); ok {
+			result = &ranges
+			s.logInfo(ctx, "Linked editing ranges: %d occurrences in %s", len(ranges.Ranges), uri)
+		}
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send linked editing range response: %v", err)
+	}
+}
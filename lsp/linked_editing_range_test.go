@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestLinkedEditingRanges(t *testing.T) {
+	text := "<div>é</div>\n<divider/>\n"
+	ranges, ok := linkedEditingRanges(text, protocol.Position{Line: 0, Character: 2})
+	if !ok {
+		t.Fatal("Expected linked ranges on a word")
+	}
+	want := []protocol.Range{
+		{Start: protocol.Position{Line: 0, Character: 1}, End: protocol.Position{Line: 0, Character: 4}},
+		{Start: protocol.Position{Line: 0, Character: 8}, End: protocol.Position{Line: 0, Character: 11}},
+	}
+	if len(ranges.Ranges) != len(want) || ranges.Ranges[0] != want[0] || ranges.Ranges[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, ranges.Ranges)
+	}
+	if ranges.WordPattern != linkedEditingWordPattern {
+		t.Errorf("Expected the word pattern, got %q", ranges.WordPattern)
+	}
+
+	if _, ok := linkedEditingRanges(text, protocol.Position{Line: 1, Character: 9}); ok {
+		t.Error("Expected no linked ranges off a word")
+	}
+}
+
+func TestLinkedEditingRangeRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	document := map[string]any{"uri": "file:///linked.html", "languageId": "html", "version": 1, "text": "<p>text</p>\n"}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := map[string]any{"textDocument": map[string]any{"uri": "file:///linked.html"}, "position": map[string]any{"line": 0, "character": 1}}
+	var linked *protocol.LinkedEditingRanges
+	if err := client.Call(ctx, "textDocument/linkedEditingRange", params, &linked); err != nil {
+		t.Fatalf("Linked editing range request failed: %v", err)
+	}
+	if linked == nil || len(linked.Ranges) != 2 || linked.Ranges[1].Start.Character != 9 {
+		t.Errorf("Expected the opening and closing tag names, got %+v", linked)
+	}
+
+	params["textDocument"] = map[string]any{"uri": "file:///unopened.html"}
+	linked = nil
+	if err := client.Call(ctx, "textDocument/linkedEditingRange", params, &linked); err != nil || linked != nil {
+		t.Errorf("Expected null for an unopened document, got %+v and %v", linked, err)
+	}
+}
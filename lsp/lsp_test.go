@@ -1,15 +1,19 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"slices"
 	"strings"
 	"testing"
 
 	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
 )
 
 // Test helper functions for LSP methods
@@ -21,6 +25,29 @@ func createTestServer() *MockLSPServer {
 	return NewMockLSPServer(createTestLogger())
 }
 
+// connectTestClient wires server to an in-memory JSON-RPC client connection.
+// Notifications sent by the server are delivered to the notify callback when non-nil.
+func connectTestClient(t testing.TB, server *MockLSPServer, notify func(*jsonrpc.Request)) jsonrpc.Conn {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server, server.ConnOpts()...)
+	clientConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+			if notify != nil {
+				notify(req)
+			}
+			return nil, nil
+		}))
+
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	return clientConn
+}
+
 func TestNewMockLSPServer(t *testing.T) {
 	// Create a temporary logger that discards output
 	logger := log.New(io.Discard, "", 0)
@@ -32,11 +59,11 @@ func TestNewMockLSPServer(t *testing.T) {
 	}
 
 	if server.documents == nil {
-		t.Fatal("documents map not initialized")
+		t.Fatal("document store not initialized")
 	}
 
-	if len(server.documents) != 0 {
-		t.Errorf("Expected empty documents map, got %d items", len(server.documents))
+	if server.documents.Len() != 0 {
+		t.Errorf("Expected empty document store, got %d items", server.documents.Len())
 	}
 }
 
@@ -54,7 +81,7 @@ func TestNewMockLSPServerWithLogger(t *testing.T) {
 	}
 
 	if server.documents == nil {
-		t.Fatal("Documents map not initialized")
+		t.Fatal("Document store not initialized")
 	}
 }
 
@@ -66,16 +93,16 @@ func TestDocumentStorage(t *testing.T) {
 
 	// Test adding a document
 	uri := "file:///test.go"
-	doc := &protocol.TextDocumentItem{
+	doc := protocol.TextDocumentItem{
 		Uri:     protocol.DocumentUri(uri),
 		Text:    "package main",
 		Version: 1,
 	}
 
-	server.documents[uri] = doc
+	server.documents.Put(doc)
 
 	// Test retrieval
-	retrieved, exists := server.documents[uri]
+	retrieved, exists := server.documents.Get(uri)
 	if !exists {
 		t.Error("Document not found after adding")
 	}
@@ -85,8 +112,8 @@ func TestDocumentStorage(t *testing.T) {
 	}
 
 	// Test removal
-	delete(server.documents, uri)
-	_, exists = server.documents[uri]
+	server.documents.Delete(uri)
+	_, exists = server.documents.Get(uri)
 	if exists {
 		t.Error("Document still exists after deletion")
 	}
@@ -100,28 +127,28 @@ func TestDocumentLifecycle(t *testing.T) {
 	uri1 := "file:///test1.go"
 	uri2 := "file:///test2.go"
 
-	doc1 := &protocol.TextDocumentItem{
+	doc1 := protocol.TextDocumentItem{
 		Uri:     protocol.DocumentUri(uri1),
 		Text:    "package main",
 		Version: 1,
 	}
 
-	doc2 := &protocol.TextDocumentItem{
+	doc2 := protocol.TextDocumentItem{
 		Uri:     protocol.DocumentUri(uri2),
 		Text:    "package test",
 		Version: 1,
 	}
 
 	// Add documents
-	server.documents[uri1] = doc1
-	server.documents[uri2] = doc2
+	server.documents.Put(doc1)
+	server.documents.Put(doc2)
 
-	if len(server.documents) != 2 {
-		t.Errorf("Expected 2 documents, got %d", len(server.documents))
+	if server.documents.Len() != 2 {
+		t.Errorf("Expected 2 documents, got %d", server.documents.Len())
 	}
 
 	// Test retrieval
-	retrieved1, exists1 := server.documents[uri1]
+	retrieved1, exists1 := server.documents.Get(uri1)
 	if !exists1 {
 		t.Error("Document 1 not found")
 	}
@@ -130,13 +157,13 @@ func TestDocumentLifecycle(t *testing.T) {
 	}
 
 	// Test removal
-	delete(server.documents, uri1)
-	if len(server.documents) != 1 {
-		t.Errorf("Expected 1 document after deletion, got %d", len(server.documents))
+	server.documents.Delete(uri1)
+	if server.documents.Len() != 1 {
+		t.Errorf("Expected 1 document after deletion, got %d", server.documents.Len())
 	}
 
 	// Test document doesn't exist
-	_, exists := server.documents[uri1]
+	_, exists := server.documents.Get(uri1)
 	if exists {
 		t.Error("Document 1 should not exist after deletion")
 	}
@@ -539,42 +566,36 @@ func TestHandleMethodSwitch(t *testing.T) {
 	}
 }
 
-// Test concurrent access to documents map
+// Test concurrent access to the document store
 func TestConcurrentDocumentAccess(t *testing.T) {
 	server := createTestServer()
 
-	// Test concurrent reads and writes to documents map
+	// Test concurrent reads and writes to the document store
 	done := make(chan bool)
 	numGoroutines := 10
 
-	// Start multiple goroutines that access the documents map
+	// Start multiple goroutines that access the document store
 	for i := 0; i < numGoroutines; i++ {
 		go func(id int) {
 			uri := fmt.Sprintf("file:///test%d.go", id)
-			doc := &protocol.TextDocumentItem{
+			doc := protocol.TextDocumentItem{
 				Uri:     protocol.DocumentUri(uri),
 				Text:    fmt.Sprintf("package test%d", id),
 				Version: 1,
 			}
 
-			server.mu.Lock()
 			// Add document
-			server.documents[uri] = doc
-			server.mu.Unlock()
+			server.documents.Put(doc)
 
-			server.mu.Lock()
 			// Read document
-			if retrieved, exists := server.documents[uri]; exists {
+			if retrieved, exists := server.documents.Get(uri); exists {
 				if retrieved.Text != fmt.Sprintf("package test%d", id) {
 					t.Errorf("Unexpected document content for %s", uri)
 				}
 			}
-			server.mu.Unlock()
 
-			server.mu.Lock()
 			// Remove document
-			delete(server.documents, uri)
-			server.mu.Unlock()
+			server.documents.Delete(uri)
 
 			done <- true
 		}(i)
@@ -593,14 +614,14 @@ func TestEdgeCases(t *testing.T) {
 	// Test empty URI
 	t.Run("EmptyURI", func(t *testing.T) {
 		uri := ""
-		doc := &protocol.TextDocumentItem{
+		doc := protocol.TextDocumentItem{
 			Uri:     protocol.DocumentUri(uri),
 			Text:    "test",
 			Version: 1,
 		}
-		server.documents[uri] = doc
+		server.documents.Put(doc)
 
-		if _, exists := server.documents[uri]; !exists {
+		if _, exists := server.documents.Get(uri); !exists {
 			t.Error("Empty URI document should be stored")
 		}
 	})
@@ -609,14 +630,14 @@ func TestEdgeCases(t *testing.T) {
 	t.Run("LongDocumentText", func(t *testing.T) {
 		uri := "file:///long.go"
 		longText := strings.Repeat("a", 10000) // 10KB of text
-		doc := &protocol.TextDocumentItem{
+		doc := protocol.TextDocumentItem{
 			Uri:     protocol.DocumentUri(uri),
 			Text:    longText,
 			Version: 1,
 		}
-		server.documents[uri] = doc
+		server.documents.Put(doc)
 
-		if retrieved, exists := server.documents[uri]; exists {
+		if retrieved, exists := server.documents.Get(uri); exists {
 			if len(retrieved.Text) != 10000 {
 				t.Errorf("Expected text length 10000, got %d", len(retrieved.Text))
 			}
@@ -628,14 +649,14 @@ func TestEdgeCases(t *testing.T) {
 	// Test zero version
 	t.Run("ZeroVersion", func(t *testing.T) {
 		uri := "file:///zero.go"
-		doc := &protocol.TextDocumentItem{
+		doc := protocol.TextDocumentItem{
 			Uri:     protocol.DocumentUri(uri),
 			Text:    "test",
 			Version: 0,
 		}
-		server.documents[uri] = doc
+		server.documents.Put(doc)
 
-		if retrieved, exists := server.documents[uri]; exists {
+		if retrieved, exists := server.documents.Get(uri); exists {
 			if retrieved.Version != 0 {
 				t.Errorf("Expected version 0, got %d", retrieved.Version)
 			}
@@ -676,3 +697,84 @@ func TestJSONSerialization(t *testing.T) {
 		})
 	}
 }
+
+func TestUnopenedURIDiagnostics(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.DiagnosticsConfig.UnopenedURIs = []string{
+		"untitled:Untitled-1",
+		"git:/repo/main.go?ref=HEAD",
+		"file:///never/opened.go",
+	}
+	server.SetConfig(cfg)
+
+	published := make(chan string, len(cfg.LSP.DiagnosticsConfig.UnopenedURIs))
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		if req.Method != "textDocument/publishDiagnostics" {
+			return
+		}
+		var params protocol.PublishDiagnosticsParams
+		if err := json.Unmarshal(*req.Params, &params); err == nil {
+			published <- string(params.Uri)
+		}
+	})
+
+	if err := client.Notify(context.Background(), "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+
+	for _, expected := range cfg.LSP.DiagnosticsConfig.UnopenedURIs {
+		if uri := <-published; uri != expected {
+			t.Errorf("Expected diagnostics for %s, got %s", expected, uri)
+		}
+	}
+	if server.openDocumentCount() != 0 {
+		t.Errorf("Expected no open documents, got %d", server.openDocumentCount())
+	}
+}
+
+func TestReferencesIncludeDeclaration(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+	uri := protocol.DocumentUri("file:///refs.go")
+
+	var definition []protocol.Location
+	if err := client.Call(ctx, "textDocument/definition", protocol.DefinitionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: uri}}, &definition); err != nil {
+		t.Fatalf("Definition request failed: %v", err)
+	}
+
+	tests := []struct {
+		includeDeclaration bool
+		wantLocations      int
+	}{
+		{false, 2},
+		{true, 3},
+		{true, 3},
+	}
+	for _, tt := range tests {
+		params := protocol.ReferenceParams{Context: protocol.ReferenceContext{IncludeDeclaration: tt.includeDeclaration}}
+		params.TextDocument.Uri = uri
+
+		var locations []protocol.Location
+		if err := client.Call(ctx, "textDocument/references", params, &locations); err != nil {
+			t.Fatalf("References request failed: %v", err)
+		}
+		if len(locations) != tt.wantLocations {
+			t.Fatalf("Expected %d locations with includeDeclaration %v, got %d", tt.wantLocations, tt.includeDeclaration, len(locations))
+		}
+		declared := slices.Contains(locations, definition[0])
+		if declared != tt.includeDeclaration {
+			t.Errorf("Expected the declaration included %v, got %v in %+v", tt.includeDeclaration, declared, locations)
+		}
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if snapshot.ReferencesWithDeclaration != 2 || snapshot.ReferencesWithoutDeclaration != 1 {
+		t.Errorf("Expected 2 requests with and 1 without the declaration, got %d and %d",
+			snapshot.ReferencesWithDeclaration, snapshot.ReferencesWithoutDeclaration)
+	}
+}
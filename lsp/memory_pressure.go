@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// memoryRecoveryRatio is the share of the heap limit the heap has to shrink
+// below before degradation ends, so a heap hovering at the limit does not
+// flap in and out of it
+const memoryRecoveryRatio = 0.9
+
+// memoryPressureTrafficKeep is the number of recent traffic messages kept
+// when caches are trimmed
+const memoryPressureTrafficKeep = 20
+
+// heapBytes returns the bytes of allocated heap objects of the process
+func heapBytes() uint64 {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem.HeapAlloc
+}
+
+// startMemoryMonitor samples the heap in the background until the
+// connection closes, if a heap limit is configured
+func (s *MockLSPServer) startMemoryMonitor(ctx context.Context, conn Conn) {
+	pressure := s.config().LSP.MemoryPressure
+	if pressure.HeapLimitMB <= 0 {
+		return
+	}
+
+	s.logInfo(ctx, "Monitoring memory every %s, degrading above %d MB of heap", pressure.Interval, pressure.HeapLimitMB)
+	go s.runMemoryMonitor(context.WithoutCancel(ctx), conn, pressure.Interval.Duration())
+}
+
+// runMemoryMonitor checks the heap every interval
+func (s *MockLSPServer) runMemoryMonitor(ctx context.Context, conn Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-conn.DisconnectNotify():
+			return
+		}
+		s.checkMemory(ctx, conn, heapBytes())
+	}
+}
+
+// checkMemory enters degradation once heap exceeds the heap limit: caches
+// are trimmed and the client is warned through window/logMessage, so a
+// harness can tell degradation from a crash. Degradation ends once the heap
+// shrinks below memoryRecoveryRatio of the limit.
+func (s *MockLSPServer) checkMemory(ctx context.Context, conn Conn, heap uint64) {
+	pressure := s.config().LSP.MemoryPressure
+	limit := uint64(pressure.HeapLimitMB) << 20
+
+	s.mu.Lock()
+	degraded := s.degraded
+	switch {
+	case !degraded && heap > limit:
+		s.degraded = true
+	case degraded && float64(heap) < float64(limit)*memoryRecoveryRatio:
+		s.degraded = false
+	}
+	changed := degraded != s.degraded
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	message := protocol.LogMessageParams{Type: protocol.MessageTypeInfo, Message: s.message(msgMemoryRecovered, heap>>20)}
+	if !degraded {
+		s.stats.RecordMemoryPressure()
+		s.trimCaches()
+		s.logError(ctx, "Memory pressure: heap at %d bytes exceeds %d MB, degrading", heap, pressure.HeapLimitMB)
+		message = protocol.LogMessageParams{
+			Type:    protocol.MessageTypeWarning,
+			Message: s.message(msgMemoryPressure, heap>>20, pressure.HeapLimitMB, pressure.MaxResponseBytes),
+		}
+	} else {
+		s.logInfo(ctx, "Memory pressure over: heap at %d bytes", heap)
+	}
+	if err := s.notify(ctx, conn, "window/logMessage", message); err != nil {
+		s.logError(ctx, "Failed to send memory pressure message: %v", err)
+	}
+}
+
+// trimCaches drops what the server can do without: all but the most recent
+// traffic and soak reports, the semantic tokens kept for deltas and the
+// pulled diagnostics kept for unchanged reports, which are answered in full
+// instead. Documents are kept, as clients rely on them.
+func (s *MockLSPServer) trimCaches() {
+	s.traffic.trim(memoryPressureTrafficKeep)
+
+	s.mu.Lock()
+	if s.soak != nil && len(s.soak.reports) > soakLeakWindow+1 {
+		s.soak.reports = append([]SoakReport(nil), s.soak.reports[len(s.soak.reports)-soakLeakWindow-1:]...)
+	}
+	s.tokenResults = nil
+	s.diagnosticResults = nil
+	s.mu.Unlock()
+
+	debug.FreeOSMemory()
+}
+
+// refuseUnderPressure reports whether a response of size bytes to method
+// is refused because the server is degraded. Inspection requests are always
+// answered, so a harness can still find out what happened.
+func (s *MockLSPServer) refuseUnderPressure(method string, size int) bool {
+	s.mu.Lock()
+	degraded := s.degraded
+	s.mu.Unlock()
+	return degraded && size > s.config().LSP.MemoryPressure.MaxResponseBytes && !strings.HasPrefix(method, inspectionPrefix)
+}
@@ -0,0 +1,87 @@
+package lsp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestMemoryPressure(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LSP.RecentTraffic = 100
+	cfg.LSP.MemoryPressure.HeapLimitMB = 64
+	cfg.LSP.MemoryPressure.MaxResponseBytes = 100
+	server := createTestServer()
+	server.SetConfig(cfg)
+
+	// checkMemory needs the server side of the connection
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server, server.ConnOpts()...)
+	messages := make(chan string, 10)
+	client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+			if req.Method == "window/logMessage" {
+				messages <- string(*req.Params)
+			}
+			return nil, nil
+		}))
+	defer serverConn.Close()
+	defer client.Close()
+
+	completion := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///memory.txt"},
+		"position":     map[string]any{"line": 0, "character": 0},
+	}
+	if err := client.Call(ctx, "textDocument/completion", completion, nil); err != nil {
+		t.Fatalf("Completion failed before memory pressure: %v", err)
+	}
+	for range 30 {
+		if err := client.Call(ctx, "$/mockLsp/stats", nil, nil); err != nil {
+			t.Fatalf("Stats request failed: %v", err)
+		}
+	}
+
+	server.checkMemory(ctx, serverConn, 32<<20)
+	if len(messages) != 0 {
+		t.Fatalf("Expected no message below the limit, got %s", <-messages)
+	}
+
+	server.checkMemory(ctx, serverConn, 80<<20)
+	if got := <-messages; !strings.Contains(got, `"type":2`) || !strings.Contains(got, "memory pressure, heap at 80 MB of 64 MB") {
+		t.Errorf("Expected a memory pressure warning, got %s", got)
+	}
+	if got := len(server.RecentTraffic()); got > memoryPressureTrafficKeep+1 {
+		t.Errorf("Expected recent traffic trimmed to %d messages, got %d", memoryPressureTrafficKeep, got)
+	}
+
+	err := client.Call(ctx, "textDocument/completion", completion, nil)
+	if errorCode(err) != int64(protocol.LSPErrorCodesRequestFailed) || !strings.Contains(err.Error(), "refused under memory pressure") {
+		t.Errorf("Expected the completion to be refused, got %v", err)
+	}
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Errorf("Expected small responses to be answered, got %v", err)
+	}
+	var stats StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &stats); err != nil {
+		t.Fatalf("Expected inspection requests to be answered, got %v", err)
+	}
+	if stats.MemoryPressureEpisodes != 1 || stats.RefusedResponses != 1 {
+		t.Errorf("Expected 1 episode and 1 refused response, got %d and %d", stats.MemoryPressureEpisodes, stats.RefusedResponses)
+	}
+
+	// Still above the recovery threshold
+	server.checkMemory(ctx, serverConn, 60<<20)
+	if len(messages) != 0 {
+		t.Fatalf("Expected no message above the recovery threshold, got %s", <-messages)
+	}
+	server.checkMemory(ctx, serverConn, 40<<20)
+	if got := <-messages; !strings.Contains(got, `"type":3`) || !strings.Contains(got, "memory pressure over, heap at 40 MB") {
+		t.Errorf("Expected a recovery message, got %s", got)
+	}
+}
@@ -0,0 +1,221 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"mock-lsp-server/config"
+)
+
+// Keys of the server-produced messages in messageCatalog
+const (
+	msgCompletionFunctionDetail = "completion.function.detail"
+	msgCompletionFunctionDoc    = "completion.function.documentation"
+	msgCompletionVariableDetail = "completion.variable.detail"
+	msgCompletionVariableDoc    = "completion.variable.documentation"
+	msgCompletionClassDetail    = "completion.class.detail"
+	msgCompletionMemberDetail   = "completion.member.detail"
+	msgCompletionModuleDetail   = "completion.module.detail"
+	msgHoverContent             = "hover.content"
+	msgSymbolClassDetail        = "symbol.class.detail"
+	msgDiagnosticWarning        = "diagnostic.warning"
+	msgDiagnosticInfo           = "diagnostic.info"
+	msgDiagnosticDeprecated     = "diagnostic.deprecated"
+	msgSyncDivergence           = "sync.divergence"
+	msgLanguageMissing          = "language.missing"
+	msgLanguageMismatch         = "language.mismatch"
+	msgReadOnlyAction           = "readonly.action"
+	msgCodeActionQuickFix       = "codeaction.quickfix"
+	msgCodeActionExtract        = "codeaction.extract"
+	msgCodeActionInline         = "codeaction.inline"
+	msgCodeActionRewrite        = "codeaction.rewrite"
+	msgCodeActionOrganize       = "codeaction.organize_imports"
+	msgCodeActionFixAll         = "codeaction.fix_all"
+	msgRenameNoSymbol           = "rename.no_symbol"
+	msgRenameInvalidName        = "rename.invalid_name"
+	msgSignatureDoc             = "signature.documentation"
+	msgSignatureParameterDoc    = "signature.parameter.documentation"
+	msgInlayHintType            = "inlayhint.type"
+	msgInlayHintParameter       = "inlayhint.parameter"
+	msgMemoryPressure           = "memory.pressure"
+	msgMemoryRecovered          = "memory.recovered"
+	msgMemoryRefused            = "memory.refused"
+	msgLatencyBudget            = "latency.budget"
+	msgSettingsRejected         = "settings.rejected"
+)
+
+// messageCatalog holds the translations of every server-produced message.
+// The pseudo locale is derived from English by pseudoLocalize.
+var messageCatalog = map[string]map[string]string{
+	config.LocaleEnglish: {
+		msgCompletionFunctionDetail: "Mock function completion",
+		msgCompletionFunctionDoc:    "This is a mock function completion",
+		msgCompletionVariableDetail: "Mock variable completion",
+		msgCompletionVariableDoc:    "This is a mock variable",
+		msgCompletionClassDetail:    "Mock class completion",
+		msgCompletionMemberDetail:   "Mock member completion",
+		msgCompletionModuleDetail:   "Mock module completion",
+		msgHoverContent:             "**Mock Hover Information**\n\nThis is mock hover content for testing purposes.",
+		msgSymbolClassDetail:        "Mock class symbol",
+		msgDiagnosticWarning:        "This is a mock warning",
+		msgDiagnosticInfo:           "This is mock info",
+		msgDiagnosticDeprecated:     "%s is deprecated",
+		msgSyncDivergence:           "mock-lsp: buffer for %s diverged from saved text at %d:%d",
+		msgLanguageMissing:          "mock-lsp: %s was opened without a languageId",
+		msgLanguageMismatch:         "mock-lsp: %s was opened as %q, but its extension suggests %q",
+		msgReadOnlyAction:           "mock-lsp runs in read-only mode and makes no changes",
+		msgCodeActionQuickFix:       "Apply mock fix %d: %s",
+		msgCodeActionExtract:        "Extract to %s",
+		msgCodeActionInline:         "Inline as %s",
+		msgCodeActionRewrite:        "Rewrite as %s",
+		msgCodeActionOrganize:       "Organize imports (mock %d)",
+		msgCodeActionFixAll:         "Fix all mock diagnostics",
+		msgRenameNoSymbol:           "There is nothing to rename here",
+		msgRenameInvalidName:        "%q is not a valid name",
+		msgSignatureDoc:             "Mock overload %d of %d",
+		msgSignatureParameterDoc:    "The mock %s parameter",
+		msgInlayHintType:            "%s is inferred as %s",
+		msgInlayHintParameter:       "%s receives %s",
+		msgMemoryPressure:           "mock-lsp: memory pressure, heap at %d MB of %d MB; caches trimmed and responses over %d bytes refused",
+		msgMemoryRecovered:          "mock-lsp: memory pressure over, heap at %d MB",
+		msgMemoryRefused:            "mock-lsp: response of %d bytes refused under memory pressure",
+		msgLatencyBudget:            "mock-lsp: %s took %s, over its %s budget (%d times so far)",
+		msgSettingsRejected:         "mock-lsp: settings rejected, keeping the current configuration: %v",
+	},
+	config.LocaleGerman: {
+		msgCompletionFunctionDetail: "Mock-Funktionsvervollständigung",
+		msgCompletionFunctionDoc:    "Dies ist eine Mock-Funktionsvervollständigung",
+		msgCompletionVariableDetail: "Mock-Variablenvervollständigung",
+		msgCompletionVariableDoc:    "Dies ist eine Mock-Variable",
+		msgCompletionClassDetail:    "Mock-Klassenvervollständigung",
+		msgCompletionMemberDetail:   "Mock-Membervervollständigung",
+		msgCompletionModuleDetail:   "Mock-Modulvervollständigung",
+		msgHoverContent:             "**Mock-Hover-Informationen**\n\nDies ist Mock-Hover-Inhalt für Testzwecke.",
+		msgSymbolClassDetail:        "Mock-Klassensymbol",
+		msgDiagnosticWarning:        "Dies ist eine Mock-Warnung",
+		msgDiagnosticInfo:           "Dies ist eine Mock-Information",
+		msgDiagnosticDeprecated:     "%s ist veraltet",
+		msgSyncDivergence:           "mock-lsp: Der Puffer für %s weicht bei %d:%d vom gespeicherten Text ab",
+		msgLanguageMissing:          "mock-lsp: %s wurde ohne languageId geöffnet",
+		msgLanguageMismatch:         "mock-lsp: %s wurde als %q geöffnet, die Dateiendung deutet auf %q hin",
+		msgReadOnlyAction:           "mock-lsp läuft im Nur-Lese-Modus und nimmt keine Änderungen vor",
+		msgCodeActionQuickFix:       "Mock-Korrektur %d anwenden: %s",
+		msgCodeActionExtract:        "In %s extrahieren",
+		msgCodeActionInline:         "Als %s einbetten",
+		msgCodeActionRewrite:        "Als %s umschreiben",
+		msgCodeActionOrganize:       "Importe organisieren (Mock %d)",
+		msgCodeActionFixAll:         "Alle Mock-Diagnosen beheben",
+		msgRenameNoSymbol:           "Hier gibt es nichts umzubenennen",
+		msgRenameInvalidName:        "%q ist kein gültiger Name",
+		msgSignatureDoc:             "Mock-Überladung %d von %d",
+		msgSignatureParameterDoc:    "Der Mock-Parameter %s",
+		msgInlayHintType:            "%s wird als %s abgeleitet",
+		msgInlayHintParameter:       "%s erhält %s",
+		msgMemoryPressure:           "mock-lsp: Speicherdruck, Heap bei %d MB von %d MB; Caches gekürzt und Antworten über %d Bytes abgelehnt",
+		msgMemoryRecovered:          "mock-lsp: Speicherdruck vorbei, Heap bei %d MB",
+		msgMemoryRefused:            "mock-lsp: Antwort mit %d Bytes wegen Speicherdruck abgelehnt",
+		msgLatencyBudget:            "mock-lsp: %s dauerte %s und überschritt sein Budget von %s (bisher %d-mal)",
+		msgSettingsRejected:         "mock-lsp: Einstellungen abgelehnt, die aktuelle Konfiguration bleibt: %v",
+	},
+	config.LocaleJapanese: {
+		msgCompletionFunctionDetail: "モック関数の補完",
+		msgCompletionFunctionDoc:    "これはモック関数の補完です",
+		msgCompletionVariableDetail: "モック変数の補完",
+		msgCompletionVariableDoc:    "これはモック変数です",
+		msgCompletionClassDetail:    "モッククラスの補完",
+		msgCompletionMemberDetail:   "モックメンバーの補完",
+		msgCompletionModuleDetail:   "モックモジュールの補完",
+		msgHoverContent:             "**モックのホバー情報**\n\nこれはテスト用のモックホバーコンテンツです。",
+		msgSymbolClassDetail:        "モッククラスのシンボル",
+		msgDiagnosticWarning:        "これはモックの警告です",
+		msgDiagnosticInfo:           "これはモックの情報です",
+		msgDiagnosticDeprecated:     "%s は非推奨です",
+		msgSyncDivergence:           "mock-lsp: %s のバッファが %d:%d で保存済みテキストと一致しません",
+		msgLanguageMissing:          "mock-lsp: %s が languageId なしで開かれました",
+		msgLanguageMismatch:         "mock-lsp: %s は %q として開かれましたが、拡張子からは %q が想定されます",
+		msgReadOnlyAction:           "mock-lsp は読み取り専用モードで動作しており、変更を行いません",
+		msgCodeActionQuickFix:       "モック修正 %d を適用: %s",
+		msgCodeActionExtract:        "%s に抽出",
+		msgCodeActionInline:         "%s としてインライン化",
+		msgCodeActionRewrite:        "%s として書き換え",
+		msgCodeActionOrganize:       "インポートを整理 (モック %d)",
+		msgCodeActionFixAll:         "すべてのモック診断を修正",
+		msgRenameNoSymbol:           "ここには名前を変更できるものがありません",
+		msgRenameInvalidName:        "%q は有効な名前ではありません",
+		msgSignatureDoc:             "モックのオーバーロード %d / %d",
+		msgSignatureParameterDoc:    "モックの %s パラメーター",
+		msgInlayHintType:            "%s は %s と推論されます",
+		msgInlayHintParameter:       "%s に %s が渡されます",
+		msgMemoryPressure:           "mock-lsp: メモリ逼迫、ヒープ %d MB / %d MB。キャッシュを削減し、%d バイトを超える応答を拒否します",
+		msgMemoryRecovered:          "mock-lsp: メモリ逼迫が解消されました。ヒープ %d MB",
+		msgMemoryRefused:            "mock-lsp: メモリ逼迫のため %d バイトの応答を拒否しました",
+		msgLatencyBudget:            "mock-lsp: %s に %s かかり、予算 %s を超えました (これまでに %d 回)",
+		msgSettingsRejected:         "mock-lsp: 設定を拒否しました。現在の構成を維持します: %v",
+	},
+}
+
+// pseudoReplacements maps ASCII letters to accented look-alikes
+var pseudoReplacements = map[rune]rune{
+	'a': 'á', 'c': 'ç', 'e': 'é', 'i': 'í', 'n': 'ñ', 'o': 'ö', 's': 'š', 'u': 'ü', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'C': 'Ç', 'E': 'É', 'I': 'Î', 'N': 'Ñ', 'O': 'Ö', 'S': 'Š', 'U': 'Û', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+// pseudoLocalize accents letters, pads the text by roughly a third and wraps
+// it in brackets, which exposes hard-coded strings, truncation and encoding
+// problems while staying readable. Format verbs are left untouched.
+func pseudoLocalize(text string) string {
+	var b strings.Builder
+	b.WriteString("[")
+	verb := false
+	for _, r := range text {
+		switch {
+		case verb:
+			verb = false
+		case r == '%':
+			verb = true
+		default:
+			if replacement, ok := pseudoReplacements[r]; ok {
+				r = replacement
+			}
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(" ")
+	b.WriteString(strings.Repeat("·", len([]rune(text))/3))
+	b.WriteString("]")
+	return b.String()
+}
+
+// resolveLocale maps a requested locale onto a catalog locale, falling back
+// from a regional variant to its language and finally to English
+func resolveLocale(locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if locale == config.LocalePseudo {
+		return locale
+	}
+	if _, ok := messageCatalog[locale]; ok {
+		return locale
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	if _, ok := messageCatalog[language]; ok {
+		return language
+	}
+	return config.LocaleEnglish
+}
+
+// localize returns the message for key in the given locale, formatted with args
+func localize(locale, key string, args ...interface{}) string {
+	locale = resolveLocale(locale)
+
+	var template string
+	if locale == config.LocalePseudo {
+		template = pseudoLocalize(messageCatalog[config.LocaleEnglish][key])
+	} else {
+		template = messageCatalog[locale][key]
+	}
+
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestResolveLocale(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		expected string
+	}{
+		{"", config.LocaleEnglish},
+		{"en-US", config.LocaleEnglish},
+		{"de", config.LocaleGerman},
+		{"de_AT", config.LocaleGerman},
+		{"JA-jp", config.LocaleJapanese},
+		{"qps-ploc", config.LocalePseudo},
+		{"fr", config.LocaleEnglish},
+	}
+
+	for _, tc := range testCases {
+		if got := resolveLocale(tc.locale); got != tc.expected {
+			t.Errorf("Expected locale %q for %q, got %q", tc.expected, tc.locale, got)
+		}
+	}
+}
+
+func TestMessageCatalogComplete(t *testing.T) {
+	for key := range messageCatalog[config.LocaleEnglish] {
+		for locale, messages := range messageCatalog {
+			if messages[key] == "" {
+				t.Errorf("Expected locale %s to translate %s", locale, key)
+			}
+		}
+	}
+}
+
+func TestPseudoLocalize(t *testing.T) {
+	got := localize(config.LocalePseudo, msgSyncDivergence, "file:///a.go", 3, 7)
+	if !strings.HasPrefix(got, "[") || !strings.HasSuffix(got, "]") {
+		t.Errorf("Expected pseudo-localized text to be bracketed, got %q", got)
+	}
+	if !strings.Contains(got, "file:///a.go") || !strings.Contains(got, "3:7") {
+		t.Errorf("Expected format verbs to survive pseudo-localization, got %q", got)
+	}
+	if got == localize(config.LocaleEnglish, msgSyncDivergence, "file:///a.go", 3, 7) {
+		t.Error("Expected pseudo-localized text to differ from English")
+	}
+}
+
+func TestLocalizedHover(t *testing.T) {
+	testCases := []struct {
+		name         string
+		configured   string
+		clientLocale string
+		expected     string
+	}{
+		{"default", "", "", config.LocaleEnglish},
+		{"client locale", "", "ja-JP", config.LocaleJapanese},
+		{"configured wins", config.LocaleGerman, "ja", config.LocaleGerman},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.Locale = tc.configured
+			server.SetConfig(cfg)
+
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			var initResult protocol.InitializeResult
+			if err := client.Call(ctx, "initialize", protocol.InitializeParams{Locale: tc.clientLocale}, &initResult); err != nil {
+				t.Fatalf("Initialize request failed: %v", err)
+			}
+
+			var hover protocol.Hover
+			params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///test.go"}}
+			if err := client.Call(ctx, "textDocument/hover", params, &hover); err != nil {
+				t.Fatalf("Hover request failed: %v", err)
+			}
+
+			markup, ok := hover.Contents.Value.(protocol.MarkupContent)
+			if !ok {
+				t.Fatalf("Expected MarkupContent, got %T", hover.Contents.Value)
+			}
+			if expected := localize(tc.expected, msgHoverContent); markup.Value != expected {
+				t.Errorf("Expected hover %q, got %q", expected, markup.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// minimalMethods are the only LSP methods served in minimal mode: the
+// lifecycle, cancellation and text document synchronization
+var minimalMethods = []string{
+	"initialize",
+	"initialized",
+	"shutdown",
+	"exit",
+	cancelRequestMethod,
+	"textDocument/didOpen",
+	"textDocument/didChange",
+	"textDocument/didSave",
+	"textDocument/didClose",
+}
+
+// inspectionPrefix marks the custom requests test harnesses use to inspect
+// the server. They stay available in minimal mode.
+const inspectionPrefix = "$/mockLsp/"
+
+// minimal reports whether the server simulates a server supporting nothing
+// but the lifecycle and text synchronization
+func (s *MockLSPServer) minimal() bool {
+	return s.config().LSP.Minimal
+}
+
+// rejectOutsideMinimal answers requests for methods minimal mode does not
+// support with MethodNotFound and drops such notifications. It returns true
+// if the message was handled.
+func (s *MockLSPServer) rejectOutsideMinimal(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	if !s.minimal() || slices.Contains(minimalMethods, req.Method) || strings.HasPrefix(req.Method, inspectionPrefix) {
+		return false
+	}
+
+	if req.Notif {
+		s.logInfo(ctx, "Ignoring %s notification in minimal mode", req.Method)
+		return true
+	}
+	s.logInfo(ctx, "Rejecting %s in minimal mode", req.Method)
+	s.replyMethodNotFound(ctx, conn, req)
+	return true
+}
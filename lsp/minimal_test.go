@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestMinimalMode(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Minimal = true
+	cfg.LSP.DiagnosticsConfig.UnopenedURIs = []string{"untitled:Untitled-1"}
+	server.SetConfig(cfg)
+
+	var received []string
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		received = append(received, req.Method)
+	})
+	ctx := context.Background()
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	capabilities, err := encodeWire(initResult.Capabilities)
+	if err != nil {
+		t.Fatalf("Failed to encode capabilities: %v", err)
+	}
+	if names := capabilityNames(initResult.Capabilities); len(names) != 1 || names[0] != "textDocumentSync" {
+		t.Errorf("Expected only textDocumentSync, got %s", capabilities)
+	}
+
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+	doc := protocol.TextDocumentItem{Uri: "file:///minimal.go", LanguageId: "go", Text: "package main\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	if err := client.Notify(ctx, "workspace/didChangeConfiguration", map[string]any{"settings": nil}); err != nil {
+		t.Fatalf("didChangeConfiguration failed: %v", err)
+	}
+
+	unsupported := []string{"textDocument/completion", "textDocument/hover", "textDocument/definition", "textDocument/documentSymbol"}
+	for _, method := range unsupported {
+		params := protocol.TextDocumentPositionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: doc.Uri}}
+		err := client.Call(ctx, method, params, nil)
+		var rpcErr *jsonrpc.Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != int64(ErrorCodeMethodNotFound) {
+			t.Errorf("Expected MethodNotFound for %s, got %v", method, err)
+		}
+	}
+
+	// Inspection requests keep working
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if snapshot.OpenDocuments != 1 {
+		t.Errorf("Expected the opened document to be synchronized, got %d open", snapshot.OpenDocuments)
+	}
+	for _, method := range received {
+		if method == "textDocument/publishDiagnostics" {
+			t.Error("Expected no diagnostics in minimal mode")
+		}
+	}
+
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
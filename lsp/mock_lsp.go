@@ -3,72 +3,476 @@ package lsp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
-	"os"
-	"reflect"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/myleshyson/lsprotocol-go/protocol"
-	"github.com/sourcegraph/jsonrpc2"
+	"mock-lsp-server/config"
+	"mock-lsp-server/documentstore"
+	"mock-lsp-server/jsonrpc"
 	"mock-lsp-server/logging"
+	"mock-lsp-server/recording"
+	"mock-lsp-server/scenario"
 )
 
 // MockLSPServer implements the LSP server handlers
 type MockLSPServer struct {
-	errorHandler *ErrorHandler
-	documents        map[string]*protocol.TextDocumentItem
-	logger           *log.Logger
-	structuredLogger *logging.StructuredLogger
-	mu               sync.Mutex // Added mutex for protecting documents map
+	errorHandler        *ErrorHandler
+	documents           *documentstore.Store
+	logger              *log.Logger
+	structuredLogger    *logging.StructuredLogger
+	clientID            string
+	clientLocale        string
+	clientName          string
+	clientVersion       string
+	client              *ClientFingerprint
+	folding             *protocol.FoldingRangeClientCapabilities // Folding range capabilities of the client
+	initOptions         *InitializationOptionsResult
+	audit               *AuditLog
+	stats               *Stats
+	current             atomic.Pointer[configSnapshot] // Configuration in effect, replaced by SetConfig
+	reconfigure         sync.Mutex                     // Serializes SetConfig
+	traffic             *trafficRecorder
+	invariants          *invariantChecker // Repairs randomized and edge-case payloads
+	state               ServerState
+	hooks               hooks
+	disabledFeatures    map[string]bool    // Features toggled at runtime, true when switched off
+	dynamic             map[string]bool    // Features registered dynamically with the client
+	registered          map[string]bool    // Dynamic features currently registered
+	registrations       *registrationQueue // Registration requests of the connection waiting to be sent
+	scenario            *scenario.Scenario
+	outbound            *notificationQueue
+	inflight            map[jsonrpc.ID]*inflightRequest
+	pendingCancels      []jsonrpc.ID        // $/cancelRequest IDs not in flight yet, oldest first
+	requestIDs          *requestIDTracker   // Recent request IDs of the connection
+	ids                 IDGenerator         // IDs of server-initiated requests
+	outstanding         map[jsonrpc.ID]bool // IDs of server-initiated requests awaiting a response
+	soak                *soakMonitor
+	indexing            bool                            // Simulated workspace indexing running
+	indexedPercent      int                             // Percent of the workspace symbols indexed while indexing runs
+	degraded            bool                            // Heap above the memory pressure limit
+	tokenResults        map[string]semanticTokensResult // Last full semantic tokens by URI
+	tokenResultSeq      int
+	diagnosticResults   map[string]diagnosticResult // Last pulled diagnostics by URI
+	diagnosticResultSeq int
+	unresolved          map[string]unresolvedCodeAction // Code actions awaiting codeAction/resolve by data ID
+	unresolvedOrder     []string                        // IDs of unresolved, oldest first
+	codeActionSeq       int
+	capture             *fixtureCapture
+	junitPath           string // JUnit report written when the session ends, empty writes none
+	protocolVersion     string
+	rootURI             string               // First workspace folder or root URI of the client
+	expectationFails    []ExpectationFailure // Responses that differed from the scenario expectations
+	store               recording.Store
+	session             string // ID of the recorded session, unique across runs
+	statsRecorded       bool
+	debounced           map[string]*time.Timer // Debounced publications by URI
+	rulesURL            string                 // Base URL of the rule pages, empty when not served
+	dataset             *MockDataset           // Cached mock data, nil generates it per request
+	done                chan struct{}          // Closed by the exit notification
+	exitStatus          int                    // Exit code chosen by the exit notification
+	exited              bool                   // Set by the first exit notification
+	mu                  sync.Mutex             // Protects the session state above
 }
 
 // NewMockLSPServer creates a new mock LSP server instance
 func NewMockLSPServer(logger *log.Logger) *MockLSPServer {
+	clientID := nextClientID()
 	server := &MockLSPServer{
-		documents: make(map[string]*protocol.TextDocumentItem),
-		logger:    logger,
+		documents:  documentstore.New(),
+		logger:     logger,
+		clientID:   clientID,
+		stats:      NewStats(clientID),
+		traffic:    newTrafficRecorder(0),
+		invariants: newInvariantChecker(),
+		state:      StateUninitialized,
+		done:       make(chan struct{}),
 		// mu is implicitly initialized to its zero value (unlocked)
 	}
 	server.errorHandler = NewErrorHandler(server)
+	server.documents.Subscribe(server.forgetClosedDocument)
+	server.SetConfig(config.DefaultConfig())
 	return server
 }
 
 // NewMockLSPServerWithStructuredLogger creates a new mock LSP server with structured logging
 func NewMockLSPServerWithStructuredLogger(structuredLogger *logging.StructuredLogger, fallbackLogger *log.Logger) *MockLSPServer {
+	clientID := nextClientID()
 	server := &MockLSPServer{
-		documents:        make(map[string]*protocol.TextDocumentItem),
-		logger:           fallbackLogger,
-		structuredLogger: structuredLogger,
+		documents:  documentstore.New(),
+		logger:     fallbackLogger,
+		clientID:   clientID,
+		stats:      NewStats(clientID),
+		traffic:    newTrafficRecorder(0),
+		invariants: newInvariantChecker(),
+		state:      StateUninitialized,
+		done:       make(chan struct{}),
 		// mu is implicitly initialized to its zero value (unlocked)
 	}
+	if structuredLogger != nil {
+		server.structuredLogger = structuredLogger.WithContext("client_id", clientID)
+	}
 	server.errorHandler = NewErrorHandler(server)
+	server.documents.Subscribe(server.forgetClosedDocument)
+	server.SetConfig(config.DefaultConfig())
 	return server
 }
 
-// logInfo logs an info message using structured logger if available, otherwise fallback
-func (s *MockLSPServer) logInfo(format string, args ...interface{}) {
+// logInfo logs an info message using structured logger if available, otherwise fallback.
+// The log fields carried by ctx are added to the message.
+func (s *MockLSPServer) logInfo(ctx context.Context, format string, args ...interface{}) {
 	if s.structuredLogger != nil {
-		s.structuredLogger.Info(format, args...)
+		s.structuredLogger.ForContext(ctx).Info(format, args...)
 	} else {
-		s.logger.Printf(format, args...)
+		s.logger.Printf("[%s] "+format+contextSuffix(ctx), append([]interface{}{s.clientID}, args...)...)
 	}
 }
 
-// logError logs an error message using structured logger if available, otherwise fallback
-func (s *MockLSPServer) logError(format string, args ...interface{}) {
+// logDebug logs a debug message using structured logger if available, otherwise fallback.
+// The log fields carried by ctx are added to the message.
+func (s *MockLSPServer) logDebug(ctx context.Context, format string, args ...interface{}) {
 	if s.structuredLogger != nil {
-		s.structuredLogger.Error(format, args...)
+		s.structuredLogger.ForContext(ctx).Debug(format, args...)
+	} else {
+		s.logger.Printf("[%s] DEBUG: "+format+contextSuffix(ctx), append([]interface{}{s.clientID}, args...)...)
+	}
+}
+
+// logError logs an error message using structured logger if available, otherwise fallback.
+// The log fields carried by ctx are added to the message.
+func (s *MockLSPServer) logError(ctx context.Context, format string, args ...interface{}) {
+	if s.structuredLogger != nil {
+		s.structuredLogger.ForContext(ctx).Error(format, args...)
+	} else {
+		s.logger.Printf("[%s] ERROR: "+format+contextSuffix(ctx), append([]interface{}{s.clientID}, args...)...)
+	}
+}
+
+// contextSuffix formats the log fields carried by ctx for the fallback
+// logger, sorted by key, or returns an empty string if there are none
+func contextSuffix(ctx context.Context) string {
+	fields := logging.FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(fields))
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		pairs = append(pairs, strings.ReplaceAll(fmt.Sprintf("%s=%v", key, fields[key]), "%", "%%"))
+	}
+	return " [" + strings.Join(pairs, " ") + "]"
+}
+
+// configSnapshot is the configuration in effect with the state derived from
+// it. SetConfig publishes a new snapshot as a whole, so handlers running
+// while the configuration changes see either the old or the new one.
+type configSnapshot struct {
+	config   *config.ServerConfig
+	traceID  string
+	random   RandomSource
+	latency  *LatencyInjector
+	redactor *redactor    // Redacts recorded traffic and logged payloads, nil keeps them
+	failures dispatchFunc // route wrapped in the middleware of the failure preset
+}
+
+// snapshot returns the configuration in effect
+func (s *MockLSPServer) snapshot() *configSnapshot {
+	return s.current.Load()
+}
+
+// config returns the server configuration in effect
+func (s *MockLSPServer) config() *config.ServerConfig {
+	return s.current.Load().config
+}
+
+// SetConfig applies a server configuration to this instance. It may be
+// called while the server is handling requests, as client settings are.
+func (s *MockLSPServer) SetConfig(cfg *config.ServerConfig) {
+	s.reconfigure.Lock()
+	defer s.reconfigure.Unlock()
+	previous := s.current.Load()
+
+	next := &configSnapshot{
+		config:   cfg,
+		traceID:  cfg.LSP.TraceMetadata.TraceID,
+		redactor: newRedactor(cfg.LSP.Redaction),
+	}
+	if next.traceID == "" {
+		next.traceID = randomHex(16)
+	}
+	// Reconfiguring keeps the random sequence going, and with it the seed
+	// of a dataset, unless the seed itself changed
+	if previous != nil && previous.config.LSP.MockData.Seed == cfg.LSP.MockData.Seed {
+		next.random = previous.random
 	} else {
-		s.logger.Printf("ERROR: "+format, args...)
+		next.random = NewSeededRandomSource(cfg.LSP.MockData.Seed)
+	}
+	next.latency = NewLatencyInjector(cfg.LSP.Latency, next.random)
+	next.failures = composeFailures(s.route, s.failureMiddlewares(cfg.LSP.FailurePreset))
+
+	s.stats.SetSLOs(cfg.LSP.Latency.SLOs)
+	s.traffic.resize(cfg.LSP.RecentTraffic)
+	s.traffic.setRedactor(next.redactor)
+	// Reconfiguring the running server keeps the ID sequence going, so IDs
+	// of requests still awaiting a response are not handed out again
+	if previous == nil || previous.config.LSP.RequestIDs != cfg.LSP.RequestIDs {
+		s.SetIDGenerator(NewIDGenerator(cfg.LSP.RequestIDs))
+	}
+	s.current.Store(next)
+}
+
+// SetRandomSource replaces the source of randomness used for latency
+// sampling and randomized responses
+func (s *MockLSPServer) SetRandomSource(src RandomSource) {
+	s.reconfigure.Lock()
+	defer s.reconfigure.Unlock()
+	next := *s.current.Load()
+	next.random = src
+	next.latency = NewLatencyInjector(next.config.LSP.Latency, src)
+	s.current.Store(&next)
+}
+
+// randomResponses returns a randomized response generator for the document,
+// or nil when randomized responses are disabled
+func (s *MockLSPServer) randomResponses(uri string) *randomResponder {
+	snapshot := s.snapshot()
+	mockData := snapshot.config.LSP.MockData
+	if !mockData.Randomize {
+		return nil
+	}
+
+	maxLines := 100
+	if text, ok := s.documentText(uri); ok {
+		maxLines = strings.Count(text, "\n") + 1
+	}
+
+	maxItems := mockData.ItemCount
+	if limit := snapshot.config.LSP.CompletionConfig.MaxItems; limit > 0 && limit < maxItems {
+		maxItems = limit
 	}
+
+	return &randomResponder{
+		src:      snapshot.random,
+		maxItems: maxItems,
+		maxLines: maxLines,
+		prefixes: mockData.CustomPrefixes,
+	}
+}
+
+// unicodeTorture returns the Unicode torture generator for the configured
+// categories, or nil when none are enabled
+func (s *MockLSPServer) unicodeTorture() *unicodeTorturer {
+	return newUnicodeTorturer(s.config().LSP.MockData.Unicode)
+}
+
+// locale returns the locale server-produced messages are served in: the
+// configured locale, else the one the client sent in initialize, else English
+func (s *MockLSPServer) locale() string {
+	if s.config().LSP.Locale != "" {
+		return s.config().LSP.Locale
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clientLocale != "" {
+		return s.clientLocale
+	}
+	return config.LocaleEnglish
+}
+
+// message returns the localized server-produced message for key
+func (s *MockLSPServer) message(key string, args ...interface{}) string {
+	return localize(s.locale(), key, args...)
+}
+
+// documentText returns the current text of an open document
+func (s *MockLSPServer) documentText(uri string) (string, bool) {
+	doc, ok := s.documents.Get(uri)
+	return doc.Text, ok
+}
+
+// replyWithPreset answers the request with the edge-case preset configured
+// for its method. It returns false when no preset applies.
+func (s *MockLSPServer) replyWithPreset(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	preset, ok := s.config().LSP.Presets[req.Method]
+	if !ok {
+		return false
+	}
+
+	var params presetParams
+	if req.Params != nil {
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			return false
+		}
+	}
+
+	result, ok := presetResponse(preset, req.Method, params, s.config().LSP.PresetReverseRanges)
+	if !ok {
+		return false
+	}
+
+	s.logInfo(ctx, "Answering %s with preset %s", req.Method, preset)
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send %s preset response: %v", preset, err)
+	}
+	return true
 }
 
+// ConnOpts returns the options a connection serving this instance must be
+// created with so its wire messages are kept in the recent traffic buffer
+func (s *MockLSPServer) ConnOpts() []jsonrpc.ConnOpt {
+	return s.traffic.connOpts()
+}
 
+// RecentTraffic returns the latest wire messages, oldest first
+func (s *MockLSPServer) RecentTraffic() []TrafficMessage {
+	return s.traffic.snapshot()
+}
 
+// ClientID returns the identifier assigned to the client served by this instance
+func (s *MockLSPServer) ClientID() string {
+	return s.clientID
+}
 
+// openDocumentCount returns the number of documents currently open
+func (s *MockLSPServer) openDocumentCount() int {
+	return s.documents.Len()
+}
+
+// reply sends a result for the given request using the wire encoder,
+// injecting trace metadata when enabled. Requests cancelled while they were
+// handled answer RequestCancelled instead of the late result, and under
+// memory pressure large results are refused.
+func (s *MockLSPServer) reply(ctx context.Context, conn Replier, req *jsonrpc.Request, result any) error {
+	if err := ctx.Err(); err != nil {
+		s.logInfo(ctx, "Dropping the result of cancelled %s", req.Method)
+		s.replyCancelled(ctx, conn, req, err)
+		return nil
+	}
+	data, err := encodeWireWithExtra(s.downgradeResult(result), s.traceExtra(ctx, req.Method))
+	if err != nil {
+		return err
+	}
+	data = s.enforceInvariants(ctx, req.Method, data)
+	if s.refuseUnderPressure(req.Method, len(data)) {
+		s.stats.RecordRefusedResponse()
+		s.logError(ctx, "Refusing %s response of %d bytes under memory pressure", req.Method, len(data))
+		return conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    int64(protocol.LSPErrorCodesRequestFailed),
+			Message: s.message(msgMemoryRefused, len(data)),
+		})
+	}
+	s.stats.RecordResponseSize(req.Method, len(data))
+	s.logPayload(ctx, "Sending %s result: %s", req.Method, data)
+	s.checkExpectations(ctx, req, data)
+	return conn.Reply(ctx, req.ID, data)
+}
+
+// notify sends a notification to the client using the wire encoder,
+// injecting trace metadata when enabled. Diagnostics and progress go through
+// the notification queue when it is enabled.
+func (s *MockLSPServer) notify(ctx context.Context, conn Conn, method string, params any) error {
+	data, err := encodeWireWithExtra(params, s.traceExtra(ctx, method))
+	if err != nil {
+		return err
+	}
+	data = s.enforceInvariants(ctx, method, data)
+	s.logPayload(ctx, "Sending %s params: %s", method, data)
+	return s.sendNotification(ctx, conn, method, notificationKey(params), data)
+}
+
+// Handle processes incoming JSON-RPC requests, implementing jsonrpc.Handler
+func (s *MockLSPServer) Handle(ctx context.Context, conn jsonrpc.Conn, req *jsonrpc.Request) {
+	s.Dispatch(ctx, conn, req)
+}
+
+// Dispatch processes a request or notification, sending the response and
+// anything else it causes through conn
+func (s *MockLSPServer) Dispatch(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	ctx = logging.ContextWithField(ctx, "method", req.Method)
+	if !req.Notif {
+		ctx = logging.ContextWithField(ctx, "request_id", req.ID.String())
+	}
+	s.captureMessage(req)
+	if req.Params != nil {
+		s.logPayload(ctx, "Received %s params: %s", req.Method, *req.Params)
+	}
+	s.checkSchema(ctx, req)
+
+	if req.Notif {
+		s.stats.RecordNotification(req.Method)
+		emit(&s.hooks, &s.hooks.notifications, NotificationEvent{Method: req.Method, Params: req.Params})
+	} else {
+		s.stats.RecordRequest(req.Method)
+		emit(&s.hooks, &s.hooks.requests, RequestEvent{ID: req.ID.String(), Method: req.Method, Params: req.Params})
+		conn = errorCountingConn{Conn: conn, stats: s.stats, method: req.Method}
+		if !s.checkRequestID(ctx, conn, req) {
+			return
+		}
+		// The budget in effect when the request arrived applies, even if
+		// the configuration changes while it is handled
+		budget, hasBudget := s.config().LSP.Latency.Budgets[req.Method]
+		start := time.Now()
+		defer func(ctx context.Context) {
+			elapsed := time.Since(start)
+			s.stats.RecordLatency(req.Method, elapsed)
+			if hasBudget {
+				s.checkLatencyBudget(ctx, conn, req.Method, elapsed, budget.Duration())
+			}
+		}(ctx)
+
+		var done func()
+		ctx, done = s.trackRequest(ctx, req)
+		defer done()
+
+		if err := s.snapshot().latency.Delay(ctx, req.Method); err != nil {
+			s.logError(ctx, "Latency injection for %s interrupted: %v", req.Method, err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			s.replyCancelled(ctx, conn, req, err)
+			return
+		}
+	}
+
+	s.snapshot().failures(ctx, conn, req)
+}
+
+// route answers a request or notification with the handler of its method,
+// unless the server state, configuration or a scenario says otherwise
+func (s *MockLSPServer) route(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if s.rejectAfterShutdown(ctx, conn, req) {
+		return
+	}
+
+	if s.rejectOutsideMinimal(ctx, conn, req) {
+		return
+	}
+
+	if s.rejectNewerMethod(ctx, conn, req) {
+		return
+	}
+
+	if s.rejectDisabledFeature(ctx, conn, req) {
+		return
+	}
+
+	if s.rejectDisallowedScheme(ctx, conn, req) {
+		return
+	}
+
+	if !req.Notif && s.replyWithScenario(ctx, conn, req) {
+		return
+	}
+
+	if !req.Notif && s.replyWithPreset(ctx, conn, req) {
+		return
+	}
 
-// Handle processes incoming JSON-RPC requests
-func (s *MockLSPServer) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
 	switch req.Method {
 	case "initialize":
 		s.handleInitialize(ctx, conn, req)
@@ -82,6 +486,20 @@ func (s *MockLSPServer) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *js
 		s.handleTextDocumentDidSave(ctx, conn, req)
 	case "textDocument/didClose":
 		s.handleTextDocumentDidClose(ctx, conn, req)
+	case "workspace/didChangeWatchedFiles":
+		s.handleDidChangeWatchedFiles(ctx, conn, req)
+	case "workspace/didChangeConfiguration":
+		s.handleDidChangeConfiguration(ctx, conn, req)
+	case cancelRequestMethod:
+		s.handleCancelRequest(ctx, conn, req)
+	case "workspace/willCreateFiles":
+		s.handleWillCreateFiles(ctx, conn, req)
+	case "workspace/willRenameFiles":
+		s.handleWillRenameFiles(ctx, conn, req)
+	case "workspace/willDeleteFiles":
+		s.handleWillDeleteFiles(ctx, conn, req)
+	case "workspace/didCreateFiles", "workspace/didRenameFiles", "workspace/didDeleteFiles":
+		s.handleDidFileOperation(ctx, conn, req)
 	case "textDocument/completion":
 		s.handleCompletion(ctx, conn, req)
 	case "textDocument/hover":
@@ -92,182 +510,439 @@ func (s *MockLSPServer) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *js
 		s.handleReferences(ctx, conn, req)
 	case "textDocument/documentSymbol":
 		s.handleDocumentSymbol(ctx, conn, req)
+	case "workspace/symbol":
+		s.handleWorkspaceSymbol(ctx, conn, req)
+	case "textDocument/diagnostic":
+		s.handleDocumentDiagnostic(ctx, conn, req)
+	case "workspace/diagnostic":
+		s.handleWorkspaceDiagnostic(ctx, conn, req)
+	case "textDocument/codeAction":
+		s.handleCodeAction(ctx, conn, req)
+	case "workspace/executeCommand":
+		s.handleExecuteCommand(ctx, conn, req)
+	case "codeAction/resolve":
+		s.handleCodeActionResolve(ctx, conn, req)
+	case "textDocument/formatting":
+		s.handleFormatting(ctx, conn, req)
+	case "textDocument/rangeFormatting":
+		s.handleRangeFormatting(ctx, conn, req)
+	case "textDocument/prepareRename":
+		s.handlePrepareRename(ctx, conn, req)
+	case "textDocument/rename":
+		s.handleRename(ctx, conn, req)
+	case "textDocument/signatureHelp":
+		s.handleSignatureHelp(ctx, conn, req)
+	case "textDocument/semanticTokens/full":
+		s.handleSemanticTokensFull(ctx, conn, req)
+	case "textDocument/semanticTokens/full/delta":
+		s.handleSemanticTokensDelta(ctx, conn, req)
+	case "textDocument/semanticTokens/range":
+		s.handleSemanticTokensRange(ctx, conn, req)
+	case "textDocument/inlayHint":
+		s.handleInlayHint(ctx, conn, req)
+	case "inlayHint/resolve":
+		s.handleInlayHintResolve(ctx, conn, req)
+	case "textDocument/foldingRange":
+		s.handleFoldingRange(ctx, conn, req)
+	case "textDocument/selectionRange":
+		s.handleSelectionRange(ctx, conn, req)
+	case "textDocument/linkedEditingRange":
+		s.handleLinkedEditingRange(ctx, conn, req)
+	case "textDocument/inlineCompletion":
+		s.handleInlineCompletion(ctx, conn, req)
 	case "shutdown":
 		s.handleShutdown(ctx, conn, req)
 	case "exit":
 		s.handleExit(ctx, conn, req)
+	case "$/mockLsp/stats":
+		s.handleStats(ctx, conn, req)
+	case "$/mockLsp/documentHash":
+		s.handleDocumentHash(ctx, conn, req)
+	case "$/mockLsp/recentTraffic":
+		s.handleRecentTraffic(ctx, conn, req)
+	case "$/mockLsp/initializationOptions":
+		s.handleInitializationOptions(ctx, conn, req)
+	case "$/mockLsp/setFeatures":
+		s.handleSetFeatures(ctx, conn, req)
+	case "$/mockLsp/soak":
+		s.handleSoak(ctx, conn, req)
 	default:
-		// Create structured error for unsupported method
-		lspErr := NewMethodNotFoundError(req.Method)
-		if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
-			// Handle reply error with context
-			replyErr := s.errorHandler.WrapError(err, ErrorCodeInternalError, "Failed to send method not found error", map[string]interface{}{
-				"method":     req.Method,
-				"request_id": req.ID,
-			})
-			s.errorHandler.HandleError(replyErr, "handle_unsupported_method")
-		}
+		s.replyMethodNotFound(ctx, conn, req)
+	}
+}
+
+// replyMethodNotFound answers a request for a method the server does not
+// support
+func (s *MockLSPServer) replyMethodNotFound(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	// Create structured error for unsupported method
+	lspErr := NewMethodNotFoundError(req.Method)
+	if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+		// Handle reply error with context
+		replyErr := s.errorHandler.WrapError(err, ErrorCodeInternalError, "Failed to send method not found error", map[string]interface{}{
+			"method":     req.Method,
+			"request_id": req.ID,
+		})
+		s.errorHandler.HandleError(ctx, replyErr, "handle_unsupported_method")
+	}
+}
+
+// replyCancelled answers a request whose context was cancelled, by
+// $/cancelRequest or shutdown, before it could be answered
+func (s *MockLSPServer) replyCancelled(ctx context.Context, conn Replier, req *jsonrpc.Request, cause error) {
+	s.stats.RecordCancelledRequest(req.Method)
+	lspErr := NewRequestCancelledError(req.Method, cause)
+	if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+		s.logError(ctx, "Failed to send cancellation error: %v", err)
 	}
 }
 
 // handleInitialize processes the initialize request
-func (s *MockLSPServer) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleInitialize(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.InitializeParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		lspErr := NewInvalidParamsError("failed to parse initialize params", err)
 		lspErr = lspErr.WithContext("method", "initialize")
 		if replyErr := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); replyErr != nil {
-			s.errorHandler.HandleError(replyErr, "initialize_send_error")
+			s.errorHandler.HandleError(ctx, replyErr, "initialize_send_error")
 		}
-		s.errorHandler.HandleError(lspErr, "initialize_parse_params")
+		s.errorHandler.HandleError(ctx, lspErr, "initialize_parse_params")
 		return
 	}
 
-	s.logInfo("Initialize request from client with root URI: %+v", params.RootUri)
+	s.logInfo(ctx, "Initialize request from client with root URI: %+v", params.RootUri)
 
-	// textDocumentSyncChange := protocol.TextDocumentSyncKind(0)
+	s.mu.Lock()
+	s.clientLocale = params.Locale
+	s.rootURI = workspaceRoot(params)
+	if params.ClientInfo != nil {
+		s.clientName = params.ClientInfo.Name
+		s.clientVersion = params.ClientInfo.Version
+	}
+	fingerprint := newClientFingerprint(params)
+	s.client = fingerprint
+	s.folding = nil
+	if params.Capabilities.TextDocument != nil {
+		s.folding = params.Capabilities.TextDocument.FoldingRange
+	}
+	s.mu.Unlock()
+	s.logInfo(ctx, "Client fingerprint: %s", fingerprint)
 
-	textDocumentSync := protocol.Or2[protocol.TextDocumentSyncOptions, protocol.TextDocumentSyncKind]{Value: protocol.TextDocumentSyncKind(0)}
+	// Decode initializationOptions again as raw JSON, so the exact value the
+	// client sent can be echoed back
+	var rawOptions struct {
+		InitializationOptions json.RawMessage `json:"initializationOptions"`
+	}
+	if err := json.Unmarshal(*req.Params, &rawOptions); err == nil {
+		s.applyInitializationOptions(ctx, rawOptions.InitializationOptions)
+	}
+	s.Audit(AuditEvent{Event: AuditInitialized})
 
-	completionProvider := protocol.CompletionOptions{TriggerCharacters: []string{".", ":"}}
-	hoverProvider := protocol.Or2[bool, protocol.HoverOptions]{Value: true}
-	definitionProvider := protocol.Or2[bool, protocol.DefinitionOptions]{Value: true}
-	referencesProvider := protocol.Or2[bool, protocol.ReferenceOptions]{Value: true}
-	documentSymbolProvider := protocol.Or2[bool, protocol.DocumentSymbolOptions]{Value: true}
+	// Features the client can register dynamically are registered once it
+	// sends initialized, instead of being announced in the result
+	dynamic := s.dynamicFeatures(params)
+	s.mu.Lock()
+	s.dynamic = dynamic
+	s.registered = nil
+	s.mu.Unlock()
+
+	s.setState(StateInitializing)
 
 	// Mock server capabilities
 	result := protocol.InitializeResult{
-		Capabilities: protocol.ServerCapabilities{
-			TextDocumentSync:       &textDocumentSync,
-			CompletionProvider:     &completionProvider,
-			HoverProvider:          &hoverProvider,
-			DefinitionProvider:     &definitionProvider,
-			ReferencesProvider:     &referencesProvider,
-			DocumentSymbolProvider: &documentSymbolProvider,
-		},
+		Capabilities: s.capabilities(),
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "Mock LSP Server",
 			Version: "1.0.0",
 		},
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
+	if err := s.reply(ctx, conn, req, result); err != nil {
 		replyErr := s.errorHandler.WrapError(err, ErrorCodeInternalError, "Failed to send initialize response", map[string]interface{}{
 			"method":     "initialize",
 			"request_id": req.ID,
 		})
-		s.errorHandler.HandleError(replyErr, "initialize_send_response")
+		s.errorHandler.HandleError(ctx, replyErr, "initialize_send_response")
+		return
+	}
+	s.Audit(AuditEvent{Event: AuditCapabilities, Capabilities: capabilityNames(result.Capabilities)})
+}
+
+// completionTriggerCharacters are the characters announced as triggering
+// completion, from the completion section of the configuration
+func (s *MockLSPServer) completionTriggerCharacters() []string {
+	if chars := s.config().LSP.CompletionConfig.TriggerCharacters; chars != nil {
+		return chars
 	}
+	return []string{}
+}
+
+// capabilities builds the server capabilities announced to the client.
+// Features disabled in the configuration, switched off at runtime,
+// registered dynamically or newer than the protocol version are left out.
+func (s *MockLSPServer) capabilities() protocol.ServerCapabilities {
+	syncChange := protocol.TextDocumentSyncKindIncremental
+	textDocumentSync := protocol.Or2[protocol.TextDocumentSyncOptions, protocol.TextDocumentSyncKind]{
+		Value: protocol.TextDocumentSyncOptions{
+			OpenClose: true,
+			Change:    &syncChange,
+			Save: &protocol.Or2[bool, protocol.SaveOptions]{
+				Value: protocol.SaveOptions{IncludeText: true},
+			},
+		},
+	}
+
+	if s.minimal() {
+		return protocol.ServerCapabilities{TextDocumentSync: &textDocumentSync}
+	}
+
+	capabilities := protocol.ServerCapabilities{TextDocumentSync: &textDocumentSync}
+	if s.announceStatically("completion") {
+		capabilities.CompletionProvider = &protocol.CompletionOptions{TriggerCharacters: s.completionTriggerCharacters()}
+	}
+	if s.announceStatically("hover") {
+		capabilities.HoverProvider = &protocol.Or2[bool, protocol.HoverOptions]{Value: true}
+	}
+	if s.announceStatically("definition") {
+		capabilities.DefinitionProvider = &protocol.Or2[bool, protocol.DefinitionOptions]{Value: true}
+	}
+	if s.announceStatically("references") {
+		capabilities.ReferencesProvider = &protocol.Or2[bool, protocol.ReferenceOptions]{Value: true}
+	}
+	if s.announceStatically("document_symbol") {
+		capabilities.DocumentSymbolProvider = &protocol.Or2[bool, protocol.DocumentSymbolOptions]{Value: true}
+	}
+	if s.announceStatically("workspace_symbol") {
+		capabilities.WorkspaceSymbolProvider = &protocol.Or2[bool, protocol.WorkspaceSymbolOptions]{Value: true}
+	}
+	if s.announceStatically("code_action") {
+		capabilities.CodeActionProvider = &protocol.Or2[bool, protocol.CodeActionOptions]{
+			Value: protocol.CodeActionOptions{
+				CodeActionKinds: s.codeActionKinds(),
+				ResolveProvider: s.config().LSP.CodeActionConfig.Resolve,
+			},
+		}
+	}
+	if commands := s.commandIDs(); len(commands) > 0 {
+		capabilities.ExecuteCommandProvider = &protocol.ExecuteCommandOptions{Commands: commands}
+	}
+	if s.announceStatically("formatting") {
+		capabilities.DocumentFormattingProvider = &protocol.Or2[bool, protocol.DocumentFormattingOptions]{Value: true}
+	}
+	if s.announceStatically("range_formatting") {
+		capabilities.DocumentRangeFormattingProvider = &protocol.Or2[bool, protocol.DocumentRangeFormattingOptions]{Value: true}
+	}
+	if s.announceStatically("signature_help") {
+		trigger, retrigger := s.signatureHelpTriggerCharacters()
+		capabilities.SignatureHelpProvider = &protocol.SignatureHelpOptions{
+			TriggerCharacters:   trigger,
+			RetriggerCharacters: retrigger,
+		}
+	}
+	if s.announceStatically("rename") {
+		capabilities.RenameProvider = &protocol.Or2[bool, protocol.RenameOptions]{
+			Value: protocol.RenameOptions{PrepareProvider: s.prepareRenameSupported()},
+		}
+	}
+	if s.announceStatically("semantic_tokens") {
+		capabilities.SemanticTokensProvider = &protocol.Or2[protocol.SemanticTokensOptions, protocol.SemanticTokensRegistrationOptions]{
+			Value: protocol.SemanticTokensOptions{
+				Legend: semanticTokensLegend,
+				Full:   &protocol.Or2[bool, protocol.SemanticTokensFullDelta]{Value: protocol.SemanticTokensFullDelta{Delta: true}},
+				Range:  &protocol.Or2[bool, protocol.LSPObject]{Value: true},
+			},
+		}
+	}
+	if s.announceStatically("inlay_hint") {
+		capabilities.InlayHintProvider = &protocol.Or3[bool, protocol.InlayHintOptions, protocol.InlayHintRegistrationOptions]{
+			Value: protocol.InlayHintOptions{ResolveProvider: s.config().LSP.InlayHintConfig.Resolve},
+		}
+	}
+	if s.announceStatically("diagnostics") && s.pullDiagnostics() {
+		capabilities.DiagnosticProvider = &protocol.Or2[protocol.DiagnosticOptions, protocol.DiagnosticRegistrationOptions]{
+			Value: protocol.DiagnosticOptions{Identifier: diagnosticSource, WorkspaceDiagnostics: true},
+		}
+	}
+	if s.announceStatically("folding_range") {
+		capabilities.FoldingRangeProvider = &protocol.Or3[bool, protocol.FoldingRangeOptions, protocol.FoldingRangeRegistrationOptions]{Value: true}
+	}
+	if s.announceStatically("selection_range") {
+		capabilities.SelectionRangeProvider = &protocol.Or3[bool, protocol.SelectionRangeOptions, protocol.SelectionRangeRegistrationOptions]{Value: true}
+	}
+	if s.announceStatically("linked_editing_range") {
+		capabilities.LinkedEditingRangeProvider = &protocol.Or3[bool, protocol.LinkedEditingRangeOptions, protocol.LinkedEditingRangeRegistrationOptions]{Value: true}
+	}
+	if s.announceStatically("inline_completion") {
+		capabilities.InlineCompletionProvider = &protocol.Or2[bool, protocol.InlineCompletionOptions]{Value: true}
+	}
+	if s.announceStatically("file_operations") {
+		capabilities.Workspace = &protocol.WorkspaceOptions{FileOperations: s.fileOperationOptions()}
+	}
+	s.restrictCapabilities(&capabilities)
+	return capabilities
 }
 
 // handleInitialized processes the initialized notification
-func (s *MockLSPServer) handleInitialized(_ context.Context, _ *jsonrpc2.Conn, _ *jsonrpc2.Request) {
-	s.logInfo("Client initialized")
+func (s *MockLSPServer) handleInitialized(ctx context.Context, conn Conn, _ *jsonrpc.Request) {
+	s.logInfo(ctx, "Client initialized")
+	s.setState(StateInitialized)
+
+	if registered, _ := s.syncRegistrations(ctx, conn); len(registered) > 0 {
+		s.logInfo(ctx, "Registering features dynamically: %v", registered)
+	}
+
+	s.startTimeline(ctx, conn)
+	s.startSoak(ctx, conn)
+	s.startMemoryMonitor(ctx, conn)
+	s.startIndexing(ctx, conn)
+	s.startSettingsPull(ctx, conn)
+
+	// Push diagnostics for documents the client never opened
+	for _, uri := range s.config().LSP.DiagnosticsConfig.UnopenedURIs {
+		s.logInfo(ctx, "Publishing diagnostics for unopened document: %s", uri)
+		s.sendMockDiagnostics(ctx, conn, uri)
+	}
 }
 
 // handleTextDocumentDidOpen processes textDocument/didOpen notifications
-func (s *MockLSPServer) handleTextDocumentDidOpen(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleTextDocumentDidOpen(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.DidOpenTextDocumentParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		lspErr := NewInvalidParamsError("failed to parse textDocument/didOpen params", err)
 		lspErr = lspErr.WithContext("method", "textDocument/didOpen")
-		s.errorHandler.HandleError(lspErr, "didOpen_parse_params")
+		s.errorHandler.HandleError(ctx, lspErr, "didOpen_parse_params")
 		return
 	}
 
-	s.documents[string(params.TextDocument.Uri)] = &params.TextDocument
-	s.logger.Printf("Opened document: %s", params.TextDocument.Uri)
+	s.documents.Put(params.TextDocument)
+	s.logInfo(ctx, "Opened document: %s", params.TextDocument.Uri)
+	s.checkLanguage(ctx, conn, params.TextDocument)
 
 	// Send mock diagnostics
 	s.sendMockDiagnostics(ctx, conn, string(params.TextDocument.Uri))
 }
 
 // handleTextDocumentDidChange processes textDocument/didChange notifications
-func (s *MockLSPServer) handleTextDocumentDidChange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleTextDocumentDidChange(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.DidChangeTextDocumentParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		s.logger.Printf("Failed to parse didChange params: %v", err)
+		s.logError(ctx, "Failed to parse didChange params: %v", err)
 		return
 	}
 
 	uri := string(params.TextDocument.Uri)
-	if doc, exists := s.documents[uri]; exists {
+	var applyErr error
+	_, exists := s.documents.Update(uri, func(doc *protocol.TextDocumentItem) {
 		// Update document version
 		doc.Version = params.TextDocument.Version
 
 		// Apply content changes
-		for _, change := range params.ContentChanges {
-			// Use reflection to get the actual value from the Or2 union type
-			changeValue := reflect.ValueOf(change)
-
-			// Get the Value field from the Or2 struct
-			valueField := changeValue.FieldByName("Value")
-			if !valueField.IsValid() {
-				s.logger.Printf("Or2 union type doesn't have Value field")
-				continue
-			}
-
-			// Get the actual underlying value
-			actualValue := valueField.Interface()
-
-			// Type switch on the actual concrete type
-			switch v := actualValue.(type) {
-			case protocol.TextDocumentContentChangePartial:
-				// Partial document change with range
-				s.logger.Printf("Partial document update for %s at range %v", uri, v.Range)
-				s.logger.Printf("Replacing text in range with: %q", v.Text)
-				// In a real implementation, apply the range-based change
-				// For this mock, we'll just note the change
-
-			case protocol.TextDocumentContentChangeWholeDocument:
-				// Whole document change
-				doc.Text = v.Text
-				s.logger.Printf("Full document update for %s", uri)
-
-			default:
-				s.logger.Printf("Unknown content change type: %T", v)
-			}
+		text, err := applyContentChanges(doc.Text, params.ContentChanges)
+		if err != nil {
+			applyErr = err
+			return
 		}
+		doc.Text = text
+	})
+	if applyErr != nil {
+		lspErr := NewInvalidParamsError("failed to apply textDocument/didChange content changes", applyErr)
+		lspErr = lspErr.WithContext("uri", uri).WithContext("version", params.TextDocument.Version)
+		s.errorHandler.HandleError(ctx, lspErr, "didChange_apply_changes")
+	}
 
-		s.logger.Printf("Document changed: %s (version %d)", uri, params.TextDocument.Version)
+	if exists {
+		s.logInfo(ctx, "Document changed: %s (version %d, %d changes)", uri, params.TextDocument.Version, len(params.ContentChanges))
 
-		// Send updated diagnostics after document change
-		s.sendMockDiagnostics(ctx, conn, uri)
+		// Send updated diagnostics once the edits pause
+		s.scheduleDiagnostics(ctx, conn, uri)
 	}
 }
 
 // handleTextDocumentDidSave processes textDocument/didSave notifications
-func (s *MockLSPServer) handleTextDocumentDidSave(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleTextDocumentDidSave(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.DidSaveTextDocumentParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		s.logger.Printf("Failed to parse didSave params: %v", err)
+		s.logError(ctx, "Failed to parse didSave params: %v", err)
 		return
 	}
 
-	s.logger.Printf("Document saved: %s", params.TextDocument.Uri)
+	s.logInfo(ctx, "Document saved: %s", params.TextDocument.Uri)
+
+	// The protocol type cannot tell an omitted text from an empty document
+	var saved struct {
+		Text *string `json:"text"`
+	}
+	if err := json.Unmarshal(*req.Params, &saved); err != nil || saved.Text == nil {
+		return
+	}
+	s.reconcileSavedText(ctx, conn, string(params.TextDocument.Uri), *saved.Text)
+}
+
+// reconcileSavedText compares the text included in didSave against the
+// server's reconstructed buffer. A divergence means the client's incremental
+// sync went wrong; it is logged, reported to the client and counted in the
+// stats, and the buffer is replaced with the saved text.
+func (s *MockLSPServer) reconcileSavedText(ctx context.Context, conn Conn, uri string, text string) {
+	var buffer string
+	_, exists := s.documents.Update(uri, func(doc *protocol.TextDocumentItem) {
+		buffer = doc.Text
+		doc.Text = text
+	})
+
+	if !exists || buffer == text {
+		return
+	}
+
+	pos := firstDivergence(buffer, text)
+	s.stats.RecordSyncDivergence()
+	s.logError(ctx, "Document %s diverged from saved text at line %d, character %d (server %d bytes, client %d bytes)",
+		uri, pos.Line, pos.Character, len(buffer), len(text))
+
+	message := protocol.LogMessageParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: s.message(msgSyncDivergence, uri, pos.Line, pos.Character),
+	}
+	if err := s.notify(ctx, conn, "window/logMessage", message); err != nil {
+		s.logError(ctx, "Failed to send divergence message: %v", err)
+	}
 }
 
 // handleTextDocumentDidClose processes textDocument/didClose notifications
-func (s *MockLSPServer) handleTextDocumentDidClose(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleTextDocumentDidClose(ctx context.Context, _ Conn, req *jsonrpc.Request) {
 	var params protocol.DidCloseTextDocumentParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		s.logger.Printf("Failed to parse didClose params: %v", err)
+		s.logError(ctx, "Failed to parse didClose params: %v", err)
 		return
 	}
 
-	delete(s.documents, string(params.TextDocument.Uri))
-	s.logger.Printf("Closed document: %s", params.TextDocument.Uri)
+	s.documents.Delete(string(params.TextDocument.Uri))
+	s.logInfo(ctx, "Closed document: %s", params.TextDocument.Uri)
+}
+
+// forgetClosedDocument drops the state kept for a document once it is
+// closed: cached semantic tokens and pulled diagnostics, pending diagnostics
+// publications and the last diagnostics version published
+func (s *MockLSPServer) forgetClosedDocument(event documentstore.Event) {
+	if event.Kind != documentstore.Closed {
+		return
+	}
+	uri := string(event.Document.Uri)
+	s.mu.Lock()
+	delete(s.tokenResults, uri)
+	delete(s.diagnosticResults, uri)
+	s.mu.Unlock()
+	s.cancelDiagnostics(uri)
+	s.invariants.forget(uri)
 }
 
 // handleCompletion processes textDocument/completion requests
-func (s *MockLSPServer) handleCompletion(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleCompletion(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.CompletionParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeInvalidParams,
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
 			Message: "failed to parse completion params",
 		}); replyErr != nil {
-			s.logger.Printf("Failed to send completion error: %v", replyErr)
+			s.logError(ctx, "Failed to send completion error: %v", replyErr)
 		}
 		return
 	}
@@ -281,11 +956,11 @@ func (s *MockLSPServer) handleCompletion(ctx context.Context, conn *jsonrpc2.Con
 		{
 			Label:  "mockFunction",
 			Kind:   &kind1,
-			Detail: "Mock function completion",
+			Detail: s.message(msgCompletionFunctionDetail),
 			Documentation: &protocol.Or2[string, protocol.MarkupContent]{
 				Value: &protocol.MarkupContent{
 					Kind:  protocol.MarkupKindMarkdown,
-					Value: "This is a mock function completion",
+					Value: s.message(msgCompletionFunctionDoc),
 				},
 			},
 			InsertText: "mockFunction()",
@@ -293,48 +968,82 @@ func (s *MockLSPServer) handleCompletion(ctx context.Context, conn *jsonrpc2.Con
 		{
 			Label:  "mockVariable",
 			Kind:   &kind2,
-			Detail: "Mock variable completion",
+			Detail: s.message(msgCompletionVariableDetail),
 			Documentation: &protocol.Or2[string, protocol.MarkupContent]{
-				Value: "This is a mock variable",
+				Value: s.message(msgCompletionVariableDoc),
 			},
 		},
 		{
 			Label:      "mockClass",
 			Kind:       &kind3,
-			Detail:     "Mock class completion",
+			Detail:     s.message(msgCompletionClassDetail),
 			InsertText: "MockClass",
 		},
 	}
 
+	s.stats.RecordCompletionTrigger(completionTriggerKey(params.Context))
+	text, _ := s.documentText(string(params.TextDocument.Uri))
+	trigger := completionTrigger(params.Context, text, params.Position)
+	contextItems := s.contextCompletionItems(trigger)
+	if contextItems != nil {
+		items = contextItems
+	} else if mix := s.completionMix(); mix != nil {
+		items = mix
+	}
+
+	if profile := s.languageProfile(string(params.TextDocument.Uri)); profile != nil {
+		items = profile.completionItems(items)
+	}
+	if s.config().LSP.MockData.Deprecated {
+		deprecateCompletionItems(items)
+	}
+	s.completionOrdering(items)
+
+	// A list for a trigger character is incomplete, so the client asks again
+	// as the user types on
 	result := protocol.CompletionList{
-		IsIncomplete: false,
+		IsIncomplete: contextItems != nil && params.Context.TriggerKind == protocol.CompletionTriggerKindTriggerCharacter,
 		Items:        items,
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
-		s.logger.Printf("Failed to send completion response: %v", err)
+	if random := s.randomResponses(string(params.TextDocument.Uri)); random != nil {
+		result = random.completionList()
+	}
+
+	if torture := s.unicodeTorture(); torture != nil {
+		result.Items = torture.completionItems(result.Items, text, params.Position)
+	}
+
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send completion response: %v", err)
 	}
 }
 
 // handleHover processes textDocument/hover requests
-func (s *MockLSPServer) handleHover(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleHover(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.HoverParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeInvalidParams,
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
 			Message: "failed to parse hover params",
 		}); replyErr != nil {
-			s.logger.Printf("Failed to send hover error: %v", replyErr)
+			s.logError(ctx, "Failed to send hover error: %v", replyErr)
 		}
 		return
 	}
 
 	// Mock hover information
+	content := s.message(msgHoverContent)
+	profile := s.languageProfile(string(params.TextDocument.Uri))
+	if profile != nil {
+		content = profile.hoverContent(content)
+	}
+	content = hoverMarkdown(content, s.config().LSP.HoverConfig.Markdown, params.TextDocument.Uri, profile)
 	result := protocol.Hover{
 		Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
 			Value: protocol.MarkupContent{
 				Kind:  protocol.MarkupKindMarkdown,
-				Value: "**Mock Hover Information**\n\nThis is mock hover content for testing purposes.",
+				Value: content,
 			},
 		},
 		Range: &protocol.Range{
@@ -346,20 +1055,29 @@ func (s *MockLSPServer) handleHover(ctx context.Context, conn *jsonrpc2.Conn, re
 		},
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
-		s.logger.Printf("Failed to send hover response: %v", err)
+	if random := s.randomResponses(string(params.TextDocument.Uri)); random != nil {
+		result = random.hover()
+	}
+
+	if torture := s.unicodeTorture(); torture != nil {
+		text, _ := s.documentText(string(params.TextDocument.Uri))
+		result = torture.hover(result, text, params.Position)
+	}
+
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send hover response: %v", err)
 	}
 }
 
 // handleDefinition processes textDocument/definition requests
-func (s *MockLSPServer) handleDefinition(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleDefinition(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.DefinitionParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeInvalidParams,
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
 			Message: "failed to parse definition params",
 		}); replyErr != nil {
-			s.logger.Printf("Failed to send definition error: %v", replyErr)
+			s.logError(ctx, "Failed to send definition error: %v", replyErr)
 		}
 		return
 	}
@@ -367,28 +1085,36 @@ func (s *MockLSPServer) handleDefinition(ctx context.Context, conn *jsonrpc2.Con
 	// Mock definition location
 	result := []protocol.Location{
 		{
-			Uri: params.TextDocument.Uri,
-			Range: protocol.Range{
-				Start: protocol.Position{Line: 0, Character: 0},
-				End:   protocol.Position{Line: 0, Character: 10},
-			},
+			Uri:   params.TextDocument.Uri,
+			Range: declarationRange,
 		},
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
-		s.logger.Printf("Failed to send definition response: %v", err)
+	if random := s.randomResponses(string(params.TextDocument.Uri)); random != nil {
+		result = random.locations(params.TextDocument.Uri, 3)
+	}
+
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send definition response: %v", err)
 	}
 }
 
+// declarationRange is where the mock symbol is declared: the definition
+// location, and the declaration included in references on request
+var declarationRange = protocol.Range{
+	Start: protocol.Position{Line: 0, Character: 0},
+	End:   protocol.Position{Line: 0, Character: 10},
+}
+
 // handleReferences processes textDocument/references requests
-func (s *MockLSPServer) handleReferences(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleReferences(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.ReferenceParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeInvalidParams,
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
 			Message: "failed to parse references params",
 		}); replyErr != nil {
-			s.logger.Printf("Failed to send references error: %v", replyErr)
+			s.logError(ctx, "Failed to send references error: %v", replyErr)
 		}
 		return
 	}
@@ -411,20 +1137,32 @@ func (s *MockLSPServer) handleReferences(ctx context.Context, conn *jsonrpc2.Con
 		},
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
-		s.logger.Printf("Failed to send references response: %v", err)
+	if random := s.randomResponses(string(params.TextDocument.Uri)); random != nil {
+		result = random.locations(params.TextDocument.Uri, random.maxItems)
+	}
+
+	// The declaration is distinct from the references, so clients that drop
+	// or duplicate it can be told apart
+	s.stats.RecordReferencesContext(params.Context.IncludeDeclaration)
+	if params.Context.IncludeDeclaration {
+		declaration := protocol.Location{Uri: params.TextDocument.Uri, Range: declarationRange}
+		result = append([]protocol.Location{declaration}, result...)
+	}
+
+	if err := replyPartialResults(ctx, s, conn, req, params.PartialResultToken, slices.Values(result)); err != nil {
+		s.logError(ctx, "Failed to send references response: %v", err)
 	}
 }
 
 // handleDocumentSymbol processes textDocument/documentSymbol requests
-func (s *MockLSPServer) handleDocumentSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+func (s *MockLSPServer) handleDocumentSymbol(ctx context.Context, conn Conn, req *jsonrpc.Request) {
 	var params protocol.DocumentSymbolParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeInvalidParams,
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
 			Message: "failed to parse document symbol params",
 		}); replyErr != nil {
-			s.logger.Printf("Failed to send document symbol error: %v", replyErr)
+			s.logError(ctx, "Failed to send document symbol error: %v", replyErr)
 		}
 		return
 	}
@@ -434,7 +1172,7 @@ func (s *MockLSPServer) handleDocumentSymbol(ctx context.Context, conn *jsonrpc2
 		{
 			Name:   "MockClass",
 			Kind:   protocol.SymbolKindClass,
-			Detail: "Mock class symbol",
+			Detail: s.message(msgSymbolClassDetail),
 			Range: protocol.Range{
 				Start: protocol.Position{Line: 0, Character: 0},
 				End:   protocol.Position{Line: 20, Character: 0},
@@ -445,72 +1183,174 @@ func (s *MockLSPServer) handleDocumentSymbol(ctx context.Context, conn *jsonrpc2
 			},
 			Children: []protocol.DocumentSymbol{
 				{
-					Name: "mockMethod",
+					Name: deprecatedMethod,
 					Kind: protocol.SymbolKindMethod,
 					Range: protocol.Range{
 						Start: protocol.Position{Line: 5, Character: 4},
 						End:   protocol.Position{Line: 10, Character: 4},
 					},
-					SelectionRange: protocol.Range{
-						Start: protocol.Position{Line: 5, Character: 4},
-						End:   protocol.Position{Line: 5, Character: 14},
-					},
+					SelectionRange: deprecatedRange,
 				},
 			},
 		},
 	}
 
-	if err := conn.Reply(ctx, req.ID, result); err != nil {
-		s.logger.Printf("Failed to send document symbol response: %v", err)
+	if s.config().LSP.MockData.Deprecated {
+		deprecateSymbols(result)
+	}
+
+	if random := s.randomResponses(string(params.TextDocument.Uri)); random != nil {
+		result = random.documentSymbols(2)
+	}
+
+	if err := replyPartialResults(ctx, s, conn, req, params.PartialResultToken, slices.Values(result)); err != nil {
+		s.logError(ctx, "Failed to send document symbol response: %v", err)
 	}
 }
 
 // handleShutdown processes shutdown requests
-func (s *MockLSPServer) handleShutdown(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
-	s.logger.Println("Shutdown request received")
-	if err := conn.Reply(ctx, req.ID, nil); err != nil {
-		s.logger.Printf("Failed to send shutdown response: %v", err)
+func (s *MockLSPServer) handleShutdown(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	s.logInfo(ctx, "Shutdown request received")
+	s.Audit(AuditEvent{Event: AuditShutdown, Reason: "shutdown request"})
+	s.setState(StateShuttingDown)
+	s.drainRequests(ctx, req.ID)
+	s.cancelAllDiagnostics()
+	s.saveCapture(ctx)
+	s.saveStats(ctx)
+	s.saveJUnitReport(ctx)
+	if err := s.reply(ctx, conn, req, nil); err != nil {
+		s.logError(ctx, "Failed to send shutdown response: %v", err)
 	}
 }
 
-// handleExit processes exit notifications
-func (s *MockLSPServer) handleExit(_ context.Context, _ *jsonrpc2.Conn, _ *jsonrpc2.Request) {
-	s.logger.Println("Exit notification received")
-	os.Exit(0)
+// handleStats processes the custom $/mockLsp/stats request
+func (s *MockLSPServer) handleStats(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if err := s.reply(ctx, conn, req, s.Stats()); err != nil {
+		s.logError(ctx, "Failed to send stats response: %v", err)
+	}
 }
 
-// sendMockDiagnostics sends mock diagnostic information for a document
-func (s *MockLSPServer) sendMockDiagnostics(ctx context.Context, conn *jsonrpc2.Conn, uri string) {
+// handleRecentTraffic returns the latest wire messages kept in memory
+func (s *MockLSPServer) handleRecentTraffic(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	result := RecentTrafficResult{Capacity: s.config().LSP.RecentTraffic, Messages: s.RecentTraffic()}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send recent traffic: %v", err)
+	}
+}
+
+// handleDocumentHash processes the custom $/mockLsp/documentHash request
+func (s *MockLSPServer) handleDocumentHash(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params DocumentHashParams
+	err := fmt.Errorf("missing params")
+	if req.Params != nil {
+		err = json.Unmarshal(*req.Params, &params)
+	}
+	if err != nil {
+		lspErr := NewInvalidParamsError("failed to parse documentHash params", err)
+		if replyErr := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); replyErr != nil {
+			s.logError(ctx, "Failed to send documentHash error: %v", replyErr)
+		}
+		return
+	}
+
+	uri := string(params.TextDocument.Uri)
+	doc, exists := s.documents.Get(uri)
+	var result DocumentHashResult
+	if exists {
+		result = hashDocument(&doc)
+	}
+
+	if !exists {
+		lspErr := NewDocumentNotFoundError(uri)
+		if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+			s.logError(ctx, "Failed to send documentHash error: %v", err)
+		}
+		return
+	}
+
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send documentHash response: %v", err)
+	}
+}
+
+// handleExit processes exit notifications: the session is saved and Done
+// is closed, leaving closing the connection and exiting to the caller
+func (s *MockLSPServer) handleExit(ctx context.Context, _ Conn, _ *jsonrpc.Request) {
+	s.logInfo(ctx, "Exit notification received")
+	s.Audit(AuditEvent{Event: AuditDisconnected, Reason: "exit notification"})
+	code := s.exitCode()
+	s.saveCapture(ctx)
+	s.saveStats(ctx)
+	s.saveJUnitReport(ctx)
+	s.setState(StateExited)
+	s.signalExit(code)
+}
+
+// sendMockDiagnostics sends mock diagnostic information for a document.
+// Nothing is published in minimal mode, with diagnostics switched off or
+// when clients pull them instead.
+func (s *MockLSPServer) sendMockDiagnostics(ctx context.Context, conn Conn, uri string) {
+	if s.minimal() || !s.featureEnabled("diagnostics") || !s.pushDiagnostics() {
+		return
+	}
+
+	params := protocol.PublishDiagnosticsParams{
+		Uri:         protocol.DocumentUri(uri),
+		Diagnostics: s.mockDiagnostics(uri),
+	}
+
+	if err := s.notify(ctx, conn, publishDiagnosticsMethod, params); err != nil {
+		s.logError(ctx, "Failed to send diagnostics notification: %v", err)
+		return
+	}
+	emit(&s.hooks, &s.hooks.diagnostics, params)
+}
+
+// mockDiagnostics returns the mock diagnostics of a document, pushed or
+// pulled alike
+func (s *MockLSPServer) mockDiagnostics(uri string) []protocol.Diagnostic {
 	severity1 := protocol.DiagnosticSeverity(protocol.DiagnosticSeverityWarning)
 	severity2 := protocol.DiagnosticSeverity(protocol.DiagnosticSeverityInformation)
 
 	diagnostics := []protocol.Diagnostic{
-		{
+		s.withRule(protocol.Diagnostic{
 			Range: protocol.Range{
 				Start: protocol.Position{Line: 1, Character: 0},
 				End:   protocol.Position{Line: 1, Character: 10},
 			},
 			Severity: &severity1,
-			Message:  "This is a mock warning",
-			Source:   "mock-lsp",
-		},
-		{
+			Message:  s.message(msgDiagnosticWarning),
+			Source:   diagnosticSource,
+		}, ruleMockWarning),
+		s.withRule(protocol.Diagnostic{
 			Range: protocol.Range{
 				Start: protocol.Position{Line: 5, Character: 15},
 				End:   protocol.Position{Line: 5, Character: 25},
 			},
 			Severity: &severity2,
-			Message:  "This is mock info",
-			Source:   "mock-lsp",
-		},
+			Message:  s.message(msgDiagnosticInfo),
+			Source:   diagnosticSource,
+		}, ruleMockInfo),
+	}
+	if s.config().LSP.MockData.Deprecated {
+		diagnostics = append(diagnostics, s.withRule(s.deprecatedDiagnostic(), ruleDeprecated))
 	}
 
-	params := protocol.PublishDiagnosticsParams{
-		Uri:         protocol.DocumentUri(uri),
-		Diagnostics: diagnostics,
+	if random := s.randomResponses(uri); random != nil {
+		diagnostics = random.diagnostics(s.config().LSP.DiagnosticsConfig.MaxIssues)
+	}
+
+	if torture := s.unicodeTorture(); torture != nil {
+		if text, ok := s.documentText(uri); ok {
+			diagnostics = torture.diagnostics(diagnostics, text)
+		}
 	}
 
-	if err := conn.Notify(ctx, "textDocument/publishDiagnostics", params); err != nil {
-		s.logger.Printf("Failed to send diagnostics notification: %v", err)
+	if preset, ok := s.config().LSP.Presets[publishDiagnosticsMethod]; ok {
+		if presetDiags, ok := presetDiagnostics(preset, protocol.DocumentUri(uri), s.config().LSP.PresetReverseRanges); ok {
+			diagnostics = presetDiags
+		}
 	}
+
+	return expandDiagnostics(diagnostics, s.config().LSP.DiagnosticsConfig)
 }
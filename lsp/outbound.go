@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// queuedMethods are the notifications sent through the notification queue
+// when it is enabled. Other notifications are always sent inline.
+var queuedMethods = []string{publishDiagnosticsMethod, progressMethod}
+
+// queuedNotification is an encoded notification waiting to be sent
+type queuedNotification struct {
+	method string
+	// key identifies notifications that supersede each other, such as the
+	// diagnostics of a URI; empty if every notification counts
+	key    string
+	params json.RawMessage
+}
+
+// notificationQueue sends notifications from a single goroutine, so
+// handlers do not block on a client that reads slowly. It holds at most
+// size notifications and applies its overflow policy beyond that. A queued
+// notification with the same method and key as a new one is replaced in
+// place: only the latest diagnostics of a URI are sent.
+type notificationQueue struct {
+	conn     Conn
+	size     int
+	overflow string
+	stats    *Stats
+
+	mu      sync.Mutex
+	space   *sync.Cond // Signalled when notifications leave the queue
+	pending []queuedNotification
+	closed  bool
+	wake    chan struct{}
+}
+
+// newNotificationQueue starts a queue sending to conn until it closes
+func newNotificationQueue(conn Conn, cfg config.NotificationQueueConfig, stats *Stats) *notificationQueue {
+	q := &notificationQueue{
+		conn:     conn,
+		size:     cfg.Size,
+		overflow: cfg.Overflow,
+		stats:    stats,
+		wake:     make(chan struct{}, 1),
+	}
+	q.space = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// push queues a notification, merging it with a queued one it supersedes.
+// When the queue is full, the oldest or the new notification is dropped,
+// or push waits for room, depending on the overflow policy.
+func (q *notificationQueue) push(n queuedNotification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n.key != "" {
+		for i := range q.pending {
+			if q.pending[i].method == n.method && q.pending[i].key == n.key {
+				q.pending[i].params = n.params
+				q.stats.RecordMergedNotification()
+				return nil
+			}
+		}
+	}
+
+	for !q.closed && len(q.pending) >= q.size {
+		switch q.overflow {
+		case config.QueueBlock:
+			q.space.Wait()
+		case config.QueueDropNewest:
+			q.stats.RecordDroppedNotification(n.method)
+			return nil
+		default:
+			q.stats.RecordDroppedNotification(q.pending[0].method)
+			q.pending[0] = queuedNotification{}
+			q.pending = q.pending[1:]
+		}
+	}
+	if q.closed {
+		return jsonrpc.ErrClosed
+	}
+
+	q.pending = append(q.pending, n)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run sends queued notifications in order until the connection closes
+func (q *notificationQueue) run() {
+	ctx := context.Background()
+	for {
+		select {
+		case <-q.wake:
+		case <-q.conn.DisconnectNotify():
+			q.mu.Lock()
+			q.closed = true
+			q.pending = nil
+			q.space.Broadcast()
+			q.mu.Unlock()
+			return
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			n := q.pending[0]
+			q.pending[0] = queuedNotification{}
+			q.pending = q.pending[1:]
+			q.space.Broadcast()
+			q.mu.Unlock()
+
+			if err := q.conn.Notify(ctx, n.method, n.params); err != nil {
+				break
+			}
+		}
+	}
+}
+
+// Len returns the number of notifications waiting to be sent
+func (q *notificationQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// notificationQueueFor returns the notification queue of conn, starting it
+// on first use, or nil if the queue is disabled
+func (s *MockLSPServer) notificationQueueFor(conn Conn) *notificationQueue {
+	cfg := s.config().LSP.NotificationQueue
+	if cfg.Size <= 0 {
+		return nil
+	}
+	conn = underlyingConn(conn)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outbound == nil || s.outbound.conn != conn {
+		s.outbound = newNotificationQueue(conn, cfg, s.stats)
+	}
+	return s.outbound
+}
+
+// sendNotification sends an encoded notification, through the notification
+// queue for the methods it applies to. data is copied before being queued,
+// so callers may reuse it.
+func (s *MockLSPServer) sendNotification(ctx context.Context, conn Conn, method, key string, data json.RawMessage) error {
+	if slices.Contains(queuedMethods, method) {
+		if q := s.notificationQueueFor(conn); q != nil {
+			return q.push(queuedNotification{method: method, key: key, params: bytes.Clone(data)})
+		}
+	}
+	return conn.Notify(ctx, method, data)
+}
+
+// notificationKey returns the key under which newer notifications supersede
+// queued ones: the URI of published diagnostics
+func notificationKey(params any) string {
+	if diagnostics, ok := params.(protocol.PublishDiagnosticsParams); ok {
+		return string(diagnostics.Uri)
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+// stalledClient is the client side of a connection that reads nothing until
+// resume is called, like a client too busy to keep up
+type stalledClient struct {
+	side     net.Conn
+	received chan string
+}
+
+// newStalledQueue returns a notification queue sending to a stalled client
+func newStalledQueue(t *testing.T, cfg config.NotificationQueueConfig) (*notificationQueue, *stalledClient, *Stats) {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	conn := jsonrpc.NewConn(context.Background(), jsonrpc.NewStream(serverSide), jsonrpc.HandlerWithError(
+		func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) { return nil, nil }))
+	t.Cleanup(func() { conn.Close() })
+
+	stats := NewStats("test")
+	return newNotificationQueue(conn, cfg, stats), &stalledClient{side: clientSide, received: make(chan string, 100)}, stats
+}
+
+// resume starts reading, passing the "id" of every notification received
+func (c *stalledClient) resume(t *testing.T) {
+	client := jsonrpc.NewConn(context.Background(), jsonrpc.NewStream(c.side),
+		jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+			var params struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(*req.Params, &params); err != nil {
+				t.Errorf("Failed to decode %s: %v", req.Method, err)
+			}
+			c.received <- params.ID
+			return nil, nil
+		}))
+	t.Cleanup(func() { client.Close() })
+}
+
+// expect waits for the notifications with the given IDs, in order
+func (c *stalledClient) expect(t *testing.T, ids ...string) {
+	t.Helper()
+	for _, want := range ids {
+		select {
+		case got := <-c.received:
+			if got != want {
+				t.Fatalf("Expected notification %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for notification %s", want)
+		}
+	}
+	select {
+	case got := <-c.received:
+		t.Errorf("Expected no more notifications, got %s", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// queued builds a notification with the given merge key and ID
+func queued(method, key, id string) queuedNotification {
+	return queuedNotification{method: method, key: key, params: json.RawMessage(`{"id":"` + id + `"}`)}
+}
+
+// waitForSending waits until the queue took the first notification out
+// and is stuck sending it
+func waitForSending(t *testing.T, q *notificationQueue) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the queue to start sending")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNotificationQueueOverflow(t *testing.T) {
+	tests := []struct {
+		overflow    string
+		wantSent    []string
+		wantDropped int64
+	}{
+		{config.QueueDropOldest, []string{"a", "c", "d"}, 1},
+		{"", []string{"a", "c", "d"}, 1},
+		{config.QueueDropNewest, []string{"a", "b", "c"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			q, client, stats := newStalledQueue(t, config.NotificationQueueConfig{Size: 2, Overflow: tt.overflow})
+
+			if err := q.push(queued(progressMethod, "", "a")); err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			waitForSending(t, q)
+			for _, id := range []string{"b", "c", "d"} {
+				if err := q.push(queued(progressMethod, "", id)); err != nil {
+					t.Fatalf("push failed: %v", err)
+				}
+			}
+			if q.Len() != 2 {
+				t.Errorf("Expected the queue to stay bounded at 2, got %d", q.Len())
+			}
+
+			client.resume(t)
+			client.expect(t, tt.wantSent...)
+			if dropped := stats.Snapshot(0).DroppedNotifications[progressMethod]; dropped != tt.wantDropped {
+				t.Errorf("Expected %d dropped, got %d", tt.wantDropped, dropped)
+			}
+		})
+	}
+}
+
+func TestNotificationQueueMergesDiagnostics(t *testing.T) {
+	q, client, stats := newStalledQueue(t, config.NotificationQueueConfig{Size: 10})
+
+	if err := q.push(queued(publishDiagnosticsMethod, "file:///a.go", "a1")); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	waitForSending(t, q)
+	for _, n := range []queuedNotification{
+		queued(publishDiagnosticsMethod, "file:///a.go", "a2"),
+		queued(publishDiagnosticsMethod, "file:///b.go", "b1"),
+		queued(publishDiagnosticsMethod, "file:///a.go", "a3"),
+		queued(publishDiagnosticsMethod, "file:///a.go", "a4"),
+	} {
+		if err := q.push(n); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	// The latest diagnostics of a.go win, in the place of the first queued
+	client.resume(t)
+	client.expect(t, "a1", "a4", "b1")
+	if merged := stats.Snapshot(0).MergedNotifications; merged != 2 {
+		t.Errorf("Expected 2 merged notifications, got %d", merged)
+	}
+}
+
+func TestNotificationQueueBlocks(t *testing.T) {
+	q, client, stats := newStalledQueue(t, config.NotificationQueueConfig{Size: 1, Overflow: config.QueueBlock})
+
+	if err := q.push(queued(progressMethod, "", "a")); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	waitForSending(t, q)
+	if err := q.push(queued(progressMethod, "", "b")); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.push(queued(progressMethod, "", "c")) }()
+	select {
+	case err := <-pushed:
+		t.Fatalf("Expected push to wait for room, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.resume(t)
+	if err := <-pushed; err != nil {
+		t.Errorf("Expected push to succeed once the client caught up, got %v", err)
+	}
+	client.expect(t, "a", "b", "c")
+	if dropped := stats.Snapshot(0).DroppedNotifications; len(dropped) != 0 {
+		t.Errorf("Expected nothing dropped, got %v", dropped)
+	}
+}
+
+func TestNotificationQueueDiagnostics(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.NotificationQueue = config.NotificationQueueConfig{Size: 16}
+	server.SetConfig(cfg)
+
+	received := make(chan protocol.PublishDiagnosticsParams, 10)
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		if req.Method != publishDiagnosticsMethod {
+			return
+		}
+		var params protocol.PublishDiagnosticsParams
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			t.Errorf("Failed to decode diagnostics: %v", err)
+		}
+		received <- params
+	})
+	ctx := context.Background()
+
+	doc := protocol.TextDocumentItem{Uri: "file:///queued.go", LanguageId: "go", Text: "package main\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	select {
+	case params := <-received:
+		if params.Uri != doc.Uri || len(params.Diagnostics) == 0 {
+			t.Errorf("Expected diagnostics for %s, got %+v", doc.Uri, params)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for queued diagnostics")
+	}
+}
+
+func TestNotificationQueuePerConnection(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.NotificationQueue = config.NotificationQueueConfig{Size: 16}
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+
+	// Requests of different methods count their errors through different
+	// wrappers of the same connection
+	hover := server.notificationQueueFor(errorCountingConn{Conn: conn, stats: server.stats, method: "textDocument/hover"})
+	references := server.notificationQueueFor(errorCountingConn{Conn: conn, stats: server.stats, method: "textDocument/references"})
+	if hover != references || server.notificationQueueFor(conn) != hover {
+		t.Error("Expected one notification queue for the connection")
+	}
+}
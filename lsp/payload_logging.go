@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"mock-lsp-server/config"
+)
+
+// logsPayload reports whether the payloads of method are logged, as
+// selected by the payload_methods of the logging config
+func logsPayload(cfg *config.ServerConfig, method string) bool {
+	for _, pattern := range cfg.Logging.PayloadMethods {
+		if matchMethod(pattern, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMethod reports whether method matches pattern, in which * stands for
+// any run of characters, slashes included, so "*" matches every method
+func matchMethod(pattern, method string) bool {
+	prefix, rest, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == method
+	}
+	if !strings.HasPrefix(method, prefix) {
+		return false
+	}
+	method = method[len(prefix):]
+	for {
+		if matchMethod(rest, method) {
+			return true
+		}
+		if method == "" {
+			return false
+		}
+		method = method[1:]
+	}
+}
+
+// logPayload logs the payload of a message for method, redacted like the
+// recorded traffic, if the payloads of method are logged. format receives
+// the method and the payload. The methods logged and the redaction come
+// from the same configuration, even while it changes.
+func (s *MockLSPServer) logPayload(ctx context.Context, format, method string, payload json.RawMessage) {
+	snapshot := s.snapshot()
+	if payload == nil || !logsPayload(snapshot.config, method) {
+		return
+	}
+	s.logInfo(ctx, format, method, *snapshot.redactor.redactJSON(&payload))
+}
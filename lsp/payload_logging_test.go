@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of a server
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPayloadLogging(t *testing.T) {
+	tests := []struct {
+		name      string
+		methods   []string
+		redaction string
+		want      []string
+		unwanted  []string
+	}{
+		{
+			"selected method",
+			[]string{"textDocument/hover"},
+			"",
+			[]string{`Received textDocument/hover params: {"position":{"character":0,"line":0},"textDocument":{"uri":"file:///payload.txt"}}`, "Sending textDocument/hover result: {"},
+			[]string{"secret didOpen text"},
+		},
+		{
+			"glob",
+			[]string{"textDocument/did*"},
+			"",
+			[]string{"secret didOpen text"},
+			[]string{"Sending textDocument/hover result"},
+		},
+		{
+			"redacted",
+			[]string{"*"},
+			config.RedactionElide,
+			[]string{"Received textDocument/didOpen params", "Sending textDocument/hover result", "[redacted len="},
+			[]string{"secret didOpen text"},
+		},
+		{"nothing selected", nil, "", nil, []string{" params: ", " result: "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs lockedBuffer
+			server := NewMockLSPServer(log.New(&logs, "", 0))
+			cfg := config.DefaultConfig()
+			cfg.Logging.PayloadMethods = tt.methods
+			cfg.LSP.Redaction.Mode = tt.redaction
+			server.SetConfig(cfg)
+			client := connectTestClient(t, server, nil)
+			ctx := context.Background()
+
+			open := map[string]any{"textDocument": map[string]any{"uri": "file:///payload.txt", "languageId": "plaintext", "version": 1, "text": "secret didOpen text"}}
+			if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+				t.Fatalf("didOpen failed: %v", err)
+			}
+			params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///payload.txt"}}
+			if err := client.Call(ctx, "textDocument/hover", params, nil); err != nil {
+				t.Fatalf("Hover failed: %v", err)
+			}
+
+			got := logs.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected the logs to contain %q, got:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Expected the logs not to contain %q, got:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadLoggingWhileReconfiguring(t *testing.T) {
+	var logs lockedBuffer
+	server := NewMockLSPServer(log.New(&logs, "", 0))
+	// Payloads are either logged redacted or not logged at all
+	redacted := config.DefaultConfig()
+	redacted.Logging.PayloadMethods = []string{"*"}
+	redacted.LSP.Redaction.Mode = config.RedactionElide
+	quiet := config.DefaultConfig()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			if i%2 == 0 {
+				server.SetConfig(redacted)
+			} else {
+				server.SetConfig(quiet)
+			}
+		}
+	}()
+	ctx := context.Background()
+	payload := json.RawMessage(`{"text":"secret payload text"}`)
+	for range 200 {
+		server.logPayload(ctx, "Received %s params: %s", "textDocument/didOpen", payload)
+	}
+	<-done
+
+	if strings.Contains(logs.String(), "secret payload text") {
+		t.Error("Expected no payload to be logged unredacted")
+	}
+}
+
+func TestMatchMethod(t *testing.T) {
+	tests := []struct {
+		pattern, method string
+		want            bool
+	}{
+		{"textDocument/hover", "textDocument/hover", true},
+		{"textDocument/hover", "textDocument/hoverX", false},
+		{"*", "initialize", true},
+		{"*", "textDocument/hover", true},
+		{"workspace/*", "workspace/symbol", true},
+		{"workspace/*", "textDocument/hover", false},
+		{"textDocument/did*", "textDocument/didChange", true},
+		{"*/did*e", "textDocument/didClose", true},
+		{"*/did*e", "textDocument/didOpen", false},
+	}
+	for _, tt := range tests {
+		if got := matchMethod(tt.pattern, tt.method); got != tt.want {
+			t.Errorf("matchMethod(%q, %q) = %v, want %v", tt.pattern, tt.method, got, tt.want)
+		}
+	}
+}
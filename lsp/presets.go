@@ -0,0 +1,403 @@
+package lsp
+
+import (
+	"fmt"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// publishDiagnosticsMethod is the key used to select a preset for the
+// diagnostics pushed after didOpen/didChange
+const publishDiagnosticsMethod = "textDocument/publishDiagnostics"
+
+// Identifiers used by the surrogate-pairs preset. Every rune outside the
+// Basic Multilingual Plane takes two UTF-16 code units, so ranges computed
+// from byte or rune counts come out wrong.
+const (
+	surrogateIdentifier = "𝒇𝒐𝒐"
+	surrogateEmoji      = "😀"
+)
+
+// presetParams holds the request fields presets use to build a response
+type presetParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     protocol.Position               `json:"position"`
+}
+
+// presetResponse returns the response the named preset produces for method.
+// ok is false when the preset has no response for the method, in which case
+// the regular handler runs. reverseRanges swaps the ends of the ranges
+// produced by the extreme-positions preset.
+func presetResponse(preset, method string, params presetParams, reverseRanges bool) (result any, ok bool) {
+	switch preset {
+	case config.PresetEmptyEverything:
+		return emptyPresetResponse(method)
+	case config.PresetNullsEverywhere:
+		return nullPresetResponse(method)
+	case config.PresetSurrogatePairs:
+		return surrogatePresetResponse(method, params)
+	case config.PresetAllOptionalFieldsSet:
+		return allOptionalPresetResponse(method, params)
+	case config.PresetExtremePositions:
+		return extremePresetResponse(method, params, reverseRanges)
+	}
+	return nil, false
+}
+
+// presetDiagnostics returns the diagnostics the named preset publishes for uri
+func presetDiagnostics(preset string, uri protocol.DocumentUri, reverseRanges bool) ([]protocol.Diagnostic, bool) {
+	switch preset {
+	case config.PresetEmptyEverything:
+		return []protocol.Diagnostic{}, true
+	case config.PresetNullsEverywhere:
+		// The diagnostics array itself is required, so the closest legal
+		// shape is a diagnostic with an empty message and every optional
+		// field omitted
+		return []protocol.Diagnostic{
+			{
+				Range:   protocol.Range{},
+				Message: "",
+			},
+		}, true
+	case config.PresetSurrogatePairs:
+		message := surrogateEmoji + " " + surrogateIdentifier + " is never used"
+		return []protocol.Diagnostic{
+			{
+				Range:   surrogateRange(protocol.Position{}, surrogateIdentifier),
+				Message: message,
+				Source:  "mock-lsp " + surrogateEmoji,
+			},
+		}, true
+	case config.PresetAllOptionalFieldsSet:
+		return allOptionalDiagnostics(uri), true
+	case config.PresetExtremePositions:
+		return extremeDiagnostics(reverseRanges), true
+	}
+	return nil, false
+}
+
+// surrogateRange returns the range text occupies when inserted at start,
+// measured in UTF-16 code units
+func surrogateRange(start protocol.Position, text string) protocol.Range {
+	return protocol.Range{
+		Start: start,
+		End: protocol.Position{
+			Line:      start.Line,
+			Character: start.Character + utf16Len(text),
+		},
+	}
+}
+
+// emptyPresetResponse answers with empty but non-null results
+func emptyPresetResponse(method string) (any, bool) {
+	switch method {
+	case "textDocument/completion":
+		return protocol.CompletionList{Items: []protocol.CompletionItem{}}, true
+	case "textDocument/hover":
+		return protocol.Hover{
+			Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+				Value: protocol.MarkupContent{Kind: protocol.MarkupKindPlainText, Value: ""},
+			},
+		}, true
+	case "textDocument/definition", "textDocument/references":
+		return []protocol.Location{}, true
+	case "textDocument/documentSymbol":
+		return []protocol.DocumentSymbol{}, true
+	}
+	return nil, false
+}
+
+// nullPresetResponse answers with null wherever the spec allows it
+func nullPresetResponse(method string) (any, bool) {
+	switch method {
+	case "textDocument/completion",
+		"textDocument/hover",
+		"textDocument/definition",
+		"textDocument/references",
+		"textDocument/documentSymbol":
+		return nil, true
+	}
+	return nil, false
+}
+
+// surrogatePresetResponse answers with astral-plane text and ranges
+// measured in UTF-16 code units
+func surrogatePresetResponse(method string, params presetParams) (any, bool) {
+	uri := params.TextDocument.Uri
+	position := params.Position
+
+	switch method {
+	case "textDocument/completion":
+		labels := []string{surrogateIdentifier, surrogateEmoji + "smile", "𝔘𝔫𝔦𝔠𝔬𝔡𝔢"}
+		items := make([]protocol.CompletionItem, len(labels))
+		for i, label := range labels {
+			items[i] = protocol.CompletionItem{
+				Label:  label,
+				Detail: surrogateEmoji + " surrogate pair completion",
+				TextEdit: &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{
+					Value: protocol.TextEdit{
+						Range:   protocol.Range{Start: position, End: position},
+						NewText: label,
+					},
+				},
+			}
+		}
+		return protocol.CompletionList{Items: items}, true
+	case "textDocument/hover":
+		rng := surrogateRange(position, surrogateIdentifier)
+		return protocol.Hover{
+			Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+				Value: protocol.MarkupContent{
+					Kind:  protocol.MarkupKindMarkdown,
+					Value: "**" + surrogateIdentifier + "** " + surrogateEmoji + "\n\n𝔥𝔬𝔳𝔢𝔯 text outside the BMP",
+				},
+			},
+			Range: &rng,
+		}, true
+	case "textDocument/definition", "textDocument/references":
+		return []protocol.Location{
+			{Uri: uri, Range: surrogateRange(position, surrogateIdentifier)},
+			{Uri: uri, Range: surrogateRange(protocol.Position{Line: position.Line + 1}, surrogateEmoji+surrogateIdentifier)},
+		}, true
+	case "textDocument/documentSymbol":
+		name := surrogateEmoji + surrogateIdentifier
+		rng := surrogateRange(protocol.Position{}, name)
+		return []protocol.DocumentSymbol{
+			{
+				Name:           name,
+				Detail:         "𝔘𝔫𝔦𝔠𝔬𝔡𝔢",
+				Kind:           protocol.SymbolKindFunction,
+				Range:          rng,
+				SelectionRange: rng,
+			},
+		}, true
+	}
+	return nil, false
+}
+
+// allOptionalPresetResponse answers with every optional field populated and
+// the least common alternative of each union type
+func allOptionalPresetResponse(method string, params presetParams) (any, bool) {
+	uri := params.TextDocument.Uri
+	position := params.Position
+	rng := surrogateRange(position, "mockOptional")
+
+	switch method {
+	case "textDocument/completion":
+		kind := protocol.CompletionItemKindFunction
+		format := protocol.InsertTextFormatSnippet
+		mode := protocol.InsertTextModeAdjustIndentation
+		merge := protocol.ApplyKindMerge
+		return protocol.CompletionList{
+			IsIncomplete: true,
+			ApplyKind: &protocol.CompletionItemApplyKinds{
+				CommitCharacters: &merge,
+				Data:             &merge,
+			},
+			ItemDefaults: &protocol.CompletionItemDefaults{
+				CommitCharacters: []string{".", "("},
+				Data:             map[string]any{"preset": config.PresetAllOptionalFieldsSet},
+				EditRange: &protocol.Or2[protocol.Range, protocol.EditRangeWithInsertReplace]{
+					Value: protocol.EditRangeWithInsertReplace{Insert: rng, Replace: rng},
+				},
+				InsertTextFormat: &format,
+				InsertTextMode:   &mode,
+			},
+			Items: []protocol.CompletionItem{
+				{
+					Label: "mockOptional",
+					LabelDetails: &protocol.CompletionItemLabelDetails{
+						Detail:      "(arg)",
+						Description: "mock package",
+					},
+					Kind:             &kind,
+					Tags:             []protocol.CompletionItemTag{protocol.CompletionItemTagDeprecated},
+					Detail:           "Completion with every optional field set",
+					Documentation:    &protocol.Or2[string, protocol.MarkupContent]{Value: protocol.MarkupContent{Kind: protocol.MarkupKindMarkdown, Value: "**mockOptional**"}},
+					Deprecated:       true,
+					Preselect:        true,
+					SortText:         "0000",
+					FilterText:       "mockOptional",
+					InsertText:       "mockOptional(${1:arg})",
+					InsertTextFormat: &format,
+					InsertTextMode:   &mode,
+					TextEdit: &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{
+						Value: protocol.InsertReplaceEdit{NewText: "mockOptional(${1:arg})", Insert: rng, Replace: rng},
+					},
+					TextEditText: "mockOptional(${1:arg})",
+					AdditionalTextEdits: []protocol.TextEdit{
+						{Range: protocol.Range{}, NewText: "import \"mock\"\n"},
+					},
+					CommitCharacters: []string{"("},
+					Command: &protocol.Command{
+						Title:     "Trigger parameter hints",
+						Command:   "editor.action.triggerParameterHints",
+						Tooltip:   "Show signature help",
+						Arguments: []any{"mockOptional"},
+					},
+					Data: map[string]any{"preset": config.PresetAllOptionalFieldsSet},
+				},
+			},
+		}, true
+	case "textDocument/hover":
+		return protocol.Hover{
+			Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+				Value: []protocol.MarkedString{
+					{Value: "Deprecated MarkedString array form"},
+					{Value: protocol.MarkedStringWithLanguage{Language: "go", Value: "func mockOptional(arg string)"}},
+				},
+			},
+			Range: &rng,
+		}, true
+	case "textDocument/definition":
+		return []protocol.LocationLink{
+			{
+				OriginSelectionRange: &rng,
+				TargetUri:            uri,
+				TargetRange:          protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 2}},
+				TargetSelectionRange: protocol.Range{Start: protocol.Position{Line: 0, Character: 5}, End: protocol.Position{Line: 0, Character: 17}},
+			},
+		}, true
+	case "textDocument/references":
+		return []protocol.Location{{Uri: uri, Range: rng}}, true
+	case "textDocument/documentSymbol":
+		child := protocol.DocumentSymbol{
+			Name:           "arg",
+			Detail:         "string",
+			Kind:           protocol.SymbolKindVariable,
+			Tags:           []protocol.SymbolTag{protocol.SymbolTagDeprecated},
+			Deprecated:     true,
+			Range:          protocol.Range{Start: protocol.Position{Line: 0, Character: 18}, End: protocol.Position{Line: 0, Character: 28}},
+			SelectionRange: protocol.Range{Start: protocol.Position{Line: 0, Character: 18}, End: protocol.Position{Line: 0, Character: 21}},
+		}
+		return []protocol.DocumentSymbol{
+			{
+				Name:           "mockOptional",
+				Detail:         "func(arg string)",
+				Kind:           protocol.SymbolKindFunction,
+				Tags:           []protocol.SymbolTag{protocol.SymbolTagDeprecated},
+				Deprecated:     true,
+				Range:          protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 2}},
+				SelectionRange: protocol.Range{Start: protocol.Position{Line: 0, Character: 5}, End: protocol.Position{Line: 0, Character: 17}},
+				Children:       []protocol.DocumentSymbol{child},
+			},
+		}, true
+	}
+	return nil, false
+}
+
+// allOptionalDiagnostics returns a diagnostic with every optional field set
+func allOptionalDiagnostics(uri protocol.DocumentUri) []protocol.Diagnostic {
+	severity := protocol.DiagnosticSeverityHint
+	rng := protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 10}}
+	return []protocol.Diagnostic{
+		{
+			Range:           rng,
+			Severity:        &severity,
+			Code:            &protocol.Or2[int32, string]{Value: "MOCK001"},
+			CodeDescription: &protocol.CodeDescription{Href: "https://example.com/diagnostics/MOCK001"},
+			Source:          diagnosticSource,
+			Message:         "Diagnostic with every optional field set",
+			Tags:            []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary, protocol.DiagnosticTagDeprecated},
+			RelatedInformation: []protocol.DiagnosticRelatedInformation{
+				{
+					Location: protocol.Location{Uri: uri, Range: rng},
+					Message:  "Related information",
+				},
+			},
+			Data: map[string]any{"preset": config.PresetAllOptionalFieldsSet},
+		},
+	}
+}
+
+// Positions used by the extreme-positions preset
+const (
+	extremeLine      = 1_000_000
+	extremeCharacter = 100_000
+	// maxUinteger is the largest value the spec allows for a uinteger
+	maxUinteger = 1<<31 - 1
+)
+
+// extremeRanges returns ranges far beyond any realistic document, a
+// zero-width range and one at the spec's uinteger limit. With reverse set,
+// each non-empty range has its start and end swapped.
+func extremeRanges(reverse bool) []protocol.Range {
+	ranges := []protocol.Range{
+		{Start: protocol.Position{Line: extremeLine, Character: 0}, End: protocol.Position{Line: extremeLine, Character: 10}},
+		{Start: protocol.Position{Line: 0, Character: extremeCharacter}, End: protocol.Position{Line: 0, Character: extremeCharacter + 10}},
+		{Start: protocol.Position{Line: extremeLine, Character: extremeCharacter}, End: protocol.Position{Line: extremeLine + 1, Character: 0}},
+		{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 5}},
+		{Start: protocol.Position{Line: maxUinteger, Character: maxUinteger - 1}, End: protocol.Position{Line: maxUinteger, Character: maxUinteger}},
+	}
+	if reverse {
+		for i, rng := range ranges {
+			ranges[i] = protocol.Range{Start: rng.End, End: rng.Start}
+		}
+	}
+	return ranges
+}
+
+// extremePresetResponse answers with positions at the far edges of what
+// clients must clamp
+func extremePresetResponse(method string, params presetParams, reverse bool) (any, bool) {
+	uri := params.TextDocument.Uri
+	ranges := extremeRanges(reverse)
+
+	switch method {
+	case "textDocument/completion":
+		items := make([]protocol.CompletionItem, len(ranges))
+		for i, rng := range ranges {
+			label := fmt.Sprintf("extremeCompletion%d", i)
+			items[i] = protocol.CompletionItem{
+				Label: label,
+				TextEdit: &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{
+					Value: protocol.TextEdit{Range: rng, NewText: label},
+				},
+			}
+		}
+		return protocol.CompletionList{Items: items}, true
+	case "textDocument/hover":
+		rng := ranges[0]
+		return protocol.Hover{
+			Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+				Value: protocol.MarkupContent{Kind: protocol.MarkupKindPlainText, Value: "Hover range far outside the document"},
+			},
+			Range: &rng,
+		}, true
+	case "textDocument/definition", "textDocument/references":
+		locations := make([]protocol.Location, len(ranges))
+		for i, rng := range ranges {
+			locations[i] = protocol.Location{Uri: uri, Range: rng}
+		}
+		return locations, true
+	case "textDocument/documentSymbol":
+		symbols := make([]protocol.DocumentSymbol, len(ranges))
+		for i, rng := range ranges {
+			symbols[i] = protocol.DocumentSymbol{
+				Name:           fmt.Sprintf("extremeSymbol%d", i),
+				Kind:           protocol.SymbolKindVariable,
+				Range:          rng,
+				SelectionRange: rng,
+			}
+		}
+		return symbols, true
+	}
+	return nil, false
+}
+
+// extremeDiagnostics returns one diagnostic per extreme range
+func extremeDiagnostics(reverse bool) []protocol.Diagnostic {
+	ranges := extremeRanges(reverse)
+	diagnostics := make([]protocol.Diagnostic, len(ranges))
+	for i, rng := range ranges {
+		severity := protocol.DiagnosticSeverityWarning
+		diagnostics[i] = protocol.Diagnostic{
+			Range:    rng,
+			Severity: &severity,
+			Message:  fmt.Sprintf("Diagnostic at line %d, character %d", rng.Start.Line, rng.Start.Character),
+			Source:   diagnosticSource,
+		}
+	}
+	return diagnostics
+}
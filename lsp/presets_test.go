@@ -0,0 +1,160 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+var presetMethods = []string{
+	"textDocument/completion",
+	"textDocument/hover",
+	"textDocument/definition",
+	"textDocument/references",
+	"textDocument/documentSymbol",
+}
+
+func TestPresetResponse_AllMethodsEncode(t *testing.T) {
+	params := presetParams{
+		TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///preset.go"},
+		Position:     protocol.Position{Line: 3, Character: 4},
+	}
+
+	for _, preset := range config.ResponsePresets {
+		for _, method := range presetMethods {
+			result, ok := presetResponse(preset, method, params, false)
+			if !ok {
+				t.Errorf("Expected preset %s to answer %s", preset, method)
+				continue
+			}
+			data, err := encodeWire(result)
+			if err != nil {
+				t.Errorf("Failed to encode %s response for %s: %v", preset, method, err)
+				continue
+			}
+			if preset == config.PresetNullsEverywhere && string(data) != "null" {
+				t.Errorf("Expected null for %s, got %s", method, data)
+			}
+		}
+
+		diagnostics, ok := presetDiagnostics(preset, params.TextDocument.Uri, false)
+		if !ok || diagnostics == nil {
+			t.Errorf("Expected non-null diagnostics for preset %s", preset)
+		}
+	}
+
+	if _, ok := presetResponse(config.PresetEmptyEverything, "initialize", params, false); ok {
+		t.Error("Expected presets not to apply to initialize")
+	}
+}
+
+func TestPresetResponse_SurrogatePairRanges(t *testing.T) {
+	params := presetParams{
+		TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///preset.go"},
+		Position:     protocol.Position{Line: 2, Character: 10},
+	}
+
+	result, _ := presetResponse(config.PresetSurrogatePairs, "textDocument/hover", params, false)
+	hover := result.(protocol.Hover)
+	if hover.Range == nil {
+		t.Fatal("Expected hover range")
+	}
+	if hover.Range.End.Character != 16 {
+		t.Errorf("Expected hover range to end at UTF-16 offset 16, got %d", hover.Range.End.Character)
+	}
+
+	result, _ = presetResponse(config.PresetSurrogatePairs, "textDocument/documentSymbol", params, false)
+	symbol := result.([]protocol.DocumentSymbol)[0]
+	if symbol.Range.End.Character != utf16Len(symbol.Name) {
+		t.Errorf("Expected symbol range width %d, got %d", utf16Len(symbol.Name), symbol.Range.End.Character)
+	}
+}
+
+func TestPresetsOverWire(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Presets = map[string]string{
+		"textDocument/hover":          config.PresetNullsEverywhere,
+		"textDocument/completion":     config.PresetEmptyEverything,
+		publishDiagnosticsMethod:      config.PresetAllOptionalFieldsSet,
+		"textDocument/documentSymbol": config.PresetAllOptionalFieldsSet,
+	}
+	server.SetConfig(cfg)
+
+	diagnostics := make(chan protocol.PublishDiagnosticsParams, 1)
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		if req.Method == publishDiagnosticsMethod {
+			var params protocol.PublishDiagnosticsParams
+			if err := json.Unmarshal(*req.Params, &params); err == nil {
+				diagnostics <- params
+			}
+		}
+	})
+	ctx := context.Background()
+
+	var hover json.RawMessage
+	if err := client.Call(ctx, "textDocument/hover", protocol.HoverParams{}, &hover); err != nil {
+		t.Fatalf("Hover request failed: %v", err)
+	}
+	if string(hover) != "null" {
+		t.Errorf("Expected null hover, got %s", hover)
+	}
+
+	var list protocol.CompletionList
+	if err := client.Call(ctx, "textDocument/completion", protocol.CompletionParams{}, &list); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+	if list.Items == nil || len(list.Items) != 0 {
+		t.Errorf("Expected empty non-null completion items, got %v", list.Items)
+	}
+
+	var symbols []protocol.DocumentSymbol
+	if err := client.Call(ctx, "textDocument/documentSymbol", protocol.DocumentSymbolParams{}, &symbols); err != nil {
+		t.Fatalf("Document symbol request failed: %v", err)
+	}
+	if len(symbols) != 1 || len(symbols[0].Tags) == 0 || len(symbols[0].Children) == 0 {
+		t.Errorf("Expected fully populated document symbol, got %+v", symbols)
+	}
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///preset.go", LanguageId: "go", Text: "package main\n"},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	published := <-diagnostics
+	if len(published.Diagnostics) != 1 || published.Diagnostics[0].CodeDescription == nil {
+		t.Errorf("Expected fully populated diagnostic, got %+v", published.Diagnostics)
+	}
+}
+
+func TestPresetResponse_ExtremePositions(t *testing.T) {
+	params := presetParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///preset.go"}}
+
+	result, ok := presetResponse(config.PresetExtremePositions, "textDocument/documentSymbol", params, false)
+	if !ok {
+		t.Fatal("Expected extreme-positions to answer documentSymbol")
+	}
+	var sawFarLine, sawFarCharacter, sawZeroWidth bool
+	for _, symbol := range result.([]protocol.DocumentSymbol) {
+		if !rangeIsOrdered(symbol.Range) {
+			t.Errorf("Expected ordered range without reversal, got %+v", symbol.Range)
+		}
+		sawFarLine = sawFarLine || symbol.Range.Start.Line >= extremeLine
+		sawFarCharacter = sawFarCharacter || symbol.Range.Start.Character >= extremeCharacter
+		sawZeroWidth = sawZeroWidth || symbol.Range.Start == symbol.Range.End
+	}
+	if !sawFarLine || !sawFarCharacter || !sawZeroWidth {
+		t.Errorf("Expected far line, far character and zero-width ranges, got %v %v %v", sawFarLine, sawFarCharacter, sawZeroWidth)
+	}
+
+	for _, diagnostic := range extremeDiagnostics(true) {
+		if diagnostic.Range.Start != diagnostic.Range.End && rangeIsOrdered(diagnostic.Range) {
+			t.Errorf("Expected reversed range, got %+v", diagnostic.Range)
+		}
+	}
+}
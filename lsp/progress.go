@@ -0,0 +1,137 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// progressMethod is the notification carrying partial results
+const progressMethod = "$/progress"
+
+// defaultPartialResultChunk is the number of items sent in each $/progress
+// notification of a partial result stream
+const defaultPartialResultChunk = 1000
+
+// partialResultStream sends a large result as a series of $/progress
+// notifications of chunkSize items. It encodes each chunk into one buffer
+// reused for the whole stream, straight from the items, so streaming a
+// result never holds more than a chunk in memory.
+type partialResultStream struct {
+	server    *MockLSPServer
+	conn      Conn
+	token     protocol.ProgressToken
+	chunkSize int
+	delay     time.Duration // Pause before every chunk but the first
+	flushed   int           // Chunks sent
+	encoder   wireEncoder
+	prefix    []byte // `{"token":<token>,"value":[`, encoded once
+}
+
+// newPartialResultStream prepares a stream of partial results for the
+// partialResultToken the client sent. A chunk size below one uses
+// defaultPartialResultChunk.
+func (s *MockLSPServer) newPartialResultStream(ctx context.Context, conn Conn, token protocol.ProgressToken, chunkSize int) (*partialResultStream, error) {
+	if chunkSize < 1 {
+		chunkSize = defaultPartialResultChunk
+	}
+	stream := &partialResultStream{server: s, conn: conn, token: token, chunkSize: chunkSize}
+	stream.encoder.extra = s.traceExtra(ctx, progressMethod)
+
+	stream.encoder.buf.WriteString(`{"token":`)
+	if err := stream.encoder.writeValue(reflect.ValueOf(token)); err != nil {
+		return nil, err
+	}
+	stream.encoder.buf.WriteString(`,"value":[`)
+	stream.prefix = append([]byte(nil), stream.encoder.buf.Bytes()...)
+	return stream, nil
+}
+
+// streamPartialResults sends the items in chunks over stream and returns
+// how many were sent. Items are encoded as they are produced, so a
+// generator never has to build the full result.
+func streamPartialResults[T any](ctx context.Context, stream *partialResultStream, items iter.Seq[T]) (int, error) {
+	sent, pending := 0, 0
+	stream.startChunk()
+	for item := range items {
+		if pending > 0 {
+			stream.encoder.buf.WriteByte(',')
+		}
+		if err := stream.encoder.writeValue(reflect.ValueOf(item)); err != nil {
+			return sent, err
+		}
+		pending++
+		if pending == stream.chunkSize {
+			if err := stream.flush(ctx); err != nil {
+				return sent, err
+			}
+			sent += pending
+			pending = 0
+			stream.startChunk()
+		}
+	}
+	if pending > 0 {
+		if err := stream.flush(ctx); err != nil {
+			return sent, err
+		}
+		sent += pending
+	}
+	return sent, nil
+}
+
+// startChunk resets the reused buffer to the start of a $/progress
+// notification, keeping its capacity
+func (p *partialResultStream) startChunk() {
+	p.encoder.buf.Reset()
+	p.encoder.buf.Write(p.prefix)
+}
+
+// flush closes the chunk in the buffer and sends it, after the configured
+// delay unless it is the first chunk. Partial results must reach the client
+// before the response, so they skip the notification queue. Notify copies
+// the params before returning, so the buffer can be reused.
+func (p *partialResultStream) flush(ctx context.Context) error {
+	if p.flushed > 0 && p.delay > 0 {
+		timer := time.NewTimer(p.delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	p.flushed++
+	p.encoder.buf.WriteString("]}")
+	return p.conn.Notify(ctx, progressMethod, json.RawMessage(p.encoder.buf.Bytes()))
+}
+
+// replyPartialResults answers a request for a list with items. When the
+// client sent a partialResultToken, the items are streamed as partial
+// results as they are produced, in chunks of the configured size, and the
+// response is an empty list, as the specification requires once partial
+// results were reported. Without a token the items are collected and sent
+// whole.
+func replyPartialResults[T any](ctx context.Context, s *MockLSPServer, conn Conn, req *jsonrpc.Request, token *protocol.ProgressToken, items iter.Seq[T]) error {
+	if token == nil {
+		return s.reply(ctx, conn, req, slices.AppendSeq([]T{}, items))
+	}
+
+	cfg := s.config().LSP.PartialResults
+	stream, err := s.newPartialResultStream(ctx, conn, *token, cfg.ChunkSize)
+	if err != nil {
+		return err
+	}
+	stream.delay = cfg.Delay.Duration()
+	sent, err := streamPartialResults(ctx, stream, items)
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	s.logInfo(ctx, "Streamed %d %s results in %d partial results", sent, req.Method, stream.flushed)
+	return s.reply(ctx, conn, req, []T{})
+}
@@ -0,0 +1,270 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+// mockWorkspaceSymbols generates n workspace symbols one at a time
+func mockWorkspaceSymbols(n int) iter.Seq[protocol.SymbolInformation] {
+	return func(yield func(protocol.SymbolInformation) bool) {
+		for i := range n {
+			line := uint32(i % 1000)
+			symbol := protocol.SymbolInformation{
+				Name: fmt.Sprintf("MockSymbol%d", i),
+				Kind: protocol.SymbolKindFunction,
+				Location: protocol.Location{
+					Uri: protocol.DocumentUri(fmt.Sprintf("file:///workspace/file%d.go", i%100)),
+					Range: protocol.Range{
+						Start: protocol.Position{Line: line, Character: 0},
+						End:   protocol.Position{Line: line, Character: 10},
+					},
+				},
+			}
+			if !yield(symbol) {
+				return
+			}
+		}
+	}
+}
+
+// progressConn connects a client passing every notification it receives to
+// notify and returns the server side of the connection
+func progressConn(tb testing.TB, server *MockLSPServer, notify func(*jsonrpc.Request)) jsonrpc.Conn {
+	tb.Helper()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server)
+	client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+			notify(req)
+			return nil, nil
+		}))
+	tb.Cleanup(func() {
+		client.Close()
+		serverConn.Close()
+	})
+	return serverConn
+}
+
+func TestStreamPartialResults(t *testing.T) {
+	server := createTestServer()
+	var mu sync.Mutex
+	var chunks []protocol.ProgressParams
+	var symbols []protocol.SymbolInformation
+	done := make(chan struct{})
+	conn := progressConn(t, server, func(req *jsonrpc.Request) {
+		var params struct {
+			Token protocol.ProgressToken       `json:"token"`
+			Value []protocol.SymbolInformation `json:"value"`
+		}
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			t.Errorf("Failed to decode %s: %v", req.Method, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		chunks = append(chunks, protocol.ProgressParams{Token: params.Token, Value: len(params.Value)})
+		symbols = append(symbols, params.Value...)
+		if len(symbols) == 2500 {
+			close(done)
+		}
+	})
+
+	ctx := context.Background()
+	token := protocol.ProgressToken{Value: "symbols-1"}
+	stream, err := server.newPartialResultStream(ctx, conn, token, 1000)
+	if err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+	sent, err := streamPartialResults(ctx, stream, mockWorkspaceSymbols(2500))
+	if err != nil {
+		t.Fatalf("Streaming failed: %v", err)
+	}
+	if sent != 2500 {
+		t.Errorf("Expected 2500 items sent, got %d", sent)
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantSizes := []int{1000, 1000, 500}
+	if len(chunks) != len(wantSizes) {
+		t.Fatalf("Expected %d chunks, got %d", len(wantSizes), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Token.Value != "symbols-1" {
+			t.Errorf("Expected token symbols-1 in chunk %d, got %v", i, chunk.Token.Value)
+		}
+		if chunk.Value != wantSizes[i] {
+			t.Errorf("Expected %d items in chunk %d, got %v", wantSizes[i], i, chunk.Value)
+		}
+	}
+	for i, symbol := range symbols {
+		if want := fmt.Sprintf("MockSymbol%d", i); symbol.Name != want {
+			t.Fatalf("Expected %s at position %d, got %s", want, i, symbol.Name)
+		}
+	}
+}
+
+func TestStreamPartialResultsEmpty(t *testing.T) {
+	server := createTestServer()
+	var received []string
+	conn := progressConn(t, server, func(req *jsonrpc.Request) {
+		received = append(received, req.Method)
+	})
+
+	ctx := context.Background()
+	stream, err := server.newPartialResultStream(ctx, conn, protocol.ProgressToken{Value: int32(7)}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+	if stream.chunkSize != defaultPartialResultChunk {
+		t.Errorf("Expected the default chunk size, got %d", stream.chunkSize)
+	}
+	sent, err := streamPartialResults(ctx, stream, mockWorkspaceSymbols(0))
+	if err != nil || sent != 0 {
+		t.Errorf("Expected nothing sent, got %d, %v", sent, err)
+	}
+	if len(received) != 0 {
+		t.Errorf("Expected no notifications for an empty result, got %v", received)
+	}
+}
+
+func TestReplyPartialResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     any
+		progress  int
+		locations int
+	}{
+		{name: "without token", locations: 3},
+		{name: "with token", token: "refs-1", progress: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.PartialResults.ChunkSize = 1
+			server.SetConfig(cfg)
+			conn := lsptest.NewConn()
+			defer conn.Close()
+
+			params := map[string]any{
+				"textDocument": map[string]any{"uri": "file:///test.go"},
+				"position":     map[string]any{"line": 0, "character": 0},
+				"context":      map[string]any{"includeDeclaration": true},
+			}
+			if tt.token != nil {
+				params["partialResultToken"] = tt.token
+			}
+			req := testRequest(t, 1, "textDocument/references", params)
+			server.Dispatch(context.Background(), conn, req)
+
+			progress := conn.Sent(progressMethod)
+			if len(progress) != tt.progress {
+				t.Fatalf("Expected %d partial results, got %d", tt.progress, len(progress))
+			}
+			for i, m := range progress {
+				var chunk struct {
+					Token string              `json:"token"`
+					Value []protocol.Location `json:"value"`
+				}
+				if err := json.Unmarshal(m.Params, &chunk); err != nil {
+					t.Fatalf("Failed to decode partial result %d: %v", i, err)
+				}
+				if chunk.Token != tt.token || len(chunk.Value) != 1 {
+					t.Errorf("Expected one location for token %v in partial result %d, got %+v", tt.token, i, chunk)
+				}
+			}
+
+			response, ok := conn.Response(req.ID)
+			if !ok {
+				t.Fatal("Expected a response")
+			}
+			var locations []protocol.Location
+			if err := json.Unmarshal(response.Result, &locations); err != nil {
+				t.Fatalf("Failed to decode response %s: %v", response.Result, err)
+			}
+			if locations == nil || len(locations) != tt.locations {
+				t.Errorf("Expected %d locations in the response, got %s", tt.locations, response.Result)
+			}
+		})
+	}
+}
+
+func TestReplyPartialResultsDelay(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.PartialResults = config.PartialResultsConfig{ChunkSize: 1, Delay: config.Duration(5 * time.Millisecond)}
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+
+	params := map[string]any{"query": "", "partialResultToken": 5}
+	start := time.Now()
+	server.Dispatch(context.Background(), conn, testRequest(t, 1, "workspace/symbol", params))
+	chunks := len(conn.Sent(progressMethod))
+	if chunks < 2 {
+		t.Fatalf("Expected several partial results, got %d", chunks)
+	}
+	if elapsed, want := time.Since(start), time.Duration(chunks-1)*5*time.Millisecond; elapsed < want {
+		t.Errorf("Expected at least %s between partial results, took %s", want, elapsed)
+	}
+}
+
+// discardConn returns a server connection to a client discarding everything
+// it receives
+func discardConn(b *testing.B, server *MockLSPServer) jsonrpc.Conn {
+	b.Helper()
+	serverSide, clientSide := net.Pipe()
+	go io.Copy(io.Discard, clientSide)
+	conn := jsonrpc.NewConn(context.Background(), jsonrpc.NewStream(serverSide), server)
+	b.Cleanup(func() {
+		conn.Close()
+		clientSide.Close()
+	})
+	return conn
+}
+
+// BenchmarkWorkspaceSymbolPartialResults answers a workspace/symbol request
+// matching 100k symbols with a partialResultToken, so the result goes out
+// as $/progress chunks
+func BenchmarkWorkspaceSymbolPartialResults(b *testing.B) {
+	benchmarkWorkspaceSymbol(b, &protocol.ProgressToken{Value: "symbols"})
+}
+
+// BenchmarkWorkspaceSymbol answers the same request without a token, in a
+// single response, for comparison with
+// BenchmarkWorkspaceSymbolPartialResults
+func BenchmarkWorkspaceSymbol(b *testing.B) {
+	benchmarkWorkspaceSymbol(b, nil)
+}
+
+// benchmarkWorkspaceSymbol answers a workspace/symbol request matching 100k
+// symbols, streamed to token when it is set
+func benchmarkWorkspaceSymbol(b *testing.B, token *protocol.ProgressToken) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.WorkspaceSymbols = 100_000
+	server.SetConfig(cfg)
+	conn := discardConn(b, server)
+	req := testRequest(b, 1, "workspace/symbol", protocol.WorkspaceSymbolParams{PartialResultToken: token})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		server.handleWorkspaceSymbol(ctx, conn, req)
+	}
+}
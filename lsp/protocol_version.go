@@ -0,0 +1,249 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// Protocol versions the server can restrict itself to
+const (
+	Protocol316 = "3.16"
+	Protocol317 = "3.17"
+	Protocol318 = "3.18"
+)
+
+// ProtocolVersions lists the versions accepted by SetProtocolVersion, oldest
+// first
+var ProtocolVersions = []string{Protocol316, Protocol317, Protocol318}
+
+// methodsSince maps the methods introduced after 3.16 to the protocol
+// version that introduced them
+var methodsSince = map[string]string{
+	"textDocument/diagnostic":           Protocol317,
+	"workspace/diagnostic":              Protocol317,
+	"textDocument/inlayHint":            Protocol317,
+	"inlayHint/resolve":                 Protocol317,
+	"textDocument/inlineValue":          Protocol317,
+	"textDocument/prepareTypeHierarchy": Protocol317,
+	"typeHierarchy/supertypes":          Protocol317,
+	"typeHierarchy/subtypes":            Protocol317,
+	"notebookDocument/didOpen":          Protocol317,
+	"notebookDocument/didChange":        Protocol317,
+	"notebookDocument/didSave":          Protocol317,
+	"notebookDocument/didClose":         Protocol317,
+	"textDocument/inlineCompletion":     Protocol318,
+	"textDocument/rangesFormatting":     Protocol318,
+	"workspace/textDocumentContent":     Protocol318,
+}
+
+// capabilitiesSince lists the server capabilities introduced after 3.16,
+// with the protocol version that introduced them and how to leave them out
+var capabilitiesSince = []struct {
+	version string
+	remove  func(*protocol.ServerCapabilities)
+}{
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.PositionEncoding = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.NotebookDocumentSync = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.DiagnosticProvider = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.InlayHintProvider = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.InlineValueProvider = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) { c.TypeHierarchyProvider = nil }},
+	{Protocol317, func(c *protocol.ServerCapabilities) {
+		if c.CompletionProvider != nil {
+			c.CompletionProvider.CompletionItem = nil
+		}
+	}},
+	{Protocol318, func(c *protocol.ServerCapabilities) { c.InlineCompletionProvider = nil }},
+}
+
+// SetProtocolVersion restricts the capabilities, methods and response shapes
+// of the server to those of a protocol version in ProtocolVersions. An empty
+// version lifts the restriction.
+func (s *MockLSPServer) SetProtocolVersion(version string) error {
+	if version != "" && !slices.Contains(ProtocolVersions, version) {
+		return fmt.Errorf("unsupported protocol version %q, must be one of: %s", version, strings.Join(ProtocolVersions, ", "))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+	return nil
+}
+
+// ProtocolVersion returns the protocol version the server is restricted to,
+// or an empty string when it is not restricted
+func (s *MockLSPServer) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocolVersion
+}
+
+// supportsProtocol reports whether the server offers what the given protocol
+// version introduced
+func (s *MockLSPServer) supportsProtocol(since string) bool {
+	version := s.ProtocolVersion()
+	return version == "" || slices.Index(ProtocolVersions, since) <= slices.Index(ProtocolVersions, version)
+}
+
+// restrictCapabilities leaves out the capabilities newer than the protocol
+// version
+func (s *MockLSPServer) restrictCapabilities(capabilities *protocol.ServerCapabilities) {
+	for _, capability := range capabilitiesSince {
+		if !s.supportsProtocol(capability.version) {
+			capability.remove(capabilities)
+		}
+	}
+}
+
+// rejectNewerMethod answers requests for methods newer than the protocol
+// version with MethodNotFound and drops such notifications, as an older
+// server would. It returns true if the message was handled.
+func (s *MockLSPServer) rejectNewerMethod(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	since, ok := methodsSince[req.Method]
+	if !ok || s.supportsProtocol(since) {
+		return false
+	}
+
+	if req.Notif {
+		s.logInfo(ctx, "Ignoring %s notification: introduced in protocol %s", req.Method, since)
+		return true
+	}
+	s.logInfo(ctx, "Rejecting %s: introduced in protocol %s", req.Method, since)
+	s.replyMethodNotFound(ctx, conn, req)
+	return true
+}
+
+// downgradeResult removes the fields of a response that are newer than the
+// protocol version. Results of other types are returned unchanged.
+func (s *MockLSPServer) downgradeResult(result any) any {
+	switch list := result.(type) {
+	case protocol.CompletionList:
+		return s.downgradeCompletionList(list)
+	case *protocol.CompletionList:
+		if list != nil {
+			downgraded := s.downgradeCompletionList(*list)
+			return &downgraded
+		}
+	case []protocol.WorkspaceSymbol:
+		if !s.supportsProtocol(Protocol317) {
+			return symbolInformation(list)
+		}
+	}
+	return result
+}
+
+// symbolInformation converts workspace symbols to the symbol information
+// answering workspace/symbol before 3.17
+func symbolInformation(symbols []protocol.WorkspaceSymbol) []protocol.SymbolInformation {
+	info := make([]protocol.SymbolInformation, 0, len(symbols))
+	for _, symbol := range symbols {
+		location, _ := symbol.Location.Value.(protocol.Location)
+		info = append(info, protocol.SymbolInformation{
+			Name:          symbol.Name,
+			Kind:          symbol.Kind,
+			Tags:          symbol.Tags,
+			ContainerName: symbol.ContainerName,
+			Location:      location,
+		})
+	}
+	return info
+}
+
+// downgradeCompletionList returns a copy of list without the fields newer
+// than the protocol version, keeping what the client would have applied
+func (s *MockLSPServer) downgradeCompletionList(list protocol.CompletionList) protocol.CompletionList {
+	if s.supportsProtocol(Protocol318) {
+		return list
+	}
+	list.Items = slices.Clone(list.Items)
+	applyCompletionKinds(&list)
+	if !s.supportsProtocol(Protocol317) {
+		applyCompletionDefaults(&list)
+	}
+	return list
+}
+
+// applyCompletionKinds removes the apply kinds and command tooltips
+// introduced in 3.18. Commit characters and data the list asks to merge with
+// its defaults are merged into the items.
+func applyCompletionKinds(list *protocol.CompletionList) {
+	kinds, defaults := list.ApplyKind, list.ItemDefaults
+	list.ApplyKind = nil
+	for i := range list.Items {
+		item := &list.Items[i]
+		if kinds != nil && defaults != nil {
+			if kinds.CommitCharacters != nil && *kinds.CommitCharacters == protocol.ApplyKindMerge && item.CommitCharacters != nil {
+				merged := slices.Clone(defaults.CommitCharacters)
+				for _, char := range item.CommitCharacters {
+					if !slices.Contains(merged, char) {
+						merged = append(merged, char)
+					}
+				}
+				item.CommitCharacters = merged
+			}
+			itemData, itemIsMap := item.Data.(map[string]any)
+			defaultData, defaultIsMap := defaults.Data.(map[string]any)
+			if kinds.Data != nil && *kinds.Data == protocol.ApplyKindMerge && itemIsMap && defaultIsMap {
+				merged := maps.Clone(defaultData)
+				maps.Copy(merged, itemData)
+				item.Data = merged
+			}
+		}
+		if item.Command != nil && item.Command.Tooltip != "" {
+			command := *item.Command
+			command.Tooltip = ""
+			item.Command = &command
+		}
+	}
+}
+
+// applyCompletionDefaults removes the item defaults, label details and
+// textEditText introduced in 3.17. The defaults are moved into the items
+// that did not set their own.
+func applyCompletionDefaults(list *protocol.CompletionList) {
+	defaults := list.ItemDefaults
+	list.ItemDefaults = nil
+	for i := range list.Items {
+		item := &list.Items[i]
+		if defaults != nil {
+			if item.CommitCharacters == nil {
+				item.CommitCharacters = defaults.CommitCharacters
+			}
+			if item.Data == nil {
+				item.Data = defaults.Data
+			}
+			if item.InsertTextFormat == nil {
+				item.InsertTextFormat = defaults.InsertTextFormat
+			}
+			if item.InsertTextMode == nil {
+				item.InsertTextMode = defaults.InsertTextMode
+			}
+			if item.TextEdit == nil && defaults.EditRange != nil {
+				item.TextEdit = defaultTextEdit(*defaults.EditRange, item)
+			}
+		}
+		item.LabelDetails = nil
+		item.TextEditText = ""
+	}
+}
+
+// defaultTextEdit is the text edit of an item relying on the default edit
+// range of its list, which inserts its textEditText or else its label
+func defaultTextEdit(editRange protocol.Or2[protocol.Range, protocol.EditRangeWithInsertReplace], item *protocol.CompletionItem) *protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit] {
+	newText := item.TextEditText
+	if newText == "" {
+		newText = item.Label
+	}
+	switch rng := editRange.Value.(type) {
+	case protocol.Range:
+		return &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{Value: protocol.TextEdit{Range: rng, NewText: newText}}
+	case protocol.EditRangeWithInsertReplace:
+		return &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{Value: protocol.InsertReplaceEdit{NewText: newText, Insert: rng.Insert, Replace: rng.Replace}}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/scenario"
+)
+
+func TestSetProtocolVersion(t *testing.T) {
+	server := createTestServer()
+	if err := server.SetProtocolVersion("3.15"); err == nil {
+		t.Error("Expected an error for an unsupported protocol version")
+	}
+	if err := server.SetProtocolVersion(Protocol317); err != nil || server.ProtocolVersion() != Protocol317 {
+		t.Errorf("Expected protocol version 3.17, got %q (%v)", server.ProtocolVersion(), err)
+	}
+
+	capabilities := protocol.ServerCapabilities{
+		InlayHintProvider:        &protocol.Or3[bool, protocol.InlayHintOptions, protocol.InlayHintRegistrationOptions]{Value: true},
+		InlineCompletionProvider: &protocol.Or2[bool, protocol.InlineCompletionOptions]{Value: true},
+	}
+	server.restrictCapabilities(&capabilities)
+	if capabilities.InlayHintProvider == nil || capabilities.InlineCompletionProvider != nil {
+		t.Errorf("Expected 3.17 to keep inlay hints and drop inline completion, got %v", capabilityNames(capabilities))
+	}
+}
+
+func TestApplyCompletionDefaults(t *testing.T) {
+	rng := protocol.Range{End: protocol.Position{Character: 4}}
+	list := protocol.CompletionList{
+		ItemDefaults: &protocol.CompletionItemDefaults{
+			CommitCharacters: []string{"."},
+			EditRange:        &protocol.Or2[protocol.Range, protocol.EditRangeWithInsertReplace]{Value: rng},
+		},
+		Items: []protocol.CompletionItem{{Label: "mock", TextEditText: "mock()"}},
+	}
+	applyCompletionDefaults(&list)
+
+	item := list.Items[0]
+	edit, ok := item.TextEdit.Value.(protocol.TextEdit)
+	if !ok || edit.NewText != "mock()" || edit.Range != rng || item.TextEditText != "" {
+		t.Errorf("Expected the default edit range to become a text edit inserting mock(), got %+v", item)
+	}
+	if list.ItemDefaults != nil || !slices.Equal(item.CommitCharacters, []string{"."}) {
+		t.Errorf("Expected the default commit characters to move into the item, got %+v", list)
+	}
+}
+
+func TestSymbolInformation(t *testing.T) {
+	location := protocol.Location{Uri: "file:///mock-workspace/class.go", Range: protocol.Range{End: protocol.Position{Character: 10}}}
+	symbols := []protocol.WorkspaceSymbol{{
+		Name:          "mockClass1",
+		Kind:          protocol.SymbolKindClass,
+		ContainerName: "class",
+		Location:      protocol.Or2[protocol.Location, protocol.LocationUriOnly]{Value: location},
+	}}
+	info := symbolInformation(symbols)
+	if len(info) != 1 || info[0].Name != "mockClass1" || info[0].ContainerName != "class" || info[0].Location != location {
+		t.Errorf("Expected the workspace symbol as symbol information, got %+v", info)
+	}
+}
+
+func TestProtocolVersionDowngrade(t *testing.T) {
+	sc, err := scenario.Parse([]byte(`{"responses": [{"method": "textDocument/inlayHint", "result": []}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Presets = map[string]string{"textDocument/completion": config.PresetAllOptionalFieldsSet}
+	server.SetConfig(cfg)
+	server.SetScenario(sc)
+	if err := server.SetProtocolVersion(Protocol316); err != nil {
+		t.Fatalf("SetProtocolVersion failed: %v", err)
+	}
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	params := protocol.InlayHintParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///hints.txt"}}
+	if err := client.Call(ctx, "textDocument/inlayHint", params, nil); errorCode(err) != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound for a 3.17 method, got %v", err)
+	}
+
+	completion := protocol.CompletionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///complete.txt"}}
+	var list struct {
+		ItemDefaults json.RawMessage `json:"itemDefaults"`
+		ApplyKind    json.RawMessage `json:"applyKind"`
+		Items        []struct {
+			LabelDetails     json.RawMessage `json:"labelDetails"`
+			CommitCharacters []string        `json:"commitCharacters"`
+			Command          struct {
+				Tooltip string `json:"tooltip"`
+			} `json:"command"`
+		} `json:"items"`
+	}
+	if err := client.Call(ctx, "textDocument/completion", completion, &list); err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if list.ItemDefaults != nil || list.ApplyKind != nil || len(list.Items) == 0 {
+		t.Fatalf("Expected items without itemDefaults and applyKind, got %+v", list)
+	}
+	for i, item := range list.Items {
+		if item.LabelDetails != nil {
+			t.Errorf("Expected item %d without labelDetails, got %s", i, item.LabelDetails)
+		}
+		if !slices.Equal(item.CommitCharacters, []string{".", "("}) {
+			t.Errorf("Expected item %d to merge the default commit characters, got %v", i, item.CommitCharacters)
+		}
+		if item.Command.Tooltip != "" {
+			t.Errorf("Expected item %d without a command tooltip, got %q", i, item.Command.Tooltip)
+		}
+	}
+
+	if err := server.SetProtocolVersion(""); err != nil {
+		t.Fatalf("SetProtocolVersion failed: %v", err)
+	}
+	if err := client.Call(ctx, "textDocument/inlayHint", params, nil); err != nil {
+		t.Errorf("Expected the canned inlay hints once unrestricted, got %v", err)
+	}
+}
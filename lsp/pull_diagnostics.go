@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// diagnosticResult is the last diagnostics report pulled for a document, so
+// a later pull with its result ID can be answered as unchanged
+type diagnosticResult struct {
+	id   string
+	data []byte // Encoded diagnostics
+}
+
+// pushDiagnostics reports whether diagnostics are published to the client
+func (s *MockLSPServer) pushDiagnostics() bool {
+	mode := s.config().LSP.DiagnosticsConfig.Mode
+	return mode == "" || mode == config.DiagnosticModePush || mode == config.DiagnosticModeBoth
+}
+
+// pullDiagnostics reports whether the client can pull diagnostics
+func (s *MockLSPServer) pullDiagnostics() bool {
+	mode := s.config().LSP.DiagnosticsConfig.Mode
+	return mode == config.DiagnosticModePull || mode == config.DiagnosticModeBoth
+}
+
+// diagnosticReport returns the diagnostics of uri and the result ID
+// identifying them. The ID only changes when the diagnostics do, so
+// unchanged is true when previousResultID is still current.
+func (s *MockLSPServer) diagnosticReport(uri, previousResultID string) (items []protocol.Diagnostic, resultID string, unchanged bool, err error) {
+	items = s.mockDiagnostics(uri)
+	if items == nil {
+		items = []protocol.Diagnostic{}
+	}
+	data, err := encodeWire(items)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.diagnosticResults == nil {
+		s.diagnosticResults = make(map[string]diagnosticResult)
+	}
+	current, ok := s.diagnosticResults[uri]
+	if !ok || !bytes.Equal(current.data, data) {
+		s.diagnosticResultSeq++
+		current = diagnosticResult{id: fmt.Sprint(s.diagnosticResultSeq), data: data}
+		s.diagnosticResults[uri] = current
+	}
+	return items, current.id, previousResultID != "" && previousResultID == current.id, nil
+}
+
+// handleDocumentDiagnostic processes textDocument/diagnostic requests,
+// answering with an unchanged report when the client still has the current
+// diagnostics. Without pull diagnostics configured the method is not found.
+func (s *MockLSPServer) handleDocumentDiagnostic(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if !s.pullDiagnostics() {
+		s.replyMethodNotFound(ctx, conn, req)
+		return
+	}
+	var params protocol.DocumentDiagnosticParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse document diagnostic params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send document diagnostic error: %v", replyErr)
+		}
+		return
+	}
+
+	uri := string(params.TextDocument.Uri)
+	items, resultID, unchanged, err := s.diagnosticReport(uri, params.PreviousResultId)
+	if err != nil {
+		s.logError(ctx, "Failed to encode diagnostics of %s: %v", uri, err)
+	}
+
+	var report any
+	if unchanged {
+		report = protocol.RelatedUnchangedDocumentDiagnosticReport{
+			Kind:     string(protocol.DocumentDiagnosticReportKindUnchanged),
+			ResultId: resultID,
+		}
+		s.logInfo(ctx, "Diagnostics of %s unchanged since %s", uri, resultID)
+	} else {
+		report = protocol.RelatedFullDocumentDiagnosticReport{
+			Kind:     string(protocol.DocumentDiagnosticReportKindFull),
+			ResultId: resultID,
+			Items:    items,
+		}
+		s.logInfo(ctx, "Pulled %d diagnostics of %s as %s", len(items), uri, resultID)
+	}
+	if err := s.reply(ctx, conn, req, report); err != nil {
+		s.logError(ctx, "Failed to send document diagnostic response: %v", err)
+	}
+}
+
+// handleWorkspaceDiagnostic processes workspace/diagnostic requests,
+// reporting on every open document and every configured unopened URI. The
+// report is sent at once rather than held open until diagnostics change.
+func (s *MockLSPServer) handleWorkspaceDiagnostic(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if !s.pullDiagnostics() {
+		s.replyMethodNotFound(ctx, conn, req)
+		return
+	}
+	var params protocol.WorkspaceDiagnosticParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse workspace diagnostic params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send workspace diagnostic error: %v", replyErr)
+		}
+		return
+	}
+
+	previous := make(map[string]string, len(params.PreviousResultIds))
+	for _, id := range params.PreviousResultIds {
+		previous[string(id.Uri)] = id.Value
+	}
+	versions := make(map[string]*int32)
+	for uri, version := range s.documents.Versions() {
+		versions[uri] = &version
+	}
+	for _, uri := range s.config().LSP.DiagnosticsConfig.UnopenedURIs {
+		if _, ok := versions[uri]; !ok {
+			versions[uri] = nil
+		}
+	}
+
+	result := protocol.WorkspaceDiagnosticReport{Items: []protocol.WorkspaceDocumentDiagnosticReport{}}
+	unchangedCount := 0
+	for _, uri := range slices.Sorted(maps.Keys(versions)) {
+		items, resultID, unchanged, err := s.diagnosticReport(uri, previous[uri])
+		if err != nil {
+			s.logError(ctx, "Failed to encode diagnostics of %s: %v", uri, err)
+		}
+		var report protocol.WorkspaceDocumentDiagnosticReport
+		if unchanged {
+			unchangedCount++
+			report.Value = protocol.WorkspaceUnchangedDocumentDiagnosticReport{
+				Kind:     string(protocol.DocumentDiagnosticReportKindUnchanged),
+				ResultId: resultID,
+				Uri:      protocol.DocumentUri(uri),
+				Version:  versions[uri],
+			}
+		} else {
+			report.Value = protocol.WorkspaceFullDocumentDiagnosticReport{
+				Kind:     string(protocol.DocumentDiagnosticReportKindFull),
+				ResultId: resultID,
+				Uri:      protocol.DocumentUri(uri),
+				Version:  versions[uri],
+				Items:    items,
+			}
+		}
+		result.Items = append(result.Items, report)
+	}
+	s.logInfo(ctx, "Workspace diagnostics for %d documents, %d unchanged", len(result.Items), unchangedCount)
+
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send workspace diagnostic response: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.DiagnosticsConfig.Mode = config.DiagnosticModePull
+	cfg.LSP.DiagnosticsConfig.UnopenedURIs = []string{"untitled:Untitled-1"}
+	server.SetConfig(cfg)
+
+	published := 0
+	server.OnDiagnosticsPublished(func(protocol.PublishDiagnosticsParams) { published++ })
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var initialized struct {
+		Capabilities struct {
+			DiagnosticProvider map[string]any `json:"diagnosticProvider"`
+		} `json:"capabilities"`
+	}
+	if err := client.Call(ctx, "initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}, &initialized); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if provider := initialized.Capabilities.DiagnosticProvider; provider["identifier"] != diagnosticSource || provider["workspaceDiagnostics"] != true {
+		t.Errorf("Expected a diagnostic provider with workspace diagnostics, got %v", provider)
+	}
+
+	document := map[string]any{"uri": "file:///pull.txt", "languageId": "plaintext", "version": 3, "text": "one\ntwo\n"}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	type report struct {
+		Kind     string           `json:"kind"`
+		ResultID string           `json:"resultId"`
+		URI      string           `json:"uri"`
+		Version  *int32           `json:"version"`
+		Items    []map[string]any `json:"items"`
+	}
+	params := map[string]any{"textDocument": map[string]any{"uri": "file:///pull.txt"}}
+	var full report
+	if err := client.Call(ctx, "textDocument/diagnostic", params, &full); err != nil {
+		t.Fatalf("Document diagnostic request failed: %v", err)
+	}
+	if full.Kind != "full" || full.ResultID == "" || len(full.Items) == 0 {
+		t.Fatalf("Expected a full report with a result ID, got %+v", full)
+	}
+
+	params["previousResultId"] = full.ResultID
+	var unchanged report
+	if err := client.Call(ctx, "textDocument/diagnostic", params, &unchanged); err != nil {
+		t.Fatalf("Document diagnostic request failed: %v", err)
+	}
+	if unchanged.Kind != "unchanged" || unchanged.ResultID != full.ResultID || unchanged.Items != nil {
+		t.Errorf("Expected an unchanged report for the current result ID, got %+v", unchanged)
+	}
+
+	var workspace struct {
+		Items []report `json:"items"`
+	}
+	previous := []any{map[string]any{"uri": "file:///pull.txt", "value": full.ResultID}}
+	if err := client.Call(ctx, "workspace/diagnostic", map[string]any{"previousResultIds": previous}, &workspace); err != nil {
+		t.Fatalf("Workspace diagnostic request failed: %v", err)
+	}
+	if len(workspace.Items) != 2 {
+		t.Fatalf("Expected reports for the open and the unopened document, got %+v", workspace.Items)
+	}
+	if opened := workspace.Items[0]; opened.URI != "file:///pull.txt" || opened.Kind != "unchanged" || opened.Version == nil || *opened.Version != 3 {
+		t.Errorf("Expected the open document unchanged at version 3, got %+v", opened)
+	}
+	if unopened := workspace.Items[1]; unopened.URI != "untitled:Untitled-1" || unopened.Kind != "full" || unopened.Version != nil {
+		t.Errorf("Expected a full report without version for the unopened document, got %+v", unopened)
+	}
+
+	if published != 0 {
+		t.Errorf("Expected no diagnostics to be pushed in pull mode, got %d publications", published)
+	}
+}
+
+func TestPullDiagnosticsNotConfigured(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	params := map[string]any{"textDocument": map[string]any{"uri": "file:///push.txt"}}
+	if err := client.Call(ctx, "textDocument/diagnostic", params, nil); errorCode(err) != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound without pull diagnostics, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// RandomSource is the source of randomness used for latency sampling and
+// randomized responses. *rand.Rand satisfies it, but it is not safe for
+// concurrent use; NewSeededRandomSource returns a source that is.
+type RandomSource interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+// lockedRandom wraps a *rand.Rand so it can be shared between handlers
+type lockedRandom struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewSeededRandomSource creates a concurrency-safe random source.
+// A zero seed selects a time-based seed.
+func NewSeededRandomSource(seed int64) RandomSource {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &lockedRandom{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Intn returns a non-negative pseudo-random number in [0,n)
+func (r *lockedRandom) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
+}
+
+// Float64 returns a pseudo-random number in [0.0,1.0)
+func (r *lockedRandom) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+// randomResponder generates structurally valid but randomized responses
+type randomResponder struct {
+	src      RandomSource
+	maxItems int
+	maxLines int
+	prefixes []string
+}
+
+// maybe returns true with probability one half, deciding whether an
+// optional field is present
+func (r *randomResponder) maybe() bool {
+	return r.src.Intn(2) == 0
+}
+
+// count returns a random item count in [0,max]
+func (r *randomResponder) count(max int) int {
+	if max <= 0 {
+		return 0
+	}
+	return r.src.Intn(max + 1)
+}
+
+// name returns a random identifier built from the configured prefixes
+func (r *randomResponder) name() string {
+	prefix := "mock"
+	if len(r.prefixes) > 0 {
+		prefix = r.prefixes[r.src.Intn(len(r.prefixes))]
+	}
+	return fmt.Sprintf("%s%d", prefix, r.src.Intn(10000))
+}
+
+// position returns a random position within the configured line bound
+func (r *randomResponder) position() protocol.Position {
+	return protocol.Position{
+		Line:      uint32(r.src.Intn(r.maxLines)),
+		Character: uint32(r.src.Intn(120)),
+	}
+}
+
+// rangeValue returns a random range whose start never follows its end
+func (r *randomResponder) rangeValue() protocol.Range {
+	start := r.position()
+	end := start
+	if r.maybe() {
+		end.Line += uint32(r.src.Intn(5))
+	}
+	end.Character += uint32(r.src.Intn(40))
+	return protocol.Range{Start: start, End: end}
+}
+
+// markup returns random markup content in either supported kind
+func (r *randomResponder) markup() protocol.MarkupContent {
+	if r.maybe() {
+		return protocol.MarkupContent{
+			Kind:  protocol.MarkupKindMarkdown,
+			Value: fmt.Sprintf("**%s**\n\nRandomized documentation.", r.name()),
+		}
+	}
+	return protocol.MarkupContent{
+		Kind:  protocol.MarkupKindPlainText,
+		Value: fmt.Sprintf("%s: randomized documentation", r.name()),
+	}
+}
+
+// completionList returns a randomized completion list
+func (r *randomResponder) completionList() protocol.CompletionList {
+	items := make([]protocol.CompletionItem, r.count(r.maxItems))
+	for i := range items {
+		kind := protocol.CompletionItemKind(1 + r.src.Intn(25))
+		item := protocol.CompletionItem{
+			Label: r.name(),
+			Kind:  &kind,
+		}
+		if r.maybe() {
+			item.Detail = fmt.Sprintf("randomized detail %d", i)
+		}
+		if r.maybe() {
+			if r.maybe() {
+				item.Documentation = &protocol.Or2[string, protocol.MarkupContent]{Value: r.markup()}
+			} else {
+				item.Documentation = &protocol.Or2[string, protocol.MarkupContent]{Value: "randomized documentation"}
+			}
+		}
+		if r.maybe() {
+			item.InsertText = item.Label
+		}
+		if r.maybe() {
+			item.SortText = fmt.Sprintf("%05d", r.src.Intn(100000))
+		}
+		if r.maybe() {
+			item.Preselect = true
+		}
+		items[i] = item
+	}
+	return protocol.CompletionList{
+		IsIncomplete: r.maybe(),
+		Items:        items,
+	}
+}
+
+// hover returns a randomized hover, optionally with a range
+func (r *randomResponder) hover() protocol.Hover {
+	hover := protocol.Hover{
+		Contents: protocol.Or3[protocol.MarkupContent, protocol.MarkedString, []protocol.MarkedString]{
+			Value: r.markup(),
+		},
+	}
+	if r.maybe() {
+		rng := r.rangeValue()
+		hover.Range = &rng
+	}
+	return hover
+}
+
+// locations returns a random number of locations within the given document
+func (r *randomResponder) locations(uri protocol.DocumentUri, max int) []protocol.Location {
+	locations := make([]protocol.Location, r.count(max))
+	for i := range locations {
+		locations[i] = protocol.Location{Uri: uri, Range: r.rangeValue()}
+	}
+	return locations
+}
+
+// documentSymbols returns a random symbol tree of bounded depth
+func (r *randomResponder) documentSymbols(depth int) []protocol.DocumentSymbol {
+	max := r.maxItems
+	if max > 10 {
+		max = 10
+	}
+	symbols := make([]protocol.DocumentSymbol, r.count(max))
+	for i := range symbols {
+		rng := r.rangeValue()
+		symbol := protocol.DocumentSymbol{
+			Name:           r.name(),
+			Kind:           protocol.SymbolKind(1 + r.src.Intn(26)),
+			Range:          rng,
+			SelectionRange: protocol.Range{Start: rng.Start, End: rng.Start},
+		}
+		if r.maybe() {
+			symbol.Detail = "randomized symbol"
+		}
+		if depth > 0 && r.maybe() {
+			symbol.Children = r.documentSymbols(depth - 1)
+		}
+		symbols[i] = symbol
+	}
+	return symbols
+}
+
+// diagnostics returns a random set of diagnostics
+func (r *randomResponder) diagnostics(max int) []protocol.Diagnostic {
+	diagnostics := make([]protocol.Diagnostic, r.count(max))
+	for i := range diagnostics {
+		diagnostic := protocol.Diagnostic{
+			Range:   r.rangeValue(),
+			Message: fmt.Sprintf("randomized diagnostic %s", r.name()),
+		}
+		if r.maybe() {
+			severity := protocol.DiagnosticSeverity(1 + r.src.Intn(4))
+			diagnostic.Severity = &severity
+		}
+		if r.maybe() {
+			diagnostic.Source = diagnosticSource
+		}
+		if r.maybe() {
+			if r.maybe() {
+				diagnostic.Code = &protocol.Or2[int32, string]{Value: int32(r.src.Intn(1000))}
+			} else {
+				diagnostic.Code = &protocol.Or2[int32, string]{Value: r.name()}
+			}
+		}
+		diagnostics[i] = diagnostic
+	}
+	return diagnostics
+}
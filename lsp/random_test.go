@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func newTestRandomResponder(seed int64) *randomResponder {
+	return &randomResponder{
+		src:      NewSeededRandomSource(seed),
+		maxItems: 20,
+		maxLines: 50,
+		prefixes: []string{"fuzz"},
+	}
+}
+
+func rangeIsOrdered(r protocol.Range) bool {
+	if r.Start.Line != r.End.Line {
+		return r.Start.Line < r.End.Line
+	}
+	return r.Start.Character <= r.End.Character
+}
+
+func TestRandomResponder_Deterministic(t *testing.T) {
+	first, err := encodeWire(newTestRandomResponder(7).completionList())
+	if err != nil {
+		t.Fatalf("encodeWire failed: %v", err)
+	}
+	second, err := encodeWire(newTestRandomResponder(7).completionList())
+	if err != nil {
+		t.Fatalf("encodeWire failed: %v", err)
+	}
+
+	if string(first) != string(second) {
+		t.Error("Expected identical responses for identical seeds")
+	}
+}
+
+func TestRandomResponder_SpecValid(t *testing.T) {
+	for seed := int64(1); seed <= 50; seed++ {
+		random := newTestRandomResponder(seed)
+
+		list := random.completionList()
+		if list.Items == nil {
+			t.Fatal("Completion items must never be null")
+		}
+		if len(list.Items) > 20 {
+			t.Errorf("Expected at most 20 items, got %d", len(list.Items))
+		}
+		for _, item := range list.Items {
+			if item.Label == "" {
+				t.Error("Completion item label must not be empty")
+			}
+			if item.Kind != nil && (*item.Kind < 1 || *item.Kind > 25) {
+				t.Errorf("Invalid completion item kind %d", *item.Kind)
+			}
+		}
+
+		for _, diagnostic := range random.diagnostics(10) {
+			if !rangeIsOrdered(diagnostic.Range) {
+				t.Errorf("Diagnostic range start after end: %+v", diagnostic.Range)
+			}
+			if diagnostic.Severity != nil && (*diagnostic.Severity < 1 || *diagnostic.Severity > 4) {
+				t.Errorf("Invalid diagnostic severity %d", *diagnostic.Severity)
+			}
+		}
+
+		for _, symbol := range random.documentSymbols(2) {
+			if symbol.Kind < 1 || symbol.Kind > 26 {
+				t.Errorf("Invalid symbol kind %d", symbol.Kind)
+			}
+			if symbol.SelectionRange.Start.Line < symbol.Range.Start.Line {
+				t.Errorf("Selection range outside symbol range: %+v", symbol)
+			}
+		}
+
+		for _, location := range random.locations("file:///fuzz.go", 5) {
+			if !rangeIsOrdered(location.Range) || location.Range.Start.Line >= 50 {
+				t.Errorf("Invalid location range: %+v", location.Range)
+			}
+		}
+	}
+}
+
+func TestRandomizedCompletionOverWire(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.Randomize = true
+	cfg.LSP.MockData.Seed = 99
+	server.SetConfig(cfg)
+
+	client := connectTestClient(t, server, nil)
+	params := protocol.CompletionParams{
+		TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///fuzz.go"},
+	}
+
+	var list protocol.CompletionList
+	if err := client.Call(context.Background(), "textDocument/completion", params, &list); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+
+	for _, item := range list.Items {
+		if item.Label == "mockFunction" {
+			t.Error("Expected randomized items instead of the static mock list")
+		}
+	}
+}
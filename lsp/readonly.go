@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// mutatingServerRequests are the server-to-client requests that change
+// client state and are never sent in read-only mode
+var mutatingServerRequests = []string{"workspace/applyEdit"}
+
+// errReadOnly is returned for server-to-client requests refused in
+// read-only mode
+var errReadOnly = errors.New("refused in read-only mode")
+
+// readOnly reports whether the server must not change client state
+func (s *MockLSPServer) readOnly() bool {
+	return s.config().LSP.ReadOnly
+}
+
+// call sends a request to the client using the wire encoder and decodes the
+// response into result. In read-only mode requests that would change client
+// state, such as workspace/applyEdit, are logged and refused without being
+// sent. Every server-initiated request goes through call, so handlers need
+// no read-only checks of their own, and gets its ID from the configured
+// ID generator.
+func (s *MockLSPServer) call(ctx context.Context, conn Conn, method string, params, result any) error {
+	if s.readOnly() && slices.Contains(mutatingServerRequests, method) {
+		s.logInfo(ctx, "Not sending %s in read-only mode", method)
+		return fmt.Errorf("%s: %w", method, errReadOnly)
+	}
+	data, err := encodeWireWithExtra(params, s.traceExtra(ctx, method))
+	if err != nil {
+		return err
+	}
+	data = s.enforceInvariants(ctx, method, data)
+	s.logPayload(ctx, "Sending %s params: %s", method, data)
+	id, release := s.nextRequestID()
+	defer release()
+	var raw json.RawMessage
+	if err := conn.Call(ctx, method, data, &raw, jsonrpc.PickID(id)); err != nil {
+		return err
+	}
+	s.logPayload(ctx, "Received %s result: %s", method, raw)
+	if result == nil || raw == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// disableCodeActions marks every code action as disabled, with the reason
+// shown by the client, when the server is in read-only mode. Code actions
+// are returned unchanged otherwise.
+func (s *MockLSPServer) disableCodeActions(actions []protocol.CodeAction) []protocol.CodeAction {
+	if !s.readOnly() {
+		return actions
+	}
+	reason := s.message(msgReadOnlyAction)
+	for i := range actions {
+		actions[i].Disabled = &protocol.CodeActionDisabled{Reason: reason}
+	}
+	return actions
+}
@@ -0,0 +1,87 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestDisableCodeActions(t *testing.T) {
+	for _, readOnly := range []bool{false, true} {
+		server := createTestServer()
+		cfg := config.DefaultConfig()
+		cfg.LSP.ReadOnly = readOnly
+		server.SetConfig(cfg)
+
+		actions := server.disableCodeActions([]protocol.CodeAction{
+			{Title: "Add mock import"},
+			{Title: "Extract mock function"},
+		})
+		for _, action := range actions {
+			if disabled := action.Disabled != nil; disabled != readOnly {
+				t.Errorf("Expected %q disabled %v in read-only mode %v", action.Title, readOnly, readOnly)
+			}
+			if readOnly && action.Disabled != nil && action.Disabled.Reason != localize(config.LocaleEnglish, msgReadOnlyAction) {
+				t.Errorf("Expected the read-only reason, got %q", action.Disabled.Reason)
+			}
+		}
+	}
+}
+
+func TestReadOnlyCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		method   string
+		wantSent bool
+	}{
+		{"applyEdit allowed", false, "workspace/applyEdit", true},
+		{"applyEdit refused", true, "workspace/applyEdit", false},
+		{"non-mutating request in read-only mode", true, "window/showMessageRequest", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.ReadOnly = tt.readOnly
+			server.SetConfig(cfg)
+
+			var received []string
+			serverSide, clientSide := net.Pipe()
+			ctx := context.Background()
+			serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server)
+			client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+				jsonrpc.HandlerWithError(func(_ context.Context, _ jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+					received = append(received, req.Method)
+					return map[string]any{"applied": true}, nil
+				}))
+			defer client.Close()
+			defer serverConn.Close()
+
+			var result map[string]any
+			err := server.call(ctx, serverConn, tt.method, protocol.ApplyWorkspaceEditParams{Label: "mock"}, &result)
+
+			if tt.wantSent {
+				if err != nil {
+					t.Fatalf("Expected the request to be sent, got %v", err)
+				}
+				if len(received) != 1 || received[0] != tt.method {
+					t.Errorf("Expected the client to receive %s, got %v", tt.method, received)
+				}
+				return
+			}
+			if !errors.Is(err, errReadOnly) {
+				t.Errorf("Expected a read-only error, got %v", err)
+			}
+			if len(received) != 0 {
+				t.Errorf("Expected nothing to be sent, got %v", received)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mock-lsp-server/recording"
+)
+
+// recordingSession returns the ID of a session recorded from start. Client
+// IDs restart with every process, while a store keeps appending across
+// runs, so the ID adds the start time and process ID.
+func recordingSession(clientID string, start time.Time) string {
+	return fmt.Sprintf("%s-%d-%s", start.UTC().Format("20060102T150405.000Z"), os.Getpid(), clientID)
+}
+
+// SetRecordingStore makes the server append every wire message of the
+// session to store, redacted like the recent traffic buffer, along with its
+// stats when the session ends. A nil store turns recording off. The first
+// failure to store a message is logged.
+func (s *MockLSPServer) SetRecordingStore(store recording.Store) {
+	session := recordingSession(s.clientID, time.Now())
+	s.mu.Lock()
+	s.store = store
+	s.session = session
+	s.statsRecorded = false
+	s.mu.Unlock()
+	if store != nil {
+		s.logInfo(context.Background(), "Recording session %s", session)
+	}
+
+	var once sync.Once
+	s.traffic.setStore(store, session, func(err error) {
+		once.Do(func() {
+			s.logError(context.Background(), "Failed to record message, later failures are not logged: %v", err)
+		})
+	})
+}
+
+// RecordStats appends the stats of the session to the recording store, once
+// per session: the first call after shutdown, exit or disconnect wins.
+// Without a store it does nothing.
+func (s *MockLSPServer) RecordStats() error {
+	s.mu.Lock()
+	store := s.store
+	session := s.session
+	recorded := s.statsRecorded
+	s.statsRecorded = true
+	s.mu.Unlock()
+	if store == nil || recorded {
+		return nil
+	}
+
+	payload, err := encodeWire(s.Stats())
+	if err != nil {
+		return err
+	}
+	return store.Append(recording.Record{
+		Time:    time.Now(),
+		Session: session,
+		Kind:    recording.KindStats,
+		Payload: payload,
+	})
+}
+
+// saveStats records the stats of the session and logs failures
+func (s *MockLSPServer) saveStats(ctx context.Context) {
+	if err := s.RecordStats(); err != nil {
+		s.logError(ctx, "Failed to record stats: %v", err)
+	}
+}
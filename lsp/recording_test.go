@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/recording"
+)
+
+func TestRecordingStore(t *testing.T) {
+	store, err := recording.OpenFile(filepath.Join(t.TempDir(), "recording.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer store.Close()
+	server := createTestServer()
+	server.SetRecordingStore(store)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	hover := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///recorded.txt"}}
+	if err := client.Call(ctx, "textDocument/hover", hover, nil); err != nil {
+		t.Fatalf("Hover failed: %v", err)
+	}
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	responses, err := store.Query(recording.Filter{Kind: recording.KindResponse, Method: "textDocument/hover"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(responses) != 1 || responses[0].Session != server.session || responses[0].Direction != trafficSent || responses[0].Latency <= 0 {
+		t.Fatalf("Expected one hover response of the session with its latency, got %+v", responses)
+	}
+
+	stats, err := store.Query(recording.Filter{Kind: recording.KindStats})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var snapshot StatsSnapshot
+	if len(stats) != 1 || stats[0].Session != server.session || json.Unmarshal(stats[0].Payload, &snapshot) != nil || snapshot.Requests["textDocument/hover"] != 1 {
+		t.Fatalf("Expected the stats of the session recorded once, got %+v", stats)
+	}
+	if err := server.RecordStats(); err != nil {
+		t.Fatalf("RecordStats failed: %v", err)
+	}
+	if stats, _ := store.Query(recording.Filter{Kind: recording.KindStats}); len(stats) != 1 {
+		t.Errorf("Expected the stats to be recorded once per session, got %d records", len(stats))
+	}
+}
+
+func TestRecordingSession(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	session := recordingSession("client-1", start)
+	if want := fmt.Sprintf("20260102T030405.006Z-%d-client-1", os.Getpid()); session != want {
+		t.Errorf("recordingSession() = %q, want %q", session, want)
+	}
+	if recordingSession("client-1", start.Add(time.Millisecond)) == session {
+		t.Error("Expected sessions started at different times to differ")
+	}
+}
@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"mock-lsp-server/config"
+)
+
+// redactor hides the string values of JSON payloads outside an allowlist of
+// fields, so recorded traffic can be shared without leaking document contents
+type redactor struct {
+	mode  string
+	allow map[string]bool
+}
+
+// newRedactor creates a redactor for the configuration, or returns nil when
+// content is kept verbatim
+func newRedactor(cfg config.RedactionConfig) *redactor {
+	if cfg.Mode == "" || cfg.Mode == config.RedactionNone {
+		return nil
+	}
+	allowlist := cfg.Allowlist
+	if len(allowlist) == 0 {
+		allowlist = config.DefaultRedactionAllowlist
+	}
+	allow := make(map[string]bool, len(allowlist))
+	for _, field := range allowlist {
+		allow[field] = true
+	}
+	return &redactor{mode: cfg.Mode, allow: allow}
+}
+
+// redactString replaces s with a placeholder revealing only its length and,
+// in hash mode, a digest that lets equal values be correlated
+func (r *redactor) redactString(s string) string {
+	if r.mode == config.RedactionHash {
+		sum := sha256.Sum256([]byte(s))
+		return fmt.Sprintf("[redacted sha256:%s len=%d]", hex.EncodeToString(sum[:6]), len(s))
+	}
+	return fmt.Sprintf("[redacted len=%d]", len(s))
+}
+
+// redactValue redacts the strings in a decoded JSON value. Values of
+// allowlisted fields are kept whole.
+func (r *redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.redactString(v)
+	case map[string]any:
+		for key, value := range v {
+			if !r.allow[key] {
+				v[key] = r.redactValue(value)
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = r.redactValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactJSON returns a redacted copy of a JSON payload. Payloads that cannot
+// be decoded are replaced entirely, as their content is unknown.
+func (r *redactor) redactJSON(raw *json.RawMessage) *json.RawMessage {
+	if r == nil || raw == nil {
+		return raw
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(*raw))
+	decoder.UseNumber()
+	var value any
+	var data []byte
+	err := decoder.Decode(&value)
+	if err == nil {
+		data, err = json.Marshal(r.redactValue(value))
+	}
+	if err != nil {
+		data, _ = json.Marshal(r.redactString(string(*raw)))
+	}
+	redacted := json.RawMessage(data)
+	return &redacted
+}
@@ -0,0 +1,97 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestRedactor_RedactJSON(t *testing.T) {
+	raw := json.RawMessage(`{"textDocument":{"uri":"file:///secret.go","languageId":"go","version":3,"text":"func secret() {}"},"tags":["a","bb"]}`)
+
+	tests := []struct {
+		name      string
+		redaction config.RedactionConfig
+		want      string
+	}{
+		{
+			name:      "elide",
+			redaction: config.RedactionConfig{Mode: config.RedactionElide},
+			want:      `{"tags":["[redacted len=1]","[redacted len=2]"],"textDocument":{"languageId":"go","text":"[redacted len=16]","uri":"file:///secret.go","version":3}}`,
+		},
+		{
+			name:      "hash",
+			redaction: config.RedactionConfig{Mode: config.RedactionHash},
+			want:      `{"tags":["[redacted sha256:ca978112ca1b len=1]","[redacted sha256:3b64db95cb55 len=2]"],"textDocument":{"languageId":"go","text":"[redacted sha256:8795c43c93af len=16]","uri":"file:///secret.go","version":3}}`,
+		},
+		{
+			name:      "custom allowlist",
+			redaction: config.RedactionConfig{Mode: config.RedactionElide, Allowlist: []string{"tags", "version"}},
+			want:      `{"tags":["a","bb"],"textDocument":{"languageId":"[redacted len=2]","text":"[redacted len=16]","uri":"[redacted len=17]","version":3}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newRedactor(tt.redaction).redactJSON(&raw)
+			if string(*got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, *got)
+			}
+		})
+	}
+
+	if newRedactor(config.RedactionConfig{Mode: config.RedactionNone}) != nil {
+		t.Error("Expected no redactor for mode none")
+	}
+	var disabled *redactor
+	if got := disabled.redactJSON(&raw); got != &raw {
+		t.Error("Expected a nil redactor to keep the payload")
+	}
+
+	invalid := json.RawMessage(`{"text": "unterminated`)
+	got := newRedactor(config.RedactionConfig{Mode: config.RedactionElide}).redactJSON(&invalid)
+	if string(*got) != `"[redacted len=22]"` {
+		t.Errorf("Expected an undecodable payload to be replaced entirely, got %s", *got)
+	}
+}
+
+func TestRecentTrafficRedaction(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.Redaction.Mode = config.RedactionHash
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	const source = "package proprietary\n"
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///secret.go", LanguageId: "go", Text: source},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	var hover protocol.Hover
+	if err := client.Call(ctx, "textDocument/hover", protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: open.TextDocument.Uri}}, &hover); err != nil {
+		t.Fatalf("Hover request failed: %v", err)
+	}
+
+	data, err := json.Marshal(server.RecentTraffic())
+	if err != nil {
+		t.Fatalf("Failed to encode traffic: %v", err)
+	}
+	dump := string(data)
+	if strings.Contains(dump, "proprietary") || strings.Contains(dump, "Mock Hover") {
+		t.Errorf("Expected document text and hover contents to be redacted, got %s", dump)
+	}
+	if !strings.Contains(dump, "file:///secret.go") {
+		t.Errorf("Expected allowlisted URIs to be kept, got %s", dump)
+	}
+
+	// The server itself still sees the verbatim content
+	if server.OpenDocuments()["file:///secret.go"].Text != source {
+		t.Error("Expected redaction to leave the document store untouched")
+	}
+}
@@ -0,0 +1,184 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// mockRenameSymbol is the word renamed in documents the server has not
+// opened, assumed to start at the requested position
+const mockRenameSymbol = "mockSymbol"
+
+// prepareRenameSupported reports whether the client declared support for
+// textDocument/prepareRename, without which the server must not announce it
+func (s *MockLSPServer) prepareRenameSupported() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client != nil && slices.Contains(s.client.Capabilities, "textDocument.rename.prepareSupport")
+}
+
+// renameTarget returns the range and text of the word to rename at pos in
+// uri. ok is false when pos is not on a word of an open document.
+func (s *MockLSPServer) renameTarget(uri string, pos protocol.Position) (rng protocol.Range, word string, ok bool) {
+	text, open := s.documentText(uri)
+	if !open {
+		end := protocol.Position{Line: pos.Line, Character: pos.Character + utf16Len(mockRenameSymbol)}
+		return protocol.Range{Start: pos, End: end}, mockRenameSymbol, true
+	}
+	word = wordAt(text, pos)
+	if word == "" {
+		return protocol.Range{}, "", false
+	}
+	rng, _ = wordRangeAt(text, pos)
+	return rng, word, true
+}
+
+// validName reports whether name can replace a word: it is a word itself
+func validName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool { return !isWordRune(r) }) < 0
+}
+
+// wordOccurrences returns the ranges of up to limit whole-word occurrences
+// of word in text, in document order
+func wordOccurrences(text, word string, limit int) []protocol.Range {
+	var ranges []protocol.Range
+	for number, line := range strings.Split(text, "\n") {
+		for offset := 0; len(ranges) < limit; {
+			i := strings.Index(line[offset:], word)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(word)
+			offset = end
+			if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWordRune(before) {
+				continue
+			}
+			if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWordRune(after) {
+				continue
+			}
+			ranges = append(ranges, protocol.Range{
+				Start: protocol.Position{Line: uint32(number), Character: utf16Len(line[:start])},
+				End:   protocol.Position{Line: uint32(number), Character: utf16Len(line[:end])},
+			})
+		}
+	}
+	return ranges
+}
+
+// renameEdit returns the workspace edit renaming word to newName: the
+// renamed occurrence first, then the other occurrences in the document and
+// in the other open documents, in URI order. The rename configuration
+// bounds the documents and the edits per document.
+func (s *MockLSPServer) renameEdit(uri string, target protocol.Range, word, newName string) protocol.WorkspaceEdit {
+	cfg := s.config().LSP.RenameConfig
+	texts := s.documents.Texts()
+
+	edits := []protocol.TextEdit{{Range: target, NewText: newName}}
+	for _, rng := range wordOccurrences(texts[uri], word, cfg.EditsPerFile) {
+		if len(edits) < cfg.EditsPerFile && rng != target {
+			edits = append(edits, protocol.TextEdit{Range: rng, NewText: newName})
+		}
+	}
+	changes := map[protocol.DocumentUri][]protocol.TextEdit{protocol.DocumentUri(uri): edits}
+
+	for _, other := range slices.Sorted(maps.Keys(texts)) {
+		if len(changes) >= cfg.Files {
+			break
+		}
+		if other == uri {
+			continue
+		}
+		var edits []protocol.TextEdit
+		for _, rng := range wordOccurrences(texts[other], word, cfg.EditsPerFile) {
+			edits = append(edits, protocol.TextEdit{Range: rng, NewText: newName})
+		}
+		if len(edits) > 0 {
+			changes[protocol.DocumentUri(other)] = edits
+		}
+	}
+	return protocol.WorkspaceEdit{Changes: changes}
+}
+
+// replyRenameError refuses a rename with RequestFailed and a message the
+// client shows to the user
+func (s *MockLSPServer) replyRenameError(ctx context.Context, conn Conn, req *jsonrpc.Request, message string) {
+	s.logInfo(ctx, "Refusing %s: %s", req.Method, message)
+	if err := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+		Code:    int64(protocol.LSPErrorCodesRequestFailed),
+		Message: message,
+	}); err != nil {
+		s.logError(ctx, "Failed to send %s error: %v", req.Method, err)
+	}
+}
+
+// handlePrepareRename processes textDocument/prepareRename requests,
+// answering with the range and text of the word at the position, or null
+// when there is none. In read-only mode renames are refused.
+func (s *MockLSPServer) handlePrepareRename(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.PrepareRenameParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse prepare rename params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send prepare rename error: %v", replyErr)
+		}
+		return
+	}
+	if s.readOnly() {
+		s.replyRenameError(ctx, conn, req, s.message(msgReadOnlyAction))
+		return
+	}
+
+	var result *protocol.PrepareRenamePlaceholder
+	if rng, word, ok := s.renameTarget(string(params.TextDocument.Uri), params.Position); ok {
+		result = &protocol.PrepareRenamePlaceholder{Range: rng, Placeholder: word}
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send prepare rename response: %v", err)
+	}
+}
+
+// handleRename processes textDocument/rename requests, answering with a
+// workspace edit that replaces the word at the position in the open
+// documents. Positions off a word and new names that are not words are
+// refused, as are all renames in read-only mode.
+func (s *MockLSPServer) handleRename(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.RenameParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse rename params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send rename error: %v", replyErr)
+		}
+		return
+	}
+	if s.readOnly() {
+		s.replyRenameError(ctx, conn, req, s.message(msgReadOnlyAction))
+		return
+	}
+	if !validName(params.NewName) {
+		s.replyRenameError(ctx, conn, req, s.message(msgRenameInvalidName, params.NewName))
+		return
+	}
+	uri := string(params.TextDocument.Uri)
+	rng, word, ok := s.renameTarget(uri, params.Position)
+	if !ok {
+		s.replyRenameError(ctx, conn, req, s.message(msgRenameNoSymbol))
+		return
+	}
+
+	edit := s.renameEdit(uri, rng, word, params.NewName)
+	s.logInfo(ctx, "Renaming %s to %s in %d documents", word, params.NewName, len(edit.Changes))
+	if err := s.reply(ctx, conn, req, edit); err != nil {
+		s.logError(ctx, "Failed to send rename response: %v", err)
+	}
+}
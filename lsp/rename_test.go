@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestWordOccurrences(t *testing.T) {
+	text := "count := 0\ncount++ // recount\nfmt.Println(count, counter, count)\n😀count"
+	got := wordOccurrences(text, "count", 10)
+	want := []protocol.Range{
+		{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 5}},
+		{Start: protocol.Position{Line: 1, Character: 0}, End: protocol.Position{Line: 1, Character: 5}},
+		{Start: protocol.Position{Line: 2, Character: 12}, End: protocol.Position{Line: 2, Character: 17}},
+		{Start: protocol.Position{Line: 2, Character: 28}, End: protocol.Position{Line: 2, Character: 33}},
+		{Start: protocol.Position{Line: 3, Character: 2}, End: protocol.Position{Line: 3, Character: 7}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d occurrences, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Occurrence %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if got := wordOccurrences(text, "count", 2); len(got) != 2 {
+		t.Errorf("Expected the limit to stop at 2 occurrences, got %d", len(got))
+	}
+
+	for name, want := range map[string]bool{"newName": true, "número_2": true, "": false, "new name": false, "a.b": false} {
+		if got := validName(name); got != want {
+			t.Errorf("validName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.RenameConfig = config.RenameConfig{Files: 2, EditsPerFile: 2}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	initialize := map[string]any{
+		"processId":    nil,
+		"rootUri":      nil,
+		"capabilities": map[string]any{"textDocument": map[string]any{"rename": map[string]any{"prepareSupport": true}}},
+	}
+	var initResult struct {
+		Capabilities struct {
+			RenameProvider map[string]any `json:"renameProvider"`
+		} `json:"capabilities"`
+	}
+	if err := client.Call(ctx, "initialize", initialize, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if initResult.Capabilities.RenameProvider["prepareProvider"] != true {
+		t.Errorf("Expected prepareProvider for a client with prepareSupport, got %v", initResult.Capabilities.RenameProvider)
+	}
+
+	documents := map[string]string{
+		"file:///a.txt": "total := 1\ntotal += total\ntotal--\n",
+		"file:///b.txt": "use(total)\n",
+		"file:///c.txt": "print(total)\n",
+		"file:///d.txt": "nothing here\n",
+	}
+	for uri, text := range documents {
+		open := map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "plaintext", "version": 1, "text": text}}
+		if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+			t.Fatalf("didOpen failed: %v", err)
+		}
+	}
+
+	at := func(uri string, line, character int) map[string]any {
+		return map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": line, "character": character}}
+	}
+	var prepared *protocol.PrepareRenamePlaceholder
+	if err := client.Call(ctx, "textDocument/prepareRename", at("file:///a.txt", 1, 11), &prepared); err != nil {
+		t.Fatalf("Prepare rename failed: %v", err)
+	}
+	if prepared == nil || prepared.Placeholder != "total" || prepared.Range.Start.Character != 9 || prepared.Range.End.Character != 14 {
+		t.Errorf("Expected the second total on line 1, got %+v", prepared)
+	}
+	prepared = nil
+	if err := client.Call(ctx, "textDocument/prepareRename", at("file:///a.txt", 0, 8), &prepared); err != nil || prepared != nil {
+		t.Errorf("Expected null off a word, got %+v (%v)", prepared, err)
+	}
+
+	params := at("file:///a.txt", 1, 11)
+	params["newName"] = "sum"
+	var edit struct {
+		Changes map[string][]protocol.TextEdit `json:"changes"`
+	}
+	if err := client.Call(ctx, "textDocument/rename", params, &edit); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(edit.Changes) != 2 || len(edit.Changes["file:///a.txt"]) != 2 || len(edit.Changes["file:///b.txt"]) != 1 {
+		t.Fatalf("Expected 2 edits in a.txt and 1 in b.txt, got %+v", edit.Changes)
+	}
+	if first := edit.Changes["file:///a.txt"][0]; first.NewText != "sum" || first.Range.Start.Line != 1 || first.Range.Start.Character != 9 {
+		t.Errorf("Expected the renamed occurrence first, got %+v", first)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		params  map[string]any
+		newName string
+	}{
+		{"invalid name", at("file:///a.txt", 0, 1), "new name"},
+		{"off a word", at("file:///a.txt", 0, 8), "sum"},
+	} {
+		tt.params["newName"] = tt.newName
+		if err := client.Call(ctx, "textDocument/rename", tt.params, nil); errorCode(err) != int64(protocol.LSPErrorCodesRequestFailed) {
+			t.Errorf("%s: expected RequestFailed, got %v", tt.name, err)
+		}
+	}
+
+	if err := client.Call(ctx, "$/mockLsp/setFeatures", SetFeaturesParams{Features: map[string]bool{"rename": false}}, nil); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	if err := client.Call(ctx, "textDocument/prepareRename", at("file:///a.txt", 0, 1), nil); errorCode(err) != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound for prepareRename with rename switched off, got %v", err)
+	}
+}
+
+func TestRenameReadOnly(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.ReadOnly = true
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///unopened.go"},
+		"position":     map[string]any{"line": 3, "character": 4},
+		"newName":      "renamed",
+	}
+	if err := client.Call(ctx, "textDocument/rename", params, nil); errorCode(err) != int64(protocol.LSPErrorCodesRequestFailed) {
+		t.Errorf("Expected RequestFailed in read-only mode, got %v", err)
+	}
+
+	cfg.LSP.ReadOnly = false
+	server.SetConfig(cfg)
+	var edit struct {
+		Changes map[string][]protocol.TextEdit `json:"changes"`
+	}
+	if err := client.Call(ctx, "textDocument/rename", params, &edit); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	edits := edit.Changes["file:///unopened.go"]
+	if len(edits) != 1 || edits[0].Range.Start.Character != 4 || edits[0].Range.End.Character != 4+uint32(len(mockRenameSymbol)) {
+		t.Errorf("Expected the mock symbol at the position renamed in an unopened document, got %+v", edit.Changes)
+	}
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// defaultIDPrefix prefixes the IDs of the prefixed strategy without a
+// configured prefix
+const defaultIDPrefix = "mock-"
+
+// maxTrackedRequestIDs bounds the incoming request IDs remembered per
+// connection; reuse of an ID older than that goes unnoticed
+const maxTrackedRequestIDs = 100_000
+
+// Ways a client can reuse a request ID, the keys of the duplicateRequestIds
+// stats
+const (
+	duplicateIDCollision = "collision" // The earlier request is still in flight
+	duplicateIDReuse     = "reuse"     // The earlier request was answered
+)
+
+// IDGenerator hands out the IDs of server-initiated requests. It must be
+// safe for concurrent use. The server skips the IDs of requests still
+// awaiting a response, so a generator may hand out an ID again, as the
+// random one does.
+type IDGenerator interface {
+	NextID() jsonrpc.ID
+}
+
+// NewIDGenerator creates the generator of a request ID strategy. Every
+// strategy is deterministic, the random one given a seed, so replays of a
+// session send the same IDs.
+func NewIDGenerator(cfg config.RequestIDsConfig) IDGenerator {
+	switch cfg.Strategy {
+	case config.IDStrategyPrefixed:
+		prefix := cfg.Prefix
+		if prefix == "" {
+			prefix = defaultIDPrefix
+		}
+		return &prefixedIDs{prefix: prefix}
+	case config.IDStrategyRandom:
+		return &randomIDs{src: NewSeededRandomSource(cfg.Seed)}
+	default:
+		return &sequentialIDs{}
+	}
+}
+
+// sequentialIDs numbers requests from 1
+type sequentialIDs struct {
+	last atomic.Uint64
+}
+
+// NextID returns the next number
+func (g *sequentialIDs) NextID() jsonrpc.ID {
+	return jsonrpc.ID{Num: g.last.Add(1)}
+}
+
+// prefixedIDs numbers requests from 1 in strings starting with prefix
+type prefixedIDs struct {
+	prefix string
+	last   atomic.Uint64
+}
+
+// NextID returns the prefix followed by the next number
+func (g *prefixedIDs) NextID() jsonrpc.ID {
+	return jsonrpc.ID{Str: fmt.Sprintf("%s%d", g.prefix, g.last.Add(1)), IsString: true}
+}
+
+// randomIDs draws request IDs from a random source, within the int32 range
+// clients decoding IDs as integers accept
+type randomIDs struct {
+	src RandomSource
+}
+
+// NextID returns a random positive number
+func (g *randomIDs) NextID() jsonrpc.ID {
+	return jsonrpc.ID{Num: uint64(g.src.Intn(math.MaxInt32)) + 1}
+}
+
+// SetIDGenerator replaces the generator of the IDs of server-initiated
+// requests
+func (s *MockLSPServer) SetIDGenerator(ids IDGenerator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = ids
+}
+
+// nextRequestID returns the ID of the next server-initiated request,
+// skipping those of requests still awaiting a response, which the random
+// strategy can draw again. The ID counts as outstanding until release is
+// called.
+func (s *MockLSPServer) nextRequestID() (id jsonrpc.ID, release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outstanding == nil {
+		s.outstanding = make(map[jsonrpc.ID]bool)
+	}
+	for {
+		id = s.ids.NextID()
+		if !s.outstanding[id] {
+			break
+		}
+	}
+	s.outstanding[id] = true
+	return id, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.outstanding, id)
+	}
+}
+
+// requestIDTracker remembers the IDs of the recent requests of a connection
+type requestIDTracker struct {
+	conn    Conn
+	methods map[jsonrpc.ID]string // Method of the request that used each ID
+	order   []jsonrpc.ID          // IDs in methods, oldest first once full
+	next    int                   // Position in order of the next ID once full
+}
+
+// record remembers id as used by method and returns the method of an
+// earlier request with the same ID, if any
+func (t *requestIDTracker) record(id jsonrpc.ID, method string) (string, bool) {
+	if earlier, ok := t.methods[id]; ok {
+		return earlier, true
+	}
+	if len(t.order) < maxTrackedRequestIDs {
+		t.order = append(t.order, id)
+	} else {
+		delete(t.methods, t.order[t.next])
+		t.order[t.next] = id
+		t.next = (t.next + 1) % maxTrackedRequestIDs
+	}
+	t.methods[id] = method
+	return "", false
+}
+
+// seen reports whether a recent request used id
+func (t *requestIDTracker) seen(id jsonrpc.ID) bool {
+	_, ok := t.methods[id]
+	return ok
+}
+
+// checkRequestID records the ID of an incoming request and reports a
+// client reusing the ID of an earlier request on the same connection: a
+// collision while that request is still in flight, a reuse once it was
+// answered. Duplicates are logged and counted, and answered with
+// InvalidRequest when rejected by the configuration. It returns false when
+// the request was rejected.
+func (s *MockLSPServer) checkRequestID(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	key := underlyingConn(conn)
+	s.mu.Lock()
+	if s.requestIDs == nil || s.requestIDs.conn != key {
+		s.requestIDs = &requestIDTracker{conn: key, methods: make(map[jsonrpc.ID]string)}
+	}
+	earlier, duplicate := s.requestIDs.record(req.ID, req.Method)
+	_, inflight := s.inflight[req.ID]
+	s.mu.Unlock()
+	if !duplicate {
+		return true
+	}
+
+	kind := duplicateIDReuse
+	message := fmt.Sprintf("request ID %s was already used by a %s request", req.ID, earlier)
+	if inflight {
+		kind = duplicateIDCollision
+		message = fmt.Sprintf("request ID %s is in use by a %s request still in flight", req.ID, earlier)
+	}
+	s.stats.RecordDuplicateRequestID(kind)
+	s.logError(ctx, "Duplicate request ID in %s: %s", req.Method, message)
+
+	if !s.config().LSP.RequestIDs.RejectDuplicates {
+		return true
+	}
+	if err := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+		Code:    jsonrpc.CodeInvalidRequest,
+		Message: message,
+	}); err != nil {
+		s.logError(ctx, "Failed to send duplicate request ID error: %v", err)
+	}
+	return false
+}
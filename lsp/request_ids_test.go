@@ -0,0 +1,177 @@
+package lsp
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+func TestNewIDGenerator(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RequestIDsConfig
+		want []string
+	}{
+		{name: "default", want: []string{"1", "2", "3"}},
+		{name: "sequential", cfg: config.RequestIDsConfig{Strategy: config.IDStrategySequential}, want: []string{"1", "2", "3"}},
+		{name: "prefixed", cfg: config.RequestIDsConfig{Strategy: config.IDStrategyPrefixed}, want: []string{`"mock-1"`, `"mock-2"`, `"mock-3"`}},
+		{name: "custom prefix", cfg: config.RequestIDsConfig{Strategy: config.IDStrategyPrefixed, Prefix: "replay/"}, want: []string{`"replay/1"`, `"replay/2"`, `"replay/3"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := NewIDGenerator(tt.cfg)
+			var got []string
+			for range tt.want {
+				got = append(got, ids.NextID().String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("NextID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIDGenerator_RandomSeeded(t *testing.T) {
+	cfg := config.RequestIDsConfig{Strategy: config.IDStrategyRandom, Seed: 42}
+	first, second := NewIDGenerator(cfg), NewIDGenerator(cfg)
+	for range 10 {
+		a, b := first.NextID(), second.NextID()
+		if a != b {
+			t.Fatalf("Expected the same seed to give the same IDs, got %s and %s", a, b)
+		}
+		if a.IsString || a.Num == 0 || a.Num > 1<<31-1 {
+			t.Errorf("Expected a positive int32 ID, got %s", a)
+		}
+	}
+}
+
+func TestServerRequestIDs(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.RequestIDs = config.RequestIDsConfig{Strategy: config.IDStrategyPrefixed, Prefix: "replay-"}
+	server.SetConfig(cfg)
+
+	var mu sync.Mutex
+	var ids []string
+	conn := progressConn(t, server, func(req *jsonrpc.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, req.ID.String())
+	})
+	ctx := context.Background()
+	for range 2 {
+		if err := server.call(ctx, conn, "workspace/codeLens/refresh", nil, nil); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+
+	// Reapplying the same configuration keeps the sequence going
+	server.SetConfig(cfg)
+	if err := server.call(ctx, conn, "workspace/codeLens/refresh", nil, nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{`"replay-1"`, `"replay-2"`, `"replay-3"`}; !slices.Equal(ids, want) {
+		t.Errorf("Expected request IDs %v, got %v", want, ids)
+	}
+}
+
+func TestNextRequestIDSkipsOutstanding(t *testing.T) {
+	server := createTestServer()
+	cfg := config.RequestIDsConfig{Strategy: config.IDStrategyRandom, Seed: 42}
+	reference := NewIDGenerator(cfg)
+	first, second := reference.NextID(), reference.NextID()
+
+	server.SetIDGenerator(NewIDGenerator(cfg))
+	id, release := server.nextRequestID()
+	if id != first {
+		t.Fatalf("Expected %s, got %s", first, id)
+	}
+	// The generator draws the first ID again while it is outstanding
+	server.SetIDGenerator(NewIDGenerator(cfg))
+	if id, _ := server.nextRequestID(); id != second {
+		t.Errorf("Expected the outstanding %s to be skipped for %s, got %s", first, second, id)
+	}
+
+	release()
+	server.SetIDGenerator(NewIDGenerator(cfg))
+	if id, _ := server.nextRequestID(); id != first {
+		t.Errorf("Expected %s to be handed out again once answered, got %s", first, id)
+	}
+}
+
+func TestCheckRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		reject   bool
+		inflight bool
+		kind     string
+	}{
+		{name: "reuse reported", kind: duplicateIDReuse},
+		{name: "collision reported", inflight: true, kind: duplicateIDCollision},
+		{name: "reuse rejected", reject: true, kind: duplicateIDReuse},
+		{name: "collision rejected", reject: true, inflight: true, kind: duplicateIDCollision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.RequestIDs.RejectDuplicates = tt.reject
+			server.SetConfig(cfg)
+			conn := lsptest.NewConn()
+			defer conn.Close()
+			ctx := context.Background()
+
+			first := testRequest(t, 1, "$/mockLsp/stats", nil)
+			if tt.inflight {
+				// The first request is still being handled when the second
+				// arrives
+				if !server.checkRequestID(ctx, conn, first) {
+					t.Fatal("Expected the first request to be accepted")
+				}
+				_, done := server.trackRequest(ctx, first)
+				defer done()
+			} else {
+				server.Dispatch(ctx, conn, first)
+			}
+			second := testRequest(t, 1, "textDocument/hover", map[string]any{})
+			server.Dispatch(ctx, conn, second)
+
+			stats := server.Stats()
+			if len(stats.DuplicateRequestIDs) != 1 || stats.DuplicateRequestIDs[tt.kind] != 1 {
+				t.Errorf("Expected one %s in the stats, got %v", tt.kind, stats.DuplicateRequestIDs)
+			}
+			var answers []lsptest.Message
+			for _, m := range conn.Messages() {
+				if m.Kind == lsptest.KindReply || m.Kind == lsptest.KindError {
+					answers = append(answers, m)
+				}
+			}
+			last := answers[len(answers)-1]
+			rejected := last.Kind == lsptest.KindError && last.Error.Code == jsonrpc.CodeInvalidRequest
+			if rejected != tt.reject {
+				t.Errorf("Expected rejected = %v, got %+v", tt.reject, last)
+			}
+		})
+	}
+}
+
+func TestCheckRequestID_PerConnection(t *testing.T) {
+	server := createTestServer()
+	ctx := context.Background()
+	for range 2 {
+		conn := lsptest.NewConn()
+		server.Dispatch(ctx, conn, testRequest(t, 1, "$/mockLsp/stats", nil))
+		conn.Close()
+	}
+	if duplicates := server.Stats().DuplicateRequestIDs; len(duplicates) != 0 {
+		t.Errorf("Expected IDs to be tracked per connection, got %v", duplicates)
+	}
+}
@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// diagnosticRule is a mock lint rule: the code of the diagnostics it
+// reports and the page describing it
+type diagnosticRule struct {
+	Code        string
+	Title       string
+	Severity    string
+	Description string
+}
+
+// Codes of the mock diagnostics
+const (
+	ruleMockWarning = "MOCK001"
+	ruleMockInfo    = "MOCK002"
+	ruleDeprecated  = "MOCK003"
+)
+
+// diagnosticRules are the rules of the mock diagnostics, in code order
+var diagnosticRules = []diagnosticRule{
+	{ruleMockWarning, "Mock warning", "Warning", "Reported on the second line of every document, so clients always have a warning to show."},
+	{ruleMockInfo, "Mock information", "Information", "Reported on the sixth line of every document, at characters 15 to 25."},
+	{ruleDeprecated, "Deprecated method", "Hint", "Reported on uses of the mock deprecated method when mock_data.deprecated is set, tagged deprecated."},
+}
+
+// rulesPath is where the rule pages are served
+const rulesPath = "/rules/"
+
+var (
+	rulesIndexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Mock LSP rules</title></head>
+<body><h1>Mock LSP rules</h1><ul>
+{{range .}}<li><a href="{{.Code}}">{{.Code}}</a>: {{.Title}}</li>
+{{end}}</ul></body></html>
+`))
+	rulePage = template.Must(template.New("rule").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Code}}: {{.Title}}</title></head>
+<body><h1>{{.Code}}: {{.Title}}</h1>
+<p>Severity: {{.Severity}}</p>
+<p>{{.Description}}</p>
+<p><a href="./">All rules</a></p></body></html>
+`))
+)
+
+// SetRulesURL sets the base URL of the HTTP listener serving RulesHandler,
+// such as http://127.0.0.1:8990. Mock diagnostics then carry their rule
+// code and a codeDescription link to its page. Empty leaves them without.
+func (s *MockLSPServer) SetRulesURL(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rulesURL = strings.TrimSuffix(url, "/")
+}
+
+// withRule returns diagnostic with the code of the rule and a link to its
+// page, if the rule pages are served
+func (s *MockLSPServer) withRule(diagnostic protocol.Diagnostic, code string) protocol.Diagnostic {
+	s.mu.Lock()
+	base := s.rulesURL
+	s.mu.Unlock()
+	if base == "" {
+		return diagnostic
+	}
+	diagnostic.Code = &protocol.Or2[int32, string]{Value: code}
+	diagnostic.CodeDescription = &protocol.CodeDescription{Href: protocol.URI(base + rulesPath + code)}
+	return diagnostic
+}
+
+// RulesHandler serves a page describing each rule of the mock diagnostics
+// under /rules/, and an index of them at /rules/ itself
+func RulesHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(rulesPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		code := strings.TrimPrefix(r.URL.Path, rulesPath)
+		if code == "" {
+			rulesIndexPage.Execute(w, diagnosticRules)
+			return
+		}
+		for _, rule := range diagnosticRules {
+			if rule.Code == code {
+				rulePage.Execute(w, rule)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	return mux
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestRulesHandler(t *testing.T) {
+	handler := RulesHandler()
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/rules/", http.StatusOK, `<a href="MOCK003">MOCK003</a>`},
+		{"/rules/MOCK001", http.StatusOK, "<h1>MOCK001: Mock warning</h1>"},
+		{"/rules/MOCK999", http.StatusNotFound, ""},
+		{"/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("GET %s = %d %q, want %d containing %q", tt.path, rec.Code, rec.Body.String(), tt.status, tt.want)
+		}
+	}
+}
+
+func TestMockDiagnosticsRules(t *testing.T) {
+	server := createTestServer()
+	for _, d := range server.mockDiagnostics("file:///a.go") {
+		if d.Code != nil || d.CodeDescription != nil {
+			t.Errorf("Expected no code without rule pages, got %+v", d)
+		}
+	}
+
+	server.SetRulesURL("http://localhost:8990/")
+	diagnostics := server.mockDiagnostics("file:///a.go")
+	want := []string{ruleMockWarning, ruleMockInfo}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %d", len(want), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Code == nil || d.Code.Value != want[i] {
+			t.Errorf("Expected code %s, got %+v", want[i], d.Code)
+		}
+		if href := protocol.URI("http://localhost:8990/rules/" + want[i]); d.CodeDescription == nil || d.CodeDescription.Href != href {
+			t.Errorf("Expected href %s, got %+v", href, d.CodeDescription)
+		}
+	}
+}
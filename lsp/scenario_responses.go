@@ -0,0 +1,62 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/scenario"
+)
+
+// replyWithScenario answers the request with the first canned response of
+// the scenario that matches its method, document URI and position. It
+// returns false when no response matches, so the built-in mock answers.
+// The inspection requests of test harnesses cannot be replaced.
+func (s *MockLSPServer) replyWithScenario(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	s.mu.Lock()
+	sc := s.scenario
+	s.mu.Unlock()
+	if sc == nil || len(sc.Responses) == 0 || strings.HasPrefix(req.Method, inspectionPrefix) {
+		return false
+	}
+
+	uri, pos := scenarioTarget(req)
+	response, ok := sc.Response(req.Method, uri, pos)
+	if !ok {
+		return false
+	}
+
+	if response.Error != nil {
+		s.logInfo(ctx, "Answering %s with a scenario error", req.Method)
+		lspErr := NewLSPError(LSPErrorCode(response.Error.Code), response.Error.Message)
+		if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+			s.logError(ctx, "Failed to send scenario error: %v", err)
+		}
+		return true
+	}
+	s.logInfo(ctx, "Answering %s with a scenario response", req.Method)
+	if err := s.reply(ctx, conn, req, response.Result); err != nil {
+		s.logError(ctx, "Failed to send scenario response: %v", err)
+	}
+	return true
+}
+
+// scenarioTarget returns the document URI and position of a request, as
+// matched by the responses and expectations of a scenario. Params of other
+// shapes still match those without uri or range.
+func scenarioTarget(req *jsonrpc.Request) (string, *scenario.Position) {
+	var params positionParams
+	if req.Params != nil {
+		_ = json.Unmarshal(*req.Params, &params)
+	}
+	var uri string
+	if params.TextDocument != nil {
+		uri = string(params.TextDocument.Uri)
+	}
+	var pos *scenario.Position
+	if params.Position != nil {
+		pos = &scenario.Position{Line: params.Position.Line, Character: params.Position.Character}
+	}
+	return uri, pos
+}
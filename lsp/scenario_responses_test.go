@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/scenario"
+)
+
+func TestScenarioResponses(t *testing.T) {
+	sc, err := scenario.Parse([]byte(`{"responses": [
+		{"method": "textDocument/hover", "uri": "file:///*.txt", "range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 5}},
+		 "result": {"contents": {"kind": "markdown", "value": "canned"}}},
+		{"method": "textDocument/definition", "uri": "file:///broken.txt", "error": {"code": -32803, "message": "index unavailable"}},
+		{"method": "$/mockLsp/stats", "result": null}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+	server := createTestServer()
+	server.SetScenario(sc)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	hoverAt := func(uri string, character uint32) string {
+		t.Helper()
+		params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: protocol.DocumentUri(uri)}, Position: protocol.Position{Character: character}}
+		var result struct {
+			Contents struct {
+				Value string `json:"value"`
+			} `json:"contents"`
+		}
+		if err := client.Call(ctx, "textDocument/hover", params, &result); err != nil {
+			t.Fatalf("Hover failed: %v", err)
+		}
+		return result.Contents.Value
+	}
+	if got := hoverAt("file:///a.txt", 3); got != "canned" {
+		t.Errorf("Expected the canned hover inside the range, got %q", got)
+	}
+	if got := hoverAt("file:///a.txt", 6); got == "canned" || got == "" {
+		t.Errorf("Expected the built-in hover outside the range, got %q", got)
+	}
+	if got := hoverAt("file:///a.go", 3); got == "canned" {
+		t.Errorf("Expected the built-in hover for other documents, got %q", got)
+	}
+
+	definition := protocol.DefinitionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///broken.txt"}}
+	err = client.Call(ctx, "textDocument/definition", definition, nil)
+	if code := errorCode(err); code != -32803 {
+		t.Errorf("Expected the scenario error -32803, got %v", err)
+	}
+
+	var stats json.RawMessage
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &stats); err != nil || string(stats) == "null" {
+		t.Errorf("Expected inspection requests to keep their answers, got %s (%v)", stats, err)
+	}
+}
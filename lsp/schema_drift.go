@@ -0,0 +1,209 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// maxSchemaDecodes bounds the decoding attempts spent on the params of one
+// message, as every unknown field costs a few
+const maxSchemaDecodes = 64
+
+// unknownFieldPattern matches the error of a decoder refusing a field
+var unknownFieldPattern = regexp.MustCompile(`unknown field "([^"]*)"`)
+
+// schemaOccurrence is a field of the params, held so it can be removed and
+// put back
+type schemaOccurrence struct {
+	object map[string]any
+	name   string
+	value  any
+	path   string
+}
+
+// fieldOccurrences returns every field called name in value, ordered by
+// path
+func fieldOccurrences(value any, name, path string) []schemaOccurrence {
+	var found []schemaOccurrence
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			child := joinSchemaPath(path, key)
+			if key == name {
+				found = append(found, schemaOccurrence{object: v, name: key, value: v[key], path: child})
+			}
+			found = append(found, fieldOccurrences(v[key], name, child)...)
+		}
+	case []any:
+		for i, item := range v {
+			found = append(found, fieldOccurrences(item, name, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return found
+}
+
+// joinSchemaPath appends key to a dotted path
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// isZeroJSON reports whether a decoded JSON value is null, false, zero or
+// empty, which the protocol types leave out when encoding without losing
+// anything
+func isZeroJSON(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// missingFields returns the paths of the fields of sent, other than zero
+// ones, that kept lacks
+func missingFields(sent, kept any, path string) []string {
+	var missing []string
+	switch s := sent.(type) {
+	case map[string]any:
+		k, _ := kept.(map[string]any)
+		for key, value := range s {
+			child := joinSchemaPath(path, key)
+			if keptValue, ok := k[key]; ok {
+				missing = append(missing, missingFields(value, keptValue, child)...)
+			} else if !isZeroJSON(value) {
+				missing = append(missing, child)
+			}
+		}
+	case []any:
+		k, _ := kept.([]any)
+		for i := 0; i < len(s) && i < len(k); i++ {
+			missing = append(missing, missingFields(s[i], k[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return missing
+}
+
+// schemaDrift decodes the params of a message into the protocol type of its
+// method and encodes them again, and returns the paths of the fields the
+// client sent that did not survive: fields the type refuses and fields it
+// silently leaves out. err reports params the type cannot hold at all, such
+// as ones missing a required field. Methods without a protocol type are not
+// checked.
+func schemaDrift(method string, notification bool, params json.RawMessage) (dropped []string, err error) {
+	decodeMessage, ok := protocol.MessageRegistry[method]
+	if !ok || params == nil {
+		return nil, nil
+	}
+	var pruned any
+	if json.Unmarshal(params, &pruned) != nil {
+		return nil, nil
+	}
+
+	decode := func() (protocol.Message, error) {
+		message := map[string]any{"jsonrpc": "2.0", "method": method, "params": pruned}
+		if !notification {
+			message["id"] = 0
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMessage(data)
+	}
+	unknownField := func(err error) string {
+		if match := unknownFieldPattern.FindStringSubmatch(fmt.Sprint(err)); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+
+	// Remove the fields the type refuses one name at a time. A name refused
+	// in one place may be valid in another, so every occurrence is put back
+	// unless that brings the refusal back.
+	var message protocol.Message
+	for attempts := 0; ; attempts++ {
+		if attempts >= maxSchemaDecodes {
+			return dropped, fmt.Errorf("gave up after %d decoding attempts", attempts)
+		}
+		message, err = decode()
+		name := unknownField(err)
+		if err == nil || name == "" {
+			break
+		}
+		occurrences := fieldOccurrences(pruned, name, "")
+		if len(occurrences) == 0 {
+			break
+		}
+		for _, o := range occurrences {
+			delete(o.object, o.name)
+		}
+		for _, o := range occurrences {
+			if attempts++; attempts >= maxSchemaDecodes {
+				return dropped, fmt.Errorf("gave up after %d decoding attempts", attempts)
+			}
+			o.object[o.name] = o.value
+			if _, err := decode(); unknownField(err) == name {
+				delete(o.object, o.name)
+				dropped = append(dropped, o.path)
+			}
+		}
+	}
+	if err != nil {
+		slices.Sort(dropped)
+		return dropped, err
+	}
+
+	typed, ok := message.(interface{ GetParams() any })
+	if !ok {
+		slices.Sort(dropped)
+		return dropped, nil
+	}
+	data, err := encodeWire(typed.GetParams())
+	if err != nil {
+		return dropped, err
+	}
+	var kept any
+	if err := json.Unmarshal(data, &kept); err != nil {
+		return dropped, err
+	}
+	dropped = append(dropped, missingFields(pruned, kept, "")...)
+	slices.Sort(dropped)
+	return slices.Compact(dropped), nil
+}
+
+// checkSchema logs the fields of the params of req that the protocol types
+// drop, when schema validation is configured. It only reports: the message
+// is handled as usual afterwards.
+func (s *MockLSPServer) checkSchema(ctx context.Context, req *jsonrpc.Request) {
+	if !s.config().LSP.SchemaValidation || req.Params == nil {
+		return
+	}
+	dropped, err := schemaDrift(req.Method, req.Notif, *req.Params)
+	if len(dropped) > 0 {
+		s.stats.RecordSchemaDrift(req.Method)
+		s.logError(ctx, "Schema drift in %s params: fields dropped by the protocol types: %s", req.Method, strings.Join(dropped, ", "))
+	}
+	if err != nil {
+		s.logError(ctx, "Schema drift in %s params: %v", req.Method, err)
+	}
+}
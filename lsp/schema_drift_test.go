@@ -0,0 +1,111 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/lsptest"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		notification bool
+		params       string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:   "matching params",
+			method: "textDocument/hover",
+			params: `{"textDocument":{"uri":"file:///a.go"},"position":{"line":1,"character":2}}`,
+		},
+		{
+			name:   "unknown fields at any depth",
+			method: "textDocument/hover",
+			params: `{"textDocument":{"uri":"file:///a.go","etag":"x"},"position":{"line":1,"character":2},"hint":true}`,
+			want:   []string{"hint", "textDocument.etag"},
+		},
+		{
+			name:   "name known elsewhere",
+			method: "textDocument/hover",
+			params: `{"textDocument":{"uri":"file:///a.go","position":1},"position":{"line":1,"character":2}}`,
+			want:   []string{"textDocument.position"},
+		},
+		{
+			name:         "notification",
+			method:       "textDocument/didClose",
+			notification: true,
+			params:       `{"textDocument":{"uri":"file:///a.go"},"reason":"tab"}`,
+			want:         []string{"reason"},
+		},
+		{
+			name:   "nested capabilities",
+			method: "initialize",
+			params: `{"processId":null,"rootUri":null,"capabilities":{"textDocument":{"hover":{"contentFormat":["markdown"],"future":{"on":true}}}}}`,
+			want:   []string{"capabilities.textDocument.hover.future"},
+		},
+		{
+			name:    "missing required field",
+			method:  "textDocument/hover",
+			params:  `{"position":{"line":1,"character":2}}`,
+			wantErr: true,
+		},
+		{
+			name:   "custom method",
+			method: "$/mockLsp/stats",
+			params: `{"anything":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped, err := schemaDrift(tt.method, tt.notification, []byte(tt.params))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if fmt.Sprint(dropped) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected dropped fields %v, got %v", tt.want, dropped)
+			}
+		})
+	}
+}
+
+func TestSchemaDriftGivesUp(t *testing.T) {
+	// Putting back every occurrence of the refused name would take a
+	// decoding attempt each
+	changes := make([]map[string]any, 2*maxSchemaDecodes)
+	for i := range changes {
+		changes[i] = map[string]any{"uri": fmt.Sprintf("file:///%d.go", i), "type": 2, "extra": i}
+	}
+	params, err := json.Marshal(map[string]any{"changes": changes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("gave up after %d decoding attempts", maxSchemaDecodes)
+	if _, err := schemaDrift("workspace/didChangeWatchedFiles", true, params); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.SchemaValidation = true
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	document := map[string]any{"uri": "file:///drift.txt", "languageId": "plaintext", "version": 1, "text": "x\n"}
+	server.Dispatch(ctx, conn, testRequest(t, 0, "textDocument/didOpen", map[string]any{"textDocument": document}))
+	server.Dispatch(ctx, conn, testRequest(t, 0, "textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": "file:///drift.txt"}, "reason": "tab"}))
+
+	if drift := server.Stats().SchemaDrift; len(drift) != 1 || drift["textDocument/didClose"] != 1 {
+		t.Errorf("Expected one drifting didClose, got %v", drift)
+	}
+}
@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// documentURIParams holds the document URI carried by textDocument/* messages
+type documentURIParams struct {
+	TextDocument struct {
+		Uri string `json:"uri"`
+	} `json:"textDocument"`
+}
+
+// uriScheme returns the lower-cased scheme of uri, or "" when it has none
+func uriScheme(uri string) string {
+	scheme, _, ok := strings.Cut(uri, ":")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// schemeAllowed reports whether documents with the scheme of uri are accepted.
+// An empty allow list accepts every scheme.
+func (s *MockLSPServer) schemeAllowed(uri string) bool {
+	allowed := s.config().LSP.AllowedSchemes
+	if len(allowed) == 0 {
+		return true
+	}
+
+	scheme := uriScheme(uri)
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectDisallowedScheme refuses textDocument/* messages whose document URI
+// uses a scheme outside the configured allow list. Requests are answered with
+// an invalid params error and notifications are dropped. It returns true when
+// the message was rejected.
+func (s *MockLSPServer) rejectDisallowedScheme(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	if len(s.config().LSP.AllowedSchemes) == 0 || req.Params == nil || !strings.HasPrefix(req.Method, "textDocument/") {
+		return false
+	}
+
+	var params documentURIParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return false
+	}
+	if s.schemeAllowed(params.TextDocument.Uri) {
+		return false
+	}
+
+	lspErr := NewUnsupportedSchemeError(params.TextDocument.Uri, uriScheme(params.TextDocument.Uri))
+	lspErr = lspErr.WithContext("method", req.Method)
+	if req.Notif {
+		s.errorHandler.HandleError(ctx, lspErr, "reject_document_scheme")
+		return true
+	}
+	if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+		s.logError(ctx, "Failed to send scheme error: %v", err)
+	}
+	return true
+}
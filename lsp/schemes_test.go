@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestURIScheme(t *testing.T) {
+	testCases := []struct {
+		uri      string
+		expected string
+	}{
+		{"file:///test.go", "file"},
+		{"untitled:Untitled-1", "untitled"},
+		{"jdt://contents/rt.jar/java.lang/String.class", "jdt"},
+		{"InMemory:///model/1", "inmemory"},
+		{"no-scheme", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := uriScheme(tc.uri); got != tc.expected {
+			t.Errorf("Expected scheme %q for %s, got %q", tc.expected, tc.uri, got)
+		}
+	}
+}
+
+func TestCustomSchemeDocuments(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.AllowedSchemes = []string{"file", "untitled", "inmemory", "jdt"}
+	server.SetConfig(cfg)
+
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	uris := []protocol.DocumentUri{
+		"untitled:Untitled-1",
+		"inmemory://model/1",
+		"jdt://contents/rt.jar/java.lang/String.class",
+		"vscode-notebook-cell:/nb.ipynb#cell1",
+	}
+	for _, uri := range uris {
+		open := protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{Uri: uri, LanguageId: "java", Text: "class String {}\n"},
+		}
+		if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+			t.Fatalf("didOpen failed: %v", err)
+		}
+	}
+
+	// A request round trip guarantees the notifications were handled
+	var locations []protocol.Location
+	params := protocol.DefinitionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: uris[2]}}
+	if err := client.Call(ctx, "textDocument/definition", params, &locations); err != nil {
+		t.Fatalf("Definition request failed: %v", err)
+	}
+	for _, location := range locations {
+		if uriScheme(string(location.Uri)) != "jdt" {
+			t.Errorf("Expected definition to stay within the jdt scheme, got %s", location.Uri)
+		}
+	}
+
+	if count := server.openDocumentCount(); count != 3 {
+		t.Errorf("Expected 3 open documents, got %d", count)
+	}
+
+	params.TextDocument.Uri = uris[3]
+	if err := client.Call(ctx, "textDocument/definition", params, &locations); err == nil {
+		t.Error("Expected an error for a disallowed scheme")
+	}
+}
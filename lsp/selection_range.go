@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// rangeContains reports whether outer contains inner
+func rangeContains(outer, inner protocol.Range) bool {
+	return !positionBefore(inner.Start, outer.Start) && !positionBefore(outer.End, inner.End)
+}
+
+// selectionRange returns the selection hierarchy at pos in text, from the
+// smallest range outwards: the word at pos, the trimmed line, the contents
+// and then the whole of every enclosing bracket pair, and the document. Each
+// range strictly contains the one before it.
+func selectionRange(text string, pairs []bracketPair, pos protocol.Position) protocol.SelectionRange {
+	pos = clampPosition(text, pos)
+	end := clampPosition(text, protocol.Position{Line: math.MaxUint32})
+	candidates := []protocol.Range{{Start: protocol.Position{}, End: end}}
+
+	if word, ok := wordRangeAt(text, pos); ok && word.Start != word.End {
+		candidates = append(candidates, word)
+	}
+	line, _ := lineAt(text, pos.Line)
+	if trimmed := strings.TrimSpace(line); trimmed != "" {
+		start := utf16Len(line[:strings.Index(line, trimmed)])
+		candidates = append(candidates, protocol.Range{
+			Start: protocol.Position{Line: pos.Line, Character: start},
+			End:   protocol.Position{Line: pos.Line, Character: start + utf16Len(trimmed)},
+		})
+	}
+	for _, pair := range pairs {
+		inner := protocol.Range{
+			Start: protocol.Position{Line: pair.open.Line, Character: pair.open.Character + 1},
+			End:   pair.close,
+		}
+		outer := protocol.Range{
+			Start: pair.open,
+			End:   protocol.Position{Line: pair.close.Line, Character: pair.close.Character + 1},
+		}
+		candidates = append(candidates, inner, outer)
+	}
+
+	// Chain the ranges around pos from the innermost out, dropping those that
+	// do not contain the range chosen before them
+	candidates = slices.DeleteFunc(candidates, func(rng protocol.Range) bool {
+		return positionBefore(pos, rng.Start) || positionBefore(rng.End, pos)
+	})
+	lines := strings.Split(text, "\n")
+	length := func(rng protocol.Range) int {
+		n := int(rng.End.Character) - int(rng.Start.Character)
+		for _, line := range lines[rng.Start.Line:rng.End.Line] {
+			n += int(utf16Len(line)) + 1
+		}
+		return n
+	}
+	slices.SortStableFunc(candidates, func(a, b protocol.Range) int {
+		return cmp.Compare(length(a), length(b))
+	})
+	var chain []protocol.Range
+	for _, rng := range candidates {
+		if len(chain) == 0 || rng != chain[len(chain)-1] && rangeContains(rng, chain[len(chain)-1]) {
+			chain = append(chain, rng)
+		}
+	}
+
+	var result *protocol.SelectionRange
+	for i := len(chain) - 1; i >= 0; i-- {
+		result = &protocol.SelectionRange{Range: chain[i], Parent: result}
+	}
+	return *result
+}
+
+// handleSelectionRange processes textDocument/selectionRange requests,
+// answering with a selection hierarchy for every position of an open
+// document and null for documents the server does not know
+func (s *MockLSPServer) handleSelectionRange(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.SelectionRangeParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse selection range params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send selection range error: %v", replyErr)
+		}
+		return
+	}
+
+	var result []protocol.SelectionRange
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		pairs := bracketPairs(text)
+		result = make([]protocol.SelectionRange, len(params.Positions))
+		for i, pos := range params.Positions {
+			result[i] = selectionRange(text, pairs, pos)
+		}
+		s.logInfo(ctx, "Selection ranges for %d positions in %s", len(result), uri)
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send selection range response: %v", err)
+	}
+}
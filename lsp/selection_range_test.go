@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// fmtSelection lists the ranges of a selection hierarchy, innermost first
+func fmtSelection(selection *protocol.SelectionRange) []string {
+	var ranges []string
+	for ; selection != nil; selection = selection.Parent {
+		rng := selection.Range
+		ranges = append(ranges, fmt.Sprintf("%d:%d-%d:%d", rng.Start.Line, rng.Start.Character, rng.End.Line, rng.End.Character))
+	}
+	return ranges
+}
+
+func TestSelectionRange(t *testing.T) {
+	text := "func main() {\n\tcall(first, second)\n}\n"
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []string
+	}{
+		{"argument", protocol.Position{Line: 1, Character: 8}, []string{"1:6-1:11", "1:6-1:19", "1:5-1:20", "1:1-1:20", "0:13-2:0", "0:12-2:1", "0:0-3:0"}},
+		{"between words", protocol.Position{Line: 1, Character: 12}, []string{"1:6-1:19", "1:5-1:20", "1:1-1:20", "0:13-2:0", "0:12-2:1", "0:0-3:0"}},
+		{"outside brackets", protocol.Position{Line: 0, Character: 2}, []string{"0:0-0:4", "0:0-0:13", "0:0-3:0"}},
+		{"past the end", protocol.Position{Line: 9, Character: 0}, []string{"0:0-3:0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := selectionRange(text, bracketPairs(text), tt.pos)
+			if got := fmtSelection(&selection); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectionRangeRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	document := map[string]any{"uri": "file:///select.txt", "languageId": "plaintext", "version": 1, "text": "one (two)\nthree\n"}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": "file:///select.txt"},
+		"positions":    []any{map[string]any{"line": 0, "character": 6}, map[string]any{"line": 1, "character": 1}},
+	}
+	var selections []protocol.SelectionRange
+	if err := client.Call(ctx, "textDocument/selectionRange", params, &selections); err != nil {
+		t.Fatalf("Selection range request failed: %v", err)
+	}
+	if len(selections) != 2 {
+		t.Fatalf("Expected a hierarchy per position, got %+v", selections)
+	}
+	if got := fmt.Sprint(fmtSelection(&selections[0])); got != "[0:5-0:8 0:4-0:9 0:0-0:9 0:0-2:0]" {
+		t.Errorf("Unexpected hierarchy for the first position: %s", got)
+	}
+	if got := fmt.Sprint(fmtSelection(&selections[1])); got != "[1:0-1:5 0:0-2:0]" {
+		t.Errorf("Unexpected hierarchy for the second position: %s", got)
+	}
+
+	params["textDocument"] = map[string]any{"uri": "file:///unopened.txt"}
+	selections = nil
+	if err := client.Call(ctx, "textDocument/selectionRange", params, &selections); err != nil || selections != nil {
+		t.Errorf("Expected null for an unopened document, got %+v and %v", selections, err)
+	}
+}
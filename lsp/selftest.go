@@ -0,0 +1,100 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/scenario"
+)
+
+// The self-test session, in the fixture format of -capture-fixtures, and
+// the scenario whose expectations check the responses to it
+var (
+	//go:embed selftest/session.json
+	SelftestSession []byte
+	//go:embed selftest/scenario.json
+	SelftestScenario []byte
+)
+
+// SelftestStep reports one message of the self-test session
+type SelftestStep struct {
+	Method string `json:"method"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SelftestReport is the outcome of a self-test: the steps of the session in
+// order and the differences from the scenario expectations
+type SelftestReport struct {
+	Passed       bool                 `json:"passed"`
+	Steps        []SelftestStep       `json:"steps"`
+	Expectations []ExpectationFailure `json:"expectations,omitempty"`
+}
+
+// selftestNonNull lists the requests of the self-test whose result must not
+// be null, since the session asks them about an open document
+var selftestNonNull = map[string]bool{
+	"initialize":              true,
+	"textDocument/completion": true,
+	"textDocument/hover":      true,
+	"textDocument/rename":     true,
+}
+
+// RunSelftest replays the embedded self-test session against server over an
+// in-process connection, with the embedded scenario set so its expectations
+// check the responses. A request passes when it is answered without error
+// and, where selftestNonNull says so, with a result; a notification passes
+// when it is sent. The report passes when every step does and the responses
+// met the expectations. Errors are returned for assets that do not load.
+func RunSelftest(ctx context.Context, server *MockLSPServer) (*SelftestReport, error) {
+	var session scenario.Fixture
+	if err := json.Unmarshal(SelftestSession, &session); err != nil {
+		return nil, fmt.Errorf("invalid self-test session: %w", err)
+	}
+	sc, err := scenario.Parse(SelftestScenario)
+	if err != nil {
+		return nil, fmt.Errorf("invalid self-test scenario: %w", err)
+	}
+	server.SetScenario(sc)
+
+	serverSide, clientSide := net.Pipe()
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server, server.ConnOpts()...)
+	defer serverConn.Close()
+	conn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+			return nil, nil
+		}))
+	defer conn.Close()
+
+	report := &SelftestReport{Passed: true, Steps: []SelftestStep{}}
+	for _, message := range session.Messages {
+		step := SelftestStep{Method: message.Method, Passed: true}
+		var params any
+		if message.Params != nil {
+			params = message.Params
+		}
+		if message.Request {
+			var result json.RawMessage
+			if err := conn.Call(ctx, message.Method, params, &result); err != nil {
+				step.Passed, step.Error = false, err.Error()
+			} else if selftestNonNull[message.Method] && (result == nil || bytes.Equal(result, []byte("null"))) {
+				step.Passed, step.Error = false, "null result"
+			}
+		} else if err := conn.Notify(ctx, message.Method, params); err != nil {
+			step.Passed, step.Error = false, err.Error()
+		}
+		report.Passed = report.Passed && step.Passed
+		report.Steps = append(report.Steps, step)
+	}
+
+	report.Expectations = server.ExpectationFailures()
+	if len(report.Expectations) > 0 {
+		report.Passed = false
+	}
+	return report, nil
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+func TestRunSelftest(t *testing.T) {
+	report, err := RunSelftest(context.Background(), createTestServer())
+	if err != nil {
+		t.Fatalf("Self-test failed to run: %v", err)
+	}
+	if !report.Passed {
+		t.Errorf("Expected the self-test to pass, got %+v", report)
+	}
+	var methods []string
+	for _, step := range report.Steps {
+		methods = append(methods, step.Method)
+	}
+	for _, method := range []string{"textDocument/didOpen", "textDocument/didChange", "textDocument/completion", "textDocument/hover", "textDocument/rename", "textDocument/didSave", "textDocument/didClose", "shutdown"} {
+		if !slices.Contains(methods, method) {
+			t.Errorf("Expected the session to send %s, got %v", method, methods)
+		}
+	}
+}
+
+func TestRunSelftestFailures(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.RenameConfig.EditsPerFile = 1
+	cfg.LSP.Features = map[string]bool{"hover": false}
+	server.SetConfig(cfg)
+
+	report, err := RunSelftest(context.Background(), server)
+	if err != nil {
+		t.Fatalf("Self-test failed to run: %v", err)
+	}
+	if report.Passed {
+		t.Fatal("Expected the self-test to fail")
+	}
+	for _, step := range report.Steps {
+		if step.Passed != (step.Method != "textDocument/hover") {
+			t.Errorf("Expected only hover to fail, got %+v", step)
+		}
+	}
+	if len(report.Expectations) != 1 || report.Expectations[0].Method != "textDocument/rename" {
+		t.Errorf("Expected the rename expectation to fail, got %+v", report.Expectations)
+	}
+}
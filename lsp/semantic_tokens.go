@@ -0,0 +1,310 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// semanticTokensLegend is the legend announced at initialize. Identifiers
+// get one of the types before keyword, picked by hashing the word.
+var semanticTokensLegend = protocol.SemanticTokensLegend{
+	TokenTypes: []string{
+		"namespace", "type", "class", "function", "method", "variable", "parameter", "property",
+		"keyword", "string", "number", "comment",
+	},
+	TokenModifiers: []string{"declaration", "readonly", "static", "deprecated"},
+}
+
+// Indexes of the token types and modifiers in semanticTokensLegend
+const (
+	tokenIdentifierTypes = 8
+	tokenKeyword         = 8
+	tokenString          = 9
+	tokenNumber          = 10
+	tokenComment         = 11
+
+	modifierDeclaration = 1 << 0
+	modifierReadonly    = 1 << 1
+	modifierStatic      = 1 << 2
+	modifierDeprecated  = 1 << 3
+)
+
+// semanticKeywords are the words highlighted as keywords, common to the
+// languages editors usually test with
+var semanticKeywords = map[string]bool{
+	"package": true, "import": true, "func": true, "function": true, "def": true, "class": true,
+	"type": true, "struct": true, "interface": true, "var": true, "let": true, "const": true,
+	"if": true, "else": true, "for": true, "while": true, "return": true,
+	"true": true, "false": true, "nil": true, "null": true,
+}
+
+// semanticToken is a token at an absolute position, in UTF-16 code units
+type semanticToken struct {
+	line, start, length  uint32
+	tokenType, modifiers uint32
+}
+
+// semanticTokensResult is the last full result sent for a document, kept so
+// a later delta request can be answered with the edits to it
+type semanticTokensResult struct {
+	id   string
+	data []uint32
+}
+
+// identifierToken returns the token of an identifier: its type and
+// modifiers are derived from a hash of the word, so the same word is
+// highlighted the same way everywhere. The first occurrence in the
+// document is its declaration.
+func identifierToken(word string, seen map[string]bool) (tokenType, modifiers uint32) {
+	h := fnv.New32a()
+	h.Write([]byte(word))
+	hash := h.Sum32()
+
+	tokenType = hash % tokenIdentifierTypes
+	if !seen[word] {
+		seen[word] = true
+		modifiers |= modifierDeclaration
+	}
+	if hash>>8&1 == 1 {
+		modifiers |= modifierReadonly
+	}
+	if hash>>9&1 == 1 {
+		modifiers |= modifierStatic
+	}
+	if hash>>10&7 == 0 {
+		modifiers |= modifierDeprecated
+	}
+	return tokenType, modifiers
+}
+
+// tokenizeLine returns the tokens of one line: line comments starting with
+// //, double-quoted strings, numbers, keywords and identifiers. Nothing
+// spans lines.
+func tokenizeLine(number uint32, line string, seen map[string]bool) []semanticToken {
+	line = strings.TrimSuffix(line, "\r")
+	var tokens []semanticToken
+	add := func(start, end int, tokenType, modifiers uint32) {
+		tokens = append(tokens, semanticToken{
+			line:      number,
+			start:     utf16Len(line[:start]),
+			length:    utf16Len(line[start:end]),
+			tokenType: tokenType,
+			modifiers: modifiers,
+		})
+	}
+
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case strings.HasPrefix(line[i:], "//"):
+			add(i, len(line), tokenComment, 0)
+			return tokens
+		case r == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			add(i, end, tokenString, 0)
+			i = end
+		case isWordRune(r):
+			end := i + strings.IndexFunc(line[i:]+" ", func(r rune) bool { return !isWordRune(r) })
+			word := line[i:end]
+			switch {
+			case unicode.IsDigit(r):
+				add(i, end, tokenNumber, 0)
+			case semanticKeywords[word]:
+				add(i, end, tokenKeyword, 0)
+			default:
+				tokenType, modifiers := identifierToken(word, seen)
+				add(i, end, tokenType, modifiers)
+			}
+			i = end
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
+
+// semanticTokens returns the tokens of text in document order
+func semanticTokens(text string) []semanticToken {
+	var tokens []semanticToken
+	seen := map[string]bool{}
+	for number, line := range strings.Split(text, "\n") {
+		tokens = append(tokens, tokenizeLine(uint32(number), line, seen)...)
+	}
+	return tokens
+}
+
+// encodeSemanticTokens encodes tokens as the protocol's integer array: each
+// token's line is relative to the previous token, and so is its start when
+// both are on the same line
+func encodeSemanticTokens(tokens []semanticToken) []uint32 {
+	data := make([]uint32, 0, 5*len(tokens))
+	var line, start uint32
+	for _, token := range tokens {
+		deltaStart := token.start
+		if token.line == line {
+			deltaStart -= start
+		}
+		data = append(data, token.line-line, deltaStart, token.length, token.tokenType, token.modifiers)
+		line, start = token.line, token.start
+	}
+	return data
+}
+
+// tokensInRange returns the tokens lying entirely within rng
+func tokensInRange(tokens []semanticToken, rng protocol.Range) []semanticToken {
+	var inRange []semanticToken
+	for _, token := range tokens {
+		start := protocol.Position{Line: token.line, Character: token.start}
+		end := protocol.Position{Line: token.line, Character: token.start + token.length}
+		if !positionBefore(start, rng.Start) && !positionBefore(rng.End, end) {
+			inRange = append(inRange, token)
+		}
+	}
+	return inRange
+}
+
+// positionBefore reports whether a comes before b
+func positionBefore(a, b protocol.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+// semanticTokensEdits returns the edits turning previous into current: a
+// single edit replacing what lies between their common prefix and suffix,
+// or none when they are equal
+func semanticTokensEdits(previous, current []uint32) []protocol.SemanticTokensEdit {
+	prefix := 0
+	for prefix < len(previous) && prefix < len(current) && previous[prefix] == current[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(current)-prefix &&
+		previous[len(previous)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(previous) && prefix == len(current) {
+		return []protocol.SemanticTokensEdit{}
+	}
+	return []protocol.SemanticTokensEdit{{
+		Start:       uint32(prefix),
+		DeleteCount: uint32(len(previous) - prefix - suffix),
+		Data:        current[prefix : len(current)-suffix],
+	}}
+}
+
+// storeSemanticTokens keeps data as the latest full result for uri under a
+// new result ID, and returns the result it replaces
+func (s *MockLSPServer) storeSemanticTokens(uri string, data []uint32) (current, previous semanticTokensResult, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokenResults == nil {
+		s.tokenResults = make(map[string]semanticTokensResult)
+	}
+	s.tokenResultSeq++
+	current = semanticTokensResult{id: fmt.Sprint(s.tokenResultSeq), data: data}
+	previous, ok = s.tokenResults[uri]
+	s.tokenResults[uri] = current
+	return current, previous, ok
+}
+
+// handleSemanticTokensFull processes textDocument/semanticTokens/full
+// requests, answering with the tokens of an open document and null for
+// documents the server does not know
+func (s *MockLSPServer) handleSemanticTokensFull(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.SemanticTokensParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse semantic tokens params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send semantic tokens error: %v", replyErr)
+		}
+		return
+	}
+
+	var result *protocol.SemanticTokens
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		current, _, _ := s.storeSemanticTokens(uri, encodeSemanticTokens(semanticTokens(text)))
+		result = &protocol.SemanticTokens{ResultId: current.id, Data: current.data}
+		s.logInfo(ctx, "Semantic tokens for %s: %d tokens, result %s", uri, len(current.data)/5, current.id)
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send semantic tokens response: %v", err)
+	}
+}
+
+// handleSemanticTokensDelta processes textDocument/semanticTokens/full/delta
+// requests. When previousResultId names the last result sent for the
+// document the answer holds the edits to it, otherwise the full tokens.
+func (s *MockLSPServer) handleSemanticTokensDelta(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.SemanticTokensDeltaParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse semantic tokens delta params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send semantic tokens delta error: %v", replyErr)
+		}
+		return
+	}
+
+	var result any
+	uri := string(params.TextDocument.Uri)
+	if text, ok := s.documentText(uri); ok {
+		current, previous, ok := s.storeSemanticTokens(uri, encodeSemanticTokens(semanticTokens(text)))
+		if ok && previous.id == params.PreviousResultId {
+			edits := semanticTokensEdits(previous.data, current.data)
+			result = protocol.SemanticTokensDelta{ResultId: current.id, Edits: edits}
+			s.logInfo(ctx, "Semantic tokens delta for %s from result %s: %d edits, result %s", uri, previous.id, len(edits), current.id)
+		} else {
+			result = protocol.SemanticTokens{ResultId: current.id, Data: current.data}
+			s.logInfo(ctx, "Semantic tokens for %s: unknown result %q, sending all %d tokens, result %s",
+				uri, params.PreviousResultId, len(current.data)/5, current.id)
+		}
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send semantic tokens delta response: %v", err)
+	}
+}
+
+// handleSemanticTokensRange processes textDocument/semanticTokens/range
+// requests, answering with the tokens lying entirely within the range.
+// Range results have no result ID, as deltas always apply to full results.
+func (s *MockLSPServer) handleSemanticTokensRange(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.SemanticTokensRangeParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse semantic tokens range params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send semantic tokens range error: %v", replyErr)
+		}
+		return
+	}
+
+	var result *protocol.SemanticTokens
+	if text, ok := s.documentText(string(params.TextDocument.Uri)); ok {
+		tokens := tokensInRange(semanticTokens(text), params.Range)
+		result = &protocol.SemanticTokens{Data: encodeSemanticTokens(tokens)}
+		s.logInfo(ctx, "Semantic tokens for lines %d-%d of %s: %d tokens",
+			params.Range.Start.Line, params.Range.End.Line, params.TextDocument.Uri, len(tokens))
+	}
+	if err := s.reply(ctx, conn, req, result); err != nil {
+		s.logError(ctx, "Failed to send semantic tokens range response: %v", err)
+	}
+}
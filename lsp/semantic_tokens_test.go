@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestTokenizeLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		types []uint32
+		spans [][2]uint32 // start and length
+	}{
+		{"keywords and numbers", "return 42", []uint32{tokenKeyword, tokenNumber}, [][2]uint32{{0, 6}, {7, 2}}},
+		{"comment to the end", "x // note \"here\"\r", []uint32{identifierType("x"), tokenComment}, [][2]uint32{{0, 1}, {2, 14}}},
+		{"escaped quote", `s = "a\"b" + t`, []uint32{identifierType("s"), tokenString, identifierType("t")}, [][2]uint32{{0, 1}, {4, 6}, {13, 1}}},
+		{"unterminated string", `"open`, []uint32{tokenString}, [][2]uint32{{0, 5}}},
+		{"UTF-16 positions", "é 😀 name", []uint32{identifierType("é"), identifierType("name")}, [][2]uint32{{0, 1}, {5, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tokenizeLine(3, tt.line, map[string]bool{})
+			if len(tokens) != len(tt.types) {
+				t.Fatalf("Expected %d tokens, got %+v", len(tt.types), tokens)
+			}
+			for i, token := range tokens {
+				if token.line != 3 || token.tokenType != tt.types[i] || token.start != tt.spans[i][0] || token.length != tt.spans[i][1] {
+					t.Errorf("Token %d: expected type %d at %v, got %+v", i, tt.types[i], tt.spans[i], token)
+				}
+			}
+		})
+	}
+}
+
+// identifierType returns the token type the tokenizer gives word
+func identifierType(word string) uint32 {
+	tokenType, _ := identifierToken(word, map[string]bool{})
+	return tokenType
+}
+
+func TestIdentifierToken(t *testing.T) {
+	seen := map[string]bool{}
+	firstType, first := identifierToken("mockValue", seen)
+	againType, again := identifierToken("mockValue", seen)
+	if firstType != againType || first&modifierDeclaration == 0 || again&modifierDeclaration != 0 || first&^modifierDeclaration != again {
+		t.Errorf("Expected the same highlighting with only the first occurrence declared, got %d/%b and %d/%b", firstType, first, againType, again)
+	}
+}
+
+func TestEncodeSemanticTokens(t *testing.T) {
+	tokens := []semanticToken{
+		{line: 0, start: 4, length: 3, tokenType: 1},
+		{line: 0, start: 10, length: 2, tokenType: 2, modifiers: 1},
+		{line: 2, start: 1, length: 5, tokenType: 3},
+	}
+	want := []uint32{0, 4, 3, 1, 0, 0, 6, 2, 2, 1, 2, 1, 5, 3, 0}
+	if got := encodeSemanticTokens(tokens); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := encodeSemanticTokens(nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected empty data, got %v", got)
+	}
+}
+
+// applySemanticTokensEdits applies delta edits to data, as a client would
+func applySemanticTokensEdits(data []uint32, edits []protocol.SemanticTokensEdit) []uint32 {
+	result := slices.Clone(data)
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		result = slices.Replace(result, int(edit.Start), int(edit.Start+edit.DeleteCount), edit.Data...)
+	}
+	return result
+}
+
+func TestSemanticTokensEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []uint32
+		current  []uint32
+	}{
+		{"equal", []uint32{1, 2, 3}, []uint32{1, 2, 3}},
+		{"changed middle", []uint32{1, 2, 3, 4, 5}, []uint32{1, 2, 9, 4, 5}},
+		{"appended", []uint32{1, 2}, []uint32{1, 2, 3, 4}},
+		{"removed", []uint32{1, 2, 3, 4}, []uint32{1, 4}},
+		{"repeated values", []uint32{0, 0, 0}, []uint32{0, 0, 0, 0, 0}},
+		{"emptied", []uint32{1, 2}, []uint32{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := semanticTokensEdits(tt.previous, tt.current)
+			if got := applySemanticTokensEdits(tt.previous, edits); !slices.Equal(got, tt.current) {
+				t.Errorf("Applying %+v gave %v, want %v", edits, got, tt.current)
+			}
+			if slices.Equal(tt.previous, tt.current) && len(edits) != 0 {
+				t.Errorf("Expected no edits, got %+v", edits)
+			}
+		})
+	}
+}
+
+func TestSemanticTokensRequests(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var initResult struct {
+		Capabilities struct {
+			SemanticTokensProvider struct {
+				Legend struct {
+					TokenTypes []string `json:"tokenTypes"`
+				} `json:"legend"`
+				Full struct {
+					Delta bool `json:"delta"`
+				} `json:"full"`
+				Range bool `json:"range"`
+			} `json:"semanticTokensProvider"`
+		} `json:"capabilities"`
+	}
+	initialize := map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}
+	if err := client.Call(ctx, "initialize", initialize, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	provider := initResult.Capabilities.SemanticTokensProvider
+	if !slices.Equal(provider.Legend.TokenTypes, semanticTokensLegend.TokenTypes) || !provider.Full.Delta || !provider.Range {
+		t.Errorf("Expected full, delta and range support with the legend, got %+v", provider)
+	}
+
+	uri := "file:///tokens.txt"
+	text := "func main() {\n\tcount := 1\n\treturn count\n}\n"
+	document := map[string]any{"uri": uri, "languageId": "plaintext", "version": 1, "text": text}
+	if err := client.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	type tokens struct {
+		ResultID string   `json:"resultId"`
+		Data     []uint32 `json:"data"`
+		Edits    []struct {
+			Start       uint32   `json:"start"`
+			DeleteCount uint32   `json:"deleteCount"`
+			Data        []uint32 `json:"data"`
+		} `json:"edits"`
+	}
+	textDocument := map[string]any{"uri": uri}
+	var full tokens
+	if err := client.Call(ctx, "textDocument/semanticTokens/full", map[string]any{"textDocument": textDocument}, &full); err != nil {
+		t.Fatalf("Semantic tokens failed: %v", err)
+	}
+	if want := encodeSemanticTokens(semanticTokens(text)); full.ResultID == "" || !slices.Equal(full.Data, want) || len(want) != 6*5 {
+		t.Errorf("Expected 6 tokens with a result ID, got %+v", full)
+	}
+
+	change := map[string]any{
+		"textDocument": map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{map[string]any{
+			"range": map[string]any{"start": map[string]any{"line": 2, "character": 8}, "end": map[string]any{"line": 2, "character": 13}},
+			"text":  "count + 2",
+		}},
+	}
+	if err := client.Notify(ctx, "textDocument/didChange", change); err != nil {
+		t.Fatalf("didChange failed: %v", err)
+	}
+	var delta tokens
+	params := map[string]any{"textDocument": textDocument, "previousResultId": full.ResultID}
+	if err := client.Call(ctx, "textDocument/semanticTokens/full/delta", params, &delta); err != nil {
+		t.Fatalf("Semantic tokens delta failed: %v", err)
+	}
+	var edits []protocol.SemanticTokensEdit
+	for _, edit := range delta.Edits {
+		edits = append(edits, protocol.SemanticTokensEdit{Start: edit.Start, DeleteCount: edit.DeleteCount, Data: edit.Data})
+	}
+	want := encodeSemanticTokens(semanticTokens("func main() {\n\tcount := 1\n\treturn count + 2\n}\n"))
+	if delta.ResultID == full.ResultID || delta.Data != nil || !slices.Equal(applySemanticTokensEdits(full.Data, edits), want) {
+		t.Errorf("Expected edits to the new tokens %v under a new result ID, got %+v", want, delta)
+	}
+
+	var stale tokens
+	params["previousResultId"] = full.ResultID
+	if err := client.Call(ctx, "textDocument/semanticTokens/full/delta", params, &stale); err != nil {
+		t.Fatalf("Semantic tokens delta failed: %v", err)
+	}
+	if stale.Edits != nil || !slices.Equal(stale.Data, want) {
+		t.Errorf("Expected all tokens for a superseded result ID, got %+v", stale)
+	}
+
+	var inRange tokens
+	rng := map[string]any{"start": map[string]any{"line": 1, "character": 0}, "end": map[string]any{"line": 1, "character": 20}}
+	if err := client.Call(ctx, "textDocument/semanticTokens/range", map[string]any{"textDocument": textDocument, "range": rng}, &inRange); err != nil {
+		t.Fatalf("Semantic tokens range failed: %v", err)
+	}
+	if inRange.ResultID != "" || len(inRange.Data) != 2*5 || inRange.Data[0] != 1 || inRange.Data[1] != 1 {
+		t.Errorf("Expected the 2 tokens of line 1 without a result ID, got %+v", inRange)
+	}
+
+	var unopened *tokens
+	if err := client.Call(ctx, "textDocument/semanticTokens/full", map[string]any{"textDocument": map[string]any{"uri": "file:///unopened.txt"}}, &unopened); err != nil {
+		t.Fatalf("Semantic tokens failed: %v", err)
+	}
+	if unopened != nil {
+		t.Errorf("Expected null for an unopened document, got %+v", unopened)
+	}
+
+	if err := client.Call(ctx, "$/mockLsp/setFeatures", map[string]any{"features": map[string]any{"semantic_tokens": false}}, nil); err != nil {
+		t.Fatalf("setFeatures failed: %v", err)
+	}
+	err := client.Call(ctx, "textDocument/semanticTokens/range", map[string]any{"textDocument": textDocument, "range": rng}, nil)
+	if errorCode(err) != int64(ErrorCodeMethodNotFound) {
+		t.Errorf("Expected MethodNotFound with semantic tokens switched off, got %v", err)
+	}
+}
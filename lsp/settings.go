@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// defaultSettingsSection is the settings section read when the
+// configuration leaves it empty
+const defaultSettingsSection = "mockLsp"
+
+// settingsSection returns the name of the settings section holding the
+// configuration overrides
+func (s *MockLSPServer) settingsSection() string {
+	if section := s.config().LSP.ClientSettings.Section; section != "" {
+		return section
+	}
+	return defaultSettingsSection
+}
+
+// applySettings merges the settings from the client into the running
+// configuration, the way initializationOptions are merged: keys are those of
+// the "lsp" section of the config file, nested objects are merged and lists
+// are replaced. Settings that leave the configuration invalid are rejected
+// as a whole, warning the client through window/logMessage. Features
+// switched on or off are registered or unregistered with clients using
+// dynamic registration.
+func (s *MockLSPServer) applySettings(ctx context.Context, conn Conn, settings json.RawMessage) {
+	cfg, ignored, err := s.config().ApplyLSPOptions(settings)
+	if err != nil {
+		s.logError(ctx, "Rejected client settings: %v", err)
+		message := protocol.LogMessageParams{Type: protocol.MessageTypeWarning, Message: s.message(msgSettingsRejected, err)}
+		if err := s.notify(ctx, conn, "window/logMessage", message); err != nil {
+			s.logError(ctx, "Failed to send settings rejection message: %v", err)
+		}
+		return
+	}
+	if ignored != nil {
+		s.logInfo(ctx, "Unrecognized client settings keys: %v", ignored)
+	}
+
+	// Keep the trace ID of the session unless the settings set one
+	if cfg.LSP.TraceMetadata.TraceID == "" {
+		cfg.LSP.TraceMetadata.TraceID = s.snapshot().traceID
+	}
+	s.SetConfig(cfg)
+	s.logInfo(ctx, "Applied client settings: %s", settings)
+	if registered, unregistered := s.syncRegistrations(ctx, conn); len(registered)+len(unregistered) > 0 {
+		s.logInfo(ctx, "Client settings registered %v and unregistered %v", registered, unregistered)
+	}
+}
+
+// pullSettings requests the settings section with workspace/configuration
+// and applies the answer. Clients answer null for sections they do not
+// have, which changes nothing.
+func (s *MockLSPServer) pullSettings(ctx context.Context, conn Conn) {
+	section := s.settingsSection()
+	var settings []json.RawMessage
+	params := protocol.ConfigurationParams{Items: []protocol.ConfigurationItem{{Section: section}}}
+	if err := s.call(ctx, conn, "workspace/configuration", params, &settings); err != nil {
+		s.logError(ctx, "workspace/configuration failed: %v", err)
+		return
+	}
+	if len(settings) == 0 || settings[0] == nil || bytes.Equal(settings[0], []byte("null")) {
+		s.logInfo(ctx, "Client has no %s settings", section)
+		return
+	}
+	s.applySettings(ctx, conn, settings[0])
+}
+
+// startSettingsPull pulls the client settings in the background when the
+// configuration asks for it, since handlers cannot wait for client responses
+func (s *MockLSPServer) startSettingsPull(ctx context.Context, conn Conn) {
+	if s.config().LSP.ClientSettings.Pull {
+		go s.pullSettings(context.WithoutCancel(ctx), conn)
+	}
+}
+
+// handleDidChangeConfiguration processes workspace/didChangeConfiguration
+// notifications. With pulling configured the notification only signals a
+// change and the settings are requested with workspace/configuration;
+// otherwise the settings section is read from the notification itself.
+func (s *MockLSPServer) handleDidChangeConfiguration(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if s.config().LSP.ClientSettings.Pull {
+		s.startSettingsPull(ctx, conn)
+		return
+	}
+
+	var params struct {
+		Settings map[string]json.RawMessage `json:"settings"`
+	}
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		s.logError(ctx, "Failed to parse didChangeConfiguration params")
+		return
+	}
+
+	section := s.settingsSection()
+	settings, ok := params.Settings[section]
+	if !ok || bytes.Equal(settings, []byte("null")) {
+		s.logInfo(ctx, "Configuration changed without %s settings", section)
+		return
+	}
+	s.applySettings(ctx, conn, settings)
+}
@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/lsptest"
+)
+
+func TestDidChangeConfiguration(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+	traceID := server.snapshot().traceID
+
+	settings := map[string]any{"mockLsp": map[string]any{
+		"completion":  map[string]any{"max_items": 2},
+		"diagnostics": map[string]any{"enabled": false},
+	}}
+	server.Dispatch(ctx, conn, testRequest(t, 0, "workspace/didChangeConfiguration", map[string]any{"settings": settings}))
+	if server.config().LSP.CompletionConfig.MaxItems != 2 || server.config().LSP.DiagnosticsConfig.Enabled {
+		t.Errorf("Expected the settings to be applied, got %+v and %+v", server.config().LSP.CompletionConfig, server.config().LSP.DiagnosticsConfig)
+	}
+	if server.config().LSP.CompletionConfig.TriggerCharacters == nil {
+		t.Error("Expected the other completion settings to be kept")
+	}
+	if server.snapshot().traceID != traceID {
+		t.Errorf("Expected the trace ID %s to be kept, got %s", traceID, server.snapshot().traceID)
+	}
+
+	settings = map[string]any{"mockLsp": map[string]any{"failure_preset": "bogus"}}
+	server.Dispatch(ctx, conn, testRequest(t, 0, "workspace/didChangeConfiguration", map[string]any{"settings": settings}))
+	if server.config().LSP.FailurePreset != "" || server.config().LSP.CompletionConfig.MaxItems != 2 {
+		t.Errorf("Expected invalid settings to be rejected as a whole, got preset %q", server.config().LSP.FailurePreset)
+	}
+	warnings := conn.Sent("window/logMessage")
+	if len(warnings) != 1 || !strings.Contains(string(warnings[0].Params), "settings rejected") {
+		t.Errorf("Expected a warning about the rejected settings, got %+v", warnings)
+	}
+
+	server.Dispatch(ctx, conn, testRequest(t, 0, "workspace/didChangeConfiguration", map[string]any{"settings": map[string]any{"other": true}}))
+	if server.config().LSP.CompletionConfig.MaxItems != 2 {
+		t.Error("Expected settings without the section to change nothing")
+	}
+}
+
+func TestPullSettings(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.ClientSettings = config.ClientSettingsConfig{Section: "mock", Pull: true}
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	conn.Answer = func(method string, _ json.RawMessage) (any, error) {
+		return []any{map[string]any{"hover": map[string]any{"max_length": 200}}}, nil
+	}
+	ctx := context.Background()
+
+	server.pullSettings(ctx, conn)
+	if server.config().LSP.HoverConfig.MaxLength != 200 {
+		t.Errorf("Expected the pulled settings to be applied, got max length %d", server.config().LSP.HoverConfig.MaxLength)
+	}
+
+	conn = lsptest.NewConn()
+	defer conn.Close()
+	server.Dispatch(ctx, conn, testRequest(t, 0, "workspace/didChangeConfiguration", map[string]any{"settings": nil}))
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	request, err := conn.WaitFor(waitCtx, func(m lsptest.Message) bool { return m.Method == "workspace/configuration" })
+	if err != nil {
+		t.Fatalf("Expected a change to pull the settings: %v", err)
+	}
+	if !strings.Contains(string(request.Params), `"section":"mock"`) {
+		t.Errorf("Expected the configured section to be requested, got %s", request.Params)
+	}
+}
+
+func TestApplySettingsKeepsRandomSource(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+	server.SetDataset(&MockDataset{Seed: 7})
+	random := server.snapshot().random
+
+	server.applySettings(ctx, conn, json.RawMessage(`{"hover": {"max_length": 50}}`))
+	if server.snapshot().random != random {
+		t.Error("Expected settings leaving the seed alone to keep the random source of the dataset")
+	}
+
+	server.applySettings(ctx, conn, json.RawMessage(`{"mock_data": {"seed": 9}}`))
+	if server.snapshot().random == random {
+		t.Error("Expected a new seed to replace the random source")
+	}
+}
+
+func TestApplySettingsWhileHandling(t *testing.T) {
+	server := createTestServer()
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 20 {
+			server.applySettings(ctx, conn, json.RawMessage(fmt.Sprintf(`{"completion": {"max_items": %d}}`, i+1)))
+		}
+	}()
+	for i := range 20 {
+		server.Dispatch(ctx, conn, testRequest(t, uint64(i+1), "textDocument/hover", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///test.go"},
+			"position":     map[string]any{"line": 0, "character": 0},
+		}))
+	}
+	<-done
+	if server.config().LSP.CompletionConfig.MaxItems != 20 {
+		t.Errorf("Expected the last settings to be in effect, got max items %d", server.config().LSP.CompletionConfig.MaxItems)
+	}
+}
@@ -0,0 +1,167 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// inflightRequest is a request whose handler has not returned yet
+type inflightRequest struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// trackRequest records req as in flight until the returned function is
+// called. The returned context is cancelled by $/cancelRequest, also when it
+// arrived before the request started, and when shutdown stops waiting for
+// the request.
+func (s *MockLSPServer) trackRequest(ctx context.Context, req *jsonrpc.Request) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	request := &inflightRequest{cancel: cancel, done: make(chan struct{})}
+
+	s.mu.Lock()
+	if s.inflight == nil {
+		s.inflight = make(map[jsonrpc.ID]*inflightRequest)
+	}
+	s.inflight[req.ID] = request
+	if s.takePendingCancelLocked(req.ID) {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	return ctx, func() {
+		s.mu.Lock()
+		if s.inflight[req.ID] == request {
+			delete(s.inflight, req.ID)
+		}
+		// A cancel kept for the ID must not cancel a later request reusing it
+		s.takePendingCancelLocked(req.ID)
+		s.mu.Unlock()
+		cancel()
+		close(request.done)
+	}
+}
+
+// ShutdownDrainTimeout returns how long shutdown waits for the requests
+// ahead of it to finish before cancelling them. A config that was not
+// validated and leaves it at zero gets the default rather than cancelling
+// every request at once.
+func (s *MockLSPServer) ShutdownDrainTimeout() time.Duration {
+	if timeout := s.config().LSP.ShutdownDrainTimeout.Duration(); timeout > 0 {
+		return timeout
+	}
+	return config.DefaultShutdownDrainTimeout
+}
+
+// drainRequests waits up to the configured drain timeout for the requests
+// in flight other than the shutdown request itself to finish, then cancels
+// the rest. Cancelled requests answer with RequestCancelled. Requests
+// handled one at a time by NewOrderedHandler are drained by it instead.
+func (s *MockLSPServer) drainRequests(ctx context.Context, shutdown jsonrpc.ID) {
+	s.mu.Lock()
+	var pending []*inflightRequest
+	for id, request := range s.inflight {
+		if id != shutdown {
+			pending = append(pending, request)
+		}
+	}
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	timeout := s.ShutdownDrainTimeout()
+	s.logInfo(ctx, "Waiting up to %s for %d requests in flight", timeout, len(pending))
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for i, request := range pending {
+		select {
+		case <-request.done:
+		case <-timer.C:
+			cancelled := 0
+			for _, request := range pending[i:] {
+				select {
+				case <-request.done:
+				default:
+					request.cancel()
+					cancelled++
+				}
+			}
+			s.logInfo(ctx, "Cancelled %d requests still in flight after %s", cancelled, timeout)
+			return
+		}
+	}
+}
+
+// exitCode returns the process exit code for an exit notification received
+// now: the expectation_failure code if a response differed from the
+// scenario expectations, else the after_shutdown code once shutdown was
+// requested, otherwise the without_shutdown code
+func (s *MockLSPServer) exitCode() int {
+	if len(s.ExpectationFailures()) > 0 {
+		return s.config().Server.ExitCode(config.ExitExpectationFailure)
+	}
+	if s.State() == StateShuttingDown {
+		return s.config().Server.ExitCode(config.ExitAfterShutdown)
+	}
+	return s.config().Server.ExitCode(config.ExitWithoutShutdown)
+}
+
+// Done returns a channel closed once the client sent the exit notification
+// and the session was saved. The server then expects whoever runs it to
+// close the connection and stop, exiting with ExitStatus.
+func (s *MockLSPServer) Done() <-chan struct{} {
+	return s.done
+}
+
+// ExitStatus returns the exit code chosen when the exit notification was
+// received, and whether it was
+func (s *MockLSPServer) ExitStatus() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exitStatus, s.exited
+}
+
+// signalExit records the exit code of the first exit notification and
+// closes the Done channel
+func (s *MockLSPServer) signalExit(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited {
+		return
+	}
+	s.exitStatus, s.exited = code, true
+	close(s.done)
+}
+
+// rejectAfterShutdown answers requests received after shutdown with
+// InvalidRequest, as the spec requires, and drops such notifications. Only
+// exit and the inspection requests of test harnesses are still served. It
+// returns true if the message was handled.
+func (s *MockLSPServer) rejectAfterShutdown(ctx context.Context, conn Conn, req *jsonrpc.Request) bool {
+	switch s.State() {
+	case StateShuttingDown, StateExited:
+	default:
+		return false
+	}
+	if req.Method == "exit" || strings.HasPrefix(req.Method, inspectionPrefix) {
+		return false
+	}
+
+	if req.Notif {
+		s.logInfo(ctx, "Ignoring %s notification after shutdown", req.Method)
+		return true
+	}
+	s.logInfo(ctx, "Rejecting %s after shutdown", req.Method)
+	lspErr := NewLSPError(ErrorCodeInvalidRequest, fmt.Sprintf("server is shutting down: %s", req.Method)).
+		WithContext("method", req.Method)
+	if err := conn.ReplyWithError(ctx, req.ID, lspErr.ToJSONRPCError()); err != nil {
+		s.logError(ctx, "Failed to send shutdown rejection: %v", err)
+	}
+	return true
+}
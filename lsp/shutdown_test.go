@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/lsptest"
+)
+
+// connectAsyncTestClient is connectTestClient with the server handling
+// requests concurrently, so that requests can be in flight at shutdown
+func connectAsyncTestClient(t *testing.T, server *MockLSPServer) jsonrpc.Conn {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), jsonrpc.AsyncHandler(server), server.ConnOpts()...)
+	clientConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) { return nil, nil }))
+
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	return clientConn
+}
+
+// errorCode returns the JSON-RPC error code of err, or 0 if it has none
+func errorCode(err error) int64 {
+	var rpcErr *jsonrpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+	return 0
+}
+
+func TestRequestsAfterShutdown(t *testing.T) {
+	lsptest.CheckLeaks(t)
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	tests := []struct {
+		method   string
+		wantCode int64
+	}{
+		{"textDocument/hover", int64(ErrorCodeInvalidRequest)},
+		{"shutdown", int64(ErrorCodeInvalidRequest)},
+		{"$/mockLsp/stats", 0},
+	}
+	for _, tt := range tests {
+		params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///after.go"}}
+		err := client.Call(ctx, tt.method, params, nil)
+		if code := errorCode(err); code != tt.wantCode {
+			t.Errorf("Expected %s to fail with code %d, got %v", tt.method, tt.wantCode, err)
+		}
+	}
+
+	doc := protocol.TextDocumentItem{Uri: "file:///after.go", LanguageId: "go", Text: "package main\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if snapshot.OpenDocuments != 0 {
+		t.Errorf("Expected didOpen after shutdown to be ignored, got %d open documents", snapshot.OpenDocuments)
+	}
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		drain         time.Duration
+		finishAfter   time.Duration
+		wantCancelled bool
+	}{
+		{"finished within the timeout", 5 * time.Second, 20 * time.Millisecond, false},
+		{"cancelled after the timeout", 50 * time.Millisecond, time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lsptest.CheckLeaks(t)
+			server := createTestServer()
+			cfg := config.DefaultConfig()
+			cfg.LSP.ShutdownDrainTimeout = config.Duration(tt.drain)
+			server.SetConfig(cfg)
+			client := connectTestClient(t, server, nil)
+
+			// A request in flight on another goroutine
+			reqCtx, done := server.trackRequest(context.Background(), &jsonrpc.Request{ID: jsonrpc.ID{Num: 1000}})
+			cancelled := make(chan bool, 1)
+			go func() {
+				select {
+				case <-reqCtx.Done():
+					cancelled <- true
+				case <-time.After(tt.finishAfter):
+					cancelled <- false
+				}
+				done()
+			}()
+
+			start := time.Now()
+			if err := client.Call(context.Background(), "shutdown", nil, nil); err != nil {
+				t.Fatalf("Shutdown failed: %v", err)
+			}
+			if got := <-cancelled; got != tt.wantCancelled {
+				t.Errorf("Expected the request cancelled %v, got %v", tt.wantCancelled, got)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected shutdown to reply promptly, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestShutdownCancelsSlowRequest(t *testing.T) {
+	lsptest.CheckLeaks(t)
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.ShutdownDrainTimeout = config.Duration(50 * time.Millisecond)
+	cfg.LSP.Latency.SLOs = map[string]config.SLOConfig{
+		"textDocument/hover": {P50: config.Duration(10 * time.Second), P99: config.Duration(10 * time.Second)},
+	}
+	server.SetConfig(cfg)
+	client := connectAsyncTestClient(t, server)
+	ctx := context.Background()
+
+	hovered := make(chan error, 1)
+	go func() {
+		params := protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///slow.go"}}
+		hovered <- client.Call(ctx, "textDocument/hover", params, nil)
+	}()
+	// Wait for the hover to be in flight
+	for deadline := time.Now().Add(time.Second); server.Stats().Requests["textDocument/hover"] == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the hover request")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	select {
+	case err := <-hovered:
+		if code := errorCode(err); code != int64(ErrorCodeRequestCancelled) {
+			t.Errorf("Expected the hover to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the cancelled hover")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.Server.ExitCodes[config.ExitWithoutShutdown] = 7
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+
+	if got := server.exitCode(); got != 7 {
+		t.Errorf("Expected exit code 7 without shutdown, got %d", got)
+	}
+	if err := client.Call(context.Background(), "shutdown", nil, nil); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if got := server.exitCode(); got != 0 {
+		t.Errorf("Expected exit code 0 after shutdown, got %d", got)
+	}
+}
+
+func TestExitClosesDone(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.Server.ExitCodes[config.ExitWithoutShutdown] = 7
+	server.SetConfig(cfg)
+	conn := lsptest.NewConn()
+	defer conn.Close()
+	ctx := context.Background()
+
+	if _, exited := server.ExitStatus(); exited {
+		t.Fatal("Expected no exit status before exit")
+	}
+	server.Dispatch(ctx, conn, testRequest(t, 0, "exit", nil))
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("Expected Done to be closed by exit")
+	}
+	if code, exited := server.ExitStatus(); !exited || code != 7 {
+		t.Errorf("Expected exit code 7 without shutdown, got %d, %v", code, exited)
+	}
+	if server.State() != StateExited {
+		t.Errorf("Expected the exited state, got %s", server.State())
+	}
+
+	// A second exit keeps the first code
+	server.Dispatch(ctx, conn, testRequest(t, 0, "exit", nil))
+	if code, _ := server.ExitStatus(); code != 7 {
+		t.Errorf("Expected the first exit code to be kept, got %d", code)
+	}
+}
+
+func TestShutdownDrainTimeoutDefault(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.ShutdownDrainTimeout = 0
+	server.SetConfig(cfg)
+	// Zero must not cancel the queued requests at once
+	if got := server.ShutdownDrainTimeout(); got != config.DefaultShutdownDrainTimeout {
+		t.Errorf("Expected the default drain timeout for zero, got %s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// signatureParameters are the parameters of the mock signatures: the n-th
+// overload takes the first n
+var signatureParameters = []string{
+	"name string",
+	"count int",
+	"enabled bool",
+	"timeout time.Duration",
+	"options ...Option",
+}
+
+// signatureHelpTriggerCharacters returns the configured trigger and
+// retrigger characters, as announced in the capabilities
+func (s *MockLSPServer) signatureHelpTriggerCharacters() (trigger, retrigger []string) {
+	cfg := s.config().LSP.SignatureHelpConfig
+	trigger, retrigger = []string{}, []string{}
+	return append(trigger, cfg.TriggerCharacters...), append(retrigger, cfg.RetriggerCharacters...)
+}
+
+// signatureHelp returns the configured number of mock overloads. The active
+// signature is the one the client had active when it asks again, the first
+// otherwise, and the active parameter cycles through its parameters with
+// the character of the position.
+func (s *MockLSPServer) signatureHelp(params protocol.SignatureHelpParams) protocol.SignatureHelp {
+	count := s.config().LSP.SignatureHelpConfig.Signatures
+	help := protocol.SignatureHelp{Signatures: make([]protocol.SignatureInformation, 0, count)}
+	for n := 1; n <= count; n++ {
+		parameters := make([]protocol.ParameterInformation, 0, n)
+		for _, parameter := range signatureParameters[:n] {
+			name, _, _ := strings.Cut(parameter, " ")
+			parameters = append(parameters, protocol.ParameterInformation{
+				Label:         protocol.Or2[string, protocol.Tuple[uint32, uint32]]{Value: parameter},
+				Documentation: &protocol.Or2[string, protocol.MarkupContent]{Value: s.message(msgSignatureParameterDoc, name)},
+			})
+		}
+		help.Signatures = append(help.Signatures, protocol.SignatureInformation{
+			Label:         fmt.Sprintf("mockFunction(%s) error", strings.Join(signatureParameters[:n], ", ")),
+			Documentation: &protocol.Or2[string, protocol.MarkupContent]{Value: s.message(msgSignatureDoc, n, count)},
+			Parameters:    parameters,
+		})
+	}
+
+	if c := params.Context; c != nil && c.IsRetrigger && c.ActiveSignatureHelp != nil && int(c.ActiveSignatureHelp.ActiveSignature) < count {
+		help.ActiveSignature = c.ActiveSignatureHelp.ActiveSignature
+	}
+	active := params.Position.Character % (help.ActiveSignature + 1)
+	activeParameter := &active
+	help.ActiveParameter = &activeParameter
+	return help
+}
+
+// handleSignatureHelp processes textDocument/signatureHelp requests
+func (s *MockLSPServer) handleSignatureHelp(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.SignatureHelpParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse signature help params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send signature help error: %v", replyErr)
+		}
+		return
+	}
+
+	help := s.signatureHelp(params)
+	s.logInfo(ctx, "Signature help for %s: signature %d, parameter %d", params.TextDocument.Uri, help.ActiveSignature, **help.ActiveParameter)
+	if err := s.reply(ctx, conn, req, help); err != nil {
+		s.logError(ctx, "Failed to send signature help response: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+
+	"mock-lsp-server/config"
+)
+
+func TestSignatureHelp(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.SignatureHelpConfig = config.SignatureHelpConfig{TriggerCharacters: []string{"(", "<"}, RetriggerCharacters: []string{","}, Signatures: 4}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var initResult struct {
+		Capabilities struct {
+			SignatureHelpProvider struct {
+				TriggerCharacters   []string `json:"triggerCharacters"`
+				RetriggerCharacters []string `json:"retriggerCharacters"`
+			} `json:"signatureHelpProvider"`
+		} `json:"capabilities"`
+	}
+	initialize := map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}
+	if err := client.Call(ctx, "initialize", initialize, &initResult); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	provider := initResult.Capabilities.SignatureHelpProvider
+	if len(provider.TriggerCharacters) != 2 || provider.TriggerCharacters[1] != "<" || len(provider.RetriggerCharacters) != 1 {
+		t.Errorf("Expected the configured trigger characters, got %+v", provider)
+	}
+
+	type signatureHelp struct {
+		Signatures []struct {
+			Label      string `json:"label"`
+			Parameters []struct {
+				Label string `json:"label"`
+			} `json:"parameters"`
+		} `json:"signatures"`
+		ActiveSignature int `json:"activeSignature"`
+		ActiveParameter int `json:"activeParameter"`
+	}
+	tests := []struct {
+		name                string
+		character           int
+		context             map[string]any
+		wantSignature       int
+		wantActiveParameter int
+	}{
+		{"invoked", 7, nil, 0, 0},
+		{"triggered", 5, map[string]any{"triggerKind": 2, "triggerCharacter": "(", "isRetrigger": false}, 0, 0},
+		{"retriggered", 14, map[string]any{"triggerKind": 2, "triggerCharacter": ",", "isRetrigger": true, "activeSignatureHelp": map[string]any{"signatures": []any{}, "activeSignature": 2}}, 2, 2},
+		{"retriggered past the overloads", 9, map[string]any{"triggerKind": 3, "isRetrigger": true, "activeSignatureHelp": map[string]any{"signatures": []any{}, "activeSignature": 7}}, 0, 0},
+		{"cycling", 14, map[string]any{"triggerKind": 3, "isRetrigger": true, "activeSignatureHelp": map[string]any{"signatures": []any{}, "activeSignature": 3}}, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]any{
+				"textDocument": map[string]any{"uri": "file:///call.txt"},
+				"position":     map[string]any{"line": 0, "character": tt.character},
+			}
+			if tt.context != nil {
+				params["context"] = tt.context
+			}
+			var help signatureHelp
+			if err := client.Call(ctx, "textDocument/signatureHelp", params, &help); err != nil {
+				t.Fatalf("Signature help failed: %v", err)
+			}
+			if len(help.Signatures) != 4 {
+				t.Fatalf("Expected 4 overloads, got %+v", help.Signatures)
+			}
+			if last := help.Signatures[3]; len(last.Parameters) != 4 || last.Label != "mockFunction(name string, count int, enabled bool, timeout time.Duration) error" {
+				t.Errorf("Expected the fourth overload to take 4 parameters, got %+v", last)
+			}
+			if help.ActiveSignature != tt.wantSignature || help.ActiveParameter != tt.wantActiveParameter {
+				t.Errorf("Expected signature %d parameter %d, got %d and %d", tt.wantSignature, tt.wantActiveParameter, help.ActiveSignature, help.ActiveParameter)
+			}
+		})
+	}
+
+	if err := client.Call(ctx, "textDocument/signatureHelp", nil, nil); errorCode(err) != int64(ErrorCodeInvalidParams) {
+		t.Errorf("Expected InvalidParams without params, got %v", err)
+	}
+}
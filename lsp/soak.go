@@ -0,0 +1,173 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// soakLeakWindow is the number of consecutive reports a count has to grow
+// in before it is reported as a likely leak
+const soakLeakWindow = 5
+
+// soakHistory is the number of soak reports kept for $/mockLsp/soak
+const soakHistory = 60
+
+// SoakReport is a periodic report of a soak session. Memory and goroutines
+// are measured for the whole process, the rest for this connection.
+type SoakReport struct {
+	Time              time.Time `json:"time"`
+	Uptime            string    `json:"uptime"`
+	HeapBytes         uint64    `json:"heapBytes"`
+	SysBytes          uint64    `json:"sysBytes"`
+	GCCycles          uint32    `json:"gcCycles"`
+	Goroutines        int       `json:"goroutines"`
+	OpenDocuments     int       `json:"openDocuments"`
+	Messages          int64     `json:"messages"`
+	MessagesPerSecond float64   `json:"messagesPerSecond"`
+	// Leaks describes the counts that grew in each of the last reports
+	Leaks []string `json:"leaks,omitempty"`
+}
+
+// soakMonitor keeps the recent reports of a soak session
+type soakMonitor struct {
+	interval time.Duration
+	reports  []SoakReport
+}
+
+// record completes report with the message rate since the previous report
+// and the leak heuristics, and keeps it
+func (m *soakMonitor) record(report SoakReport) SoakReport {
+	if n := len(m.reports); n > 0 {
+		previous := m.reports[n-1]
+		if elapsed := report.Time.Sub(previous.Time).Seconds(); elapsed > 0 {
+			report.MessagesPerSecond = float64(report.Messages-previous.Messages) / elapsed
+		}
+	}
+
+	history := append(m.reports, report)
+	if growth, ok := growing(history, func(r SoakReport) int { return r.Goroutines }); ok {
+		report.Leaks = append(report.Leaks, "goroutines "+growth)
+	}
+	if growth, ok := growing(history, func(r SoakReport) int { return r.OpenDocuments }); ok {
+		report.Leaks = append(report.Leaks, "open documents "+growth)
+	}
+
+	m.reports = append(m.reports, report)
+	if len(m.reports) > soakHistory {
+		m.reports = m.reports[len(m.reports)-soakHistory:]
+	}
+	return report
+}
+
+// growing reports whether value grew in each of the last soakLeakWindow
+// reports, and describes the growth
+func growing(reports []SoakReport, value func(SoakReport) int) (string, bool) {
+	if len(reports) <= soakLeakWindow {
+		return "", false
+	}
+	window := reports[len(reports)-soakLeakWindow-1:]
+	for i := 1; i < len(window); i++ {
+		if value(window[i]) <= value(window[i-1]) {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("grew in each of the last %d reports, from %d to %d",
+		soakLeakWindow, value(window[0]), value(window[len(window)-1])), true
+}
+
+// SetSoakInterval turns on soak reporting: once the client sends
+// initialized, memory usage, goroutines, open documents and message rates
+// are logged every interval, along with likely leaks. Zero turns it off.
+func (s *MockLSPServer) SetSoakInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.soak = nil
+	if interval > 0 {
+		s.soak = &soakMonitor{interval: interval}
+	}
+}
+
+// SoakReports returns the recent soak reports, oldest first
+func (s *MockLSPServer) SoakReports() []SoakReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.soak == nil {
+		return []SoakReport{}
+	}
+	return append([]SoakReport{}, s.soak.reports...)
+}
+
+// startSoak reports on the session in the background until the connection
+// closes, if soak reporting is on
+func (s *MockLSPServer) startSoak(ctx context.Context, conn Conn) {
+	s.mu.Lock()
+	monitor := s.soak
+	s.mu.Unlock()
+	if monitor == nil {
+		return
+	}
+
+	s.logInfo(ctx, "Soak reporting every %s", monitor.interval)
+	go s.runSoak(context.WithoutCancel(ctx), conn, monitor)
+}
+
+// runSoak logs a soak report every interval
+func (s *MockLSPServer) runSoak(ctx context.Context, conn Conn, monitor *soakMonitor) {
+	ticker := time.NewTicker(monitor.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-conn.DisconnectNotify():
+			return
+		}
+
+		report := s.sampleSoak()
+		s.mu.Lock()
+		report = monitor.record(report)
+		s.mu.Unlock()
+
+		s.logInfo(ctx, "Soak: heap %d bytes, sys %d bytes, %d GC cycles, %d goroutines, %d open documents, %.1f messages/s",
+			report.HeapBytes, report.SysBytes, report.GCCycles, report.Goroutines, report.OpenDocuments, report.MessagesPerSecond)
+		for _, leak := range report.Leaks {
+			s.logError(ctx, "Soak: possible leak, %s", leak)
+		}
+	}
+}
+
+// sampleSoak measures the process and the session for a soak report
+func (s *MockLSPServer) sampleSoak() SoakReport {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := s.Stats()
+
+	var messages int64
+	for _, count := range stats.Requests {
+		messages += count
+	}
+	for _, count := range stats.Notifications {
+		messages += count
+	}
+
+	return SoakReport{
+		Time:          time.Now(),
+		Uptime:        stats.Uptime,
+		HeapBytes:     mem.HeapAlloc,
+		SysBytes:      mem.Sys,
+		GCCycles:      mem.NumGC,
+		Goroutines:    runtime.NumGoroutine(),
+		OpenDocuments: stats.OpenDocuments,
+		Messages:      messages,
+	}
+}
+
+// handleSoak returns the recent soak reports
+func (s *MockLSPServer) handleSoak(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	if err := s.reply(ctx, conn, req, s.SoakReports()); err != nil {
+		s.logError(ctx, "Failed to send soak reports: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/lsptest"
+)
+
+func TestSoakMonitorRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		goroutines []int
+		documents  []int
+		wantLeaks  []string
+	}{
+		{"steady", []int{10, 10, 10, 10, 10, 10}, []int{1, 1, 1, 1, 1, 1}, nil},
+		{"too few reports", []int{10, 11, 12, 13, 14}, []int{1, 2, 3, 4, 5}, nil},
+		{"growing goroutines", []int{10, 11, 12, 13, 14, 15}, []int{1, 1, 1, 1, 1, 1}, []string{"goroutines grew in each of the last 5 reports, from 10 to 15"}},
+		{"growth with a dip", []int{10, 11, 12, 11, 14, 15}, []int{1, 1, 1, 1, 1, 1}, nil},
+		{"growing documents", []int{10, 10, 10, 10, 10, 10}, []int{0, 2, 4, 6, 8, 10}, []string{"open documents grew in each of the last 5 reports, from 0 to 10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &soakMonitor{interval: time.Second}
+			start := time.Now()
+			var report SoakReport
+			for i := range tt.goroutines {
+				report = monitor.record(SoakReport{
+					Time:          start.Add(time.Duration(i) * time.Second),
+					Goroutines:    tt.goroutines[i],
+					OpenDocuments: tt.documents[i],
+					Messages:      int64(i * 30),
+				})
+			}
+			if strings.Join(report.Leaks, "\n") != strings.Join(tt.wantLeaks, "\n") {
+				t.Errorf("Expected leaks %q, got %q", tt.wantLeaks, report.Leaks)
+			}
+			if report.MessagesPerSecond != 30 {
+				t.Errorf("Expected 30 messages/s, got %v", report.MessagesPerSecond)
+			}
+		})
+	}
+}
+
+func TestSoakMonitorHistory(t *testing.T) {
+	monitor := &soakMonitor{interval: time.Second}
+	for i := range soakHistory + 10 {
+		monitor.record(SoakReport{Messages: int64(i)})
+	}
+	if len(monitor.reports) != soakHistory || monitor.reports[0].Messages != 10 {
+		t.Errorf("Expected the last %d reports, got %d starting at %d", soakHistory, len(monitor.reports), monitor.reports[0].Messages)
+	}
+}
+
+func TestSoakReporting(t *testing.T) {
+	lsptest.CheckLeaks(t)
+	server := createTestServer()
+	server.SetSoakInterval(10 * time.Millisecond)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	var reports []SoakReport
+	if err := client.Call(ctx, "$/mockLsp/soak", nil, &reports); err != nil {
+		t.Fatalf("Soak request failed: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("Expected no soak reports before initialized, got %d", len(reports))
+	}
+
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+	doc := protocol.TextDocumentItem{Uri: "file:///soak.txt", LanguageId: "plaintext", Text: "text\n", Version: 1}
+	if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{TextDocument: doc}); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	for deadline := time.Now().Add(time.Second); len(reports) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for soak reports, got %d", len(reports))
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := client.Call(ctx, "$/mockLsp/soak", nil, &reports); err != nil {
+			t.Fatalf("Soak request failed: %v", err)
+		}
+	}
+	last := reports[len(reports)-1]
+	if last.OpenDocuments != 1 || last.Goroutines == 0 || last.HeapBytes == 0 || last.Messages < 3 {
+		t.Errorf("Expected the report to measure the session, got %+v", last)
+	}
+}
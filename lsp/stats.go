@@ -0,0 +1,557 @@
+package lsp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+// maxLatencySamples bounds the number of latency samples kept per method
+const maxLatencySamples = 1024
+
+// clientCounter hands out sequential client IDs across all server instances
+var clientCounter atomic.Int64
+
+// nextClientID returns a new unique client identifier
+func nextClientID() string {
+	return fmt.Sprintf("client-%d", clientCounter.Add(1))
+}
+
+// methodTimings holds the measurements collected for a single method
+type methodTimings struct {
+	latencies        []time.Duration
+	next             int
+	maxResponseBytes int
+}
+
+// Stats tracks per-client message counters for a single server instance
+type Stats struct {
+	mu            sync.Mutex
+	clientID      string
+	startedAt     time.Time
+	requests      map[string]int64
+	notifications map[string]int64
+	timings       map[string]*methodTimings
+	slos          map[string]config.SLOConfig
+	divergences   int64
+	mismatches    int64
+	dropped       map[string]int64
+	merged        int64
+	coalesced     int64
+	triggers      map[string]int64
+	withDecl      int64
+	withoutDecl   int64
+	watched       map[string]int64
+	pressure      int64
+	refused       int64
+	drift         map[string]int64
+	dataMismatch  int64
+	applyEdits    map[string]int64
+	overruns      map[string]int64
+	cancelled     map[string]int64
+	invariants    map[string]int64
+	errors        map[string]int64
+	expectations  map[string]int64
+	duplicateIDs  map[string]int64
+}
+
+// StatsSnapshot is a point-in-time copy of the collected statistics
+type StatsSnapshot struct {
+	ClientID      string                 `json:"clientId"`
+	StartedAt     time.Time              `json:"startedAt"`
+	Uptime        string                 `json:"uptime"`
+	TotalRequests int64                  `json:"totalRequests"`
+	Requests      map[string]int64       `json:"requests"`
+	Notifications map[string]int64       `json:"notifications"`
+	OpenDocuments int                    `json:"openDocuments"`
+	Latencies     map[string]LatencyStat `json:"latencies,omitempty"`
+	SLOs          []SLOResult            `json:"slos,omitempty"`
+	// SyncDivergences counts saves whose text differed from the server's buffer
+	SyncDivergences int64 `json:"syncDivergences"`
+	// LanguageMismatches counts documents opened with a missing languageId or
+	// one contradicting their extension
+	LanguageMismatches int64 `json:"languageMismatches"`
+	// DroppedNotifications counts, per method, the notifications the full
+	// notification queue dropped
+	DroppedNotifications map[string]int64 `json:"droppedNotifications,omitempty"`
+	// MergedNotifications counts queued diagnostics replaced by newer ones
+	// for the same URI before being sent
+	MergedNotifications int64 `json:"mergedNotifications"`
+	// CoalescedDiagnostics counts diagnostics publications skipped because
+	// the document changed again within the update delay
+	CoalescedDiagnostics int64 `json:"coalescedDiagnostics"`
+	// CompletionTriggers counts completion requests by trigger kind, with
+	// the character for trigger characters, or "none" without a context
+	CompletionTriggers map[string]int64 `json:"completionTriggers,omitempty"`
+	// ReferencesWithDeclaration and ReferencesWithoutDeclaration count
+	// references requests by their context.includeDeclaration
+	ReferencesWithDeclaration    int64 `json:"referencesWithDeclaration"`
+	ReferencesWithoutDeclaration int64 `json:"referencesWithoutDeclaration"`
+	// WatchedFileEvents counts the didChangeWatchedFiles events received by
+	// change type: created, changed or deleted
+	WatchedFileEvents map[string]int64 `json:"watchedFileEvents,omitempty"`
+	// MemoryPressureEpisodes counts the times the heap crossed the memory
+	// pressure limit, and RefusedResponses the responses refused meanwhile
+	MemoryPressureEpisodes int64 `json:"memoryPressureEpisodes"`
+	RefusedResponses       int64 `json:"refusedResponses"`
+	// SchemaDrift counts, per method, the messages whose params had fields
+	// the protocol types dropped, with schema validation configured
+	SchemaDrift map[string]int64 `json:"schemaDrift,omitempty"`
+	// CodeActionDataMismatches counts codeAction/resolve requests whose data
+	// differed from what was sent with the code action, or was missing
+	CodeActionDataMismatches int64 `json:"codeActionDataMismatches"`
+	// ApplyEdits counts the workspace/applyEdit requests sent by commands,
+	// by outcome: applied, rejected by the client, or failed
+	ApplyEdits map[string]int64 `json:"applyEdits,omitempty"`
+	// BudgetOverruns counts, per method, the requests that took longer than
+	// their configured latency budget
+	BudgetOverruns map[string]int64 `json:"budgetOverruns,omitempty"`
+	// CancelledRequests counts, per method, the requests answered with
+	// RequestCancelled instead of a result
+	CancelledRequests map[string]int64 `json:"cancelledRequests,omitempty"`
+	// InvariantViolations counts, per invariant, the protocol constraints
+	// broken by randomized or edge-case responses and repaired before sending
+	InvariantViolations map[string]int64 `json:"invariantViolations,omitempty"`
+	// ErrorResponses counts, per method, the requests answered with an
+	// error other than RequestCancelled
+	ErrorResponses map[string]int64 `json:"errorResponses,omitempty"`
+	// ExpectationChecks counts, per method, the responses compared with
+	// scenario expectations, and ExpectationFailures lists those that differed
+	ExpectationChecks   map[string]int64     `json:"expectationChecks,omitempty"`
+	ExpectationFailures []ExpectationFailure `json:"expectationFailures,omitempty"`
+	// DuplicateRequestIDs counts the requests whose ID the client had
+	// already used on the connection, as a collision with a request still in
+	// flight or a reuse of an answered one
+	DuplicateRequestIDs map[string]int64 `json:"duplicateRequestIds,omitempty"`
+	// Client is the fingerprint of the connected client, once initialized
+	Client *ClientFingerprint `json:"client,omitempty"`
+}
+
+// LatencyStat summarizes the measured latencies and sizes for a method
+type LatencyStat struct {
+	Samples          int    `json:"samples"`
+	P50              string `json:"p50"`
+	P99              string `json:"p99"`
+	MaxResponseBytes int    `json:"maxResponseBytes"`
+}
+
+// SLOResult compares the declared SLO for a method with the measured values
+type SLOResult struct {
+	Method         string `json:"method"`
+	TargetP50      string `json:"targetP50"`
+	TargetP99      string `json:"targetP99"`
+	ActualP50      string `json:"actualP50"`
+	ActualP99      string `json:"actualP99"`
+	TargetMaxBytes int    `json:"targetMaxBytes,omitempty"`
+	ActualMaxBytes int    `json:"actualMaxBytes"`
+	Samples        int    `json:"samples"`
+	Met            bool   `json:"met"`
+}
+
+// NewStats creates a new statistics collector for the given client
+func NewStats(clientID string) *Stats {
+	return &Stats{
+		clientID:      clientID,
+		startedAt:     time.Now(),
+		requests:      make(map[string]int64),
+		notifications: make(map[string]int64),
+		timings:       make(map[string]*methodTimings),
+		dropped:       make(map[string]int64),
+		triggers:      make(map[string]int64),
+		watched:       make(map[string]int64),
+		drift:         make(map[string]int64),
+		applyEdits:    make(map[string]int64),
+		overruns:      make(map[string]int64),
+		cancelled:     make(map[string]int64),
+		invariants:    make(map[string]int64),
+		errors:        make(map[string]int64),
+		expectations:  make(map[string]int64),
+		duplicateIDs:  make(map[string]int64),
+	}
+}
+
+// SetSLOs sets the SLO declarations the snapshot is compared against
+func (st *Stats) SetSLOs(slos map[string]config.SLOConfig) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.slos = slos
+}
+
+// RecordRequest counts an incoming request for the given method
+func (st *Stats) RecordRequest(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.requests[method]++
+}
+
+// RecordNotification counts an incoming notification for the given method
+func (st *Stats) RecordNotification(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.notifications[method]++
+}
+
+// RecordLatency records how long a request took to handle
+func (st *Stats) RecordLatency(method string, latency time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	timings := st.timingsFor(method)
+	if len(timings.latencies) < maxLatencySamples {
+		timings.latencies = append(timings.latencies, latency)
+		return
+	}
+	timings.latencies[timings.next] = latency
+	timings.next = (timings.next + 1) % maxLatencySamples
+}
+
+// RecordResponseSize records the encoded size of a response
+func (st *Stats) RecordResponseSize(method string, size int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	timings := st.timingsFor(method)
+	if size > timings.maxResponseBytes {
+		timings.maxResponseBytes = size
+	}
+}
+
+// RecordSyncDivergence counts a didSave whose text differed from the
+// server's reconstructed buffer
+func (st *Stats) RecordSyncDivergence() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.divergences++
+}
+
+// RecordLanguageMismatch counts a didOpen whose languageId was missing or
+// contradicted the document's extension
+func (st *Stats) RecordLanguageMismatch() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.mismatches++
+}
+
+// RecordDroppedNotification counts a notification dropped because the
+// notification queue was full
+func (st *Stats) RecordDroppedNotification(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.dropped[method]++
+}
+
+// RecordMergedNotification counts a queued notification superseded by a
+// newer one before it was sent
+func (st *Stats) RecordMergedNotification() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.merged++
+}
+
+// RecordCoalescedDiagnostics counts a pending diagnostics publication
+// replaced by a newer change of the document
+func (st *Stats) RecordCoalescedDiagnostics() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.coalesced++
+}
+
+// RecordCompletionTrigger counts a completion request by how the client
+// triggered it
+func (st *Stats) RecordCompletionTrigger(trigger string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.triggers[trigger]++
+}
+
+// RecordReferencesContext counts a references request by whether it asked
+// for the declaration to be included
+func (st *Stats) RecordReferencesContext(includeDeclaration bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if includeDeclaration {
+		st.withDecl++
+	} else {
+		st.withoutDecl++
+	}
+}
+
+// RecordWatchedFileEvents adds the counts of watched file events received
+// in a didChangeWatchedFiles notification, by change type
+func (st *Stats) RecordWatchedFileEvents(counts map[string]int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for change, count := range counts {
+		st.watched[change] += count
+	}
+}
+
+// RecordMemoryPressure counts the heap crossing the memory pressure limit
+func (st *Stats) RecordMemoryPressure() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pressure++
+}
+
+// RecordRefusedResponse counts a response refused under memory pressure
+func (st *Stats) RecordRefusedResponse() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.refused++
+}
+
+// RecordSchemaDrift counts a message of method whose params had fields the
+// protocol types dropped
+func (st *Stats) RecordSchemaDrift(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.drift[method]++
+}
+
+// RecordCodeActionDataMismatch counts a resolved code action whose data did
+// not come back as sent
+func (st *Stats) RecordCodeActionDataMismatch() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.dataMismatch++
+}
+
+// RecordApplyEdit counts a workspace/applyEdit request by its outcome
+func (st *Stats) RecordApplyEdit(outcome string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.applyEdits[outcome]++
+}
+
+// RecordBudgetOverrun counts a request that took longer than the latency
+// budget of its method, returning the number of overruns for the method
+func (st *Stats) RecordBudgetOverrun(method string) int64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.overruns[method]++
+	return st.overruns[method]
+}
+
+// RecordCancelledRequest counts a request of method answered with
+// RequestCancelled
+func (st *Stats) RecordCancelledRequest(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.cancelled[method]++
+}
+
+// RecordInvariantViolations adds the repaired violations of each invariant
+func (st *Stats) RecordInvariantViolations(violations map[string]int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for invariant, count := range violations {
+		st.invariants[invariant] += count
+	}
+}
+
+// RecordErrorResponse counts a request of method answered with an error
+func (st *Stats) RecordErrorResponse(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.errors[method]++
+}
+
+// RecordDuplicateRequestID counts a request reusing an ID, by kind:
+// collision or reuse
+func (st *Stats) RecordDuplicateRequestID(kind string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.duplicateIDs[kind]++
+}
+
+// RecordExpectationCheck counts a response of method compared with the
+// scenario expectations
+func (st *Stats) RecordExpectationCheck(method string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.expectations[method]++
+}
+
+// timingsFor returns the timings for a method, creating them if needed.
+// Callers must hold st.mu.
+func (st *Stats) timingsFor(method string) *methodTimings {
+	timings, ok := st.timings[method]
+	if !ok {
+		timings = &methodTimings{}
+		st.timings[method] = timings
+	}
+	return timings
+}
+
+// Snapshot returns a copy of the current statistics
+func (st *Stats) Snapshot(openDocuments int) StatsSnapshot {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	snapshot := StatsSnapshot{
+		ClientID:             st.clientID,
+		StartedAt:            st.startedAt,
+		Uptime:               time.Since(st.startedAt).Round(time.Millisecond).String(),
+		Requests:             make(map[string]int64, len(st.requests)),
+		Notifications:        make(map[string]int64, len(st.notifications)),
+		OpenDocuments:        openDocuments,
+		Latencies:            make(map[string]LatencyStat, len(st.timings)),
+		SyncDivergences:      st.divergences,
+		LanguageMismatches:   st.mismatches,
+		MergedNotifications:  st.merged,
+		CoalescedDiagnostics: st.coalesced,
+
+		MemoryPressureEpisodes: st.pressure,
+		RefusedResponses:       st.refused,
+
+		CodeActionDataMismatches: st.dataMismatch,
+
+		ReferencesWithDeclaration:    st.withDecl,
+		ReferencesWithoutDeclaration: st.withoutDecl,
+	}
+	for method, count := range st.requests {
+		snapshot.Requests[method] = count
+		snapshot.TotalRequests += count
+	}
+	for method, count := range st.notifications {
+		snapshot.Notifications[method] = count
+	}
+	if len(st.dropped) > 0 {
+		snapshot.DroppedNotifications = make(map[string]int64, len(st.dropped))
+		for method, count := range st.dropped {
+			snapshot.DroppedNotifications[method] = count
+		}
+	}
+	if len(st.triggers) > 0 {
+		snapshot.CompletionTriggers = make(map[string]int64, len(st.triggers))
+		for trigger, count := range st.triggers {
+			snapshot.CompletionTriggers[trigger] = count
+		}
+	}
+	if len(st.watched) > 0 {
+		snapshot.WatchedFileEvents = make(map[string]int64, len(st.watched))
+		for change, count := range st.watched {
+			snapshot.WatchedFileEvents[change] = count
+		}
+	}
+	if len(st.drift) > 0 {
+		snapshot.SchemaDrift = make(map[string]int64, len(st.drift))
+		for method, count := range st.drift {
+			snapshot.SchemaDrift[method] = count
+		}
+	}
+	if len(st.applyEdits) > 0 {
+		snapshot.ApplyEdits = make(map[string]int64, len(st.applyEdits))
+		for outcome, count := range st.applyEdits {
+			snapshot.ApplyEdits[outcome] = count
+		}
+	}
+	if len(st.overruns) > 0 {
+		snapshot.BudgetOverruns = make(map[string]int64, len(st.overruns))
+		for method, count := range st.overruns {
+			snapshot.BudgetOverruns[method] = count
+		}
+	}
+	if len(st.cancelled) > 0 {
+		snapshot.CancelledRequests = make(map[string]int64, len(st.cancelled))
+		for method, count := range st.cancelled {
+			snapshot.CancelledRequests[method] = count
+		}
+	}
+	if len(st.invariants) > 0 {
+		snapshot.InvariantViolations = make(map[string]int64, len(st.invariants))
+		for invariant, count := range st.invariants {
+			snapshot.InvariantViolations[invariant] = count
+		}
+	}
+	if len(st.errors) > 0 {
+		snapshot.ErrorResponses = make(map[string]int64, len(st.errors))
+		for method, count := range st.errors {
+			snapshot.ErrorResponses[method] = count
+		}
+	}
+	if len(st.expectations) > 0 {
+		snapshot.ExpectationChecks = make(map[string]int64, len(st.expectations))
+		for method, count := range st.expectations {
+			snapshot.ExpectationChecks[method] = count
+		}
+	}
+	if len(st.duplicateIDs) > 0 {
+		snapshot.DuplicateRequestIDs = make(map[string]int64, len(st.duplicateIDs))
+		for kind, count := range st.duplicateIDs {
+			snapshot.DuplicateRequestIDs[kind] = count
+		}
+	}
+
+	measured := make(map[string][2]time.Duration, len(st.timings))
+	for method, timings := range st.timings {
+		p50 := percentile(timings.latencies, 0.50)
+		p99 := percentile(timings.latencies, 0.99)
+		measured[method] = [2]time.Duration{p50, p99}
+		snapshot.Latencies[method] = LatencyStat{
+			Samples:          len(timings.latencies),
+			P50:              p50.String(),
+			P99:              p99.String(),
+			MaxResponseBytes: timings.maxResponseBytes,
+		}
+	}
+
+	methods := make([]string, 0, len(st.slos))
+	for method := range st.slos {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	for _, method := range methods {
+		slo := st.slos[method]
+		result := SLOResult{
+			Method:         method,
+			TargetP50:      slo.P50.String(),
+			TargetP99:      slo.P99.String(),
+			TargetMaxBytes: slo.MaxResponseBytes,
+			Met:            true,
+		}
+		if timings, ok := st.timings[method]; ok {
+			actual := measured[method]
+			result.ActualP50 = actual[0].String()
+			result.ActualP99 = actual[1].String()
+			result.ActualMaxBytes = timings.maxResponseBytes
+			result.Samples = len(timings.latencies)
+			if slo.P50 > 0 && actual[0] > slo.P50.Duration() {
+				result.Met = false
+			}
+			if slo.P99 > 0 && actual[1] > slo.P99.Duration() {
+				result.Met = false
+			}
+			if slo.MaxResponseBytes > 0 && timings.maxResponseBytes > slo.MaxResponseBytes {
+				result.Met = false
+			}
+		}
+		snapshot.SLOs = append(snapshot.SLOs, result)
+	}
+	return snapshot
+}
+
+// percentile returns the p-th percentile of the given samples
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(math.Ceil(float64(len(sorted))*p)) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
@@ -0,0 +1,235 @@
+package lsp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// latencyNoise is the smallest latency increase counted as a regression.
+// The mock answers most requests in microseconds, so relative changes below
+// it are scheduling noise.
+const latencyNoise = time.Millisecond
+
+// StatsChange is a single difference between two stats snapshots
+type StatsChange struct {
+	Kind       string `json:"kind"`    // requests, notifications, completion_triggers, latency, response_size, slo, sync, language, client
+	Subject    string `json:"subject"` // Method or client property the change concerns
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Regression bool   `json:"regression"`
+}
+
+// String formats the change for a single line of output
+func (c StatsChange) String() string {
+	label := "changed"
+	if c.Regression {
+		label = "REGRESSION"
+	}
+	return fmt.Sprintf("%-10s %s %s: %s -> %s", label, c.Kind, c.Subject, c.Before, c.After)
+}
+
+// StatsDiff lists the differences between two stats snapshots
+type StatsDiff struct {
+	Changes     []StatsChange `json:"changes"`
+	Regressions int           `json:"regressions"`
+}
+
+// add records a change, counting it if it is a regression
+func (d *StatsDiff) add(change StatsChange) {
+	d.Changes = append(d.Changes, change)
+	if change.Regression {
+		d.Regressions++
+	}
+}
+
+// DiffStats compares the stats of a session before a client change with
+// those of a session after it. Latencies and response sizes count as
+// regressions when they grew by more than tolerance, a fraction of the
+// earlier value. Methods and completion triggers the client stopped using,
+// newly missed SLOs, more sync divergences, language mismatches or dropped
+// notifications and dropped client capabilities are always regressions.
+func DiffStats(before, after StatsSnapshot, tolerance float64) StatsDiff {
+	diff := StatsDiff{Changes: []StatsChange{}}
+	diffCounts(&diff, "requests", before.Requests, after.Requests)
+	diffCounts(&diff, "notifications", before.Notifications, after.Notifications)
+	diffCounts(&diff, "completion_triggers", before.CompletionTriggers, after.CompletionTriggers)
+	diffLatencies(&diff, before.Latencies, after.Latencies, tolerance)
+	diffSLOs(&diff, before.SLOs, after.SLOs)
+
+	if before.SyncDivergences != after.SyncDivergences {
+		diff.add(StatsChange{
+			Kind:       "sync",
+			Subject:    "divergences",
+			Before:     fmt.Sprint(before.SyncDivergences),
+			After:      fmt.Sprint(after.SyncDivergences),
+			Regression: after.SyncDivergences > before.SyncDivergences,
+		})
+	}
+
+	if before.LanguageMismatches != after.LanguageMismatches {
+		diff.add(StatsChange{
+			Kind:       "language",
+			Subject:    "mismatches",
+			Before:     fmt.Sprint(before.LanguageMismatches),
+			After:      fmt.Sprint(after.LanguageMismatches),
+			Regression: after.LanguageMismatches > before.LanguageMismatches,
+		})
+	}
+
+	for _, method := range unionKeys(before.DroppedNotifications, after.DroppedNotifications) {
+		b, a := before.DroppedNotifications[method], after.DroppedNotifications[method]
+		if b != a {
+			diff.add(StatsChange{
+				Kind:       "dropped",
+				Subject:    method,
+				Before:     fmt.Sprint(b),
+				After:      fmt.Sprint(a),
+				Regression: a > b,
+			})
+		}
+	}
+
+	diffClients(&diff, before.Client, after.Client)
+	return diff
+}
+
+// diffCounts compares per-method message counts. A method that is no longer
+// sent at all is a regression.
+func diffCounts(diff *StatsDiff, kind string, before, after map[string]int64) {
+	for _, method := range unionKeys(before, after) {
+		b, a := before[method], after[method]
+		if b == a {
+			continue
+		}
+		diff.add(StatsChange{
+			Kind:       kind,
+			Subject:    method,
+			Before:     fmt.Sprint(b),
+			After:      fmt.Sprint(a),
+			Regression: b > 0 && a == 0,
+		})
+	}
+}
+
+// diffLatencies compares latency percentiles and maximum response sizes of
+// the methods measured in both sessions
+func diffLatencies(diff *StatsDiff, before, after map[string]LatencyStat, tolerance float64) {
+	for _, method := range unionKeys(before, after) {
+		b, okBefore := before[method]
+		a, okAfter := after[method]
+		if !okBefore || !okAfter {
+			continue
+		}
+
+		for _, p := range []struct{ name, before, after string }{
+			{"p50", b.P50, a.P50},
+			{"p99", b.P99, a.P99},
+		} {
+			bd, errBefore := time.ParseDuration(p.before)
+			ad, errAfter := time.ParseDuration(p.after)
+			if errBefore != nil || errAfter != nil {
+				continue
+			}
+			if ad-bd >= latencyNoise && float64(ad) > float64(bd)*(1+tolerance) {
+				diff.add(StatsChange{
+					Kind:       "latency",
+					Subject:    method + " " + p.name,
+					Before:     p.before,
+					After:      fmt.Sprintf("%s (%s)", p.after, growth(float64(bd), float64(ad))),
+					Regression: true,
+				})
+			}
+		}
+
+		if float64(a.MaxResponseBytes) > float64(b.MaxResponseBytes)*(1+tolerance) {
+			diff.add(StatsChange{
+				Kind:       "response_size",
+				Subject:    method,
+				Before:     fmt.Sprint(b.MaxResponseBytes),
+				After:      fmt.Sprintf("%d (%s)", a.MaxResponseBytes, growth(float64(b.MaxResponseBytes), float64(a.MaxResponseBytes))),
+				Regression: true,
+			})
+		}
+	}
+}
+
+// growth formats the relative increase from before to after
+func growth(before, after float64) string {
+	if before == 0 {
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", (after-before)/before*100)
+}
+
+// diffSLOs reports SLOs whose outcome changed between the sessions
+func diffSLOs(diff *StatsDiff, before, after []SLOResult) {
+	met := make(map[string]bool, len(before))
+	for _, result := range before {
+		met[result.Method] = result.Met
+	}
+	for _, result := range after {
+		wasMet, ok := met[result.Method]
+		if !ok || wasMet == result.Met {
+			continue
+		}
+		diff.add(StatsChange{
+			Kind:       "slo",
+			Subject:    result.Method,
+			Before:     sloOutcome(wasMet),
+			After:      sloOutcome(result.Met),
+			Regression: !result.Met,
+		})
+	}
+}
+
+// sloOutcome names the outcome of an SLO
+func sloOutcome(met bool) string {
+	if met {
+		return "met"
+	}
+	return "missed"
+}
+
+// diffClients compares the client fingerprints. Dropped notable
+// capabilities are regressions; everything else is informational.
+func diffClients(diff *StatsDiff, before, after *ClientFingerprint) {
+	if before == nil || after == nil {
+		return
+	}
+	for _, field := range []struct{ name, before, after string }{
+		{"name", before.Name, after.Name},
+		{"version", before.Version, after.Version},
+		{"positionEncodings", strings.Join(before.PositionEncodings, ","), strings.Join(after.PositionEncodings, ",")},
+		{"hash", before.Hash, after.Hash},
+	} {
+		if field.before != field.after {
+			diff.add(StatsChange{Kind: "client", Subject: field.name, Before: field.before, After: field.after})
+		}
+	}
+
+	for _, capability := range before.Capabilities {
+		if !slices.Contains(after.Capabilities, capability) {
+			diff.add(StatsChange{Kind: "client", Subject: capability, Before: "declared", After: "missing", Regression: true})
+		}
+	}
+	for _, capability := range after.Capabilities {
+		if !slices.Contains(before.Capabilities, capability) {
+			diff.add(StatsChange{Kind: "client", Subject: capability, Before: "missing", After: "declared"})
+		}
+	}
+}
+
+// unionKeys returns the sorted keys present in either map
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
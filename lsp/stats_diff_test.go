@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestDiffStats(t *testing.T) {
+	base := func() StatsSnapshot {
+		return StatsSnapshot{
+			Requests:           map[string]int64{"initialize": 1, "textDocument/hover": 4},
+			Notifications:      map[string]int64{"initialized": 1},
+			CompletionTriggers: map[string]int64{"invoked": 2, "triggerCharacter:.": 3},
+			Latencies: map[string]LatencyStat{
+				"textDocument/hover": {Samples: 4, P50: "2ms", P99: "10ms", MaxResponseBytes: 100},
+			},
+			SLOs: []SLOResult{{Method: "textDocument/hover", Met: true}},
+			Client: &ClientFingerprint{
+				Name:         "editor",
+				Version:      "1.0",
+				Capabilities: []string{"textDocument.hover", "window.workDoneProgress"},
+				Hash:         "aaaaaaaaaaaa",
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		mutate          func(*StatsSnapshot)
+		wantChanges     int
+		wantRegressions int
+	}{
+		{
+			name:   "identical sessions",
+			mutate: func(s *StatsSnapshot) {},
+		},
+		{
+			name:        "more requests",
+			mutate:      func(s *StatsSnapshot) { s.Requests["textDocument/hover"] = 6 },
+			wantChanges: 1,
+		},
+		{
+			name:            "method no longer sent",
+			mutate:          func(s *StatsSnapshot) { delete(s.Notifications, "initialized") },
+			wantChanges:     1,
+			wantRegressions: 1,
+		},
+		{
+			name:            "trigger characters no longer used",
+			mutate:          func(s *StatsSnapshot) { s.CompletionTriggers = map[string]int64{"invoked": 5} },
+			wantChanges:     2,
+			wantRegressions: 1,
+		},
+		{
+			name: "latency within tolerance",
+			mutate: func(s *StatsSnapshot) {
+				s.Latencies["textDocument/hover"] = LatencyStat{P50: "2ms", P99: "10.5ms", MaxResponseBytes: 100}
+			},
+		},
+		{
+			name: "latency growth below the noise floor",
+			mutate: func(s *StatsSnapshot) {
+				s.Latencies["textDocument/hover"] = LatencyStat{P50: "2.5ms", P99: "10ms", MaxResponseBytes: 100}
+			},
+		},
+		{
+			name: "slower and larger responses",
+			mutate: func(s *StatsSnapshot) {
+				s.Latencies["textDocument/hover"] = LatencyStat{P50: "2ms", P99: "30ms", MaxResponseBytes: 500}
+			},
+			wantChanges:     2,
+			wantRegressions: 2,
+		},
+		{
+			name:            "missed SLO",
+			mutate:          func(s *StatsSnapshot) { s.SLOs[0].Met = false },
+			wantChanges:     1,
+			wantRegressions: 1,
+		},
+		{
+			name:            "new sync divergences",
+			mutate:          func(s *StatsSnapshot) { s.SyncDivergences = 2 },
+			wantChanges:     1,
+			wantRegressions: 1,
+		},
+		{
+			name:            "new language mismatches",
+			mutate:          func(s *StatsSnapshot) { s.LanguageMismatches = 1 },
+			wantChanges:     1,
+			wantRegressions: 1,
+		},
+		{
+			name: "more dropped notifications",
+			mutate: func(s *StatsSnapshot) {
+				s.DroppedNotifications = map[string]int64{"textDocument/publishDiagnostics": 3}
+			},
+			wantChanges:     1,
+			wantRegressions: 1,
+		},
+		{
+			name: "client upgrade dropping a capability",
+			mutate: func(s *StatsSnapshot) {
+				s.Client = &ClientFingerprint{
+					Name:         "editor",
+					Version:      "2.0",
+					Capabilities: []string{"textDocument.hover", "textDocument.inlayHint"},
+					Hash:         "bbbbbbbbbbbb",
+				}
+			},
+			wantChanges:     4,
+			wantRegressions: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := base()
+			tt.mutate(&after)
+			diff := DiffStats(base(), after, 0.10)
+
+			if len(diff.Changes) != tt.wantChanges {
+				t.Errorf("Expected %d changes, got %d: %v", tt.wantChanges, len(diff.Changes), diff.Changes)
+			}
+			if diff.Regressions != tt.wantRegressions {
+				t.Errorf("Expected %d regressions, got %d: %v", tt.wantRegressions, diff.Regressions, diff.Changes)
+			}
+		})
+	}
+}
+
+func TestStatsChangeString(t *testing.T) {
+	change := StatsChange{Kind: "slo", Subject: "textDocument/hover", Before: "met", After: "missed", Regression: true}
+	want := "REGRESSION slo textDocument/hover: met -> missed"
+	if got := change.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
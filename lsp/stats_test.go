@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestStats_Snapshot(t *testing.T) {
+	stats := NewStats("client-test")
+	stats.RecordRequest("textDocument/hover")
+	stats.RecordRequest("textDocument/hover")
+	stats.RecordRequest("textDocument/completion")
+	stats.RecordNotification("textDocument/didOpen")
+
+	snapshot := stats.Snapshot(3)
+
+	if snapshot.ClientID != "client-test" {
+		t.Errorf("Expected client ID client-test, got %s", snapshot.ClientID)
+	}
+	if snapshot.Requests["textDocument/hover"] != 2 {
+		t.Errorf("Expected 2 hover requests, got %d", snapshot.Requests["textDocument/hover"])
+	}
+	if snapshot.TotalRequests != 3 {
+		t.Errorf("Expected 3 total requests, got %d", snapshot.TotalRequests)
+	}
+	if snapshot.Notifications["textDocument/didOpen"] != 1 {
+		t.Errorf("Expected 1 didOpen notification, got %d", snapshot.Notifications["textDocument/didOpen"])
+	}
+	if snapshot.OpenDocuments != 3 {
+		t.Errorf("Expected 3 open documents, got %d", snapshot.OpenDocuments)
+	}
+
+	// Mutating the snapshot must not affect the collector
+	snapshot.Requests["textDocument/hover"] = 100
+	if stats.Snapshot(0).Requests["textDocument/hover"] != 2 {
+		t.Error("Snapshot shares state with the collector")
+	}
+}
+
+func TestClientIDsAreUnique(t *testing.T) {
+	first := createTestServer()
+	second := createTestServer()
+
+	if first.ClientID() == second.ClientID() {
+		t.Errorf("Expected distinct client IDs, both were %s", first.ClientID())
+	}
+	if !strings.HasPrefix(first.ClientID(), "client-") {
+		t.Errorf("Unexpected client ID format: %s", first.ClientID())
+	}
+}
+
+func TestHandleStatsRequest(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///stats.go", LanguageId: "go", Text: "package main", Version: 1},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("Failed to send didOpen: %v", err)
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+
+	if snapshot.ClientID != server.ClientID() {
+		t.Errorf("Expected client ID %s, got %s", server.ClientID(), snapshot.ClientID)
+	}
+	if snapshot.Notifications["textDocument/didOpen"] != 1 {
+		t.Errorf("Expected 1 didOpen notification, got %d", snapshot.Notifications["textDocument/didOpen"])
+	}
+	if snapshot.Requests["$/mockLsp/stats"] != 1 {
+		t.Errorf("Expected stats request to be counted, got %d", snapshot.Requests["$/mockLsp/stats"])
+	}
+	if snapshot.OpenDocuments != 1 {
+		t.Errorf("Expected 1 open document, got %d", snapshot.OpenDocuments)
+	}
+}
+
+func TestStats_SLOComparison(t *testing.T) {
+	stats := NewStats("client-slo")
+	stats.SetSLOs(map[string]config.SLOConfig{
+		"textDocument/completion": {
+			P50: config.Duration(10 * time.Millisecond),
+			P99: config.Duration(20 * time.Millisecond),
+		},
+		"textDocument/hover": {
+			P50:              config.Duration(10 * time.Millisecond),
+			P99:              config.Duration(20 * time.Millisecond),
+			MaxResponseBytes: 100,
+		},
+	})
+
+	for i := 0; i < 100; i++ {
+		stats.RecordLatency("textDocument/completion", 5*time.Millisecond)
+		stats.RecordLatency("textDocument/hover", 5*time.Millisecond)
+	}
+	stats.RecordResponseSize("textDocument/hover", 500)
+
+	snapshot := stats.Snapshot(0)
+	if len(snapshot.SLOs) != 2 {
+		t.Fatalf("Expected 2 SLO results, got %d", len(snapshot.SLOs))
+	}
+
+	completion, hover := snapshot.SLOs[0], snapshot.SLOs[1]
+	if completion.Method != "textDocument/completion" || !completion.Met {
+		t.Errorf("Expected completion SLO to be met, got %+v", completion)
+	}
+	if hover.Method != "textDocument/hover" || hover.Met {
+		t.Errorf("Expected hover SLO to be violated by response size, got %+v", hover)
+	}
+	if hover.ActualMaxBytes != 500 {
+		t.Errorf("Expected hover max bytes 500, got %d", hover.ActualMaxBytes)
+	}
+
+	stats.RecordLatency("textDocument/completion", time.Second)
+	stats.RecordLatency("textDocument/completion", time.Second)
+	if stats.Snapshot(0).SLOs[0].Met {
+		t.Error("Expected completion SLO to be violated by slow p99")
+	}
+}
+
+func TestStats_SLOTargets(t *testing.T) {
+	stats := NewStats("client-slo-targets")
+	stats.SetSLOs(map[string]config.SLOConfig{
+		// p50 is breached while p99 holds
+		"textDocument/completion": {
+			P50: config.Duration(10 * time.Millisecond),
+			P99: config.Duration(time.Second),
+		},
+		// Only the response size is declared
+		"textDocument/hover": {MaxResponseBytes: 100},
+	})
+
+	for i := 0; i < 100; i++ {
+		stats.RecordLatency("textDocument/completion", 50*time.Millisecond)
+		stats.RecordLatency("textDocument/hover", 50*time.Millisecond)
+	}
+	stats.RecordResponseSize("textDocument/hover", 80)
+
+	slos := stats.Snapshot(0).SLOs
+	if slos[0].Met {
+		t.Errorf("Expected completion SLO to be violated by slow p50, got %+v", slos[0])
+	}
+	if !slos[1].Met {
+		t.Errorf("Expected the size-only hover SLO to be met, got %+v", slos[1])
+	}
+
+	stats.RecordResponseSize("textDocument/hover", 200)
+	if stats.Snapshot(0).SLOs[1].Met {
+		t.Error("Expected the size-only hover SLO to be violated by response size")
+	}
+}
@@ -0,0 +1,235 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// stressMethods are the requests synthetic stress clients pick from, all
+// answered from the document every client opens
+var stressMethods = []string{
+	"textDocument/completion",
+	"textDocument/hover",
+	"textDocument/definition",
+	"textDocument/references",
+	"textDocument/documentSymbol",
+}
+
+// stressChangeEvery is the number of requests after which a stress client
+// edits its document
+const stressChangeEvery = 5
+
+// Stress describes a stress run: Clients synthetic clients, each connected
+// to its own in-process server instance as with the TCP listener, sending
+// Requests requests concurrently
+type Stress struct {
+	Clients  int   // Number of concurrent clients
+	Requests int   // Requests sent by every client
+	Seed     int64 // Seed of the request mix; 0 picks a time-based seed
+}
+
+// StressMethod reports the requests sent for one method during a stress run
+type StressMethod struct {
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	P50      string `json:"p50"`
+	P99      string `json:"p99"`
+}
+
+// StressReport summarizes a stress run. Mismatches lists the clients whose
+// server counted other requests than the client sent, which means
+// connections leaked into each other.
+type StressReport struct {
+	Seed              int64                   `json:"seed"`
+	Clients           int                     `json:"clients"`
+	Requests          int64                   `json:"requests"`
+	Errors            int64                   `json:"errors"`
+	Duration          string                  `json:"duration"`
+	RequestsPerSecond float64                 `json:"requestsPerSecond"`
+	P50               string                  `json:"p50"`
+	P90               string                  `json:"p90"`
+	P99               string                  `json:"p99"`
+	Max               string                  `json:"max"`
+	Methods           map[string]StressMethod `json:"methods"`
+	Mismatches        []string                `json:"mismatches,omitempty"`
+}
+
+// stressClient collects what a single synthetic client measured
+type stressClient struct {
+	clientID  string
+	latencies map[string][]time.Duration
+	errors    map[string]int64
+	mismatch  string
+}
+
+// RunStress runs a stress session: it creates a server with newServer for
+// every synthetic client up front, then has the clients initialize, open a
+// document and send a random mix of requests, editing the document every
+// few requests, all at the same time. Failed requests are counted as errors;
+// a client that cannot initialize or shut down fails the run.
+func RunStress(ctx context.Context, newServer func() *MockLSPServer, stress Stress) (*StressReport, error) {
+	if stress.Seed == 0 {
+		stress.Seed = time.Now().UnixNano()
+	}
+	report := &StressReport{Seed: stress.Seed, Clients: stress.Clients, Methods: map[string]StressMethod{}}
+
+	clients := make([]*stressClient, stress.Clients)
+	errs := make([]error, stress.Clients)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range clients {
+		server := newServer()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients[i], errs[i] = runStressClient(ctx, server, i, stress.Requests, stress.Seed+int64(i))
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	report.Duration = elapsed.String()
+
+	var all []time.Duration
+	methods := map[string][]time.Duration{}
+	errors := map[string]int64{}
+	seen := map[string]bool{}
+	for i, client := range clients {
+		if errs[i] != nil {
+			return report, fmt.Errorf("client %d: %w", i+1, errs[i])
+		}
+		for method, latencies := range client.latencies {
+			methods[method] = append(methods[method], latencies...)
+			all = append(all, latencies...)
+		}
+		for method, n := range client.errors {
+			errors[method] += n
+			report.Errors += n
+		}
+		if client.mismatch != "" {
+			report.Mismatches = append(report.Mismatches, client.mismatch)
+		}
+		if seen[client.clientID] {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf("client ID %s assigned twice", client.clientID))
+		}
+		seen[client.clientID] = true
+	}
+
+	for _, method := range slices.Sorted(maps.Keys(methods)) {
+		latencies := methods[method]
+		report.Methods[method] = StressMethod{
+			Requests: int64(len(latencies)),
+			Errors:   errors[method],
+			P50:      percentile(latencies, 0.50).String(),
+			P99:      percentile(latencies, 0.99).String(),
+		}
+	}
+	report.Requests = int64(len(all))
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		report.RequestsPerSecond = float64(report.Requests) / seconds
+	}
+	report.P50 = percentile(all, 0.50).String()
+	report.P90 = percentile(all, 0.90).String()
+	report.P99 = percentile(all, 0.99).String()
+	report.Max = percentile(all, 1).String()
+	return report, nil
+}
+
+// runStressClient connects a synthetic client to server and runs its
+// session, then checks that the server counted exactly the requests the
+// client sent
+func runStressClient(ctx context.Context, server *MockLSPServer, n, requests int, seed int64) (*stressClient, error) {
+	serverSide, clientSide := net.Pipe()
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server, server.ConnOpts()...)
+	defer serverConn.Close()
+	conn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+			return nil, nil
+		}))
+	defer conn.Close()
+
+	client := &stressClient{
+		clientID:  server.ClientID(),
+		latencies: map[string][]time.Duration{},
+		errors:    map[string]int64{},
+	}
+	sent := map[string]int64{}
+	call := func(method string, params any) error {
+		sent[method]++
+		return conn.Call(ctx, method, params, nil)
+	}
+
+	// Raw JSON: the protocol types do not marshal through encoding/json
+	root := "file:///stress/"
+	initialize := map[string]any{
+		"processId":    nil,
+		"rootUri":      root,
+		"clientInfo":   map[string]any{"name": "stress", "version": fmt.Sprint(n + 1)},
+		"capabilities": map[string]any{},
+	}
+	if err := call("initialize", initialize); err != nil {
+		return nil, fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := conn.Notify(ctx, "initialized", map[string]any{}); err != nil {
+		return nil, fmt.Errorf("initialized failed: %w", err)
+	}
+
+	uri := fmt.Sprintf("%sclient%d.go", root, n+1)
+	text := fmt.Sprintf("package stress\n\nfunc main() {\n\tprintln(\"client %d\")\n}\n", n+1)
+	document := map[string]any{"uri": uri, "languageId": "go", "version": 1, "text": text}
+	if err := conn.Notify(ctx, "textDocument/didOpen", map[string]any{"textDocument": document}); err != nil {
+		return nil, fmt.Errorf("didOpen failed: %w", err)
+	}
+
+	src := NewSeededRandomSource(seed)
+	version := 1
+	position := map[string]any{"line": 3, "character": 2}
+	for i := 1; i <= requests; i++ {
+		method := stressMethods[src.Intn(len(stressMethods))]
+		params := map[string]any{"textDocument": map[string]any{"uri": uri}, "position": position}
+		switch method {
+		case "textDocument/references":
+			params["context"] = map[string]any{"includeDeclaration": true}
+		case "textDocument/documentSymbol":
+			delete(params, "position")
+		}
+
+		started := time.Now()
+		err := call(method, params)
+		client.latencies[method] = append(client.latencies[method], time.Since(started))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			client.errors[method]++
+		}
+
+		if i%stressChangeEvery == 0 {
+			version++
+			change := map[string]any{
+				"textDocument": map[string]any{"uri": uri, "version": version},
+				"contentChanges": []any{map[string]any{
+					"range": map[string]any{"start": map[string]any{"line": 1, "character": 0}, "end": map[string]any{"line": 1, "character": 0}},
+					"text":  fmt.Sprintf("// edit %d\n", version),
+				}},
+			}
+			if err := conn.Notify(ctx, "textDocument/didChange", change); err != nil {
+				return nil, fmt.Errorf("didChange failed: %w", err)
+			}
+		}
+	}
+
+	if err := call("shutdown", nil); err != nil {
+		return nil, fmt.Errorf("shutdown failed: %w", err)
+	}
+	if counted := server.Stats().Requests; !maps.Equal(counted, sent) {
+		client.mismatch = fmt.Sprintf("%s counted requests %v, the client sent %v", client.clientID, counted, sent)
+	}
+	return client, nil
+}
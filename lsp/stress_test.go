@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunStress(t *testing.T) {
+	var servers []*MockLSPServer
+	newServer := func() *MockLSPServer {
+		server := createTestServer()
+		servers = append(servers, server)
+		return server
+	}
+
+	report, err := RunStress(context.Background(), newServer, Stress{Clients: 4, Requests: 30, Seed: 3})
+	if err != nil {
+		t.Fatalf("RunStress failed: %v", err)
+	}
+
+	if report.Clients != 4 || report.Requests != 120 || report.Errors != 0 || report.Seed != 3 {
+		t.Errorf("Expected 120 successful requests from 4 clients, got %+v", report)
+	}
+	if len(report.Mismatches) != 0 {
+		t.Errorf("Expected every server to count its own client's requests, got %v", report.Mismatches)
+	}
+	var total int64
+	for method, stat := range report.Methods {
+		total += stat.Requests
+		if stat.P50 == "" || stat.P99 == "" {
+			t.Errorf("Expected latencies for %s, got %+v", method, stat)
+		}
+	}
+	if total != 120 || report.RequestsPerSecond <= 0 {
+		t.Errorf("Expected the methods to add up to 120 requests at a positive rate, got %d at %f", total, report.RequestsPerSecond)
+	}
+
+	for _, server := range servers {
+		if server.State() != StateShuttingDown {
+			t.Errorf("Expected %s to be shut down, got %s", server.ClientID(), server.State())
+		}
+		if stats := server.Stats(); stats.OpenDocuments != 1 || stats.Notifications["textDocument/didChange"] != 6 {
+			t.Errorf("Expected one open document changed 6 times on %s, got %+v", server.ClientID(), stats)
+		}
+	}
+}
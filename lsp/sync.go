@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// positionOffset converts an LSP position into a byte offset within text.
+// Characters past the end of a line clamp to the end of that line, as the
+// spec requires; lines past the end of the document are an error.
+func positionOffset(text string, pos protocol.Position) (int, error) {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return 0, fmt.Errorf("line %d is beyond the end of the document", pos.Line)
+		}
+		offset += next + 1
+	}
+
+	lineText := text[offset:]
+	if end := strings.IndexByte(lineText, '\n'); end >= 0 {
+		lineText = lineText[:end]
+	}
+	lineText = strings.TrimSuffix(lineText, "\r")
+
+	return offset + utf16ToByteOffset(lineText, pos.Character), nil
+}
+
+// applyContentChange applies a single didChange content change to text
+func applyContentChange(text string, change protocol.TextDocumentContentChangeEvent) (string, error) {
+	switch v := change.Value.(type) {
+	case protocol.TextDocumentContentChangePartial:
+		start, err := positionOffset(text, v.Range.Start)
+		if err != nil {
+			return text, err
+		}
+		end, err := positionOffset(text, v.Range.End)
+		if err != nil {
+			return text, err
+		}
+		if start > end {
+			return text, fmt.Errorf("range start %+v is after end %+v", v.Range.Start, v.Range.End)
+		}
+		return text[:start] + v.Text + text[end:], nil
+	case protocol.TextDocumentContentChangeWholeDocument:
+		return v.Text, nil
+	default:
+		return text, fmt.Errorf("unknown content change type: %T", v)
+	}
+}
+
+// applyContentChanges applies didChange content changes in order. On error
+// the original text is returned untouched.
+func applyContentChanges(text string, changes []protocol.TextDocumentContentChangeEvent) (string, error) {
+	updated := text
+	for i, change := range changes {
+		var err error
+		updated, err = applyContentChange(updated, change)
+		if err != nil {
+			return text, fmt.Errorf("content change %d: %w", i, err)
+		}
+	}
+	return updated, nil
+}
+
+// firstDivergence returns the position of the first difference between two
+// texts, measured in UTF-16 code units
+func firstDivergence(a, b string) protocol.Position {
+	common := 0
+	for common < len(a) && common < len(b) && a[common] == b[common] {
+		common++
+	}
+	// Back up to a rune boundary so the prefix is valid UTF-8
+	for common > 0 && common < len(a) && !utf8.RuneStart(a[common]) {
+		common--
+	}
+
+	prefix := a[:common]
+	line := uint32(strings.Count(prefix, "\n"))
+	lineStart := strings.LastIndexByte(prefix, '\n') + 1
+	return protocol.Position{Line: line, Character: utf16Len(prefix[lineStart:])}
+}
@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+func partialChange(startLine, startChar, endLine, endChar uint32, text string) protocol.TextDocumentContentChangeEvent {
+	return protocol.TextDocumentContentChangeEvent{
+		Value: protocol.TextDocumentContentChangePartial{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: startLine, Character: startChar},
+				End:   protocol.Position{Line: endLine, Character: endChar},
+			},
+			Text: text,
+		},
+	}
+}
+
+func TestApplyContentChanges(t *testing.T) {
+	testCases := []struct {
+		name        string
+		text        string
+		changes     []protocol.TextDocumentContentChangeEvent
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Insert",
+			text:     "hello world\n",
+			changes:  []protocol.TextDocumentContentChangeEvent{partialChange(0, 5, 0, 5, ",")},
+			expected: "hello, world\n",
+		},
+		{
+			name:     "Delete Across Lines",
+			text:     "one\ntwo\nthree\n",
+			changes:  []protocol.TextDocumentContentChangeEvent{partialChange(0, 3, 2, 0, " ")},
+			expected: "one three\n",
+		},
+		{
+			name: "Sequential Changes",
+			text: "abc",
+			changes: []protocol.TextDocumentContentChangeEvent{
+				partialChange(0, 3, 0, 3, "\ndef"),
+				partialChange(1, 0, 1, 1, "D"),
+			},
+			expected: "abc\nDef",
+		},
+		{
+			name:     "Surrogate Pairs",
+			text:     "\U0001F600x\U0001F600y\n",
+			changes:  []protocol.TextDocumentContentChangeEvent{partialChange(0, 3, 0, 5, "-")},
+			expected: "\U0001F600x-y\n",
+		},
+		{
+			name:     "CRLF Line Endings",
+			text:     "ab\r\ncd\r\n",
+			changes:  []protocol.TextDocumentContentChangeEvent{partialChange(0, 10, 1, 1, "")},
+			expected: "abd\r\n",
+		},
+		{
+			name:     "Whole Document",
+			text:     "old",
+			changes:  []protocol.TextDocumentContentChangeEvent{{Value: protocol.TextDocumentContentChangeWholeDocument{Text: "new"}}},
+			expected: "new",
+		},
+		{
+			name:        "Line Out Of Range",
+			text:        "abc",
+			changes:     []protocol.TextDocumentContentChangeEvent{partialChange(3, 0, 3, 0, "x")},
+			expected:    "abc",
+			expectError: true,
+		},
+		{
+			name:        "Reversed Range",
+			text:        "abc",
+			changes:     []protocol.TextDocumentContentChangeEvent{partialChange(0, 2, 0, 1, "x")},
+			expected:    "abc",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := applyContentChanges(tc.text, tc.changes)
+			if tc.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			} else if !tc.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFirstDivergence(t *testing.T) {
+	pos := firstDivergence("line one\n\U0001F600abc", "line one\n\U0001F600abd")
+	if pos.Line != 1 || pos.Character != 4 {
+		t.Errorf("Expected divergence at 1:4, got %d:%d", pos.Line, pos.Character)
+	}
+}
+
+func TestDidSaveReconciliation(t *testing.T) {
+	server := createTestServer()
+	messages := make(chan protocol.LogMessageParams, 1)
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		if req.Method != "window/logMessage" {
+			return
+		}
+		var params protocol.LogMessageParams
+		if err := json.Unmarshal(*req.Params, &params); err == nil {
+			messages <- params
+		}
+	})
+	ctx := context.Background()
+	uri := "file:///save.go"
+
+	notify := func(method string, params any) {
+		if err := client.Notify(ctx, method, params); err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+	}
+
+	notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "go", "version": 1, "text": "package main\n"},
+	})
+	notify("textDocument/didChange", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{
+			map[string]any{
+				"range": map[string]any{"start": map[string]any{"line": 1, "character": 0}, "end": map[string]any{"line": 1, "character": 0}},
+				"text":  "func main() {}\n",
+			},
+		},
+	})
+
+	// Matching text must not be reported
+	notify("textDocument/didSave", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"text":         "package main\nfunc main() {}\n",
+	})
+	// A client that lost an edit
+	notify("textDocument/didSave", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"text":         "package main\n",
+	})
+
+	select {
+	case message := <-messages:
+		if message.Type != protocol.MessageTypeWarning {
+			t.Errorf("Expected warning message, got type %d", message.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected divergence to be reported")
+	}
+
+	var snapshot StatsSnapshot
+	if err := client.Call(ctx, "$/mockLsp/stats", nil, &snapshot); err != nil {
+		t.Fatalf("Stats request failed: %v", err)
+	}
+	if snapshot.SyncDivergences != 1 {
+		t.Errorf("Expected 1 sync divergence, got %d", snapshot.SyncDivergences)
+	}
+
+	if text, _ := server.documentText(uri); text != "package main\n" {
+		t.Errorf("Expected buffer reconciled to saved text, got %q", text)
+	}
+}
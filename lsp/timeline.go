@@ -0,0 +1,94 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/scenario"
+)
+
+// timelineMessageTypes maps the message types of scenario events to LSP
+// message types
+var timelineMessageTypes = map[string]protocol.MessageType{
+	"error":   protocol.MessageTypeError,
+	"warning": protocol.MessageTypeWarning,
+	"info":    protocol.MessageTypeInfo,
+	"log":     protocol.MessageTypeLog,
+}
+
+// SetScenario sets the scenario whose timeline runs once the client sends
+// initialized
+func (s *MockLSPServer) SetScenario(sc *scenario.Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario = sc
+}
+
+// startTimeline runs the timeline of the scenario in the background. It
+// stops when the connection closes.
+func (s *MockLSPServer) startTimeline(ctx context.Context, conn Conn) {
+	s.mu.Lock()
+	sc := s.scenario
+	s.mu.Unlock()
+	if sc == nil || len(sc.Timeline) == 0 {
+		return
+	}
+
+	s.logInfo(ctx, "Starting scenario timeline with %d events", len(sc.Timeline))
+	go s.runTimeline(context.WithoutCancel(ctx), conn, sc)
+}
+
+// runTimeline runs each event of the timeline at its offset from now
+func (s *MockLSPServer) runTimeline(ctx context.Context, conn Conn, sc *scenario.Scenario) {
+	start := time.Now()
+	for _, event := range sc.Timeline {
+		timer := time.NewTimer(sc.Offset(event) - time.Since(start))
+		select {
+		case <-timer.C:
+		case <-conn.DisconnectNotify():
+			timer.Stop()
+			s.logInfo(ctx, "Scenario timeline stopped: connection closed")
+			return
+		}
+		s.runTimelineEvent(ctx, conn, event)
+	}
+	s.logInfo(ctx, "Scenario timeline finished")
+}
+
+// runTimelineEvent performs the action of a single timeline event
+func (s *MockLSPServer) runTimelineEvent(ctx context.Context, conn Conn, event scenario.Event) {
+	s.logInfo(ctx, "Timeline event at %s: %s", event.At, event.Action)
+
+	switch event.Action {
+	case scenario.ActionPublishDiagnostics:
+		s.sendMockDiagnostics(ctx, conn, event.URI)
+	case scenario.ActionRequestConfiguration:
+		var settings []any
+		params := protocol.ConfigurationParams{Items: []protocol.ConfigurationItem{{Section: event.Section}}}
+		if err := s.call(ctx, conn, "workspace/configuration", params, &settings); err != nil {
+			s.logError(ctx, "workspace/configuration failed: %v", err)
+			return
+		}
+		s.logInfo(ctx, "Client configuration: %v", settings)
+	case scenario.ActionShowMessage:
+		params := protocol.ShowMessageParams{Type: timelineMessageType(event.Type), Message: event.Message}
+		if err := s.notify(ctx, conn, "window/showMessage", params); err != nil {
+			s.logError(ctx, "Failed to send window/showMessage: %v", err)
+		}
+	case scenario.ActionLogMessage:
+		params := protocol.LogMessageParams{Type: timelineMessageType(event.Type), Message: event.Message}
+		if err := s.notify(ctx, conn, "window/logMessage", params); err != nil {
+			s.logError(ctx, "Failed to send window/logMessage: %v", err)
+		}
+	}
+}
+
+// timelineMessageType returns the LSP message type of a scenario message
+// type, defaulting to info
+func timelineMessageType(name string) protocol.MessageType {
+	if messageType, ok := timelineMessageTypes[name]; ok {
+		return messageType
+	}
+	return protocol.MessageTypeInfo
+}
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/scenario"
+)
+
+func TestScenarioTimeline(t *testing.T) {
+	// An hour-long session at 3600x speed
+	sc, err := scenario.Parse([]byte(`{"speed": 3600, "timeline": [
+		{"at": "1m", "action": "show_message", "message": "Indexing finished", "type": "warning"},
+		{"at": "5s", "action": "publish_diagnostics", "uri": "untitled:Untitled-1"},
+		{"at": "30s", "action": "request_configuration", "section": "mock"},
+		{"at": "1h", "action": "log_message", "message": "Session over"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+
+	server := createTestServer()
+	server.SetScenario(sc)
+
+	received := make(chan *jsonrpc.Request, 10)
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		received <- req
+	})
+	ctx := context.Background()
+
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	select {
+	case req := <-received:
+		t.Fatalf("Expected nothing before initialized, got %s", req.Method)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	start := time.Now()
+	if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+
+	want := []string{publishDiagnosticsMethod, "workspace/configuration", "window/showMessage", "window/logMessage"}
+	var last *jsonrpc.Request
+	for _, method := range want {
+		select {
+		case req := <-received:
+			if req.Method != method {
+				t.Fatalf("Expected %s next, got %s", method, req.Method)
+			}
+			last = req
+			if method == "workspace/configuration" {
+				var params protocol.ConfigurationParams
+				if err := json.Unmarshal(*req.Params, &params); err != nil || len(params.Items) != 1 || params.Items[0].Section != "mock" {
+					t.Errorf("Expected the mock section to be requested, got %s", *req.Params)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %s", method)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the last event after 1h/3600 = 1s, got %s", elapsed)
+	}
+
+	var message protocol.LogMessageParams
+	if err := json.Unmarshal(*last.Params, &message); err != nil {
+		t.Fatalf("Failed to decode log message: %v", err)
+	}
+	if message.Message != "Session over" || message.Type != protocol.MessageTypeInfo {
+		t.Errorf("Expected the final info message, got %+v", message)
+	}
+}
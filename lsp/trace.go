@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// defaultTraceField is the key trace metadata is stored under when the
+// configuration does not name one
+const defaultTraceField = "mockLspTrace"
+
+// TraceMetadata identifies the span of a single mock response within the
+// server's trace, in W3C trace context form
+type TraceMetadata struct {
+	TraceID     string `json:"traceId"`
+	SpanID      string `json:"spanId"`
+	Traceparent string `json:"traceparent"`
+	Method      string `json:"method"`
+}
+
+// newTraceMetadata creates the metadata of a new span in the given trace
+func newTraceMetadata(traceID, method string) TraceMetadata {
+	spanID := randomHex(8)
+	return TraceMetadata{
+		TraceID:     traceID,
+		SpanID:      spanID,
+		Traceparent: fmt.Sprintf("00-%s-%s-01", traceID, spanID),
+		Method:      method,
+	}
+}
+
+// randomHex returns n random bytes encoded as lowercase hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// traceExtra returns the entries to merge into the data and experimental
+// sections of a message for method, or nil when trace injection is disabled
+func (s *MockLSPServer) traceExtra(ctx context.Context, method string) map[string]any {
+	trace := s.config().LSP.TraceMetadata
+	if !trace.Enabled {
+		return nil
+	}
+
+	field := trace.Field
+	if field == "" {
+		field = defaultTraceField
+	}
+
+	metadata := newTraceMetadata(s.snapshot().traceID, method)
+	s.logDebug(ctx, "Tracing %s as span %s of trace %s", method, metadata.SpanID, metadata.TraceID)
+	return map[string]any{field: metadata}
+}
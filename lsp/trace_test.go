@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// traceFrom extracts the trace metadata stored under field in a data section
+func traceFrom(t *testing.T, section any, field string) TraceMetadata {
+	t.Helper()
+	data, err := json.Marshal(section)
+	if err != nil {
+		t.Fatalf("Failed to marshal section: %v", err)
+	}
+	var wrapper map[string]TraceMetadata
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		t.Fatalf("Expected an object in %s, got error: %v", data, err)
+	}
+	return wrapper[field]
+}
+
+func TestNewTraceMetadata(t *testing.T) {
+	first := newTraceMetadata(testTraceID, "textDocument/hover")
+	second := newTraceMetadata(testTraceID, "textDocument/hover")
+
+	if len(first.SpanID) != 16 {
+		t.Errorf("Expected a 16 character span ID, got %q", first.SpanID)
+	}
+	if first.SpanID == second.SpanID {
+		t.Error("Expected each span to get a new span ID")
+	}
+	if expected := "00-" + testTraceID + "-" + first.SpanID + "-01"; first.Traceparent != expected {
+		t.Errorf("Expected traceparent %s, got %s", expected, first.Traceparent)
+	}
+}
+
+func TestTraceMetadataInjection(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.TraceMetadata = config.TraceMetadataConfig{Enabled: true, Field: "otel", TraceID: testTraceID}
+	server.SetConfig(cfg)
+
+	diagnostics := make(chan protocol.PublishDiagnosticsParams, 1)
+	client := connectTestClient(t, server, func(req *jsonrpc.Request) {
+		var params protocol.PublishDiagnosticsParams
+		if req.Method == "textDocument/publishDiagnostics" && json.Unmarshal(*req.Params, &params) == nil {
+			diagnostics <- params
+		}
+	})
+	ctx := context.Background()
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize request failed: %v", err)
+	}
+	if trace := traceFrom(t, initResult.Capabilities.Experimental, "otel"); trace.TraceID != testTraceID || trace.Method != "initialize" {
+		t.Errorf("Expected initialize trace in experimental capabilities, got %+v", trace)
+	}
+
+	var completions protocol.CompletionList
+	params := protocol.CompletionParams{TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///test.go"}}
+	if err := client.Call(ctx, "textDocument/completion", params, &completions); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+	for _, item := range completions.Items {
+		if trace := traceFrom(t, item.Data, "otel"); trace.TraceID != testTraceID {
+			t.Errorf("Expected trace ID %s in %s data, got %+v", testTraceID, item.Label, trace)
+		}
+	}
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///test.go", LanguageId: "go", Text: "package main\n"},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	published := <-diagnostics
+	if len(published.Diagnostics) == 0 {
+		t.Fatal("Expected diagnostics to be published")
+	}
+	for _, diagnostic := range published.Diagnostics {
+		trace := traceFrom(t, diagnostic.Data, "otel")
+		if trace.TraceID != testTraceID || trace.Method != "textDocument/publishDiagnostics" {
+			t.Errorf("Expected publishDiagnostics trace in diagnostic data, got %+v", trace)
+		}
+	}
+}
+
+func TestTraceMetadataDisabled(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+
+	var initResult protocol.InitializeResult
+	if err := client.Call(context.Background(), "initialize", protocol.InitializeParams{}, &initResult); err != nil {
+		t.Fatalf("Initialize request failed: %v", err)
+	}
+	if initResult.Capabilities.Experimental != nil {
+		t.Errorf("Expected no experimental capabilities, got %v", initResult.Capabilities.Experimental)
+	}
+}
+
+func TestTraceMetadataLoggedAtDebug(t *testing.T) {
+	var logs lockedBuffer
+	server := NewMockLSPServer(log.New(&logs, "", 0))
+	cfg := config.DefaultConfig()
+	cfg.LSP.TraceMetadata = config.TraceMetadataConfig{Enabled: true, TraceID: testTraceID}
+	server.SetConfig(cfg)
+
+	server.traceExtra(context.Background(), "textDocument/hover")
+	if got := logs.String(); !strings.Contains(got, "DEBUG: Tracing textDocument/hover") {
+		t.Errorf("Expected the span to be logged at debug level, got %q", got)
+	}
+}
@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/recording"
+)
+
+// Directions and kinds of recorded wire messages
+const (
+	trafficReceived     = "received"
+	trafficSent         = "sent"
+	trafficRequest      = "request"
+	trafficNotification = "notification"
+	trafficResponse     = "response"
+)
+
+// TrafficMessage is a wire message kept in the recent traffic ring buffer
+type TrafficMessage struct {
+	Time      time.Time        `json:"time"`
+	Direction string           `json:"direction"`
+	Kind      string           `json:"kind"`
+	ID        string           `json:"id,omitempty"`
+	Method    string           `json:"method,omitempty"`
+	Payload   *json.RawMessage `json:"payload,omitempty"`
+	Error     *jsonrpc.Error   `json:"error,omitempty"`
+}
+
+// RecentTrafficResult is the response to $/mockLsp/recentTraffic
+type RecentTrafficResult struct {
+	Capacity int              `json:"capacity"`
+	Messages []TrafficMessage `json:"messages"`
+}
+
+// trafficRecorder keeps the latest wire messages of a connection in a ring
+// buffer, so the context of an unexpected client behavior can be inspected
+// without enabling full tracing
+type trafficRecorder struct {
+	mu       sync.Mutex
+	messages []TrafficMessage
+	next     int
+	capacity int
+	methods  map[pendingRequest]pendingCall // Requests awaiting a response
+	redactor *redactor                      // Hides payload contents, nil keeps them
+	store    recording.Store                // Also keeps every message, nil keeps none
+	session  string
+	onError  func(error) // Reports failures to store a message
+}
+
+// pendingRequest identifies a request by the direction it travelled in, as
+// client and server number their requests independently
+type pendingRequest struct {
+	direction string
+	id        jsonrpc.ID
+}
+
+// pendingCall is the method and send time of a request awaiting a response
+type pendingCall struct {
+	method string
+	start  time.Time
+}
+
+// newTrafficRecorder creates a recorder keeping up to capacity messages.
+// A capacity of zero disables recording.
+func newTrafficRecorder(capacity int) *trafficRecorder {
+	return &trafficRecorder{capacity: capacity, methods: make(map[pendingRequest]pendingCall)}
+}
+
+// resize changes the capacity, keeping the latest messages that still fit
+func (r *trafficRecorder) resize(capacity int) {
+	messages := r.snapshot()
+	if len(messages) > capacity {
+		messages = messages[len(messages)-capacity:]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.capacity = capacity
+	r.messages = messages
+	r.next = 0
+}
+
+// trim drops all but the newest keep messages without changing the
+// capacity, freeing the memory the older ones held
+func (r *trafficRecorder) trim(keep int) {
+	messages := r.snapshot()
+	if len(messages) <= keep {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append([]TrafficMessage(nil), messages[len(messages)-keep:]...)
+	r.next = 0
+}
+
+// setRedactor makes the recorder redact the payloads of later messages
+func (r *trafficRecorder) setRedactor(redactor *redactor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactor = redactor
+}
+
+// setStore makes the recorder also append every later message of session to
+// store, reporting failures to onError. A nil store stops it.
+func (r *trafficRecorder) setStore(store recording.Store, session string, onError func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store = store
+	r.session = session
+	r.onError = onError
+}
+
+// record stores a message, overwriting the oldest once the buffer is full.
+// latency is the time since the request a response answers. The message is
+// appended to the store once the recorder is unlocked, so a slow store
+// does not hold up the messages of other handlers.
+func (r *trafficRecorder) record(msg TrafficMessage, latency time.Duration) {
+	r.mu.Lock()
+	if r.capacity == 0 && r.store == nil {
+		r.mu.Unlock()
+		return
+	}
+	msg.Time = time.Now()
+	msg.Payload = r.redactor.redactJSON(msg.Payload)
+	if msg.Error != nil && msg.Error.Data != nil {
+		redacted := *msg.Error
+		redacted.Data = r.redactor.redactJSON(msg.Error.Data)
+		msg.Error = &redacted
+	}
+	store, onError := r.store, r.onError
+	var record recording.Record
+	if store != nil {
+		record = r.storeRecord(msg, latency)
+	}
+	switch {
+	case r.capacity == 0:
+	case len(r.messages) < r.capacity:
+		r.messages = append(r.messages, msg)
+	default:
+		r.messages[r.next] = msg
+		r.next = (r.next + 1) % r.capacity
+	}
+	r.mu.Unlock()
+
+	if store != nil {
+		if err := store.Append(record); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// recordRequest stores a request or notification and remembers the method of
+// requests so their responses can be labelled
+func (r *trafficRecorder) recordRequest(direction string, req *jsonrpc.Request) {
+	msg := TrafficMessage{Direction: direction, Kind: trafficNotification, Method: req.Method, Payload: req.Params}
+	if !req.Notif {
+		msg.Kind = trafficRequest
+		msg.ID = req.ID.String()
+		r.mu.Lock()
+		r.methods[pendingRequest{direction, req.ID}] = pendingCall{method: req.Method, start: time.Now()}
+		r.mu.Unlock()
+	}
+	r.record(msg, 0)
+}
+
+// recordResponse stores a response, labelled with the method it answers
+func (r *trafficRecorder) recordResponse(direction string, resp *jsonrpc.Response) {
+	request := pendingRequest{trafficReceived, resp.ID}
+	if direction == trafficReceived {
+		request.direction = trafficSent
+	}
+
+	r.mu.Lock()
+	call, ok := r.methods[request]
+	delete(r.methods, request)
+	r.mu.Unlock()
+
+	var latency time.Duration
+	if ok {
+		latency = time.Since(call.start)
+	}
+	r.record(TrafficMessage{
+		Direction: direction,
+		Kind:      trafficResponse,
+		ID:        resp.ID.String(),
+		Method:    call.method,
+		Payload:   resp.Result,
+		Error:     resp.Error,
+	}, latency)
+}
+
+// storeRecord converts a redacted message to a record of the session
+func (r *trafficRecorder) storeRecord(msg TrafficMessage, latency time.Duration) recording.Record {
+	record := recording.Record{
+		Time:      msg.Time,
+		Session:   r.session,
+		Direction: msg.Direction,
+		Kind:      msg.Kind,
+		ID:        msg.ID,
+		Method:    msg.Method,
+		Latency:   config.Duration(latency),
+	}
+	if msg.Payload != nil {
+		record.Payload = *msg.Payload
+	}
+	if msg.Error != nil {
+		record.Error, _ = json.Marshal(msg.Error)
+	}
+	return record
+}
+
+// snapshot returns the recorded messages, oldest first
+func (r *trafficRecorder) snapshot() []TrafficMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := make([]TrafficMessage, 0, len(r.messages))
+	messages = append(messages, r.messages[r.next:]...)
+	return append(messages, r.messages[:r.next]...)
+}
+
+// connOpts returns the connection options that feed the recorder
+func (r *trafficRecorder) connOpts() []jsonrpc.ConnOpt {
+	return []jsonrpc.ConnOpt{
+		jsonrpc.OnRecv(func(req *jsonrpc.Request, resp *jsonrpc.Response) {
+			if resp != nil {
+				r.recordResponse(trafficReceived, resp)
+			} else if req != nil {
+				r.recordRequest(trafficReceived, req)
+			}
+		}),
+		jsonrpc.OnSend(func(req *jsonrpc.Request, resp *jsonrpc.Response) {
+			if resp != nil {
+				r.recordResponse(trafficSent, resp)
+			} else if req != nil {
+				r.recordRequest(trafficSent, req)
+			}
+		}),
+	}
+}
@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/recording"
+)
+
+func TestTrafficRecorderRing(t *testing.T) {
+	recorder := newTrafficRecorder(3)
+	for _, method := range []string{"a", "b", "c", "d", "e"} {
+		recorder.recordRequest(trafficReceived, &jsonrpc.Request{Method: method, Notif: true})
+	}
+
+	messages := recorder.snapshot()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	for i, expected := range []string{"c", "d", "e"} {
+		if messages[i].Method != expected {
+			t.Errorf("Expected message %d to be %s, got %s", i, expected, messages[i].Method)
+		}
+	}
+
+	recorder.resize(2)
+	messages = recorder.snapshot()
+	if len(messages) != 2 || messages[0].Method != "d" || messages[1].Method != "e" {
+		t.Errorf("Expected resize to keep the latest messages, got %+v", messages)
+	}
+
+	recorder.resize(0)
+	recorder.recordRequest(trafficReceived, &jsonrpc.Request{Method: "f", Notif: true})
+	if messages := recorder.snapshot(); len(messages) != 0 {
+		t.Errorf("Expected no messages with zero capacity, got %d", len(messages))
+	}
+}
+
+// blockingStore holds every Append until release is closed
+type blockingStore struct {
+	recording.Store
+	appending chan struct{}
+	release   chan struct{}
+}
+
+func (s *blockingStore) Append(recording.Record) error {
+	s.appending <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func TestTrafficRecorderAppendsUnlocked(t *testing.T) {
+	recorder := newTrafficRecorder(3)
+	store := &blockingStore{appending: make(chan struct{}, 2), release: make(chan struct{})}
+	defer close(store.release)
+	recorder.setStore(store, "session", nil)
+
+	go recorder.recordRequest(trafficReceived, &jsonrpc.Request{Method: "a", Notif: true})
+	<-store.appending
+	// A slow store must not hold up the other messages
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		recorder.recordRequest(trafficReceived, &jsonrpc.Request{Method: "b", Notif: true})
+	}()
+	select {
+	case <-store.appending:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the second message to reach the store while the first is appended")
+	}
+	if messages := recorder.snapshot(); len(messages) != 2 {
+		t.Errorf("Expected 2 messages in the ring buffer, got %d", len(messages))
+	}
+}
+
+func TestRecentTraffic(t *testing.T) {
+	server := createTestServer()
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///test.go", LanguageId: "go", Text: "package main\n"},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	var hover protocol.Hover
+	if err := client.Call(ctx, "textDocument/hover", protocol.HoverParams{TextDocument: protocol.TextDocumentIdentifier{Uri: open.TextDocument.Uri}}, &hover); err != nil {
+		t.Fatalf("Hover request failed: %v", err)
+	}
+	if err := client.Call(ctx, "unknown/method", nil, nil); err == nil {
+		t.Fatal("Expected unknown method to fail")
+	}
+
+	var result RecentTrafficResult
+	if err := client.Call(ctx, "$/mockLsp/recentTraffic", nil, &result); err != nil {
+		t.Fatalf("recentTraffic request failed: %v", err)
+	}
+	if result.Capacity != config.DefaultConfig().LSP.RecentTraffic {
+		t.Errorf("Expected capacity %d, got %d", config.DefaultConfig().LSP.RecentTraffic, result.Capacity)
+	}
+
+	expected := []struct {
+		direction, kind, method string
+	}{
+		{trafficReceived, trafficNotification, "textDocument/didOpen"},
+		{trafficSent, trafficNotification, "textDocument/publishDiagnostics"},
+		{trafficReceived, trafficRequest, "textDocument/hover"},
+		{trafficSent, trafficResponse, "textDocument/hover"},
+		{trafficReceived, trafficRequest, "unknown/method"},
+		{trafficSent, trafficResponse, "unknown/method"},
+		{trafficReceived, trafficRequest, "$/mockLsp/recentTraffic"},
+	}
+	if len(result.Messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %+v", len(expected), result.Messages)
+	}
+	for i, e := range expected {
+		msg := result.Messages[i]
+		if msg.Direction != e.direction || msg.Kind != e.kind || msg.Method != e.method {
+			t.Errorf("Expected message %d to be %s %s %s, got %s %s %s", i, e.direction, e.kind, e.method, msg.Direction, msg.Kind, msg.Method)
+		}
+	}
+	if result.Messages[5].Error == nil {
+		t.Error("Expected the unknown method response to carry its error")
+	}
+}
@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+// unicodeSamples holds the torture strings for each configured category
+var unicodeSamples = map[string][]string{
+	// Stacked combining marks: several code points render as one glyph
+	config.UnicodeCombining: {"e\u0301", "n\u0303\u0323", "Z\u0351\u0308\u0324\u0330"},
+	// Right-to-left scripts and a bidi override that reorders what follows
+	config.UnicodeRTL: {"\u05e9\u05dc\u05d5\u05dd", "\u0645\u0631\u062d\u0628\u0627", "\u202eevil\u202c"},
+	// ZWJ sequences, variation selectors and skin-tone modifiers
+	config.UnicodeEmoji: {"\U0001F600", "\U0001F469\u200d\U0001F4BB", "\U0001F3F3\ufe0f\u200d\U0001F308", "\U0001F44D\U0001F3FD"},
+	// Astral-plane code points that take two UTF-16 code units each
+	config.UnicodeAstral: {"\U0001D487\U0001D490\U0001D490", "\U00010437", "\U0002070E"},
+}
+
+// unicodeTorturer mixes torture strings into generated labels and messages
+type unicodeTorturer struct {
+	samples []string
+}
+
+// newUnicodeTorturer returns a torturer for the given categories, or nil when
+// none are configured
+func newUnicodeTorturer(categories []string) *unicodeTorturer {
+	var samples []string
+	for _, category := range categories {
+		samples = append(samples, unicodeSamples[category]...)
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return &unicodeTorturer{samples: samples}
+}
+
+// decorate appends the i-th torture sample to text
+func (u *unicodeTorturer) decorate(text string, i int) string {
+	return text + u.samples[i%len(u.samples)]
+}
+
+// completionItems decorates item labels and replaces the word under the
+// cursor through a text edit whose range is measured in UTF-16 code units
+func (u *unicodeTorturer) completionItems(items []protocol.CompletionItem, text string, pos protocol.Position) []protocol.CompletionItem {
+	rng, ok := wordRangeAt(text, pos)
+	if !ok {
+		rng = protocol.Range{Start: pos, End: pos}
+	}
+
+	decorated := make([]protocol.CompletionItem, len(items))
+	for i, item := range items {
+		item.Label = u.decorate(item.Label, i)
+		item.InsertText = ""
+		item.TextEdit = &protocol.Or2[protocol.TextEdit, protocol.InsertReplaceEdit]{
+			Value: protocol.TextEdit{Range: rng, NewText: item.Label},
+		}
+		decorated[i] = item
+	}
+	return decorated
+}
+
+// hover decorates the hover text and points its range at the word under the
+// cursor in UTF-16 code units
+func (u *unicodeTorturer) hover(hover protocol.Hover, text string, pos protocol.Position) protocol.Hover {
+	if markup, ok := hover.Contents.Value.(protocol.MarkupContent); ok {
+		markup.Value = u.decorate(markup.Value+" ", 0)
+		hover.Contents.Value = markup
+	}
+	if rng, ok := wordRangeAt(text, pos); ok {
+		hover.Range = &rng
+	}
+	return hover
+}
+
+// diagnostics decorates diagnostic messages and clamps their ranges onto
+// valid UTF-16 boundaries of the document
+func (u *unicodeTorturer) diagnostics(diagnostics []protocol.Diagnostic, text string) []protocol.Diagnostic {
+	decorated := make([]protocol.Diagnostic, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		diagnostic.Message = u.decorate(diagnostic.Message+" ", i)
+		diagnostic.Range = clampRange(text, diagnostic.Range)
+		decorated[i] = diagnostic
+	}
+	return decorated
+}
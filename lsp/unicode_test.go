@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestNewUnicodeTorturer(t *testing.T) {
+	if newUnicodeTorturer(nil) != nil {
+		t.Error("Expected no torturer without categories")
+	}
+
+	torture := newUnicodeTorturer([]string{config.UnicodeAstral})
+	if torture == nil {
+		t.Fatal("Expected torturer for astral category")
+	}
+	for i := range torture.samples {
+		decorated := torture.decorate("label", i)
+		if utf16Len(decorated) <= uint32(len([]rune(decorated))) {
+			t.Errorf("Expected astral sample in %q to use surrogate pairs", decorated)
+		}
+	}
+}
+
+func TestUnicodeTorture_Diagnostics(t *testing.T) {
+	torture := newUnicodeTorturer(config.UnicodeCategories)
+	diagnostics := torture.diagnostics([]protocol.Diagnostic{
+		{
+			Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 1}, End: protocol.Position{Line: 5, Character: 25}},
+			Message: "mock",
+		},
+	}, "\U0001F600 x")
+
+	if !strings.HasPrefix(diagnostics[0].Message, "mock ") || diagnostics[0].Message == "mock " {
+		t.Errorf("Expected decorated message, got %q", diagnostics[0].Message)
+	}
+	expected := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 0},
+		End:   protocol.Position{Line: 0, Character: 4},
+	}
+	if diagnostics[0].Range != expected {
+		t.Errorf("Expected %+v, got %+v", expected, diagnostics[0].Range)
+	}
+}
+
+func TestUnicodeTortureCompletionOverWire(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.Unicode = []string{config.UnicodeEmoji, config.UnicodeRTL}
+	server.SetConfig(cfg)
+
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	open := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{Uri: "file:///torture.go", LanguageId: "go", Text: "x := \U0001F600moc"},
+	}
+	if err := client.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	params := protocol.CompletionParams{
+		TextDocument: protocol.TextDocumentIdentifier{Uri: "file:///torture.go"},
+		Position:     protocol.Position{Line: 0, Character: 10},
+	}
+	var list protocol.CompletionList
+	if err := client.Call(ctx, "textDocument/completion", params, &list); err != nil {
+		t.Fatalf("Completion request failed: %v", err)
+	}
+
+	if len(list.Items) == 0 {
+		t.Fatal("Expected completion items")
+	}
+	for _, item := range list.Items {
+		if item.TextEdit == nil {
+			t.Fatalf("Expected text edit on %q", item.Label)
+		}
+		edit, ok := item.TextEdit.Value.(protocol.TextEdit)
+		if !ok {
+			t.Fatalf("Expected plain text edit, got %T", item.TextEdit.Value)
+		}
+		if edit.Range.Start.Character != 7 || edit.Range.End.Character != 10 {
+			t.Errorf("Expected edit range 7-10 in UTF-16 units, got %+v", edit.Range)
+		}
+		if edit.NewText != item.Label {
+			t.Errorf("Expected edit text %q, got %q", item.Label, edit.NewText)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+// utf16Len returns the length of s in UTF-16 code units, the unit LSP
+// positions are measured in by default. Astral-plane runes count as two.
+func utf16Len(s string) uint32 {
+	var n uint32
+	for _, r := range s {
+		n += uint32(utf16.RuneLen(r))
+	}
+	return n
+}
+
+// lineAt returns the given zero-based line of text without its line ending
+func lineAt(text string, line uint32) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if int(line) >= len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[line], "\r"), true
+}
+
+// utf16ToByteOffset converts a UTF-16 character offset within line to a byte
+// offset. Offsets past the end clamp to the line length and offsets that
+// fall inside a surrogate pair snap back to the start of the rune.
+func utf16ToByteOffset(line string, character uint32) int {
+	var units uint32
+	for i, r := range line {
+		width := uint32(utf16.RuneLen(r))
+		if units+width > character {
+			return i
+		}
+		units += width
+	}
+	return len(line)
+}
+
+// isWordRune reports whether r belongs to an identifier-like word. Combining
+// marks are included so a base character is never split from its accents.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// wordRangeAt returns the range of the word touching pos, measured in UTF-16
+// code units. ok is false when pos lies beyond the end of text.
+func wordRangeAt(text string, pos protocol.Position) (rng protocol.Range, ok bool) {
+	line, ok := lineAt(text, pos.Line)
+	if !ok {
+		return protocol.Range{}, false
+	}
+
+	offset := utf16ToByteOffset(line, pos.Character)
+	start, end := offset, offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !isWordRune(r) {
+			break
+		}
+		start -= size
+	}
+	for end < len(line) {
+		r, size := utf8.DecodeRuneInString(line[end:])
+		if !isWordRune(r) {
+			break
+		}
+		end += size
+	}
+
+	return protocol.Range{
+		Start: protocol.Position{Line: pos.Line, Character: utf16Len(line[:start])},
+		End:   protocol.Position{Line: pos.Line, Character: utf16Len(line[:end])},
+	}, true
+}
+
+// clampPosition moves pos onto a valid UTF-16 boundary inside text
+func clampPosition(text string, pos protocol.Position) protocol.Position {
+	line, ok := lineAt(text, pos.Line)
+	if !ok {
+		last := uint32(strings.Count(text, "\n"))
+		line, _ = lineAt(text, last)
+		return protocol.Position{Line: last, Character: utf16Len(line)}
+	}
+	return protocol.Position{
+		Line:      pos.Line,
+		Character: utf16Len(line[:utf16ToByteOffset(line, pos.Character)]),
+	}
+}
+
+// clampRange moves both ends of rng onto valid UTF-16 boundaries inside text
+func clampRange(text string, rng protocol.Range) protocol.Range {
+	return protocol.Range{
+		Start: clampPosition(text, rng.Start),
+		End:   clampPosition(text, rng.End),
+	}
+}
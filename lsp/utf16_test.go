@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestUTF16Len(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected uint32
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"\u00e9", 1},
+		{"e\u0301", 2},
+		{"\U0001F600", 2},
+		{surrogateIdentifier, 6},
+	}
+
+	for _, tc := range testCases {
+		if got := utf16Len(tc.text); got != tc.expected {
+			t.Errorf("Expected utf16Len(%q) = %d, got %d", tc.text, tc.expected, got)
+		}
+	}
+}
+
+func TestWordRangeAt(t *testing.T) {
+	text := "package main\n\U0001F600 \U0001D487\U0001D490\U0001D490 = cafe\u0301\n"
+
+	testCases := []struct {
+		name     string
+		pos      protocol.Position
+		expected protocol.Range
+	}{
+		{
+			name:     "ASCII word",
+			pos:      protocol.Position{Line: 0, Character: 2},
+			expected: protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 7}},
+		},
+		{
+			name:     "Astral word after emoji",
+			pos:      protocol.Position{Line: 1, Character: 4},
+			expected: protocol.Range{Start: protocol.Position{Line: 1, Character: 3}, End: protocol.Position{Line: 1, Character: 9}},
+		},
+		{
+			name:     "Combining mark stays with its base",
+			pos:      protocol.Position{Line: 1, Character: 12},
+			expected: protocol.Range{Start: protocol.Position{Line: 1, Character: 12}, End: protocol.Position{Line: 1, Character: 17}},
+		},
+		{
+			name:     "Inside surrogate pair snaps to rune start",
+			pos:      protocol.Position{Line: 1, Character: 1},
+			expected: protocol.Range{Start: protocol.Position{Line: 1, Character: 0}, End: protocol.Position{Line: 1, Character: 0}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rng, ok := wordRangeAt(text, tc.pos)
+			if !ok {
+				t.Fatal("Expected a range")
+			}
+			if rng != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, rng)
+			}
+		})
+	}
+
+	if _, ok := wordRangeAt(text, protocol.Position{Line: 10}); ok {
+		t.Error("Expected no range past the end of the document")
+	}
+}
+
+func TestClampRange(t *testing.T) {
+	text := "\U0001F600\U0001F600\nab"
+	rng := clampRange(text, protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 3},
+		End:   protocol.Position{Line: 7, Character: 40},
+	})
+
+	expected := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 2},
+		End:   protocol.Position{Line: 1, Character: 2},
+	}
+	if rng != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rng)
+	}
+}
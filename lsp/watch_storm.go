@@ -0,0 +1,149 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// watchStormProbe is the request sent after every burst to measure how long
+// the server takes to get through it. Servers other than the mock answer it
+// with MethodNotFound, which takes just as long.
+const watchStormProbe = "$/mockLsp/stats"
+
+// WatchStorm describes a flood of workspace/didChangeWatchedFiles events
+type WatchStorm struct {
+	Files    int           // Files changed in every burst
+	Bursts   int           // Number of bursts
+	Interval time.Duration // Pause between bursts
+	Batch    int           // Events per notification
+	Root     string        // URI of the workspace folder holding the files
+	Seed     int64         // Seed of the change types; 0 picks a time-based seed
+}
+
+// WatchStormBurst reports a single burst of a watch storm
+type WatchStormBurst struct {
+	Created       int    `json:"created"`
+	Changed       int    `json:"changed"`
+	Deleted       int    `json:"deleted"`
+	Notifications int    `json:"notifications"`
+	Sent          string `json:"sent"`
+	Probe         string `json:"probe"`
+}
+
+// WatchStormReport summarizes a watch storm. Probe is how long the server
+// took to answer a request sent right after a burst.
+type WatchStormReport struct {
+	Seed     int64             `json:"seed"`
+	Events   int               `json:"events"`
+	Duration string            `json:"duration"`
+	MaxProbe string            `json:"maxProbe"`
+	Bursts   []WatchStormBurst `json:"bursts"`
+}
+
+// file returns the URI of the i-th file of the storm
+func (w WatchStorm) file(i int) string {
+	return fmt.Sprintf("%s/file%d.txt", strings.TrimSuffix(w.Root, "/"), i)
+}
+
+// RunWatchStorm initializes the server at the other end of rwc, floods it
+// with watched file events in bursts and shuts it down. Files are created
+// in the first burst; later bursts change two thirds of the existing files
+// and delete the rest, and recreate the deleted ones. Requests from the
+// server are answered with null.
+func RunWatchStorm(ctx context.Context, rwc io.ReadWriteCloser, storm WatchStorm) (*WatchStormReport, error) {
+	if storm.Seed == 0 {
+		storm.Seed = time.Now().UnixNano()
+	}
+	storm.Batch = max(storm.Batch, 1)
+	src := NewSeededRandomSource(storm.Seed)
+	report := &WatchStormReport{Seed: storm.Seed, Bursts: []WatchStormBurst{}}
+
+	conn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(rwc),
+		jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+			return nil, nil
+		}))
+	defer conn.Close()
+
+	// Raw JSON: the protocol types do not marshal through encoding/json
+	initialize := map[string]any{
+		"processId":        os.Getpid(),
+		"rootUri":          storm.Root,
+		"workspaceFolders": []any{map[string]any{"uri": storm.Root, "name": "watch-storm"}},
+		"capabilities": map[string]any{
+			"workspace": map[string]any{"didChangeWatchedFiles": map[string]any{"dynamicRegistration": true}},
+		},
+	}
+	if err := conn.Call(ctx, "initialize", initialize, nil); err != nil {
+		return report, fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := conn.Notify(ctx, "initialized", map[string]any{}); err != nil {
+		return report, fmt.Errorf("initialized failed: %w", err)
+	}
+
+	start := time.Now()
+	var maxProbe time.Duration
+	exists := make([]bool, storm.Files)
+	for burst := 0; burst < storm.Bursts; burst++ {
+		if burst > 0 {
+			time.Sleep(storm.Interval)
+		}
+
+		var result WatchStormBurst
+		var changes []any
+		sent := time.Now()
+		for i := range exists {
+			changeType := protocol.FileChangeTypeCreated
+			switch {
+			case !exists[i]:
+				exists[i] = true
+				result.Created++
+			case src.Intn(3) < 2:
+				changeType = protocol.FileChangeTypeChanged
+				result.Changed++
+			default:
+				changeType = protocol.FileChangeTypeDeleted
+				exists[i] = false
+				result.Deleted++
+			}
+			changes = append(changes, map[string]any{"uri": storm.file(i), "type": uint32(changeType)})
+
+			if len(changes) == storm.Batch || i == len(exists)-1 {
+				params := map[string]any{"changes": changes}
+				if err := conn.Notify(ctx, "workspace/didChangeWatchedFiles", params); err != nil {
+					return report, fmt.Errorf("didChangeWatchedFiles failed: %w", err)
+				}
+				result.Notifications++
+				report.Events += len(changes)
+				changes = nil
+			}
+		}
+		result.Sent = time.Since(sent).String()
+
+		probed := time.Now()
+		var rpcErr *jsonrpc.Error
+		if err := conn.Call(ctx, watchStormProbe, nil, nil); err != nil && !errors.As(err, &rpcErr) {
+			return report, fmt.Errorf("probe after burst %d failed: %w", burst+1, err)
+		}
+		probe := time.Since(probed)
+		maxProbe = max(maxProbe, probe)
+		result.Probe = probe.String()
+		report.Bursts = append(report.Bursts, result)
+	}
+	report.Duration = time.Since(start).String()
+	report.MaxProbe = maxProbe.String()
+
+	if err := conn.Call(ctx, "shutdown", nil, nil); err != nil {
+		return report, fmt.Errorf("shutdown failed: %w", err)
+	}
+	// The server exits, so the connection may close before this is sent
+	_ = conn.Notify(ctx, "exit", nil)
+	return report, nil
+}
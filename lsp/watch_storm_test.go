@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestRunWatchStorm(t *testing.T) {
+	server := createTestServer()
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+
+	serverConn := jsonrpc.NewConn(ctx, jsonrpc.NewStream(serverSide), server)
+	defer serverConn.Close()
+
+	storm := WatchStorm{Files: 25, Bursts: 4, Batch: 10, Root: "file:///storm/", Seed: 7}
+	report, err := RunWatchStorm(ctx, clientSide, storm)
+	if err != nil {
+		t.Fatalf("RunWatchStorm failed: %v", err)
+	}
+
+	if report.Seed != 7 || report.Events != 100 || len(report.Bursts) != 4 {
+		t.Fatalf("Expected 100 events in 4 bursts with seed 7, got %+v", report)
+	}
+	totals := make(map[string]int64)
+	for i, burst := range report.Bursts {
+		if burst.Created+burst.Changed+burst.Deleted != 25 || burst.Notifications != 3 {
+			t.Errorf("Expected burst %d to change 25 files in 3 notifications, got %+v", i, burst)
+		}
+		totals["created"] += int64(burst.Created)
+		totals["changed"] += int64(burst.Changed)
+		totals["deleted"] += int64(burst.Deleted)
+	}
+	if report.Bursts[0].Created != 25 {
+		t.Errorf("Expected the first burst to create every file, got %+v", report.Bursts[0])
+	}
+
+	received := server.Stats().WatchedFileEvents
+	for change, count := range totals {
+		if received[change] != count {
+			t.Errorf("Expected the server to count %d %s events, got %d", count, change, received[change])
+		}
+	}
+	select {
+	case <-server.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the storm to end with exit, got %s", server.State())
+	}
+	if code, exited := server.ExitStatus(); !exited || code != 0 {
+		t.Errorf("Expected exit after shutdown with code 0, got %d, %v", code, exited)
+	}
+}
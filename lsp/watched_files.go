@@ -0,0 +1,34 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/jsonrpc"
+)
+
+// fileChangeTypeNames names the watched file change types in stats
+var fileChangeTypeNames = map[protocol.FileChangeType]string{
+	protocol.FileChangeTypeCreated: "created",
+	protocol.FileChangeTypeChanged: "changed",
+	protocol.FileChangeTypeDeleted: "deleted",
+}
+
+// handleDidChangeWatchedFiles counts the watched file events of a
+// workspace/didChangeWatchedFiles notification by change type
+func (s *MockLSPServer) handleDidChangeWatchedFiles(ctx context.Context, _ Conn, req *jsonrpc.Request) {
+	var params protocol.DidChangeWatchedFilesParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		s.logError(ctx, "Failed to parse didChangeWatchedFiles params: %v", err)
+		return
+	}
+
+	counts := make(map[string]int64)
+	for _, change := range params.Changes {
+		counts[fileChangeTypeNames[change.Type]]++
+	}
+	s.stats.RecordWatchedFileEvents(counts)
+	s.logInfo(ctx, "Watched files changed: %d created, %d changed, %d deleted",
+		counts["created"], counts["changed"], counts["deleted"])
+}
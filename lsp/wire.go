@@ -0,0 +1,318 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// encodeWire marshals an LSP payload into JSON.
+//
+// The generated protocol enums implement json.Marshaler on pointer receivers by
+// calling json.Marshal on themselves, which recurses until the stack overflows
+// whenever the value is addressable (pointer fields, slice elements). encodeWire
+// walks the value with reflection instead, writing enums as their underlying
+// scalar and union types (Or2, Or3, ...) as the value they carry.
+func encodeWire(v any) (json.RawMessage, error) {
+	return encodeWireWithExtra(v, nil)
+}
+
+// encodeWireWithExtra marshals an LSP payload like encodeWire and merges the
+// entries of extra into every open-ended data and experimental section of it.
+// Sections holding something other than an object are left untouched.
+func encodeWireWithExtra(v any, extra map[string]any) (json.RawMessage, error) {
+	e := &wireEncoder{extra: extra}
+	if err := e.writeValue(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(e.buf.Bytes()), nil
+}
+
+// wireEncoder accumulates the JSON encoding of a payload
+type wireEncoder struct {
+	buf   bytes.Buffer
+	extra map[string]any
+}
+
+// writeValue writes the JSON encoding of v
+func (e *wireEncoder) writeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+
+	t := v.Type()
+	if t == rawMessageType {
+		if v.IsNil() {
+			e.buf.WriteString("null")
+		} else {
+			e.buf.Write(v.Bytes())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.writeValue(v.Elem())
+	}
+
+	if isUnionType(t) {
+		return e.writeValue(v.Field(0))
+	}
+
+	if t.Implements(marshalerType) {
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		data, err := json.Marshal(v.Float())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	case reflect.String:
+		data, err := json.Marshal(v.String())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			data, err := json.Marshal(v.Bytes())
+			if err != nil {
+				return err
+			}
+			e.buf.Write(data)
+			return nil
+		}
+		return e.writeArray(v)
+	case reflect.Array:
+		return e.writeArray(v)
+	case reflect.Map:
+		return e.writeMap(v)
+	case reflect.Struct:
+		return e.writeStruct(v)
+	default:
+		return fmt.Errorf("unsupported type for wire encoding: %s", t)
+	}
+	return nil
+}
+
+// isUnionType reports whether t is one of the protocol's OrN/NullableOrN unions
+// (or a named alias of one), which wrap a single interface-typed Value field
+func isUnionType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() != 1 {
+		return false
+	}
+	field := t.Field(0)
+	return field.Name == "Value" && field.Type.Kind() == reflect.Interface
+}
+
+// writeArray writes a slice or array as a JSON array
+func (e *wireEncoder) writeArray(v reflect.Value) error {
+	e.buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		if err := e.writeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte(']')
+	return nil
+}
+
+// writeMap writes a map as a JSON object with sorted keys
+func (e *wireEncoder) writeMap(v reflect.Value) error {
+	if v.IsNil() {
+		e.buf.WriteString("null")
+		return nil
+	}
+
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		var name string
+		switch key.Kind() {
+		case reflect.String:
+			name = key.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			name = strconv.FormatInt(key.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			name = strconv.FormatUint(key.Uint(), 10)
+		default:
+			return fmt.Errorf("unsupported map key type for wire encoding: %s", key.Type())
+		}
+		entries = append(entries, entry{key: name, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	e.buf.WriteByte('{')
+	for i, entry := range entries {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(entry.key)
+		e.buf.Write(name)
+		e.buf.WriteByte(':')
+		if err := e.writeValue(entry.value); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// writeStruct writes a struct as a JSON object honoring json tags
+func (e *wireEncoder) writeStruct(v reflect.Value) error {
+	e.buf.WriteByte('{')
+	first := true
+	if err := e.writeFields(v, &first); err != nil {
+		return err
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// writeFields writes the fields of a struct, inlining untagged embedded structs
+func (e *wireEncoder) writeFields(v reflect.Value, first *bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldValue := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := e.writeFields(embedded, first); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if e.extra != nil && isExtensibleField(field) {
+			fieldValue = e.mergeExtra(fieldValue)
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyWireValue(fieldValue) {
+			continue
+		}
+		if strings.Contains(opts, "omitzero") && fieldValue.IsZero() {
+			continue
+		}
+
+		if !*first {
+			e.buf.WriteByte(',')
+		}
+		*first = false
+
+		key, _ := json.Marshal(name)
+		e.buf.Write(key)
+		e.buf.WriteByte(':')
+		if err := e.writeValue(fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExtensibleField reports whether a struct field is one of the protocol's
+// open-ended data or experimental sections, which accept arbitrary values
+func isExtensibleField(field reflect.StructField) bool {
+	return (field.Name == "Data" || field.Name == "Experimental") && field.Type.Kind() == reflect.Interface
+}
+
+// mergeExtra returns an object holding the entries of an extensible field
+// plus the encoder's extra entries. Keys already present win, so values a
+// client relies on (such as completion resolve data) survive.
+func (e *wireEncoder) mergeExtra(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.ValueOf(maps.Clone(e.extra))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return v
+	}
+
+	merged := maps.Clone(e.extra)
+	iter := v.MapRange()
+	for iter.Next() {
+		merged[iter.Key().String()] = iter.Value().Interface()
+	}
+	return reflect.ValueOf(merged)
+}
+
+// isEmptyWireValue mirrors encoding/json's omitempty rules
+func isEmptyWireValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
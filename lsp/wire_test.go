@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+)
+
+func TestEncodeWire_ProtocolEnums(t *testing.T) {
+	kind := protocol.CompletionItemKindFunction
+	severity := protocol.DiagnosticSeverityWarning
+
+	testCases := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{
+			name:  "enum pointer field",
+			value: protocol.CompletionItem{Label: "a", Kind: &kind},
+			want:  `{"kind":3,"label":"a"}`,
+		},
+		{
+			name:  "enum in slice element",
+			value: []protocol.Diagnostic{{Message: "m", Severity: &severity, Range: protocol.Range{}}},
+			want:  `[{"message":"m","range":{"end":{"character":0,"line":0},"start":{"character":0,"line":0}},"severity":2}]`,
+		},
+		{
+			name: "union with pointer value",
+			value: protocol.Or2[string, protocol.MarkupContent]{
+				Value: &protocol.MarkupContent{Kind: protocol.MarkupKindMarkdown, Value: "doc"},
+			},
+			want: `{"kind":"markdown","value":"doc"}`,
+		},
+		{
+			name:  "nil value",
+			value: nil,
+			want:  `null`,
+		},
+		{
+			name:  "map keys sorted",
+			value: map[string]int{"b": 2, "a": 1},
+			want:  `{"a":1,"b":2}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := encodeWire(tc.value)
+			if err != nil {
+				t.Fatalf("encodeWire failed: %v", err)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("encodeWire produced invalid JSON %s: %v", data, err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatalf("Invalid expectation: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("encodeWire() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestEncodeWireWithExtra(t *testing.T) {
+	extra := map[string]any{"trace": "abc"}
+
+	testCases := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{
+			name:  "nil data",
+			value: protocol.CompletionItem{Label: "a"},
+			want:  `{"data":{"trace":"abc"},"label":"a"}`,
+		},
+		{
+			name:  "object data keeps its entries",
+			value: protocol.CompletionItem{Label: "a", Data: map[string]any{"id": 1, "trace": "mine"}},
+			want:  `{"data":{"id":1,"trace":"mine"},"label":"a"}`,
+		},
+		{
+			name:  "scalar data untouched",
+			value: protocol.CompletionItem{Label: "a", Data: 42},
+			want:  `{"data":42,"label":"a"}`,
+		},
+		{
+			name:  "experimental capabilities",
+			value: protocol.ServerCapabilities{},
+			want:  `{"experimental":{"trace":"abc"}}`,
+		},
+		{
+			name:  "non-interface data untouched",
+			value: protocol.SemanticTokens{Data: []uint32{1}},
+			want:  `{"data":[1]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := encodeWireWithExtra(tc.value, extra)
+			if err != nil {
+				t.Fatalf("encodeWireWithExtra failed: %v", err)
+			}
+			if string(data) != tc.want {
+				t.Errorf("encodeWireWithExtra() = %s, want %s", data, tc.want)
+			}
+		})
+	}
+}
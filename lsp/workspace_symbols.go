@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+)
+
+// defaultWorkspaceRoot holds the workspace symbols of clients that sent no
+// workspace folder or root URI
+const defaultWorkspaceRoot = "file:///mock-workspace"
+
+// workspaceRoot returns the URI of the first workspace folder of the
+// initialize params, or else their root URI
+func workspaceRoot(params protocol.InitializeParams) string {
+	if params.WorkspaceFolders != nil && len(*params.WorkspaceFolders) > 0 {
+		return string((*params.WorkspaceFolders)[0].Uri)
+	}
+	if params.RootUri != nil {
+		return string(*params.RootUri)
+	}
+	return ""
+}
+
+// symbolExtension returns the file extension of the workspace symbol files
+func symbolExtension(cfg *config.ServerConfig) string {
+	if len(cfg.LSP.Extensions) > 0 {
+		return cfg.LSP.Extensions[0]
+	}
+	return ".go"
+}
+
+// workspaceSymbolSeq yields the configured number of workspace symbols,
+// generating each as it is iterated, and returns their number. Their kinds
+// follow the configured percentages and are interleaved. The symbols of a
+// kind share a file, ten lines apart, and their names cycle through the
+// custom prefixes.
+func workspaceSymbolSeq(cfg *config.ServerConfig) (iter.Seq[DatasetSymbol], int) {
+	mockData := cfg.LSP.MockData
+	counts := kindCounts[protocol.SymbolKind](config.SymbolKinds, mockData.SymbolKinds, mockData.WorkspaceSymbols)
+	if counts == nil {
+		return func(func(DatasetSymbol) bool) {}, 0
+	}
+
+	ext := symbolExtension(cfg)
+	prefixes := mockData.CustomPrefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{"mock"}
+	}
+
+	return func(yield func(DatasetSymbol) bool) {
+		emitted := make([]int, len(counts))
+		for total := 0; total < mockData.WorkspaceSymbols; {
+			for i, kc := range counts {
+				if emitted[i] == kc.count {
+					continue
+				}
+				emitted[i]++
+				n := emitted[i]
+				symbol := DatasetSymbol{
+					Name: fmt.Sprintf("%s%s%d", prefixes[total%len(prefixes)], pascalCase(kc.name), n),
+					Kind: uint32(kc.kind),
+					File: kc.name + ext,
+					Line: uint32(n-1) * 10,
+				}
+				total++
+				if !yield(symbol) {
+					return
+				}
+			}
+		}
+	}, mockData.WorkspaceSymbols
+}
+
+// generateWorkspaceSymbols generates the workspace symbols of
+// workspaceSymbolSeq at once
+func generateWorkspaceSymbols(cfg *config.ServerConfig) []DatasetSymbol {
+	symbols, count := workspaceSymbolSeq(cfg)
+	return slices.AppendSeq(make([]DatasetSymbol, 0, count), symbols)
+}
+
+// workspaceSymbols returns the workspace symbols of the dataset, if it was
+// generated from the current config, or else freshly generated ones, with
+// their files in the workspace root, and their number. The symbols are
+// converted, and generated, as the sequence is iterated.
+func (s *MockLSPServer) workspaceSymbols() (iter.Seq[protocol.WorkspaceSymbol], int) {
+	cfg := s.config()
+	var generated iter.Seq[DatasetSymbol]
+	var count int
+	if s.dataset != nil && s.dataset.Key == datasetKey(cfg) {
+		generated, count = slices.Values(s.dataset.Symbols), len(s.dataset.Symbols)
+	} else {
+		generated, count = workspaceSymbolSeq(cfg)
+	}
+
+	s.mu.Lock()
+	root := strings.TrimSuffix(s.rootURI, "/")
+	s.mu.Unlock()
+	if root == "" {
+		root = defaultWorkspaceRoot
+	}
+	ext := symbolExtension(cfg)
+
+	return func(yield func(protocol.WorkspaceSymbol) bool) {
+		for symbol := range generated {
+			if !yield(protocol.WorkspaceSymbol{
+				Name:          symbol.Name,
+				Kind:          protocol.SymbolKind(symbol.Kind),
+				ContainerName: strings.TrimSuffix(symbol.File, ext),
+				Location: protocol.Or2[protocol.Location, protocol.LocationUriOnly]{
+					Value: protocol.Location{
+						Uri: protocol.DocumentUri(root + "/" + symbol.File),
+						Range: protocol.Range{
+							Start: protocol.Position{Line: symbol.Line},
+							End:   protocol.Position{Line: symbol.Line, Character: uint32(utf8.RuneCountInString(symbol.Name))},
+						},
+					},
+				},
+			}) {
+				return
+			}
+		}
+	}, count
+}
+
+// matchesSymbolQuery reports whether the characters of query appear in name
+// in order, ignoring case, as clients expect from a fuzzy symbol search. An
+// empty query matches every symbol.
+func matchesSymbolQuery(name, query string) bool {
+	for _, q := range query {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		i := strings.IndexFunc(name, func(r rune) bool { return unicode.ToLower(r) == unicode.ToLower(q) })
+		if i < 0 {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(name[i:])
+		name = name[i+size:]
+	}
+	return true
+}
+
+// handleWorkspaceSymbol processes workspace/symbol requests, answering with
+// the indexed mock workspace symbols matching the query
+func (s *MockLSPServer) handleWorkspaceSymbol(ctx context.Context, conn Conn, req *jsonrpc.Request) {
+	var params protocol.WorkspaceSymbolParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		if replyErr := conn.ReplyWithError(ctx, req.ID, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: "failed to parse workspace symbol params",
+		}); replyErr != nil {
+			s.logError(ctx, "Failed to send workspace symbol error: %v", replyErr)
+		}
+		return
+	}
+
+	// The symbols are generated and filtered as the reply consumes them, so
+	// neither all the symbols nor all the matches are held at once
+	matched := 0
+	symbols, count := s.workspaceSymbols()
+	matches := func(yield func(protocol.WorkspaceSymbol) bool) {
+		for symbol := range s.indexedSymbols(symbols, count) {
+			if !matchesSymbolQuery(symbol.Name, params.Query) {
+				continue
+			}
+			matched++
+			if !yield(symbol) {
+				return
+			}
+		}
+	}
+
+	if err := replyPartialResults(ctx, s, conn, req, params.PartialResultToken, matches); err != nil {
+		s.logError(ctx, "Failed to send workspace symbol response: %v", err)
+	}
+	s.logInfo(ctx, "Workspace symbol query %q matched %d symbols", params.Query, matched)
+}
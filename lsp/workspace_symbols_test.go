@@ -0,0 +1,99 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myleshyson/lsprotocol-go/protocol"
+	"mock-lsp-server/config"
+)
+
+func TestMatchesSymbolQuery(t *testing.T) {
+	tests := []struct {
+		name, query string
+		want        bool
+	}{
+		{"mockClass1", "", true},
+		{"mockClass1", "mockclass", true},
+		{"mockClass1", "mc1", true},
+		{"mockClass1", "MOCK class", true},
+		{"mockClass1", "1mock", false},
+		{"mockClass1", "mockClass12", false},
+		{"ÉtéFunction3", "étéf", true},
+	}
+	for _, tt := range tests {
+		if got := matchesSymbolQuery(tt.name, tt.query); got != tt.want {
+			t.Errorf("matchesSymbolQuery(%q, %q) = %v, want %v", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspaceSymbol(t *testing.T) {
+	server := createTestServer()
+	cfg := config.DefaultConfig()
+	cfg.LSP.MockData.CustomPrefixes = []string{"mock"}
+	cfg.LSP.MockData.WorkspaceSymbols = 10
+	cfg.LSP.MockData.SymbolKinds = map[string]int{"class": 50, "function": 30, "enum_member": 20}
+	server.SetConfig(cfg)
+	client := connectTestClient(t, server, nil)
+	ctx := context.Background()
+
+	initialize := map[string]any{
+		"processId":        nil,
+		"rootUri":          nil,
+		"capabilities":     map[string]any{},
+		"workspaceFolders": []map[string]any{{"uri": "file:///project/", "name": "project"}},
+	}
+	if err := client.Call(ctx, "initialize", initialize, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	type symbol struct {
+		Name          string              `json:"name"`
+		Kind          protocol.SymbolKind `json:"kind"`
+		ContainerName string              `json:"containerName"`
+		Location      struct {
+			URI   string         `json:"uri"`
+			Range protocol.Range `json:"range"`
+		} `json:"location"`
+	}
+	var all []symbol
+	if err := client.Call(ctx, "workspace/symbol", map[string]any{"query": ""}, &all); err != nil {
+		t.Fatalf("Workspace symbol request failed: %v", err)
+	}
+	if len(all) != 10 {
+		t.Fatalf("Expected 10 symbols, got %d", len(all))
+	}
+	kinds := map[protocol.SymbolKind]int{}
+	for _, s := range all {
+		kinds[s.Kind]++
+	}
+	if kinds[protocol.SymbolKindClass] != 5 || kinds[protocol.SymbolKindFunction] != 3 || kinds[protocol.SymbolKindEnumMember] != 2 {
+		t.Errorf("Expected 5 classes, 3 functions and 2 enum members, got %v", kinds)
+	}
+	first := all[0]
+	if first.Name != "mockClass1" || first.Location.URI != "file:///project/class.go" || first.ContainerName != "class" {
+		t.Errorf("Expected the first class in the workspace folder, got %+v", first)
+	}
+	if first.Location.Range.End.Character != uint32(len(first.Name)) {
+		t.Errorf("Expected the range to span the name, got %+v", first.Location.Range)
+	}
+
+	var matched []symbol
+	if err := client.Call(ctx, "workspace/symbol", map[string]any{"query": "enummem"}, &matched); err != nil {
+		t.Fatalf("Workspace symbol request failed: %v", err)
+	}
+	if len(matched) != 2 {
+		t.Fatalf("Expected the 2 enum members to match, got %+v", matched)
+	}
+	for _, s := range matched {
+		if !strings.HasPrefix(s.Name, "mockEnumMember") {
+			t.Errorf("Expected an enum member, got %s", s.Name)
+		}
+	}
+
+	if err := client.Call(ctx, "workspace/symbol", nil, nil); errorCode(err) != int64(ErrorCodeInvalidParams) {
+		t.Errorf("Expected InvalidParams without params, got %v", err)
+	}
+}
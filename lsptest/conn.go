@@ -0,0 +1,168 @@
+package lsptest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+// Kinds of the messages a Conn records
+const (
+	KindReply        = "reply"
+	KindError        = "error"
+	KindNotification = "notification"
+	KindCall         = "call"
+)
+
+// Message is a message sent through a Conn
+type Message struct {
+	Kind   string          // One of the Kind constants
+	ID     jsonrpc.ID      // Of replies and errors
+	Method string          // Of notifications and calls
+	Params json.RawMessage // Of notifications and calls
+	Result json.RawMessage // Of replies
+	Error  *jsonrpc.Error  // Of errors
+}
+
+// Conn records the messages a server sends through it instead of writing
+// them to a client, so handlers can be tested without a connection. It has
+// the method set of the connection interface of the lsp package and is safe
+// for concurrent use. Use NewConn to create one.
+type Conn struct {
+	// Answer returns the result of a request the server sends to the client.
+	// Nil answers every request with null.
+	Answer func(method string, params json.RawMessage) (any, error)
+
+	mu        sync.Mutex
+	messages  []Message
+	sent      chan struct{} // Closed and replaced on every message
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewConn returns a Conn that has recorded nothing
+func NewConn() *Conn {
+	return &Conn{sent: make(chan struct{}), closed: make(chan struct{})}
+}
+
+// record appends m to the recorded messages
+func (c *Conn) record(m Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, m)
+	close(c.sent)
+	c.sent = make(chan struct{})
+}
+
+// Reply records a response to the request id
+func (c *Conn) Reply(_ context.Context, id jsonrpc.ID, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	c.record(Message{Kind: KindReply, ID: id, Result: data})
+	return nil
+}
+
+// ReplyWithError records an error response to the request id
+func (c *Conn) ReplyWithError(_ context.Context, id jsonrpc.ID, respErr *jsonrpc.Error) error {
+	c.record(Message{Kind: KindError, ID: id, Error: respErr})
+	return nil
+}
+
+// Notify records a notification
+func (c *Conn) Notify(_ context.Context, method string, params any, _ ...jsonrpc.CallOption) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.record(Message{Kind: KindNotification, Method: method, Params: data})
+	return nil
+}
+
+// Call records a request and decodes the answer of Answer into result
+func (c *Conn) Call(_ context.Context, method string, params, result any, _ ...jsonrpc.CallOption) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.record(Message{Kind: KindCall, Method: method, Params: data})
+
+	var answer any
+	if c.Answer != nil {
+		if answer, err = c.Answer(method, data); err != nil {
+			return err
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(answer)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, result)
+}
+
+// DisconnectNotify returns a channel closed by Close
+func (c *Conn) DisconnectNotify() <-chan struct{} {
+	return c.closed
+}
+
+// Close marks the connection closed, stopping the goroutines the server
+// ties to it
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// Messages returns the messages recorded so far, in the order they were sent
+func (c *Conn) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Response returns the reply or error sent for the request id
+func (c *Conn) Response(id jsonrpc.ID) (Message, bool) {
+	for _, m := range c.Messages() {
+		if (m.Kind == KindReply || m.Kind == KindError) && m.ID == id {
+			return m, true
+		}
+	}
+	return Message{}, false
+}
+
+// Sent returns the notifications and calls of method recorded so far
+func (c *Conn) Sent(method string) []Message {
+	var sent []Message
+	for _, m := range c.Messages() {
+		if (m.Kind == KindNotification || m.Kind == KindCall) && m.Method == method {
+			sent = append(sent, m)
+		}
+	}
+	return sent
+}
+
+// WaitFor waits until a message matching match is recorded or ctx is done,
+// for messages handlers send from goroutines of their own
+func (c *Conn) WaitFor(ctx context.Context, match func(Message) bool) (Message, error) {
+	for {
+		c.mu.Lock()
+		sent := c.sent
+		messages := c.messages
+		c.mu.Unlock()
+		for _, m := range messages {
+			if match(m) {
+				return m, nil
+			}
+		}
+		select {
+		case <-sent:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
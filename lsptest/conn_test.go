@@ -0,0 +1,74 @@
+package lsptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"mock-lsp-server/jsonrpc"
+)
+
+func TestConnRecords(t *testing.T) {
+	conn := NewConn()
+	conn.Answer = func(method string, params json.RawMessage) (any, error) {
+		if method == "workspace/applyEdit" {
+			return map[string]bool{"applied": true}, nil
+		}
+		return nil, errors.New("unexpected request")
+	}
+	ctx := context.Background()
+
+	conn.Reply(ctx, jsonrpc.ID{Num: 1}, []int{1, 2})
+	conn.ReplyWithError(ctx, jsonrpc.ID{Str: "two", IsString: true}, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams})
+	conn.Notify(ctx, "window/logMessage", map[string]any{"type": 3})
+
+	var applied struct {
+		Applied bool `json:"applied"`
+	}
+	if err := conn.Call(ctx, "workspace/applyEdit", map[string]any{}, &applied); err != nil || !applied.Applied {
+		t.Errorf("Expected the answer decoded into the result, got %+v and %v", applied, err)
+	}
+	if err := conn.Call(ctx, "window/showDocument", nil, nil); err == nil {
+		t.Error("Expected the error of Answer")
+	}
+
+	if m, ok := conn.Response(jsonrpc.ID{Num: 1}); !ok || string(m.Result) != "[1,2]" {
+		t.Errorf("Expected the reply to request 1, got %+v", m)
+	}
+	if m, ok := conn.Response(jsonrpc.ID{Str: "two", IsString: true}); !ok || m.Error.Code != jsonrpc.CodeInvalidParams {
+		t.Errorf("Expected the error response to request two, got %+v", m)
+	}
+	if sent := conn.Sent("window/logMessage"); len(sent) != 1 || sent[0].Kind != KindNotification {
+		t.Errorf("Expected one logMessage notification, got %+v", sent)
+	}
+	if len(conn.Messages()) != 5 {
+		t.Errorf("Expected 5 messages, got %+v", conn.Messages())
+	}
+}
+
+func TestConnWaitFor(t *testing.T) {
+	conn := NewConn()
+	go conn.Notify(context.Background(), "late", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if m, err := conn.WaitFor(ctx, func(m Message) bool { return m.Method == "late" }); err != nil || m.Kind != KindNotification {
+		t.Errorf("Expected the notification sent from another goroutine, got %+v and %v", m, err)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := conn.WaitFor(short, func(m Message) bool { return m.Method == "never" }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to end the wait, got %v", err)
+	}
+
+	conn.Close()
+	conn.Close()
+	select {
+	case <-conn.DisconnectNotify():
+	default:
+		t.Error("Expected Close to close the disconnect channel")
+	}
+}
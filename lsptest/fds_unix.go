@@ -0,0 +1,43 @@
+//go:build !windows
+
+package lsptest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// openFDs returns the open file descriptors of the process and what they
+// refer to. Descriptors the Go runtime keeps for its network poller are
+// left out.
+func openFDs() map[int]string {
+	dir := "/dev/fd"
+	if runtime.GOOS == "linux" {
+		dir = "/proc/self/fd"
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	fds := make(map[int]string)
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil && runtime.GOOS == "linux" {
+			// The descriptor that listed the directory, closed by now
+			continue
+		}
+		if strings.HasPrefix(target, "anon_inode:") {
+			continue
+		}
+		fds[fd] = target
+	}
+	return fds
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package lsptest
+
+// openFDs returns nil, since handles cannot be listed on Windows
+func openFDs() map[int]string {
+	return nil
+}
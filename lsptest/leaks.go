@@ -0,0 +1,130 @@
+// Package lsptest provides helpers for tests that run server sessions, such
+// as checking that a session leaves no goroutines or file descriptors behind.
+package lsptest
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LeakTimeout is how long CheckLeaks waits for goroutines and file
+// descriptors to go away before reporting them as leaked
+var LeakTimeout = 2 * time.Second
+
+// ignoredGoroutines are stack frames of goroutines that are started by the
+// runtime or the testing package and outlive any single test
+var ignoredGoroutines = []string{
+	"created by testing.",
+	"testing.tRunner",
+	"os/signal.signal_recv",
+	"runtime.ensureSigM",
+}
+
+// Resources is a snapshot of the goroutines and open file descriptors of
+// the process
+type Resources struct {
+	// Goroutines maps goroutine IDs to their stacks
+	Goroutines map[int]string
+	// FDs maps open file descriptors to what they refer to, where known. It
+	// is nil on platforms where descriptors cannot be listed.
+	FDs map[int]string
+}
+
+// Snapshot records the goroutines and open file descriptors of the process
+func Snapshot() Resources {
+	return Resources{Goroutines: goroutines(), FDs: openFDs()}
+}
+
+// Leaked describes the goroutines and file descriptors in r that were not in
+// before, sorted by ID
+func (r Resources) Leaked(before Resources) []string {
+	var leaks []string
+	for _, id := range slices.Sorted(maps.Keys(r.Goroutines)) {
+		if _, ok := before.Goroutines[id]; !ok {
+			leaks = append(leaks, fmt.Sprintf("goroutine %d:\n%s", id, r.Goroutines[id]))
+		}
+	}
+	if before.FDs == nil {
+		return leaks
+	}
+	for _, fd := range slices.Sorted(maps.Keys(r.FDs)) {
+		if _, ok := before.FDs[fd]; !ok {
+			leaks = append(leaks, fmt.Sprintf("file descriptor %d: %s", fd, r.FDs[fd]))
+		}
+	}
+	return leaks
+}
+
+// CheckLeaks snapshots the goroutines and file descriptors of the process
+// and fails tb if new ones are still around once the test and its cleanups
+// have finished, after waiting up to LeakTimeout for them to go away. Call
+// it first in a test, before connecting clients, so that it runs after the
+// cleanups closing them. It cannot tell apart the resources of tests running
+// in parallel.
+func CheckLeaks(tb testing.TB) {
+	tb.Helper()
+	before := Snapshot()
+	tb.Cleanup(func() {
+		leaks := waitForLeaks(before, LeakTimeout)
+		if len(leaks) > 0 {
+			tb.Errorf("Leaked %d resources:\n%s", len(leaks), strings.Join(leaks, "\n\n"))
+		}
+	})
+}
+
+// waitForLeaks polls for resources leaked since before until there are
+// none or timeout passes, and returns the last leaks found
+func waitForLeaks(before Resources, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for delay := time.Millisecond; ; delay = min(2*delay, 100*time.Millisecond) {
+		leaks := Snapshot().Leaked(before)
+		if len(leaks) == 0 || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(delay)
+	}
+}
+
+// goroutines returns the stacks of the running goroutines by ID, without
+// those of the runtime and the testing package
+func goroutines() map[int]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[int]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		// Stacks start with "goroutine 7 [running]:"
+		header, _, _ := strings.Cut(stack, "\n")
+		idText, _, ok := strings.Cut(strings.TrimPrefix(header, "goroutine "), " ")
+		id, err := strconv.Atoi(idText)
+		if !ok || err != nil || ignoredGoroutine(stack) {
+			continue
+		}
+		stacks[id] = stack
+	}
+	return stacks
+}
+
+// ignoredGoroutine reports whether stack belongs to a goroutine that is not
+// checked for leaks
+func ignoredGoroutine(stack string) bool {
+	for _, frame := range ignoredGoroutines {
+		if strings.Contains(stack, frame) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package lsptest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingTB records the failures and cleanups of a test instead of
+// applying them to the enclosing test
+type recordingTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recordingTB) Helper()          {}
+func (r *recordingTB) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, format)
+}
+
+// finish runs the recorded cleanups, last registered first
+func (r *recordingTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestLeakedGoroutine(t *testing.T) {
+	before := Snapshot()
+	stop := make(chan struct{})
+	go func() { <-stop }()
+
+	leaks := Snapshot().Leaked(before)
+	if len(leaks) != 1 || !strings.Contains(leaks[0], "TestLeakedGoroutine") {
+		t.Errorf("Expected the goroutine started by the test to leak, got %q", leaks)
+	}
+
+	close(stop)
+	if leaks := waitForLeaks(before, time.Second); len(leaks) > 0 {
+		t.Errorf("Expected no leaks after the goroutine returned, got %q", leaks)
+	}
+}
+
+func TestLeakedFileDescriptor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File descriptors cannot be listed on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "open.txt")
+	before := Snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	leaks := Snapshot().Leaked(before)
+	if len(leaks) != 1 || (runtime.GOOS == "linux" && !strings.HasSuffix(leaks[0], path)) {
+		t.Errorf("Expected %s to leak, got %q", path, leaks)
+	}
+
+	file.Close()
+	if leaks := Snapshot().Leaked(before); len(leaks) > 0 {
+		t.Errorf("Expected no leaks after closing the file, got %q", leaks)
+	}
+}
+
+func TestCheckLeaks(t *testing.T) {
+	defer func(timeout time.Duration) { LeakTimeout = timeout }(LeakTimeout)
+	LeakTimeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		stopped  bool
+		wantFail bool
+	}{
+		{"goroutine stopped by a cleanup", true, false},
+		{"goroutine left running", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			CheckLeaks(tb)
+
+			stop := make(chan struct{})
+			go func() { <-stop }()
+			if tt.stopped {
+				tb.Cleanup(func() { close(stop) })
+			} else {
+				defer close(stop)
+			}
+
+			tb.finish()
+			if failed := len(tb.errors) > 0; failed != tt.wantFail {
+				t.Errorf("Expected the check to fail %v, got %v", tt.wantFail, failed)
+			}
+		})
+	}
+}
@@ -2,16 +2,36 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"github.com/sourcegraph/jsonrpc2"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"mock-lsp-server/activation"
+	"mock-lsp-server/config"
+	"mock-lsp-server/crash"
+	"mock-lsp-server/directories"
+	"mock-lsp-server/environment"
+	"mock-lsp-server/jsonrpc"
 	"mock-lsp-server/logging"
 	"mock-lsp-server/lsp"
+	"mock-lsp-server/pidfile"
+	"mock-lsp-server/recording"
+	"mock-lsp-server/scenario"
+	"mock-lsp-server/transport"
+	"mock-lsp-server/update"
 )
 
 // func parseFlags() (config *Config, output string, err error) {
@@ -19,10 +39,40 @@ func loadConfig(progname string, args []string) (*MockLSPServerConfig, error) {
 	flags := flag.NewFlagSet(progname, flag.ContinueOnError)
 
 	var conf MockLSPServerConfig
-	flags.StringVar(&conf.AppName, "appName", "mock-lsp-server", "set application name")
+	flags.StringVar(&conf.AppName, "appName", defaultAppName, "set application name")
 	flags.StringVar(&conf.LogDir, "log_dir", "", "set log directory")
-	flags.StringVar(&conf.ConfigPath, "config", "", "set config file")
+	flags.StringVar(&conf.ConfigPath, "config", "", "set config file (- reads it from stdin)")
+	flags.StringVar(&conf.ConfigInline, "config-inline", "", "use this base64-encoded config file instead of -config")
 	flags.BoolVar(&conf.ShowInfo, "info", false, "set show info flag")
+	flags.StringVar(&conf.LogOutput, "log", logOutputAuto, "log output: auto, file, stderr or none (auto uses stderr in containers, otherwise file)")
+	flags.BoolVar(&conf.LogFallback, "log-fallback", true, "fall back to the default log directory, then stderr, when the log file cannot be opened")
+	flags.BoolVar(&conf.PIDFile, "pid-file", false, "write a PID file in the runtime directory and refuse to start while another instance holds it")
+	flags.BoolVar(&conf.AllowMultiple, "allow-multiple", false, "start even if -pid-file finds another running instance")
+	flags.StringVar(&conf.Mode, "mode", modeStdio, "transport: stdio, or tcp to accept connections on -addr")
+	flags.StringVar(&conf.Addr, "addr", ":8989", "address to listen on with -mode tcp")
+	flags.StringVar(&conf.ControlAddr, "control-addr", "", "serve pages describing the mock diagnostic rules over HTTP on this address and link diagnostics to them (empty disables)")
+	flags.StringVar(&conf.RPC, "rpc", rpcJSONRPC2, "message stream under the jsonrpc2 connection: jsonrpc2, or internal for batching, -framing and fault injection")
+	flags.StringVar(&conf.Framing, "framing", string(transport.FramingHeader), "message framing of -rpc internal: "+strings.Join(framings(), ", "))
+	flags.StringVar(&conf.AuditPath, "audit", "", "append lifecycle audit events as JSON lines to this file")
+	flags.StringVar(&conf.RecordPath, "record", "", "append the wire messages and stats of every session to this recording store")
+	flags.StringVar(&conf.RecordBackend, "record-backend", recording.BackendFile, "storage backend of -record: "+strings.Join(recording.Backends(), ", "))
+	flags.StringVar(&conf.ScenarioPath, "scenario", "", "run the timeline of this scenario file after initialize and answer with its canned responses (- reads it from stdin)")
+	flags.StringVar(&conf.ScenarioInline, "scenario-inline", "", "use this base64-encoded scenario file instead of -scenario")
+	flags.StringVar(&conf.Pack, "pack", "", "serve with the config and scenario of this workspace pack: a name in -packs-dir or the path of a pack archive")
+	flags.StringVar(&conf.PacksDir, "packs-dir", "", "directory of the workspace packs (default the packs directory under the data directory)")
+	flags.StringVar(&conf.PackWorkspace, "pack-workspace", "", "extract the workspace files of -pack into this directory")
+	flags.StringVar(&conf.Protocol, "protocol", "", "restrict capabilities, methods and response shapes to this protocol version: "+strings.Join(lsp.ProtocolVersions, ", ")+" (default unrestricted)")
+	flags.StringVar(&conf.ClientProfile, "client-profile", "", "adjust the defaults to the quirks of this editor: "+strings.Join(config.ClientProfiles, ", ")+"; the config file still overrides them")
+	flags.StringVar(&conf.FailurePreset, "failure-preset", "", "inject the failures of this bundle: "+strings.Join(config.FailurePresets, ", ")+"; overrides lsp.failure_preset")
+	flags.BoolVar(&conf.Minimal, "minimal", false, "support only initialize, shutdown, exit and text sync; answer everything else with MethodNotFound")
+	flags.BoolVar(&conf.CheckUpdate, "check-update", false, "check GitHub for a newer release and report it on stderr and in the log")
+	flags.IntVar(&conf.FuzzSync, "fuzz-sync", 0, "replay this many random edit sequences through didChange and exit")
+	flags.Int64Var(&conf.FuzzSeed, "fuzz-seed", 0, "seed for -fuzz-sync (0 picks a time-based seed)")
+	flags.StringVar(&conf.CaptureDir, "capture-fixtures", "", "write the documents, positions and messages of every session as a replayable fixture to this directory")
+	flags.StringVar(&conf.JUnit, "junit", "", "write a JUnit XML report of the error responses, latency budgets, scenario expectations and SLOs of each session to this file when it ends")
+	flags.BoolVar(&conf.CacheMockData, "cache-mock-data", false, "cache the generated mock data in the cache directory, keyed by seed and mock data config, and reuse it on later runs")
+	flags.DurationVar(&conf.SoakInterval, "soak", 0, "log memory, goroutines, open documents, message rates and likely leaks at this interval (0 disables)")
+	flags.BoolVar(&conf.HelpExitCodes, "help-exit-codes", false, "print the exit code of each outcome, as configured by -config, and exit")
 
 	err := flags.Parse(args)
 
@@ -30,63 +80,891 @@ func loadConfig(progname string, args []string) (*MockLSPServerConfig, error) {
 		return nil, err
 	}
 
+	if conf.LogOutput != logOutputAuto && !slices.Contains(logging.Outputs, conf.LogOutput) {
+		return nil, fmt.Errorf("invalid -log value %q: must be %s or one of %s", conf.LogOutput, logOutputAuto, strings.Join(logging.Outputs, ", "))
+	}
+
+	if !slices.Contains(transportModes, conf.Mode) {
+		return nil, fmt.Errorf("invalid -mode value %q: must be one of %s", conf.Mode, strings.Join(transportModes, ", "))
+	}
+
+	if !slices.Contains(rpcImplementations, conf.RPC) {
+		return nil, fmt.Errorf("invalid -rpc value %q: must be one of %s", conf.RPC, strings.Join(rpcImplementations, ", "))
+	}
+
+	if !slices.Contains(framings(), conf.Framing) {
+		return nil, fmt.Errorf("invalid -framing value %q: must be one of %s", conf.Framing, strings.Join(framings(), ", "))
+	}
+
+	if conf.Framing != string(transport.FramingHeader) && conf.RPC != rpcInternal {
+		return nil, fmt.Errorf("-framing %s requires -rpc %s", conf.Framing, rpcInternal)
+	}
+
+	if conf.Protocol != "" && !slices.Contains(lsp.ProtocolVersions, conf.Protocol) {
+		return nil, fmt.Errorf("invalid -protocol value %q: must be one of %s", conf.Protocol, strings.Join(lsp.ProtocolVersions, ", "))
+	}
+
+	if conf.ClientProfile != "" && !slices.Contains(config.ClientProfiles, conf.ClientProfile) {
+		return nil, fmt.Errorf("invalid -client-profile value %q: must be one of %s", conf.ClientProfile, strings.Join(config.ClientProfiles, ", "))
+	}
+
+	if conf.FailurePreset != "" && !slices.Contains(config.FailurePresets, conf.FailurePreset) {
+		return nil, fmt.Errorf("invalid -failure-preset value %q: must be one of %s", conf.FailurePreset, strings.Join(config.FailurePresets, ", "))
+	}
+
+	if conf.SoakInterval < 0 {
+		return nil, fmt.Errorf("invalid -soak value %s: must not be negative", conf.SoakInterval)
+	}
+
+	if conf.ConfigInline != "" {
+		if conf.ConfigPath != "" {
+			return nil, errors.New("-config and -config-inline are mutually exclusive")
+		}
+		if conf.ConfigData, err = decodeInline(conf.ConfigInline); err != nil {
+			return nil, fmt.Errorf("invalid -config-inline value: %w", err)
+		}
+	}
+
+	if conf.ScenarioInline != "" {
+		if conf.ScenarioPath != "" {
+			return nil, errors.New("-scenario and -scenario-inline are mutually exclusive")
+		}
+		if conf.ScenarioData, err = decodeInline(conf.ScenarioInline); err != nil {
+			return nil, fmt.Errorf("invalid -scenario-inline value: %w", err)
+		}
+	}
+
+	if conf.Pack != "" {
+		for _, source := range []struct{ flag, value string }{
+			{"config", conf.ConfigPath}, {"config-inline", conf.ConfigInline},
+			{"scenario", conf.ScenarioPath}, {"scenario-inline", conf.ScenarioInline},
+		} {
+			if source.value != "" {
+				return nil, fmt.Errorf("-pack and -%s are mutually exclusive", source.flag)
+			}
+		}
+	} else if conf.PackWorkspace != "" {
+		return nil, errors.New("-pack-workspace requires -pack")
+	}
+
+	if conf.ConfigPath == stdinPath && conf.ScenarioPath == stdinPath {
+		return nil, errors.New("-config - and -scenario - cannot both read stdin")
+	}
+
+	// Serving over stdio needs stdin for the messages
+	if (conf.ConfigPath == stdinPath || conf.ScenarioPath == stdinPath) && conf.Mode == modeStdio && !conf.HelpExitCodes && conf.FuzzSync == 0 {
+		return nil, fmt.Errorf("reading a file from stdin requires -mode %s, since -mode %s reads messages from stdin; use -config-inline or -scenario-inline instead", modeTCP, modeStdio)
+	}
+
 	return &conf, nil
 }
 
 type MockLSPServerConfig struct {
-	AppName    string
-	LogDir     string
-	ConfigPath string
-	ShowInfo   bool
+	AppName        string
+	LogDir         string
+	ConfigPath     string
+	ConfigInline   string
+	ConfigData     []byte // Config file read from stdin or -config-inline
+	ShowInfo       bool
+	LogOutput      string
+	LogFallback    bool
+	PIDFile        bool
+	AllowMultiple  bool
+	CheckUpdate    bool
+	Minimal        bool
+	Protocol       string
+	ClientProfile  string
+	FailurePreset  string
+	Mode           string
+	Addr           string
+	ControlAddr    string
+	CacheMockData  bool
+	RPC            string
+	Framing        string
+	AuditPath      string
+	RecordPath     string
+	RecordBackend  string
+	ScenarioPath   string
+	ScenarioInline string
+	ScenarioData   []byte // Scenario file read from stdin or -scenario-inline
+	Pack           string
+	PacksDir       string
+	PackWorkspace  string
+	FuzzSync       int
+	FuzzSeed       int64
+	SoakInterval   time.Duration
+	CaptureDir     string
+	JUnit          string
+	HelpExitCodes  bool
+}
+
+// defaultAppName is the default of -appName
+const defaultAppName = "mock-lsp-server"
+
+// version is the version of the build, set with -ldflags "-X main.version=..."
+var version = "dev"
+
+// updateCheckTimeout bounds the -check-update request
+const updateCheckTimeout = 10 * time.Second
+
+// logOutputAuto lets the detected environment choose the log output
+const logOutputAuto = "auto"
+
+// Transports selected with -mode
+const (
+	modeStdio = "stdio"
+	modeTCP   = "tcp"
+)
+
+// transportModes lists the values accepted by -mode
+var transportModes = []string{modeStdio, modeTCP}
+
+// Message streams selected with -rpc. Both carry a sourcegraph/jsonrpc2
+// connection, which still dispatches the messages.
+const (
+	rpcJSONRPC2 = "jsonrpc2"
+	rpcInternal = "internal"
+)
+
+// rpcImplementations lists the values accepted by -rpc
+var rpcImplementations = []string{rpcJSONRPC2, rpcInternal}
+
+// framings lists the values accepted by -framing
+func framings() []string {
+	names := make([]string, len(transport.Framings))
+	for i, framing := range transport.Framings {
+		names[i] = string(framing)
+	}
+	return names
+}
+
+// syncFuzzEdits is the number of edits in each -fuzz-sync sequence
+const syncFuzzEdits = 50
+
+// stdinPath is the -config and -scenario value that reads the file from stdin
+const stdinPath = "-"
+
+// decodeInline decodes the base64 value of -config-inline or
+// -scenario-inline, with or without padding
+func decodeInline(value string) ([]byte, error) {
+	encoding := base64.StdEncoding
+	if !strings.HasSuffix(value, "=") && len(value)%4 != 0 {
+		encoding = base64.RawStdEncoding
+	}
+	return encoding.DecodeString(value)
+}
+
+// readStdin reads the config or scenario file given as -config - or
+// -scenario - into ConfigData or ScenarioData
+func (c *MockLSPServerConfig) readStdin(stdin io.Reader) error {
+	if c.ConfigPath != stdinPath && c.ScenarioPath != stdinPath {
+		return nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if c.ConfigPath == stdinPath {
+		c.ConfigData = data
+	} else {
+		c.ScenarioData = data
+	}
+	return nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		os.Exit(runStats(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch-storm" {
+		os.Exit(runWatchStorm(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "stress" {
+		os.Exit(runStress(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "recordings" {
+		os.Exit(runRecordings(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "packs" {
+		os.Exit(runPacks(os.Args[0], os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	config, err := loadConfig(os.Args[0], os.Args[1:])
 
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Printf("Failed to load config: %v", err)
+		os.Exit(defaultFatalExitCode())
+	}
+
+	if err := config.readStdin(os.Stdin); err != nil {
+		log.Printf("Failed to load config: %v", err)
+		os.Exit(defaultFatalExitCode())
+	}
+
+	if err := config.loadPack(); err != nil {
+		log.Printf("Failed to load pack: %v", err)
+		os.Exit(defaultFatalExitCode())
+	}
+
+	if config.HelpExitCodes {
+		os.Exit(runHelpExitCodes(config.ConfigPath, config.ConfigData, os.Stdout))
+	}
+
+	if config.FuzzSync > 0 {
+		os.Exit(runSyncFuzz(config.FuzzSync, config.FuzzSeed, os.Stdout))
 	}
 
+	// The exit code of the session is applied once every deferred cleanup,
+	// such as flushing the logs, has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Adjust directory and logging defaults to the environment, e.g. containers
+	env, homeBase := adjustToEnvironment(config)
+
+	crashes := &crashReporter{dir: crashDirectory(config.AppName), flags: config}
+	defer crashes.recoverPanic()
+
 	// Configure logging
-	logger, logManager, err := setupLogging(config.AppName, config.LogDir, config.ConfigPath, config.ShowInfo)
+	logger, logManager, err := setupLogging(config.AppName, config.LogDir, config.ConfigPath, config.ConfigData, config.ShowInfo, config.LogOutput, config.LogFallback)
 
 	if err != nil {
-		log.Fatalf("Failed to setup logging: %v", err)
+		crashes.fatalf("Failed to setup logging: %v", err)
+	}
+	crashes.logManager = logManager
+
+	for _, finding := range env.Findings() {
+		logger.Printf("Environment: %s", finding)
+	}
+	if homeBase != "" {
+		logger.Printf("Environment: using %s for data, config and cache directories", homeBase)
+	}
+
+	// Single-instance locking
+	var pidFilePath string
+	if config.PIDFile {
+		pidFile, err := acquirePIDFile(runtimeDirectory(config.AppName), config.AppName, config.AllowMultiple)
+		if err != nil {
+			crashes.fatalf("Failed to acquire PID file: %v", err)
+		}
+		if pidFile == nil {
+			logger.Printf("Another %s instance is running; starting anyway because of -allow-multiple", config.AppName)
+		} else {
+			defer pidFile.Release()
+			crashes.deferCleanup(func() { pidFile.Release() })
+			pidFilePath = pidFile.Path()
+		}
 	}
 
 	defer logManager.Close()
+	crashes.deferCleanup(func() { logManager.Close() })
 
 	logger.Println("Starting Mock LSP Server...")
 
+	if config.CheckUpdate {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+			defer cancel()
+			checkForUpdate(ctx, update.ReleasesURL, os.Stderr, logger)
+		}()
+	}
+
 	// Create structured logger for better logging
 	structuredLogger := logManager.NewStructuredLogger().WithContext("component", "lsp-server")
-	server := lsp.NewMockLSPServerWithStructuredLogger(structuredLogger, logger)
 
-	// Create JSON-RPC connection using stdio
-	handler := func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
-		server.Handle(ctx, conn, req)
-		return nil, nil
+	serverConfig, err := loadServerConfig(config.ConfigPath, config.ConfigData, config.ClientProfile, logManager)
+	if err != nil {
+		crashes.fatalf("Failed to load server config: %v", err)
+	}
+	if config.Minimal {
+		serverConfig.LSP.Minimal = true
+	}
+	if config.ClientProfile != "" {
+		logger.Printf("Adjusted the defaults to the %s client profile", config.ClientProfile)
+	}
+	if config.FailurePreset != "" {
+		serverConfig.LSP.FailurePreset = config.FailurePreset
 	}
+	if serverConfig.LSP.FailurePreset != "" {
+		logger.Printf("Injecting the failures of the %s preset", serverConfig.LSP.FailurePreset)
+	}
+	crashes.serverConfig = serverConfig
 
-	readWriteCloser := newStdioReadWriteCloser()
-	ctx := context.Background()
+	// Audit stream of lifecycle events, separate from the debug log
+	var audit *lsp.AuditLog
+	if config.AuditPath != "" {
+		auditFile, err := os.OpenFile(config.AuditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			crashes.fatalf("Failed to open audit log: %v", err)
+		}
+		defer auditFile.Close()
+		audit = lsp.NewAuditLog(auditFile)
+	}
 
-	conn := jsonrpc2.NewConn(
-		ctx,
-		jsonrpc2.NewBufferedStream(readWriteCloser, jsonrpc2.VSCodeObjectCodec{}),
-		jsonrpc2.HandlerWithError(handler),
-		jsonrpc2.SetLogger(logger),
-	)
+	// Every session is recorded in the same store
+	var store recording.Store
+	if config.RecordPath != "" {
+		store, err = recording.Open(config.RecordBackend, config.RecordPath)
+		if err != nil {
+			crashes.fatalf("Failed to open recording store: %v", err)
+		}
+		defer store.Close()
+	}
+
+	// Scripted events run in every session
+	var sc *scenario.Scenario
+	if config.ScenarioPath != "" || config.ScenarioData != nil {
+		name := config.ScenarioPath
+		if config.ScenarioData != nil {
+			sc, err = scenario.Parse(config.ScenarioData)
+			if name == "" {
+				name = "from -scenario-inline"
+			}
+		} else {
+			sc, err = scenario.Load(config.ScenarioPath)
+		}
+		if err != nil {
+			crashes.fatalf("Failed to load scenario: %v", err)
+		}
+		logger.Printf("Loaded scenario %s with %d timeline events, %d responses and %d expectations", name, len(sc.Timeline), len(sc.Responses), len(sc.Expectations))
+	}
+
+	// Mock data shared by every session, reused across runs when cached
+	var dataset *lsp.MockDataset
+	if config.CacheMockData {
+		dir := datasetDirectory(config.AppName)
+		var reused bool
+		dataset, reused, err = lsp.LoadMockDataset(dir, serverConfig)
+		switch {
+		case err != nil && dataset == nil:
+			crashes.fatalf("Failed to load mock data: %v", err)
+		case err != nil:
+			logger.Printf("Failed to cache mock data, it will be regenerated next run: %v", err)
+		case reused:
+			logger.Printf("Reused cached mock data %s from %s", dataset.Key, dir)
+		default:
+			logger.Printf("Generated mock data %s and cached it in %s", dataset.Key, dir)
+		}
+	}
+
+	// Control HTTP listener serving the pages diagnostics link to
+	var rulesURL string
+	if config.ControlAddr != "" {
+		listener, err := net.Listen("tcp", config.ControlAddr)
+		if err != nil {
+			crashes.fatalf("Failed to listen on -control-addr: %v", err)
+		}
+		control := &http.Server{Handler: lsp.RulesHandler(), ReadHeaderTimeout: 10 * time.Second}
+		defer control.Close()
+		go control.Serve(listener)
+		rulesURL = controlURL(listener.Addr())
+		logger.Printf("Serving diagnostic rule pages on %s/rules/", rulesURL)
+	}
+
+	newServer := func() *lsp.MockLSPServer {
+		server := lsp.NewMockLSPServerWithStructuredLogger(structuredLogger, logger)
+		server.SetConfig(serverConfig)
+		server.SetAuditLog(audit)
+		if store != nil {
+			server.SetRecordingStore(store)
+		}
+		server.SetScenario(sc)
+		server.SetSoakInterval(config.SoakInterval)
+		server.SetCaptureDir(config.CaptureDir)
+		server.SetJUnitPath(config.JUnit)
+		server.SetProtocolVersion(config.Protocol) // Validated by loadConfig
+		server.SetRulesURL(rulesURL)
+		if dataset != nil {
+			server.SetDataset(dataset)
+		}
+		return server
+	}
+	newStream := newStreamFactory(config.RPC, config.Framing)
+
+	// Sockets inherited through systemd socket activation or the -mode tcp
+	// listener replace stdio
+	transportErrs := newTransportErrors(structuredLogger)
+	listeners, transport, err := transportListeners(config.Mode, config.Addr, transportErrs)
+	if err != nil {
+		crashes.fatalf("Failed to listen: %v", err)
+	}
+
+	ready := newReadyLine(config.AppName, serverConfig, logManager.GetLogPath())
+	ready.PIDFile = pidFilePath
+
+	if len(listeners) > 0 {
+		ready.Transport = transport
+		for _, listener := range listeners {
+			ready.Addresses = append(ready.Addresses, activation.Address(listener))
+		}
+		structuredLogger.Info("Mock LSP Server started, accepting connections on %s", strings.Join(ready.Addresses, ", "))
+		if err := writeReadyLine(os.Stderr, ready); err != nil {
+			structuredLogger.Error("Failed to write readiness line: %v", err)
+		}
+
+		if env.PID1 {
+			closers := make([]io.Closer, len(listeners))
+			for i, listener := range listeners {
+				closers[i] = listener
+			}
+			stopTermination := closeOnTermination(logger, closers...)
+			defer stopTermination()
+		}
+
+		serveListeners(listeners, newServer, newStream, logger, crashes, transportErrs)
+		transportErrs.logSummary()
+		log.Println("Mock LSP Server stopped")
+		return
+	}
+
+	// Create JSON-RPC connection using stdio
+	server := newServer()
+	conn := newServerConn(context.Background(), newStream(newStdioReadWriteCloser()), server, logger, crashes)
 
 	defer conn.Close()
 
+	// Dump the recent wire traffic to stderr on SIGQUIT
+	stopTrafficDump := dumpTrafficOnSignal(server, os.Stderr)
+	defer stopTrafficDump()
+
+	if env.PID1 {
+		stopTermination := closeOnTermination(logger, conn)
+		defer stopTermination()
+	}
+
 	structuredLogger.Info("Mock LSP Server started, waiting for requests...")
 
-	// Wait for the connection to close
-	<-conn.DisconnectNotify()
+	if err := writeReadyLine(os.Stderr, ready); err != nil {
+		structuredLogger.Error("Failed to write readiness line: %v", err)
+	}
+
+	server.Audit(lsp.AuditEvent{Event: lsp.AuditConnected, Remote: "stdio"})
+
+	// Wait for the client to exit or close the connection
+	select {
+	case <-server.Done():
+		// The exit notification saved the session and chose the exit code
+		exitCode, _ = server.ExitStatus()
+	case <-conn.DisconnectNotify():
+		server.Audit(lsp.AuditEvent{Event: lsp.AuditDisconnected, Reason: "connection closed"})
+		saveSession(server, logger)
+		exitCode = expectationExitCode(server, serverConfig)
+	}
 	log.Println("Mock LSP Server stopped")
 }
 
+// transportListeners returns the listeners to serve instead of stdio and
+// the transport they use for the readiness line: the sockets of systemd
+// socket activation, or with -mode tcp a listener on addr. Socket activation
+// cannot be combined with -mode tcp.
+func transportListeners(mode, addr string, transportErrs *transportErrors) ([]net.Listener, string, error) {
+	listeners, err := activation.Listeners()
+	if err != nil {
+		return nil, "", fmt.Errorf("socket activation: %w", err)
+	}
+	if len(listeners) > 0 {
+		if mode == modeTCP {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+			return nil, "", fmt.Errorf("-mode tcp cannot be combined with socket activation")
+		}
+		return listeners, "socket", nil
+	}
+
+	if mode != modeTCP {
+		return nil, modeStdio, nil
+	}
+	listener, err := transportErrs.listen(addr)
+	if err != nil {
+		return nil, "", err
+	}
+	return []net.Listener{listener}, modeTCP, nil
+}
+
+// controlURL returns the base URL of the control listener at addr. An
+// unspecified host is replaced by localhost, which editors can open.
+func controlURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// streamFactory wraps a connection in the message stream selected with -rpc
+type streamFactory func(rwc io.ReadWriteCloser) jsonrpc.ObjectStream
+
+// newStreamFactory returns the streamFactory of an -rpc stream and
+// -framing
+func newStreamFactory(rpc, framing string) streamFactory {
+	if rpc == rpcInternal {
+		return func(rwc io.ReadWriteCloser) jsonrpc.ObjectStream {
+			return transport.NewStream(rwc, transport.Options{Framing: transport.Framing(framing)})
+		}
+	}
+	return func(rwc io.ReadWriteCloser) jsonrpc.ObjectStream {
+		return jsonrpc.NewStream(rwc)
+	}
+}
+
+// newServerConn creates the JSON-RPC connection serving server over stream.
+// Messages are handled in order off the read loop, so $/cancelRequest can
+// reach the request it cancels. The server replies to requests itself.
+func newServerConn(ctx context.Context, stream jsonrpc.ObjectStream, server *lsp.MockLSPServer, logger *log.Logger, crashes *crashReporter) jsonrpc.Conn {
+	return jsonrpc.NewConn(
+		ctx,
+		stream,
+		lsp.NewOrderedHandler(crashes.handler(server), server.ShutdownDrainTimeout),
+		append(server.ConnOpts(), jsonrpc.SetLogger(logger))...,
+	)
+}
+
+// serveListeners accepts connections on every listener until all of them
+// are closed, serving each connection with a fresh server instance
+func serveListeners(listeners []net.Listener, newServer func() *lsp.MockLSPServer, newStream streamFactory, logger *log.Logger, crashes *crashReporter, transportErrs *transportErrors) {
+	var wg sync.WaitGroup
+	for _, listener := range listeners {
+		wg.Add(1)
+		go func(listener net.Listener) {
+			defer wg.Done()
+			if err := serveListener(listener, newServer, newStream, logger, crashes, transportErrs); err != nil {
+				logger.Printf("Stopped accepting connections on %s: %v", activation.Address(listener), err)
+			}
+		}(listener)
+	}
+	wg.Wait()
+}
+
+// serveListener accepts connections on listener until it is closed.
+// Transient accept errors are retried with backoff; other errors stop it.
+func serveListener(listener net.Listener, newServer func() *lsp.MockLSPServer, newStream streamFactory, logger *log.Logger, crashes *crashReporter, transportErrs *transportErrors) error {
+	failures := 0
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			failures++
+			if transportErrs.retryAccept(listener, err, failures) {
+				continue
+			}
+			return err
+		}
+		failures = 0
+
+		server := newServer()
+		logger.Printf("Accepted connection from %s as %s", netConn.RemoteAddr(), server.ClientID())
+		conn := newServerConn(context.Background(), newStream(netConn), server, logger, crashes)
+		server.Audit(lsp.AuditEvent{Event: lsp.AuditConnected, Remote: netConn.RemoteAddr().String()})
+		go func() {
+			select {
+			case <-server.Done():
+				// The exit notification ends this session only, the
+				// listener keeps serving the others
+				conn.Close()
+				logger.Printf("Client %s exited", server.ClientID())
+			case <-conn.DisconnectNotify():
+				logger.Printf("Client %s disconnected", server.ClientID())
+				server.Audit(lsp.AuditEvent{Event: lsp.AuditDisconnected, Reason: "connection closed"})
+				saveSession(server, logger)
+			}
+		}()
+	}
+}
+
+// saveSession writes the fixture captured from a session that ended, if
+// -capture-fixtures is set, its JUnit report, if -junit is set, and records
+// its stats, if -record is set
+func saveSession(server *lsp.MockLSPServer, logger *log.Logger) {
+	path, err := server.WriteCapture()
+	if err != nil {
+		logger.Printf("Failed to write captured fixture: %v", err)
+	} else if path != "" {
+		logger.Printf("Captured fixture written to %s", path)
+	}
+	if path, err := server.WriteJUnitReport(); err != nil {
+		logger.Printf("Failed to write JUnit report: %v", err)
+	} else if path != "" {
+		logger.Printf("JUnit report written to %s", path)
+	}
+	if err := server.RecordStats(); err != nil {
+		logger.Printf("Failed to record stats: %v", err)
+	}
+}
+
+// expectationExitCode returns the expectation_failure exit code of
+// serverConfig if responses of the session that ended differed from the
+// scenario expectations, otherwise 0
+func expectationExitCode(server *lsp.MockLSPServer, serverConfig *config.ServerConfig) int {
+	failures := server.ExpectationFailures()
+	if len(failures) == 0 {
+		return 0
+	}
+	log.Printf("%d responses differed from the scenario expectations", len(failures))
+	return serverConfig.Server.ExitCode(config.ExitExpectationFailure)
+}
+
+// readyLine is the machine-readable line written to stderr once the server
+// accepts messages, so harnesses that spawn it can detect readiness and
+// discover where it listens and logs
+type readyLine struct {
+	Event     string   `json:"event"`
+	PID       int      `json:"pid"`
+	AppName   string   `json:"app_name"`
+	Version   string   `json:"version"`
+	Transport string   `json:"transport"`
+	Addresses []string `json:"addresses"`
+	LogPath   string   `json:"log_path,omitempty"`
+	PIDFile   string   `json:"pid_file,omitempty"`
+}
+
+// newReadyLine describes the running server; logPath is empty when no log
+// file is written
+func newReadyLine(appName string, serverConfig *config.ServerConfig, logPath string) readyLine {
+	return readyLine{
+		Event:     "ready",
+		PID:       os.Getpid(),
+		AppName:   appName,
+		Version:   serverConfig.Server.Version,
+		Transport: "stdio",
+		Addresses: []string{},
+		LogPath:   logPath,
+	}
+}
+
+// writeReadyLine writes the readiness line as a single line of JSON
+func writeReadyLine(out io.Writer, ready readyLine) error {
+	return json.NewEncoder(out).Encode(ready)
+}
+
+// runSyncFuzz replays random edit sequences through the server's didChange
+// handler, writes the report as JSON and returns the process exit code
+func runSyncFuzz(sequences int, seed int64, out io.Writer) int {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	report, err := lsp.RunSyncFuzz(seed, sequences, syncFuzzEdits)
+	if err != nil {
+		log.Printf("Sync fuzz failed: %v", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("Failed to write sync fuzz report: %v", err)
+		return 1
+	}
+
+	if len(report.Mismatches) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// exitCodeOutcomes describes the outcomes of config.ExitOutcomes for
+// -help-exit-codes
+var exitCodeOutcomes = map[string]string{
+	config.ExitAfterShutdown:      "exit notification after a shutdown request",
+	config.ExitWithoutShutdown:    "exit notification without a shutdown request",
+	config.ExitFatal:              "fatal error, e.g. an invalid config or scenario",
+	config.ExitExpectationFailure: "scenario expectations were not met",
+}
+
+// defaultFatalExitCode returns the fatal exit code of the default config,
+// used until the server config is loaded
+func defaultFatalExitCode() int {
+	return config.DefaultConfig().Server.ExitCode(config.ExitFatal)
+}
+
+// runHelpExitCodes writes the exit code of each outcome, as configured in
+// the config file at configPath, to out. It returns the process exit code.
+func runHelpExitCodes(configPath string, configData []byte, out io.Writer) int {
+	serverConfig, err := loadConfigSource(configPath, configData, "")
+	if err == nil {
+		err = serverConfig.Validate()
+	}
+	if err != nil {
+		log.Printf("Failed to load server config: %v", err)
+		return defaultFatalExitCode()
+	}
+
+	fmt.Fprintln(out, "Exit codes:")
+	for _, outcome := range config.ExitOutcomes {
+		fmt.Fprintf(out, "  %3d  %-20s %s\n", serverConfig.Server.ExitCode(outcome), outcome, exitCodeOutcomes[outcome])
+	}
+	return 0
+}
+
+// checkForUpdate compares the running build with the latest release at url
+// and reports the outcome on out and in the log. Failures are only logged.
+func checkForUpdate(ctx context.Context, url string, out io.Writer, logger *log.Logger) {
+	result, err := update.Check(ctx, http.DefaultClient, url, version)
+	if err != nil {
+		logger.Printf("Update check failed: %v", err)
+		return
+	}
+	logger.Println(result)
+	if result.Available {
+		fmt.Fprintln(out, result)
+	}
+}
+
+// adjustToEnvironment detects the runtime environment, redirects the XDG
+// base directories when the user has no usable home and resolves an automatic
+// log output. It returns the environment and the redirected base directory,
+// which is empty when the directories were left alone.
+func adjustToEnvironment(config *MockLSPServerConfig) (*environment.Environment, string) {
+	u, err := user.Current()
+	if err != nil {
+		u = nil
+	}
+	env := environment.Detect(u)
+	homeBase := env.ConfigureDirectories(config.AppName, u)
+	if config.LogOutput == logOutputAuto {
+		config.LogOutput = env.DefaultLogOutput()
+	}
+	return env, homeBase
+}
+
+// crashReporter writes crash reports holding whatever state startup has
+// reached: the command-line flags, then the recent log entries and finally the
+// server configuration
+type crashReporter struct {
+	dir          string
+	flags        *MockLSPServerConfig
+	logManager   *logging.Manager
+	serverConfig *config.ServerConfig
+	cleanups     []func() // Deferred calls of main that fatalf would skip
+}
+
+// crashDirectory returns the cache directory crash reports are written to,
+// or the system temporary directory when it cannot be determined
+func crashDirectory(appName string) string {
+	u, err := user.Current()
+	if err != nil {
+		return os.TempDir()
+	}
+	dir, err := directories.NewDirectoryResolver(appName, u, false).GetCacheDirectory()
+	if err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// datasetDirectory returns the directory cached mock data is kept in, under
+// the cache directory or else the system temporary directory
+func datasetDirectory(appName string) string {
+	return filepath.Join(crashDirectory(appName), "datasets")
+}
+
+// runtimeDirectory returns the directory PID files are written to, or the
+// system temporary directory when it cannot be determined
+func runtimeDirectory(appName string) string {
+	u, err := user.Current()
+	if err != nil {
+		return os.TempDir()
+	}
+	dir, err := directories.NewDirectoryResolver(appName, u, true).GetRuntimeDirectory()
+	if err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// acquirePIDFile writes the PID file for appName in dir. With allowMultiple
+// a running instance is tolerated: no PID file is written and both are nil.
+func acquirePIDFile(dir, appName string, allowMultiple bool) (*pidfile.PIDFile, error) {
+	pidFile, err := pidfile.Acquire(filepath.Join(dir, appName+".pid"))
+	if errors.Is(err, pidfile.ErrAlreadyRunning) && allowMultiple {
+		return nil, nil
+	}
+	return pidFile, err
+}
+
+// write stores a crash report for reason and returns its path, or an empty
+// string when it could not be written
+func (c *crashReporter) write(reason string) string {
+	report := crash.NewReport(c.flags.AppName, reason)
+	report.AddConfig("flags", c.flags)
+	if c.serverConfig != nil {
+		report.AddConfig("server", c.serverConfig)
+	}
+	if c.logManager != nil {
+		report.RecentLog = c.logManager.RecentEntries()
+	}
+
+	path, err := report.Write(c.dir)
+	if err != nil {
+		log.Printf("Failed to write crash report: %v", err)
+		return ""
+	}
+	log.Printf("Crash report written to %s", path)
+	return path
+}
+
+// fatalf writes a crash report, logs the message and exits with the fatal
+// exit code of the server config, or the default one before it is loaded
+func (c *crashReporter) fatalf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	c.write(message)
+	log.Print(message)
+	c.cleanup()
+	os.Exit(c.exitCode())
+}
+
+// deferCleanup makes fatalf call fn before exiting, for the deferred calls
+// of main that os.Exit would skip, such as removing the PID file
+func (c *crashReporter) deferCleanup(fn func()) {
+	c.cleanups = append(c.cleanups, fn)
+}
+
+// cleanup calls the functions passed to deferCleanup, last one first
+func (c *crashReporter) cleanup() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups = nil
+}
+
+// exitCode returns the exit code fatalf exits with
+func (c *crashReporter) exitCode() int {
+	if c.serverConfig != nil {
+		return c.serverConfig.Server.ExitCode(config.ExitFatal)
+	}
+	return defaultFatalExitCode()
+}
+
+// recoverPanic writes a crash report for a panic in progress and then
+// resumes panicking. It must be called directly by a deferred statement.
+func (c *crashReporter) recoverPanic() {
+	if r := recover(); r != nil {
+		c.write(fmt.Sprintf("panic: %v", r))
+		panic(r)
+	}
+}
+
+// handler wraps handler so its panics are reported
+func (c *crashReporter) handler(handler jsonrpc.Handler) jsonrpc.Handler {
+	return jsonrpc.HandlerFunc(func(ctx context.Context, conn jsonrpc.Conn, req *jsonrpc.Request) {
+		defer c.recoverPanic()
+		handler.Handle(ctx, conn, req)
+	})
+}
+
 // stdioReadWriteCloser combines stdin and stdout into a single ReadWriteCloser
 type stdioReadWriteCloser struct {
 	io.Reader
@@ -106,17 +984,28 @@ func newStdioReadWriteCloser() io.ReadWriteCloser {
 	}
 }
 
-func setupLogging(appName string, logDir, configPath string, showInfo bool) (*log.Logger, *logging.Manager, error) {
+func setupLogging(appName string, logDir, configPath string, configData []byte, showInfo bool, logOutput string, logFallback bool) (*log.Logger, *logging.Manager, error) {
 	u, err := user.Current()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get current user: %v", err)
 	}
 
 	// Create logging manager
-	logManager := logging.NewManager(appName, u, true)
+	// Only the file output may create directories
+	logManager := logging.NewManager(appName, u, logOutput == logging.OutputFile)
+	if err := logManager.SetOutput(logOutput); err != nil {
+		return nil, nil, err
+	}
+	logManager.SetFallback(logFallback)
 
-	// Get default config path if not specified
-	if configPath == "" {
+	// Config files read from stdin or inline are parsed up front, otherwise
+	// get the default config path if not specified
+	if configData != nil {
+		if err := logManager.ParseConfig(configData); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config: %v", err)
+		}
+		configPath = ""
+	} else if configPath == "" {
 		configPath, err = logManager.GetDefaultConfigPath()
 		if err != nil {
 			log.Printf("Warning: failed to get default config path: %v", err)
@@ -146,6 +1035,37 @@ func setupLogging(appName string, logDir, configPath string, showInfo bool) (*lo
 	return logger, logManager, nil
 }
 
+// loadServerConfig loads and validates the server configuration, sharing the
+// config file used for logging. Missing files fall back to the defaults,
+// adjusted to the client profile if one is given.
+func loadServerConfig(configPath string, configData []byte, clientProfile string, logManager *logging.Manager) (*config.ServerConfig, error) {
+	if configPath == "" && configData == nil {
+		if defaultPath, err := logManager.GetDefaultConfigPath(); err == nil {
+			configPath = defaultPath
+		}
+	}
+
+	serverConfig, err := loadConfigSource(configPath, configData, clientProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := serverConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+
+	return serverConfig, nil
+}
+
+// loadConfigSource loads the configuration from configData when the config
+// file was read from stdin or given inline, and otherwise from configPath
+func loadConfigSource(configPath string, configData []byte, clientProfile string) (*config.ServerConfig, error) {
+	if configData != nil {
+		return config.ParseWithProfile(configData, clientProfile)
+	}
+	return config.LoadFromFileWithProfile(configPath, clientProfile)
+}
+
 func printLogInfo(info *logging.LogInfo, logger *log.Logger) {
 	logger.Printf("=== Logging Configuration ===\n")
 	logger.Printf("App Name: %s\n", info.AppName)
@@ -155,7 +1075,16 @@ func printLogInfo(info *logging.LogInfo, logger *log.Logger) {
 	logger.Printf("Config Path: %s\n", info.ConfigPath)
 
 	logger.Printf("\n=== Directory Resolution ===\n")
-	if info.UsingCLIDir {
+	for _, fallback := range info.Fallbacks {
+		logger.Printf("! %s\n", fallback)
+	}
+	if info.Output == logging.OutputNone {
+		logger.Printf("✓ Logging disabled\n")
+	} else if info.UsingStderr {
+		logger.Printf("✓ Using stderr only\n")
+	} else if len(info.Fallbacks) > 0 {
+		logger.Printf("✓ Using user-specific default directory\n")
+	} else if info.UsingCLIDir {
 		logger.Printf("✓ Using CLI-specified directory\n")
 	} else if info.UsingConfigDir {
 		logger.Printf("✓ Using config file directory\n")
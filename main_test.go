@@ -1,9 +1,30 @@
 package main
 
 import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/jsonrpc"
+	"mock-lsp-server/logging"
+	"mock-lsp-server/lsp"
+	"mock-lsp-server/recording"
 )
 
 // Test for the version that returns the manager too
@@ -14,7 +35,7 @@ func Test_setupLoggingWithManager(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	logger, manager, err := setupLogging("test-app", tempDir, "", false)
+	logger, manager, err := setupLogging("test-app", tempDir, "", nil, false, logging.OutputFile, true)
 	if err != nil {
 		t.Fatalf("setupLoggingWithManager() error = %v", err)
 	}
@@ -38,6 +59,25 @@ func Test_setupLoggingWithManager(t *testing.T) {
 	}
 }
 
+func Test_setupLoggingWithoutFiles(t *testing.T) {
+	for _, output := range []string{logging.OutputStderr, logging.OutputNone} {
+		t.Run(output, func(t *testing.T) {
+			logDir := filepath.Join(t.TempDir(), "logs")
+
+			logger, manager, err := setupLogging("test-app", logDir, "", nil, false, output, true)
+			if err != nil {
+				t.Fatalf("setupLogging() error = %v", err)
+			}
+			defer manager.Close()
+			logger.Println("Test message")
+
+			if _, err := os.Stat(logDir); !os.IsNotExist(err) {
+				t.Errorf("Expected no log directory to be created for %s output, got %v", output, err)
+			}
+		})
+	}
+}
+
 func Test_loadConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -51,10 +91,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server", // default value
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   false,
+				AppName:       "mock-lsp-server", // default value
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -63,10 +110,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-log_dir", "/tmp/logs"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "/tmp/logs",
-				ConfigPath: "",
-				ShowInfo:   false,
+				AppName:       "mock-lsp-server",
+				LogDir:        "/tmp/logs",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -75,10 +129,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-config", "/path/to/config.json"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "",
-				ConfigPath: "/path/to/config.json",
-				ShowInfo:   false,
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "/path/to/config.json",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -87,22 +148,224 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-info"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   true,
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      true,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "minimal flag",
+			progname: "mock-lsp-server",
+			args:     []string{"--minimal"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				Minimal:       true,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "record flag",
+			progname: "mock-lsp-server",
+			args:     []string{"-record", "sessions.jsonl"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				RecordPath:    "sessions.jsonl",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "protocol flag",
+			progname: "mock-lsp-server",
+			args:     []string{"--protocol", "3.16"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				Protocol:      "3.16",
 			},
 			wantErr: false,
 		},
+		{
+			name:     "client profile flag",
+			progname: "mock-lsp-server",
+			args:     []string{"--client-profile", "helix"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				ClientProfile: "helix",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "failure preset flag",
+			progname: "mock-lsp-server",
+			args:     []string{"-failure-preset", "flaky-network"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				FailurePreset: "flaky-network",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "inline config without padding",
+			progname: "mock-lsp-server",
+			args:     []string{"-config-inline", "eyJsc3AiOnt9fQ"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				ConfigInline:  "eyJsc3AiOnt9fQ",
+				ConfigData:    []byte(`{"lsp":{}}`),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "inline scenario",
+			progname: "mock-lsp-server",
+			args:     []string{"-scenario-inline", "eyJ0aW1lbGluZSI6W119"},
+			want: &MockLSPServerConfig{
+				AppName:        "mock-lsp-server",
+				LogOutput:      "auto",
+				LogFallback:    true,
+				Mode:           "stdio",
+				Addr:           ":8989",
+				RPC:            "jsonrpc2",
+				Framing:        "header",
+				RecordBackend:  "file",
+				ScenarioInline: "eyJ0aW1lbGluZSI6W119",
+				ScenarioData:   []byte(`{"timeline":[]}`),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "config from stdin over tcp",
+			progname: "mock-lsp-server",
+			args:     []string{"-config", "-", "-mode", "tcp"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				ConfigPath:    "-",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "tcp",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "scenario flag",
+			progname: "mock-lsp-server",
+			args:     []string{"-scenario", "session.json"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				ScenarioPath:  "session.json",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "pack flags",
+			progname: "mock-lsp-server",
+			args:     []string{"-pack", "web", "-packs-dir", "packs", "-pack-workspace", "workspace"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				Pack:          "web",
+				PacksDir:      "packs",
+				PackWorkspace: "workspace",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "pack with scenario",
+			progname: "mock-lsp-server",
+			args:     []string{"-pack", "web", "-scenario", "session.json"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "pack workspace without pack",
+			progname: "mock-lsp-server",
+			args:     []string{"-pack-workspace", "workspace"},
+			want:     nil,
+			wantErr:  true,
+		},
 		{
 			name:     "appName flag",
 			progname: "test-program",
 			args:     []string{"-appName", "custom-app"},
 			want: &MockLSPServerConfig{
-				AppName:    "custom-app",
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   false,
+				AppName:       "custom-app",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -111,10 +374,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-appName", "test-app", "-log_dir", "/var/log", "-config", "config.yaml", "-info"},
 			want: &MockLSPServerConfig{
-				AppName:    "test-app",
-				LogDir:     "/var/log",
-				ConfigPath: "config.yaml",
-				ShowInfo:   true,
+				AppName:       "test-app",
+				LogDir:        "/var/log",
+				ConfigPath:    "config.yaml",
+				ShowInfo:      true,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -123,10 +393,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"--log_dir=/home/user/logs", "--config=/etc/config.toml", "--info=true"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "/home/user/logs",
-				ConfigPath: "/etc/config.toml",
-				ShowInfo:   true,
+				AppName:       "mock-lsp-server",
+				LogDir:        "/home/user/logs",
+				ConfigPath:    "/etc/config.toml",
+				ShowInfo:      true,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -135,10 +412,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-log_dir", "/tmp", "--config=/path/config", "-info"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "/tmp",
-				ConfigPath: "/path/config",
-				ShowInfo:   true,
+				AppName:       "mock-lsp-server",
+				LogDir:        "/tmp",
+				ConfigPath:    "/path/config",
+				ShowInfo:      true,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -147,10 +431,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-log_dir", "", "-config", ""},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   false,
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -159,10 +450,17 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-info=false"}, // explicit false
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   false,
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
@@ -171,14 +469,224 @@ func Test_loadConfig(t *testing.T) {
 			progname: "mock-lsp-server",
 			args:     []string{"-info=true"},
 			want: &MockLSPServerConfig{
-				AppName:    "mock-lsp-server",
-				LogDir:     "",
-				ConfigPath: "",
-				ShowInfo:   true,
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      true,
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "stderr log output",
+			progname: "mock-lsp-server",
+			args:     []string{"-log", "stderr", "-log-fallback=false"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogDir:        "",
+				ConfigPath:    "",
+				ShowInfo:      false,
+				LogOutput:     "stderr",
+				LogFallback:   false,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "help exit codes",
+			progname: "mock-lsp-server",
+			args:     []string{"-help-exit-codes"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				HelpExitCodes: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "soak interval",
+			progname: "mock-lsp-server",
+			args:     []string{"-soak", "5m"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				SoakInterval:  5 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "control address",
+			progname: "mock-lsp-server",
+			args:     []string{"-control-addr", "127.0.0.1:8990"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				ControlAddr:   "127.0.0.1:8990",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "cache mock data",
+			progname: "mock-lsp-server",
+			args:     []string{"-cache-mock-data"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+				CacheMockData: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "tcp mode",
+			progname: "mock-lsp-server",
+			args:     []string{"-mode", "tcp", "-addr", "127.0.0.1:9000"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "tcp",
+				Addr:          "127.0.0.1:9000",
+				RPC:           "jsonrpc2",
+				Framing:       "header",
+				RecordBackend: "file",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "internal rpc with line framing",
+			progname: "mock-lsp-server",
+			args:     []string{"-rpc", "internal", "-framing", "line"},
+			want: &MockLSPServerConfig{
+				AppName:       "mock-lsp-server",
+				LogOutput:     "auto",
+				LogFallback:   true,
+				Mode:          "stdio",
+				Addr:          ":8989",
+				RPC:           "internal",
+				Framing:       "line",
+				RecordBackend: "file",
 			},
 			wantErr: false,
 		},
 		// Error cases
+		{
+			name:     "invalid mode",
+			progname: "mock-lsp-server",
+			args:     []string{"-mode", "pipe"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown rpc implementation",
+			progname: "mock-lsp-server",
+			args:     []string{"-rpc", "grpc"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "line framing without internal rpc",
+			progname: "mock-lsp-server",
+			args:     []string{"-framing", "line"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown protocol version",
+			progname: "mock-lsp-server",
+			args:     []string{"-protocol", "3.15"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown client profile",
+			progname: "mock-lsp-server",
+			args:     []string{"-client-profile", "sublime"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown failure preset",
+			progname: "mock-lsp-server",
+			args:     []string{"-failure-preset", "meteor-strike"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "config and inline config",
+			progname: "mock-lsp-server",
+			args:     []string{"-config", "config.json", "-config-inline", "eyJsc3AiOnt9fQ=="},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid inline scenario",
+			progname: "mock-lsp-server",
+			args:     []string{"-scenario-inline", "not base64!"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "config from stdin over stdio",
+			progname: "mock-lsp-server",
+			args:     []string{"-config", "-"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "config and scenario from stdin",
+			progname: "mock-lsp-server",
+			args:     []string{"-config", "-", "-scenario", "-", "-mode", "tcp"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "negative soak interval",
+			progname: "mock-lsp-server",
+			args:     []string{"-soak", "-1s"},
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid log output",
+			progname: "mock-lsp-server",
+			args:     []string{"-log", "syslog"},
+			want:     nil,
+			wantErr:  true,
+		},
 		{
 			name:     "unknown flag",
 			progname: "mock-lsp-server",
@@ -338,7 +846,7 @@ func Benchmark_setupLogging(b *testing.B) {
 	defer os.RemoveAll(tempDir)
 
 	for b.Loop() {
-		logger, logManager, err := setupLogging("benchmark-app", tempDir, "", false)
+		logger, logManager, err := setupLogging("benchmark-app", tempDir, "", nil, false, logging.OutputFile, true)
 		if err != nil {
 			b.Fatalf("setupLogging() error = %v", err)
 		}
@@ -350,3 +858,712 @@ func Benchmark_setupLogging(b *testing.B) {
 		}
 	}
 }
+
+func Test_runSyncFuzz(t *testing.T) {
+	var out bytes.Buffer
+	if code := runSyncFuzz(3, 7, &out); code != 0 {
+		t.Fatalf("runSyncFuzz() = %d, want 0; report: %s", code, out.String())
+	}
+
+	var report lsp.SyncFuzzReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("runSyncFuzz() wrote invalid JSON: %v", err)
+	}
+	if report.Seed != 7 || report.Edits != 3*syncFuzzEdits {
+		t.Errorf("runSyncFuzz() report = %+v", report)
+	}
+}
+
+func Test_runHelpExitCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"exit_codes": {"expectation_failure": 10}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runHelpExitCodes(path, nil, &out); code != 0 {
+		t.Fatalf("runHelpExitCodes() = %d, want 0", code)
+	}
+	for _, want := range []string{"0  after_shutdown", "1  without_shutdown", "2  fatal", "10  expectation_failure"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runHelpExitCodes() output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"server": {"exit_codes": {"fatal": 300}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if code := runHelpExitCodes(invalid, nil, io.Discard); code != 2 {
+		t.Errorf("runHelpExitCodes() with an invalid config = %d, want 2", code)
+	}
+}
+
+func Test_readStdin(t *testing.T) {
+	config, err := loadConfig("test-prog", []string{"-scenario", "-", "-mode", "tcp"})
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if err := config.readStdin(strings.NewReader(`{"timeline": []}`)); err != nil {
+		t.Fatalf("readStdin() failed: %v", err)
+	}
+	if string(config.ScenarioData) != `{"timeline": []}` || config.ConfigData != nil {
+		t.Errorf("readStdin() read scenario %q and config %q", config.ScenarioData, config.ConfigData)
+	}
+
+	serverConfig, err := loadConfigSource("", []byte(`{"lsp": {"completion": {"max_items": 7}}}`), "")
+	if err != nil {
+		t.Fatalf("loadConfigSource() failed: %v", err)
+	}
+	if serverConfig.LSP.CompletionConfig.MaxItems != 7 || !serverConfig.LSP.HoverConfig.Enabled {
+		t.Errorf("loadConfigSource() did not merge the config into the defaults: %+v", serverConfig.LSP)
+	}
+	if _, err := loadConfigSource("", []byte(`{"lsp":`), ""); err == nil {
+		t.Error("loadConfigSource() accepted a truncated config")
+	}
+}
+
+func Test_crashReporter(t *testing.T) {
+	dir := t.TempDir()
+	config, err := loadConfig("test-prog", []string{"-log", "none"})
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	crashes := &crashReporter{dir: dir, flags: config}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected the panic to be resumed, got %v", r)
+			}
+		}()
+		defer crashes.recoverPanic()
+		panic("boom")
+	}()
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "crash-*.json"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected one crash report, got %v", matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("Failed to read crash report: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"reason": "panic: boom"`)) {
+		t.Errorf("Expected panic reason in report, got %s", data)
+	}
+}
+
+func Test_crashReporterCleanup(t *testing.T) {
+	dir := t.TempDir()
+	pidFile, err := acquirePIDFile(dir, "test-app", false)
+	if err != nil {
+		t.Fatalf("acquirePIDFile() failed: %v", err)
+	}
+	crashes := &crashReporter{dir: t.TempDir(), flags: &MockLSPServerConfig{AppName: "test-app"}}
+	var order []string
+	crashes.deferCleanup(func() {
+		order = append(order, "pid file")
+		pidFile.Release()
+	})
+	crashes.deferCleanup(func() { order = append(order, "logs") })
+
+	// fatalf runs these before exiting
+	crashes.cleanup()
+	if want := []string{"logs", "pid file"}; !slices.Equal(order, want) {
+		t.Errorf("Expected cleanups in order %v, got %v", want, order)
+	}
+	if _, err := os.Stat(pidFile.Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed, got %v", err)
+	}
+	crashes.cleanup()
+	if len(order) != 2 {
+		t.Errorf("Expected cleanups to run once, got %v", order)
+	}
+}
+
+func Test_writeReadyLine(t *testing.T) {
+	tempDir := t.TempDir()
+	_, manager, err := setupLogging("test-app", tempDir, "", nil, false, logging.OutputFile, true)
+	if err != nil {
+		t.Fatalf("setupLogging() error = %v", err)
+	}
+	defer manager.Close()
+
+	var buf bytes.Buffer
+	ready := newReadyLine("test-app", config.DefaultConfig(), manager.GetLogPath())
+	if err := writeReadyLine(&buf, ready); err != nil {
+		t.Fatalf("writeReadyLine() error = %v", err)
+	}
+
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 1 || !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		t.Errorf("Expected a single line, got %q", buf.String())
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", buf.String(), err)
+	}
+	if got["event"] != "ready" || got["transport"] != "stdio" {
+		t.Errorf("Expected ready event over stdio, got %v", got)
+	}
+	if pid, _ := got["pid"].(float64); int(pid) != os.Getpid() {
+		t.Errorf("Expected pid %d, got %v", os.Getpid(), got["pid"])
+	}
+	if expected := filepath.Join(tempDir, "test-app.log"); got["log_path"] != expected {
+		t.Errorf("Expected log_path %s, got %v", expected, got["log_path"])
+	}
+}
+
+func Test_acquirePIDFile(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := acquirePIDFile(dir, "test-app", false)
+	if err != nil {
+		t.Fatalf("acquirePIDFile() error = %v", err)
+	}
+	defer first.Release()
+	if expected := filepath.Join(dir, "test-app.pid"); first.Path() != expected {
+		t.Errorf("Expected PID file %s, got %s", expected, first.Path())
+	}
+
+	if _, err := acquirePIDFile(dir, "test-app", false); err == nil {
+		t.Error("Expected a second instance to be refused")
+	}
+
+	second, err := acquirePIDFile(dir, "test-app", true)
+	if err != nil || second != nil {
+		t.Errorf("Expected -allow-multiple to start without a PID file, got %v, %v", second, err)
+	}
+}
+
+func Test_transportListeners(t *testing.T) {
+	transportErrs := testTransportErrors(t)
+	listeners, transport, err := transportListeners(modeStdio, ":8989", transportErrs)
+	if err != nil || len(listeners) != 0 || transport != "stdio" {
+		t.Errorf("transportListeners(stdio) = %v, %q, %v, want no listeners over stdio", listeners, transport, err)
+	}
+
+	listeners, transport, err = transportListeners(modeTCP, "127.0.0.1:0", transportErrs)
+	if err != nil {
+		t.Fatalf("transportListeners(tcp) error = %v", err)
+	}
+	defer listeners[0].Close()
+	if len(listeners) != 1 || transport != "tcp" || listeners[0].Addr().Network() != "tcp" {
+		t.Errorf("transportListeners(tcp) = %v, %q, want one TCP listener", listeners, transport)
+	}
+
+	if _, _, err := transportListeners(modeTCP, "127.0.0.1:-1", transportErrs); err == nil {
+		t.Error("Expected an invalid -addr to fail")
+	}
+}
+
+func Test_controlURL(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"127.0.0.1:8990", "http://127.0.0.1:8990"},
+		{"[::]:8990", "http://localhost:8990"},
+		{"0.0.0.0:8990", "http://localhost:8990"},
+		{"[::1]:8990", "http://[::1]:8990"},
+	}
+	for _, tt := range tests {
+		addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := controlURL(addr); got != tt.want {
+			t.Errorf("controlURL(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func Test_serveListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	logger := log.New(io.Discard, "", 0)
+	newServer := func() *lsp.MockLSPServer { return lsp.NewMockLSPServer(logger) }
+	crashes := &crashReporter{dir: t.TempDir(), flags: &MockLSPServerConfig{AppName: "test-app"}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveListener(listener, newServer, newStreamFactory(rpcJSONRPC2, "header"), logger, crashes, testTransportErrors(t))
+	}()
+
+	// Each connection gets its own server instance
+	clientIDs := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		netConn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		ctx := context.Background()
+		client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(netConn),
+			jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+				return nil, nil
+			}))
+
+		var stats lsp.StatsSnapshot
+		if err := client.Call(ctx, "$/mockLsp/stats", nil, &stats); err != nil {
+			t.Fatalf("Stats request failed: %v", err)
+		}
+		clientIDs[stats.ClientID] = true
+		client.Close()
+	}
+	if len(clientIDs) != 2 {
+		t.Errorf("Expected 2 distinct client IDs, got %v", clientIDs)
+	}
+
+	listener.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Expected serveListener to stop cleanly, got %v", err)
+	}
+}
+
+func Test_newServerConnDrainsOnShutdown(t *testing.T) {
+	server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+	cfg := config.DefaultConfig()
+	cfg.LSP.ShutdownDrainTimeout = config.Duration(50 * time.Millisecond)
+	cfg.LSP.Latency.Delays = map[string]config.Duration{"textDocument/hover": config.Duration(time.Hour)}
+	server.SetConfig(cfg)
+
+	serverSide, clientSide := net.Pipe()
+	ctx := context.Background()
+	crashes := &crashReporter{dir: t.TempDir(), flags: &MockLSPServerConfig{AppName: "test-app"}}
+	serverConn := newServerConn(ctx, jsonrpc.NewStream(serverSide), server, log.New(io.Discard, "", 0), crashes)
+	client := jsonrpc.NewConn(ctx, jsonrpc.NewStream(clientSide),
+		jsonrpc.HandlerWithError(func(context.Context, jsonrpc.Conn, *jsonrpc.Request) (any, error) {
+			return nil, nil
+		}))
+	t.Cleanup(func() {
+		client.Close()
+		serverConn.Close()
+	})
+
+	if err := client.Call(ctx, "initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}, nil); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	// The hover is still being handled, one at a time, when shutdown is read
+	hover := make(chan error, 1)
+	go func() {
+		params := map[string]any{"textDocument": map[string]any{"uri": "file:///drain.go"}, "position": map[string]any{"line": 0, "character": 0}}
+		hover <- client.Call(ctx, "textDocument/hover", params, nil)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := client.Call(shutdownCtx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	var rpcErr *jsonrpc.Error
+	if err := <-hover; !errors.As(err, &rpcErr) || rpcErr.Code != int64(lsp.ErrorCodeRequestCancelled) {
+		t.Errorf("Expected the hover to be cancelled after the drain timeout, got %v", err)
+	}
+}
+
+func Test_newServerConnRepliesOnce(t *testing.T) {
+	server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+	serverSide, clientSide := net.Pipe()
+	crashes := &crashReporter{dir: t.TempDir(), flags: &MockLSPServerConfig{AppName: "test-app"}}
+	serverConn := newServerConn(context.Background(), newStreamFactory(rpcInternal, "line")(serverSide), server, log.New(io.Discard, "", 0), crashes)
+	t.Cleanup(func() {
+		clientSide.Close()
+		serverConn.Close()
+	})
+
+	frames := make(chan []byte, 16)
+	go func() {
+		scanner := bufio.NewScanner(clientSide)
+		for scanner.Scan() {
+			frames <- bytes.Clone(scanner.Bytes())
+		}
+		close(frames)
+	}()
+	go func() {
+		io.WriteString(clientSide, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"processId":null,"rootUri":null,"capabilities":{}}}`+"\n")
+		io.WriteString(clientSide, `[{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///once.go"},"position":{"line":0,"character":0}}},`+
+			`{"jsonrpc":"2.0","method":"initialized","params":{}},{"jsonrpc":"2.0","id":3,"method":"shutdown"}]`+"\n")
+	}()
+
+	// Responses are counted until the server stays quiet, so a second
+	// response to any ID would be seen
+	responses := make(map[string]int)
+	var batches [][]string
+	for {
+		var frame []byte
+		select {
+		case frame = <-frames:
+		case <-time.After(300 * time.Millisecond):
+		}
+		if frame == nil {
+			break
+		}
+		var messages []struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		batch := bytes.HasPrefix(frame, []byte("["))
+		if !batch {
+			frame = append(append([]byte("["), frame...), ']')
+		}
+		if err := json.Unmarshal(frame, &messages); err != nil {
+			t.Fatalf("Invalid frame %s: %v", frame, err)
+		}
+		var ids []string
+		for _, message := range messages {
+			if message.Method == "" {
+				responses[string(message.ID)]++
+				ids = append(ids, string(message.ID))
+			}
+		}
+		if batch {
+			batches = append(batches, ids)
+		}
+	}
+
+	if want := map[string]int{"1": 1, "2": 1, "3": 1}; !reflect.DeepEqual(responses, want) {
+		t.Errorf("Expected one response per request ID, got %v", responses)
+	}
+	if len(batches) != 1 || !reflect.DeepEqual(batches[0], []string{"2", "3"}) {
+		t.Errorf("Expected the batch answered by one batch of its two requests, got %v", batches)
+	}
+}
+
+func Test_checkForUpdate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v99.0.0", "html_url": "https://example.com/releases/v99.0.0"}`))
+	}))
+	defer srv.Close()
+
+	oldVersion := version
+	version = "1.0.0"
+	defer func() { version = oldVersion }()
+
+	var out, logs bytes.Buffer
+	checkForUpdate(context.Background(), srv.URL, &out, log.New(&logs, "", 0))
+
+	if !strings.Contains(out.String(), "99.0.0") {
+		t.Errorf("Expected the newer release on stderr, got %q", out.String())
+	}
+	if !strings.Contains(logs.String(), "99.0.0") {
+		t.Errorf("Expected the newer release in the log, got %q", logs.String())
+	}
+
+	out.Reset()
+	logs.Reset()
+	checkForUpdate(context.Background(), srv.URL+"/unreachable\x00", &out, log.New(&logs, "", 0))
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing on stderr after a failed check, got %q", out.String())
+	}
+	if !strings.Contains(logs.String(), "Update check failed") {
+		t.Errorf("Expected the failure in the log, got %q", logs.String())
+	}
+}
+
+func Test_runStats(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, snapshot lsp.StatsSnapshot) string {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatalf("Failed to encode stats: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write stats: %v", err)
+		}
+		return path
+	}
+	before := write("before.json", lsp.StatsSnapshot{Requests: map[string]int64{"initialize": 1, "shutdown": 1}})
+	same := write("same.json", lsp.StatsSnapshot{Requests: map[string]int64{"initialize": 1, "shutdown": 1}})
+	after := write("after.json", lsp.StatsSnapshot{Requests: map[string]int64{"initialize": 1}})
+	failing := write("failing.json", lsp.StatsSnapshot{
+		Requests:       map[string]int64{"initialize": 1, "textDocument/hover": 2},
+		ErrorResponses: map[string]int64{"textDocument/hover": 1},
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"no subcommand", nil, 2, ""},
+		{"unknown subcommand", []string{"show", before, after}, 2, ""},
+		{"missing file argument", []string{"diff", before}, 2, ""},
+		{"unreadable file", []string{"diff", before, filepath.Join(dir, "missing.json")}, 2, ""},
+		{"no differences", []string{"diff", before, same}, 0, "0 changes, 0 regressions"},
+		{"regression", []string{"diff", before, after}, 1, "REGRESSION requests shutdown: 1 -> 0"},
+		{"json output", []string{"diff", "-json", before, after}, 1, `"regressions": 1`},
+		{"junit missing file argument", []string{"junit"}, 2, ""},
+		{"junit passing", []string{"junit", before}, 0, `<testsuites name="mock-lsp-server" tests="2" failures="0">`},
+		{"junit failing", []string{"junit", failing}, 1, `<failure message="1 of 2 requests answered with an error" type="requests"></failure>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runStats("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runStats() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func Test_runRecordings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	store, err := recording.OpenFile(path)
+	if err != nil {
+		t.Fatalf("Failed to open recording: %v", err)
+	}
+	for _, record := range []recording.Record{
+		{Session: "1", Kind: recording.KindResponse, Method: "textDocument/hover", Latency: config.Duration(300 * time.Millisecond)},
+		{Session: "1", Kind: recording.KindResponse, Method: "textDocument/completion", Latency: config.Duration(time.Millisecond)},
+	} {
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	store.Close()
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantCode  int
+		wantLines int
+	}{
+		{"no subcommand", nil, 2, 0},
+		{"missing store", []string{"query"}, 2, 0},
+		{"inverted latency range", []string{"query", "-min-latency", "1s", "-max-latency", "1ms", path}, 2, 0},
+		{"unreadable store", []string{"query", path + ".missing"}, 1, 0},
+		{"unknown backend", []string{"query", "-backend", "csv", path}, 1, 0},
+		{"everything", []string{"query", path}, 0, 2},
+		{"slow responses", []string{"query", "-min-latency", "100ms", path}, 0, 1},
+		{"by method", []string{"query", "-method", "textDocument/completion", path}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runRecordings("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runRecordings() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if lines := strings.Count(out.String(), "\n"); lines != tt.wantLines {
+				t.Errorf("Expected %d records, got %q", tt.wantLines, out.String())
+			}
+		})
+	}
+	if _, err := os.Stat(path + ".missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected querying a missing store not to create it, got %v", err)
+	}
+}
+
+// writeTestPack writes a zip workspace pack with entries at path
+func writeTestPack(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range entries {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_runPacks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPack(t, filepath.Join(dir, "web.zip"), map[string]string{
+		"pack.json":         `{"description": "Web app"}`,
+		"config.json":       `{}`,
+		"files/src/main.ts": "export {};\n",
+	})
+	writeTestPack(t, filepath.Join(dir, "empty.zip"), map[string]string{"pack.json": `{}`})
+	workspace := t.TempDir()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"no subcommand", nil, 2, ""},
+		{"extract without target", []string{"extract", "-dir", dir, "web"}, 2, ""},
+		{"list", []string{"list", "-dir", dir}, 0, "empty\t0 files\t\nweb\t1 files, config\tWeb app\n"},
+		{"extract missing pack", []string{"extract", "-dir", dir, "missing", workspace}, 1, ""},
+		{"extract", []string{"extract", "-dir", dir, "web", workspace}, 0, "Extracted 1 files of web to " + workspace + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runPacks("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runPacks() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("Expected output %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "src", "main.ts")); err != nil {
+		t.Errorf("Expected the workspace files to be extracted: %v", err)
+	}
+}
+
+func Test_loadPack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "web.zip")
+	writeTestPack(t, path, map[string]string{
+		"scenario.json":     `{"timeline": []}`,
+		"files/src/main.ts": "export {};\n",
+	})
+	workspace := filepath.Join(t.TempDir(), "workspace")
+
+	conf := &MockLSPServerConfig{Pack: "web", PacksDir: dir, PackWorkspace: workspace}
+	if err := conf.loadPack(); err != nil {
+		t.Fatalf("loadPack() unexpected error: %v", err)
+	}
+	if conf.ConfigData != nil || string(conf.ScenarioData) != `{"timeline": []}` {
+		t.Errorf("Expected the scenario of the pack and no config, got %q and %q", conf.ConfigData, conf.ScenarioData)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "src", "main.ts")); err != nil {
+		t.Errorf("Expected the workspace files to be extracted: %v", err)
+	}
+
+	conf = &MockLSPServerConfig{Pack: path, PacksDir: t.TempDir()}
+	if err := conf.loadPack(); err != nil || conf.ScenarioData == nil {
+		t.Errorf("Expected a pack path to be opened directly, got %v", err)
+	}
+	conf = &MockLSPServerConfig{Pack: "missing", PacksDir: dir}
+	if err := conf.loadPack(); err == nil {
+		t.Error("Expected an error for a missing pack")
+	}
+}
+
+func Test_runWatchStorm(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+	go func() {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		jsonrpc.NewConn(context.Background(), jsonrpc.NewStream(netConn), server)
+	}()
+	addr := listener.Addr().String()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"no server", nil, 2, ""},
+		{"address and command", []string{"-addr", addr, "--", "mock-lsp-server"}, 2, ""},
+		{"no files", []string{"-addr", addr, "-files", "0"}, 2, ""},
+		{"unreachable server", []string{"-addr", "127.0.0.1:1"}, 1, ""},
+		{"storm", []string{"-addr", addr, "-files", "30", "-bursts", "2", "-interval", "0", "-seed", "5"}, 0, `"events": 60`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runWatchStorm("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runWatchStorm() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+	if got := server.Stats().WatchedFileEvents["created"]; got < 30 {
+		t.Errorf("Expected the server to count at least 30 created files, got %d", got)
+	}
+}
+
+func Test_runStress(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"no clients", []string{"-clients", "0"}, 2, ""},
+		{"extra arguments", []string{"now"}, 2, ""},
+		{"missing config", []string{"-config", filepath.Join(t.TempDir(), "missing.json"), "-clients", "1", "-requests", "1"}, 0, `"requests": 1,`},
+		{"stress", []string{"-clients", "3", "-requests", "20", "-seed", "9"}, 0, `"requests": 60,`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runStress("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runStress() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"lsp": {"completion": {"max_items": -1}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if code := runStress("mock-lsp-server", []string{"-config", invalid}, &out, &errOut); code != 2 {
+		t.Errorf("Expected exit code 2 for an invalid config, got %d", code)
+	}
+}
+
+func Test_runSelftest(t *testing.T) {
+	failing := filepath.Join(t.TempDir(), "failing.json")
+	if err := os.WriteFile(failing, []byte(`{"lsp": {"rename": {"edits_per_file": 1}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"extra arguments", []string{"now"}, 2, ""},
+		{"both assets", []string{"-print-session", "-print-scenario"}, 2, ""},
+		{"print session", []string{"-print-session"}, 0, `"method": "textDocument/rename"`},
+		{"print scenario", []string{"-print-scenario"}, 0, `"expectations"`},
+		{"selftest", nil, 0, "PASS textDocument/rename\n"},
+		{"json report", []string{"-json"}, 0, `"passed": true`},
+		{"drifted config", []string{"-config", failing}, 1, "FAIL textDocument/rename expectation: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runSelftest("mock-lsp-server", tt.args, &out, &errOut); code != tt.wantCode {
+				t.Errorf("runSelftest() = %d, want %d; stderr: %s", code, tt.wantCode, errOut.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+}
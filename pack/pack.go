@@ -0,0 +1,290 @@
+// Package pack loads workspace packs: single archives holding the files of
+// a workspace together with the config and scenario to serve it with, so
+// teams can share reproducible test environments as one artifact.
+//
+// A pack is a zip or (optionally gzipped) tar archive laid out as
+//
+//	pack.json       optional metadata, {"description": "..."}
+//	config.json     optional config file
+//	scenario.json   optional scenario file
+//	files/...       workspace files
+package pack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Names of the entries of a pack
+const (
+	manifestName = "pack.json"
+	configName   = "config.json"
+	scenarioName = "scenario.json"
+	filesDir     = "files/"
+)
+
+// MaxSize bounds the uncompressed size of the entries of a pack, so a
+// malicious archive cannot exhaust memory
+const MaxSize = 64 << 20
+
+// Extensions lists the archive extensions of packs, longest first so
+// .tar.gz is recognized before .gz would be
+var Extensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// ErrNotFound is returned by Manager.Load for packs missing from the
+// directory
+var ErrNotFound = errors.New("pack not found")
+
+// Pack is the content of a workspace pack
+type Pack struct {
+	Name        string            // File name without the archive extension
+	Path        string            // Path of the archive
+	Description string            // From pack.json
+	Config      []byte            // config.json, nil when the pack has none
+	Scenario    []byte            // scenario.json, nil when the pack has none
+	Files       map[string][]byte // Workspace files keyed by slash-separated path below files/
+}
+
+// manifest is the content of pack.json
+type manifest struct {
+	Description string `json:"description"`
+}
+
+// Name returns the name of the pack archive at path, and false if path does
+// not have an archive extension
+func Name(path string) (string, bool) {
+	base := filepath.Base(path)
+	for _, ext := range Extensions {
+		if name, ok := strings.CutSuffix(base, ext); ok && name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Open reads the pack archive at path
+func Open(path string) (*Pack, error) {
+	name, ok := Name(path)
+	if !ok {
+		return nil, fmt.Errorf("unsupported pack %s: extension must be one of %s", path, strings.Join(Extensions, ", "))
+	}
+	entries, err := readArchive(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack %s: %w", path, err)
+	}
+	p, err := build(entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pack %s: %w", path, err)
+	}
+	p.Name, p.Path = name, path
+	return p, nil
+}
+
+// readArchive returns the regular files of the zip or tar archive at path,
+// keyed by their slash-separated names
+func readArchive(path string) (map[string][]byte, error) {
+	if strings.HasSuffix(path, ".zip") {
+		return readZip(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if !strings.HasSuffix(path, ".tar") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	return readTar(reader)
+}
+
+// readZip returns the regular files of a zip archive
+func readZip(path string) (map[string][]byte, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	entries := make(map[string][]byte)
+	var size int64
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if !file.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", file.Name)
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := readEntry(reader, &size)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries[file.Name] = data
+	}
+	return entries, nil
+}
+
+// readTar returns the regular files of a tar stream
+func readTar(reader io.Reader) (map[string][]byte, error) {
+	archive := tar.NewReader(reader)
+	entries := make(map[string][]byte)
+	var size int64
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("%s is not a regular file", header.Name)
+		}
+		data, err := readEntry(archive, &size)
+		if err != nil {
+			return nil, err
+		}
+		entries[header.Name] = data
+	}
+}
+
+// readEntry reads an archive entry, adding its length to size and failing
+// once size exceeds MaxSize
+func readEntry(reader io.Reader, size *int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, MaxSize-*size+1))
+	if err != nil {
+		return nil, err
+	}
+	if *size += int64(len(data)); *size > MaxSize {
+		return nil, fmt.Errorf("content exceeds %d bytes", MaxSize)
+	}
+	return data, nil
+}
+
+// build sorts the entries of an archive into a pack. Entries outside the
+// layout of a pack and paths escaping it are rejected.
+func build(entries map[string][]byte) (*Pack, error) {
+	p := &Pack{Files: make(map[string][]byte)}
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		clean := strings.TrimPrefix(path.Clean(name), "./")
+		if !filepath.IsLocal(filepath.FromSlash(clean)) {
+			return nil, fmt.Errorf("entry %s escapes the pack", name)
+		}
+		data := entries[name]
+		switch {
+		case clean == manifestName:
+			var m manifest
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&m); err != nil {
+				return nil, fmt.Errorf("%s: %w", manifestName, err)
+			}
+			p.Description = m.Description
+		case clean == configName:
+			p.Config = data
+		case clean == scenarioName:
+			p.Scenario = data
+		case strings.HasPrefix(clean, filesDir):
+			p.Files[strings.TrimPrefix(clean, filesDir)] = data
+		default:
+			return nil, fmt.Errorf("unexpected entry %s: must be %s, %s, %s or below %s", name, manifestName, configName, scenarioName, filesDir)
+		}
+	}
+	return p, nil
+}
+
+// Extract writes the workspace files of the pack below dir, creating the
+// folders they need. Existing files are overwritten.
+func (p *Pack) Extract(dir string) error {
+	for _, name := range slices.Sorted(maps.Keys(p.Files)) {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, p.Files[name], 0o644); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Manager lists and loads the packs kept in a directory. Use NewManager to
+// create one.
+type Manager struct {
+	dir string
+}
+
+// NewManager returns a Manager for the packs in dir
+func NewManager(dir string) *Manager {
+	return &Manager{dir: dir}
+}
+
+// Dir returns the directory of the packs
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// List returns the packs in the directory, ordered by name. A missing
+// directory holds no packs. Files without an archive extension are ignored.
+func (m *Manager) List() ([]*Pack, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packs: %w", err)
+	}
+	var packs []*Pack
+	for _, entry := range entries {
+		if _, ok := Name(entry.Name()); !ok || entry.IsDir() {
+			continue
+		}
+		p, err := Open(filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	slices.SortFunc(packs, func(a, b *Pack) int { return strings.Compare(a.Name, b.Name) })
+	return packs, nil
+}
+
+// Load opens the pack with the given name, trying each archive extension in
+// turn. It returns an error wrapping ErrNotFound when there is none.
+func (m *Manager) Load(name string) (*Pack, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid pack name %q", name)
+	}
+	for _, ext := range Extensions {
+		path := filepath.Join(m.dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return Open(path)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s in %s", ErrNotFound, name, m.dir)
+}
@@ -0,0 +1,151 @@
+package pack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTarGz writes entries as a gzipped tar archive at path
+func writeTarGz(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+	for name, content := range entries {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeZip writes entries as a zip archive at path
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range entries {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	entries := map[string]string{
+		"pack.json":              `{"description": "Two files"}`,
+		"config.json":            `{"lsp": {"hover": {"max_length": 200}}}`,
+		"./files/src/main.ts":    "import './util';\n",
+		"files/src/util.ts":      "export {};\n",
+		"files/../scenario.json": `{"timeline": []}`,
+	}
+	for _, name := range []string{"web.tar.gz", "web.zip"} {
+		path := filepath.Join(dir, name)
+		if strings.HasSuffix(name, ".zip") {
+			writeZip(t, path, entries)
+		} else {
+			writeTarGz(t, path, entries)
+		}
+		p, err := Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", name, err)
+		}
+		if p.Name != "web" || p.Description != "Two files" || p.Config == nil || p.Scenario == nil {
+			t.Errorf("Expected the pack metadata, config and scenario of %s, got %+v", name, p)
+		}
+		if len(p.Files) != 2 || string(p.Files["src/util.ts"]) != "export {};\n" {
+			t.Errorf("Expected the workspace files of %s, got %v", name, p.Files)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries map[string]string
+		want    string
+	}{
+		{"escaping entry", map[string]string{"files/../../etc/passwd": ""}, "escapes the pack"},
+		{"unexpected entry", map[string]string{"notes.txt": ""}, "unexpected entry"},
+		{"unknown manifest key", map[string]string{"pack.json": `{"author": "me"}`}, "pack.json"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "bad.tar.gz")
+		writeTarGz(t, path, tt.entries)
+		if _, err := Open(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if _, err := Open(filepath.Join(dir, "web.rar")); err == nil {
+		t.Error("Expected an error for an unsupported extension")
+	}
+}
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "zeta.zip"), map[string]string{"files/a.txt": "a"})
+	writeTarGz(t, filepath.Join(dir, "alpha.tgz"), map[string]string{"files/b/c.txt": "c"})
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(dir)
+
+	packs, err := manager.List()
+	if err != nil {
+		t.Fatalf("Failed to list packs: %v", err)
+	}
+	if len(packs) != 2 || packs[0].Name != "alpha" || packs[1].Name != "zeta" {
+		t.Errorf("Expected the two packs ordered by name, got %+v", packs)
+	}
+
+	p, err := manager.Load("alpha")
+	if err != nil {
+		t.Fatalf("Failed to load pack: %v", err)
+	}
+	workspace := t.TempDir()
+	if err := p.Extract(workspace); err != nil {
+		t.Fatalf("Failed to extract pack: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "b", "c.txt")); err != nil || string(data) != "c" {
+		t.Errorf("Expected the extracted file, got %q and %v", data, err)
+	}
+
+	if _, err := manager.Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := manager.Load("../alpha"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected names with separators to be rejected, got %v", err)
+	}
+	if packs, err := NewManager(filepath.Join(dir, "missing")).List(); err != nil || len(packs) != 0 {
+		t.Errorf("Expected no packs in a missing directory, got %v and %v", packs, err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"mock-lsp-server/directories"
+	"mock-lsp-server/pack"
+)
+
+// packsUsage describes the packs subcommand
+const packsUsage = "usage: %[1]s packs list [-dir dir]\n       %[1]s packs extract [-dir dir] pack target"
+
+// packsDirectory returns the directory workspace packs are kept in, under
+// the data directory or else the working directory
+func packsDirectory(appName string) string {
+	dir := "."
+	if u, err := user.Current(); err == nil {
+		if dataDir, err := directories.NewDirectoryResolver(appName, u, false).GetDataDirectory(); err == nil {
+			dir = dataDir
+		}
+	}
+	return filepath.Join(dir, "packs")
+}
+
+// openPack opens the workspace pack selected by name: the archive at that
+// path if it has a pack extension and exists, otherwise the pack of that
+// name in dir
+func openPack(name, dir string) (*pack.Pack, error) {
+	if _, ok := pack.Name(name); ok {
+		if _, err := os.Stat(name); err == nil {
+			return pack.Open(name)
+		}
+	}
+	return pack.NewManager(dir).Load(name)
+}
+
+// loadPack reads the config and scenario of the pack selected with -pack
+// into ConfigData and ScenarioData, and extracts its workspace files into
+// -pack-workspace when set. A pack without a config file leaves the default
+// config file in use.
+func (c *MockLSPServerConfig) loadPack() error {
+	if c.Pack == "" {
+		return nil
+	}
+	dir := c.PacksDir
+	if dir == "" {
+		dir = packsDirectory(c.AppName)
+	}
+	p, err := openPack(c.Pack, dir)
+	if err != nil {
+		return err
+	}
+	c.ConfigData, c.ScenarioData = p.Config, p.Scenario
+	if c.PackWorkspace != "" {
+		if err := p.Extract(c.PackWorkspace); err != nil {
+			return fmt.Errorf("failed to extract pack %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// packContents summarizes what a pack holds, e.g. "3 files, config, scenario"
+func packContents(p *pack.Pack) string {
+	contents := []string{fmt.Sprintf("%d files", len(p.Files))}
+	if p.Config != nil {
+		contents = append(contents, "config")
+	}
+	if p.Scenario != nil {
+		contents = append(contents, "scenario")
+	}
+	return strings.Join(contents, ", ")
+}
+
+// runPacks runs the packs subcommand with the arguments following "packs":
+// list writes the name, contents and description of every pack in the
+// packs directory, and extract writes the workspace files of a pack to a
+// directory. It returns the process exit code: 0 on success, 1 when a pack
+// cannot be read or extracted and 2 for invalid arguments.
+func runPacks(progname string, args []string, out, errOut io.Writer) int {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "extract") {
+		fmt.Fprintf(errOut, packsUsage+"\n", progname)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(progname+" packs "+args[0], flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	dir := flags.String("dir", "", "directory of the packs (default the packs directory under the data directory)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *dir == "" {
+		*dir = packsDirectory(defaultAppName)
+	}
+
+	if args[0] == "list" {
+		if flags.NArg() != 0 {
+			fmt.Fprintf(errOut, packsUsage+"\n", progname)
+			return 2
+		}
+		packs, err := pack.NewManager(*dir).List()
+		if err != nil {
+			fmt.Fprintln(errOut, err)
+			return 1
+		}
+		for _, p := range packs {
+			fmt.Fprintf(out, "%s\t%s\t%s\n", p.Name, packContents(p), p.Description)
+		}
+		return 0
+	}
+
+	if flags.NArg() != 2 {
+		fmt.Fprintf(errOut, packsUsage+"\n", progname)
+		return 2
+	}
+	p, err := openPack(flags.Arg(0), *dir)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	if err := p.Extract(flags.Arg(1)); err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Extracted %d files of %s to %s\n", len(p.Files), p.Name, flags.Arg(1))
+	return 0
+}
@@ -0,0 +1,105 @@
+// Package pidfile writes PID files that double as single-instance locks, so
+// a second server with the same app name refuses to start.
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAlreadyRunning is returned by Acquire when a live process holds the PID file
+var ErrAlreadyRunning = errors.New("another instance is already running")
+
+// writeGrace is how long an unreadable PID file is given to be written, as
+// the process that created it may not have written its PID yet
+const writeGrace = 250 * time.Millisecond
+
+// PIDFile is a PID file held by this process
+type PIDFile struct {
+	path string
+}
+
+// Acquire creates the PID file at path holding the current PID. A file left
+// behind by a process that is no longer running is replaced; one held by a
+// live process fails with an error wrapping ErrAlreadyRunning.
+func Acquire(path string) (*PIDFile, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write PID file %s: %w", path, err)
+			}
+			return &PIDFile{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create PID file %s: %w", path, err)
+		}
+
+		pid, err := readWritten(path)
+		if os.IsNotExist(err) {
+			// Released in the meantime
+			continue
+		}
+		if err == nil && processAlive(pid) {
+			return nil, fmt.Errorf("%w with PID %d (%s)", ErrAlreadyRunning, pid, path)
+		}
+
+		// Stale or unreadable: remove it and try again
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale PID file %s: %w", path, err)
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire PID file %s", path)
+}
+
+// readWritten reads the PID file at path, retrying while it is unreadable
+// for up to writeGrace so a file just created by another process is not
+// taken for a stale one
+func readWritten(path string) (int, error) {
+	deadline := time.Now().Add(writeGrace)
+	for {
+		pid, err := Read(path)
+		if err == nil || os.IsNotExist(err) || time.Now().After(deadline) {
+			return pid, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Read returns the PID stored in the file at path
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s", path)
+	}
+	return pid, nil
+}
+
+// Path returns the location of the PID file
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// Release removes the PID file if it still holds this process's PID
+func (p *PIDFile) Release() error {
+	if pid, err := Read(p.path); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove PID file %s: %w", p.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pidfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pid")
+
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if pid, err := Read(path); err != nil || pid != os.Getpid() {
+		t.Errorf("Expected PID file to hold %d, got %d (%v)", os.Getpid(), pid, err)
+	}
+
+	if _, err := Acquire(path); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected PID file to be removed, got %v", err)
+	}
+}
+
+func TestAcquireReplacesStaleFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"exited process", "2147483646\n"},
+		{"garbage", "not a pid"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.pid")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write PID file: %v", err)
+			}
+
+			pidFile, err := Acquire(path)
+			if err != nil {
+				t.Fatalf("Expected stale PID file to be replaced, got %v", err)
+			}
+			defer pidFile.Release()
+
+			if pid, _ := Read(path); pid != os.Getpid() {
+				t.Errorf("Expected PID file to hold %d, got %d", os.Getpid(), pid)
+			}
+		})
+	}
+}
+
+func TestReleaseKeepsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pid")
+	pidFile, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// Another instance took over the file
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatalf("Failed to overwrite PID file: %v", err)
+	}
+	if err := pidFile.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected foreign PID file to be kept, got %v", err)
+	}
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pid")
+	const instances = 8
+
+	var wg sync.WaitGroup
+	acquired := make(chan *PIDFile, instances)
+	for range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pidFile, err := Acquire(path)
+			if err == nil {
+				acquired <- pidFile
+			} else if !errors.Is(err, ErrAlreadyRunning) {
+				t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(acquired)
+
+	if len(acquired) != 1 {
+		t.Fatalf("Expected exactly one instance to acquire the PID file, got %d", len(acquired))
+	}
+	(<-acquired).Release()
+}
+
+func TestAcquireWaitsForPIDToBeWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pid")
+	// Another instance created the file but has not written its PID yet
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Failed to create PID file: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	}()
+
+	if _, err := Acquire(path); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected the file being written to be held, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package pidfile
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists. On
+// Windows FindProcess opens a handle, which fails for exited processes.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
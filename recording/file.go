@@ -0,0 +1,96 @@
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileStore keeps records as JSON lines in a flat file
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenFile opens the JSON lines file at path as a store, creating it and its
+// directory if needed. Records are appended to the existing ones.
+func OpenFile(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+	return &fileStore{path: path, file: file}, nil
+}
+
+// Append writes the record as a line
+func (s *fileStore) Append(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.New("recording file is closed")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+// Records decodes the file a line at a time, holding one record in memory
+func (s *fileStore) Records(filter Filter) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		file, err := os.Open(s.path)
+		if err != nil {
+			yield(Record{}, fmt.Errorf("failed to open recording file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		decoder := json.NewDecoder(file)
+		for matched := 0; filter.Limit == 0 || matched < filter.Limit; {
+			var record Record
+			if err := decoder.Decode(&record); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(Record{}, fmt.Errorf("invalid recording file %s: %w", s.path, err))
+				return
+			}
+			if !filter.Matches(record) {
+				continue
+			}
+			matched++
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Query collects the records selected by the filter
+func (s *fileStore) Query(filter Filter) ([]Record, error) {
+	return collect(s.Records(filter))
+}
+
+// Close closes the file. Queries keep working.
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,167 @@
+package recording
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the sqlite3 driver
+	"mock-lsp-server/config"
+)
+
+// sqliteSchema creates the records table and the indexes the filters use.
+// Times and latencies are stored in nanoseconds.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS records (
+	seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+	time      INTEGER NOT NULL,
+	session   TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	id        TEXT NOT NULL,
+	method    TEXT NOT NULL,
+	latency   INTEGER NOT NULL,
+	payload   BLOB,
+	error     BLOB
+);
+CREATE INDEX IF NOT EXISTS records_method ON records (method, kind, latency);
+CREATE INDEX IF NOT EXISTS records_session ON records (session, kind);
+CREATE INDEX IF NOT EXISTS records_time ON records (time);
+`
+
+// sqliteStore keeps records in an SQLite database, indexed by method,
+// latency, session and time
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the SQLite database at path as a store, creating it and
+// its directory if needed. Records are appended to the existing ones. The
+// database is opened in WAL mode, so it can be queried while a server is
+// recording into it. The driver needs cgo; builds without it fail here.
+func OpenSQLite(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_journal_mode=WAL&_busy_timeout=5000"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording database: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open recording database %s: %w", path, err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// Append inserts the record
+func (s *sqliteStore) Append(record Record) error {
+	_, err := s.db.Exec(
+		`INSERT INTO records (time, session, direction, kind, id, method, latency, payload, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Time.UnixNano(), record.Session, record.Direction, record.Kind, record.ID, record.Method,
+		int64(record.Latency), []byte(record.Payload), []byte(record.Error),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+// sqliteQuery returns the statement selecting the records of the filter,
+// and its arguments
+func sqliteQuery(filter Filter) (string, []any) {
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		where = append(where, condition)
+		args = append(args, arg)
+	}
+	if filter.Session != "" {
+		add("session = ?", filter.Session)
+	}
+	if filter.Kind != "" {
+		add("kind = ?", filter.Kind)
+	}
+	if filter.Method != "" {
+		add("method = ?", filter.Method)
+	}
+	if !filter.Since.IsZero() {
+		add("time >= ?", filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		add("time <= ?", filter.Until.UnixNano())
+	}
+	if filter.MinLatency != 0 || filter.MaxLatency != 0 {
+		add("kind = ?", KindResponse)
+		add("latency >= ?", int64(filter.MinLatency))
+		if filter.MaxLatency != 0 {
+			add("latency <= ?", int64(filter.MaxLatency))
+		}
+	}
+
+	query := "SELECT time, session, direction, kind, id, method, latency, payload, error FROM records"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY seq"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return query, args
+}
+
+// Records lets the database filter the records and reads them a row at a
+// time
+func (s *sqliteStore) Records(filter Filter) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		query, args := sqliteQuery(filter)
+		rows, err := s.db.Query(query, args...)
+		if err != nil {
+			yield(Record{}, fmt.Errorf("failed to query recording database: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var record Record
+			var nanos, latency int64
+			var payload, errData []byte
+			if err := rows.Scan(&nanos, &record.Session, &record.Direction, &record.Kind, &record.ID, &record.Method, &latency, &payload, &errData); err != nil {
+				yield(Record{}, fmt.Errorf("invalid record in recording database: %w", err))
+				return
+			}
+			record.Time = time.Unix(0, nanos)
+			record.Latency = config.Duration(latency)
+			if len(payload) > 0 {
+				record.Payload = json.RawMessage(payload)
+			}
+			if len(errData) > 0 {
+				record.Error = json.RawMessage(errData)
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(Record{}, fmt.Errorf("failed to query recording database: %w", err))
+		}
+	}
+}
+
+// Query collects the records selected by the filter
+func (s *sqliteStore) Query(filter Filter) ([]Record, error) {
+	return collect(s.Records(filter))
+}
+
+// Close closes the database
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
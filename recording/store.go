@@ -0,0 +1,139 @@
+// Package recording stores the wire messages and stats of recorded sessions
+// in a pluggable backend and queries them, so large captured corpora can be
+// analyzed without external tooling.
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+// Kinds of records
+const (
+	KindRequest      = "request"
+	KindNotification = "notification"
+	KindResponse     = "response"
+	KindStats        = "stats"
+)
+
+// Record is a wire message or stats snapshot of a recorded session
+type Record struct {
+	Time      time.Time `json:"time"`
+	Session   string    `json:"session"`
+	Direction string    `json:"direction,omitempty"` // received or sent, empty for stats
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	// Latency is the time from a request to its response, set on responses
+	Latency config.Duration `json:"latency,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// Filter selects records. Zero fields match every record.
+type Filter struct {
+	Session    string
+	Kind       string
+	Method     string
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	Since      time.Time
+	Until      time.Time
+	Limit      int // Maximum number of records returned
+}
+
+// Matches reports whether the record is selected by the filter. A latency
+// range only selects responses.
+func (f Filter) Matches(r Record) bool {
+	switch {
+	case f.Session != "" && r.Session != f.Session,
+		f.Kind != "" && r.Kind != f.Kind,
+		f.Method != "" && r.Method != f.Method,
+		!f.Since.IsZero() && r.Time.Before(f.Since),
+		!f.Until.IsZero() && r.Time.After(f.Until):
+		return false
+	}
+	if f.MinLatency == 0 && f.MaxLatency == 0 {
+		return true
+	}
+	latency := r.Latency.Duration()
+	return r.Kind == KindResponse && latency >= f.MinLatency && (f.MaxLatency == 0 || latency <= f.MaxLatency)
+}
+
+// Store keeps records. Implementations are safe for concurrent use.
+type Store interface {
+	// Append adds a record
+	Append(record Record) error
+	// Records yields the records selected by the filter, oldest first,
+	// reading them from the store as the iteration goes, so corpora larger
+	// than memory can be filtered down. It stops at the first error.
+	Records(filter Filter) iter.Seq2[Record, error]
+	// Query returns the records selected by the filter, oldest first
+	Query(filter Filter) ([]Record, error)
+	// Close releases the store
+	Close() error
+}
+
+// collect gathers the records of an iteration into a slice
+func collect(records iter.Seq2[Record, error]) ([]Record, error) {
+	collected := []Record{}
+	for record, err := range records {
+		if err != nil {
+			return collected, err
+		}
+		collected = append(collected, record)
+	}
+	return collected, nil
+}
+
+// Opener opens the store of a backend at path
+type Opener func(path string) (Store, error)
+
+// Built-in backends
+const (
+	BackendFile   = "file"   // A JSON lines file
+	BackendSQLite = "sqlite" // An SQLite database
+)
+
+var (
+	backendsMu sync.Mutex
+	backends   = map[string]Opener{BackendFile: OpenFile, BackendSQLite: OpenSQLite}
+)
+
+// Register makes a backend available to Open under name. Builds embedding a
+// database driver register their backends this way.
+func Register(name string, open Opener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[name] = open
+}
+
+// Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Open opens the store of the named backend at path
+func Open(backend, path string) (Store, error) {
+	backendsMu.Lock()
+	open, ok := backends[backend]
+	backendsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown recording backend %q, must be one of: %s", backend, strings.Join(Backends(), ", "))
+	}
+	return open(path)
+}
@@ -0,0 +1,114 @@
+package recording
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+func TestFileStore(t *testing.T) {
+	testStore(t, BackendFile, filepath.Join(t.TempDir(), "sessions", "recording.jsonl"))
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, BackendSQLite, filepath.Join(t.TempDir(), "sessions", "recording.db"))
+}
+
+// testStore checks a backend keeps what is appended across reopening and
+// filters it
+func testStore(t *testing.T, backend, path string) {
+	store, err := Open(backend, path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []Record{
+		{Time: start, Session: "1", Direction: "received", Kind: KindRequest, ID: "1", Method: "textDocument/hover", Payload: json.RawMessage(`{"line":1}`)},
+		{Time: start.Add(time.Second), Session: "1", Direction: "sent", Kind: KindResponse, ID: "1", Method: "textDocument/hover", Latency: config.Duration(250 * time.Millisecond)},
+		{Time: start.Add(2 * time.Second), Session: "2", Direction: "sent", Kind: KindResponse, ID: "1", Method: "textDocument/hover", Latency: config.Duration(5 * time.Millisecond)},
+		{Time: start.Add(3 * time.Second), Session: "2", Direction: "sent", Kind: KindResponse, ID: "2", Method: "textDocument/completion", Latency: config.Duration(time.Second)},
+	}
+	for _, record := range records {
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Reopening appends to the recorded sessions
+	store, err = Open(backend, path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.Append(Record{Time: start.Add(4 * time.Second), Session: "3", Kind: KindStats}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // Session and ID of the records
+	}{
+		{"everything", Filter{}, []string{"1/1", "1/1", "2/1", "2/2", "3/"}},
+		{"by method", Filter{Method: "textDocument/hover"}, []string{"1/1", "1/1", "2/1"}},
+		{"slow responses", Filter{MinLatency: 100 * time.Millisecond}, []string{"1/1", "2/2"}},
+		{"latency range", Filter{Method: "textDocument/hover", MaxLatency: 100 * time.Millisecond}, []string{"2/1"}},
+		{"by session and kind", Filter{Session: "2", Kind: KindResponse}, []string{"2/1", "2/2"}},
+		{"by time", Filter{Since: start.Add(time.Second), Until: start.Add(2 * time.Second)}, []string{"1/1", "2/1"}},
+		{"limited", Filter{Limit: 2}, []string{"1/1", "1/1"}},
+	}
+	got, err := store.Query(Filter{Kind: KindRequest})
+	if err != nil || len(got) != 1 || string(got[0].Payload) != `{"line":1}` || !got[0].Time.Equal(start) {
+		t.Errorf("Expected the request to keep its time and payload, got %+v (%v)", got, err)
+	}
+
+	// Breaking out of the iteration stops reading
+	read := 0
+	for _, err := range store.Records(Filter{}) {
+		if err != nil {
+			t.Fatalf("Records failed: %v", err)
+		}
+		if read++; read == 2 {
+			break
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var keys []string
+			for _, record := range got {
+				keys = append(keys, record.Session+"/"+record.ID)
+			}
+			if strings.Join(keys, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Expected %v, got %v", tt.want, keys)
+			}
+		})
+	}
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open("csv", "recording.csv"); err == nil || !strings.Contains(err.Error(), BackendFile) {
+		t.Errorf("Expected an unknown backend to list the registered ones, got %v", err)
+	}
+
+	Register("memory", func(string) (Store, error) { return nil, nil })
+	defer func() {
+		backendsMu.Lock()
+		delete(backends, "memory")
+		backendsMu.Unlock()
+	}()
+	if got := strings.Join(Backends(), ","); got != "file,memory,sqlite" {
+		t.Errorf("Expected the registered backends file, memory and sqlite, got %s", got)
+	}
+}
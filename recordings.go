@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"mock-lsp-server/recording"
+)
+
+// recordingsUsage describes the recordings subcommand
+const recordingsUsage = "usage: %s recordings query [-backend name] [-session id] [-kind kind] [-method method] [-min-latency d] [-max-latency d] [-limit n] store"
+
+// runRecordings runs the recordings subcommand with the arguments following
+// "recordings": it writes the records of a store written with -record that
+// match the filters as JSON lines. It returns the process exit code: 0 on
+// success, 1 when the store cannot be read and 2 for invalid arguments.
+func runRecordings(progname string, args []string, out, errOut io.Writer) int {
+	if len(args) == 0 || args[0] != "query" {
+		fmt.Fprintf(errOut, recordingsUsage+"\n", progname)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(progname+" recordings query", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	backend := flags.String("backend", recording.BackendFile, "storage backend of the store: "+strings.Join(recording.Backends(), ", "))
+	var filter recording.Filter
+	flags.StringVar(&filter.Session, "session", "", "only records of this session")
+	flags.StringVar(&filter.Kind, "kind", "", "only records of this kind: request, notification, response or stats")
+	flags.StringVar(&filter.Method, "method", "", "only records of this method")
+	flags.DurationVar(&filter.MinLatency, "min-latency", 0, "only responses at least this slow")
+	flags.DurationVar(&filter.MaxLatency, "max-latency", 0, "only responses at most this slow (0 for no limit)")
+	flags.IntVar(&filter.Limit, "limit", 0, "write at most this many records (0 for no limit)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || filter.MinLatency < 0 || filter.MaxLatency < 0 || filter.Limit < 0 ||
+		(filter.MaxLatency > 0 && filter.MaxLatency < filter.MinLatency) {
+		fmt.Fprintf(errOut, recordingsUsage+"\n", progname)
+		return 2
+	}
+
+	if _, err := os.Stat(flags.Arg(0)); err != nil {
+		fmt.Fprintf(errOut, "Failed to read recording store: %v\n", err)
+		return 1
+	}
+	store, err := recording.Open(*backend, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	defer store.Close()
+	encoder := json.NewEncoder(out)
+	for record, err := range store.Records(filter) {
+		if err != nil {
+			fmt.Fprintln(errOut, err)
+			return 1
+		}
+		if err := encoder.Encode(record); err != nil {
+			fmt.Fprintf(errOut, "Failed to write record: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,203 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Expectation is the response body the server must generate for the
+// requests for Method, optionally limited to documents whose URI matches a
+// glob and to positions inside a range. Values at the Ignore paths, such as
+// "$.items[*].data", are left out of the comparison.
+type Expectation struct {
+	Method   string          `json:"method"`
+	URI      string          `json:"uri,omitempty"`   // path.Match glob, e.g. "file:///*/*_test.go"
+	Range    *Range          `json:"range,omitempty"` // Matches positions from start up to and including end
+	Response json.RawMessage `json:"response"`
+	Ignore   []string        `json:"ignore,omitempty"` // JSON paths of values that may differ
+}
+
+// validate checks that the expectation names a method, has a valid glob,
+// range and ignore paths, and a response to compare with
+func (e Expectation) validate() error {
+	if e.Method == "" {
+		return errors.New("needs a method")
+	}
+	if _, err := path.Match(e.URI, ""); err != nil {
+		return fmt.Errorf("invalid uri glob %q: %w", e.URI, err)
+	}
+	if e.Range != nil && e.Range.End.before(e.Range.Start) {
+		return errors.New("range ends before it starts")
+	}
+	if e.Response == nil {
+		return errors.New("needs a response")
+	}
+	for _, ignore := range e.Ignore {
+		if _, err := parseJSONPath(ignore); err != nil {
+			return fmt.Errorf("invalid ignore path %q: %w", ignore, err)
+		}
+	}
+	return nil
+}
+
+// MatchingExpectations returns the expectations that apply to a request for
+// method on uri at pos, in file order. pos may be nil.
+func (sc *Scenario) MatchingExpectations(method, uri string, pos *Position) []*Expectation {
+	var matching []*Expectation
+	for i := range sc.Expectations {
+		e := &sc.Expectations[i]
+		if matchesRequest(e.Method, e.URI, e.Range, method, uri, pos) {
+			matching = append(matching, e)
+		}
+	}
+	return matching
+}
+
+// Diff compares a generated response body with the expected one and
+// returns a description of each difference, prefixed with its JSON path.
+// It returns nil when they match.
+func (e *Expectation) Diff(actual json.RawMessage) []string {
+	var want, got any
+	if err := decodeJSON(e.Response, &want); err != nil {
+		return []string{fmt.Sprintf("$: invalid expected response: %v", err)}
+	}
+	if err := decodeJSON(actual, &got); err != nil {
+		return []string{fmt.Sprintf("$: invalid response: %v", err)}
+	}
+
+	ignore := make([]jsonPath, 0, len(e.Ignore))
+	for _, p := range e.Ignore {
+		parsed, _ := parseJSONPath(p) // Checked by validate
+		ignore = append(ignore, parsed)
+	}
+	d := differ{ignore: ignore}
+	d.diff(nil, want, got)
+	return d.differences
+}
+
+// decodeJSON decodes data keeping numbers as written, so 1 and 1.0 differ
+// only if their values do
+func decodeJSON(data json.RawMessage, v *any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// differ collects the differences between two decoded JSON values
+type differ struct {
+	ignore      []jsonPath
+	differences []string
+}
+
+// diff compares the values at path, recursing into objects and arrays
+func (d *differ) diff(at []any, want, got any) {
+	if d.ignored(at) {
+		return
+	}
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			d.report(at, "expected an object, got %s", describe(got))
+			return
+		}
+		for _, key := range sortedKeys(w) {
+			child := appendPath(at, key)
+			if value, ok := g[key]; ok {
+				d.diff(child, w[key], value)
+			} else if !d.ignored(child) {
+				d.report(child, "missing, expected %s", describe(w[key]))
+			}
+		}
+		for _, key := range sortedKeys(g) {
+			if _, ok := w[key]; !ok && !d.ignored(appendPath(at, key)) {
+				d.report(appendPath(at, key), "unexpected %s", describe(g[key]))
+			}
+		}
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			d.report(at, "expected an array, got %s", describe(got))
+			return
+		}
+		for i := range min(len(w), len(g)) {
+			d.diff(appendPath(at, i), w[i], g[i])
+		}
+		if len(w) != len(g) {
+			d.report(at, "expected %d elements, got %d", len(w), len(g))
+		}
+	default:
+		if !sameScalar(want, got) {
+			d.report(at, "expected %s, got %s", describe(want), describe(got))
+		}
+	}
+}
+
+// ignored reports whether the value at path matches an ignore path
+func (d *differ) ignored(at []any) bool {
+	return slices.ContainsFunc(d.ignore, func(p jsonPath) bool { return p.matches(at) })
+}
+
+// report records a difference at path
+func (d *differ) report(at []any, format string, args ...any) {
+	d.differences = append(d.differences, formatPath(at)+": "+fmt.Sprintf(format, args...))
+}
+
+// appendPath returns a copy of path extended by a key or index, so sibling
+// paths never share a backing array
+func appendPath(at []any, elem any) []any {
+	return append(slices.Clip(at), elem)
+}
+
+// sameScalar compares two strings, numbers, booleans or nulls, numbers by
+// value
+func sameScalar(want, got any) bool {
+	wn, wantNumber := want.(json.Number)
+	gn, gotNumber := got.(json.Number)
+	if !wantNumber || !gotNumber {
+		return want == got
+	}
+	wf, errW := wn.Float64()
+	gf, errG := gn.Float64()
+	return wn == gn || (errW == nil && errG == nil && wf == gf)
+}
+
+// sortedKeys returns the keys of an object, sorted so differences are
+// reported in a stable order
+func sortedKeys(m map[string]any) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+// describe renders a value in a difference, shortened if long
+func describe(v any) string {
+	data, _ := json.Marshal(v)
+	if s := string(data); len(s) <= 60 {
+		return s
+	}
+	return string(data[:57]) + "..."
+}
+
+// formatPath renders a path as JSON path, e.g. $.items[0].label
+func formatPath(at []any) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, elem := range at {
+		switch e := elem.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", e)
+		case string:
+			if isIdentifier(e) {
+				b.WriteString("." + e)
+			} else {
+				fmt.Fprintf(&b, "[%q]", e)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package scenario
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		path string
+		at   []any
+		want bool
+	}{
+		{"$", nil, true},
+		{"$", []any{"items"}, false},
+		{"$.items", []any{"items"}, true},
+		{"$.items[*].data", []any{"items", 3, "data"}, true},
+		{"$.items[1].data", []any{"items", 3, "data"}, false},
+		{"$.items[3]['data']", []any{"items", 3, "data"}, true},
+		{`$["sort text"]`, []any{"sort text"}, true},
+		{"$.*.label", []any{"items", "label"}, true},
+		{"$..data", []any{"items", 0, "data"}, true},
+		{"$..data", []any{"data"}, true},
+		{"$..data", []any{"items", 0, "data", "id"}, false},
+		{"$..[0]", []any{"items", 0}, true},
+		{"$.items..range.start", []any{"items", 2, "textEdit", "range", "start"}, true},
+	}
+	for _, tt := range tests {
+		p, err := parseJSONPath(tt.path)
+		if err != nil {
+			t.Errorf("parseJSONPath(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if got := p.matches(tt.at); got != tt.want {
+			t.Errorf("%s matching %v = %v, want %v", tt.path, tt.at, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"items", "$.", "$[", "$[-1]", "$.a b", "$items"} {
+		if _, err := parseJSONPath(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestExpectationDiff(t *testing.T) {
+	expected := `{"isIncomplete": false, "items": [
+		{"label": "mockFunction", "kind": 3, "data": {"id": 1}},
+		{"label": "mockVariable", "kind": 6.0, "data": {"id": 2}}
+	]}`
+	tests := []struct {
+		name   string
+		actual string
+		ignore []string
+		want   []string
+	}{
+		{"identical", `{"isIncomplete": false, "items": [{"label": "mockFunction", "kind": 3, "data": {"id": 1}}, {"label": "mockVariable", "kind": 6, "data": {"id": 2}}]}`, nil, nil},
+		{
+			"changed values",
+			`{"isIncomplete": true, "items": [{"label": "mockFunc", "kind": 3, "data": {"id": 7}}, {"label": "mockVariable", "kind": 6, "data": {"id": 8}}]}`,
+			[]string{"$.items[*].data"},
+			[]string{`$.isIncomplete: expected false, got true`, `$.items[0].label: expected "mockFunction", got "mockFunc"`},
+		},
+		{
+			"missing and unexpected fields",
+			`{"items": [{"label": "mockFunction", "kind": 3, "detail": "func()"}], "extra": null}`,
+			[]string{"$..data"},
+			[]string{"$.isIncomplete: missing, expected false", "$.extra: unexpected null", "$.items[0].detail: unexpected \"func()\"", "$.items: expected 2 elements, got 1"},
+		},
+		{"wrong type", `[]`, nil, []string{"$: expected an object, got []"}},
+		{"ignore everything", `null`, []string{"$"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Expectation{Method: "textDocument/completion", Response: json.RawMessage(expected), Ignore: tt.ignore}
+			if err := e.validate(); err != nil {
+				t.Fatalf("Invalid expectation: %v", err)
+			}
+			got := e.Diff(json.RawMessage(tt.actual))
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("Expected differences\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestExpectationMatching(t *testing.T) {
+	sc, err := Parse([]byte(`{"expectations": [
+		{"method": "textDocument/hover", "uri": "file:///src/*.go", "response": {"contents": "go"}},
+		{"method": "textDocument/hover", "range": {"start": {"line": 1, "character": 0}, "end": {"line": 1, "character": 9}}, "response": null}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+	if got := sc.MatchingExpectations("textDocument/hover", "file:///src/a.go", &Position{Line: 1, Character: 2}); len(got) != 2 {
+		t.Errorf("Expected both expectations to match, got %d", len(got))
+	}
+	if got := sc.MatchingExpectations("textDocument/hover", "file:///src/a.ts", nil); len(got) != 0 {
+		t.Errorf("Expected no expectation to match, got %d", len(got))
+	}
+
+	for _, invalid := range []string{
+		`{"expectations": [{"response": {}}]}`,
+		`{"expectations": [{"method": "textDocument/hover"}]}`,
+		`{"expectations": [{"method": "textDocument/hover", "response": {}, "ignore": ["items"]}]}`,
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil || !strings.Contains(err.Error(), "expectations[0]") {
+			t.Errorf("Expected an error for %s, got %v", invalid, err)
+		}
+	}
+}
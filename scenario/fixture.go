@@ -0,0 +1,76 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mock-lsp-server/config"
+)
+
+// Fixture is an editing session captured with -capture-fixtures: the
+// documents the client opened, the positions it asked about and every
+// message it sent, so the session can be replayed by tests
+type Fixture struct {
+	// Client is the clientInfo name and version sent with initialize
+	Client    string            `json:"client,omitempty"`
+	Documents []FixtureDocument `json:"documents"`
+	Positions []FixturePosition `json:"positions"`
+	Messages  []FixtureMessage  `json:"messages"`
+}
+
+// FixtureDocument is a document as the client opened it
+type FixtureDocument struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int32  `json:"version"`
+	Text       string `json:"text"`
+}
+
+// FixturePosition is a position a request was made at, with the word there
+// at the time
+type FixturePosition struct {
+	Method    string `json:"method"`
+	URI       string `json:"uri"`
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+	Word      string `json:"word,omitempty"`
+}
+
+// FixtureMessage is a message from the client, at a time relative to the
+// first message of the session
+type FixtureMessage struct {
+	At      config.Duration `json:"at"`
+	Method  string          `json:"method"`
+	Request bool            `json:"request,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// LoadFixture reads the fixture file at path
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture file %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// Write stores the fixture as indented JSON at path, creating its directory
+func (f *Fixture) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture file: %w", err)
+	}
+	return nil
+}
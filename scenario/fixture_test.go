@@ -0,0 +1,44 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+func TestFixtureWriteAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "fixture.json")
+	fixture := &Fixture{
+		Client:    "editor 1.2",
+		Documents: []FixtureDocument{{URI: "file:///a.go", LanguageID: "go", Version: 1, Text: "package a\n"}},
+		Positions: []FixturePosition{{Method: "textDocument/hover", URI: "file:///a.go", Line: 0, Character: 8, Word: "a"}},
+		Messages: []FixtureMessage{
+			{At: config.Duration(1500 * time.Millisecond), Method: "textDocument/hover", Request: true, Params: json.RawMessage(`{"position":{"line":0,"character":8}}`)},
+		},
+	}
+	if err := fixture.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	loaded, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if loaded.Client != fixture.Client || loaded.Documents[0] != fixture.Documents[0] || loaded.Positions[0] != fixture.Positions[0] {
+		t.Errorf("Expected %+v, got %+v", fixture, loaded)
+	}
+	message := loaded.Messages[0]
+	var params bytes.Buffer
+	json.Compact(&params, message.Params)
+	if message.At.Duration() != 1500*time.Millisecond || !message.Request || params.String() != `{"position":{"line":0,"character":8}}` {
+		t.Errorf("Expected the message to round-trip, got %+v", message)
+	}
+
+	if _, err := LoadFixture(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing fixture file")
+	}
+}
@@ -0,0 +1,126 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonPath is a parsed JSON path such as $.items[*].data or $..data. It
+// supports member names, array indexes, the * wildcard and .. for any depth.
+type jsonPath []pathSegment
+
+// pathSegment selects the children of a value by name, index or wildcard,
+// or with deep set their descendants at any depth
+type pathSegment struct {
+	name     string
+	index    int // -1 unless an index is selected
+	wildcard bool
+	deep     bool
+}
+
+// parseJSONPath parses a path starting with $
+func parseJSONPath(p string) (jsonPath, error) {
+	rest, ok := strings.CutPrefix(p, "$")
+	if !ok {
+		return nil, errors.New("must start with $")
+	}
+	var parsed jsonPath
+	for rest != "" {
+		segment := pathSegment{index: -1}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.deep = true
+			rest = rest[1:]
+			if strings.HasPrefix(rest, ".[") {
+				rest = rest[1:]
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "*" {
+				segment.wildcard = true
+			} else if isIdentifier(name) {
+				segment.name = name
+			} else {
+				return nil, fmt.Errorf("invalid member name %q", name)
+			}
+			parsed = append(parsed, segment)
+			continue
+		}
+		if !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, errors.New("missing ]")
+		}
+		selector := rest[1:end]
+		rest = rest[end+1:]
+		switch {
+		case selector == "*":
+			segment.wildcard = true
+		case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+			segment.name = selector[1 : len(selector)-1]
+		default:
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q", selector)
+			}
+			segment.index = index
+		}
+		parsed = append(parsed, segment)
+	}
+	return parsed, nil
+}
+
+// selects reports whether the segment selects a child by key or index
+func (s pathSegment) selects(elem any) bool {
+	switch e := elem.(type) {
+	case string:
+		return s.wildcard || (s.index < 0 && s.name == e)
+	case int:
+		return s.wildcard || s.index == e
+	}
+	return false
+}
+
+// matches reports whether the path selects the value at the keys and
+// indexes of at
+func (p jsonPath) matches(at []any) bool {
+	if len(p) == 0 {
+		return len(at) == 0
+	}
+	segment := p[0]
+	if !segment.deep {
+		return len(at) > 0 && segment.selects(at[0]) && p[1:].matches(at[1:])
+	}
+	for i, elem := range at {
+		if segment.selects(elem) && p[1:].matches(at[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdentifier reports whether name can follow a dot in a JSON path
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Response is a canned answer to the requests for Method, optionally
+// limited to documents whose URI matches a glob and to positions inside a
+// range. Either Result or Error is sent.
+type Response struct {
+	Method string          `json:"method"`
+	URI    string          `json:"uri,omitempty"`   // path.Match glob, e.g. "file:///*/*_test.go"
+	Range  *Range          `json:"range,omitempty"` // Matches positions from start up to and including end
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ResponseError  `json:"error,omitempty"`
+}
+
+// ResponseError is a JSON-RPC error sent instead of a result
+type ResponseError struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+// Range is a range of positions in a document
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+}
+
+// before reports whether p comes before other
+func (p Position) before(other Position) bool {
+	return p.Line < other.Line || (p.Line == other.Line && p.Character < other.Character)
+}
+
+// Contains reports whether pos lies inside the range, end included
+func (r Range) Contains(pos Position) bool {
+	return !pos.before(r.Start) && !r.End.before(pos)
+}
+
+// validate checks that the response names a method, has a valid glob and
+// range, and sends either a result or an error
+func (r Response) validate() error {
+	if r.Method == "" {
+		return errors.New("needs a method")
+	}
+	if _, err := path.Match(r.URI, ""); err != nil {
+		return fmt.Errorf("invalid uri glob %q: %w", r.URI, err)
+	}
+	if r.Range != nil && r.Range.End.before(r.Range.Start) {
+		return errors.New("range ends before it starts")
+	}
+	if (r.Result == nil) == (r.Error == nil) {
+		return errors.New("needs either a result or an error")
+	}
+	return nil
+}
+
+// matches reports whether the response applies to a request for method on
+// uri at pos. pos is nil for requests without a position, which only match
+// responses without a range.
+func (r Response) matches(method, uri string, pos *Position) bool {
+	return matchesRequest(r.Method, r.URI, r.Range, method, uri, pos)
+}
+
+// matchesRequest reports whether a response or expectation for wantMethod,
+// limited to the uriGlob and rng when set, applies to a request for method
+// on uri at pos
+func matchesRequest(wantMethod, uriGlob string, rng *Range, method, uri string, pos *Position) bool {
+	if wantMethod != method {
+		return false
+	}
+	if uriGlob != "" {
+		if ok, _ := path.Match(uriGlob, uri); !ok {
+			return false
+		}
+	}
+	if rng != nil && (pos == nil || !rng.Contains(*pos)) {
+		return false
+	}
+	return true
+}
+
+// Response returns the first canned response, in file order, that applies
+// to a request for method on uri at pos. pos may be nil.
+func (sc *Scenario) Response(method, uri string, pos *Position) (*Response, bool) {
+	for i := range sc.Responses {
+		if sc.Responses[i].matches(method, uri, pos) {
+			return &sc.Responses[i], true
+		}
+	}
+	return nil, false
+}
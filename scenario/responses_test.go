@@ -0,0 +1,47 @@
+package scenario
+
+import "testing"
+
+func TestResponseMatching(t *testing.T) {
+	sc, err := Parse([]byte(`{"responses": [
+		{"method": "textDocument/hover", "uri": "file:///src/*_test.go", "result": "test"},
+		{"method": "textDocument/hover", "range": {"start": {"line": 2, "character": 4}, "end": {"line": 3, "character": 0}}, "result": "range"},
+		{"method": "textDocument/hover", "result": "any"},
+		{"method": "textDocument/completion", "range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 0}}, "result": "start"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse scenario: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		pos    *Position
+		want   string
+	}{
+		{"glob", "textDocument/hover", "file:///src/a_test.go", &Position{Line: 2, Character: 5}, `"test"`},
+		{"glob does not cross directories", "textDocument/hover", "file:///src/pkg/a_test.go", &Position{Line: 0}, `"any"`},
+		{"inside the range", "textDocument/hover", "file:///src/a.go", &Position{Line: 2, Character: 4}, `"range"`},
+		{"range end included", "textDocument/hover", "file:///src/a.go", &Position{Line: 3, Character: 0}, `"range"`},
+		{"after the range", "textDocument/hover", "file:///src/a.go", &Position{Line: 3, Character: 1}, `"any"`},
+		{"no position", "textDocument/hover", "file:///src/a.go", nil, `"any"`},
+		{"range needs a position", "textDocument/completion", "file:///src/a.go", nil, ""},
+		{"other method", "textDocument/definition", "file:///src/a.go", &Position{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := sc.Response(tt.method, tt.uri, tt.pos)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Expected no response, got %s", response.Result)
+				}
+				return
+			}
+			if !ok || string(response.Result) != tt.want {
+				t.Errorf("Expected response %s, got %v", tt.want, response)
+			}
+		})
+	}
+}
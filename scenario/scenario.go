@@ -0,0 +1,163 @@
+// Package scenario loads scenario files, which script what the server does
+// over the course of a session and what it answers.
+package scenario
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+// Action is something the server does at a point of the timeline
+type Action string
+
+// Timeline actions
+const (
+	ActionPublishDiagnostics   Action = "publish_diagnostics"   // Publish diagnostics for URI
+	ActionRequestConfiguration Action = "request_configuration" // Send workspace/configuration for Section
+	ActionShowMessage          Action = "show_message"          // Send window/showMessage
+	ActionLogMessage           Action = "log_message"           // Send window/logMessage
+)
+
+// Actions lists the valid timeline actions
+var Actions = []Action{ActionPublishDiagnostics, ActionRequestConfiguration, ActionShowMessage, ActionLogMessage}
+
+// MessageTypes lists the valid types of show_message and log_message events
+var MessageTypes = []string{"error", "warning", "info", "log"}
+
+// Scenario is the content of a scenario file
+type Scenario struct {
+	// Speed divides every time of the timeline, so a long session can be
+	// simulated in a fraction of the time. Zero means real time.
+	Speed float64 `json:"speed"`
+	// Timeline lists the events run after the client sends initialized,
+	// ordered by time
+	Timeline []Event `json:"timeline"`
+	// Responses lists canned answers that replace the built-in mock
+	// responses of the requests they match
+	Responses []Response `json:"responses"`
+	// Expectations lists the response bodies the server must generate for
+	// the requests they match, so scenarios notice when the config drifts
+	Expectations []Expectation `json:"expectations"`
+}
+
+// Event is a scripted action at a time relative to the initialized
+// notification
+type Event struct {
+	At      config.Duration `json:"at"`
+	Action  Action          `json:"action"`
+	URI     string          `json:"uri,omitempty"`     // publish_diagnostics
+	Section string          `json:"section,omitempty"` // request_configuration; empty asks for everything
+	Message string          `json:"message,omitempty"` // show_message and log_message
+	Type    string          `json:"type,omitempty"`    // show_message and log_message; defaults to info
+}
+
+// Load reads and validates the scenario file at path
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	sc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// isJSON reports whether a scenario is written in JSON, which is how every
+// scenario starting with an object brace is read. Anything else is YAML.
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// Parse decodes and validates a scenario written in JSON or YAML; one
+// starting with "{" is JSON. Hardened builds refuse YAML. Unknown keys are rejected, so typos do not
+// silently drop events. The timeline is sorted by time, keeping the file
+// order of events at the same time.
+func Parse(data []byte) (*Scenario, error) {
+	if !isJSON(data) {
+		converted, err := yamlToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var sc Scenario
+	if err := decoder.Decode(&sc); err != nil {
+		return nil, err
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(sc.Timeline, func(a, b Event) int {
+		return cmp.Compare(a.At, b.At)
+	})
+	return &sc, nil
+}
+
+// Validate checks the speed, every timeline event, response and expectation
+func (sc *Scenario) Validate() error {
+	if sc.Speed < 0 {
+		return fmt.Errorf("speed must not be negative, got %v", sc.Speed)
+	}
+	for i, event := range sc.Timeline {
+		if err := event.validate(); err != nil {
+			return fmt.Errorf("timeline[%d]: %w", i, err)
+		}
+	}
+	for i, response := range sc.Responses {
+		if err := response.validate(); err != nil {
+			return fmt.Errorf("responses[%d]: %w", i, err)
+		}
+	}
+	for i, expectation := range sc.Expectations {
+		if err := expectation.validate(); err != nil {
+			return fmt.Errorf("expectations[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that the event has a known action and the fields the
+// action needs
+func (e Event) validate() error {
+	if e.At < 0 {
+		return fmt.Errorf("at must not be negative, got %s", e.At)
+	}
+	switch e.Action {
+	case ActionPublishDiagnostics:
+		if e.URI == "" {
+			return fmt.Errorf("%s needs a uri", e.Action)
+		}
+	case ActionShowMessage, ActionLogMessage:
+		if e.Message == "" {
+			return fmt.Errorf("%s needs a message", e.Action)
+		}
+		if e.Type != "" && !slices.Contains(MessageTypes, e.Type) {
+			return fmt.Errorf("invalid message type %q: must be one of %v", e.Type, MessageTypes)
+		}
+	case ActionRequestConfiguration:
+	default:
+		return fmt.Errorf("unknown action %q: must be one of %v", e.Action, Actions)
+	}
+	return nil
+}
+
+// Offset returns how long after the start of the timeline the event runs,
+// scaled by the speed of the scenario
+func (sc *Scenario) Offset(e Event) time.Duration {
+	if sc.Speed <= 0 {
+		return e.At.Duration()
+	}
+	return time.Duration(float64(e.At.Duration()) / sc.Speed)
+}
@@ -0,0 +1,114 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty scenario", `{}`, ""},
+		{"all actions", `{"timeline": [
+			{"at": "5s", "action": "publish_diagnostics", "uri": "file:///a.go"},
+			{"at": "30s", "action": "request_configuration", "section": "mock"},
+			{"at": "1m", "action": "show_message", "message": "Indexing finished", "type": "warning"},
+			{"at": "1m", "action": "log_message", "message": "Still here"}
+		]}`, ""},
+		{"unknown action", `{"timeline": [{"at": "1s", "action": "rename"}]}`, "unknown action"},
+		{"unknown key", `{"timeline": [{"at": "1s", "action": "show_message", "mesage": "typo"}]}`, "unknown field"},
+		{"missing uri", `{"timeline": [{"at": "1s", "action": "publish_diagnostics"}]}`, "needs a uri"},
+		{"missing message", `{"timeline": [{"at": "1s", "action": "log_message"}]}`, "needs a message"},
+		{"invalid message type", `{"timeline": [{"at": "1s", "action": "show_message", "message": "hi", "type": "fatal"}]}`, "invalid message type"},
+		{"negative time", `{"timeline": [{"at": "-1s", "action": "request_configuration"}]}`, "must not be negative"},
+		{"negative speed", `{"speed": -2}`, "speed must not be negative"},
+		{"responses", `{"responses": [
+			{"method": "textDocument/hover", "uri": "file:///*.go", "range": {"start": {"line": 1, "character": 0}, "end": {"line": 1, "character": 9}}, "result": null},
+			{"method": "textDocument/definition", "error": {"code": -32803, "message": "failed"}}
+		]}`, ""},
+		{"response without method", `{"responses": [{"result": {}}]}`, "needs a method"},
+		{"response without result", `{"responses": [{"method": "textDocument/hover"}]}`, "needs either a result or an error"},
+		{"response with result and error", `{"responses": [{"method": "textDocument/hover", "result": {}, "error": {"code": 1, "message": "no"}}]}`, "needs either a result or an error"},
+		{"invalid glob", `{"responses": [{"method": "textDocument/hover", "uri": "file:///[", "result": {}}]}`, "invalid uri glob"},
+		{"reversed range", `{"responses": [{"method": "textDocument/hover", "range": {"start": {"line": 2, "character": 0}, "end": {"line": 1, "character": 0}}, "result": {}}]}`, "range ends before it starts"},
+		{"invalid duration", `{"timeline": [{"at": "soon", "action": "request_configuration"}]}`, "invalid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSortsTimeline(t *testing.T) {
+	sc, err := Parse([]byte(`{"timeline": [
+		{"at": "1m", "action": "log_message", "message": "third"},
+		{"at": "5s", "action": "log_message", "message": "first"},
+		{"at": "1m", "action": "log_message", "message": "fourth"},
+		{"at": "30s", "action": "log_message", "message": "second"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var order []string
+	for _, event := range sc.Timeline {
+		order = append(order, event.Message)
+	}
+	if got := strings.Join(order, ","); got != "first,second,third,fourth" {
+		t.Errorf("Expected events sorted by time in file order, got %s", got)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	event := Event{At: config.Duration(time.Minute), Action: ActionRequestConfiguration}
+	tests := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{60, time.Second},
+	}
+
+	for _, tt := range tests {
+		sc := &Scenario{Speed: tt.speed}
+		if got := sc.Offset(event); got != tt.want {
+			t.Errorf("Expected offset %s at speed %v, got %s", tt.want, tt.speed, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	if err := os.WriteFile(path, []byte(`{"timeline": [{"at": "2s", "action": "bogus"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write scenario: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
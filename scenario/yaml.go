@@ -0,0 +1,66 @@
+//go:build !hardened
+
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToJSON converts a YAML scenario to JSON, so it is decoded as strictly
+// as a JSON one. Only the first document of the YAML stream is read.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err := jsonValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// jsonValue converts a decoded YAML value to one encoding/json can marshal.
+// Mapping keys must be scalars; they become their string form, as YAML
+// reads keys such as 200 or true as numbers and booleans.
+func jsonValue(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+		return v, nil
+	case map[any]any:
+		object := make(map[string]any, len(v))
+		for key, item := range v {
+			switch key.(type) {
+			case map[string]any, map[any]any, []any:
+				return nil, fmt.Errorf("unsupported mapping key %v", key)
+			}
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[fmt.Sprint(key)] = converted
+		}
+		return object, nil
+	case []any:
+		for i, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
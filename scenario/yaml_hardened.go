@@ -0,0 +1,15 @@
+//go:build hardened
+
+package scenario
+
+import "errors"
+
+// ErrYAMLDisabled is returned for YAML scenarios by hardened builds, which
+// only decode scenarios as JSON into their typed fields and do not link the
+// YAML decoder
+var ErrYAMLDisabled = errors.New("YAML scenarios are disabled in hardened builds; write the scenario in JSON")
+
+// yamlToJSON refuses YAML scenarios
+func yamlToJSON(_ []byte) ([]byte, error) {
+	return nil, ErrYAMLDisabled
+}
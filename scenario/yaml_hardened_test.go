@@ -0,0 +1,17 @@
+//go:build hardened
+
+package scenario
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseYAMLHardened(t *testing.T) {
+	if _, err := Parse([]byte("speed: 2\n")); !errors.Is(err, ErrYAMLDisabled) {
+		t.Errorf("Expected YAML scenarios to be refused, got %v", err)
+	}
+	if sc, err := Parse([]byte(`{"speed": 2}`)); err != nil || sc.Speed != 2 {
+		t.Errorf("Expected JSON scenarios to still be read, got %+v (%v)", sc, err)
+	}
+}
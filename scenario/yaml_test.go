@@ -0,0 +1,47 @@
+//go:build !hardened
+
+package scenario
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mock-lsp-server/config"
+)
+
+func TestParseYAML(t *testing.T) {
+	sc, err := Parse([]byte(`# Indexing finishes after a while
+speed: 2
+timeline:
+  - at: 1m
+    action: show_message
+    message: Indexing finished
+  - {at: 5s, action: publish_diagnostics, uri: "file:///a.go"}
+responses:
+  - method: textDocument/hover
+    uri: file:///project/*.go
+    result:
+      contents: {kind: markdown, value: "**Custom** hover"}
+      200: true
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if sc.Speed != 2 || len(sc.Timeline) != 2 || sc.Timeline[0].URI != "file:///a.go" || sc.Timeline[1].At != config.Duration(time.Minute) {
+		t.Errorf("Unexpected scenario %+v", sc)
+	}
+	if len(sc.Responses) != 1 || string(sc.Responses[0].Result) != `{"200":true,"contents":{"kind":"markdown","value":"**Custom** hover"}}` {
+		t.Errorf("Expected the result converted to JSON, got %+v", sc.Responses)
+	}
+
+	for data, wantErr := range map[string]string{
+		"timeline:\n  - at: 1s\n    action: show_message\n    mesage: typo\n": "unknown field",
+		"timeline:\n  - at: 1s\n    action: rename\n":                         "unknown action",
+		"responses: [\n": "yaml:",
+	} {
+		if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error containing %q for %q, got %v", wantErr, data, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/lsp"
+)
+
+// selftestUsage describes the selftest subcommand
+const selftestUsage = "usage: %s selftest [-json] [-config path] [-print-session | -print-scenario]"
+
+// runSelftest runs the selftest subcommand with the arguments following
+// "selftest": it replays the embedded smoke-test session against a server
+// in the same process and writes a pass/fail line per step, or the report
+// as JSON with -json. -print-session and -print-scenario write the embedded
+// assets instead, as examples of the fixture and scenario formats. It
+// returns the process exit code: 0 when the self-test passed, 1 when it
+// failed and 2 for invalid arguments.
+func runSelftest(progname string, args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet(progname+" selftest", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	configPath := flags.String("config", "", "server configuration file (defaults to the built-in configuration, which the expectations are written for)")
+	asJSON := flags.Bool("json", false, "write the report as JSON")
+	printSession := flags.Bool("print-session", false, "write the embedded session and exit")
+	printScenario := flags.Bool("print-scenario", false, "write the embedded scenario and exit")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 || (*printSession && *printScenario) {
+		fmt.Fprintf(errOut, selftestUsage+"\n", progname)
+		return 2
+	}
+	switch {
+	case *printSession:
+		out.Write(lsp.SelftestSession)
+		return 0
+	case *printScenario:
+		out.Write(lsp.SelftestScenario)
+		return 0
+	}
+
+	serverConfig := config.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if serverConfig, err = config.LoadFromFileWithDefaults(*configPath); err == nil {
+			err = serverConfig.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(errOut, "Failed to load server config: %v\n", err)
+			return 2
+		}
+	}
+	server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+	server.SetConfig(serverConfig)
+
+	report, err := lsp.RunSelftest(context.Background(), server)
+	if err != nil {
+		fmt.Fprintf(errOut, "Self-test failed to run: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(errOut, "Failed to write self-test report: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, step := range report.Steps {
+			if step.Passed {
+				fmt.Fprintf(out, "PASS %s\n", step.Method)
+			} else {
+				fmt.Fprintf(out, "FAIL %s: %s\n", step.Method, step.Error)
+			}
+		}
+		for _, failure := range report.Expectations {
+			for _, difference := range failure.Differences {
+				fmt.Fprintf(out, "FAIL %s expectation: %s\n", failure.Method, difference)
+			}
+		}
+	}
+
+	if !report.Passed {
+		fmt.Fprintln(errOut, "Self-test failed")
+		return 1
+	}
+	return 0
+}
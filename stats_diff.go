@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"mock-lsp-server/lsp"
+)
+
+// statsUsage describes the stats subcommand
+const statsUsage = "usage: %[1]s stats diff [-tolerance percent] [-json] before.json after.json\n       %[1]s stats junit stats.json"
+
+// runStats runs the stats subcommand with the arguments following "stats":
+// diff compares two stats files and junit writes a stats file as a JUnit
+// report. It returns the process exit code: 0 without regressions or failed
+// test cases, 1 with them and 2 for invalid arguments or unreadable files.
+func runStats(progname string, args []string, out, errOut io.Writer) int {
+	if len(args) == 0 || (args[0] != "diff" && args[0] != "junit") {
+		fmt.Fprintf(errOut, statsUsage+"\n", progname)
+		return 2
+	}
+	if args[0] == "junit" {
+		return runStatsJUnit(progname, args[1:], out, errOut)
+	}
+
+	flags := flag.NewFlagSet(progname+" stats diff", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	tolerance := flags.Float64("tolerance", 10, "percentage latencies and response sizes may grow before counting as regressions")
+	asJSON := flags.Bool("json", false, "write the differences as JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 || *tolerance < 0 {
+		fmt.Fprintf(errOut, statsUsage+"\n", progname)
+		return 2
+	}
+
+	before, err := readStatsSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	after, err := readStatsSnapshot(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	diff := lsp.DiffStats(before, after, *tolerance/100)
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			fmt.Fprintf(errOut, "Failed to write stats diff: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, change := range diff.Changes {
+			fmt.Fprintln(out, change)
+		}
+		fmt.Fprintf(out, "%d changes, %d regressions\n", len(diff.Changes), diff.Regressions)
+	}
+
+	if diff.Regressions > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runStatsJUnit writes the stats file named by args as a JUnit report
+func runStatsJUnit(progname string, args []string, out, errOut io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(errOut, statsUsage+"\n", progname)
+		return 2
+	}
+	snapshot, err := readStatsSnapshot(args[0])
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	report := lsp.NewJUnitReport(snapshot)
+	if err := report.Write(out); err != nil {
+		fmt.Fprintf(errOut, "Failed to write JUnit report: %v\n", err)
+		return 2
+	}
+	if report.Failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readStatsSnapshot reads a $/mockLsp/stats result saved as JSON
+func readStatsSnapshot(path string) (lsp.StatsSnapshot, error) {
+	var snapshot lsp.StatsSnapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read stats file: %w", err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("failed to parse stats file %s: %w", path, err)
+	}
+	if snapshot.Requests == nil && snapshot.Notifications == nil {
+		return snapshot, fmt.Errorf("stats file %s holds no request or notification counts", path)
+	}
+	return snapshot, nil
+}
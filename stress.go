@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"mock-lsp-server/config"
+	"mock-lsp-server/lsp"
+)
+
+// stressUsage describes the stress subcommand
+const stressUsage = "usage: %s stress [-clients n] [-requests n] [-seed n] [-config path]"
+
+// runStress runs the stress subcommand with the arguments following
+// "stress": it serves -clients synthetic in-process clients concurrently,
+// each with its own server instance as the TCP listener does, and writes
+// the throughput and latency report as JSON. It returns the process exit
+// code: 0 when every request succeeded, 1 when requests failed, a session
+// failed or connections leaked into each other, and 2 for invalid
+// arguments.
+func runStress(progname string, args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet(progname+" stress", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	configPath := flags.String("config", "", "server configuration file (defaults to the built-in configuration)")
+	var stress lsp.Stress
+	flags.IntVar(&stress.Clients, "clients", 10, "number of concurrent clients")
+	flags.IntVar(&stress.Requests, "requests", 100, "requests sent by every client")
+	flags.Int64Var(&stress.Seed, "seed", 0, "seed of the request mix (0 picks a time-based seed)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 || stress.Clients < 1 || stress.Requests < 1 {
+		fmt.Fprintf(errOut, stressUsage+"\n", progname)
+		return 2
+	}
+
+	serverConfig := config.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if serverConfig, err = config.LoadFromFileWithDefaults(*configPath); err == nil {
+			err = serverConfig.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(errOut, "Failed to load server config: %v\n", err)
+			return 2
+		}
+	}
+	newServer := func() *lsp.MockLSPServer {
+		server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+		server.SetConfig(serverConfig)
+		return server
+	}
+
+	report, err := lsp.RunStress(context.Background(), newServer, stress)
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		fmt.Fprintf(errOut, "Failed to write stress report: %v\n", encodeErr)
+		return 1
+	}
+	switch {
+	case err != nil:
+		fmt.Fprintf(errOut, "Stress run failed: %v\n", err)
+		return 1
+	case len(report.Mismatches) > 0:
+		fmt.Fprintf(errOut, "Stress run found %d connections that leaked into each other\n", len(report.Mismatches))
+		return 1
+	case report.Errors > 0:
+		fmt.Fprintf(errOut, "Stress run had %d failed requests\n", report.Errors)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// closeOnTermination closes the given closers when the process receives
+// SIGTERM or SIGINT. The kernel ignores these signals for PID 1 unless it
+// handles them, so a server running as a container's init process would not
+// stop otherwise. The returned function stops handling the signals.
+func closeOnTermination(logger *log.Logger, closers ...io.Closer) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Printf("Received %s, shutting down", sig)
+			for _, closer := range closers {
+				closer.Close()
+			}
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
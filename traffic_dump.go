@@ -0,0 +1,18 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+
+	"mock-lsp-server/lsp"
+)
+
+// writeTrafficDump writes the server's recent wire traffic to out as JSON
+func writeTrafficDump(server *lsp.MockLSPServer, out io.Writer) {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(server.RecentTraffic()); err != nil {
+		log.Printf("Failed to write recent traffic: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mock-lsp-server/lsp"
+)
+
+// dumpTrafficOnSignal writes the recent wire traffic to out whenever the
+// process receives SIGQUIT, instead of the default goroutine dump and exit.
+// The returned function stops handling the signal.
+func dumpTrafficOnSignal(server *lsp.MockLSPServer, out io.Writer) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGQUIT)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				writeTrafficDump(server, out)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
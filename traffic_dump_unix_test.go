@@ -0,0 +1,62 @@
+//go:build !windows
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"mock-lsp-server/lsp"
+)
+
+// chanWriter forwards each write to a channel
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func Test_dumpTrafficOnSignal(t *testing.T) {
+	server := lsp.NewMockLSPServer(log.New(io.Discard, "", 0))
+	out := make(chanWriter, 1)
+
+	stop := dumpTrafficOnSignal(server, out)
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGQUIT); err != nil {
+		t.Fatalf("Failed to send SIGQUIT: %v", err)
+	}
+
+	select {
+	case data := <-out:
+		var messages []lsp.TrafficMessage
+		if err := json.Unmarshal(data, &messages); err != nil {
+			t.Errorf("Expected a JSON traffic dump, got %s: %v", data, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a traffic dump after SIGQUIT")
+	}
+}
+
+func Test_closeOnTermination(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	stop := closeOnTermination(log.New(io.Discard, "", 0), server)
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to send SIGTERM: %v", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the connection to be closed after SIGTERM, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import (
+	"io"
+
+	"mock-lsp-server/lsp"
+)
+
+// dumpTrafficOnSignal is a no-op on Windows, which has no SIGQUIT; use the
+// $/mockLsp/recentTraffic request instead
+func dumpTrafficOnSignal(_ *lsp.MockLSPServer, _ io.Writer) func() {
+	return func() {}
+}
@@ -0,0 +1,330 @@
+// Package transport carries JSON-RPC messages over a byte stream without
+// depending on a JSON-RPC library. Its Stream has the method set of
+// jsonrpc2.ObjectStream, so it can replace the framing of
+// sourcegraph/jsonrpc2 under an existing connection, which still dispatches
+// the messages. It adds what that framing lacks: batches, newline-delimited
+// framing, a frame size limit and a hook to inject faults into every frame.
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Framing selects how messages are delimited on the stream
+type Framing string
+
+// Supported framings
+const (
+	// FramingHeader precedes every message with a Content-Length header, as
+	// the Language Server Protocol specifies
+	FramingHeader Framing = "header"
+	// FramingLine writes every message on a line of its own
+	FramingLine Framing = "line"
+)
+
+// Framings lists the supported framings
+var Framings = []Framing{FramingHeader, FramingLine}
+
+// Directions passed to an Intercept
+const (
+	Incoming = "incoming"
+	Outgoing = "outgoing"
+)
+
+// Intercept is called with the body of every frame before it is parsed or
+// written, and returns the body to use instead. Returning nil drops the
+// frame, and returning an error fails the read or write, which is how
+// faults are injected. An Intercept may block to delay a frame.
+type Intercept func(direction string, frame []byte) ([]byte, error)
+
+// ObjectStream reads and writes JSON-RPC messages. It is the method set of
+// jsonrpc2.ObjectStream, so both implementations can carry a connection.
+type ObjectStream interface {
+	WriteObject(obj any) error
+	ReadObject(v any) error
+	Close() error
+}
+
+var _ ObjectStream = (*Stream)(nil)
+
+// DefaultMaxFrameSize bounds the body of a frame read from a stream whose
+// Options leave MaxFrameSize zero
+const DefaultMaxFrameSize = 64 << 20
+
+// ErrFrameTooLarge is returned when a frame read exceeds the maximum size.
+// The stream cannot find the next frame after it.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// Options configures a Stream
+type Options struct {
+	Framing      Framing   // One of Framings; anything else means FramingHeader
+	Intercept    Intercept // Nil passes every frame unchanged
+	MaxFrameSize int       // Largest frame body read in bytes, 0 means DefaultMaxFrameSize
+}
+
+// Stream is an ObjectStream over a byte stream. Batches read from the
+// stream are handed out one message at a time, and the responses to the
+// requests of a batch are held back and written together as one batch once
+// all of them are answered, as JSON-RPC 2.0 requires.
+type Stream struct {
+	rwc     io.ReadWriteCloser
+	reader  *bufio.Reader
+	options Options
+
+	readMu  sync.Mutex
+	pending [][]byte // Messages of the last batch not yet read
+
+	writeMu sync.Mutex
+	batches []*batch // Batches with unanswered requests, oldest first
+}
+
+// batch collects the responses to the requests of a batch
+type batch struct {
+	ids       []string // Unanswered request IDs
+	responses []json.RawMessage
+}
+
+// NewStream returns a Stream over rwc
+func NewStream(rwc io.ReadWriteCloser, options Options) *Stream {
+	if !slices.Contains(Framings, options.Framing) {
+		options.Framing = FramingHeader
+	}
+	if options.MaxFrameSize <= 0 {
+		options.MaxFrameSize = DefaultMaxFrameSize
+	}
+	return &Stream{rwc: rwc, reader: bufio.NewReader(rwc), options: options}
+}
+
+// ReadObject reads the next message into v
+func (s *Stream) ReadObject(v any) error {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	for len(s.pending) == 0 {
+		frame, err := s.readFrame()
+		if err != nil {
+			return err
+		}
+		if s.options.Intercept != nil {
+			if frame, err = s.options.Intercept(Incoming, frame); err != nil {
+				return err
+			}
+			if frame == nil {
+				continue
+			}
+		}
+		if err := s.queue(frame); err != nil {
+			return err
+		}
+	}
+
+	message := s.pending[0]
+	s.pending = s.pending[1:]
+	return json.Unmarshal(message, v)
+}
+
+// queue makes the messages of frame the next ones read. The requests of a
+// batch are registered so their responses can be collected.
+func (s *Stream) queue(frame []byte) error {
+	trimmed := bytes.TrimLeft(frame, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		s.pending = append(s.pending, frame)
+		return nil
+	}
+
+	var messages []json.RawMessage
+	if err := json.Unmarshal(trimmed, &messages); err != nil {
+		// Not a batch after all; let the reader report the malformed message
+		s.pending = append(s.pending, frame)
+		return nil
+	}
+	if len(messages) == 0 {
+		return s.writeFrame([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"empty batch"}}`))
+	}
+
+	b := &batch{}
+	for _, message := range messages {
+		if id, ok := requestID(message); ok {
+			b.ids = append(b.ids, id)
+		}
+		s.pending = append(s.pending, message)
+	}
+	if len(b.ids) > 0 {
+		s.writeMu.Lock()
+		s.batches = append(s.batches, b)
+		s.writeMu.Unlock()
+	}
+	return nil
+}
+
+// envelope holds the members of a message that tell its kind
+type envelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method *string         `json:"method"`
+}
+
+// requestID returns the ID of a request, normalized for comparison with the
+// IDs of responses. Notifications and responses have none.
+func requestID(message []byte) (string, bool) {
+	var e envelope
+	if json.Unmarshal(message, &e) != nil || e.Method == nil || len(e.ID) == 0 || string(e.ID) == "null" {
+		return "", false
+	}
+	return normalizeID(e.ID), true
+}
+
+// normalizeID returns id in a form that equal IDs share
+func normalizeID(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, id) != nil {
+		return string(id)
+	}
+	return buf.String()
+}
+
+// WriteObject writes obj as a message. A response to a request of a batch
+// is held back until the whole batch is answered.
+func (s *Stream) WriteObject(obj any) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var e envelope
+	if json.Unmarshal(data, &e) == nil && e.Method == nil && len(e.ID) > 0 {
+		id := normalizeID(e.ID)
+		for i, b := range s.batches {
+			n := slices.Index(b.ids, id)
+			if n < 0 {
+				continue
+			}
+			b.ids = slices.Delete(b.ids, n, n+1)
+			b.responses = append(b.responses, data)
+			if len(b.ids) > 0 {
+				return nil
+			}
+			s.batches = slices.Delete(s.batches, i, i+1)
+			batched, err := json.Marshal(b.responses)
+			if err != nil {
+				return err
+			}
+			return s.writeFrameLocked(batched)
+		}
+	}
+	return s.writeFrameLocked(data)
+}
+
+// writeFrame writes a frame
+func (s *Stream) writeFrame(body []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.writeFrameLocked(body)
+}
+
+// writeFrameLocked writes a frame with the framing of the stream. The
+// caller must hold s.writeMu.
+func (s *Stream) writeFrameLocked(body []byte) error {
+	if s.options.Intercept != nil {
+		var err error
+		if body, err = s.options.Intercept(Outgoing, body); err != nil || body == nil {
+			return err
+		}
+	}
+
+	var frame []byte
+	switch s.options.Framing {
+	case FramingLine:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, body); err != nil {
+			buf.Reset()
+			buf.Write(bytes.ReplaceAll(body, []byte("\n"), nil))
+		}
+		buf.WriteByte('\n')
+		frame = buf.Bytes()
+	default:
+		frame = append([]byte(fmt.Sprintf("Content-Length: %d\r\n\r\n", len(body))), body...)
+	}
+	_, err := s.rwc.Write(frame)
+	return err
+}
+
+// readFrame reads the body of the next frame, refusing bodies larger than
+// the maximum frame size before reading them
+func (s *Stream) readFrame() ([]byte, error) {
+	if s.options.Framing == FramingLine {
+		for {
+			line, err := s.readLine()
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+				return trimmed, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	length := -1
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	if length > s.options.MaxFrameSize {
+		return nil, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrFrameTooLarge, length, s.options.MaxFrameSize)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// readLine reads up to and including the next newline, failing once the
+// line without its line ending outgrows the maximum frame size
+func (s *Stream) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := s.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(bytes.TrimRight(line, "\r\n")) > s.options.MaxFrameSize {
+			return nil, fmt.Errorf("%w: line exceeds %d bytes", ErrFrameTooLarge, s.options.MaxFrameSize)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+}
+
+// Close closes the underlying stream
+func (s *Stream) Close() error {
+	return s.rwc.Close()
+}
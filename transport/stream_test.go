@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// echoHandler answers every request with its params
+func echoHandler(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+	if req.Params == nil {
+		return nil, nil
+	}
+	return req.Params, nil
+}
+
+// serve serves echoHandler over one end of a pipe with options and returns
+// the other end
+func serve(t *testing.T, options Options) net.Conn {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	conn := jsonrpc2.NewConn(context.Background(), NewStream(serverConn, options), jsonrpc2.HandlerWithError(echoHandler))
+	t.Cleanup(func() {
+		clientConn.Close()
+		conn.Close()
+	})
+	return clientConn
+}
+
+func TestStreamWithJSONRPC2(t *testing.T) {
+	for _, framing := range Framings {
+		t.Run(string(framing), func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			server := jsonrpc2.NewConn(context.Background(), NewStream(serverConn, Options{Framing: framing}), jsonrpc2.HandlerWithError(echoHandler))
+			defer server.Close()
+			client := jsonrpc2.NewConn(context.Background(), NewStream(clientConn, Options{Framing: framing}), jsonrpc2.HandlerWithError(echoHandler))
+			defer client.Close()
+
+			var got map[string]string
+			if err := client.Call(context.Background(), "echo", map[string]string{"text": "line\nbreak"}, &got); err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+			if got["text"] != "line\nbreak" {
+				t.Errorf("Expected the params echoed, got %v", got)
+			}
+		})
+	}
+}
+
+func TestHeaderFraming(t *testing.T) {
+	conn := serve(t, Options{})
+	body := `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`
+	go io.WriteString(conn, "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body)
+
+	reader := bufio.NewReader(conn)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header, "Content-Length: "), "\r\n"))
+	if err != nil {
+		t.Fatalf("Expected a Content-Length header, got %q", header)
+	}
+	if blank, _ := reader.ReadString('\n'); blank != "\r\n" {
+		t.Fatalf("Expected the header to end with a blank line, got %q", blank)
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(reader, response); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var got struct {
+		ID     int   `json:"id"`
+		Result []int `json:"result"`
+	}
+	if err := json.Unmarshal(response, &got); err != nil || got.ID != 1 || len(got.Result) != 1 || got.Result[0] != 1 {
+		t.Errorf("Unexpected response %q: %v", response, err)
+	}
+}
+
+func TestLineFramingBatch(t *testing.T) {
+	conn := serve(t, Options{Framing: FramingLine})
+	go io.WriteString(conn, "\n"+`[{"jsonrpc":"2.0","id":1,"method":"echo","params":["a"]},`+
+		`{"jsonrpc":"2.0","method":"notify"},`+
+		`{"jsonrpc":"2.0","id":"two","method":"echo","params":["b"]}]`+"\n")
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var responses []struct {
+		ID     any      `json:"id"`
+		Result []string `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(line), &responses); err != nil {
+		t.Fatalf("Expected one batch on one line, got %q: %v", line, err)
+	}
+	results := map[any]string{}
+	for _, response := range responses {
+		results[response.ID] = strings.Join(response.Result, "")
+	}
+	if len(responses) != 2 || results[float64(1)] != "a" || results["two"] != "b" {
+		t.Errorf("Expected the responses to both requests, got %q", line)
+	}
+}
+
+func TestEmptyBatch(t *testing.T) {
+	conn := serve(t, Options{Framing: FramingLine})
+	go io.WriteString(conn, "[]\n")
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(line, `"id":null`) || !strings.Contains(line, "-32600") {
+		t.Errorf("Expected an Invalid Request error, got %q and %v", line, err)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	errBroken := errors.New("broken")
+	intercept := func(direction string, frame []byte) ([]byte, error) {
+		switch {
+		case direction == Incoming && strings.Contains(string(frame), "drop"):
+			return nil, nil
+		case direction == Outgoing && strings.Contains(string(frame), "corrupt"):
+			return []byte(strings.Replace(string(frame), "corrupt", "garbled", 1)), nil
+		case direction == Incoming && strings.Contains(string(frame), "break"):
+			return nil, errBroken
+		}
+		return frame, nil
+	}
+	conn := serve(t, Options{Framing: FramingLine, Intercept: intercept})
+	reader := bufio.NewReader(conn)
+
+	go io.WriteString(conn, `{"jsonrpc":"2.0","id":1,"method":"echo","params":["drop"]}`+"\n"+
+		`{"jsonrpc":"2.0","id":2,"method":"echo","params":["corrupt"]}`+"\n")
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.Contains(line, `"id":2`) || !strings.Contains(line, "garbled") {
+		t.Errorf("Expected the first request dropped and the second response altered, got %q and %v", line, err)
+	}
+
+	go io.WriteString(conn, `{"jsonrpc":"2.0","id":3,"method":"echo","params":["break"]}`+"\n")
+	if line, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("Expected the connection to close on an intercept error, got %q", line)
+	}
+}
+
+func TestMaxFrameSize(t *testing.T) {
+	tests := []struct {
+		framing Framing
+		frame   string
+	}{
+		{framing: FramingHeader, frame: "Content-Length: 1000000000\r\n\r\n{}"},
+		{framing: FramingLine, frame: `{"jsonrpc":"2.0","id":1,"method":"echo","params":["` + strings.Repeat("x", 5000) + `"]}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.framing), func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			defer clientConn.Close()
+			stream := NewStream(serverConn, Options{Framing: tt.framing, MaxFrameSize: 4096})
+			go io.WriteString(clientConn, tt.frame)
+
+			var msg json.RawMessage
+			if err := stream.ReadObject(&msg); !errors.Is(err, ErrFrameTooLarge) {
+				t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+			}
+		})
+	}
+
+	// Frames up to the maximum are read
+	conn := serve(t, Options{Framing: FramingLine, MaxFrameSize: 4096})
+	go io.WriteString(conn, `{"jsonrpc":"2.0","id":1,"method":"echo","params":["`+strings.Repeat("x", 4000)+`"]}`+"\n")
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || !strings.Contains(line, `"id":1`) {
+		t.Errorf("Expected a frame within the maximum answered, got %q and %v", line, err)
+	}
+}
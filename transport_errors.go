@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"mock-lsp-server/activation"
+	"mock-lsp-server/logging"
+)
+
+// Backoff of the retries of transient transport errors
+const (
+	// listenAttempts bounds the attempts to listen on -addr
+	listenAttempts = 5
+	// listenRetryDelay is the delay before the second attempt to listen,
+	// doubling with every further attempt
+	listenRetryDelay = 100 * time.Millisecond
+	// acceptRetryDelay is the delay after the first of consecutive failed
+	// accepts, doubling with every further failure up to maxRetryDelay
+	acceptRetryDelay = 5 * time.Millisecond
+	maxRetryDelay    = time.Second
+)
+
+// transportErrors retries the transient errors of the listeners serving
+// -mode tcp and socket activation with backoff, where giving up would end
+// the server, and counts them for the summary logged when the server stops.
+// Use newTransportErrors to create one.
+type transportErrors struct {
+	log           *logging.StructuredLogger
+	sleep         func(time.Duration)
+	listenRetries atomic.Int64
+	acceptRetries atomic.Int64
+}
+
+// newTransportErrors returns a transportErrors logging to log
+func newTransportErrors(log *logging.StructuredLogger) *transportErrors {
+	return &transportErrors{log: log.WithContext("component", "transport"), sleep: time.Sleep}
+}
+
+// isTransient reports whether err is a transport error that may go away on
+// its own: a timeout, running out of file descriptors or buffers, a
+// connection reset before it was accepted or an address still held by a
+// previous instance
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED, syscall.ECONNRESET, syscall.EADDRINUSE} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before retry n, counting from 1, doubling from
+// base up to maxRetryDelay
+func backoff(n int, base time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < n && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// listen listens on the TCP address addr, retrying transient errors up to
+// listenAttempts times
+func (t *transportErrors) listen(addr string) (net.Listener, error) {
+	for attempt := 1; ; attempt++ {
+		listener, err := net.Listen("tcp", addr)
+		if err == nil || attempt == listenAttempts || !isTransient(err) {
+			return listener, err
+		}
+		delay := backoff(attempt, listenRetryDelay)
+		t.listenRetries.Add(1)
+		t.log.WithContext("attempt", attempt).Warning("Listening on %s failed, retrying in %s: %v", addr, delay, err)
+		t.sleep(delay)
+	}
+}
+
+// retryAccept waits before accepting again after the failures-th
+// consecutive failed accept on listener. It reports false for errors that
+// are not transient, which end the listener.
+func (t *transportErrors) retryAccept(listener net.Listener, err error, failures int) bool {
+	if !isTransient(err) {
+		return false
+	}
+	delay := backoff(failures, acceptRetryDelay)
+	t.acceptRetries.Add(1)
+	t.log.WithContext("attempt", failures).Warning("Accepting on %s failed, retrying in %s: %v", activation.Address(listener), delay, err)
+	t.sleep(delay)
+	return true
+}
+
+// logSummary logs how many transport errors were retried, if any
+func (t *transportErrors) logSummary() {
+	listenRetries, acceptRetries := t.listenRetries.Load(), t.acceptRetries.Load()
+	if listenRetries+acceptRetries == 0 {
+		return
+	}
+	t.log.WithContext("listen_retries", listenRetries).WithContext("accept_retries", acceptRetries).
+		Warning("Retried %d transport errors", listenRetries+acceptRetries)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"mock-lsp-server/logging"
+	"mock-lsp-server/lsp"
+)
+
+// testTransportErrors returns a transportErrors that logs nothing and does
+// not sleep between retries
+func testTransportErrors(t *testing.T) *transportErrors {
+	t.Helper()
+	_, manager, err := setupLogging("test-app", t.TempDir(), "", nil, false, logging.OutputNone, true)
+	if err != nil {
+		t.Fatalf("setupLogging() error = %v", err)
+	}
+	transportErrs := newTransportErrors(manager.NewStructuredLogger())
+	transportErrs.sleep = func(time.Duration) {}
+	return transportErrs
+}
+
+// failingListener fails its accepts with errs in turn, then reports that it
+// was closed
+type failingListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *failingListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	if len(l.errs) == 0 {
+		return nil, net.ErrClosed
+	}
+	err := l.errs[0]
+	l.errs = l.errs[1:]
+	return nil, err
+}
+
+func Test_isTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}, true},
+		{&net.OpError{Op: "listen", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}, true},
+		{os.ErrDeadlineExceeded, true},
+		{&net.OpError{Op: "listen", Err: os.NewSyscallError("bind", syscall.EACCES)}, false},
+		{errors.New("invalid port"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	if got := backoff(1, acceptRetryDelay); got != acceptRetryDelay {
+		t.Errorf("backoff(1) = %s, want %s", got, acceptRetryDelay)
+	}
+	if got := backoff(3, acceptRetryDelay); got != 4*acceptRetryDelay {
+		t.Errorf("backoff(3) = %s, want %s", got, 4*acceptRetryDelay)
+	}
+	if got := backoff(100, acceptRetryDelay); got != maxRetryDelay {
+		t.Errorf("backoff(100) = %s, want %s", got, maxRetryDelay)
+	}
+}
+
+func Test_serveListenerRetries(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	newServer := func() *lsp.MockLSPServer { return lsp.NewMockLSPServer(logger) }
+	crashes := &crashReporter{dir: t.TempDir(), flags: &MockLSPServerConfig{AppName: "test-app"}}
+	transportErrs := testTransportErrors(t)
+
+	emfile := &net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	listener := &failingListener{errs: []error{emfile, emfile}}
+	if err := serveListener(listener, newServer, newStreamFactory(rpcJSONRPC2, "header"), logger, crashes, transportErrs); err != nil {
+		t.Errorf("Expected transient errors to be retried, got %v", err)
+	}
+	if got := transportErrs.acceptRetries.Load(); got != 2 {
+		t.Errorf("Expected 2 accept retries, got %d", got)
+	}
+
+	fatal := errors.New("listener broken")
+	listener = &failingListener{errs: []error{fatal}}
+	if err := serveListener(listener, newServer, newStreamFactory(rpcJSONRPC2, "header"), logger, crashes, transportErrs); !errors.Is(err, fatal) {
+		t.Errorf("Expected other errors to stop the listener, got %v", err)
+	}
+}
+
+func Test_transportErrorsListen(t *testing.T) {
+	held, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	transportErrs := testTransportErrors(t)
+	// The address is released while the first retry waits
+	transportErrs.sleep = func(time.Duration) { held.Close() }
+
+	listener, err := transportErrs.listen(held.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected listening to succeed once the address is released, got %v", err)
+	}
+	listener.Close()
+	if got := transportErrs.listenRetries.Load(); got != 1 {
+		t.Errorf("Expected 1 listen retry, got %d", got)
+	}
+}
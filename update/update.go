@@ -0,0 +1,173 @@
+// Package update checks the project's GitHub releases for a newer build of
+// the server. Checks only happen when explicitly requested.
+package update
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReleasesURL is the GitHub API endpoint describing the latest release
+const ReleasesURL = "https://api.github.com/repos/rockerBOO/mock-lsp-server/releases/latest"
+
+// release is the part of a GitHub release the check uses
+type release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Result is the outcome of an update check
+type Result struct {
+	Current   string // Version of the running build
+	Latest    string // Version of the latest release
+	URL       string // Page of the latest release
+	Available bool   // The latest release is newer than the running build
+	Known     bool   // The running build has a release version to compare
+}
+
+// Check fetches the latest release from url and compares it with current
+func Check(ctx context.Context, client *http.Client, url, current string) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query releases: %s", resp.Status)
+	}
+
+	var latest release
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	latestVersion, ok := parseVersion(latest.TagName)
+	if !ok {
+		return nil, fmt.Errorf("latest release has no version tag: %q", latest.TagName)
+	}
+
+	result := &Result{
+		Current: current,
+		Latest:  strings.TrimPrefix(latest.TagName, "v"),
+		URL:     latest.HTMLURL,
+	}
+	if currentVersion, ok := parseVersion(current); ok {
+		result.Known = true
+		result.Available = compareVersions(latestVersion, currentVersion) > 0
+	}
+	return result, nil
+}
+
+// String describes the result in a single line
+func (r *Result) String() string {
+	switch {
+	case !r.Known:
+		return fmt.Sprintf("Latest release is %s (running development build %s): %s", r.Latest, r.Current, r.URL)
+	case r.Available:
+		return fmt.Sprintf("A newer release is available: %s (running %s): %s", r.Latest, r.Current, r.URL)
+	default:
+		return fmt.Sprintf("Running the latest release (%s)", r.Current)
+	}
+}
+
+// version is a parsed semantic version
+type version struct {
+	core       [3]int
+	prerelease string
+}
+
+// parseVersion parses a semantic version with an optional "v" prefix.
+// Build metadata is ignored.
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, prerelease, _ := strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return version{}, false
+	}
+	var v version
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.core[i] = n
+	}
+	v.prerelease = prerelease
+	return v, true
+}
+
+// compareVersions returns -1, 0 or 1 when a is older than, the same as or
+// newer than b. A prerelease is older than the release it precedes.
+func compareVersions(a, b version) int {
+	for i := range a.core {
+		if a.core[i] != b.core[i] {
+			if a.core[i] < b.core[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	default:
+		return comparePrereleases(a.prerelease, b.prerelease)
+	}
+}
+
+// comparePrereleases compares prerelease versions as semver orders them:
+// identifier by identifier, numeric ones by value and before alphanumeric
+// ones, and a shorter list of otherwise equal identifiers first
+func comparePrereleases(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifiers(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// compareIdentifiers compares two prerelease identifiers
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		// Without leading zeros the longer number is the larger one, which
+		// also holds for numbers too long for an int
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if c := cmp.Compare(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// isNumeric reports whether a prerelease identifier consists of digits only
+func isNumeric(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,93 @@
+package update
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.0", "1.1.9", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.2.0-rc.10", "1.2.0-rc.2", 1},
+		{"1.2.0-rc.2", "1.2.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-rc.1", "1.0.0-rc.1", 0},
+		{"1.0.0-rc.99999999999999999999", "1.0.0-rc.100", 1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			a, okA := parseVersion(tt.a)
+			b, okB := parseVersion(tt.b)
+			if !okA || !okB {
+				t.Fatalf("Expected %s and %s to parse", tt.a, tt.b)
+			}
+			if got := compareVersions(a, b); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "dev", "1.0", "1.0.x", "1.0.0.0"} {
+		if _, ok := parseVersion(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"tag_name": "v1.2.0", "html_url": "https://example.com/releases/v1.2.0"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		current       string
+		wantAvailable bool
+		wantKnown     bool
+	}{
+		{"older", "1.1.0", true, true},
+		{"same", "1.2.0", false, true},
+		{"newer", "1.3.0-rc.1", false, true},
+		{"development", "dev", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Check(context.Background(), srv.Client(), srv.URL+"/latest", tt.current)
+			if err != nil {
+				t.Fatalf("Check() failed: %v", err)
+			}
+			if result.Latest != "1.2.0" {
+				t.Errorf("Expected latest 1.2.0, got %s", result.Latest)
+			}
+			if result.Available != tt.wantAvailable || result.Known != tt.wantKnown {
+				t.Errorf("Expected available=%v known=%v, got %+v", tt.wantAvailable, tt.wantKnown, result)
+			}
+			if result.String() == "" {
+				t.Error("Expected a description of the result")
+			}
+		})
+	}
+
+	if _, err := Check(context.Background(), srv.Client(), srv.URL+"/missing", "1.0.0"); err == nil {
+		t.Error("Expected an error for a failed request")
+	}
+}
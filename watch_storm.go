@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"time"
+
+	"mock-lsp-server/lsp"
+)
+
+// watchStormUsage describes the watch-storm subcommand
+const watchStormUsage = "usage: %s watch-storm [flags] (-addr host:port | -- command [args...])"
+
+// watchStormExitTimeout bounds the wait for a spawned server to exit
+const watchStormExitTimeout = 5 * time.Second
+
+// runWatchStorm runs the watch-storm subcommand with the arguments following
+// "watch-storm": it floods a server listening on -addr, or one spawned with
+// the remaining arguments and spoken to over stdio, with watched file
+// events and writes the report as JSON. It returns the process exit code: 0
+// when the storm completed, 1 when the session failed and 2 for invalid
+// arguments.
+func runWatchStorm(progname string, args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet(progname+" watch-storm", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	addr := flags.String("addr", "", "connect to a server listening on this TCP address instead of spawning one")
+	var storm lsp.WatchStorm
+	flags.IntVar(&storm.Files, "files", 100, "files changed in every burst")
+	flags.IntVar(&storm.Bursts, "bursts", 10, "number of bursts")
+	flags.DurationVar(&storm.Interval, "interval", 100*time.Millisecond, "pause between bursts")
+	flags.IntVar(&storm.Batch, "batch", 50, "events per didChangeWatchedFiles notification")
+	flags.StringVar(&storm.Root, "root", "file:///watch-storm", "URI of the workspace folder holding the files")
+	flags.Int64Var(&storm.Seed, "seed", 0, "seed of the change types (0 picks a time-based seed)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (*addr == "") == (flags.NArg() == 0) || storm.Files < 1 || storm.Bursts < 1 || storm.Batch < 1 || storm.Interval < 0 {
+		fmt.Fprintf(errOut, watchStormUsage+"\n", progname)
+		return 2
+	}
+
+	var rwc io.ReadWriteCloser
+	var cmd *exec.Cmd
+	if *addr != "" {
+		netConn, err := net.Dial("tcp", *addr)
+		if err != nil {
+			fmt.Fprintf(errOut, "Failed to connect: %v\n", err)
+			return 1
+		}
+		rwc = netConn
+	} else {
+		var err error
+		cmd = exec.Command(flags.Arg(0), flags.Args()[1:]...)
+		cmd.Stderr = errOut
+		if rwc, err = startStdioServer(cmd); err != nil {
+			fmt.Fprintf(errOut, "Failed to start server: %v\n", err)
+			return 1
+		}
+	}
+
+	report, err := lsp.RunWatchStorm(context.Background(), rwc, storm)
+	rwc.Close()
+	if cmd != nil {
+		waitForServer(cmd, watchStormExitTimeout)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		fmt.Fprintf(errOut, "Failed to write watch storm report: %v\n", encodeErr)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(errOut, "Watch storm failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// stdioConn speaks to a spawned server over its stdin and stdout
+type stdioConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// startStdioServer starts cmd and returns a connection to its stdio
+func startStdioServer(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return stdioConn{Reader: stdout, WriteCloser: stdin}, nil
+}
+
+// waitForServer waits up to timeout for a spawned server to exit, and kills
+// it when it does not
+func waitForServer(cmd *exec.Cmd, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+	}
+}